@@ -11,14 +11,29 @@ import (
 type ControllerName string
 
 const (
-	ServiceInstanceController       ControllerName = "ServiceInstance"
-	ServiceBindingController        ControllerName = "ServiceBinding"
-	FinalizerName                   string         = "services.cloud.sap.com/sap-btp-finalizer"
-	StaleBindingIDLabel             string         = "services.cloud.sap.com/stale"
-	StaleBindingRotationOfLabel     string         = "services.cloud.sap.com/rotationOf"
-	ForceRotateAnnotation           string         = "services.cloud.sap.com/forceRotate"
-	PreventDeletion                 string         = "services.cloud.sap.com/preventDeletion"
-	UseInstanceMetadataNameInSecret string         = "services.cloud.sap.com/useInstanceMetadataName"
+	ServiceInstanceController        ControllerName = "ServiceInstance"
+	ServiceBindingController         ControllerName = "ServiceBinding"
+	FinalizerName                    string         = "services.cloud.sap.com/sap-btp-finalizer"
+	StaleBindingIDLabel              string         = "services.cloud.sap.com/stale"
+	StaleBindingRotationOfLabel      string         = "services.cloud.sap.com/rotationOf"
+	StaleBindingSinceAnnotation      string         = "services.cloud.sap.com/staleSince"
+	StaleBindingRotatedAtAnnotation  string         = "services.cloud.sap.com/rotatedAt"
+	ForceRotateAnnotation            string         = "services.cloud.sap.com/forceRotate"
+	RefreshSecretAnnotation          string         = "services.cloud.sap.com/refresh-secret"
+	PreventDeletion                  string         = "services.cloud.sap.com/preventDeletion"
+	UseInstanceMetadataNameInSecret  string         = "services.cloud.sap.com/useInstanceMetadataName"
+	SecretBindingUIDAnnotation       string         = "services.cloud.sap.com/bindingUID"
+	SecretBindingNamespaceAnnotation string         = "services.cloud.sap.com/bindingNamespace"
+	SecretManagedKeysAnnotation      string         = "services.cloud.sap.com/managedKeys"
+	SecretEncryptedKeysAnnotation    string         = "services.cloud.sap.com/encryptedKeys"
+	DRSourceClusterAnnotation        string         = "services.cloud.sap.com/drSourceClusterID"
+	DRResourceIDAnnotation           string         = "services.cloud.sap.com/drResourceID"
+	AdoptionRunLabel                 string         = "services.cloud.sap.com/adoptionRun"
+	AdoptionPendingAnnotation        string         = "services.cloud.sap.com/adoptionPending"
+	BindingUIDLabel                  string         = "services.cloud.sap.com/bindingUID"
+	CatalogOfferingLabel             string         = "services.cloud.sap.com/serviceOfferingName"
+	HoldRotationAnnotation           string         = "services.cloud.sap.com/hold-rotation"
+	AllowSecretReflectionAnnotation  string         = "services.cloud.sap.com/allowSecretReflectionFrom"
 )
 
 type HTTPStatusCodeError struct {
@@ -55,6 +70,9 @@ const (
 	// ConditionReady represents if the resource ready for usage.
 	ConditionReady = "Ready"
 
+	// ConditionProgressing represents whether an operation of the resource is in progress.
+	ConditionProgressing = "Progressing"
+
 	// ConditionCredRotationInProgress represents if cred rotation is in progress
 	ConditionCredRotationInProgress = "CredRotationInProgress"
 
@@ -63,6 +81,39 @@ const (
 
 	// ConditionShared represents information about the instance share situation
 	ConditionShared = "Shared"
+
+	// ConditionDrifted represents whether the instance in SM differs from the desired state
+	ConditionDrifted = "Drifted"
+
+	// ConditionDegraded represents whether SM reports the resource as not ready although its last operation did not fail
+	ConditionDegraded = "Degraded"
+
+	// ConditionCancellationRequested represents whether the resource was deleted while its creation was pending
+	ConditionCancellationRequested = "CancellationRequested"
+
+	// ConditionDeferred represents whether an update issued by the operator waits for the maintenance window of the resource
+	ConditionDeferred = "Deferred"
+
+	// ConditionSharingNotPermitted represents whether the binding was rejected because its shared instance is not consumable by its subaccount
+	ConditionSharingNotPermitted = "SharingNotPermitted"
+
+	// ConditionRotationDeferred represents whether the credentials rotation of the binding waits for pods holding it
+	ConditionRotationDeferred = "RotationDeferred"
+
+	// ConditionOwnershipMismatch represents whether an update or deletion was refused because the resource in SM is owned by another cluster or resource
+	ConditionOwnershipMismatch = "OwnershipMismatch"
+
+	// ConditionUpstreamMaintenance represents whether the binding is paused while its service instance is being updated
+	ConditionUpstreamMaintenance = "UpstreamMaintenance"
+
+	// ConditionInstanceInfoConflict represents whether the credentials of the binding contain keys of the instance info
+	ConditionInstanceInfoConflict = "InstanceInfoConflict"
+
+	// ConditionCertificateExpiring represents whether a certificate in the secrets of the binding expires soon, or expired
+	ConditionCertificateExpiring = "CertificateExpiring"
+
+	// ConditionParametersOutdated represents whether the parameters read by parametersFrom changed since the credentials of the binding were issued
+	ConditionParametersOutdated = "ParametersOutdated"
 )
 
 // +kubebuilder:object:generate=false
@@ -0,0 +1,123 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AdoptionRunSpec defines which existing resources of a subaccount in Service Manager are imported
+type AdoptionRunSpec struct {
+	// The name of the secret with the access credentials of the subaccount whose resources are imported,
+	// the credentials of the cluster are used if neither the secret nor the landscape is specified
+	// +optional
+	BTPAccessCredentialsSecret string `json:"btpAccessCredentialsSecret,omitempty"`
+
+	// The landscape whose access credentials are used, see the landscape of the service instance
+	// +optional
+	Landscape string `json:"landscape,omitempty"`
+
+	// ServiceOfferingNames restricts the import to the instances of these service offerings, all offerings if empty
+	// +optional
+	ServiceOfferingNames []string `json:"serviceOfferingNames,omitempty"`
+
+	// InstanceIDs restricts the import to the instances with these IDs in Service Manager, e.g. to import a single
+	// instance that was created in the SAP BTP cockpit
+	// +optional
+	// +kubebuilder:validation:items:Pattern=`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`
+	InstanceIDs []string `json:"instanceIDs,omitempty"`
+
+	// Labels restricts the import to the instances that have all these labels in Service Manager
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// ImportBindings imports the bindings of the imported instances as well. The credentials of a binding are stored
+	// in its secret only once the run approves the binding, see approveBindings.
+	// +optional
+	ImportBindings bool `json:"importBindings,omitempty"`
+
+	// ApproveBindings removes the services.cloud.sap.com/adoptionPending annotation from the bindings imported by
+	// the run, so that their credentials are stored in their secrets
+	// +optional
+	ApproveBindings bool `json:"approveBindings,omitempty"`
+}
+
+// AdoptionCounts counts the resources found by an adoption run
+type AdoptionCounts struct {
+	// Imported is the number of resources created by the run
+	Imported int `json:"imported"`
+
+	// AlreadyImported is the number of resources already managed in the namespace of the run
+	AlreadyImported int `json:"alreadyImported"`
+
+	// Skipped is the number of resources managed by another cluster
+	Skipped int `json:"skipped"`
+
+	// Failed is the number of resources which could not be imported, see the failures of the run
+	Failed int `json:"failed"`
+}
+
+// AdoptionRunStatus defines the observed state of AdoptionRun
+type AdoptionRunStatus struct {
+	// Service adoption run conditions
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Last generation that was acted on
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Indicates when the run completed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// The service instances found by the run
+	Instances AdoptionCounts `json:"instances,omitempty"`
+
+	// The service bindings found by the run
+	Bindings AdoptionCounts `json:"bindings,omitempty"`
+
+	// The reasons the resources could not be imported, the first 20 are kept
+	Failures []string `json:"failures,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[0].reason",name="Status",type=string
+// +kubebuilder:printcolumn:JSONPath=".status.instances.imported",name="Instances",type=integer
+// +kubebuilder:printcolumn:JSONPath=".status.bindings.imported",name="Bindings",type=integer
+// +kubebuilder:printcolumn:JSONPath=".metadata.creationTimestamp",name="Age",type=date
+
+// AdoptionRun is the Schema for the adoptionruns API
+type AdoptionRun struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AdoptionRunSpec   `json:"spec,omitempty"`
+	Status AdoptionRunStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AdoptionRunList contains a list of AdoptionRun
+type AdoptionRunList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AdoptionRun `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AdoptionRun{}, &AdoptionRunList{})
+}
@@ -0,0 +1,68 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BindingInjectionSpec defines which pods consume the credentials of a service binding
+type BindingInjectionSpec struct {
+	// Selector selects the pods in the namespace of the injection that the binding credentials are injected into
+	// +required
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// The k8s name of the service binding to inject, should be in the namespace of the injection
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	ServiceBindingName string `json:"serviceBindingName"`
+
+	// EnvPrefix is prepended to the names of the environment variables created from the binding secret keys
+	// +optional
+	EnvPrefix string `json:"envPrefix,omitempty"`
+
+	// MountPath is the path in the containers where the binding secret is mounted as a volume.
+	// If not specified, the binding secret is injected only as environment variables.
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:JSONPath=".spec.serviceBindingName",name="Binding",type=string
+// +kubebuilder:printcolumn:JSONPath=".metadata.creationTimestamp",name="Age",type=date
+
+// BindingInjection is the Schema for the bindinginjections API
+type BindingInjection struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec BindingInjectionSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// BindingInjectionList contains a list of BindingInjection
+type BindingInjectionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BindingInjection `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BindingInjection{}, &BindingInjectionList{})
+}
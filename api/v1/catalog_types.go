@@ -0,0 +1,147 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ServiceOfferingSpec is a service offering of the SAP Service Manager marketplace of the subaccount of the cluster
+type ServiceOfferingSpec struct {
+	// The ID of the service offering in Service Manager
+	ID string `json:"id"`
+
+	// The name of the service offering in the catalog, the serviceOfferingName of service instances
+	Name string `json:"name"`
+
+	// The description of the service offering
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Indicates whether service instances of the offering can be bound
+	// +optional
+	Bindable bool `json:"bindable,omitempty"`
+
+	// Indicates whether the plan of service instances of the offering can be changed
+	// +optional
+	PlanUpdatable bool `json:"planUpdatable,omitempty"`
+
+	// The ID of the broker of the service offering in Service Manager
+	// +optional
+	BrokerID string `json:"brokerID,omitempty"`
+
+	// The name of the broker of the service offering
+	// +optional
+	BrokerName string `json:"brokerName,omitempty"`
+
+	// The data center of the service offering, the dataCenter of service instances
+	// +optional
+	DataCenter string `json:"dataCenter,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:JSONPath=".spec.name",name="Offering",type=string
+// +kubebuilder:printcolumn:JSONPath=".spec.dataCenter",name="Data Center",type=string
+// +kubebuilder:printcolumn:JSONPath=".spec.brokerName",name="Broker",type=string
+// +kubebuilder:printcolumn:JSONPath=".spec.bindable",name="Bindable",type=boolean
+// +kubebuilder:printcolumn:JSONPath=".metadata.creationTimestamp",name="Age",type=date
+
+// ServiceOffering is a read-only copy of a service offering of Service Manager, maintained by the operator
+type ServiceOffering struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ServiceOfferingSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ServiceOfferingList contains a list of ServiceOffering
+type ServiceOfferingList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ServiceOffering `json:"items"`
+}
+
+// ServicePlanSpec is a service plan of the SAP Service Manager marketplace of the subaccount of the cluster
+type ServicePlanSpec struct {
+	// The ID of the service plan in Service Manager, the servicePlanID of service instances
+	ID string `json:"id"`
+
+	// The name of the service plan in the catalog, the servicePlanName of service instances
+	Name string `json:"name"`
+
+	// The description of the service plan
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Indicates whether the service plan is free of charge
+	// +optional
+	Free bool `json:"free,omitempty"`
+
+	// Indicates whether service instances of the plan can be bound
+	// +optional
+	Bindable bool `json:"bindable,omitempty"`
+
+	// The ID of the service offering of the plan in Service Manager
+	ServiceOfferingID string `json:"serviceOfferingID"`
+
+	// The name of the service offering of the plan in the catalog
+	// +optional
+	ServiceOfferingName string `json:"serviceOfferingName,omitempty"`
+
+	// The data center of the service offering of the plan
+	// +optional
+	DataCenter string `json:"dataCenter,omitempty"`
+
+	// The JSON schemas of the parameters of the plan published by the broker, by resource and operation,
+	// e.g. service_instance.create.parameters
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	Schemas *runtime.RawExtension `json:"schemas,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:JSONPath=".spec.serviceOfferingName",name="Offering",type=string
+// +kubebuilder:printcolumn:JSONPath=".spec.name",name="Plan",type=string
+// +kubebuilder:printcolumn:JSONPath=".spec.dataCenter",name="Data Center",type=string
+// +kubebuilder:printcolumn:JSONPath=".spec.free",name="Free",type=boolean
+// +kubebuilder:printcolumn:JSONPath=".metadata.creationTimestamp",name="Age",type=date
+
+// ServicePlan is a read-only copy of a service plan of Service Manager, maintained by the operator
+type ServicePlan struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ServicePlanSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ServicePlanList contains a list of ServicePlan
+type ServicePlanList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ServicePlan `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ServiceOffering{}, &ServiceOfferingList{}, &ServicePlan{}, &ServicePlanList{})
+}
@@ -0,0 +1,61 @@
+package v1
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+)
+
+// undescribedProperties returns the paths of the schema properties without a description,
+// kubectl explain shows nothing for them
+func undescribedProperties(schema map[string]interface{}, path string) []string {
+	var paths []string
+	properties, _ := schema["properties"].(map[string]interface{})
+	for name, property := range properties {
+		if path == "" && name == "metadata" {
+			continue
+		}
+		propertySchema, _ := property.(map[string]interface{})
+		propertyPath := path + "." + name
+		if _, ok := propertySchema["description"]; !ok {
+			paths = append(paths, propertyPath)
+		}
+		paths = append(paths, undescribedProperties(propertySchema, propertyPath)...)
+		if items, ok := propertySchema["items"].(map[string]interface{}); ok {
+			paths = append(paths, undescribedProperties(items, propertyPath+"[]")...)
+		}
+	}
+	return paths
+}
+
+var _ = Describe("CRD schema", func() {
+	It("should describe every field", func() {
+		files, err := filepath.Glob(filepath.Join("..", "..", "config", "crd", "bases", "*.yaml"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).ToNot(BeEmpty())
+
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			Expect(err).ToNot(HaveOccurred())
+			crd := struct {
+				Spec struct {
+					Versions []struct {
+						Name   string `json:"name"`
+						Schema struct {
+							OpenAPIV3Schema map[string]interface{} `json:"openAPIV3Schema"`
+						} `json:"schema"`
+					} `json:"versions"`
+				} `json:"spec"`
+			}{}
+			Expect(yaml.Unmarshal(content, &crd)).To(Succeed())
+
+			for _, version := range crd.Spec.Versions {
+				Expect(undescribedProperties(version.Schema.OpenAPIV3Schema, "")).To(BeEmpty(), fmt.Sprintf("%s %s", filepath.Base(file), version.Name))
+			}
+		}
+	})
+})
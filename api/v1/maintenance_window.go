@@ -0,0 +1,89 @@
+package v1
+
+import (
+	"fmt"
+	"time"
+
+	// the time zones of maintenance windows are resolved without relying on the zoneinfo of the image
+	_ "time/tzdata"
+)
+
+// maxMaintenanceWindowDuration is the longest window, windows start at most once a day
+const maxMaintenanceWindowDuration = 7 * 24 * time.Hour
+
+var weekdays = map[Weekday]time.Weekday{
+	"Sunday":    time.Sunday,
+	"Monday":    time.Monday,
+	"Tuesday":   time.Tuesday,
+	"Wednesday": time.Wednesday,
+	"Thursday":  time.Thursday,
+	"Friday":    time.Friday,
+	"Saturday":  time.Saturday,
+}
+
+// Validate checks that the start, the duration, the time zone and the days of the window can be parsed
+func (w *MaintenanceWindow) Validate() error {
+	_, _, _, err := w.parse()
+	return err
+}
+
+// Next returns whether the window is open at the given time and, if it is not, when it opens next
+func (w *MaintenanceWindow) Next(now time.Time) (bool, time.Time, error) {
+	start, duration, location, err := w.parse()
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	local := now.In(location)
+	var next time.Time
+	// windows are at most a week long, so a window open now started at most a week ago
+	for day := -7; day <= 7; day++ {
+		date := local.AddDate(0, 0, day)
+		windowStart := time.Date(date.Year(), date.Month(), date.Day(), start.Hour(), start.Minute(), 0, 0, location)
+		if !w.startsOn(windowStart.Weekday()) {
+			continue
+		}
+		if !windowStart.After(now) && now.Before(windowStart.Add(duration)) {
+			return true, time.Time{}, nil
+		}
+		if windowStart.After(now) && (next.IsZero() || windowStart.Before(next)) {
+			next = windowStart
+		}
+	}
+	return false, next, nil
+}
+
+func (w *MaintenanceWindow) startsOn(weekday time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, day := range w.Days {
+		if weekdays[day] == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *MaintenanceWindow) parse() (time.Time, time.Duration, *time.Location, error) {
+	start, err := time.Parse("15:04", w.Start)
+	if err != nil {
+		return time.Time{}, 0, nil, fmt.Errorf("spec.maintenanceWindow.start '%s' is invalid, expected HH:MM", w.Start)
+	}
+	duration, err := time.ParseDuration(w.Duration)
+	if err != nil {
+		return time.Time{}, 0, nil, fmt.Errorf("spec.maintenanceWindow.duration is invalid: %s", err.Error())
+	}
+	if duration <= 0 || duration > maxMaintenanceWindowDuration {
+		return time.Time{}, 0, nil, fmt.Errorf("spec.maintenanceWindow.duration must be positive and at most %s", maxMaintenanceWindowDuration)
+	}
+	location, err := time.LoadLocation(w.TimeZone)
+	if err != nil {
+		return time.Time{}, 0, nil, fmt.Errorf("spec.maintenanceWindow.timeZone '%s' is invalid: %s", w.TimeZone, err.Error())
+	}
+	for _, day := range w.Days {
+		if _, ok := weekdays[day]; !ok {
+			return time.Time{}, 0, nil, fmt.Errorf("spec.maintenanceWindow.days contains the invalid day '%s'", day)
+		}
+	}
+	return start, duration, location, nil
+}
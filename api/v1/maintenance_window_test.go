@@ -0,0 +1,98 @@
+package v1
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Maintenance Window Test", func() {
+	var window *MaintenanceWindow
+	BeforeEach(func() {
+		window = &MaintenanceWindow{Days: []Weekday{"Saturday"}, Start: "22:00", Duration: "4h", TimeZone: "Europe/Berlin"}
+	})
+
+	berlin := func(value string) time.Time {
+		location, err := time.LoadLocation("Europe/Berlin")
+		Expect(err).ToNot(HaveOccurred())
+		t, err := time.ParseInLocation("2006-01-02 15:04", value, location)
+		Expect(err).ToNot(HaveOccurred())
+		return t
+	}
+
+	Context("Next", func() {
+		It("should be open inside the window", func() {
+			// 2026-10-17 is a Saturday
+			open, _, err := window.Next(berlin("2026-10-17 23:30"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(open).To(BeTrue())
+		})
+
+		It("should be open after midnight when the window started the day before", func() {
+			open, _, err := window.Next(berlin("2026-10-18 01:59"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(open).To(BeTrue())
+		})
+
+		It("should return the next start before the window", func() {
+			open, next, err := window.Next(berlin("2026-10-14 12:00"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(open).To(BeFalse())
+			Expect(next).To(BeTemporally("==", berlin("2026-10-17 22:00")))
+		})
+
+		It("should return the start of the next week after the window", func() {
+			open, next, err := window.Next(berlin("2026-10-18 02:00"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(open).To(BeFalse())
+			Expect(next).To(BeTemporally("==", berlin("2026-10-24 22:00")))
+		})
+
+		It("should start every day when no days are given", func() {
+			window.Days = nil
+			open, next, err := window.Next(berlin("2026-10-14 12:00"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(open).To(BeFalse())
+			Expect(next).To(BeTemporally("==", berlin("2026-10-14 22:00")))
+		})
+
+		It("should use UTC when no time zone is given", func() {
+			window.TimeZone = ""
+			_, next, err := window.Next(time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(next).To(BeTemporally("==", time.Date(2026, 10, 17, 22, 0, 0, 0, time.UTC)))
+		})
+	})
+
+	Context("Validate", func() {
+		It("should accept a valid window", func() {
+			Expect(window.Validate()).To(Succeed())
+		})
+
+		It("should reject an invalid start", func() {
+			window.Start = "25:00"
+			Expect(window.Validate()).To(MatchError(ContainSubstring("spec.maintenanceWindow.start")))
+		})
+
+		It("should reject a duration longer than a week", func() {
+			window.Duration = "169h"
+			Expect(window.Validate()).To(MatchError(ContainSubstring("at most")))
+		})
+
+		It("should reject a non-positive duration", func() {
+			window.Duration = "0s"
+			Expect(window.Validate()).To(MatchError(ContainSubstring("must be positive")))
+		})
+
+		It("should reject an unknown time zone", func() {
+			window.TimeZone = "Mars/Olympus"
+			Expect(window.Validate()).To(MatchError(ContainSubstring("spec.maintenanceWindow.timeZone")))
+		})
+
+		It("should reject an unknown day", func() {
+			window.Days = []Weekday{"Funday"}
+			Expect(window.Validate()).To(MatchError(ContainSubstring("Funday")))
+		})
+	})
+})
@@ -0,0 +1,104 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NamespaceBTPStatusName is the name of the NamespaceBTPStatus the operator maintains in each namespace
+const NamespaceBTPStatusName = "sap-btp-operator"
+
+// NamespaceOperationFailure is a failed operation of a resource of the namespace
+type NamespaceOperationFailure struct {
+	// The kind of the resource, ServiceInstance or ServiceBinding
+	Kind string `json:"kind"`
+
+	// The name of the resource
+	Name string `json:"name"`
+
+	// The type of the operation, one of provision, update, bind, rotate or delete
+	Operation string `json:"operation"`
+
+	// The error the operation failed with
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Indicates when the operation failed
+	Time metav1.Time `json:"time"`
+}
+
+// NamespaceBTPStatusStatus defines the observed state of NamespaceBTPStatus
+type NamespaceBTPStatusStatus struct {
+	// The number of instances and bindings of the namespace with an asynchronous operation in progress in Service Manager
+	InFlightOperations int `json:"inFlightOperations"`
+
+	// The number of calls to Service Manager made for the resources of the namespace in the last hour
+	SMCallsLastHour int `json:"smCallsLastHour"`
+
+	// The number of operations of the resources of the namespace that completed in the last hour
+	OperationsLastHour int `json:"operationsLastHour"`
+
+	// The number of operations of the resources of the namespace that failed in the last hour
+	FailuresLastHour int `json:"failuresLastHour"`
+
+	// The latest failed operations of the last hour, most recent first
+	// +optional
+	RecentFailures []NamespaceOperationFailure `json:"recentFailures,omitempty"`
+
+	// Indicates that the reconciles of the resources of the namespace are delayed, since the access credentials they
+	// call Service Manager with are close to their call quota
+	Throttled bool `json:"throttled"`
+
+	// The access credentials secrets close to their call quota, as namespace/name
+	// +optional
+	ThrottledCredentials []string `json:"throttledCredentials,omitempty"`
+
+	// Indicates when the status last changed
+	// +optional
+	LastUpdated metav1.Time `json:"lastUpdated,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:JSONPath=".status.inFlightOperations",name="In Flight",type=integer
+// +kubebuilder:printcolumn:JSONPath=".status.failuresLastHour",name="Failures",type=integer
+// +kubebuilder:printcolumn:JSONPath=".status.throttled",name="Throttled",type=boolean
+// +kubebuilder:printcolumn:JSONPath=".status.lastUpdated",name="Updated",type=date
+
+// NamespaceBTPStatus reports the operations of the instances and bindings of its namespace, so that the owners of the
+// namespace can diagnose them without access to the metrics of the operator. The operator maintains the
+// NamespaceBTPStatus named sap-btp-operator in each namespace with instances or bindings.
+type NamespaceBTPStatus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status NamespaceBTPStatusStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NamespaceBTPStatusList contains a list of NamespaceBTPStatus
+type NamespaceBTPStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NamespaceBTPStatus `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NamespaceBTPStatus{}, &NamespaceBTPStatusList{})
+}
@@ -0,0 +1,119 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OperatorConfigName is the name of the OperatorConfig in the release namespace that the operator applies
+const OperatorConfigName = "sap-btp-operator"
+
+// OperatorConfigSpec overrides settings of the operator at runtime, the settings of the installation are used for the
+// fields that are not specified
+type OperatorConfigSpec struct {
+	// ClusterID overrides the ID of the cluster. Resources created after the change are labeled and recovered with the
+	// new ID, resources created before keep the ID they were created with.
+	// +optional
+	ClusterID string `json:"clusterID,omitempty"`
+
+	// ManagementNamespace overrides the namespace with the access credentials secrets of namespaces, landscapes and
+	// subaccounts. The namespace must exist, the secrets are read from it right away.
+	// +optional
+	ManagementNamespace string `json:"managementNamespace,omitempty"`
+
+	// PollInterval overrides the interval at which ongoing operations are polled, at least 1s
+	// +optional
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty"`
+
+	// LongPollInterval overrides the interval at which blocked and degraded resources are checked again, at least the
+	// poll interval
+	// +optional
+	LongPollInterval *metav1.Duration `json:"longPollInterval,omitempty"`
+}
+
+// AppliedClusterID is a cluster ID the operator applied at a time
+type AppliedClusterID struct {
+	// The ID of the cluster
+	ClusterID string `json:"clusterID"`
+
+	// Indicates when the operator applied the cluster ID
+	AppliedAt metav1.Time `json:"appliedAt"`
+}
+
+// AppliedOperatorConfig are the settings the operator applied at a time
+type AppliedOperatorConfig struct {
+	// The ID of the cluster
+	ClusterID string `json:"clusterID"`
+
+	// The namespace with the access credentials secrets
+	ManagementNamespace string `json:"managementNamespace,omitempty"`
+
+	// The interval at which ongoing operations are polled
+	PollInterval metav1.Duration `json:"pollInterval"`
+
+	// The interval at which blocked and degraded resources are checked again
+	LongPollInterval metav1.Duration `json:"longPollInterval"`
+
+	// Indicates when the operator applied the settings
+	AppliedAt metav1.Time `json:"appliedAt"`
+}
+
+// OperatorConfigStatus defines the observed state of OperatorConfig
+type OperatorConfigStatus struct {
+	// Operator config conditions
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Last generation that was acted on
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The last changes of the settings applied by the operator, the current settings last. The operator keeps the
+	// last 10 changes to restore them after a restart.
+	History []AppliedOperatorConfig `json:"history,omitempty"`
+
+	// All changes of the cluster ID applied by the operator, the current ID last. Resources keep the cluster ID that
+	// was in effect when they were created.
+	ClusterIDHistory []AppliedClusterID `json:"clusterIDHistory,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[0].reason",name="Status",type=string
+// +kubebuilder:printcolumn:JSONPath=".metadata.creationTimestamp",name="Age",type=date
+
+// OperatorConfig changes settings of the operator without restarting it, only the OperatorConfig named
+// sap-btp-operator in the release namespace is applied
+type OperatorConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   OperatorConfigSpec   `json:"spec,omitempty"`
+	Status OperatorConfigStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// OperatorConfigList contains a list of OperatorConfig
+type OperatorConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OperatorConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OperatorConfig{}, &OperatorConfigList{})
+}
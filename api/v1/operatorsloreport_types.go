@@ -0,0 +1,114 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OperatorSLOReportName is the name of the OperatorSLOReport in the release namespace that the operator publishes
+const OperatorSLOReportName = "sap-btp-operator"
+
+// OperatorSLOReportSpec defines the objective the operations of the operator are measured against
+type OperatorSLOReportSpec struct {
+	// Objective is the target success rate of each operation type in percent, e.g. 99.5, 99.9 if not set.
+	// The error budget of an operation type is the share of failed operations the objective allows in the window.
+	// +kubebuilder:validation:Pattern=`^(100(\.0+)?|[0-9]{1,2}(\.[0-9]+)?)$`
+	// +optional
+	Objective string `json:"objective,omitempty"`
+}
+
+// TimeToReady are percentiles of the time from the request of an operation until the resource was ready, or deleted
+type TimeToReady struct {
+	// The median time to ready
+	P50 metav1.Duration `json:"p50"`
+
+	// The 90th percentile of the time to ready
+	P90 metav1.Duration `json:"p90"`
+
+	// The 99th percentile of the time to ready
+	P99 metav1.Duration `json:"p99"`
+}
+
+// OperationSLO are the outcomes of the operations of a type in the window of the report
+type OperationSLO struct {
+	// The type of the operations, one of provision, update, bind, rotate or delete
+	Operation string `json:"operation"`
+
+	// The number of operations that completed in the window
+	Total int `json:"total"`
+
+	// The number of operations that succeeded
+	Succeeded int `json:"succeeded"`
+
+	// The number of operations that failed
+	Failed int `json:"failed"`
+
+	// The share of succeeded operations in percent
+	SuccessRate string `json:"successRate"`
+
+	// The share of the error budget that is left in percent, negative when the objective is missed
+	ErrorBudgetRemaining string `json:"errorBudgetRemaining"`
+
+	// Percentiles of the time to ready of the succeeded operations, unset if none were measured
+	// +optional
+	TimeToReady *TimeToReady `json:"timeToReady,omitempty"`
+}
+
+// OperatorSLOReportStatus defines the observed state of OperatorSLOReport
+type OperatorSLOReportStatus struct {
+	// The rolling window the outcomes are counted in
+	Window metav1.Duration `json:"window,omitempty"`
+
+	// The objective the error budgets are computed with
+	Objective string `json:"objective,omitempty"`
+
+	// Indicates when the report was generated
+	GeneratedAt metav1.Time `json:"generatedAt,omitempty"`
+
+	// The outcomes by operation type
+	Operations []OperationSLO `json:"operations,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:JSONPath=".status.objective",name="Objective",type=string
+// +kubebuilder:printcolumn:JSONPath=".status.window",name="Window",type=string
+// +kubebuilder:printcolumn:JSONPath=".status.generatedAt",name="Generated",type=date
+
+// OperatorSLOReport publishes the rolling success rates and times to ready of the operations of the operator, only the
+// OperatorSLOReport named sap-btp-operator in the release namespace is updated
+type OperatorSLOReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   OperatorSLOReportSpec   `json:"spec,omitempty"`
+	Status OperatorSLOReportStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// OperatorSLOReportList contains a list of OperatorSLOReport
+type OperatorSLOReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OperatorSLOReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OperatorSLOReport{}, &OperatorSLOReportList{})
+}
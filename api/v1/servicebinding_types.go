@@ -19,7 +19,9 @@ package v1
 import (
 	"github.com/SAP/sap-btp-service-operator/api"
 	"github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/secrets/template"
 	v1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -32,15 +34,23 @@ type ServiceBindingSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	// The k8s name of the service instance to bind, should be in the namespace of the binding
-	// +required
+	// The k8s name of the service instance to bind, should be in the namespace of the binding.
+	// Either ServiceInstanceName or ServiceInstanceID must be specified.
+	// +optional
 	// +kubebuilder:validation:MinLength=1
-	ServiceInstanceName string `json:"serviceInstanceName"`
+	ServiceInstanceName string `json:"serviceInstanceName,omitempty"`
 
 	// The namespace of the referenced instance, if empty Binding's namespace will be used
 	// +optional
 	ServiceInstanceNamespace string `json:"serviceInstanceNamespace,omitempty"`
 
+	// The ID in Service Manager of a shared service instance to bind, instead of a ServiceInstance in the cluster,
+	// e.g. an instance shared by another cluster of the subaccount. The instance must be shared and visible to the
+	// subaccount of the access credentials of the binding namespace.
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	ServiceInstanceID string `json:"serviceInstanceID,omitempty"`
+
 	// The name of the binding in Service Manager
 	// +optional
 	ExternalName string `json:"externalName"`
@@ -68,8 +78,10 @@ type ServiceBindingSpec struct {
 	// NEVER be used to hold sensitive information. To set parameters that
 	// contain secret information, you should ALWAYS store that information
 	// in a Secret and use the ParametersFrom field.
+	// With the jsonpatch ParametersMergeStrategy, Parameters is a JSON patch, an array of operations.
 	// +optional
 	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Type=""
 	Parameters *runtime.RawExtension `json:"parameters,omitempty"`
 
 	// List of sources to populate parameters.
@@ -79,6 +91,27 @@ type ServiceBindingSpec struct {
 	// +optional
 	ParametersFrom []ParametersFromSource `json:"parametersFrom,omitempty"`
 
+	// ParametersMergeStrategy defines how the sources of ParametersFrom and Parameters are merged. With shallow, the
+	// default, a top-level parameter set by more than one source is a user error in the specification. With deep,
+	// nested objects are merged recursively and other values are overridden by later sources, the sources of
+	// ParametersFrom in their order followed by Parameters. With jsonpatch, Parameters is a JSON patch (RFC 6902)
+	// applied to the merged sources of ParametersFrom.
+	// +optional
+	ParametersMergeStrategy ParametersMergeStrategy `json:"parametersMergeStrategy,omitempty"`
+
+	// ParametersFromInstance sets binding parameters to fields of the status of the service instance, resolved when
+	// the binding is created, e.g. {"instance_guid": "instanceID"} for brokers that expect the instance ID as bind
+	// parameter. The binding is created once all referenced fields are set.
+	// A parameter name that is also set by Parameters or ParametersFrom is a user error in the specification.
+	// +optional
+	ParametersFromInstance map[string]InstanceStatusField `json:"parametersFromInstance,omitempty"`
+
+	// BindResource identifies the resource the credentials are issued for, sent as bind_resource of the Open Service
+	// Broker API to brokers that issue credentials per application or route. It is only sent for the offerings the
+	// operator is configured to send bind resources for, bindings to instances of other offerings are blocked.
+	// +optional
+	BindResource *BindResource `json:"bindResource,omitempty"`
+
 	// UserInfo contains information about the user that last modified this
 	// instance. This field is set by the API server and not settable by the
 	// end-user. User-provided values for this field are not saved.
@@ -99,6 +132,344 @@ type ServiceBindingSpec struct {
 	// +optional
 	// +kubebuilder:pruning:PreserveUnknownFields
 	SecretTemplate string `json:"secretTemplate,omitempty"`
+
+	// SecretTemplateDelimiters replaces the default delimiters of the actions in SecretTemplate, e.g. with "[[" and "]]"
+	// when the binding is deployed with a Helm chart that would otherwise render the template.
+	// +optional
+	SecretTemplateDelimiters *SecretTemplateDelimiters `json:"secretTemplateDelimiters,omitempty"`
+
+	// SecretFormat is the name of the built-in formatter that shapes the binding credentials
+	// into the structure expected by the client libraries of the service (e.g. hana, xsuaa, destination, service-manager).
+	// If not specified, the formatter registered for the offering of the referenced instance is used.
+	// Use "none" to store the credentials as returned by the broker.
+	// Use "dockerconfigjson" to store container registry credentials as a kubernetes.io/dockerconfigjson secret
+	// that can be used as image pull secret.
+	// +optional
+	SecretFormat string `json:"secretFormat,omitempty"`
+
+	// SecretFileFormat defines how the credentials and the service instance info are laid out in the secret:
+	// keyPerValue (default) stores each of them under its own key, json, yaml, dotenv and properties store them as a
+	// single file under SecretKey, or credentials.json, credentials.yaml, .env and credentials.properties.
+	// serviceBinding stores each of them under its own key with the entries of the Service Binding for Kubernetes
+	// specification (servicebinding.io) and exposes the secret in status.binding.
+	// It applies after SecretFormat and cannot be combined with SecretRootKey or SecretTemplate.
+	// +optional
+	SecretFileFormat SecretFileFormat `json:"secretFileFormat,omitempty"`
+
+	// InstanceInfoConflictPolicy defines which value the secret keeps when the credentials returned by the broker
+	// contain a key of the service instance info, i.e. instance_name, instance_guid, plan, label, type or tags.
+	// preferOperator (default) stores the instance info, preferBroker keeps the credentials, prefixOperator keeps the
+	// credentials and stores the instance info with the btp_ prefix. Conflicts are reported in the InstanceInfoConflict
+	// condition.
+	// +optional
+	InstanceInfoConflictPolicy InstanceInfoConflictPolicy `json:"instanceInfoConflictPolicy,omitempty"`
+
+	// SecretKeyMapping renames the credentials and the service instance info in the secret, e.g. {"uri": "DATABASE_URL"}.
+	// In the single file formats it renames the top-level entries of the file.
+	// It cannot be combined with SecretTemplate.
+	// +optional
+	SecretKeyMapping map[string]string `json:"secretKeyMapping,omitempty"`
+
+	// SecretExcludeKeys are credentials and service instance info that are not stored in the secret.
+	// They are excluded before SecretKeyMapping is applied, so they refer to the names returned by the broker.
+	// It cannot be combined with SecretTemplate.
+	// +optional
+	SecretExcludeKeys []string `json:"secretExcludeKeys,omitempty"`
+
+	// SecretType is the type of the secret, e.g. kubernetes.io/basic-auth or kubernetes.io/tls, Opaque if not set.
+	// The keys required by the type, e.g. username and password, must be stored in the secret, see SecretKeyMapping.
+	// It cannot be combined with SecretTemplate, which sets the type in the template.
+	// +optional
+	SecretType corev1.SecretType `json:"secretType,omitempty"`
+
+	// SecretLabels are added to the labels of the secret, e.g. for tooling that selects secrets by label.
+	// They take precedence over the labels of a SecretTemplate.
+	// +optional
+	SecretLabels map[string]string `json:"secretLabels,omitempty"`
+
+	// SecretAnnotations are added to the annotations of the secret.
+	// They take precedence over the annotations of a SecretTemplate.
+	// +optional
+	SecretAnnotations map[string]string `json:"secretAnnotations,omitempty"`
+
+	// TLSSecret stores the certificate credentials of the binding, e.g. of x509 service keys, in a kubernetes.io/tls
+	// secret with the keys tls.crt, tls.key and ca.crt. The secret is written alongside the binding secret,
+	// or the binding secret itself is written as the TLS secret when no name is set.
+	// +optional
+	TLSSecret *TLSSecret `json:"tlsSecret,omitempty"`
+
+	// Secrets are additional secrets populated with parts of the credentials of the binding, e.g. an app-config secret
+	// next to the binding secret. They are written alongside the binding secret and deleted with the binding.
+	// +optional
+	Secrets []SecretOutput `json:"secrets,omitempty"`
+
+	// MetadataConfigMap is the name of a config map written next to the binding secret with the non-sensitive metadata of
+	// the binding, i.e. the name, ID, plan, offering and tags of the instance and the endpoints of the credentials, so that
+	// applications and dashboards can consume it without read access to secrets. It is deleted with the binding.
+	// +optional
+	MetadataConfigMap string `json:"metadataConfigMap,omitempty"`
+
+	// CredentialsTarget stores the credentials in an external secret store instead of the binding secret, or serves them
+	// to the volumes of the Secrets Store CSI driver, so that they are not stored in the cluster. Credentials in Vault are
+	// written when the binding is created or rotated and when they drifted in the store, and deleted with the binding.
+	// +optional
+	CredentialsTarget *CredentialsTarget `json:"credentialsTarget,omitempty"`
+
+	// SecretImmutable creates the binding secret as immutable secret, which cannot be modified by other actors and is not
+	// watched by the kubelet. The secret is deleted and recreated when its credentials change, e.g. by a rotation.
+	// +optional
+	SecretImmutable bool `json:"secretImmutable,omitempty"`
+
+	// ReadinessGates are conditions of other objects in the namespace of the binding that must be true
+	// before the binding is created, in addition to the readiness of the service instance.
+	// +optional
+	ReadinessGates []ReadinessGate `json:"readinessGates,omitempty"`
+
+	// SecretOwnerRef references a Deployment, StatefulSet or Job in the namespace of the binding, e.g. the consuming Deployment,
+	// that owns the binding secret instead of the binding, so the secret is garbage collected with that object.
+	// The binding is tracked by the services.cloud.sap.com/bindingUID annotation of the secret.
+	// +optional
+	SecretOwnerRef *SecretOwnerReference `json:"secretOwnerRef,omitempty"`
+
+	// SecretConsumers are the names of the service accounts in the namespace of the binding that are granted read access
+	// to the binding secret when the operator stores the secrets in a dedicated binding secrets namespace.
+	// Without consumers the secret cannot be read from the namespace of the binding.
+	// +optional
+	SecretConsumers []string `json:"secretConsumers,omitempty"`
+
+	// ImagePullServiceAccounts are the names of the service accounts in the namespace of the binding whose imagePullSecrets
+	// reference the binding secret, e.g. of a binding to a container registry. The secret must be of type
+	// kubernetes.io/dockerconfigjson. Removed references are restored, and dropped when the binding is deleted.
+	// +optional
+	ImagePullServiceAccounts []string `json:"imagePullServiceAccounts,omitempty"`
+
+	// SecretTargetNamespaces are namespaces the binding secret is replicated to, e.g. to share the credentials with the
+	// applications of other teams. A target namespace must permit the replication with the
+	// services.cloud.sap.com/allowSecretReflectionFrom annotation listing the namespace of the binding, or "*".
+	// The replicas are updated with the binding secret and deleted when the binding is deleted.
+	// +optional
+	SecretTargetNamespaces []string `json:"secretTargetNamespaces,omitempty"`
+
+	// EncryptKeys are keys of the binding secret whose values are stored encrypted with the public key
+	// referenced by EncryptionKeyRef, so that only the consuming application can read them.
+	// +optional
+	EncryptKeys []string `json:"encryptKeys,omitempty"`
+
+	// EncryptionKeyRef references the PEM encoded RSA public key used to encrypt the EncryptKeys.
+	// +optional
+	EncryptionKeyRef *EncryptionKeyReference `json:"encryptionKeyRef,omitempty"`
+
+	// ClusterIDOverride is the ID of the cluster that created the binding in Service Manager, for bindings taken over
+	// from another cluster. The binding is labeled with it and recovered by it instead of the ID of this cluster.
+	// Requires the ClusterIDOverride feature gate.
+	// +optional
+	ClusterIDOverride string `json:"clusterIDOverride,omitempty"`
+
+	// DeletionPolicy defines whether the binding is deleted in Service Manager when it is deleted from the cluster.
+	// Delete (default) deletes it and its secret, Orphan keeps it in Service Manager and keeps its secret in the cluster.
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// InstanceStatusField is a field of the status of a service instance that can be passed as binding parameter
+// +kubebuilder:validation:Enum=instanceID;subaccountID;tags
+type InstanceStatusField string
+
+const (
+	// InstanceStatusFieldInstanceID is the ID of the instance in Service Manager
+	InstanceStatusFieldInstanceID InstanceStatusField = "instanceID"
+	// InstanceStatusFieldSubaccountID is the subaccount of the instance
+	InstanceStatusFieldSubaccountID InstanceStatusField = "subaccountID"
+	// InstanceStatusFieldTags are the tags of the offering of the instance together with its custom tags, passed as array
+	InstanceStatusFieldTags InstanceStatusField = "tags"
+)
+
+// EncryptionKeyReference references a public key in a config map or a secret in the namespace of the binding
+type EncryptionKeyReference struct {
+	// Kind of the referenced object, ConfigMap or Secret
+	// +required
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	Kind string `json:"kind"`
+
+	// Name of the referenced object in the namespace of the binding
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Key of the public key in the referenced object
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	Key string `json:"key"`
+}
+
+// TLSSecret defines the kubernetes.io/tls secret of the certificate credentials of a binding.
+// The keys refer to the credentials returned by the broker.
+type TLSSecret struct {
+	// Name of the TLS secret in the namespace of the binding, it must differ from the name of the binding secret.
+	// If not set, the binding secret holds only the certificate credentials.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// CertificateKey is the credential with the PEM encoded certificate chain stored as tls.crt, certificate if not set
+	// +optional
+	CertificateKey string `json:"certificateKey,omitempty"`
+
+	// PrivateKeyKey is the credential with the PEM encoded private key stored as tls.key, key if not set
+	// +optional
+	PrivateKeyKey string `json:"privateKeyKey,omitempty"`
+
+	// CAKey is the credential with the PEM encoded CA certificate stored as ca.crt, ca if not set.
+	// The ca.crt key is omitted when the credentials have no CA certificate.
+	// +optional
+	CAKey string `json:"caKey,omitempty"`
+}
+
+// SecretOutput defines an additional secret populated with the credentials selected by the key prefix and the included keys,
+// or generated by a template
+type SecretOutput struct {
+	// Name of the secret in the namespace of the binding, it must differ from the names of the binding secret and the TLS secret
+	Name string `json:"name"`
+
+	// KeyPrefix selects the credentials whose keys start with the prefix, they are stored without the prefix
+	// +optional
+	KeyPrefix string `json:"keyPrefix,omitempty"`
+
+	// IncludeKeys selects the credentials with the listed keys
+	// +optional
+	IncludeKeys []string `json:"includeKeys,omitempty"`
+
+	// Template generates the secret like spec.secretTemplate, it cannot be combined with keyPrefix and includeKeys
+	// and generates no config maps
+	// +optional
+	Template string `json:"template,omitempty"`
+
+	// Type of the secret, Opaque if not set
+	// +optional
+	Type corev1.SecretType `json:"type,omitempty"`
+}
+
+// CredentialsTarget is the external secret store the credentials of a binding are written to, exactly one of its
+// targets must be set
+type CredentialsTarget struct {
+	// Vault writes the credentials to a secret of a KV version 2 secrets engine of the Vault server configured for the operator
+	// +optional
+	Vault *VaultCredentialsTarget `json:"vault,omitempty"`
+
+	// CSI serves the credentials to the pods mounting the binding with a volume of the Secrets Store CSI driver, they
+	// are read from Service Manager on each mount and stored neither in the cluster nor in an external store
+	// +optional
+	CSI *CSICredentialsTarget `json:"csi,omitempty"`
+}
+
+// CSICredentialsTarget serves the credentials of a binding with the sap-btp provider of the Secrets Store CSI driver
+type CSICredentialsTarget struct {
+}
+
+// VaultCredentialsTarget defines the secret of a KV version 2 secrets engine of Vault the credentials are written to
+type VaultCredentialsTarget struct {
+	// Mount is the path the KV version 2 secrets engine is mounted at, secret if not set
+	// +optional
+	Mount string `json:"mount,omitempty"`
+
+	// Path of the secret in the secrets engine
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+
+	// Auth is the Kubernetes auth method the operator logs in to Vault with
+	// +required
+	Auth VaultKubernetesAuth `json:"auth"`
+}
+
+// VaultKubernetesAuth defines the login with the Kubernetes auth method of Vault, the operator logs in with a token of a
+// service account in the namespace of the binding
+type VaultKubernetesAuth struct {
+	// MountPath is the path the Kubernetes auth method is mounted at, kubernetes if not set
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+
+	// Role of the Kubernetes auth method bound to the service account
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	Role string `json:"role"`
+
+	// ServiceAccountName is the service account in the namespace of the binding whose token is exchanged for a Vault token,
+	// default if not set
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+// BindResource is the resource the credentials of a binding are issued for
+type BindResource struct {
+	// AppGUID is the ID of the application the credentials are issued for
+	// +optional
+	AppGUID string `json:"appGuid,omitempty"`
+
+	// Route is the URL of the application the credentials are issued for, for route services
+	// +optional
+	Route string `json:"route,omitempty"`
+}
+
+// SecretTemplateDelimiters are the delimiters of the actions in a secret template
+type SecretTemplateDelimiters struct {
+	// Left is the delimiter that starts an action, e.g. [[
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	Left string `json:"left"`
+
+	// Right is the delimiter that ends an action, e.g. ]]
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	Right string `json:"right"`
+}
+
+// Delimiters returns the delimiters of the template package, the default delimiters if none are set
+func (d *SecretTemplateDelimiters) Delimiters() template.Delimiters {
+	if d == nil {
+		return template.Delimiters{}
+	}
+	return template.Delimiters{Left: d.Left, Right: d.Right}
+}
+
+// SecretOwnerReference references the object that owns the binding secret
+type SecretOwnerReference struct {
+	// APIVersion of the referenced object, e.g. apps/v1
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	APIVersion string `json:"apiVersion"`
+
+	// Kind of the referenced object, e.g. Deployment
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	Kind string `json:"kind"`
+
+	// Name of the referenced object in the namespace of the binding
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// ReadinessGate references a condition of an object that must be true before the binding is created
+type ReadinessGate struct {
+	// APIVersion of the referenced object, e.g. batch/v1
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	APIVersion string `json:"apiVersion"`
+
+	// Kind of the referenced object, e.g. Job
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	Kind string `json:"kind"`
+
+	// Name of the referenced object in the namespace of the binding
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// ConditionType is the type of the condition in the status of the referenced object that must be True, e.g. Complete
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	ConditionType string `json:"conditionType"`
 }
 
 // ServiceBindingStatus defines the observed state of ServiceBinding
@@ -129,11 +500,85 @@ type ServiceBindingStatus struct {
 	// Indicates whether binding is ready for usage
 	Ready metav1.ConditionStatus `json:"ready,omitempty"`
 
+	// The phase of the binding derived from its conditions, one of Pending, Provisioning, Ready, Failed and Deleting,
+	// for tools that interpret simple phases only
+	Phase Phase `json:"phase,omitempty"`
+
 	// Indicates when binding secret was rotated
 	LastCredentialsRotationTime *metav1.Time `json:"lastCredentialsRotationTime,omitempty"`
 
+	// Indicates when the binding secret is rotated next according to the credentials rotation policy
+	NextCredentialsRotationTime *metav1.Time `json:"nextCredentialsRotationTime,omitempty"`
+
+	// The revision of the last credentials rotation, the rotated binding, its secret and its name in Service Manager
+	// are suffixed with -r<revision>
+	CredentialsRotationRevision int64 `json:"credentialsRotationRevision,omitempty"`
+
+	// HashedParametersFrom is the hash of the parameters read from the secrets referenced by parametersFrom when the
+	// binding was created, the binding is outdated when the secrets change
+	HashedParametersFrom string `json:"hashedParametersFrom,omitempty"`
+
 	// The subaccount id of the service binding
 	SubaccountID string `json:"subaccountID,omitempty"`
+
+	// The fields of the credentials referenced by the secret template that the credentials contained when the secret
+	// was first rendered, a warning event is recorded when later credentials, e.g. after a rotation, lack any of them
+	// +optional
+	SecretTemplateFields []string `json:"secretTemplateFields,omitempty"`
+
+	// The service accounts whose imagePullSecrets reference the binding secret
+	// +optional
+	ImagePullServiceAccounts []string `json:"imagePullServiceAccounts,omitempty"`
+
+	// The namespaces the binding secret is replicated to
+	// +optional
+	SecretTargetNamespaces []string `json:"secretTargetNamespaces,omitempty"`
+
+	// The version of the secret in the credentials target written by the operator, the credentials are written again
+	// when the secret was deleted or has another version
+	// +optional
+	CredentialsTargetVersion int64 `json:"credentialsTargetVersion,omitempty"`
+
+	// The shared instance the binding consumes by its ID, resolved in Service Manager when the binding is created
+	// +optional
+	SharedInstance *SharedInstanceInfo `json:"sharedInstance,omitempty"`
+
+	// The secret of the binding, set when it is stored in the serviceBinding layout, so that the binding is a
+	// ProvisionedService of the Service Binding for Kubernetes specification
+	// +optional
+	Binding *ProvisionedServiceBinding `json:"binding,omitempty"`
+
+	// Indicates when the operator last reconciled the binding, updated at most once per reconcile telemetry interval
+	// unless the requeue reason changes
+	// +optional
+	LastReconcileTime *metav1.Time `json:"lastReconcileTime,omitempty"`
+
+	// Indicates when the operator last called Service Manager for the binding
+	// +optional
+	LastSMRequestTime *metav1.Time `json:"lastSMRequestTime,omitempty"`
+
+	// Why the operator requeued the binding after its last reconcile, the error of a failed reconcile or the reason of
+	// the ongoing operation, empty if it was not requeued
+	// +optional
+	LastRequeueReason string `json:"lastRequeueReason,omitempty"`
+}
+
+// SharedInstanceInfo describes a shared instance in Service Manager that a binding consumes by its ID
+type SharedInstanceInfo struct {
+	// The name of the instance in Service Manager
+	Name string `json:"name,omitempty"`
+
+	// The name of the service offering of the instance
+	ServiceOfferingName string `json:"serviceOfferingName,omitempty"`
+
+	// The name of the service plan of the instance
+	ServicePlanName string `json:"servicePlanName,omitempty"`
+}
+
+// ProvisionedServiceBinding references the secret of a binding in the status of a ProvisionedService
+type ProvisionedServiceBinding struct {
+	// The name of the secret of the binding
+	Name string `json:"name"`
 }
 
 // +kubebuilder:object:root=true
@@ -146,7 +591,8 @@ type ServiceBindingStatus struct {
 // +kubebuilder:printcolumn:JSONPath=".status.bindingID",name="ID",type=string,priority=1
 // +kubebuilder:printcolumn:JSONPath=".status.conditions[0].message",name="Message",type=string,priority=1
 
-// ServiceBinding is the Schema for the servicebindings API
+// ServiceBinding is the Schema for the servicebindings API.
+// It requests credentials of a ServiceInstance from Service Manager and stores them in a Secret.
 type ServiceBinding struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -208,12 +654,20 @@ type ServiceBindingList struct {
 	Items           []ServiceBinding `json:"items"`
 }
 
+// CredentialsRotationPolicy configures the automatic rotation of the credentials of a binding
 type CredentialsRotationPolicy struct {
+	// Whether the credentials of the binding are rotated automatically.
+	// The credentials are also rotated once when the services.cloud.sap.com/forceRotate annotation is added.
 	Enabled bool `json:"enabled"`
-	// What frequency to perform binding rotation.
+	// What frequency to perform binding rotation, as a Go duration such as 720h. Defaults to 72h.
+	// Must be longer than rotatedBindingTTL if the operator is configured with the rotation frequency guard.
 	RotationFrequency string `json:"rotationFrequency,omitempty"`
-	// For how long to keep the rotated binding.
+	// For how long to keep the rotated binding after the new credentials became ready, as a Go duration such as 48h.
+	// Defaults to 48h, at least 1s when the rotation is enabled.
 	RotatedBindingTTL string `json:"rotatedBindingTTL,omitempty"`
+	// Whether the credentials are rotated when the parameters read by parametersFrom change. Otherwise the binding
+	// reports the ParametersOutdated condition and the new parameters apply with the next rotation.
+	RotateOnParametersChange bool `json:"rotateOnParametersChange,omitempty"`
 }
 
 func init() {
@@ -18,23 +18,79 @@ package v1
 
 import (
 	"fmt"
+	"net/url"
 	"reflect"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	"github.com/SAP/sap-btp-service-operator/api"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	"github.com/SAP/sap-btp-service-operator/internal/secrets/formatter"
 	"github.com/SAP/sap-btp-service-operator/internal/secrets/template"
 )
 
 // log is for logging in this package.
 var servicebindinglog = logf.Log.WithName("servicebinding-resource")
 
+// minRotatedBindingTTL is the shortest time a rotated binding is kept, so it is not deleted right after the rotation
+const minRotatedBindingTTL = time.Second
+
+// readinessGateKinds are the kinds readiness gates may reference, the operator is permitted to read only these kinds
+var readinessGateKinds = []schema.GroupKind{
+	{Group: "apps", Kind: "Deployment"},
+	{Group: "apps", Kind: "StatefulSet"},
+	{Group: "batch", Kind: "Job"},
+	{Group: GroupVersion.Group, Kind: "ServiceInstance"},
+	{Group: GroupVersion.Group, Kind: "ServiceBinding"},
+}
+
+// secretOwnerKinds are the kinds that may own a binding secret, the operator is permitted to read only these kinds
+var secretOwnerKinds = []schema.GroupKind{
+	{Group: "apps", Kind: "Deployment"},
+	{Group: "apps", Kind: "StatefulSet"},
+	{Group: "batch", Kind: "Job"},
+}
+
+// rotationFrequencyGuard rejects rotation policies that rotate before the previous binding expired, see SetRotationFrequencyGuard
+var rotationFrequencyGuard bool
+
+// SetRotationFrequencyGuard configures whether the webhook requires the rotation frequency to be longer than the rotated binding TTL
+func SetRotationFrequencyGuard(enabled bool) {
+	rotationFrequencyGuard = enabled
+}
+
+// secretTemplateDryRun renders secret templates with placeholder credentials at admission, see SetSecretTemplateDryRun
+var secretTemplateDryRun bool
+
+// SetSecretTemplateDryRun configures whether the webhook rejects secret templates that do not generate a valid secret
+// when they are rendered with placeholders for the credentials and the service instance info they reference
+func SetSecretTemplateDryRun(enabled bool) {
+	secretTemplateDryRun = enabled
+}
+
+// featureGates are the feature gates of the operator, see SetFeatureGates
+var featureGates *config.FeatureGates
+
+// SetFeatureGates configures the webhook to reject the fields of bindings whose feature gate is disabled, which the
+// controller would otherwise ignore. Without feature gates, e.g. in btp-lint, all fields are accepted.
+func SetFeatureGates(gates config.FeatureGates) {
+	featureGates = &gates
+}
+
 func (sb *ServiceBinding) SetupWebhookWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(sb).
@@ -51,29 +107,92 @@ var _ webhook.Validator = &ServiceBinding{}
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
 func (sb *ServiceBinding) ValidateCreate() (admission.Warnings, error) {
 	servicebindinglog.Info("validate create", "name", sb.Name)
+	if err := sb.validateInstanceReference(); err != nil {
+		return nil, err
+	}
 	if sb.Spec.CredRotationPolicy != nil {
 		if err := sb.validateCredRotatingConfig(); err != nil {
 			return nil, err
 		}
+		if err := sb.validateCredRotationSchedule(); err != nil {
+			return nil, err
+		}
 	}
 	if sb.Spec.SecretTemplate != "" {
 		if err := sb.validateSecretTemplate(); err != nil {
 			return nil, errors.Wrap(err, "spec.secretTemplate is invalid")
 		}
 	}
+	if err := sb.validateFeatureGates(nil); err != nil {
+		return nil, err
+	}
+	if err := sb.validateSecretFormat(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateSecretFileFormat(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateSecretKeyMapping(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateSecretTypeAndLabels(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateTLSSecret(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateSecretOutputs(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateMetadataConfigMap(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateCredentialsTarget(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateSecretTemplateDelimiters(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateReadinessGates(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateSecretOwnerRef(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateBindResource(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateEncryption(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateSecretConsumers(); err != nil {
+		return nil, err
+	}
+	if err := validateParameters(sb.Spec.ParametersMergeStrategy, sb.Spec.Parameters, true); err != nil {
+		return nil, err
+	}
+	if err := validateParametersFrom(sb.Spec.ParametersFrom); err != nil {
+		return nil, err
+	}
 	return nil, nil
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
 func (sb *ServiceBinding) ValidateUpdate(old runtime.Object) (admission.Warnings, error) {
 	servicebindinglog.Info("validate update", "name", sb.Name)
+	oldBinding := old.(*ServiceBinding)
 	if sb.Spec.CredRotationPolicy != nil {
 		if err := sb.validateCredRotatingConfig(); err != nil {
 			return nil, err
 		}
+		// the schedule is validated only when the policy changed, so updates of existing bindings are not blocked
+		if !reflect.DeepEqual(sb.Spec.CredRotationPolicy, oldBinding.Spec.CredRotationPolicy) {
+			if err := sb.validateCredRotationSchedule(); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	oldBinding := old.(*ServiceBinding)
 	isStale := false
 	if oldBinding.Labels != nil {
 		if _, ok := oldBinding.Labels[api.StaleBindingIDLabel]; ok {
@@ -87,6 +206,67 @@ func (sb *ServiceBinding) ValidateUpdate(old runtime.Object) (admission.Warnings
 		}
 	}
 
+	if err := sb.validateInstanceReference(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateFeatureGates(oldBinding); err != nil {
+		return nil, err
+	}
+	if err := sb.validateSecretFormat(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateSecretFileFormat(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateSecretKeyMapping(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateSecretTypeAndLabels(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateTLSSecret(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateSecretOutputs(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateMetadataConfigMap(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateCredentialsTarget(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateSecretTemplateDelimiters(); err != nil {
+		return nil, err
+	}
+	// the template is validated only when it changed, so updates of existing bindings are not blocked
+	if sb.Spec.SecretTemplate != "" && (sb.Spec.SecretTemplate != oldBinding.Spec.SecretTemplate ||
+		!reflect.DeepEqual(sb.Spec.SecretTemplateDelimiters, oldBinding.Spec.SecretTemplateDelimiters)) {
+		if err := sb.validateSecretTemplate(); err != nil {
+			return nil, errors.Wrap(err, "spec.secretTemplate is invalid")
+		}
+	}
+	// the gates are validated only when they changed, so updates of existing bindings are not blocked
+	if !reflect.DeepEqual(sb.Spec.ReadinessGates, oldBinding.Spec.ReadinessGates) {
+		if err := sb.validateReadinessGates(); err != nil {
+			return nil, err
+		}
+	}
+	if !reflect.DeepEqual(sb.Spec.SecretOwnerRef, oldBinding.Spec.SecretOwnerRef) {
+		if err := sb.validateSecretOwnerRef(); err != nil {
+			return nil, err
+		}
+	}
+	if err := sb.validateBindResource(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateEncryption(); err != nil {
+		return nil, err
+	}
+	if err := sb.validateSecretConsumers(); err != nil {
+		return nil, err
+	}
+
 	specChanged := sb.specChanged(oldBinding)
 	if specChanged && (sb.Status.BindingID != "" || isStale) {
 		return nil, fmt.Errorf("updating service bindings is not supported")
@@ -108,6 +288,16 @@ func (sb *ServiceBinding) specChanged(oldBinding *ServiceBinding) bool {
 	//allow changing cred rotation config
 	oldSpec.CredRotationPolicy = nil
 	newSpec.CredRotationPolicy = nil
+	//allow changing the consumers of the secret
+	oldSpec.SecretConsumers = nil
+	newSpec.SecretConsumers = nil
+	oldSpec.ImagePullServiceAccounts = nil
+	newSpec.ImagePullServiceAccounts = nil
+	oldSpec.SecretTargetNamespaces = nil
+	newSpec.SecretTargetNamespaces = nil
+	//allow changing the deletion policy
+	oldSpec.DeletionPolicy = ""
+	newSpec.DeletionPolicy = ""
 	return !reflect.DeepEqual(oldSpec, newSpec)
 }
 
@@ -119,22 +309,455 @@ func (sb *ServiceBinding) ValidateDelete() (admission.Warnings, error) {
 	return nil, nil
 }
 
+// validateCredRotatingConfig verifies at admission that the durations of the rotation policy can be parsed,
+// the controller relies on them to schedule rotations and to delete rotated bindings
 func (sb *ServiceBinding) validateCredRotatingConfig() error {
-	_, err := time.ParseDuration(sb.Spec.CredRotationPolicy.RotatedBindingTTL)
-	if err != nil {
+	if err := validatePolicyDuration("rotatedBindingTTL", sb.Spec.CredRotationPolicy.RotatedBindingTTL); err != nil {
 		return err
 	}
-	_, err = time.ParseDuration(sb.Spec.CredRotationPolicy.RotationFrequency)
+	return validatePolicyDuration("rotationFrequency", sb.Spec.CredRotationPolicy.RotationFrequency)
+}
+
+func validatePolicyDuration(field, value string) error {
+	duration, err := time.ParseDuration(value)
 	if err != nil {
-		return err
+		return fmt.Errorf("spec.credentialsRotationPolicy.%s is invalid: %s", field, err.Error())
 	}
+	if duration < 0 {
+		return fmt.Errorf("spec.credentialsRotationPolicy.%s must not be negative", field)
+	}
+	return nil
+}
 
+// validateCredRotationSchedule verifies that an enabled rotation keeps rotated bindings long enough
+// and, unless disabled in the operator configuration, does not rotate before the previous binding expired
+func (sb *ServiceBinding) validateCredRotationSchedule() error {
+	if !sb.Spec.CredRotationPolicy.Enabled {
+		return nil
+	}
+	ttl, _ := time.ParseDuration(sb.Spec.CredRotationPolicy.RotatedBindingTTL)
+	frequency, _ := time.ParseDuration(sb.Spec.CredRotationPolicy.RotationFrequency)
+
+	if ttl < minRotatedBindingTTL {
+		return fmt.Errorf("spec.credentialsRotationPolicy.rotatedBindingTTL must be at least %s", minRotatedBindingTTL)
+	}
+	if rotationFrequencyGuard && frequency <= ttl {
+		return fmt.Errorf("spec.credentialsRotationPolicy.rotationFrequency (%s) must be longer than rotatedBindingTTL (%s)", frequency, ttl)
+	}
 	return nil
 }
 
 func (sb *ServiceBinding) validateSecretTemplate() error {
 	servicebindinglog.Info("validate specified secretTemplate")
 
-	_, err := template.ParseTemplate("", sb.Spec.SecretTemplate)
+	delims := sb.Spec.SecretTemplateDelimiters.Delimiters()
+	if secretTemplateDryRun {
+		return template.DryRun("", sb.Spec.SecretTemplate, delims, "smBindingCredentials", "serviceInstanceInfos", "instanceParameters", "bindingParameters")
+	}
+	_, err := template.ParseTemplate("", sb.Spec.SecretTemplate, delims)
+	return err
+}
+
+func (sb *ServiceBinding) validateSecretFormat() error {
+	if !formatter.IsValid(sb.Spec.SecretFormat) {
+		return fmt.Errorf("spec.secretFormat '%s' is not supported, supported formats are %v", sb.Spec.SecretFormat, append(formatter.Names(), formatter.None))
+	}
+	if sb.Spec.SecretFormat == formatter.DockerConfigJSON {
+		return sb.validateDockerConfigJSONFormat()
+	}
+	return nil
+}
+
+// validateDockerConfigJSONFormat verifies that the docker config of the dockerconfigjson format is stored under its own
+// key of a secret that can be used as image pull secret
+func (sb *ServiceBinding) validateDockerConfigJSONFormat() error {
+	if len(sb.Spec.SecretTemplate) > 0 || sb.Spec.SecretKey != nil || sb.Spec.SecretRootKey != nil ||
+		(len(sb.Spec.SecretFileFormat) > 0 && sb.Spec.SecretFileFormat != SecretFileFormatKeyPerValue) ||
+		(sb.Spec.TLSSecret != nil && len(sb.Spec.TLSSecret.Name) == 0) {
+		return fmt.Errorf("spec.secretFormat '%s' stores the docker config under the .dockerconfigjson key and cannot be combined with "+
+			"spec.secretTemplate, spec.secretKey, spec.secretRootKey, spec.secretFileFormat or a spec.tlsSecret without a name", formatter.DockerConfigJSON)
+	}
+	if len(sb.Spec.SecretType) > 0 && sb.Spec.SecretType != corev1.SecretTypeDockerConfigJson {
+		return fmt.Errorf("spec.secretFormat '%s' requires spec.secretType %s", formatter.DockerConfigJSON, corev1.SecretTypeDockerConfigJson)
+	}
+	return nil
+}
+
+// validateInstanceReference verifies that the binding references either a ServiceInstance of the cluster or a shared
+// instance by its ID in SM, which has no status to read parameters from but its ID
+func (sb *ServiceBinding) validateInstanceReference() error {
+	if len(sb.Spec.ServiceInstanceName) == 0 && len(sb.Spec.ServiceInstanceID) == 0 {
+		return fmt.Errorf("either spec.serviceInstanceName or spec.serviceInstanceID must be specified")
+	}
+	if len(sb.Spec.ServiceInstanceID) == 0 {
+		return nil
+	}
+	if len(sb.Spec.ServiceInstanceName) > 0 || len(sb.Spec.ServiceInstanceNamespace) > 0 {
+		return fmt.Errorf("spec.serviceInstanceName and spec.serviceInstanceNamespace cannot be specified together with spec.serviceInstanceID")
+	}
+	for name, field := range sb.Spec.ParametersFromInstance {
+		if field != InstanceStatusFieldInstanceID {
+			return fmt.Errorf("spec.parametersFromInstance.%s: only %s can be passed from a shared instance referenced by spec.serviceInstanceID", name, InstanceStatusFieldInstanceID)
+		}
+	}
+	return nil
+}
+
+// validateSecretFileFormat verifies that a single file or the serviceBinding layout of the secret is not combined with
+// the other ways to lay out the secret
+func (sb *ServiceBinding) validateSecretFileFormat() error {
+	if len(sb.Spec.SecretFileFormat) == 0 || sb.Spec.SecretFileFormat == SecretFileFormatKeyPerValue {
+		return nil
+	}
+	if sb.Spec.SecretRootKey != nil {
+		return fmt.Errorf("spec.secretRootKey cannot be specified together with spec.secretFileFormat '%s'", sb.Spec.SecretFileFormat)
+	}
+	if len(sb.Spec.SecretTemplate) > 0 {
+		return fmt.Errorf("spec.secretTemplate cannot be specified together with spec.secretFileFormat '%s'", sb.Spec.SecretFileFormat)
+	}
+	return nil
+}
+
+// validateSecretTypeAndLabels verifies the type, labels and annotations of the secret, the labels and annotations
+// the operator sets on the secret cannot be overridden
+func (sb *ServiceBinding) validateSecretTypeAndLabels() error {
+	if len(sb.Spec.SecretType) > 0 {
+		if len(sb.Spec.SecretTemplate) > 0 {
+			return fmt.Errorf("spec.secretType cannot be combined with spec.secretTemplate, set the type in the template")
+		}
+		// service account tokens are populated and deleted by the token controller
+		if sb.Spec.SecretType == corev1.SecretTypeServiceAccountToken {
+			return fmt.Errorf("spec.secretType '%s' is not supported", sb.Spec.SecretType)
+		}
+	}
+	if errs := metav1validation.ValidateLabels(sb.Spec.SecretLabels, field.NewPath("spec", "secretLabels")); len(errs) > 0 {
+		return errs.ToAggregate()
+	}
+	if errs := apivalidation.ValidateAnnotations(sb.Spec.SecretAnnotations, field.NewPath("spec", "secretAnnotations")); len(errs) > 0 {
+		return errs.ToAggregate()
+	}
+	for path, keys := range map[string]map[string]string{"spec.secretLabels": sb.Spec.SecretLabels, "spec.secretAnnotations": sb.Spec.SecretAnnotations} {
+		for key := range keys {
+			if key == "binding" || strings.HasPrefix(key, GroupVersion.Group+"/") {
+				return fmt.Errorf("%s must not set '%s', it is set by the operator", path, key)
+			}
+		}
+	}
+	return nil
+}
+
+// validateTLSSecret verifies that a separate TLS secret does not replace the binding secret, and that a binding secret
+// written as TLS secret is not shaped by other options
+func (sb *ServiceBinding) validateTLSSecret() error {
+	tlsSecret := sb.Spec.TLSSecret
+	if tlsSecret == nil {
+		return nil
+	}
+	if len(tlsSecret.Name) > 0 {
+		if errs := validation.IsDNS1123Subdomain(tlsSecret.Name); len(errs) > 0 {
+			return fmt.Errorf("spec.tlsSecret.name '%s' is not a valid secret name: %s", tlsSecret.Name, strings.Join(errs, ", "))
+		}
+		if tlsSecret.Name == sb.Spec.SecretName {
+			return fmt.Errorf("spec.tlsSecret.name must differ from spec.secretName, omit it to write the binding secret as TLS secret")
+		}
+		return nil
+	}
+	if len(sb.Spec.SecretTemplate) > 0 || len(sb.Spec.SecretType) > 0 || sb.Spec.SecretKey != nil ||
+		sb.Spec.SecretRootKey != nil || len(sb.Spec.SecretFileFormat) > 0 {
+		return fmt.Errorf("spec.tlsSecret without a name writes the binding secret as TLS secret and cannot be combined with " +
+			"spec.secretTemplate, spec.secretType, spec.secretKey, spec.secretRootKey or spec.secretFileFormat")
+	}
+	return nil
+}
+
+// validateSecretOutputs verifies that the additional secrets have distinct valid names and select the credentials either
+// by key or by a template
+func (sb *ServiceBinding) validateSecretOutputs() error {
+	names := map[string]bool{sb.Spec.SecretName: true}
+	if sb.Spec.TLSSecret != nil && len(sb.Spec.TLSSecret.Name) > 0 {
+		names[sb.Spec.TLSSecret.Name] = true
+	}
+	for i, output := range sb.Spec.Secrets {
+		if errs := validation.IsDNS1123Subdomain(output.Name); len(errs) > 0 {
+			return fmt.Errorf("spec.secrets[%d].name '%s' is not a valid secret name: %s", i, output.Name, strings.Join(errs, ", "))
+		}
+		if names[output.Name] {
+			return fmt.Errorf("spec.secrets[%d].name '%s' must differ from spec.secretName, spec.tlsSecret.name and the names of the other secrets", i, output.Name)
+		}
+		names[output.Name] = true
+		if len(output.Template) > 0 {
+			if len(output.KeyPrefix) > 0 || len(output.IncludeKeys) > 0 || len(output.Type) > 0 {
+				return fmt.Errorf("spec.secrets[%d].template cannot be combined with keyPrefix, includeKeys or type, which the template sets", i)
+			}
+			if err := validateSecretOutputTemplate(output.Template); err != nil {
+				return errors.Wrapf(err, "spec.secrets[%d].template is invalid", i)
+			}
+			continue
+		}
+		if len(output.KeyPrefix) == 0 && len(output.IncludeKeys) == 0 {
+			return fmt.Errorf("spec.secrets[%d] must select the credentials with keyPrefix, includeKeys or a template", i)
+		}
+		for _, key := range output.IncludeKeys {
+			if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+				return fmt.Errorf("spec.secrets[%d].includeKeys '%s' is not a valid secret key: %s", i, key, strings.Join(errs, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// validateMetadataConfigMap verifies the name of the metadata config map
+func (sb *ServiceBinding) validateMetadataConfigMap() error {
+	if len(sb.Spec.MetadataConfigMap) == 0 {
+		return nil
+	}
+	if errs := validation.IsDNS1123Subdomain(sb.Spec.MetadataConfigMap); len(errs) > 0 {
+		return fmt.Errorf("spec.metadataConfigMap '%s' is not a valid config map name: %s", sb.Spec.MetadataConfigMap, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// validateCredentialsTarget verifies that exactly one target is set, the location of the credentials in Vault, and that
+// no other option stores them in the cluster
+func (sb *ServiceBinding) validateCredentialsTarget() error {
+	target := sb.Spec.CredentialsTarget
+	if target == nil {
+		return nil
+	}
+	if (target.Vault == nil) == (target.CSI == nil) {
+		return fmt.Errorf("spec.credentialsTarget requires exactly one of vault or csi")
+	}
+	if len(sb.Spec.Secrets) > 0 || sb.Spec.TLSSecret != nil || len(sb.Spec.SecretTargetNamespaces) > 0 ||
+		len(sb.Spec.ImagePullServiceAccounts) > 0 || len(sb.Spec.SecretConsumers) > 0 || sb.Spec.SecretOwnerRef != nil || sb.Spec.SecretImmutable {
+		return fmt.Errorf("spec.credentialsTarget stores the credentials outside of the cluster and cannot be combined with " +
+			"spec.secrets, spec.tlsSecret, spec.secretTargetNamespaces, spec.imagePullServiceAccounts, spec.secretConsumers, " +
+			"spec.secretOwnerRef or spec.secretImmutable")
+	}
+	if target.Vault == nil {
+		return nil
+	}
+	if len(target.Vault.Path) == 0 {
+		return fmt.Errorf("spec.credentialsTarget.vault.path is required")
+	}
+	if err := validateVaultPath("spec.credentialsTarget.vault.path", target.Vault.Path); err != nil {
+		return err
+	}
+	if err := validateVaultPath("spec.credentialsTarget.vault.mount", target.Vault.Mount); err != nil {
+		return err
+	}
+	if err := validateVaultPath("spec.credentialsTarget.vault.auth.mountPath", target.Vault.Auth.MountPath); err != nil {
+		return err
+	}
+	if len(target.Vault.Auth.Role) == 0 {
+		return fmt.Errorf("spec.credentialsTarget.vault.auth.role is required")
+	}
+	if name := target.Vault.Auth.ServiceAccountName; len(name) > 0 {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return fmt.Errorf("spec.credentialsTarget.vault.auth.serviceAccountName '%s' is not a valid service account name: %s", name, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// validateFeatureGates rejects the fields of features whose feature gate is disabled in the operator. On update only
+// the fields that changed are rejected, so updates of existing bindings are not blocked.
+func (sb *ServiceBinding) validateFeatureGates(oldBinding *ServiceBinding) error {
+	oldSpec := &ServiceBindingSpec{}
+	if oldBinding != nil {
+		oldSpec = &oldBinding.Spec
+	}
+	if len(sb.Spec.SecretFormat) > 0 && sb.Spec.SecretFormat != formatter.None && sb.Spec.SecretFormat != oldSpec.SecretFormat &&
+		!featureEnabled(config.SecretFormatters) {
+		return featureDisabledError("spec.secretFormat", config.SecretFormatters)
+	}
+	if len(sb.Spec.ImagePullServiceAccounts) > 0 && len(oldSpec.ImagePullServiceAccounts) == 0 && !featureEnabled(config.ImagePullServiceAccounts) {
+		return featureDisabledError("spec.imagePullServiceAccounts", config.ImagePullServiceAccounts)
+	}
+	target, oldTarget := sb.Spec.CredentialsTarget, oldSpec.CredentialsTarget
+	if target == nil {
+		return nil
+	}
+	if target.Vault != nil && (oldTarget == nil || oldTarget.Vault == nil) && !featureEnabled(config.VaultCredentials) {
+		return featureDisabledError("spec.credentialsTarget.vault", config.VaultCredentials)
+	}
+	if target.CSI != nil && (oldTarget == nil || oldTarget.CSI == nil) && !featureEnabled(config.CSICredentials) {
+		return featureDisabledError("spec.credentialsTarget.csi", config.CSICredentials)
+	}
+	return nil
+}
+
+func featureEnabled(feature config.Feature) bool {
+	return featureGates == nil || featureGates.Enabled(feature)
+}
+
+func featureDisabledError(field string, feature config.Feature) error {
+	return fmt.Errorf("%s requires the %s feature gate, which is disabled in the operator", field, feature)
+}
+
+// validateVaultPath verifies that a path of the Vault API does not escape its mount or secret
+func validateVaultPath(field, value string) error {
+	if len(value) == 0 {
+		return nil
+	}
+	for _, segment := range strings.Split(value, "/") {
+		if len(segment) == 0 || segment == "." || segment == ".." {
+			return fmt.Errorf("%s '%s' must consist of non-empty segments separated by '/' other than '.' and '..'", field, value)
+		}
+	}
+	return nil
+}
+
+// validateSecretOutputTemplate verifies the template of an additional secret, which is rendered with the default delimiters
+func validateSecretOutputTemplate(text string) error {
+	if secretTemplateDryRun {
+		return template.DryRun("", text, template.Delimiters{}, "smBindingCredentials", "serviceInstanceInfos", "instanceParameters", "bindingParameters")
+	}
+	_, err := template.ParseTemplate("", text, template.Delimiters{})
 	return err
 }
+
+// validateSecretKeyMapping verifies that the credentials are renamed to valid secret keys, each to its own key
+func (sb *ServiceBinding) validateSecretKeyMapping() error {
+	if len(sb.Spec.SecretKeyMapping) == 0 && len(sb.Spec.SecretExcludeKeys) == 0 {
+		return nil
+	}
+	if len(sb.Spec.SecretTemplate) > 0 {
+		return fmt.Errorf("spec.secretTemplate cannot be specified together with spec.secretKeyMapping or spec.secretExcludeKeys")
+	}
+	excluded := make(map[string]bool, len(sb.Spec.SecretExcludeKeys))
+	for _, key := range sb.Spec.SecretExcludeKeys {
+		excluded[key] = true
+	}
+	keys := make([]string, 0, len(sb.Spec.SecretKeyMapping))
+	for key := range sb.Spec.SecretKeyMapping {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	renamedFrom := make(map[string]string, len(keys))
+	for _, key := range keys {
+		target := sb.Spec.SecretKeyMapping[key]
+		if errs := validation.IsConfigMapKey(target); len(errs) > 0 {
+			return fmt.Errorf("spec.secretKeyMapping[%s] '%s' is not a valid secret key: %s", key, target, strings.Join(errs, ", "))
+		}
+		if target == ".metadata" {
+			return fmt.Errorf("spec.secretKeyMapping[%s] renames to the reserved key '.metadata' of the secret metadata", key)
+		}
+		if excluded[key] {
+			return fmt.Errorf("spec.secretKeyMapping[%s] renames a key of spec.secretExcludeKeys", key)
+		}
+		if source, ok := renamedFrom[target]; ok {
+			return fmt.Errorf("spec.secretKeyMapping[%s] and spec.secretKeyMapping[%s] are both renamed to '%s'", source, key, target)
+		}
+		renamedFrom[target] = key
+	}
+	return nil
+}
+
+// validateSecretTemplateDelimiters verifies that the delimiters are set for a secret template and differ from each other
+func (sb *ServiceBinding) validateSecretTemplateDelimiters() error {
+	delims := sb.Spec.SecretTemplateDelimiters
+	if delims == nil {
+		return nil
+	}
+	if len(sb.Spec.SecretTemplate) == 0 {
+		return fmt.Errorf("spec.secretTemplateDelimiters requires spec.secretTemplate")
+	}
+	if len(delims.Left) == 0 || len(delims.Right) == 0 {
+		return fmt.Errorf("spec.secretTemplateDelimiters.left and spec.secretTemplateDelimiters.right must not be empty")
+	}
+	if delims.Left == delims.Right {
+		return fmt.Errorf("spec.secretTemplateDelimiters.left and spec.secretTemplateDelimiters.right must differ")
+	}
+	return nil
+}
+
+// validateReadinessGates verifies that the readiness gates reference kinds the operator is permitted to read
+func (sb *ServiceBinding) validateReadinessGates() error {
+	for i, gate := range sb.Spec.ReadinessGates {
+		gv, err := schema.ParseGroupVersion(gate.APIVersion)
+		if err != nil {
+			return fmt.Errorf("spec.readinessGates[%d].apiVersion is invalid: %s", i, err.Error())
+		}
+		if !containsGroupKind(readinessGateKinds, gv.WithKind(gate.Kind).GroupKind()) {
+			return fmt.Errorf("spec.readinessGates[%d] references kind '%s' of group '%s' which is not supported, supported kinds are %v", i, gate.Kind, gv.Group, readinessGateKinds)
+		}
+	}
+	return nil
+}
+
+// validateSecretOwnerRef verifies that the secret owner is of a kind the operator is permitted to read
+func (sb *ServiceBinding) validateSecretOwnerRef() error {
+	ownerRef := sb.Spec.SecretOwnerRef
+	if ownerRef == nil {
+		return nil
+	}
+	gv, err := schema.ParseGroupVersion(ownerRef.APIVersion)
+	if err != nil {
+		return fmt.Errorf("spec.secretOwnerRef.apiVersion is invalid: %s", err.Error())
+	}
+	if !containsGroupKind(secretOwnerKinds, gv.WithKind(ownerRef.Kind).GroupKind()) {
+		return fmt.Errorf("spec.secretOwnerRef references kind '%s' of group '%s' which is not supported, supported kinds are %v", ownerRef.Kind, gv.Group, secretOwnerKinds)
+	}
+	return nil
+}
+
+// validateBindResource verifies that the bind resource identifies an application or a route
+func (sb *ServiceBinding) validateBindResource() error {
+	bindResource := sb.Spec.BindResource
+	if bindResource == nil {
+		return nil
+	}
+	if len(strings.TrimSpace(bindResource.AppGUID)) == 0 && len(bindResource.Route) == 0 {
+		return fmt.Errorf("spec.bindResource must set appGuid or route")
+	}
+	if len(bindResource.Route) > 0 {
+		if route, err := url.Parse(bindResource.Route); err != nil || len(route.Scheme) == 0 || len(route.Host) == 0 {
+			return fmt.Errorf("spec.bindResource.route '%s' must be an absolute URL", bindResource.Route)
+		}
+	}
+	return nil
+}
+
+func containsGroupKind(kinds []schema.GroupKind, kind schema.GroupKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// validateEncryption verifies that keys to encrypt are only specified together with the public key
+func (sb *ServiceBinding) validateEncryption() error {
+	if len(sb.Spec.EncryptKeys) > 0 && sb.Spec.EncryptionKeyRef == nil {
+		return fmt.Errorf("spec.encryptionKeyRef is required when spec.encryptKeys is specified")
+	}
+	if len(sb.Spec.EncryptKeys) == 0 && sb.Spec.EncryptionKeyRef != nil {
+		return fmt.Errorf("spec.encryptKeys is required when spec.encryptionKeyRef is specified")
+	}
+	return nil
+}
+
+// validateSecretConsumers verifies that the consumers of the secret and the service accounts pulling images with it
+// are names of service accounts, and that the secret is replicated to other namespaces only
+func (sb *ServiceBinding) validateSecretConsumers() error {
+	for i, serviceAccount := range sb.Spec.SecretConsumers {
+		if errs := validation.IsDNS1123Subdomain(serviceAccount); len(errs) > 0 {
+			return fmt.Errorf("spec.secretConsumers[%d] '%s' is not a valid service account name: %s", i, serviceAccount, strings.Join(errs, ", "))
+		}
+	}
+	for i, serviceAccount := range sb.Spec.ImagePullServiceAccounts {
+		if errs := validation.IsDNS1123Subdomain(serviceAccount); len(errs) > 0 {
+			return fmt.Errorf("spec.imagePullServiceAccounts[%d] '%s' is not a valid service account name: %s", i, serviceAccount, strings.Join(errs, ", "))
+		}
+	}
+	for i, namespace := range sb.Spec.SecretTargetNamespaces {
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return fmt.Errorf("spec.secretTargetNamespaces[%d] '%s' is not a valid namespace name: %s", i, namespace, strings.Join(errs, ", "))
+		}
+		if namespace == sb.Namespace {
+			return fmt.Errorf("spec.secretTargetNamespaces[%d] '%s' is the namespace of the binding", i, namespace)
+		}
+	}
+	return nil
+}
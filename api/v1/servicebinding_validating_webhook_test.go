@@ -2,9 +2,11 @@ package v1
 
 import (
 	"github.com/SAP/sap-btp-service-operator/api"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
 	"github.com/lithammer/dedent"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -43,6 +45,466 @@ var _ = Describe("Service Binding Webhook Test", func() {
 
 				Expect(err).Should(MatchError(ContainSubstring("spec.secretTemplate is invalid")))
 			})
+
+			It("should parse secretTemplate with custom delimiters", func() {
+				binding.Spec.SecretTemplate = dedent.Dedent(`
+				                                       apiVersion: v1
+				                                       kind: Secret
+				                                       stringData:
+				                                         secretKey: [[ .secretValue | quote ]]`)
+				binding.Spec.SecretTemplateDelimiters = &SecretTemplateDelimiters{Left: "[[", Right: "]]"}
+				_, err := binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+
+				binding.Spec.SecretTemplate = "secretKey: [[ .secretValue | quote"
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretTemplate is invalid")))
+			})
+
+			It("should fail if custom delimiters are invalid", func() {
+				binding.Spec.SecretTemplateDelimiters = &SecretTemplateDelimiters{Left: "%%", Right: "%%"}
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretTemplateDelimiters.left and spec.secretTemplateDelimiters.right must differ")))
+
+				binding.Spec.SecretTemplate = ""
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretTemplateDelimiters requires spec.secretTemplate")))
+			})
+
+			It("should succeed if secretFormat is supported", func() {
+				binding.Spec.SecretFormat = "xsuaa"
+				_, err := binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should fail if secretFormat is not supported", func() {
+				binding.Spec.SecretFormat = "unknown"
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretFormat 'unknown' is not supported")))
+			})
+
+			It("should fail if rotated binding TTL is too short", func() {
+				binding.Spec.CredRotationPolicy.RotatedBindingTTL = "0s"
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("rotatedBindingTTL must be at least")))
+			})
+
+			It("should allow a rotation frequency that is not longer than rotated binding TTL by default", func() {
+				binding.Spec.CredRotationPolicy.RotationFrequency = "1s"
+				binding.Spec.CredRotationPolicy.RotatedBindingTTL = "1h"
+				_, err := binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			When("secret template dry run is enabled", func() {
+				BeforeEach(func() {
+					SetSecretTemplateDryRun(true)
+				})
+				AfterEach(func() {
+					SetSecretTemplateDryRun(false)
+				})
+
+				It("should succeed if the template generates a valid secret", func() {
+					binding.Spec.SecretTemplate = dedent.Dedent(`
+					                                       apiVersion: v1
+					                                       kind: Secret
+					                                       stringData:
+					                                         url: {{ .smBindingCredentials.uaa.url }}
+					                                         plan: {{ .serviceInstanceInfos.plan }}
+					                                         region: {{ .instanceParameters.region }}
+					                                         role: {{ .bindingParameters.role }}`)
+					_, err := binding.ValidateCreate()
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("should fail if the template does not generate a valid secret", func() {
+					binding.Spec.SecretTemplate = dedent.Dedent(`
+					                                       apiVersion: v1
+					                                       kind: Secret
+					                                       metadata:
+					                                         namespace: {{ .serviceInstanceInfos.instance_name }}`)
+					_, err := binding.ValidateCreate()
+					Expect(err).Should(MatchError(ContainSubstring("metadata field namespace is not allowed")))
+				})
+			})
+
+			When("rotation frequency guard is enabled", func() {
+				BeforeEach(func() {
+					SetRotationFrequencyGuard(true)
+				})
+				AfterEach(func() {
+					SetRotationFrequencyGuard(false)
+				})
+
+				It("should fail if rotation frequency is not longer than rotated binding TTL", func() {
+					_, err := binding.ValidateCreate()
+					Expect(err).Should(MatchError(ContainSubstring("must be longer than rotatedBindingTTL")))
+				})
+			})
+
+			It("should fail if rotated binding TTL cannot be parsed", func() {
+				binding.Spec.CredRotationPolicy.RotatedBindingTTL = "2d"
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.credentialsRotationPolicy.rotatedBindingTTL is invalid")))
+			})
+
+			It("should fail if rotated binding TTL is negative", func() {
+				binding.Spec.CredRotationPolicy = &CredentialsRotationPolicy{
+					Enabled:           false,
+					RotatedBindingTTL: "-1h",
+					RotationFrequency: "0ns",
+				}
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.credentialsRotationPolicy.rotatedBindingTTL must not be negative")))
+			})
+
+			It("should not validate the schedule when rotation is disabled", func() {
+				binding.Spec.CredRotationPolicy = &CredentialsRotationPolicy{
+					Enabled:           false,
+					RotatedBindingTTL: "0ns",
+					RotationFrequency: "0ns",
+				}
+				_, err := binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should succeed if readiness gates reference supported kinds", func() {
+				binding.Spec.ReadinessGates = []ReadinessGate{
+					{APIVersion: "batch/v1", Kind: "Job", Name: "migration", ConditionType: "Complete"},
+					{APIVersion: "services.cloud.sap.com/v1", Kind: "ServiceInstance", Name: "db", ConditionType: "Ready"},
+				}
+				_, err := binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should fail if a readiness gate references an unsupported kind", func() {
+				binding.Spec.ReadinessGates = []ReadinessGate{{APIVersion: "v1", Kind: "Secret", Name: "credentials", ConditionType: "Ready"}}
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.readinessGates[0] references kind 'Secret'")))
+			})
+
+			It("should succeed if the secret owner is of a supported kind", func() {
+				binding.Spec.SecretOwnerRef = &SecretOwnerReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "my-app"}
+				_, err := binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should fail if the secret owner is of an unsupported kind", func() {
+				binding.Spec.SecretOwnerRef = &SecretOwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "my-config"}
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretOwnerRef references kind 'ConfigMap'")))
+			})
+
+			It("should succeed with the type, labels and annotations of the secret", func() {
+				binding.Spec.SecretTemplate = ""
+				binding.Spec.SecretType = corev1.SecretTypeBasicAuth
+				binding.Spec.SecretLabels = map[string]string{"app.kubernetes.io/part-of": "my-app"}
+				binding.Spec.SecretAnnotations = map[string]string{"reloader.stakater.com/match": "true"}
+				_, err := binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should fail if the secret type is combined with a secret template or not supported", func() {
+				binding.Spec.SecretTemplate = ""
+				binding.Spec.SecretType = corev1.SecretTypeServiceAccountToken
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretType 'kubernetes.io/service-account-token' is not supported")))
+				binding.Spec.SecretType = corev1.SecretTypeTLS
+				binding.Spec.SecretTemplate = "apiVersion: v1\nkind: Secret\n"
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretType cannot be combined with spec.secretTemplate")))
+			})
+
+			It("should succeed with a TLS secret alongside or instead of the binding secret", func() {
+				binding.Spec.TLSSecret = &TLSSecret{Name: "my-tls-secret"}
+				_, err := binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+				binding.Spec.SecretTemplate = ""
+				binding.Spec.TLSSecret = &TLSSecret{CertificateKey: "cert"}
+				_, err = binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should fail if the TLS secret replaces the binding secret or the binding secret is shaped otherwise", func() {
+				binding.Spec.SecretName = "my-secret"
+				binding.Spec.TLSSecret = &TLSSecret{Name: "my-secret"}
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.tlsSecret.name must differ from spec.secretName")))
+				binding.Spec.TLSSecret = &TLSSecret{}
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.tlsSecret without a name writes the binding secret as TLS secret")))
+			})
+
+			It("should succeed with additional secrets selecting the credentials by key or template", func() {
+				binding.Spec.TLSSecret = &TLSSecret{Name: "my-tls-secret"}
+				binding.Spec.Secrets = []SecretOutput{
+					{Name: "app-config", KeyPrefix: "config_", IncludeKeys: []string{"url"}},
+					{Name: "db", Template: "apiVersion: v1\nkind: Secret\nstringData:\n  user: {{ .smBindingCredentials.user }}\n"},
+				}
+				_, err := binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should fail if additional secrets clash with other secrets or select no credentials", func() {
+				binding.Spec.SecretName = "my-secret"
+				binding.Spec.Secrets = []SecretOutput{{Name: "my-secret", KeyPrefix: "config_"}}
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secrets[0].name 'my-secret' must differ from spec.secretName")))
+				binding.Spec.Secrets = []SecretOutput{{Name: "app-config", KeyPrefix: "config_"}, {Name: "app-config", IncludeKeys: []string{"url"}}}
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secrets[1].name 'app-config' must differ")))
+				binding.Spec.Secrets = []SecretOutput{{Name: "app-config"}}
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secrets[0] must select the credentials")))
+				binding.Spec.Secrets = []SecretOutput{{Name: "app-config", KeyPrefix: "config_", Template: "apiVersion: v1\nkind: Secret\n"}}
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secrets[0].template cannot be combined with keyPrefix")))
+				binding.Spec.Secrets = []SecretOutput{{Name: "app-config", Template: "{{ .smBindingCredentials.user"}}
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secrets[0].template is invalid")))
+			})
+
+			It("should fail for an invalid metadata config map name", func() {
+				binding.Spec.MetadataConfigMap = "my-binding-metadata"
+				_, err := binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+				binding.Spec.MetadataConfigMap = "My_Metadata"
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.metadataConfigMap 'My_Metadata' is not a valid config map name")))
+			})
+
+			It("should fail for a credentials target escaping its path or combined with secrets in the cluster", func() {
+				binding.Spec.CredentialsTarget = &CredentialsTarget{Vault: &VaultCredentialsTarget{Path: "apps/my-binding",
+					Auth: VaultKubernetesAuth{Role: "my-role", ServiceAccountName: "my-app"}}}
+				_, err := binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+				binding.Spec.CredentialsTarget.Vault.Path = "apps/../../sys/policy"
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.credentialsTarget.vault.path 'apps/../../sys/policy' must consist of non-empty segments")))
+				binding.Spec.CredentialsTarget.Vault.Path = "apps/my-binding"
+				binding.Spec.CredentialsTarget.Vault.Mount = "/kv"
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.credentialsTarget.vault.mount '/kv'")))
+				binding.Spec.CredentialsTarget.Vault.Mount = ""
+				binding.Spec.TLSSecret = &TLSSecret{Name: "my-tls"}
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.credentialsTarget stores the credentials outside of the cluster")))
+			})
+
+			It("should fail for a credentials target without or with both targets", func() {
+				binding.Spec.CredentialsTarget = &CredentialsTarget{CSI: &CSICredentialsTarget{}}
+				_, err := binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+				binding.Spec.CredentialsTarget.Vault = &VaultCredentialsTarget{Path: "apps/my-binding", Auth: VaultKubernetesAuth{Role: "my-role"}}
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.credentialsTarget requires exactly one of vault or csi")))
+				binding.Spec.CredentialsTarget = &CredentialsTarget{}
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.credentialsTarget requires exactly one of vault or csi")))
+			})
+
+			When("feature gates are disabled in the operator", func() {
+				BeforeEach(func() {
+					gates := config.FeatureGates{}
+					Expect(gates.Decode("CSICredentials=true")).To(Succeed())
+					SetFeatureGates(gates)
+				})
+				AfterEach(func() {
+					featureGates = nil
+				})
+
+				It("should fail for the fields of the disabled features", func() {
+					binding.Spec.SecretFormat = "dockerconfigjson"
+					_, err := binding.ValidateCreate()
+					Expect(err).Should(MatchError("spec.secretFormat requires the SecretFormatters feature gate, which is disabled in the operator"))
+					binding.Spec.SecretFormat = "none"
+					_, err = binding.ValidateCreate()
+					Expect(err).ToNot(HaveOccurred())
+					binding.Spec.ImagePullServiceAccounts = []string{"default"}
+					_, err = binding.ValidateCreate()
+					Expect(err).Should(MatchError("spec.imagePullServiceAccounts requires the ImagePullServiceAccounts feature gate, which is disabled in the operator"))
+					binding.Spec.ImagePullServiceAccounts = nil
+					binding.Spec.CredentialsTarget = &CredentialsTarget{Vault: &VaultCredentialsTarget{Path: "apps/my-binding", Auth: VaultKubernetesAuth{Role: "my-role"}}}
+					_, err = binding.ValidateCreate()
+					Expect(err).Should(MatchError(ContainSubstring("spec.credentialsTarget.vault requires the VaultCredentials feature gate")))
+					binding.Spec.CredentialsTarget = &CredentialsTarget{CSI: &CSICredentialsTarget{}}
+					_, err = binding.ValidateCreate()
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("should not block updates of bindings created before the feature was disabled", func() {
+					binding.Spec.SecretFormat = "hana"
+					newBinding := binding.DeepCopy()
+					newBinding.Spec.SecretLabels = map[string]string{"app": "my-app"}
+					_, err := newBinding.ValidateUpdate(binding)
+					Expect(err).ToNot(HaveOccurred())
+					newBinding.Spec.SecretFormat = "xsuaa"
+					_, err = newBinding.ValidateUpdate(binding)
+					Expect(err).Should(MatchError(ContainSubstring("spec.secretFormat requires the SecretFormatters feature gate")))
+				})
+			})
+
+			It("should fail for invalid or reserved labels and annotations", func() {
+				binding.Spec.SecretLabels = map[string]string{"app": "not a label value"}
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretLabels")))
+				binding.Spec.SecretLabels = nil
+				binding.Spec.SecretAnnotations = map[string]string{"services.cloud.sap.com/bindingUID": "uid"}
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretAnnotations must not set 'services.cloud.sap.com/bindingUID'")))
+			})
+
+			It("should succeed if the bind resource identifies an application or a route", func() {
+				binding.Spec.BindResource = &BindResource{AppGUID: "app-guid"}
+				_, err := binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+				binding.Spec.BindResource = &BindResource{Route: "https://my-app.example.com"}
+				_, err = binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should fail if the bind resource is empty or its route is not a URL", func() {
+				binding.Spec.BindResource = &BindResource{}
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.bindResource must set appGuid or route")))
+				binding.Spec.BindResource = &BindResource{Route: "my-app"}
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("must be an absolute URL")))
+			})
+
+			It("should succeed if keys to encrypt are specified with a public key", func() {
+				binding.Spec.EncryptKeys = []string{"password"}
+				binding.Spec.EncryptionKeyRef = &EncryptionKeyReference{Kind: "ConfigMap", Name: "app-public-key", Key: "public.pem"}
+				_, err := binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should fail if keys to encrypt are specified without a public key", func() {
+				binding.Spec.EncryptKeys = []string{"password"}
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.encryptionKeyRef is required")))
+			})
+
+			It("should fail if a public key is specified without keys to encrypt", func() {
+				binding.Spec.EncryptionKeyRef = &EncryptionKeyReference{Kind: "Secret", Name: "app-public-key", Key: "public.pem"}
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.encryptKeys is required")))
+			})
+
+			It("should fail if a secret consumer is not a service account name", func() {
+				binding.Spec.SecretConsumers = []string{"my-app", "My_App"}
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretConsumers[1] 'My_App' is not a valid service account name")))
+			})
+
+			It("should succeed with the dockerconfigjson format of the credentials of registries", func() {
+				binding.Spec.SecretTemplate = ""
+				binding.Spec.SecretFormat = "dockerconfigjson"
+				_, err := binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+				binding.Spec.SecretType = corev1.SecretTypeDockerConfigJson
+				_, err = binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should fail if the dockerconfigjson format is combined with another layout or type of the secret", func() {
+				binding.Spec.SecretFormat = "dockerconfigjson"
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("cannot be combined with spec.secretTemplate")))
+				binding.Spec.SecretTemplate = ""
+				binding.Spec.SecretType = corev1.SecretTypeOpaque
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretFormat 'dockerconfigjson' requires spec.secretType kubernetes.io/dockerconfigjson")))
+			})
+
+			It("should fail if an image pull service account is not a service account name", func() {
+				binding.Spec.ImagePullServiceAccounts = []string{"My_Builder"}
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.imagePullServiceAccounts[0] 'My_Builder' is not a valid service account name")))
+			})
+
+			It("should fail if a target namespace of the secret is not a namespace name or the namespace of the binding", func() {
+				binding.Spec.SecretTargetNamespaces = []string{"team.a"}
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretTargetNamespaces[0] 'team.a' is not a valid namespace name")))
+				binding.Spec.SecretTargetNamespaces = []string{"team-a", binding.Namespace}
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretTargetNamespaces[1] '%s' is the namespace of the binding", binding.Namespace)))
+			})
+			It("should succeed if the credentials are stored as a single file without a secret template", func() {
+				binding.Spec.SecretFileFormat = SecretFileFormatDotenv
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretTemplate cannot be specified together with spec.secretFileFormat 'dotenv'")))
+
+				binding.Spec.SecretTemplate = ""
+				_, err = binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+			})
+			It("should fail if a single file is combined with a root key", func() {
+				binding.Spec.SecretFileFormat = SecretFileFormatYAML
+				binding.Spec.SecretRootKey = &[]string{"root"}[0]
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretRootKey cannot be specified together with spec.secretFileFormat 'yaml'")))
+			})
+			It("should fail if the serviceBinding layout is combined with a secret template", func() {
+				binding.Spec.SecretFileFormat = SecretFileFormatServiceBinding
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretTemplate cannot be specified together with spec.secretFileFormat 'serviceBinding'")))
+			})
+			It("should succeed if credentials are renamed and excluded without a secret template", func() {
+				binding.Spec.SecretKeyMapping = map[string]string{"uri": "DATABASE_URL"}
+				binding.Spec.SecretExcludeKeys = []string{"certificate"}
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretTemplate cannot be specified together with spec.secretKeyMapping")))
+
+				binding.Spec.SecretTemplate = ""
+				_, err = binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+			})
+			It("should fail if credentials are renamed to invalid or duplicate keys", func() {
+				binding.Spec.SecretTemplate = ""
+				binding.Spec.SecretKeyMapping = map[string]string{"uri": "database url"}
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretKeyMapping[uri] 'database url' is not a valid secret key")))
+
+				binding.Spec.SecretKeyMapping = map[string]string{"uri": "DATABASE_URL", "url": "DATABASE_URL"}
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretKeyMapping[uri] and spec.secretKeyMapping[url] are both renamed to 'DATABASE_URL'")))
+
+				binding.Spec.SecretKeyMapping = map[string]string{"uri": "DATABASE_URL"}
+				binding.Spec.SecretExcludeKeys = []string{"uri"}
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretKeyMapping[uri] renames a key of spec.secretExcludeKeys")))
+
+				binding.Spec.SecretExcludeKeys = nil
+				binding.Spec.SecretKeyMapping = map[string]string{"metadata": ".metadata"}
+				_, err = binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.secretKeyMapping[metadata] renames to the reserved key '.metadata'")))
+			})
+			It("should succeed if a shared instance is referenced by its ID", func() {
+				binding.Spec.ServiceInstanceName = ""
+				binding.Spec.ServiceInstanceID = "shared-instance-id"
+				binding.Spec.ParametersFromInstance = map[string]InstanceStatusField{"instance_guid": InstanceStatusFieldInstanceID}
+				_, err := binding.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+			})
+			It("should fail if no instance is referenced", func() {
+				binding.Spec.ServiceInstanceName = ""
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("either spec.serviceInstanceName or spec.serviceInstanceID must be specified")))
+			})
+			It("should fail if a shared instance is referenced together with a ServiceInstance", func() {
+				binding.Spec.ServiceInstanceID = "shared-instance-id"
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("cannot be specified together with spec.serviceInstanceID")))
+			})
+			It("should fail if status fields other than the ID are passed from a shared instance", func() {
+				binding.Spec.ServiceInstanceName = ""
+				binding.Spec.ServiceInstanceID = "shared-instance-id"
+				binding.Spec.ParametersFromInstance = map[string]InstanceStatusField{"subaccount": InstanceStatusFieldSubaccountID}
+				_, err := binding.ValidateCreate()
+				Expect(err).Should(MatchError(ContainSubstring("spec.parametersFromInstance.subaccount: only instanceID can be passed")))
+			})
 		})
 
 		Context("Validate update of spec before binding is created (failure recovery)", func() {
@@ -68,6 +530,22 @@ var _ = Describe("Service Binding Webhook Test", func() {
 					})
 				})
 
+				When("Secret template changed to an invalid template", func() {
+					It("should fail", func() {
+						newBinding.Spec.SecretTemplate = "stringData: {{ .smBindingCredentials"
+						_, err := newBinding.ValidateUpdate(binding)
+						Expect(err).Should(MatchError(ContainSubstring("spec.secretTemplate is invalid")))
+					})
+				})
+
+				When("Secret format changed to an unsupported format", func() {
+					It("should fail", func() {
+						newBinding.Spec.SecretFormat = "unknown"
+						_, err := newBinding.ValidateUpdate(binding)
+						Expect(err).Should(MatchError(ContainSubstring("spec.secretFormat 'unknown' is not supported")))
+					})
+				})
+
 				When("Parameters were changed", func() {
 					It("should succeed", func() {
 						newBinding.Spec.Parameters = &runtime.RawExtension{
@@ -119,6 +597,18 @@ var _ = Describe("Service Binding Webhook Test", func() {
 					Expect(err).ToNot(HaveOccurred())
 				})
 
+				It("should fail when rotation frequency is not longer than rotated binding TTL and the guard is enabled", func() {
+					SetRotationFrequencyGuard(true)
+					defer SetRotationFrequencyGuard(false)
+					newBinding.Spec.CredRotationPolicy = &CredentialsRotationPolicy{
+						Enabled:           true,
+						RotatedBindingTTL: "2h",
+						RotationFrequency: "1h",
+					}
+					_, err := newBinding.ValidateUpdate(binding)
+					Expect(err).Should(MatchError(ContainSubstring("must be longer than rotatedBindingTTL")))
+				})
+
 				It("should fail when duration format not valid", func() {
 					newBinding.Spec.CredRotationPolicy = &CredentialsRotationPolicy{
 						Enabled:           true,
@@ -179,6 +669,38 @@ var _ = Describe("Service Binding Webhook Test", func() {
 					})
 				})
 
+				When("deletion policy changed", func() {
+					It("should succeed", func() {
+						newBinding.Spec.DeletionPolicy = DeletionPolicyOrphan
+						_, err := newBinding.ValidateUpdate(binding)
+						Expect(err).ToNot(HaveOccurred())
+					})
+				})
+
+				When("secret consumers changed", func() {
+					It("should succeed", func() {
+						newBinding.Spec.SecretConsumers = []string{"my-app"}
+						_, err := newBinding.ValidateUpdate(binding)
+						Expect(err).ToNot(HaveOccurred())
+					})
+				})
+
+				When("image pull service accounts changed", func() {
+					It("should succeed", func() {
+						newBinding.Spec.ImagePullServiceAccounts = []string{"builder"}
+						_, err := newBinding.ValidateUpdate(binding)
+						Expect(err).ToNot(HaveOccurred())
+					})
+				})
+
+				When("secret target namespaces changed", func() {
+					It("should succeed", func() {
+						newBinding.Spec.SecretTargetNamespaces = []string{"team-a"}
+						_, err := newBinding.ValidateUpdate(binding)
+						Expect(err).ToNot(HaveOccurred())
+					})
+				})
+
 				When("SecretKey name changed", func() {
 					It("should fail", func() {
 						secretKey := "secret-key"
@@ -255,6 +777,18 @@ var _ = Describe("Service Binding Webhook Test", func() {
 					Expect(err).ToNot(HaveOccurred())
 				})
 
+				It("should fail when rotation frequency is not longer than rotated binding TTL and the guard is enabled", func() {
+					SetRotationFrequencyGuard(true)
+					defer SetRotationFrequencyGuard(false)
+					newBinding.Spec.CredRotationPolicy = &CredentialsRotationPolicy{
+						Enabled:           true,
+						RotatedBindingTTL: "2h",
+						RotationFrequency: "1h",
+					}
+					_, err := newBinding.ValidateUpdate(binding)
+					Expect(err).Should(MatchError(ContainSubstring("must be longer than rotatedBindingTTL")))
+				})
+
 				It("should fail when duration format not valid", func() {
 					newBinding.Spec.CredRotationPolicy = &CredentialsRotationPolicy{
 						Enabled:           true,
@@ -24,6 +24,19 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// EnforcementMode defines how drift of the instance in Service Manager from the desired state is handled
+// +kubebuilder:validation:Enum=Enforce;Warn;Ignore
+type EnforcementMode string
+
+const (
+	// EnforcementModeEnforce reverts drifted labels and parameters of the instance in Service Manager
+	EnforcementModeEnforce EnforcementMode = "Enforce"
+	// EnforcementModeWarn reports drift of the instance with a condition and an event
+	EnforcementModeWarn EnforcementMode = "Warn"
+	// EnforcementModeIgnore does not check the instance for drift
+	EnforcementModeIgnore EnforcementMode = "Ignore"
+)
+
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
@@ -51,19 +64,35 @@ type ServiceInstanceSpec struct {
 	// The name of the instance in Service Manager
 	ExternalName string `json:"externalName,omitempty"`
 
+	// InstanceID is the ID of an existing instance in Service Manager, e.g. one created in the SAP BTP cockpit, which is
+	// adopted instead of provisioning a new instance. The instance must have the externalName as its name and must not
+	// belong to another cluster or namespace. Requires the Adoption feature gate, it cannot be changed once the
+	// instance is created.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`
+	InstanceID string `json:"instanceID,omitempty"`
+
 	// Indicates the desired shared state
 	// +optional
 	// +kubebuilder:default={}
 	Shared *bool `json:"shared,omitempty"`
 
+	// AllowedConsumers are the namespaces whose bindings may consume the shared instance by its ID, bindings of other
+	// namespaces are not permitted. If not specified, the bindings of all namespaces may consume it. Applies to
+	// shared instances only.
+	// +optional
+	AllowedConsumers []string `json:"allowedConsumers,omitempty"`
+
 	// Provisioning parameters for the instance.
 	//
 	// The Parameters field is NOT secret or secured in any way and should
 	// NEVER be used to hold sensitive information. To set parameters that
 	// contain secret information, you should ALWAYS store that information
 	// in a Secret and use the ParametersFrom field.
+	// With the jsonpatch ParametersMergeStrategy, Parameters is a JSON patch, an array of operations.
 	// +optional
 	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Type=""
 	Parameters *runtime.RawExtension `json:"parameters,omitempty"`
 
 	// List of sources to populate parameters.
@@ -73,6 +102,14 @@ type ServiceInstanceSpec struct {
 	// +optional
 	ParametersFrom []ParametersFromSource `json:"parametersFrom,omitempty"`
 
+	// ParametersMergeStrategy defines how the sources of ParametersFrom and Parameters are merged. With shallow, the
+	// default, a top-level parameter set by more than one source is a user error in the specification. With deep,
+	// nested objects are merged recursively and other values are overridden by later sources, the sources of
+	// ParametersFrom in their order followed by Parameters. With jsonpatch, Parameters is a JSON patch (RFC 6902)
+	// applied to the merged sources of ParametersFrom.
+	// +optional
+	ParametersMergeStrategy ParametersMergeStrategy `json:"parametersMergeStrategy,omitempty"`
+
 	// List of custom tags describing the ServiceInstance, will be copied to `ServiceBinding` secret in the key called `tags`.
 	// +optional
 	CustomTags []string `json:"customTags,omitempty"`
@@ -85,8 +122,74 @@ type ServiceInstanceSpec struct {
 
 	// The name of the btp access credentials secret
 	BTPAccessCredentialsSecret string `json:"btpAccessCredentialsSecret,omitempty"`
+
+	// Landscape selects the access credentials of the instance when credentials for several landscapes or subaccounts
+	// are configured, they are read from the secret sap-btp-service-operator-<landscape> in the management namespace.
+	// Cannot be combined with btpAccessCredentialsSecret.
+	// +optional
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Landscape string `json:"landscape,omitempty"`
+
+	// AcceptMaintenanceUpdate indicates whether maintenance updates offered by the broker
+	// for the plan of the instance should be applied, see status.upgradeAvailable
+	// +optional
+	AcceptMaintenanceUpdate bool `json:"acceptMaintenanceUpdate,omitempty"`
+
+	// EnforcementMode defines how changes made to the labels and parameters of the instance directly in Service Manager are handled.
+	// Enforce reverts them, Warn only reports them with the Drifted condition and an event, Ignore (default) does not check for them.
+	// +optional
+	// +kubebuilder:default=Ignore
+	EnforcementMode EnforcementMode `json:"enforcementMode,omitempty"`
+
+	// ExpiresAt is the time at which the operator deletes the instance and its bindings, e.g. for instances of dev environments.
+	// Warning events are recorded ahead of the expiration. The time must be in the future when it is set,
+	// instances marked with preventDeletion do not expire.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// MaintenanceWindow restricts the updates of the instance in Service Manager issued by the operator on its own,
+	// i.e. accepted maintenance updates and the reverting of drift, to a recurring window. Outside the window they are
+	// deferred, see the Deferred condition and status.nextMaintenanceWindow. Updates of the spec are applied right away.
+	// +optional
+	MaintenanceWindow *MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+
+	// ClusterIDOverride is the ID of the cluster that created the instance in Service Manager, for instances taken over
+	// from another cluster. The instance is labeled with it and recovered by it instead of the ID of this cluster.
+	// Requires the ClusterIDOverride feature gate, it cannot be changed once the instance is created.
+	// +optional
+	ClusterIDOverride string `json:"clusterIDOverride,omitempty"`
+
+	// DeletionPolicy defines whether the instance is deprovisioned when it is deleted from the cluster. Delete (default)
+	// deprovisions it, Orphan keeps it in Service Manager, e.g. to manage it from another cluster.
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
 }
 
+// MaintenanceWindow is a recurring period of time
+type MaintenanceWindow struct {
+	// Days of the week on which the window starts, e.g. [Saturday, Sunday], every day if empty
+	// +optional
+	Days []Weekday `json:"days,omitempty"`
+
+	// Start is the time of day at which the window starts, in the format HH:MM
+	// +required
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+
+	// Duration of the window, e.g. 4h, at most a week
+	// +required
+	Duration string `json:"duration"`
+
+	// TimeZone is the IANA name of the time zone of the start time, e.g. Europe/Berlin, UTC if empty
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// Weekday is a day of the week
+// +kubebuilder:validation:Enum=Monday;Tuesday;Wednesday;Thursday;Friday;Saturday;Sunday
+type Weekday string
+
 // ServiceInstanceStatus defines the observed state of ServiceInstance
 type ServiceInstanceStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -114,11 +217,67 @@ type ServiceInstanceStatus struct {
 	// Indicates whether instance is ready for usage
 	Ready metav1.ConditionStatus `json:"ready,omitempty"`
 
+	// The phase of the instance derived from its conditions, one of Pending, Provisioning, Ready, Failed and Deleting,
+	// for tools that interpret simple phases only
+	Phase Phase `json:"phase,omitempty"`
+
 	// HashedSpec is the hashed spec without the shared property
 	HashedSpec string `json:"hashedSpec,omitempty"`
 
+	// HashedParametersFrom is the hash of the parameters read from the secrets referenced by parametersFrom when they
+	// were last sent to SM, the instance is updated when the secrets change
+	HashedParametersFrom string `json:"hashedParametersFrom,omitempty"`
+
+	// The allowed consumers of the shared instance as labeled in Service Manager
+	AllowedConsumers []string `json:"allowedConsumers,omitempty"`
+
 	// The subaccount id of the service instance
 	SubaccountID string `json:"subaccountID,omitempty"`
+
+	// The ID of the plan of the instance in Service Manager, recorded once the instance is provisioned
+	ServicePlanID string `json:"servicePlanID,omitempty"`
+
+	// HashedPlan is the hash of the offering and plan of the spec the servicePlanID was resolved from, the plan is
+	// resolved again once they change
+	HashedPlan string `json:"hashedPlan,omitempty"`
+
+	// The ID of the offering of the plan in Service Manager
+	ServiceOfferingID string `json:"serviceOfferingID,omitempty"`
+
+	// The ID of the broker of the offering in Service Manager
+	BrokerID string `json:"brokerID,omitempty"`
+
+	// Indicates whether the broker offers a maintenance update for the instance
+	UpgradeAvailable bool `json:"upgradeAvailable,omitempty"`
+
+	// The maintenance info version offered by the broker when an upgrade is available
+	AvailableMaintenanceVersion string `json:"availableMaintenanceVersion,omitempty"`
+
+	// Indicates when the maintenance info of the instance was last checked
+	LastMaintenanceCheck *metav1.Time `json:"lastMaintenanceCheck,omitempty"`
+
+	// Indicates when the instance was last checked for drift in Service Manager
+	LastDriftCheck *metav1.Time `json:"lastDriftCheck,omitempty"`
+
+	// Indicates when the warning about the upcoming expiration of the instance was last recorded
+	LastExpirationWarning *metav1.Time `json:"lastExpirationWarning,omitempty"`
+
+	// Indicates when the next maintenance window of the instance starts, while an update is deferred to it
+	NextMaintenanceWindow *metav1.Time `json:"nextMaintenanceWindow,omitempty"`
+
+	// Indicates when the operator last reconciled the instance, updated at most once per reconcile telemetry interval
+	// unless the requeue reason changes
+	// +optional
+	LastReconcileTime *metav1.Time `json:"lastReconcileTime,omitempty"`
+
+	// Indicates when the operator last called Service Manager for the instance
+	// +optional
+	LastSMRequestTime *metav1.Time `json:"lastSMRequestTime,omitempty"`
+
+	// Why the operator requeued the instance after its last reconcile, the error of a failed reconcile or the reason of
+	// the ongoing operation, empty if it was not requeued
+	// +optional
+	LastRequeueReason string `json:"lastRequeueReason,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -134,7 +293,8 @@ type ServiceInstanceStatus struct {
 // +kubebuilder:printcolumn:JSONPath=".status.instanceID",name="ID",type=string,priority=1
 // +kubebuilder:printcolumn:JSONPath=".status.conditions[0].message",name="Message",type=string,priority=1
 
-// ServiceInstance is the Schema for the serviceinstances API
+// ServiceInstance is the Schema for the serviceinstances API.
+// It provisions an instance of a service offering and plan in Service Manager.
 type ServiceInstance struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
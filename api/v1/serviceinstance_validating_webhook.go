@@ -19,14 +19,17 @@ package v1
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	"github.com/SAP/sap-btp-service-operator/api"
+	"github.com/SAP/sap-btp-service-operator/internal/secrets"
 )
 
 func (si *ServiceInstance) SetupWebhookWithManager(mgr ctrl.Manager) error {
@@ -43,7 +46,19 @@ var _ webhook.Validator = &ServiceInstance{}
 var serviceinstancelog = logf.Log.WithName("serviceinstance-resource")
 
 func (si *ServiceInstance) ValidateCreate() (warnings admission.Warnings, err error) {
-	return nil, nil
+	if err := si.validateMaintenanceWindow(); err != nil {
+		return nil, err
+	}
+	if err := si.validateExpiration(); err != nil {
+		return nil, err
+	}
+	if err := validateParameters(si.Spec.ParametersMergeStrategy, si.Spec.Parameters, true); err != nil {
+		return nil, err
+	}
+	if err := validateParametersFrom(si.Spec.ParametersFrom); err != nil {
+		return nil, err
+	}
+	return nil, si.validateLandscape()
 }
 
 func (si *ServiceInstance) ValidateUpdate(old runtime.Object) (warnings admission.Warnings, err error) {
@@ -53,7 +68,61 @@ func (si *ServiceInstance) ValidateUpdate(old runtime.Object) (warnings admissio
 	if oldInstance.Spec.BTPAccessCredentialsSecret != si.Spec.BTPAccessCredentialsSecret {
 		return nil, fmt.Errorf("changing the btpAccessCredentialsSecret for an existing instance is not allowed")
 	}
-	return nil, nil
+	if oldInstance.Spec.Landscape != si.Spec.Landscape {
+		return nil, fmt.Errorf("changing the landscape for an existing instance is not allowed")
+	}
+	if oldInstance.Spec.ClusterIDOverride != si.Spec.ClusterIDOverride {
+		return nil, fmt.Errorf("changing the clusterIDOverride for an existing instance is not allowed")
+	}
+	if oldInstance.Spec.InstanceID != si.Spec.InstanceID {
+		return nil, fmt.Errorf("changing the instanceID for an existing instance is not allowed")
+	}
+	if err := si.validateMaintenanceWindow(); err != nil {
+		return nil, err
+	}
+	if !oldInstance.Spec.ExpiresAt.Equal(si.Spec.ExpiresAt) {
+		if err := si.validateExpiration(); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateParameters(si.Spec.ParametersMergeStrategy, si.Spec.Parameters, parametersChanged(oldInstance.Spec.Parameters, si.Spec.Parameters)); err != nil {
+		return nil, err
+	}
+	if err := validateParametersFrom(si.Spec.ParametersFrom); err != nil {
+		return nil, err
+	}
+	return nil, si.validateLandscape()
+}
+
+// validateExpiration rejects expiration times in the past, existing instances keep an expiration time that has passed
+func (si *ServiceInstance) validateExpiration() error {
+	if si.Spec.ExpiresAt == nil || time.Now().Before(si.Spec.ExpiresAt.Time) {
+		return nil
+	}
+	return fmt.Errorf("invalid expiresAt '%s': the expiration time must be in the future", si.Spec.ExpiresAt.UTC().Format(time.RFC3339))
+}
+
+func (si *ServiceInstance) validateMaintenanceWindow() error {
+	if si.Spec.MaintenanceWindow == nil {
+		return nil
+	}
+	return si.Spec.MaintenanceWindow.Validate()
+}
+
+func (si *ServiceInstance) validateLandscape() error {
+	if len(si.Spec.Landscape) == 0 {
+		return nil
+	}
+	if len(si.Spec.BTPAccessCredentialsSecret) > 0 {
+		return fmt.Errorf("landscape and btpAccessCredentialsSecret cannot be specified together")
+	}
+	if errs := validation.IsDNS1123Label(si.Spec.Landscape); len(errs) > 0 {
+		return fmt.Errorf("invalid landscape '%s': %s", si.Spec.Landscape, strings.Join(errs, ", "))
+	}
+	if secrets.IsReservedLandscape(si.Spec.Landscape) {
+		return fmt.Errorf("invalid landscape '%s': the name is reserved", si.Spec.Landscape)
+	}
+	return nil
 }
 
 func (si *ServiceInstance) ValidateDelete() (warnings admission.Warnings, err error) {
@@ -1,9 +1,15 @@
 package v1
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/SAP/sap-btp-service-operator/api"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 var _ = Describe("Service Instance Webhook Test", func() {
@@ -12,7 +18,188 @@ var _ = Describe("Service Instance Webhook Test", func() {
 		instance = getInstance()
 	})
 
+	Context("Validate Create", func() {
+		When("landscape is specified", func() {
+			It("should succeed", func() {
+				instance.Spec.Landscape = "eu10"
+				_, err := instance.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		When("the maintenance window is invalid", func() {
+			It("should fail", func() {
+				instance.Spec.MaintenanceWindow = &MaintenanceWindow{Start: "02:00", Duration: "2h", TimeZone: "Nowhere/Town"}
+				_, err := instance.ValidateCreate()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("spec.maintenanceWindow.timeZone"))
+			})
+		})
+
+		When("landscape and btpAccessCredentialsSecret are specified", func() {
+			It("should fail", func() {
+				instance.Spec.Landscape = "eu10"
+				instance.Spec.BTPAccessCredentialsSecret = "my-secret"
+				_, err := instance.ValidateCreate()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("landscape and btpAccessCredentialsSecret cannot be specified together"))
+			})
+		})
+
+		When("landscape is invalid", func() {
+			It("should fail", func() {
+				instance.Spec.Landscape = "EU_10"
+				_, err := instance.ValidateCreate()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid landscape 'EU_10'"))
+			})
+		})
+
+		When("expiresAt is in the past", func() {
+			It("should fail", func() {
+				instance.Spec.ExpiresAt = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+				_, err := instance.ValidateCreate()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("the expiration time must be in the future"))
+			})
+		})
+
+		When("expiresAt is in the future", func() {
+			It("should succeed", func() {
+				instance.Spec.ExpiresAt = &metav1.Time{Time: time.Now().Add(time.Hour)}
+				_, err := instance.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		When("parameters are merged as a JSON patch", func() {
+			It("should succeed for a JSON patch", func() {
+				instance.Spec.ParametersMergeStrategy = ParametersMergeJSONPatch
+				instance.Spec.Parameters = &runtime.RawExtension{Raw: []byte(`[{"op": "replace", "path": "/plan", "value": "large"}]`)}
+				_, err := instance.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should fail for parameters that are not a JSON patch", func() {
+				instance.Spec.ParametersMergeStrategy = ParametersMergeJSONPatch
+				instance.Spec.Parameters = &runtime.RawExtension{Raw: []byte(`{"plan": "large"}`)}
+				_, err := instance.ValidateCreate()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("spec.parameters must be a JSON patch with parametersMergeStrategy jsonpatch"))
+			})
+
+			It("should fail for a JSON patch with another strategy", func() {
+				instance.Spec.Parameters = &runtime.RawExtension{Raw: []byte(`[{"op": "replace", "path": "/plan", "value": "large"}]`)}
+				_, err := instance.ValidateCreate()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("spec.parameters must be an object"))
+			})
+		})
+
+		When("parameters exceed the limits of SM", func() {
+			It("should fail for parameters that are too large", func() {
+				instance.Spec.Parameters = &runtime.RawExtension{Raw: []byte(fmt.Sprintf(`{"certificate": %q}`, strings.Repeat("a", DefaultParametersMaxSize)))}
+				_, err := instance.ValidateCreate()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("spec.parameters is too large"))
+				Expect(err.Error()).To(ContainSubstring("move large values"))
+			})
+
+			It("should fail for parameters that are nested too deeply", func() {
+				instance.Spec.Parameters = &runtime.RawExtension{Raw: []byte(strings.Repeat(`{"a": `, DefaultParametersMaxDepth+1) + "1" + strings.Repeat("}", DefaultParametersMaxDepth+1))}
+				_, err := instance.ValidateCreate()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(fmt.Sprintf("the limit is %d levels", DefaultParametersMaxDepth)))
+			})
+
+			It("should accept updates which keep the parameters", func() {
+				instance.Spec.Parameters = &runtime.RawExtension{Raw: []byte(fmt.Sprintf(`{"certificate": %q}`, strings.Repeat("a", DefaultParametersMaxSize)))}
+				newInstance := instance.DeepCopy()
+				newInstance.Spec.Parameters.Raw = []byte(fmt.Sprintf(`{"certificate": %q}`, strings.Repeat("b", DefaultParametersMaxSize)))
+				_, err := newInstance.ValidateUpdate(instance)
+				Expect(err).To(HaveOccurred())
+				newInstance.Spec.Parameters = instance.Spec.Parameters
+				newInstance.Spec.ExternalName = "renamed"
+				_, err = newInstance.ValidateUpdate(instance)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should not enforce disabled limits", func() {
+				SetParametersLimits(0, 0)
+				defer SetParametersLimits(DefaultParametersMaxSize, DefaultParametersMaxDepth)
+				instance.Spec.Parameters = &runtime.RawExtension{Raw: []byte(fmt.Sprintf(`{"certificate": %q}`, strings.Repeat("a", DefaultParametersMaxSize)))}
+				_, err := instance.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		When("parametersFrom selects a resource field", func() {
+			BeforeEach(func() {
+				instance.Spec.ParametersFrom = []ParametersFromSource{{ResourceFieldRef: &ResourceFieldReference{
+					APIVersion: "networking.k8s.io/v1", Kind: "Ingress", Name: "my-app", JSONPath: ".spec.rules[0].host", Parameter: "hostname"}}}
+			})
+
+			It("should succeed", func() {
+				_, err := instance.ValidateCreate()
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should fail for an invalid JSONPath", func() {
+				instance.Spec.ParametersFrom[0].ResourceFieldRef.JSONPath = "{.spec.rules[0}"
+				_, err := instance.ValidateCreate()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("spec.parametersFrom[0].resourceFieldRef.jsonPath is invalid"))
+			})
+
+			It("should fail when combined with a secret", func() {
+				instance.Spec.ParametersFrom[0].SecretKeyRef = &SecretKeyReference{Name: "my-secret", Key: "parameters"}
+				_, err := instance.ValidateCreate()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("resourceFieldRef cannot be combined with secretKeyRef or configMapKeyRef"))
+			})
+		})
+
+		When("landscape is reserved", func() {
+			It("should fail", func() {
+				instance.Spec.Landscape = "tls"
+				_, err := instance.ValidateCreate()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid landscape 'tls': the name is reserved"))
+			})
+		})
+	})
+
 	Context("Validate Update", func() {
+		When("landscape changed", func() {
+			It("should fail", func() {
+				newInstance := getInstance()
+				newInstance.Spec.Landscape = "us10"
+				_, err := newInstance.ValidateUpdate(instance)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("changing the landscape for an existing instance is not allowed"))
+			})
+		})
+
+		When("clusterIDOverride changed", func() {
+			It("should fail", func() {
+				newInstance := getInstance()
+				newInstance.Spec.ClusterIDOverride = "primary-cluster"
+				_, err := newInstance.ValidateUpdate(instance)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("changing the clusterIDOverride for an existing instance is not allowed"))
+			})
+		})
+
+		When("instanceID changed", func() {
+			It("should fail", func() {
+				newInstance := getInstance()
+				newInstance.Spec.InstanceID = "0f4e1c3a-6b1d-4a52-9d36-8e6f4c1b2a71"
+				_, err := newInstance.ValidateUpdate(instance)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("changing the instanceID for an existing instance is not allowed"))
+			})
+		})
+
 		When("btpAccessCredentialsSecret changed", func() {
 			It("should fail", func() {
 				instance := getInstance()
@@ -24,6 +211,26 @@ var _ = Describe("Service Instance Webhook Test", func() {
 				Expect(err.Error()).To(ContainSubstring("changing the btpAccessCredentialsSecret for an existing instance is not allowed"))
 			})
 		})
+
+		When("expiresAt changed to the past", func() {
+			It("should fail", func() {
+				newInstance := getInstance()
+				newInstance.Spec.ExpiresAt = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+				_, err := newInstance.ValidateUpdate(instance)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("the expiration time must be in the future"))
+			})
+		})
+
+		When("expiresAt passed and did not change", func() {
+			It("should succeed", func() {
+				instance.Spec.ExpiresAt = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+				newInstance := instance.DeepCopy()
+				newInstance.Labels = map[string]string{"label": "value"}
+				_, err := newInstance.ValidateUpdate(instance)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
 	})
 
 	Context("Validate Delete", func() {
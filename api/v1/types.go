@@ -1,11 +1,244 @@
 package v1
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+// DeletionPolicy defines what happens to the resource in Service Manager when its resource in the cluster is deleted
+// +kubebuilder:validation:Enum=Delete;Orphan
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the resource in Service Manager together with the resource in the cluster
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyOrphan keeps the resource in Service Manager, and the secret of a binding, when the resource in
+	// the cluster is deleted
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
+// SecretFileFormat defines how the credentials are laid out in the binding secret
+// +kubebuilder:validation:Enum=keyPerValue;json;yaml;dotenv;properties;serviceBinding
+type SecretFileFormat string
+
+const (
+	// SecretFileFormatKeyPerValue stores each credential under its own key of the secret
+	SecretFileFormatKeyPerValue SecretFileFormat = "keyPerValue"
+	// SecretFileFormatJSON stores the credentials as a JSON document under a single key
+	SecretFileFormatJSON SecretFileFormat = "json"
+	// SecretFileFormatYAML stores the credentials as a YAML document under a single key
+	SecretFileFormatYAML SecretFileFormat = "yaml"
+	// SecretFileFormatDotenv stores the credentials as environment variable assignments under a single key
+	SecretFileFormatDotenv SecretFileFormat = "dotenv"
+	// SecretFileFormatProperties stores the credentials as Java properties under a single key
+	SecretFileFormatProperties SecretFileFormat = "properties"
+	// SecretFileFormatServiceBinding stores each credential under its own key, with the type and provider entries and
+	// the well-known entries of the Service Binding for Kubernetes specification
+	SecretFileFormatServiceBinding SecretFileFormat = "serviceBinding"
+)
+
+// Phase summarizes the conditions of a resource for tools that interpret simple phases only
+type Phase string
+
+const (
+	// PhasePending is the phase of resources that were not acted on yet or are blocked, e.g. by a missing instance
+	PhasePending Phase = "Pending"
+	// PhaseProvisioning is the phase of resources with an operation in progress, including updates of ready resources
+	PhaseProvisioning Phase = "Provisioning"
+	// PhaseReady is the phase of resources that are ready for usage
+	PhaseReady Phase = "Ready"
+	// PhaseFailed is the phase of resources whose last operation failed
+	PhaseFailed Phase = "Failed"
+	// PhaseDeleting is the phase of resources that are being deleted
+	PhaseDeleting Phase = "Deleting"
+)
+
+// InstanceInfoConflictPolicy defines which value the binding secret keeps when the credentials returned by the broker
+// contain a key of the service instance info, e.g. plan or type
+// +kubebuilder:validation:Enum=preferBroker;preferOperator;prefixOperator
+type InstanceInfoConflictPolicy string
+
+const (
+	// InstanceInfoPreferBroker keeps the credentials of the broker and omits the conflicting instance info
+	InstanceInfoPreferBroker InstanceInfoConflictPolicy = "preferBroker"
+	// InstanceInfoPreferOperator replaces the credentials of the broker with the instance info
+	InstanceInfoPreferOperator InstanceInfoConflictPolicy = "preferOperator"
+	// InstanceInfoPrefixOperator keeps the credentials of the broker and stores the conflicting instance info with the
+	// btp_ prefix, e.g. btp_plan
+	InstanceInfoPrefixOperator InstanceInfoConflictPolicy = "prefixOperator"
+)
+
+// ParametersMergeStrategy defines how the sources of parametersFrom and the parameters are merged
+// +kubebuilder:validation:Enum=shallow;deep;jsonpatch
+type ParametersMergeStrategy string
+
+const (
+	// ParametersMergeShallow merges the top-level parameters, a parameter set by more than one source is an error
+	ParametersMergeShallow ParametersMergeStrategy = "shallow"
+	// ParametersMergeDeep merges nested objects recursively, other values are overridden by later sources, the
+	// sources of parametersFrom in their order followed by the parameters
+	ParametersMergeDeep ParametersMergeStrategy = "deep"
+	// ParametersMergeJSONPatch merges the sources of parametersFrom shallowly and applies the parameters, a JSON patch
+	// (RFC 6902), to the result
+	ParametersMergeJSONPatch ParametersMergeStrategy = "jsonpatch"
+)
+
+const (
+	// DefaultParametersMaxSize is the default maximum size in bytes of the parameters sent to SM, larger requests are
+	// rejected by SM
+	DefaultParametersMaxSize = 64 * 1024
+	// DefaultParametersMaxDepth is the default maximum nesting depth of the objects and arrays of the parameters sent to SM
+	DefaultParametersMaxDepth = 32
+)
+
+// parametersMaxSize and parametersMaxDepth are the limits of spec.parameters, see SetParametersLimits
+var (
+	parametersMaxSize  = DefaultParametersMaxSize
+	parametersMaxDepth = DefaultParametersMaxDepth
+)
+
+// SetParametersLimits configures the size and nesting limits the webhook enforces on spec.parameters, 0 disables a limit
+func SetParametersLimits(maxSize, maxDepth int) {
+	parametersMaxSize = maxSize
+	parametersMaxDepth = maxDepth
+}
+
+// validateParameters verifies that the parameters are a JSON patch if they are merged as one and an object otherwise,
+// and that they are within the limits of SM if checkLimits is set
+func validateParameters(strategy ParametersMergeStrategy, parameters *runtime.RawExtension, checkLimits bool) error {
+	if parameters == nil || len(parameters.Raw) == 0 {
+		return nil
+	}
+	parametersJSON, err := yaml.YAMLToJSON(parameters.Raw)
+	if err != nil {
+		return fmt.Errorf("spec.parameters is invalid: %w", err)
+	}
+	// the limits are enforced on changes of the parameters only, resources accepted before the limits were lowered can
+	// still be updated
+	if checkLimits {
+		if err := ValidateParametersLimits(parametersJSON, parametersMaxSize, parametersMaxDepth); err != nil {
+			return fmt.Errorf("spec.parameters is too large: %w", err)
+		}
+	}
+	if strategy != ParametersMergeJSONPatch {
+		if isJSONArray(parametersJSON) {
+			return fmt.Errorf("spec.parameters must be an object, a JSON patch requires parametersMergeStrategy %s", ParametersMergeJSONPatch)
+		}
+		return nil
+	}
+	if _, err := jsonpatch.DecodePatch(parametersJSON); err != nil {
+		return fmt.Errorf("spec.parameters must be a JSON patch with parametersMergeStrategy %s: %w", ParametersMergeJSONPatch, err)
+	}
+	return nil
+}
+
+// validateParametersFrom verifies the sources of parametersFrom that select fields of objects, the objects themselves
+// are read by the operator
+func validateParametersFrom(sources []ParametersFromSource) error {
+	for i, source := range sources {
+		ref := source.ResourceFieldRef
+		if ref == nil {
+			continue
+		}
+		if source.SecretKeyRef != nil || source.ConfigMapKeyRef != nil {
+			return fmt.Errorf("spec.parametersFrom[%d]: resourceFieldRef cannot be combined with secretKeyRef or configMapKeyRef", i)
+		}
+		if _, err := schema.ParseGroupVersion(ref.APIVersion); err != nil {
+			return fmt.Errorf("spec.parametersFrom[%d].resourceFieldRef.apiVersion is invalid: %w", i, err)
+		}
+		if err := jsonpath.New("resourceFieldRef").Parse(ref.Template()); err != nil {
+			return fmt.Errorf("spec.parametersFrom[%d].resourceFieldRef.jsonPath is invalid: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// ValidateParametersLimits verifies that the JSON parameters are within the size and nesting limits of SM, a limit of
+// 0 is not enforced. The error describes how to reduce them.
+func ValidateParametersLimits(parametersJSON []byte, maxSize, maxDepth int) error {
+	if maxSize > 0 && len(parametersJSON) > maxSize {
+		return fmt.Errorf("the parameters are %d bytes, the limit is %d bytes, move large values, e.g. certificates or "+
+			"files, out of the parameters or pass them in separate resources", len(parametersJSON), maxSize)
+	}
+	if maxDepth <= 0 {
+		return nil
+	}
+	var parameters interface{}
+	if err := json.Unmarshal(parametersJSON, &parameters); err != nil {
+		return err
+	}
+	if depth := parametersDepth(parameters); depth > maxDepth {
+		return fmt.Errorf("the parameters are nested %d levels deep, the limit is %d levels, flatten the nested objects "+
+			"or arrays", depth, maxDepth)
+	}
+	return nil
+}
+
+// parametersDepth returns the nesting depth of the objects and arrays of the decoded JSON value, 0 for a scalar
+func parametersDepth(value interface{}) int {
+	var children []interface{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, child := range v {
+			children = append(children, child)
+		}
+	case []interface{}:
+		children = v
+	default:
+		return 0
+	}
+	depth := 0
+	for _, child := range children {
+		if childDepth := parametersDepth(child); childDepth > depth {
+			depth = childDepth
+		}
+	}
+	return depth + 1
+}
+
+// parametersChanged checks whether the raw parameters of an update differ from the old ones
+func parametersChanged(oldParameters, parameters *runtime.RawExtension) bool {
+	if oldParameters == nil || parameters == nil {
+		return oldParameters != parameters
+	}
+	return !bytes.Equal(oldParameters.Raw, parameters.Raw)
+}
+
+func isJSONArray(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
 // ParametersFromSource represents the source of a set of Parameters
 type ParametersFromSource struct {
+	// The ConfigMap key to select from, for parameters that are not sensitive.
+	// The value must be a JSON object.
+	// +optional
+	ConfigMapKeyRef *ConfigMapKeyReference `json:"configMapKeyRef,omitempty"`
 	// The Secret key to select from.
 	// The value must be a JSON object.
 	// +optional
 	SecretKeyRef *SecretKeyReference `json:"secretKeyRef,omitempty"`
+	// The field of an object in the namespace of the resource to select from, e.g. the host of an Ingress.
+	// The value is passed as a single parameter. The kinds that can be referenced are configured by the cluster admin.
+	// +optional
+	ResourceFieldRef *ResourceFieldReference `json:"resourceFieldRef,omitempty"`
+}
+
+// ConfigMapKeyReference references a key of a ConfigMap.
+type ConfigMapKeyReference struct {
+	// The name of the config map in the namespace of the resource to select from.
+	Name string `json:"name"`
+	// The key of the config map to select from.
+	Key string `json:"key"`
 }
 
 // SecretKeyReference references a key of a Secret.
@@ -15,3 +248,32 @@ type SecretKeyReference struct {
 	// The key of the secret to select from.  Must be a valid secret key.
 	Key string `json:"key"`
 }
+
+// ResourceFieldReference references a field of an object, selected with a JSONPath expression.
+type ResourceFieldReference struct {
+	// The API version of the object, e.g. v1 or networking.k8s.io/v1.
+	// +kubebuilder:validation:MinLength=1
+	APIVersion string `json:"apiVersion"`
+	// The kind of the object, e.g. ConfigMap or Ingress.
+	// +kubebuilder:validation:MinLength=1
+	Kind string `json:"kind"`
+	// The name of the object in the namespace of the resource to select from.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// The JSONPath expression selecting the value, e.g. {.spec.rules[0].host}. An expression selecting several
+	// values passes them as an array.
+	// +kubebuilder:validation:MinLength=1
+	JSONPath string `json:"jsonPath"`
+	// The name of the parameter the value is passed in.
+	// +kubebuilder:validation:MinLength=1
+	Parameter string `json:"parameter"`
+}
+
+// Template returns the JSONPath expression as template, the braces of the expression are optional
+func (r *ResourceFieldReference) Template() string {
+	expression := strings.TrimSpace(r.JSONPath)
+	if strings.HasPrefix(expression, "{") {
+		return expression
+	}
+	return "{" + expression + "}"
+}
@@ -0,0 +1,32 @@
+package webhooks
+
+import (
+	"fmt"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	v1admission "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// validateAdoptionMetadata prevents users other than the operator from labeling resources as imported by an adoption
+// run, since resources that no cluster manages in SM are adopted only when imported by a run, and from removing the
+// adoptionPending annotation, which releases the credentials of an imported binding before the run approves it
+func validateAdoptionMetadata(operation v1admission.Operation, oldObject, object metav1.Object) error {
+	run := object.GetLabels()[api.AdoptionRunLabel]
+	if operation == v1admission.Create {
+		if len(run) > 0 {
+			return fmt.Errorf("the label '%s' is set by adoption runs only", api.AdoptionRunLabel)
+		}
+		return nil
+	}
+	if operation != v1admission.Update {
+		return nil
+	}
+	if oldObject.GetLabels()[api.AdoptionRunLabel] != run {
+		return fmt.Errorf("the label '%s' is set by adoption runs only", api.AdoptionRunLabel)
+	}
+	if oldObject.GetAnnotations()[api.AdoptionPendingAnnotation] == "true" && object.GetAnnotations()[api.AdoptionPendingAnnotation] != "true" {
+		return fmt.Errorf("the annotation '%s' is removed by adoption run '%s', set approveBindings in the run to approve the binding", api.AdoptionPendingAnnotation, run)
+	}
+	return nil
+}
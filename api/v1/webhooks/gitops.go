@@ -0,0 +1,115 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// the annotations read by GitOps engines to order the deletion of resources and to exclude resources from pruning
+const (
+	argoSyncWaveAnnotation       = "argocd.argoproj.io/sync-wave"
+	argoSyncOptionsAnnotation    = "argocd.argoproj.io/sync-options"
+	argoCompareOptionsAnnotation = "argocd.argoproj.io/compare-options"
+	fluxPruneAnnotation          = "kustomize.toolkit.fluxcd.io/prune"
+	dependsOnAnnotation          = "config.kubernetes.io/depends-on"
+
+	argoPruneLast        = "PruneLast=true"
+	argoPruneDisabled    = "Prune=false"
+	argoIgnoreExtraneous = "IgnoreExtraneous"
+	fluxPruneDisabled    = "disabled"
+)
+
+// addInstanceGitOpsHints lets Argo CD prune the instance only after all other resources of the application, its
+// bindings included, were pruned
+func addInstanceGitOpsHints(instance *servicesv1.ServiceInstance) {
+	annotations := instance.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[argoSyncOptionsAnnotation] = appendSyncOption(annotations[argoSyncOptionsAnnotation], argoPruneLast)
+	instance.SetAnnotations(annotations)
+}
+
+// addBindingGitOpsHints orders the binding after its instance, so that GitOps engines apply it after and delete it
+// before the instance. On create, the secret of the binding is excluded from pruning, since it is owned by the
+// operator and deleted with the binding. Hints set by the user are kept.
+func addBindingGitOpsHints(ctx context.Context, reader client.Reader, binding *servicesv1.ServiceBinding, create bool) {
+	annotations := binding.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	instanceNamespace := binding.Namespace
+	if len(binding.Spec.ServiceInstanceNamespace) > 0 {
+		instanceNamespace = binding.Spec.ServiceInstanceNamespace
+	}
+	// bindings referring to the instance by its ID in SM have no instance in the cluster to be ordered after
+	if len(binding.Spec.ServiceInstanceName) > 0 {
+		if _, found := annotations[argoSyncWaveAnnotation]; !found {
+			wave := instanceSyncWave(ctx, reader, types.NamespacedName{Namespace: instanceNamespace, Name: binding.Spec.ServiceInstanceName})
+			annotations[argoSyncWaveAnnotation] = strconv.Itoa(wave + 1)
+		}
+		if _, found := annotations[dependsOnAnnotation]; !found {
+			annotations[dependsOnAnnotation] = fmt.Sprintf("%s/namespaces/%s/ServiceInstance/%s",
+				servicesv1.GroupVersion.Group, instanceNamespace, binding.Spec.ServiceInstanceName)
+		}
+		binding.SetAnnotations(annotations)
+	}
+
+	// the spec of a binding cannot be changed after it was created
+	if !create || binding.Spec.CredentialsTarget != nil {
+		return
+	}
+	if binding.Spec.SecretAnnotations == nil {
+		binding.Spec.SecretAnnotations = map[string]string{}
+	}
+	for key, value := range map[string]string{
+		argoSyncOptionsAnnotation:    argoPruneDisabled,
+		argoCompareOptionsAnnotation: argoIgnoreExtraneous,
+		fluxPruneAnnotation:          fluxPruneDisabled,
+	} {
+		if _, found := binding.Spec.SecretAnnotations[key]; !found {
+			binding.Spec.SecretAnnotations[key] = value
+		}
+	}
+}
+
+// instanceSyncWave returns the Argo CD sync wave of the instance, 0 if the instance or its wave is not set. The default
+// wave is assumed if the instance cannot be read, so that a hint never blocks the admission of a binding.
+func instanceSyncWave(ctx context.Context, reader client.Reader, key types.NamespacedName) int {
+	if reader == nil {
+		return 0
+	}
+	instance := &servicesv1.ServiceInstance{}
+	if err := reader.Get(ctx, key, instance); err != nil {
+		// a missing instance is created in the same sync, in the default wave unless it is annotated otherwise
+		if !apierrors.IsNotFound(err) {
+			bindinglog.Error(err, "failed to read the sync wave of the instance, assuming the default wave", "instance", key.String())
+		}
+		return 0
+	}
+	// an invalid wave is rejected by Argo CD when it syncs the instance
+	wave, _ := strconv.Atoi(strings.TrimSpace(instance.GetAnnotations()[argoSyncWaveAnnotation]))
+	return wave
+}
+
+// appendSyncOption adds the option to the comma separated sync options, unless they already set it
+func appendSyncOption(options, option string) string {
+	name, _, _ := strings.Cut(option, "=")
+	for _, existing := range strings.Split(options, ",") {
+		existingName, _, _ := strings.Cut(strings.TrimSpace(existing), "=")
+		if existingName == name {
+			return options
+		}
+	}
+	if len(strings.TrimSpace(options)) == 0 {
+		return option
+	}
+	return options + "," + option
+}
@@ -0,0 +1,160 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	jsonpatch "github.com/evanphx/json-patch"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1admission "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("GitOps hints", func() {
+	var scheme *runtime.Scheme
+
+	// mutate sends the object to the defaulter and applies the patches of the response to it
+	mutate := func(handler admission.Handler, kind string, operation v1admission.Operation, obj client.Object) {
+		raw, err := json.Marshal(obj)
+		Expect(err).ToNot(HaveOccurred())
+		req := admission.Request{AdmissionRequest: v1admission.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Group: "services.cloud.sap.com", Version: "v1", Kind: kind},
+			Namespace: obj.GetNamespace(),
+			Operation: operation,
+			Object:    runtime.RawExtension{Raw: raw},
+			OldObject: runtime.RawExtension{Raw: raw},
+		}}
+		res := handler.Handle(context.Background(), req)
+		Expect(res.Allowed).To(BeTrue())
+		rawPatch, err := json.Marshal(res.Patches)
+		Expect(err).ToNot(HaveOccurred())
+		patch, err := jsonpatch.DecodePatch(rawPatch)
+		Expect(err).ToNot(HaveOccurred())
+		patched, err := patch.Apply(raw)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(json.Unmarshal(patched, obj)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(scheme)).To(Succeed())
+	})
+
+	Context("instances", func() {
+		var defaulter *ServiceInstanceDefaulter
+		var instance *servicesv1.ServiceInstance
+
+		BeforeEach(func() {
+			defaulter = &ServiceInstanceDefaulter{Decoder: admission.NewDecoder(scheme), GitOpsHints: true}
+			instance = &servicesv1.ServiceInstance{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "services.cloud.sap.com/v1", Kind: "ServiceInstance"},
+				ObjectMeta: metav1.ObjectMeta{Name: "instance", Namespace: "test-ns"},
+				Spec:       servicesv1.ServiceInstanceSpec{ServiceOfferingName: "offering", ServicePlanName: "plan", ExternalName: "instance"},
+			}
+		})
+
+		It("should prune instances last", func() {
+			mutate(defaulter, "ServiceInstance", v1admission.Create, instance)
+			Expect(instance.Annotations).To(HaveKeyWithValue(argoSyncOptionsAnnotation, argoPruneLast))
+		})
+
+		It("should keep the sync options of the user", func() {
+			instance.Annotations = map[string]string{argoSyncOptionsAnnotation: "Replace=true"}
+			mutate(defaulter, "ServiceInstance", v1admission.Update, instance)
+			Expect(instance.Annotations).To(HaveKeyWithValue(argoSyncOptionsAnnotation, "Replace=true,PruneLast=true"))
+
+			instance.Annotations[argoSyncOptionsAnnotation] = "PruneLast=false"
+			mutate(defaulter, "ServiceInstance", v1admission.Update, instance)
+			Expect(instance.Annotations).To(HaveKeyWithValue(argoSyncOptionsAnnotation, "PruneLast=false"))
+		})
+
+		It("should not annotate instances when the feature gate is disabled", func() {
+			defaulter.GitOpsHints = false
+			mutate(defaulter, "ServiceInstance", v1admission.Create, instance)
+			Expect(instance.Annotations).To(BeEmpty())
+		})
+	})
+
+	Context("bindings", func() {
+		var defaulter *ServiceBindingDefaulter
+		var binding *servicesv1.ServiceBinding
+
+		BeforeEach(func() {
+			instance := &servicesv1.ServiceInstance{ObjectMeta: metav1.ObjectMeta{Name: "instance", Namespace: "instances",
+				Annotations: map[string]string{argoSyncWaveAnnotation: "2"}}}
+			defaulter = &ServiceBindingDefaulter{
+				Decoder:     admission.NewDecoder(scheme),
+				GitOpsHints: true,
+				Client:      fake.NewClientBuilder().WithScheme(scheme).WithObjects(instance).Build(),
+			}
+			binding = &servicesv1.ServiceBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "services.cloud.sap.com/v1", Kind: "ServiceBinding"},
+				ObjectMeta: metav1.ObjectMeta{Name: "binding", Namespace: "test-ns"},
+				Spec:       servicesv1.ServiceBindingSpec{ServiceInstanceName: "instance", ExternalName: "binding", SecretName: "binding"},
+			}
+		})
+
+		It("should order bindings after their instance", func() {
+			mutate(defaulter, "ServiceBinding", v1admission.Create, binding)
+			Expect(binding.Annotations).To(HaveKeyWithValue(argoSyncWaveAnnotation, "1"))
+			Expect(binding.Annotations).To(HaveKeyWithValue(dependsOnAnnotation, "services.cloud.sap.com/namespaces/test-ns/ServiceInstance/instance"))
+
+			binding.Annotations = nil
+			binding.Spec.ServiceInstanceNamespace = "instances"
+			mutate(defaulter, "ServiceBinding", v1admission.Update, binding)
+			Expect(binding.Annotations).To(HaveKeyWithValue(argoSyncWaveAnnotation, "3"))
+			Expect(binding.Annotations).To(HaveKeyWithValue(dependsOnAnnotation, "services.cloud.sap.com/namespaces/instances/ServiceInstance/instance"))
+		})
+
+		It("should order bindings in the default wave if the instance cannot be read", func() {
+			defaulter.Client = interceptor.NewClient(defaulter.Client.(client.WithWatch), interceptor.Funcs{
+				Get: func(context.Context, client.WithWatch, client.ObjectKey, client.Object, ...client.GetOption) error {
+					return fmt.Errorf("the cache is not synced")
+				},
+			})
+			binding.Spec.ServiceInstanceNamespace = "instances"
+			mutate(defaulter, "ServiceBinding", v1admission.Create, binding)
+			Expect(binding.Annotations).To(HaveKeyWithValue(argoSyncWaveAnnotation, "1"))
+		})
+
+		It("should keep the sync wave of the user", func() {
+			binding.Annotations = map[string]string{argoSyncWaveAnnotation: "-1"}
+			mutate(defaulter, "ServiceBinding", v1admission.Create, binding)
+			Expect(binding.Annotations).To(HaveKeyWithValue(argoSyncWaveAnnotation, "-1"))
+		})
+
+		It("should exclude the secrets of new bindings from pruning", func() {
+			binding.Spec.SecretAnnotations = map[string]string{fluxPruneAnnotation: "enabled"}
+			mutate(defaulter, "ServiceBinding", v1admission.Create, binding)
+			Expect(binding.Spec.SecretAnnotations).To(Equal(map[string]string{
+				argoSyncOptionsAnnotation:    argoPruneDisabled,
+				argoCompareOptionsAnnotation: argoIgnoreExtraneous,
+				fluxPruneAnnotation:          "enabled",
+			}))
+		})
+
+		It("should not change the spec of existing bindings", func() {
+			mutate(defaulter, "ServiceBinding", v1admission.Update, binding)
+			Expect(binding.Spec.SecretAnnotations).To(BeEmpty())
+			Expect(binding.Annotations).To(HaveKey(argoSyncWaveAnnotation))
+		})
+
+		It("should not order bindings of instances outside the cluster", func() {
+			binding.Spec.ServiceInstanceName = ""
+			binding.Spec.ServiceInstanceID = "1234"
+			mutate(defaulter, "ServiceBinding", v1admission.Create, binding)
+			Expect(binding.Annotations).To(BeEmpty())
+			Expect(binding.Spec.SecretAnnotations).To(HaveLen(3))
+		})
+	})
+})
@@ -0,0 +1,160 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	v1admission "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:webhook:path=/validate-services-cloud-sap-com-v1-parametersfrom,mutating=false,failurePolicy=ignore,groups=services.cloud.sap.com,resources=serviceinstances;servicebindings,verbs=create;update,versions=v1,name=vparametersfrom.kb.io,sideEffects=None,admissionReviewVersions=v1beta1;v1
+
+const (
+	// ParametersFromValidationWarn admits resources with unresolvable parametersFrom and returns a warning
+	ParametersFromValidationWarn = "warn"
+	// ParametersFromValidationEnforce rejects resources with unresolvable parametersFrom
+	ParametersFromValidationEnforce = "enforce"
+	// ParametersFromValidationDisabled turns off the parametersFrom validation
+	ParametersFromValidationDisabled = "disabled"
+)
+
+var parametersFromLog = logf.Log.WithName("parametersfrom-webhook")
+
+// ValidateParametersFromValidation verifies that the configured validation mode is supported
+func ValidateParametersFromValidation(mode string) error {
+	switch mode {
+	case ParametersFromValidationWarn, ParametersFromValidationEnforce, ParametersFromValidationDisabled:
+		return nil
+	}
+	return fmt.Errorf("parametersFrom validation '%s' is not supported, supported values are %s, %s and %s",
+		mode, ParametersFromValidationWarn, ParametersFromValidationEnforce, ParametersFromValidationDisabled)
+}
+
+// ParametersFromValidator verifies that the secrets and config maps referenced by parametersFrom exist and contain the referenced key
+type ParametersFromValidator struct {
+	// Client reads the secrets live, so no informer holds all the secrets of the cluster
+	Client  client.Reader
+	Decoder *admission.Decoder
+	// Enforce rejects the request instead of admitting it with warnings
+	Enforce bool
+}
+
+func (v *ParametersFromValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var obj, oldObj client.Object
+	switch req.Kind.Kind {
+	case "ServiceInstance":
+		obj, oldObj = &servicesv1.ServiceInstance{}, &servicesv1.ServiceInstance{}
+	case "ServiceBinding":
+		obj, oldObj = &servicesv1.ServiceBinding{}, &servicesv1.ServiceBinding{}
+	default:
+		return admission.Allowed("")
+	}
+
+	if err := v.Decoder.Decode(req, obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if !obj.GetDeletionTimestamp().IsZero() {
+		return admission.Allowed("")
+	}
+
+	parametersFrom := getParametersFrom(obj)
+	if req.Operation == v1admission.Update {
+		if err := v.Decoder.DecodeRaw(req.OldObject, oldObj); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		// validate only when parametersFrom changed, so updates of the operator (e.g. finalizers) are never blocked
+		if reflect.DeepEqual(parametersFrom, getParametersFrom(oldObj)) {
+			return admission.Allowed("")
+		}
+	}
+
+	namespace := obj.GetNamespace()
+	if len(namespace) == 0 {
+		namespace = req.Namespace
+	}
+
+	var problems, warnings []string
+	for _, source := range parametersFrom {
+		if source.SecretKeyRef != nil && source.ConfigMapKeyRef != nil {
+			problems = append(problems, "a source references both a secret and a config map")
+			continue
+		}
+		if source.SecretKeyRef != nil {
+			problem, err := v.validateSecretKeyRef(ctx, namespace, source.SecretKeyRef)
+			if err != nil {
+				parametersFromLog.Error(err, "failed to verify parametersFrom secret", "namespace", namespace, "secret", source.SecretKeyRef.Name)
+				warnings = append(warnings, fmt.Sprintf("could not verify secret '%s': %s", source.SecretKeyRef.Name, err.Error()))
+				continue
+			}
+			if len(problem) > 0 {
+				problems = append(problems, problem)
+			}
+		}
+		if source.ConfigMapKeyRef != nil {
+			problem, err := v.validateConfigMapKeyRef(ctx, namespace, source.ConfigMapKeyRef)
+			if err != nil {
+				parametersFromLog.Error(err, "failed to verify parametersFrom config map", "namespace", namespace, "configmap", source.ConfigMapKeyRef.Name)
+				warnings = append(warnings, fmt.Sprintf("could not verify config map '%s': %s", source.ConfigMapKeyRef.Name, err.Error()))
+				continue
+			}
+			if len(problem) > 0 {
+				problems = append(problems, problem)
+			}
+		}
+	}
+
+	if len(problems) > 0 && v.Enforce {
+		return admission.Denied(fmt.Sprintf("spec.parametersFrom is invalid: %s", strings.Join(problems, ", ")))
+	}
+	return admission.Allowed("").WithWarnings(append(problems, warnings...)...)
+}
+
+// validateSecretKeyRef returns a description of the problem with the reference or an empty string if it resolves
+func (v *ParametersFromValidator) validateSecretKeyRef(ctx context.Context, namespace string, secretKeyRef *servicesv1.SecretKeyReference) (string, error) {
+	secret := &corev1.Secret{}
+	if err := v.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: secretKeyRef.Name}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Sprintf("secret '%s' not found in namespace '%s'", secretKeyRef.Name, namespace), nil
+		}
+		return "", err
+	}
+	if _, ok := secret.Data[secretKeyRef.Key]; !ok {
+		return fmt.Sprintf("secret '%s' has no key '%s'", secretKeyRef.Name, secretKeyRef.Key), nil
+	}
+	return "", nil
+}
+
+// validateConfigMapKeyRef returns a description of the problem with the reference or an empty string if it resolves
+func (v *ParametersFromValidator) validateConfigMapKeyRef(ctx context.Context, namespace string, configMapKeyRef *servicesv1.ConfigMapKeyReference) (string, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := v.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: configMapKeyRef.Name}, configMap); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Sprintf("config map '%s' not found in namespace '%s'", configMapKeyRef.Name, namespace), nil
+		}
+		return "", err
+	}
+	if _, ok := configMap.Data[configMapKeyRef.Key]; !ok {
+		return fmt.Sprintf("config map '%s' has no key '%s'", configMapKeyRef.Name, configMapKeyRef.Key), nil
+	}
+	return "", nil
+}
+
+func getParametersFrom(obj client.Object) []servicesv1.ParametersFromSource {
+	switch o := obj.(type) {
+	case *servicesv1.ServiceInstance:
+		return o.Spec.ParametersFrom
+	case *servicesv1.ServiceBinding:
+		return o.Spec.ParametersFrom
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1admission "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("ParametersFrom Validator", func() {
+	var (
+		validator *ParametersFromValidator
+		instance  *servicesv1.ServiceInstance
+	)
+
+	request := func(operation v1admission.Operation, obj, oldObj *servicesv1.ServiceInstance) admission.Request {
+		raw, err := json.Marshal(obj)
+		Expect(err).ToNot(HaveOccurred())
+		req := admission.Request{AdmissionRequest: v1admission.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Group: "services.cloud.sap.com", Version: "v1", Kind: "ServiceInstance"},
+			Namespace: obj.Namespace,
+			Operation: operation,
+			Object:    runtime.RawExtension{Raw: raw},
+		}}
+		if oldObj != nil {
+			oldRaw, err := json.Marshal(oldObj)
+			Expect(err).ToNot(HaveOccurred())
+			req.OldObject = runtime.RawExtension{Raw: oldRaw}
+		}
+		return req
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(scheme)).To(Succeed())
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "param-secret", Namespace: "test-ns"},
+			Data:       map[string][]byte{"secret-parameter": []byte(`{"key":"value"}`)},
+		}
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "param-configmap", Namespace: "test-ns"},
+			Data:       map[string]string{"configmap-parameter": `{"plan":"value"}`},
+		}
+		validator = &ParametersFromValidator{
+			Client:  fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret, configMap).Build(),
+			Decoder: admission.NewDecoder(scheme),
+		}
+		instance = &servicesv1.ServiceInstance{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "services.cloud.sap.com/v1", Kind: "ServiceInstance"},
+			ObjectMeta: metav1.ObjectMeta{Name: "instance", Namespace: "test-ns"},
+			Spec: servicesv1.ServiceInstanceSpec{
+				ServiceOfferingName: "offering",
+				ServicePlanName:     "plan",
+				ParametersFrom: []servicesv1.ParametersFromSource{
+					{SecretKeyRef: &servicesv1.SecretKeyReference{Name: "param-secret", Key: "secret-parameter"}},
+				},
+			},
+		}
+	})
+
+	When("referenced secret and key exist", func() {
+		It("should allow without warnings", func() {
+			res := validator.Handle(context.Background(), request(v1admission.Create, instance, nil))
+			Expect(res.Allowed).To(BeTrue())
+			Expect(res.Warnings).To(BeEmpty())
+		})
+	})
+
+	When("referenced secret does not exist", func() {
+		BeforeEach(func() {
+			instance.Spec.ParametersFrom[0].SecretKeyRef.Name = "missing-secret"
+		})
+
+		It("should allow with a warning", func() {
+			res := validator.Handle(context.Background(), request(v1admission.Create, instance, nil))
+			Expect(res.Allowed).To(BeTrue())
+			Expect(res.Warnings).To(ConsistOf(ContainSubstring("secret 'missing-secret' not found")))
+		})
+
+		It("should deny when enforced", func() {
+			validator.Enforce = true
+			res := validator.Handle(context.Background(), request(v1admission.Create, instance, nil))
+			Expect(res.Allowed).To(BeFalse())
+			Expect(res.Result.Message).To(ContainSubstring("secret 'missing-secret' not found"))
+		})
+
+		It("should allow updates that do not change parametersFrom", func() {
+			validator.Enforce = true
+			updated := instance.DeepCopy()
+			updated.Finalizers = []string{"finalizer"}
+			res := validator.Handle(context.Background(), request(v1admission.Update, updated, instance))
+			Expect(res.Allowed).To(BeTrue())
+		})
+	})
+
+	When("referenced key does not exist in the secret", func() {
+		It("should deny when enforced", func() {
+			validator.Enforce = true
+			instance.Spec.ParametersFrom[0].SecretKeyRef.Key = "missing-key"
+			res := validator.Handle(context.Background(), request(v1admission.Create, instance, nil))
+			Expect(res.Allowed).To(BeFalse())
+			Expect(res.Result.Message).To(ContainSubstring("secret 'param-secret' has no key 'missing-key'"))
+		})
+	})
+
+	When("a config map is referenced", func() {
+		It("should allow an existing config map and key without warnings", func() {
+			instance.Spec.ParametersFrom = append(instance.Spec.ParametersFrom, servicesv1.ParametersFromSource{
+				ConfigMapKeyRef: &servicesv1.ConfigMapKeyReference{Name: "param-configmap", Key: "configmap-parameter"},
+			})
+			res := validator.Handle(context.Background(), request(v1admission.Create, instance, nil))
+			Expect(res.Allowed).To(BeTrue())
+			Expect(res.Warnings).To(BeEmpty())
+		})
+
+		It("should deny a missing key when enforced", func() {
+			validator.Enforce = true
+			instance.Spec.ParametersFrom = []servicesv1.ParametersFromSource{
+				{ConfigMapKeyRef: &servicesv1.ConfigMapKeyReference{Name: "param-configmap", Key: "missing-key"}},
+			}
+			res := validator.Handle(context.Background(), request(v1admission.Create, instance, nil))
+			Expect(res.Allowed).To(BeFalse())
+			Expect(res.Result.Message).To(ContainSubstring("config map 'param-configmap' has no key 'missing-key'"))
+		})
+
+		It("should warn about a source referencing both a secret and a config map", func() {
+			instance.Spec.ParametersFrom[0].ConfigMapKeyRef = &servicesv1.ConfigMapKeyReference{Name: "param-configmap", Key: "configmap-parameter"}
+			res := validator.Handle(context.Background(), request(v1admission.Create, instance, nil))
+			Expect(res.Allowed).To(BeTrue())
+			Expect(res.Warnings).To(ConsistOf(ContainSubstring("both a secret and a config map")))
+		})
+	})
+
+	When("validation mode is configured", func() {
+		It("should accept the supported modes", func() {
+			for _, mode := range []string{ParametersFromValidationWarn, ParametersFromValidationEnforce, ParametersFromValidationDisabled} {
+				Expect(ValidateParametersFromValidation(mode)).To(Succeed())
+			}
+		})
+
+		It("should reject an unknown mode", func() {
+			Expect(ValidateParametersFromValidation("strict")).To(MatchError(ContainSubstring("parametersFrom validation 'strict' is not supported")))
+		})
+	})
+})
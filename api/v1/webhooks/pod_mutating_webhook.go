@@ -0,0 +1,143 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:webhook:path=/mutate-v1-pod-binding-injection,mutating=true,failurePolicy=ignore,groups="",resources=pods,verbs=create,versions=v1,name=mpodbindinginjection.kb.io,sideEffects=None,admissionReviewVersions=v1beta1;v1
+// +kubebuilder:rbac:groups=services.cloud.sap.com,resources=bindinginjections,verbs=get;list;watch
+
+const injectedVolumePrefix = "sap-btp-binding-"
+
+var podlog = logf.Log.WithName("pod-binding-injection-webhook")
+
+// PodBindingInjector injects the credentials of service bindings into pods matching a BindingInjection
+type PodBindingInjector struct {
+	Client  client.Reader
+	Decoder *admission.Decoder
+	// SecretsNamespace is the namespace binding secrets are stored in instead of the namespace of the binding,
+	// such secrets cannot be referenced by pods
+	SecretsNamespace string
+}
+
+func (p *PodBindingInjector) Handle(ctx context.Context, req admission.Request) admission.Response {
+	pod := &corev1.Pod{}
+	if err := p.Decoder.Decode(req, pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	injections := &servicesv1.BindingInjectionList{}
+	if err := p.Client.List(ctx, injections, client.InNamespace(req.Namespace)); err != nil {
+		podlog.Error(err, "failed to list binding injections", "namespace", req.Namespace)
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	injected := false
+	var warnings []string
+	for i := range injections.Items {
+		injection := &injections.Items[i]
+		selector, err := metav1.LabelSelectorAsSelector(&injection.Spec.Selector)
+		if err != nil {
+			podlog.Error(err, "invalid selector in binding injection", "name", injection.Name)
+			continue
+		}
+		if selector.Empty() || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+
+		binding := &servicesv1.ServiceBinding{}
+		if err := p.Client.Get(ctx, types.NamespacedName{Namespace: req.Namespace, Name: injection.Spec.ServiceBindingName}, binding); err != nil {
+			// a missing binding should not block the workload, the pod is created without its credentials
+			podlog.Error(err, "failed to get service binding for injection", "name", injection.Name, "binding", injection.Spec.ServiceBindingName)
+			warnings = append(warnings, fmt.Sprintf("binding injection '%s' skipped: %s", injection.Name, err.Error()))
+			continue
+		}
+
+		if len(p.SecretsNamespace) > 0 {
+			warnings = append(warnings, fmt.Sprintf("binding injection '%s' skipped: binding secrets are stored in namespace '%s' and cannot be mounted into pods", injection.Name, p.SecretsNamespace))
+			continue
+		}
+
+		podlog.Info("injecting service binding into pod", "binding", binding.Name, "secret", binding.Spec.SecretName)
+		injectBindingSecret(pod, injection, binding.Spec.SecretName)
+		injected = true
+	}
+
+	if !injected {
+		return admission.Allowed("").WithWarnings(warnings...)
+	}
+
+	marshaledPod, err := json.Marshal(pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaledPod).WithWarnings(warnings...)
+}
+
+func injectBindingSecret(pod *corev1.Pod, injection *servicesv1.BindingInjection, secretName string) {
+	volumeName := uniqueVolumeName(pod, injectedVolumePrefix+injection.Name)
+	for i := range pod.Spec.InitContainers {
+		injectIntoContainer(&pod.Spec.InitContainers[i], injection, secretName, volumeName)
+	}
+	for i := range pod.Spec.Containers {
+		injectIntoContainer(&pod.Spec.Containers[i], injection, secretName, volumeName)
+	}
+
+	if len(injection.Spec.MountPath) > 0 {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: volumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: secretName},
+			},
+		})
+	}
+}
+
+func injectIntoContainer(container *corev1.Container, injection *servicesv1.BindingInjection, secretName, volumeName string) {
+	container.EnvFrom = append(container.EnvFrom, corev1.EnvFromSource{
+		Prefix:    injection.Spec.EnvPrefix,
+		SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: secretName}},
+	})
+	if len(injection.Spec.MountPath) > 0 {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      volumeName,
+			MountPath: injection.Spec.MountPath,
+			ReadOnly:  true,
+		})
+	}
+}
+
+// uniqueVolumeName shortens the name to a valid volume name and adds a suffix if a volume of the pod already has it
+func uniqueVolumeName(pod *corev1.Pod, name string) string {
+	taken := make(map[string]bool, len(pod.Spec.Volumes))
+	for _, volume := range pod.Spec.Volumes {
+		taken[volume.Name] = true
+	}
+
+	candidate := trimVolumeName(name, "")
+	for i := 1; taken[candidate]; i++ {
+		candidate = trimVolumeName(name, fmt.Sprintf("-%d", i))
+	}
+	return candidate
+}
+
+func trimVolumeName(name, suffix string) string {
+	if len(name)+len(suffix) > validation.DNS1123LabelMaxLength {
+		name = strings.TrimRight(name[:validation.DNS1123LabelMaxLength-len(suffix)], "-.")
+	}
+	return name + suffix
+}
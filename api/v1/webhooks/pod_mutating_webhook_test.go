@@ -0,0 +1,147 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1admission "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("Pod Binding Injector", func() {
+	var (
+		injector         *PodBindingInjector
+		pod              *corev1.Pod
+		objects          []client.Object
+		secretsNamespace string
+	)
+
+	handle := func() admission.Response {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(scheme)).To(Succeed())
+		injector = &PodBindingInjector{
+			Client:           fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+			Decoder:          admission.NewDecoder(scheme),
+			SecretsNamespace: secretsNamespace,
+		}
+
+		raw, err := json.Marshal(pod)
+		Expect(err).ToNot(HaveOccurred())
+		return injector.Handle(context.Background(), admission.Request{AdmissionRequest: v1admission.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			Namespace: "test-ns",
+			Operation: v1admission.Create,
+			Object:    runtime.RawExtension{Raw: raw},
+		}})
+	}
+
+	BeforeEach(func() {
+		secretsNamespace = ""
+		pod = &corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "test-ns", Labels: map[string]string{"app": "my-app"}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app"}}},
+		}
+		objects = []client.Object{
+			&servicesv1.ServiceBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "test-ns"},
+				Spec:       servicesv1.ServiceBindingSpec{ServiceInstanceName: "my-instance", SecretName: "my-binding-secret"},
+			},
+			&servicesv1.BindingInjection{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-injection", Namespace: "test-ns"},
+				Spec: servicesv1.BindingInjectionSpec{
+					Selector:           metav1.LabelSelector{MatchLabels: map[string]string{"app": "my-app"}},
+					ServiceBindingName: "my-binding",
+					EnvPrefix:          "MY_",
+					MountPath:          "/etc/binding",
+				},
+			},
+		}
+	})
+
+	When("pod matches a binding injection", func() {
+		It("should inject env vars and volume from the binding secret", func() {
+			res := handle()
+			Expect(res.Allowed).To(BeTrue())
+			Expect(res.Patches).ToNot(BeEmpty())
+
+			patched, err := json.Marshal(res.Patches)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(patched)).To(ContainSubstring(`"prefix":"MY_"`))
+			Expect(string(patched)).To(ContainSubstring(`"secretName":"my-binding-secret"`))
+			Expect(string(patched)).To(ContainSubstring(`"mountPath":"/etc/binding"`))
+		})
+	})
+
+	When("pod does not match any binding injection", func() {
+		It("should not mutate the pod", func() {
+			pod.Labels = map[string]string{"app": "other"}
+			res := handle()
+			Expect(res.Allowed).To(BeTrue())
+			Expect(res.Patches).To(BeEmpty())
+		})
+	})
+
+	When("referenced binding does not exist", func() {
+		It("should admit the pod with a warning", func() {
+			objects = objects[1:]
+			res := handle()
+			Expect(res.Allowed).To(BeTrue())
+			Expect(res.Patches).To(BeEmpty())
+			Expect(res.Warnings).To(ConsistOf(ContainSubstring("binding injection 'my-injection' skipped")))
+		})
+	})
+
+	When("binding secrets are stored in a dedicated namespace", func() {
+		It("should admit the pod with a warning", func() {
+			secretsNamespace = "binding-secrets"
+			res := handle()
+			Expect(res.Allowed).To(BeTrue())
+			Expect(res.Patches).To(BeEmpty())
+			Expect(res.Warnings).To(ConsistOf(ContainSubstring("binding secrets are stored in namespace 'binding-secrets'")))
+		})
+	})
+
+	Context("injectBindingSecret", func() {
+		var injection *servicesv1.BindingInjection
+
+		BeforeEach(func() {
+			injection = objects[1].(*servicesv1.BindingInjection)
+		})
+
+		It("should inject into init containers as well", func() {
+			pod.Spec.InitContainers = []corev1.Container{{Name: "init", Image: "init"}}
+			injectBindingSecret(pod, injection, "my-binding-secret")
+			Expect(pod.Spec.InitContainers[0].EnvFrom).To(HaveLen(1))
+			Expect(pod.Spec.InitContainers[0].VolumeMounts).To(HaveLen(1))
+			Expect(pod.Spec.Containers[0].EnvFrom).To(HaveLen(1))
+		})
+
+		It("should not reuse the name of an existing volume", func() {
+			pod.Spec.Volumes = []corev1.Volume{{Name: injectedVolumePrefix + injection.Name}}
+			injectBindingSecret(pod, injection, "my-binding-secret")
+			Expect(pod.Spec.Volumes).To(HaveLen(2))
+			Expect(pod.Spec.Volumes[1].Name).To(Equal(injectedVolumePrefix + injection.Name + "-1"))
+			Expect(pod.Spec.Containers[0].VolumeMounts[0].Name).To(Equal(pod.Spec.Volumes[1].Name))
+		})
+
+		It("should keep long volume names valid", func() {
+			injection.Name = strings.Repeat("a", 70)
+			pod.Spec.Volumes = []corev1.Volume{{Name: uniqueVolumeName(pod, injectedVolumePrefix+injection.Name)}}
+			injectBindingSecret(pod, injection, "my-binding-secret")
+			Expect(pod.Spec.Volumes[1].Name).To(HaveLen(63))
+			Expect(pod.Spec.Volumes[1].Name).To(HaveSuffix("-1"))
+		})
+	})
+})
@@ -0,0 +1,198 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	v1admission "k8s.io/api/admission/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:webhook:path=/mutate-services-cloud-sap-com-v1-policy,mutating=true,failurePolicy=ignore,groups=services.cloud.sap.com,resources=serviceinstances;servicebindings,verbs=create;update,versions=v1,name=mpolicy.kb.io,sideEffects=None,admissionReviewVersions=v1beta1;v1
+
+const (
+	// PolicyFailurePolicyFail rejects the request if the policy service cannot be reached or does not respond properly
+	PolicyFailurePolicyFail = "Fail"
+	// PolicyFailurePolicyIgnore admits the request unchanged with a warning if the policy service cannot be reached
+	PolicyFailurePolicyIgnore = "Ignore"
+)
+
+var policyLog = logf.Log.WithName("policy-webhook")
+
+// ValidatePolicyFailurePolicy verifies that the configured failure policy is supported
+func ValidatePolicyFailurePolicy(failurePolicy string) error {
+	switch failurePolicy {
+	case PolicyFailurePolicyFail, PolicyFailurePolicyIgnore:
+		return nil
+	}
+	return fmt.Errorf("policy failure policy '%s' is not supported, supported values are %s and %s",
+		failurePolicy, PolicyFailurePolicyFail, PolicyFailurePolicyIgnore)
+}
+
+// PolicyRequest is sent to the policy service for each creation of an instance or binding and each update that
+// changes its spec
+type PolicyRequest struct {
+	Kind      string              `json:"kind"`
+	Operation string              `json:"operation"`
+	Namespace string              `json:"namespace"`
+	Name      string              `json:"name"`
+	Username  string              `json:"username"`
+	Groups    []string            `json:"groups,omitempty"`
+	Spec      json.RawMessage     `json:"spec"`
+	OldSpec   json.RawMessage     `json:"oldSpec,omitempty"`
+	Labels    map[string]string   `json:"labels,omitempty"`
+	Extra     map[string][]string `json:"extra,omitempty"`
+}
+
+// PolicyResponse is the decision of the policy service. A denied request is rejected with the message, an allowed
+// request is admitted after applying the patch, a JSON patch (RFC 6902) on the spec, if any.
+type PolicyResponse struct {
+	Allowed bool            `json:"allowed"`
+	Message string          `json:"message,omitempty"`
+	Patch   json.RawMessage `json:"patch,omitempty"`
+}
+
+// PolicyWebhook calls out to an external policy service, e.g. OPA, with the spec of the instances and bindings that are
+// created or updated, and admits, denies or mutates them according to its response
+type PolicyWebhook struct {
+	// Client calls the policy service, its timeout bounds the admission of the resources
+	Client *http.Client
+	URL    string
+	// FailurePolicy is Fail or Ignore
+	FailurePolicy string
+}
+
+func (p *PolicyWebhook) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Kind.Kind != "ServiceInstance" && req.Kind.Kind != "ServiceBinding" {
+		return admission.Allowed("")
+	}
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(req.Object.Raw, &obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok && metadata["deletionTimestamp"] != nil {
+		return admission.Allowed("")
+	}
+
+	policyRequest := PolicyRequest{
+		Kind:      req.Kind.Kind,
+		Operation: string(req.Operation),
+		Namespace: req.Namespace,
+		Name:      req.Name,
+		Username:  req.UserInfo.Username,
+		Groups:    req.UserInfo.Groups,
+		Labels:    getLabels(obj),
+	}
+	for key, values := range req.UserInfo.Extra {
+		if policyRequest.Extra == nil {
+			policyRequest.Extra = map[string][]string{}
+		}
+		policyRequest.Extra[key] = values
+	}
+	var err error
+	if policyRequest.Spec, err = json.Marshal(obj["spec"]); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if req.Operation == v1admission.Update {
+		oldObj := map[string]interface{}{}
+		if err := json.Unmarshal(req.OldObject.Raw, &oldObj); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		// the policy decides on the spec only, so updates of the operator (e.g. finalizers) are never blocked
+		if reflect.DeepEqual(obj["spec"], oldObj["spec"]) {
+			return admission.Allowed("")
+		}
+		if policyRequest.OldSpec, err = json.Marshal(oldObj["spec"]); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+
+	response, err := p.decide(ctx, policyRequest)
+	if err != nil {
+		policyLog.Error(err, "failed to call the policy service", "kind", req.Kind.Kind, "namespace", req.Namespace, "name", req.Name)
+		if p.FailurePolicy == PolicyFailurePolicyIgnore {
+			return admission.Allowed("").WithWarnings(fmt.Sprintf("the policy service was not consulted: %s", err.Error()))
+		}
+		return admission.Denied(fmt.Sprintf("the policy service could not be consulted: %s", err.Error()))
+	}
+	if !response.Allowed {
+		message := response.Message
+		if len(message) == 0 {
+			message = "denied by the policy service"
+		}
+		return admission.Denied(message)
+	}
+	if len(response.Patch) == 0 {
+		return admission.Allowed(response.Message)
+	}
+
+	patch, err := jsonpatch.DecodePatch(response.Patch)
+	if err != nil {
+		return admission.Denied(fmt.Sprintf("the policy service returned an invalid patch: %s", err.Error()))
+	}
+	patchedSpec, err := patch.Apply(policyRequest.Spec)
+	if err != nil {
+		return admission.Denied(fmt.Sprintf("the patch of the policy service does not apply to the spec: %s", err.Error()))
+	}
+	spec := map[string]interface{}{}
+	if err := json.Unmarshal(patchedSpec, &spec); err != nil {
+		return admission.Denied(fmt.Sprintf("the patch of the policy service does not result in a valid spec: %s", err.Error()))
+	}
+	obj["spec"] = spec
+	mutated, err := json.Marshal(obj)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, mutated)
+}
+
+// decide posts the request to the policy service and returns its response
+func (p *PolicyWebhook) decide(ctx context.Context, policyRequest PolicyRequest) (*PolicyResponse, error) {
+	body, err := json.Marshal(policyRequest)
+	if err != nil {
+		return nil, err
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpResponse, err := p.Client.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+	responseBody, err := io.ReadAll(io.LimitReader(httpResponse.Body, 1024*1024))
+	if err != nil {
+		return nil, err
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d: %s", httpResponse.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+	response := &PolicyResponse{}
+	if err := json.Unmarshal(responseBody, response); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return response, nil
+}
+
+func getLabels(obj map[string]interface{}) map[string]string {
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	labels, _ := metadata["labels"].(map[string]interface{})
+	if len(labels) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(labels))
+	for key, value := range labels {
+		result[key] = fmt.Sprintf("%v", value)
+	}
+	return result
+}
@@ -0,0 +1,116 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1admission "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("Policy Webhook", func() {
+	var (
+		server   *httptest.Server
+		webhook  *PolicyWebhook
+		instance *servicesv1.ServiceInstance
+		received []PolicyRequest
+		decision PolicyResponse
+	)
+
+	request := func(operation v1admission.Operation, obj, oldObj *servicesv1.ServiceInstance) admission.Request {
+		raw, err := json.Marshal(obj)
+		Expect(err).ToNot(HaveOccurred())
+		req := admission.Request{AdmissionRequest: v1admission.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Group: "services.cloud.sap.com", Version: "v1", Kind: "ServiceInstance"},
+			Namespace: obj.Namespace,
+			Name:      obj.Name,
+			Operation: operation,
+			Object:    runtime.RawExtension{Raw: raw},
+		}}
+		if oldObj != nil {
+			oldRaw, err := json.Marshal(oldObj)
+			Expect(err).ToNot(HaveOccurred())
+			req.OldObject = runtime.RawExtension{Raw: oldRaw}
+		}
+		return req
+	}
+
+	BeforeEach(func() {
+		received = nil
+		decision = PolicyResponse{Allowed: true}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			policyRequest := PolicyRequest{}
+			Expect(json.NewDecoder(r.Body).Decode(&policyRequest)).To(Succeed())
+			received = append(received, policyRequest)
+			Expect(json.NewEncoder(w).Encode(decision)).To(Succeed())
+		}))
+		webhook = &PolicyWebhook{Client: server.Client(), URL: server.URL, FailurePolicy: PolicyFailurePolicyFail}
+		instance = &servicesv1.ServiceInstance{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "services.cloud.sap.com/v1", Kind: "ServiceInstance"},
+			ObjectMeta: metav1.ObjectMeta{Name: "instance", Namespace: "test-ns"},
+			Spec:       servicesv1.ServiceInstanceSpec{ServiceOfferingName: "offering", ServicePlanName: "large"},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should send the spec to the policy service and admit allowed resources", func() {
+		response := webhook.Handle(context.Background(), request(v1admission.Create, instance, nil))
+		Expect(response.Allowed).To(BeTrue())
+		Expect(response.Patches).To(BeEmpty())
+		Expect(received).To(HaveLen(1))
+		Expect(received[0].Kind).To(Equal("ServiceInstance"))
+		Expect(received[0].Operation).To(Equal("CREATE"))
+		Expect(received[0].Namespace).To(Equal("test-ns"))
+		Expect(string(received[0].Spec)).To(ContainSubstring(`"servicePlanName":"large"`))
+	})
+
+	It("should deny resources with the message of the policy service", func() {
+		decision = PolicyResponse{Allowed: false, Message: "plan large is not allowed in test-ns"}
+		response := webhook.Handle(context.Background(), request(v1admission.Create, instance, nil))
+		Expect(response.Allowed).To(BeFalse())
+		Expect(response.Result.Message).To(Equal("plan large is not allowed in test-ns"))
+	})
+
+	It("should apply the patch of the policy service to the spec", func() {
+		decision = PolicyResponse{Allowed: true, Patch: []byte(`[{"op": "replace", "path": "/servicePlanName", "value": "small"}]`)}
+		response := webhook.Handle(context.Background(), request(v1admission.Create, instance, nil))
+		Expect(response.Allowed).To(BeTrue())
+		Expect(response.Patches).To(HaveLen(1))
+		Expect(response.Patches[0].Path).To(Equal("/spec/servicePlanName"))
+		Expect(response.Patches[0].Value).To(Equal("small"))
+	})
+
+	It("should not consult the policy service on updates that keep the spec", func() {
+		updated := instance.DeepCopy()
+		updated.Finalizers = []string{"finalizer"}
+		Expect(webhook.Handle(context.Background(), request(v1admission.Update, updated, instance)).Allowed).To(BeTrue())
+		Expect(received).To(BeEmpty())
+
+		updated.Spec.ServicePlanName = "small"
+		Expect(webhook.Handle(context.Background(), request(v1admission.Update, updated, instance)).Allowed).To(BeTrue())
+		Expect(received).To(HaveLen(1))
+		Expect(string(received[0].OldSpec)).To(ContainSubstring(`"servicePlanName":"large"`))
+	})
+
+	It("should apply the failure policy when the policy service is unavailable", func() {
+		server.Close()
+		response := webhook.Handle(context.Background(), request(v1admission.Create, instance, nil))
+		Expect(response.Allowed).To(BeFalse())
+		Expect(response.Result.Message).To(ContainSubstring("the policy service could not be consulted"))
+
+		webhook.FailurePolicy = PolicyFailurePolicyIgnore
+		response = webhook.Handle(context.Background(), request(v1admission.Create, instance, nil))
+		Expect(response.Allowed).To(BeTrue())
+		Expect(response.Warnings).To(ConsistOf(ContainSubstring("the policy service was not consulted")))
+	})
+})
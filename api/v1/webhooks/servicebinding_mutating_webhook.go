@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/SAP/sap-btp-service-operator/api"
 	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
 	v1admission "k8s.io/api/admission/v1"
 	v1 "k8s.io/api/authentication/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -18,9 +21,17 @@ var bindinglog = logf.Log.WithName("servicebinding-webhook")
 
 type ServiceBindingDefaulter struct {
 	Decoder *admission.Decoder
+	// OperatorUsername is the user of the operator, the only user that may label bindings as imported by an
+	// adoption run and approve them
+	OperatorUsername string
+	// GitOpsHints annotates bindings and their secrets, so that GitOps engines delete bindings before their instance
+	// and never prune the secrets
+	GitOpsHints bool
+	// Client reads the instance of a binding to order the binding after it
+	Client client.Reader
 }
 
-func (s *ServiceBindingDefaulter) Handle(_ context.Context, req admission.Request) admission.Response {
+func (s *ServiceBindingDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
 	bindinglog.Info("Defaulter webhook for servicebinding")
 	binding := &servicesv1.ServiceBinding{}
 	err := s.Decoder.Decode(req, binding)
@@ -28,6 +39,19 @@ func (s *ServiceBindingDefaulter) Handle(_ context.Context, req admission.Reques
 		return admission.Errored(http.StatusBadRequest, err)
 	}
 
+	if req.UserInfo.Username != s.OperatorUsername {
+		var oldBinding *servicesv1.ServiceBinding
+		if req.Operation == v1admission.Update {
+			oldBinding = &servicesv1.ServiceBinding{}
+			if err := s.Decoder.DecodeRaw(req.OldObject, oldBinding); err != nil {
+				return admission.Errored(http.StatusBadRequest, err)
+			}
+		}
+		if err := validateAdoptionMetadata(req.Operation, oldBinding, binding); err != nil {
+			return admission.Denied(err.Error())
+		}
+	}
+
 	// mutate the fields
 	if len(binding.Spec.ExternalName) == 0 {
 		bindinglog.Info("externalName not provided, defaulting to k8s name", "name", binding.Name)
@@ -48,6 +72,15 @@ func (s *ServiceBindingDefaulter) Handle(_ context.Context, req admission.Reques
 		}
 	}
 
+	if req.Operation == v1admission.Create {
+		// adding the finalizer on creation saves the reconciler an update round trip
+		controllerutil.AddFinalizer(binding, api.FinalizerName)
+	}
+
+	if s.GitOpsHints && (req.Operation == v1admission.Create || req.Operation == v1admission.Update) {
+		addBindingGitOpsHints(ctx, s.Client, binding, req.Operation == v1admission.Create)
+	}
+
 	if req.Operation == v1admission.Create || req.Operation == v1admission.Delete {
 		binding.Spec.UserInfo = &v1.UserInfo{
 			Username: req.UserInfo.Username,
@@ -0,0 +1,125 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1admission "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("ServiceBinding Defaulter", func() {
+	var (
+		defaulter *ServiceBindingDefaulter
+		binding   *servicesv1.ServiceBinding
+	)
+
+	const operatorUsername = "system:serviceaccount:sap-btp-operator:sap-btp-operator"
+
+	handleAs := func(username string, operation v1admission.Operation, oldBinding *servicesv1.ServiceBinding) admission.Response {
+		raw, err := json.Marshal(binding)
+		Expect(err).ToNot(HaveOccurred())
+		req := admission.Request{AdmissionRequest: v1admission.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Group: "services.cloud.sap.com", Version: "v1", Kind: "ServiceBinding"},
+			Namespace: binding.Namespace,
+			Operation: operation,
+			Object:    runtime.RawExtension{Raw: raw},
+		}}
+		req.UserInfo.Username = username
+		if operation == v1admission.Update {
+			oldRaw, err := json.Marshal(oldBinding)
+			Expect(err).ToNot(HaveOccurred())
+			req.OldObject = runtime.RawExtension{Raw: oldRaw}
+		}
+		return defaulter.Handle(context.Background(), req)
+	}
+
+	handle := func(operation v1admission.Operation) admission.Response {
+		return handleAs("user", operation, binding)
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(scheme)).To(Succeed())
+		defaulter = &ServiceBindingDefaulter{Decoder: admission.NewDecoder(scheme), OperatorUsername: operatorUsername}
+		binding = &servicesv1.ServiceBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "services.cloud.sap.com/v1", Kind: "ServiceBinding"},
+			ObjectMeta: metav1.ObjectMeta{Name: "binding", Namespace: "test-ns"},
+			Spec:       servicesv1.ServiceBindingSpec{ServiceInstanceName: "instance", ExternalName: "binding", SecretName: "binding"},
+		}
+	})
+
+	It("should add the finalizer on create", func() {
+		res := handle(v1admission.Create)
+		Expect(res.Allowed).To(BeTrue())
+		Expect(finalizerPatches(res)).To(ConsistOf(ContainSubstring(api.FinalizerName)))
+	})
+
+	It("should not add the finalizer twice on create", func() {
+		binding.Finalizers = []string{api.FinalizerName}
+		res := handle(v1admission.Create)
+		Expect(res.Allowed).To(BeTrue())
+		Expect(finalizerPatches(res)).To(BeEmpty())
+	})
+
+	It("should not touch the finalizers on update", func() {
+		binding.Finalizers = []string{api.FinalizerName}
+		res := handle(v1admission.Update)
+		Expect(res.Allowed).To(BeTrue())
+		Expect(finalizerPatches(res)).To(BeEmpty())
+	})
+
+	Context("adoption", func() {
+		var pending *servicesv1.ServiceBinding
+
+		BeforeEach(func() {
+			pending = binding.DeepCopy()
+			pending.Labels = map[string]string{api.AdoptionRunLabel: "import"}
+			pending.Annotations = map[string]string{api.AdoptionPendingAnnotation: "true"}
+		})
+
+		It("should let only the operator label bindings as imported by an adoption run", func() {
+			unlabeled := binding.DeepCopy()
+			binding.Labels = map[string]string{api.AdoptionRunLabel: "import"}
+			Expect(handle(v1admission.Create).Allowed).To(BeFalse())
+			Expect(handleAs(operatorUsername, v1admission.Create, nil).Allowed).To(BeTrue())
+			Expect(handleAs("user", v1admission.Update, unlabeled).Allowed).To(BeFalse())
+		})
+
+		It("should let only the operator approve imported bindings", func() {
+			binding.Labels = map[string]string{api.AdoptionRunLabel: "import"}
+			res := handleAs("user", v1admission.Update, pending)
+			Expect(res.Allowed).To(BeFalse())
+			Expect(res.Result.Message).To(ContainSubstring("set approveBindings in the run"))
+			Expect(handleAs(operatorUsername, v1admission.Update, pending).Allowed).To(BeTrue())
+		})
+
+		It("should allow other changes of imported bindings", func() {
+			binding = pending.DeepCopy()
+			binding.Spec.SecretName = "other-secret"
+			Expect(handleAs("user", v1admission.Update, pending).Allowed).To(BeTrue())
+		})
+	})
+})
+
+// finalizerPatches returns the patches of the response that change the finalizers
+func finalizerPatches(res admission.Response) []string {
+	var patches []string
+	for _, patch := range res.Patches {
+		if strings.HasPrefix(patch.Path, "/metadata/finalizers") {
+			raw, err := json.Marshal(patch)
+			Expect(err).ToNot(HaveOccurred())
+			patches = append(patches, string(raw))
+		}
+	}
+	return patches
+}
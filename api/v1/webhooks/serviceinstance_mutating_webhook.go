@@ -9,7 +9,9 @@ import (
 	v1admission "k8s.io/api/admission/v1"
 	v1 "k8s.io/api/authentication/v1"
 
+	"github.com/SAP/sap-btp-service-operator/api"
 	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -20,6 +22,11 @@ var instancelog = logf.Log.WithName("serviceinstance-webhook")
 
 type ServiceInstanceDefaulter struct {
 	Decoder *admission.Decoder
+	// OperatorUsername is the user of the operator, the only user that may label instances as imported by an
+	// adoption run
+	OperatorUsername string
+	// GitOpsHints annotates instances, so that GitOps engines delete them after their bindings
+	GitOpsHints bool
 }
 
 func (s *ServiceInstanceDefaulter) Handle(_ context.Context, req admission.Request) admission.Response {
@@ -30,12 +37,34 @@ func (s *ServiceInstanceDefaulter) Handle(_ context.Context, req admission.Reque
 		return admission.Errored(http.StatusBadRequest, err)
 	}
 
+	if req.UserInfo.Username != s.OperatorUsername {
+		var oldInstance *servicesv1.ServiceInstance
+		if req.Operation == v1admission.Update {
+			oldInstance = &servicesv1.ServiceInstance{}
+			if err := s.Decoder.DecodeRaw(req.OldObject, oldInstance); err != nil {
+				return admission.Errored(http.StatusBadRequest, err)
+			}
+		}
+		if err := validateAdoptionMetadata(req.Operation, oldInstance, instance); err != nil {
+			return admission.Denied(err.Error())
+		}
+	}
+
 	// mutate the fields
 	if len(instance.Spec.ExternalName) == 0 {
 		instancelog.Info("externalName not provided, defaulting to k8s name", "name", instance.Name)
 		instance.Spec.ExternalName = instance.Name
 	}
 
+	if req.Operation == v1admission.Create {
+		// adding the finalizer on creation saves the reconciler an update round trip
+		controllerutil.AddFinalizer(instance, api.FinalizerName)
+	}
+
+	if s.GitOpsHints && (req.Operation == v1admission.Create || req.Operation == v1admission.Update) {
+		addInstanceGitOpsHints(instance)
+	}
+
 	err = s.setServiceInstanceUserInfo(req, instance)
 	if err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
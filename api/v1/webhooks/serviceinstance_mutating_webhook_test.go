@@ -0,0 +1,70 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1admission "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("ServiceInstance Defaulter", func() {
+	var (
+		defaulter *ServiceInstanceDefaulter
+		instance  *servicesv1.ServiceInstance
+	)
+
+	handle := func(operation v1admission.Operation) admission.Response {
+		raw, err := json.Marshal(instance)
+		Expect(err).ToNot(HaveOccurred())
+		req := admission.Request{AdmissionRequest: v1admission.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Group: "services.cloud.sap.com", Version: "v1", Kind: "ServiceInstance"},
+			Namespace: instance.Namespace,
+			Operation: operation,
+			Object:    runtime.RawExtension{Raw: raw},
+		}}
+		if operation == v1admission.Update {
+			req.OldObject = runtime.RawExtension{Raw: raw}
+		}
+		return defaulter.Handle(context.Background(), req)
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(scheme)).To(Succeed())
+		defaulter = &ServiceInstanceDefaulter{Decoder: admission.NewDecoder(scheme)}
+		instance = &servicesv1.ServiceInstance{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "services.cloud.sap.com/v1", Kind: "ServiceInstance"},
+			ObjectMeta: metav1.ObjectMeta{Name: "instance", Namespace: "test-ns"},
+			Spec:       servicesv1.ServiceInstanceSpec{ServiceOfferingName: "offering", ServicePlanName: "plan", ExternalName: "instance"},
+		}
+	})
+
+	It("should add the finalizer on create", func() {
+		res := handle(v1admission.Create)
+		Expect(res.Allowed).To(BeTrue())
+		Expect(finalizerPatches(res)).To(ConsistOf(ContainSubstring(api.FinalizerName)))
+	})
+
+	It("should not add the finalizer twice on create", func() {
+		instance.Finalizers = []string{api.FinalizerName}
+		res := handle(v1admission.Create)
+		Expect(res.Allowed).To(BeTrue())
+		Expect(finalizerPatches(res)).To(BeEmpty())
+	})
+
+	It("should not touch the finalizers on update", func() {
+		instance.Finalizers = []string{api.FinalizerName}
+		res := handle(v1admission.Update)
+		Expect(res.Allowed).To(BeTrue())
+		Expect(finalizerPatches(res)).To(BeEmpty())
+	})
+})
@@ -27,17 +27,707 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdoptionCounts) DeepCopyInto(out *AdoptionCounts) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdoptionCounts.
+func (in *AdoptionCounts) DeepCopy() *AdoptionCounts {
+	if in == nil {
+		return nil
+	}
+	out := new(AdoptionCounts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdoptionRun) DeepCopyInto(out *AdoptionRun) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdoptionRun.
+func (in *AdoptionRun) DeepCopy() *AdoptionRun {
+	if in == nil {
+		return nil
+	}
+	out := new(AdoptionRun)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AdoptionRun) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdoptionRunList) DeepCopyInto(out *AdoptionRunList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AdoptionRun, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdoptionRunList.
+func (in *AdoptionRunList) DeepCopy() *AdoptionRunList {
+	if in == nil {
+		return nil
+	}
+	out := new(AdoptionRunList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AdoptionRunList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdoptionRunSpec) DeepCopyInto(out *AdoptionRunSpec) {
+	*out = *in
+	if in.ServiceOfferingNames != nil {
+		in, out := &in.ServiceOfferingNames, &out.ServiceOfferingNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.InstanceIDs != nil {
+		in, out := &in.InstanceIDs, &out.InstanceIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdoptionRunSpec.
+func (in *AdoptionRunSpec) DeepCopy() *AdoptionRunSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AdoptionRunSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdoptionRunStatus) DeepCopyInto(out *AdoptionRunStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Failures != nil {
+		in, out := &in.Failures, &out.Failures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdoptionRunStatus.
+func (in *AdoptionRunStatus) DeepCopy() *AdoptionRunStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AdoptionRunStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppliedClusterID) DeepCopyInto(out *AppliedClusterID) {
+	*out = *in
+	in.AppliedAt.DeepCopyInto(&out.AppliedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppliedClusterID.
+func (in *AppliedClusterID) DeepCopy() *AppliedClusterID {
+	if in == nil {
+		return nil
+	}
+	out := new(AppliedClusterID)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppliedOperatorConfig) DeepCopyInto(out *AppliedOperatorConfig) {
+	*out = *in
+	out.PollInterval = in.PollInterval
+	out.LongPollInterval = in.LongPollInterval
+	in.AppliedAt.DeepCopyInto(&out.AppliedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppliedOperatorConfig.
+func (in *AppliedOperatorConfig) DeepCopy() *AppliedOperatorConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(AppliedOperatorConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BindResource) DeepCopyInto(out *BindResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BindResource.
+func (in *BindResource) DeepCopy() *BindResource {
+	if in == nil {
+		return nil
+	}
+	out := new(BindResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BindingInjection) DeepCopyInto(out *BindingInjection) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BindingInjection.
+func (in *BindingInjection) DeepCopy() *BindingInjection {
+	if in == nil {
+		return nil
+	}
+	out := new(BindingInjection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BindingInjection) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BindingInjectionList) DeepCopyInto(out *BindingInjectionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BindingInjection, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BindingInjectionList.
+func (in *BindingInjectionList) DeepCopy() *BindingInjectionList {
+	if in == nil {
+		return nil
+	}
+	out := new(BindingInjectionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BindingInjectionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BindingInjectionSpec) DeepCopyInto(out *BindingInjectionSpec) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BindingInjectionSpec.
+func (in *BindingInjectionSpec) DeepCopy() *BindingInjectionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BindingInjectionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CSICredentialsTarget) DeepCopyInto(out *CSICredentialsTarget) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CSICredentialsTarget.
+func (in *CSICredentialsTarget) DeepCopy() *CSICredentialsTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(CSICredentialsTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapKeyReference) DeepCopyInto(out *ConfigMapKeyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapKeyReference.
+func (in *ConfigMapKeyReference) DeepCopy() *ConfigMapKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CredentialsTarget) DeepCopyInto(out *CredentialsTarget) {
+	*out = *in
+	if in.Vault != nil {
+		in, out := &in.Vault, &out.Vault
+		*out = new(VaultCredentialsTarget)
+		**out = **in
+	}
+	if in.CSI != nil {
+		in, out := &in.CSI, &out.CSI
+		*out = new(CSICredentialsTarget)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialsTarget.
+func (in *CredentialsTarget) DeepCopy() *CredentialsTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(CredentialsTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CredentialsRotationPolicy) DeepCopyInto(out *CredentialsRotationPolicy) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialsRotationPolicy.
-func (in *CredentialsRotationPolicy) DeepCopy() *CredentialsRotationPolicy {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialsRotationPolicy.
+func (in *CredentialsRotationPolicy) DeepCopy() *CredentialsRotationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CredentialsRotationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EncryptionKeyReference) DeepCopyInto(out *EncryptionKeyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EncryptionKeyReference.
+func (in *EncryptionKeyReference) DeepCopy() *EncryptionKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(EncryptionKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]Weekday, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceBTPStatus) DeepCopyInto(out *NamespaceBTPStatus) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceBTPStatus.
+func (in *NamespaceBTPStatus) DeepCopy() *NamespaceBTPStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceBTPStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NamespaceBTPStatus) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceBTPStatusList) DeepCopyInto(out *NamespaceBTPStatusList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NamespaceBTPStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceBTPStatusList.
+func (in *NamespaceBTPStatusList) DeepCopy() *NamespaceBTPStatusList {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceBTPStatusList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NamespaceBTPStatusList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceBTPStatusStatus) DeepCopyInto(out *NamespaceBTPStatusStatus) {
+	*out = *in
+	if in.RecentFailures != nil {
+		in, out := &in.RecentFailures, &out.RecentFailures
+		*out = make([]NamespaceOperationFailure, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ThrottledCredentials != nil {
+		in, out := &in.ThrottledCredentials, &out.ThrottledCredentials
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.LastUpdated.DeepCopyInto(&out.LastUpdated)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceBTPStatusStatus.
+func (in *NamespaceBTPStatusStatus) DeepCopy() *NamespaceBTPStatusStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceBTPStatusStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceOperationFailure) DeepCopyInto(out *NamespaceOperationFailure) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceOperationFailure.
+func (in *NamespaceOperationFailure) DeepCopy() *NamespaceOperationFailure {
 	if in == nil {
 		return nil
 	}
-	out := new(CredentialsRotationPolicy)
+	out := new(NamespaceOperationFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperationSLO) DeepCopyInto(out *OperationSLO) {
+	*out = *in
+	if in.TimeToReady != nil {
+		in, out := &in.TimeToReady, &out.TimeToReady
+		*out = new(TimeToReady)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperationSLO.
+func (in *OperationSLO) DeepCopy() *OperationSLO {
+	if in == nil {
+		return nil
+	}
+	out := new(OperationSLO)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfig) DeepCopyInto(out *OperatorConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfig.
+func (in *OperatorConfig) DeepCopy() *OperatorConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OperatorConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfigList) DeepCopyInto(out *OperatorConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]OperatorConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigList.
+func (in *OperatorConfigList) DeepCopy() *OperatorConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OperatorConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfigSpec) DeepCopyInto(out *OperatorConfigSpec) {
+	*out = *in
+	if in.PollInterval != nil {
+		in, out := &in.PollInterval, &out.PollInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.LongPollInterval != nil {
+		in, out := &in.LongPollInterval, &out.LongPollInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigSpec.
+func (in *OperatorConfigSpec) DeepCopy() *OperatorConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfigStatus) DeepCopyInto(out *OperatorConfigStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]AppliedOperatorConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ClusterIDHistory != nil {
+		in, out := &in.ClusterIDHistory, &out.ClusterIDHistory
+		*out = make([]AppliedClusterID, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfigStatus.
+func (in *OperatorConfigStatus) DeepCopy() *OperatorConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorSLOReport) DeepCopyInto(out *OperatorSLOReport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorSLOReport.
+func (in *OperatorSLOReport) DeepCopy() *OperatorSLOReport {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorSLOReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OperatorSLOReport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorSLOReportList) DeepCopyInto(out *OperatorSLOReportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]OperatorSLOReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorSLOReportList.
+func (in *OperatorSLOReportList) DeepCopy() *OperatorSLOReportList {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorSLOReportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OperatorSLOReportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorSLOReportSpec) DeepCopyInto(out *OperatorSLOReportSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorSLOReportSpec.
+func (in *OperatorSLOReportSpec) DeepCopy() *OperatorSLOReportSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorSLOReportSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorSLOReportStatus) DeepCopyInto(out *OperatorSLOReportStatus) {
+	*out = *in
+	out.Window = in.Window
+	in.GeneratedAt.DeepCopyInto(&out.GeneratedAt)
+	if in.Operations != nil {
+		in, out := &in.Operations, &out.Operations
+		*out = make([]OperationSLO, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorSLOReportStatus.
+func (in *OperatorSLOReportStatus) DeepCopy() *OperatorSLOReportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorSLOReportStatus)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -45,11 +735,21 @@ func (in *CredentialsRotationPolicy) DeepCopy() *CredentialsRotationPolicy {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ParametersFromSource) DeepCopyInto(out *ParametersFromSource) {
 	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(ConfigMapKeyReference)
+		**out = **in
+	}
 	if in.SecretKeyRef != nil {
 		in, out := &in.SecretKeyRef, &out.SecretKeyRef
 		*out = new(SecretKeyReference)
 		**out = **in
 	}
+	if in.ResourceFieldRef != nil {
+		in, out := &in.ResourceFieldRef, &out.ResourceFieldRef
+		*out = new(ResourceFieldReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ParametersFromSource.
@@ -62,6 +762,51 @@ func (in *ParametersFromSource) DeepCopy() *ParametersFromSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisionedServiceBinding) DeepCopyInto(out *ProvisionedServiceBinding) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionedServiceBinding.
+func (in *ProvisionedServiceBinding) DeepCopy() *ProvisionedServiceBinding {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisionedServiceBinding)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReadinessGate) DeepCopyInto(out *ReadinessGate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReadinessGate.
+func (in *ReadinessGate) DeepCopy() *ReadinessGate {
+	if in == nil {
+		return nil
+	}
+	out := new(ReadinessGate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceFieldReference) DeepCopyInto(out *ResourceFieldReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceFieldReference.
+func (in *ResourceFieldReference) DeepCopy() *ResourceFieldReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceFieldReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretKeyReference) DeepCopyInto(out *SecretKeyReference) {
 	*out = *in
@@ -72,7 +817,57 @@ func (in *SecretKeyReference) DeepCopy() *SecretKeyReference {
 	if in == nil {
 		return nil
 	}
-	out := new(SecretKeyReference)
+	out := new(SecretKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretOutput) DeepCopyInto(out *SecretOutput) {
+	*out = *in
+	if in.IncludeKeys != nil {
+		in, out := &in.IncludeKeys, &out.IncludeKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretOutput.
+func (in *SecretOutput) DeepCopy() *SecretOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretOwnerReference) DeepCopyInto(out *SecretOwnerReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretOwnerReference.
+func (in *SecretOwnerReference) DeepCopy() *SecretOwnerReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretOwnerReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretTemplateDelimiters) DeepCopyInto(out *SecretTemplateDelimiters) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretTemplateDelimiters.
+func (in *SecretTemplateDelimiters) DeepCopy() *SecretTemplateDelimiters {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretTemplateDelimiters)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -161,6 +956,18 @@ func (in *ServiceBindingSpec) DeepCopyInto(out *ServiceBindingSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ParametersFromInstance != nil {
+		in, out := &in.ParametersFromInstance, &out.ParametersFromInstance
+		*out = make(map[string]InstanceStatusField, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.BindResource != nil {
+		in, out := &in.BindResource, &out.BindResource
+		*out = new(BindResource)
+		**out = **in
+	}
 	if in.UserInfo != nil {
 		in, out := &in.UserInfo, &out.UserInfo
 		*out = new(authenticationv1.UserInfo)
@@ -171,6 +978,89 @@ func (in *ServiceBindingSpec) DeepCopyInto(out *ServiceBindingSpec) {
 		*out = new(CredentialsRotationPolicy)
 		**out = **in
 	}
+	if in.SecretTemplateDelimiters != nil {
+		in, out := &in.SecretTemplateDelimiters, &out.SecretTemplateDelimiters
+		*out = new(SecretTemplateDelimiters)
+		**out = **in
+	}
+	if in.SecretKeyMapping != nil {
+		in, out := &in.SecretKeyMapping, &out.SecretKeyMapping
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SecretExcludeKeys != nil {
+		in, out := &in.SecretExcludeKeys, &out.SecretExcludeKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecretLabels != nil {
+		in, out := &in.SecretLabels, &out.SecretLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SecretAnnotations != nil {
+		in, out := &in.SecretAnnotations, &out.SecretAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.TLSSecret != nil {
+		in, out := &in.TLSSecret, &out.TLSSecret
+		*out = new(TLSSecret)
+		**out = **in
+	}
+	if in.Secrets != nil {
+		in, out := &in.Secrets, &out.Secrets
+		*out = make([]SecretOutput, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CredentialsTarget != nil {
+		in, out := &in.CredentialsTarget, &out.CredentialsTarget
+		*out = new(CredentialsTarget)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReadinessGates != nil {
+		in, out := &in.ReadinessGates, &out.ReadinessGates
+		*out = make([]ReadinessGate, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecretOwnerRef != nil {
+		in, out := &in.SecretOwnerRef, &out.SecretOwnerRef
+		*out = new(SecretOwnerReference)
+		**out = **in
+	}
+	if in.SecretConsumers != nil {
+		in, out := &in.SecretConsumers, &out.SecretConsumers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImagePullServiceAccounts != nil {
+		in, out := &in.ImagePullServiceAccounts, &out.ImagePullServiceAccounts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecretTargetNamespaces != nil {
+		in, out := &in.SecretTargetNamespaces, &out.SecretTargetNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.EncryptKeys != nil {
+		in, out := &in.EncryptKeys, &out.EncryptKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.EncryptionKeyRef != nil {
+		in, out := &in.EncryptionKeyRef, &out.EncryptionKeyRef
+		*out = new(EncryptionKeyReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceBindingSpec.
@@ -197,6 +1087,43 @@ func (in *ServiceBindingStatus) DeepCopyInto(out *ServiceBindingStatus) {
 		in, out := &in.LastCredentialsRotationTime, &out.LastCredentialsRotationTime
 		*out = (*in).DeepCopy()
 	}
+	if in.NextCredentialsRotationTime != nil {
+		in, out := &in.NextCredentialsRotationTime, &out.NextCredentialsRotationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.SecretTemplateFields != nil {
+		in, out := &in.SecretTemplateFields, &out.SecretTemplateFields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImagePullServiceAccounts != nil {
+		in, out := &in.ImagePullServiceAccounts, &out.ImagePullServiceAccounts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecretTargetNamespaces != nil {
+		in, out := &in.SecretTargetNamespaces, &out.SecretTargetNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SharedInstance != nil {
+		in, out := &in.SharedInstance, &out.SharedInstance
+		*out = new(SharedInstanceInfo)
+		**out = **in
+	}
+	if in.Binding != nil {
+		in, out := &in.Binding, &out.Binding
+		*out = new(ProvisionedServiceBinding)
+		**out = **in
+	}
+	if in.LastReconcileTime != nil {
+		in, out := &in.LastReconcileTime, &out.LastReconcileTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSMRequestTime != nil {
+		in, out := &in.LastSMRequestTime, &out.LastSMRequestTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceBindingStatus.
@@ -276,6 +1203,11 @@ func (in *ServiceInstanceSpec) DeepCopyInto(out *ServiceInstanceSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.AllowedConsumers != nil {
+		in, out := &in.AllowedConsumers, &out.AllowedConsumers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Parameters != nil {
 		in, out := &in.Parameters, &out.Parameters
 		*out = new(runtime.RawExtension)
@@ -298,6 +1230,15 @@ func (in *ServiceInstanceSpec) DeepCopyInto(out *ServiceInstanceSpec) {
 		*out = new(authenticationv1.UserInfo)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.MaintenanceWindow != nil {
+		in, out := &in.MaintenanceWindow, &out.MaintenanceWindow
+		*out = new(MaintenanceWindow)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceInstanceSpec.
@@ -325,6 +1266,35 @@ func (in *ServiceInstanceStatus) DeepCopyInto(out *ServiceInstanceStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.AllowedConsumers != nil {
+		in, out := &in.AllowedConsumers, &out.AllowedConsumers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastMaintenanceCheck != nil {
+		in, out := &in.LastMaintenanceCheck, &out.LastMaintenanceCheck
+		*out = (*in).DeepCopy()
+	}
+	if in.LastDriftCheck != nil {
+		in, out := &in.LastDriftCheck, &out.LastDriftCheck
+		*out = (*in).DeepCopy()
+	}
+	if in.LastExpirationWarning != nil {
+		in, out := &in.LastExpirationWarning, &out.LastExpirationWarning
+		*out = (*in).DeepCopy()
+	}
+	if in.NextMaintenanceWindow != nil {
+		in, out := &in.NextMaintenanceWindow, &out.NextMaintenanceWindow
+		*out = (*in).DeepCopy()
+	}
+	if in.LastReconcileTime != nil {
+		in, out := &in.LastReconcileTime, &out.LastReconcileTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSMRequestTime != nil {
+		in, out := &in.LastSMRequestTime, &out.LastSMRequestTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceInstanceStatus.
@@ -336,3 +1306,233 @@ func (in *ServiceInstanceStatus) DeepCopy() *ServiceInstanceStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceOffering) DeepCopyInto(out *ServiceOffering) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceOffering.
+func (in *ServiceOffering) DeepCopy() *ServiceOffering {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceOffering)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ServiceOffering) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceOfferingList) DeepCopyInto(out *ServiceOfferingList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ServiceOffering, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceOfferingList.
+func (in *ServiceOfferingList) DeepCopy() *ServiceOfferingList {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceOfferingList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ServiceOfferingList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceOfferingSpec) DeepCopyInto(out *ServiceOfferingSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceOfferingSpec.
+func (in *ServiceOfferingSpec) DeepCopy() *ServiceOfferingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceOfferingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServicePlan) DeepCopyInto(out *ServicePlan) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServicePlan.
+func (in *ServicePlan) DeepCopy() *ServicePlan {
+	if in == nil {
+		return nil
+	}
+	out := new(ServicePlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ServicePlan) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServicePlanList) DeepCopyInto(out *ServicePlanList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ServicePlan, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServicePlanList.
+func (in *ServicePlanList) DeepCopy() *ServicePlanList {
+	if in == nil {
+		return nil
+	}
+	out := new(ServicePlanList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ServicePlanList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServicePlanSpec) DeepCopyInto(out *ServicePlanSpec) {
+	*out = *in
+	if in.Schemas != nil {
+		in, out := &in.Schemas, &out.Schemas
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServicePlanSpec.
+func (in *ServicePlanSpec) DeepCopy() *ServicePlanSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ServicePlanSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedInstanceInfo) DeepCopyInto(out *SharedInstanceInfo) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedInstanceInfo.
+func (in *SharedInstanceInfo) DeepCopy() *SharedInstanceInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedInstanceInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSSecret) DeepCopyInto(out *TLSSecret) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSSecret.
+func (in *TLSSecret) DeepCopy() *TLSSecret {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSSecret)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TimeToReady) DeepCopyInto(out *TimeToReady) {
+	*out = *in
+	out.P50 = in.P50
+	out.P90 = in.P90
+	out.P99 = in.P99
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TimeToReady.
+func (in *TimeToReady) DeepCopy() *TimeToReady {
+	if in == nil {
+		return nil
+	}
+	out := new(TimeToReady)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultCredentialsTarget) DeepCopyInto(out *VaultCredentialsTarget) {
+	*out = *in
+	out.Auth = in.Auth
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultCredentialsTarget.
+func (in *VaultCredentialsTarget) DeepCopy() *VaultCredentialsTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultCredentialsTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultKubernetesAuth) DeepCopyInto(out *VaultKubernetesAuth) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultKubernetesAuth.
+func (in *VaultKubernetesAuth) DeepCopy() *VaultKubernetesAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultKubernetesAuth)
+	in.DeepCopyInto(out)
+	return out
+}
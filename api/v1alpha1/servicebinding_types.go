@@ -190,6 +190,7 @@ type ServiceBindingList struct {
 }
 
 type CredentialsRotationPolicy struct {
+	// Whether the credentials of the binding are rotated automatically.
 	Enabled bool `json:"enabled"`
 	// What frequency to perform binding rotation.
 	RotationFrequency string `json:"rotationFrequency,omitempty"`
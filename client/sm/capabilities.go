@@ -0,0 +1,139 @@
+package sm
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/client/sm/types"
+)
+
+// Feature is an optional capability of the Service Manager API
+type Feature string
+
+const (
+	// FeatureAttachLastOperations is the attach_last_operations query parameter of list requests
+	FeatureAttachLastOperations Feature = "attach_last_operations"
+	// FeatureSharedInstances is the sharing of service instances
+	FeatureSharedInstances Feature = "shared_instances"
+
+	infoURL = "/v1/info"
+	// UnknownAPIVersion is reported when the Service Manager does not expose its API version
+	UnknownAPIVersion = "unknown"
+
+	capabilitiesTTL = 30 * time.Minute
+)
+
+// Capabilities are the API version and the optional features of a Service Manager
+type Capabilities struct {
+	URL        string
+	APIVersion string
+	Features   map[Feature]bool
+	detectedAt time.Time
+	// complete is false if a probe failed, such capabilities are not cached and detected again on next use
+	complete bool
+}
+
+// Supports returns true if the feature was detected as supported, features which were not detected are not supported
+func (c *Capabilities) Supports(feature Feature) bool {
+	if c == nil {
+		return false
+	}
+	return c.Features[feature]
+}
+
+// Detected returns true if the Service Manager either accepted or rejected the feature, a feature whose probe failed is
+// not detected and its support is unknown
+func (c *Capabilities) Detected(feature Feature) bool {
+	if c == nil {
+		return false
+	}
+	_, detected := c.Features[feature]
+	return detected
+}
+
+type info struct {
+	APIVersion string `json:"api_version,omitempty"`
+}
+
+// capabilitiesKey identifies a tenant of a Service Manager, the client ID of the access credentials is issued per subaccount
+type capabilitiesKey struct {
+	url      string
+	clientID string
+}
+
+// capabilitiesCache holds the capabilities per Service Manager URL and subaccount, the clients are created per reconcile
+// and the Service Manager of every tenant is detected once per capabilitiesTTL
+var capabilitiesCache sync.Map
+
+// Capabilities detects the API version and the optional features of the Service Manager on first use
+func (client *serviceManagerClient) Capabilities() *Capabilities {
+	key := capabilitiesKey{url: client.Config.URL, clientID: client.Config.ClientID}
+	if cached, ok := capabilitiesCache.Load(key); ok {
+		if capabilities := cached.(*Capabilities); time.Since(capabilities.detectedAt) < capabilitiesTTL {
+			return capabilities
+		}
+	}
+
+	capabilities := client.detectCapabilities()
+	if capabilities.complete {
+		capabilitiesCache.Store(key, capabilities)
+	}
+	return capabilities
+}
+
+// detectCapabilities probes the optional features with list requests, older Service Manager versions reject unknown
+// query parameters and fields
+func (client *serviceManagerClient) detectCapabilities() *Capabilities {
+	capabilities := &Capabilities{
+		URL:        client.Config.URL,
+		APIVersion: UnknownAPIVersion,
+		Features:   map[Feature]bool{},
+		detectedAt: time.Now(),
+		complete:   true,
+	}
+
+	smInfo := &info{}
+	if err := client.get(smInfo, infoURL, nil); err == nil {
+		if len(smInfo.APIVersion) > 0 {
+			capabilities.APIVersion = smInfo.APIVersion
+		}
+	} else if !isDefinitive(err) {
+		capabilities.complete = false
+	}
+
+	client.probeFeature(capabilities, FeatureAttachLastOperations, &Parameters{GeneralParams: []string{"attach_last_operations=true"}})
+	client.probeFeature(capabilities, FeatureSharedInstances, &Parameters{FieldQuery: []string{"shared eq true"}})
+	return capabilities
+}
+
+// probeFeature lists a single service instance with the query parameters of the feature, the feature is recorded
+// only if the Service Manager accepted or rejected the parameters, any other answer leaves the capabilities incomplete
+func (client *serviceManagerClient) probeFeature(capabilities *Capabilities, feature Feature, params *Parameters) {
+	params.GeneralParams = append(params.GeneralParams, "max_items=1")
+	response, err := client.Call(http.MethodGet, types.ServiceInstancesURL, nil, params)
+	if err != nil {
+		capabilities.complete = false
+		return
+	}
+	response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK:
+		capabilities.Features[feature] = true
+	case http.StatusBadRequest, http.StatusNotFound:
+		capabilities.Features[feature] = false
+	default:
+		capabilities.complete = false
+	}
+}
+
+// isDefinitive returns true if the Service Manager answered that it does not know the request, older versions do not
+// expose their API version
+func isDefinitive(err error) bool {
+	var smError *ServiceManagerError
+	if errors.As(err, &smError) {
+		return smError.StatusCode == http.StatusBadRequest || smError.StatusCode == http.StatusNotFound
+	}
+	return false
+}
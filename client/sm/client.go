@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -27,6 +28,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/SAP/sap-btp-service-operator/api"
 	"github.com/SAP/sap-btp-service-operator/client/sm/types"
@@ -46,15 +48,18 @@ const (
 type Client interface {
 	ListInstances(*Parameters) (*types.ServiceInstances, error)
 	GetInstanceByID(string, *Parameters) (*types.ServiceInstance, error)
+	GetInstanceParameters(id string, q *Parameters) (map[string]interface{}, error)
 	UpdateInstance(id string, updatedInstance *types.ServiceInstance, serviceName string, planName string, q *Parameters, user string, dataCenter string) (*types.ServiceInstance, string, error)
 	Provision(instance *types.ServiceInstance, serviceName string, planName string, q *Parameters, user string, dataCenter string) (*ProvisionResponse, error)
 	Deprovision(id string, q *Parameters, user string) (string, error)
+	UpdateInstanceLabels(id string, labelChanges []*types.LabelChange, user string) error
 
 	ListBindings(*Parameters) (*types.ServiceBindings, error)
 	GetBindingByID(string, *Parameters) (*types.ServiceBinding, error)
 	Bind(binding *types.ServiceBinding, q *Parameters, user string) (*types.ServiceBinding, string, error)
 	Unbind(id string, q *Parameters, user string) (string, error)
-	RenameBinding(id, newName, newK8SName string) (*types.ServiceBinding, error)
+	// RenameBinding renames the binding and replaces the value of its k8sNameLabel, the label is not changed if k8sNameLabel is empty
+	RenameBinding(id, newName, k8sNameLabel, newK8SName string) (*types.ServiceBinding, error)
 	ShareInstance(id string, user string) error
 	UnShareInstance(id string, user string) error
 
@@ -63,6 +68,10 @@ type Client interface {
 
 	Status(string, *Parameters) (*types.Operation, error)
 
+	// Capabilities returns the API version and the optional features of the Service Manager,
+	// nil means that all features are assumed to be supported
+	Capabilities() *Capabilities
+
 	// Call makes HTTP request to the Service Manager server with authentication.
 	// It should be used only in case there is no already implemented method for such an operation
 	Call(method string, smpath string, body io.Reader, q *Parameters) (*http.Response, error)
@@ -105,6 +114,8 @@ type planInfo struct {
 type ProvisionResponse struct {
 	InstanceID   string
 	PlanID       string
+	OfferingID   string
+	BrokerID     string
 	Location     string
 	SubaccountID string
 	Tags         json.RawMessage
@@ -115,24 +126,26 @@ func NewClient(ctx context.Context, config *ClientConfig, httpClient auth.HTTPCl
 	if httpClient != nil {
 		return &serviceManagerClient{Context: ctx, Config: config, HTTPClient: httpClient}, nil
 	}
+	authClient, err := NewAuthClient(config, config.OnRequest)
+	if err != nil {
+		return nil, err
+	}
+	return &serviceManagerClient{Context: ctx, Config: config, HTTPClient: authClient}, nil
+}
+
+// NewAuthClient returns an HTTP client which fetches and refreshes the token of the configured credentials, it can be
+// shared by the clients of the credentials. onTokenRequest is called for each request to the token endpoint if set.
+func NewAuthClient(config *ClientConfig, onTokenRequest func()) (auth.HTTPClient, error) {
 	ccConfig := &clientcredentials.Config{
 		ClientID:     config.ClientID,
 		ClientSecret: config.ClientSecret,
 		TokenURL:     config.TokenURL + config.TokenURLSuffix,
 		AuthStyle:    oauth2.AuthStyleInParams,
 	}
-
-	var authClient auth.HTTPClient
-	var err error
 	if len(config.TLSCertKey) > 0 && len(config.TLSPrivateKey) > 0 {
-		authClient, err = auth.NewAuthClientWithTLS(ccConfig, config.TLSCertKey, config.TLSPrivateKey)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		authClient = auth.NewAuthClient(ccConfig, config.SSLDisabled)
+		return auth.NewAuthClientWithTLS(ccConfig, config.TLSCertKey, config.TLSPrivateKey, onTokenRequest)
 	}
-	return &serviceManagerClient{Context: ctx, Config: config, HTTPClient: authClient}, nil
+	return auth.NewAuthClient(ccConfig, config.SSLDisabled, onTokenRequest), nil
 }
 
 // Provision provisions a new service instance in service manager
@@ -174,6 +187,8 @@ func (client *serviceManagerClient) Provision(instance *types.ServiceInstance, s
 	}
 
 	if planInfo.serviceOffering != nil {
+		res.OfferingID = planInfo.serviceOffering.ID
+		res.BrokerID = planInfo.serviceOffering.BrokerID
 		res.Tags = planInfo.serviceOffering.Tags
 	}
 
@@ -206,6 +221,14 @@ func (client *serviceManagerClient) GetInstanceByID(id string, q *Parameters) (*
 	return instance, err
 }
 
+// GetInstanceParameters returns the parameters of the instance as stored by the broker
+func (client *serviceManagerClient) GetInstanceParameters(id string, q *Parameters) (map[string]interface{}, error) {
+	parameters := make(map[string]interface{})
+	err := client.get(&parameters, types.ServiceInstancesURL+"/"+id+"/parameters", q)
+
+	return parameters, err
+}
+
 // ListBindings returns service bindings registered in the Service Manager satisfying provided queries
 func (client *serviceManagerClient) ListBindings(q *Parameters) (*types.ServiceBindings, error) {
 	bindings := &types.ServiceBindings{}
@@ -237,14 +260,18 @@ func (client *serviceManagerClient) Unbind(id string, q *Parameters, user string
 	return client.delete(types.ServiceBindingsURL+"/"+id, q, user)
 }
 
+// UpdateInstance updates the instance in service manager, the plan is resolved from the service and plan names
+// unless planName is empty, then the ServicePlanID of updatedInstance is sent as is
 func (client *serviceManagerClient) UpdateInstance(id string, updatedInstance *types.ServiceInstance, serviceName string, planName string, q *Parameters, user string, dataCenter string) (*types.ServiceInstance, string, error) {
 	var result *types.ServiceInstance
 
-	planInfo, err := client.getPlanInfo(updatedInstance.ServicePlanID, serviceName, planName, dataCenter)
-	if err != nil {
-		return nil, "", err
+	if len(planName) > 0 {
+		planInfo, err := client.getPlanInfo(updatedInstance.ServicePlanID, serviceName, planName, dataCenter)
+		if err != nil {
+			return nil, "", err
+		}
+		updatedInstance.ServicePlanID = planInfo.planID
 	}
-	updatedInstance.ServicePlanID = planInfo.planID
 	location, err := client.update(updatedInstance, types.ServiceInstancesURL, id, q, user, &result)
 	if err != nil {
 		return nil, "", err
@@ -252,26 +279,38 @@ func (client *serviceManagerClient) UpdateInstance(id string, updatedInstance *t
 	return result, location, nil
 }
 
-func (client *serviceManagerClient) RenameBinding(id, newName, newK8SName string) (*types.ServiceBinding, error) {
-	const k8sNameLabel = "_k8sname"
+// UpdateInstanceLabels applies the label changes to the instance in service manager
+func (client *serviceManagerClient) UpdateInstanceLabels(id string, labelChanges []*types.LabelChange, user string) error {
+	updateRequest := map[string]interface{}{
+		"labels": labelChanges,
+	}
+
+	var result *types.ServiceInstance
+	_, err := client.update(updateRequest, types.ServiceInstancesURL, id, nil, user, &result)
+	return err
+}
+
+func (client *serviceManagerClient) RenameBinding(id, newName, k8sNameLabel, newK8SName string) (*types.ServiceBinding, error) {
 	renameRequest := map[string]interface{}{
 		"name": newName,
-		"labels": []*types.LabelChange{
+	}
+	if len(k8sNameLabel) > 0 {
+		renameRequest["labels"] = []*types.LabelChange{
 			{
 				Key:       k8sNameLabel,
 				Operation: types.RemoveLabelOperation,
 			},
-		},
+		}
 	}
 
 	var result *types.ServiceBinding
 	_, err := client.update(renameRequest, types.ServiceBindingsURL, id, nil, "", &result)
-	if err != nil {
-		return nil, err
+	if err != nil || len(k8sNameLabel) == 0 {
+		return result, err
 	}
 
 	// due to sm issue (no "replace" label and remove+add not supported in the same request)
-	// adding the new _k8sname value in another request
+	// adding the new k8s name label value in another request
 	addLabelRequest := map[string]interface{}{
 		"labels": []*types.LabelChange{
 			{
@@ -440,23 +479,61 @@ func (client *serviceManagerClient) Call(method string, smpath string, body io.R
 func (client *serviceManagerClient) callWithUser(method string, smpath string, body io.Reader, q *Parameters, user string) (*http.Response, error) {
 	fullURL := httputil.NormalizeURL(client.Config.URL) + BuildURL(smpath, q)
 
-	req, err := http.NewRequest(method, fullURL, body)
+	// the calls are cancelled with the context of the client, e.g. of the reconcile, and bounded by their budget
+	ctx := client.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	budget := callBudget(method, smpath)
+	timeout := client.Config.Timeouts.For(budget)
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 	req.Header.Add("Content-Type", "application/json")
 	if len(user) > 0 {
 		req.Header.Add(originatingIdentityHeader, user)
 	}
+	if client.Config.OnRequest != nil {
+		client.Config.OnRequest()
+	}
 
 	resp, err := client.HTTPClient.Do(req)
 	if err != nil {
+		timedOut := timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded)
+		cancel()
+		if timedOut {
+			return nil, client.timeoutError(budget, timeout)
+		}
 		return nil, err
 	}
+	respBody := &cancelOnClose{ReadCloser: resp.Body, ctx: ctx, cancel: cancel}
+	if timeout > 0 {
+		respBody.timedOut = func() error { return client.timeoutError(budget, timeout) }
+	}
+	resp.Body = respBody
 
 	return resp, nil
 }
 
+// timeoutError reports a call that exceeded the timeout of its budget. The request may have reached Service Manager, so
+// the error is transient: the resource is looked up by its labels before the call is retried.
+func (client *serviceManagerClient) timeoutError(budget CallBudget, timeout time.Duration) error {
+	if client.Config.OnTimeout != nil {
+		client.Config.OnTimeout(budget)
+	}
+	return &ServiceManagerError{
+		StatusCode:  http.StatusGatewayTimeout,
+		Description: fmt.Sprintf("the %s call to Service Manager timed out after %s", budget, timeout),
+	}
+}
+
 func (client *serviceManagerClient) getPlanInfo(planID string, serviceName string, planName string, dataCenter string) (*planInfo, error) {
 
 	offerings, err := client.getServiceOfferingsByNameAndDataCenter(serviceName, dataCenter)
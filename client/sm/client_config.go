@@ -26,4 +26,10 @@ type ClientConfig struct {
 	TLSCertKey     string
 	TLSPrivateKey  string
 	SSLDisabled    bool
+	// OnRequest is called for each request sent to Service Manager, e.g. to count the calls made with the credentials
+	OnRequest func()
+	// Timeouts bound the calls to Service Manager by budget
+	Timeouts Timeouts
+	// OnTimeout is called for each call to Service Manager that exceeded the timeout of its budget
+	OnTimeout func(budget CallBudget)
 }
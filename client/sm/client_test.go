@@ -3,7 +3,10 @@ package sm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/http/httptest"
+	"time"
 
 	"github.com/SAP/sap-btp-service-operator/client/sm/types"
 	. "github.com/onsi/ginkgo"
@@ -103,6 +106,28 @@ var _ = Describe("Client test", func() {
 					Expect(err).ShouldNot(HaveOccurred())
 					Expect(result).To(Equal(instance))
 				})
+				It("should notify OnRequest", func() {
+					requests := 0
+					observedClient, err := NewClient(context.TODO(), &ClientConfig{URL: smServer.URL, OnRequest: func() { requests++ }}, fakeAuthClient)
+					Expect(err).ToNot(HaveOccurred())
+					_, err = observedClient.GetInstanceByID(instance.ID, params)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(requests).To(Equal(1))
+				})
+				It("should notify OnRequest of the token requests", func() {
+					tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Set("Content-Type", "application/json")
+						_, _ = w.Write([]byte(`{"access_token": "` + validToken + `", "token_type": "bearer", "expires_in": 3600}`))
+					}))
+					defer tokenServer.Close()
+
+					requests := 0
+					observedClient, err := NewClient(context.TODO(), &ClientConfig{URL: smServer.URL, TokenURL: tokenServer.URL, ClientID: "id", ClientSecret: "secret", OnRequest: func() { requests++ }}, nil)
+					Expect(err).ToNot(HaveOccurred())
+					_, err = observedClient.GetInstanceByID(instance.ID, params)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(requests).To(Equal(2))
+				})
 			})
 
 			Context("When there is no instance with this id", func() {
@@ -144,6 +169,33 @@ var _ = Describe("Client test", func() {
 			})
 		})
 
+		Describe("Get service instance parameters", func() {
+			Context("When the instance has parameters", func() {
+				BeforeEach(func() {
+					handlerDetails = []HandlerDetails{
+						{Method: http.MethodGet, Path: types.ServiceInstancesURL + "/" + instance.ID + "/parameters", ResponseBody: []byte(`{"key": "value"}`), ResponseStatusCode: http.StatusOK},
+					}
+				})
+				It("should return them", func() {
+					result, err := client.GetInstanceParameters(instance.ID, params)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(result).To(Equal(map[string]interface{}{"key": "value"}))
+				})
+			})
+
+			Context("When the parameters cannot be retrieved", func() {
+				BeforeEach(func() {
+					handlerDetails = []HandlerDetails{
+						{Method: http.MethodGet, Path: types.ServiceInstancesURL + "/" + instance.ID + "/parameters", ResponseStatusCode: http.StatusBadRequest},
+					}
+				})
+				It("should return error", func() {
+					_, err := client.GetInstanceParameters(instance.ID, params)
+					expectErrorToContainSubstringAndStatusCode(err, "", http.StatusBadRequest)
+				})
+			})
+		})
+
 		Describe("Provision", func() {
 			BeforeEach(func() {
 				instanceResponseBody, _ := json.Marshal(instance)
@@ -151,6 +203,7 @@ var _ = Describe("Client test", func() {
 					ID:          "service_id",
 					Name:        serviceName,
 					CatalogName: serviceName,
+					BrokerID:    "broker_id",
 				}
 				plan := &types.ServicePlan{
 					ID:                planID,
@@ -180,6 +233,9 @@ var _ = Describe("Client test", func() {
 					Expect(err).ShouldNot(HaveOccurred())
 					Expect(res.Location).Should(HaveLen(0))
 					Expect(res.InstanceID).To(Equal(instance.ID))
+					Expect(res.PlanID).To(Equal(planID))
+					Expect(res.OfferingID).To(Equal(serviceID))
+					Expect(res.BrokerID).To(Equal("broker_id"))
 				})
 
 				Context("When multiple matching plan names returned from SM", func() {
@@ -440,6 +496,23 @@ var _ = Describe("Client test", func() {
 				})
 			})
 
+			Context("When the plan is not given by its name", func() {
+				BeforeEach(func() {
+					responseBody, _ := json.Marshal(instance)
+					handlerDetails = []HandlerDetails{
+						{Method: http.MethodPatch, Path: types.ServiceInstancesURL + "/" + instance.ID, ResponseBody: responseBody, ResponseStatusCode: http.StatusOK},
+					}
+				})
+				It("should send the plan ID without resolving it", func() {
+					planID := instance.ServicePlanID
+					responseInstance, _, err := client.UpdateInstance(instance.ID, instance, "", "", params, "test-user", "")
+
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(responseInstance).To(Equal(instance))
+					Expect(instance.ServicePlanID).To(Equal(planID))
+				})
+			})
+
 			Context("When valid instance is being updated asynchronously", func() {
 				var locationHeader string
 				BeforeEach(func() {
@@ -532,6 +605,34 @@ var _ = Describe("Client test", func() {
 				})
 			})
 		})
+
+		Describe("Update labels", func() {
+			labelChanges := []*types.LabelChange{{Key: "key", Operation: types.AddLabelOperation, Values: []string{"value"}}}
+			Context("When the labels are updated successfully", func() {
+				BeforeEach(func() {
+					responseBody, _ := json.Marshal(instance)
+					handlerDetails = []HandlerDetails{
+						{Method: http.MethodPatch, Path: types.ServiceInstancesURL + "/" + instance.ID, ResponseBody: responseBody, ResponseStatusCode: http.StatusOK},
+					}
+				})
+				It("should succeed", func() {
+					err := client.UpdateInstanceLabels(instance.ID, labelChanges, "test-user")
+					Expect(err).ShouldNot(HaveOccurred())
+				})
+			})
+
+			Context("When service manager returns an error", func() {
+				BeforeEach(func() {
+					handlerDetails = []HandlerDetails{
+						{Method: http.MethodPatch, Path: types.ServiceInstancesURL + "/" + instance.ID, ResponseBody: []byte(`{ "description": "description"}`), ResponseStatusCode: http.StatusBadRequest},
+					}
+				})
+				It("should return error", func() {
+					err := client.UpdateInstanceLabels(instance.ID, labelChanges, "test-user")
+					expectErrorToContainSubstringAndStatusCode(err, "description", http.StatusBadRequest)
+				})
+			})
+		})
 	})
 
 	Describe("Bindings", func() {
@@ -939,7 +1040,13 @@ TSTAhYWEQVZqRKYQMYGHpNlU
 			})
 
 			It("should rename binding", func() {
-				res, err := client.RenameBinding(binding.ID, "newname", "newk8sname")
+				res, err := client.RenameBinding(binding.ID, "newname", "_k8sname", "newk8sname")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res.ID).To(Equal("bindingID"))
+			})
+
+			It("should rename binding without k8s name label", func() {
+				res, err := client.RenameBinding(binding.ID, "newname", "", "newk8sname")
 				Expect(err).ToNot(HaveOccurred())
 				Expect(res.ID).To(Equal("bindingID"))
 			})
@@ -972,6 +1079,79 @@ TSTAhYWEQVZqRKYQMYGHpNlU
 			Expect(result).To(Equal(operation))
 		})
 	})
+
+	Describe("Capabilities", func() {
+		When("the Service Manager accepts the probes", func() {
+			BeforeEach(func() {
+				responseBody, _ := json.Marshal(info{APIVersion: "2.1"})
+				handlerDetails = []HandlerDetails{
+					{Method: http.MethodGet, Path: infoURL, ResponseBody: responseBody, ResponseStatusCode: http.StatusOK},
+					{Method: http.MethodGet, Path: types.ServiceInstancesURL, ResponseBody: []byte(`{"items":[]}`), ResponseStatusCode: http.StatusOK},
+				}
+			})
+
+			It("should return the detected capabilities", func() {
+				capabilities := client.Capabilities()
+				Expect(capabilities.APIVersion).To(Equal("2.1"))
+				Expect(capabilities.Supports(FeatureAttachLastOperations)).To(BeTrue())
+				Expect(capabilities.Supports(FeatureSharedInstances)).To(BeTrue())
+				Expect(client.Capabilities()).To(BeIdenticalTo(capabilities))
+			})
+		})
+
+		When("the Service Manager rejects the probes", func() {
+			BeforeEach(func() {
+				handlerDetails = []HandlerDetails{
+					{Method: http.MethodGet, Path: types.ServiceInstancesURL, ResponseStatusCode: http.StatusBadRequest},
+				}
+			})
+
+			It("should not support the features", func() {
+				capabilities := client.Capabilities()
+				Expect(capabilities.APIVersion).To(Equal(UnknownAPIVersion))
+				Expect(capabilities.Supports(FeatureAttachLastOperations)).To(BeFalse())
+				Expect(capabilities.Detected(FeatureAttachLastOperations)).To(BeTrue())
+				Expect(capabilities.Supports(FeatureSharedInstances)).To(BeFalse())
+				Expect(client.Capabilities()).To(BeIdenticalTo(capabilities))
+			})
+		})
+
+		When("the Service Manager does not know the probes", func() {
+			BeforeEach(func() {
+				handlerDetails = []HandlerDetails{
+					{Method: http.MethodGet, Path: types.ServiceInstancesURL, ResponseStatusCode: http.StatusNotFound},
+				}
+			})
+
+			It("should not support the features", func() {
+				capabilities := client.Capabilities()
+				Expect(capabilities.Supports(FeatureSharedInstances)).To(BeFalse())
+				Expect(capabilities.Detected(FeatureSharedInstances)).To(BeTrue())
+				Expect(client.Capabilities()).To(BeIdenticalTo(capabilities))
+			})
+		})
+
+		When("the probes fail", func() {
+			BeforeEach(func() {
+				handlerDetails = []HandlerDetails{
+					{Method: http.MethodGet, Path: types.ServiceInstancesURL, ResponseStatusCode: http.StatusBadGateway},
+				}
+			})
+
+			It("should not detect the features and detect them again", func() {
+				capabilities := client.Capabilities()
+				Expect(capabilities.Supports(FeatureAttachLastOperations)).To(BeFalse())
+				Expect(capabilities.Detected(FeatureAttachLastOperations)).To(BeFalse())
+				Expect(capabilities.Detected(FeatureSharedInstances)).To(BeFalse())
+				Expect(client.Capabilities()).NotTo(BeIdenticalTo(capabilities))
+			})
+		})
+
+		It("should not support features without capabilities", func() {
+			var capabilities *Capabilities
+			Expect(capabilities.Supports(FeatureAttachLastOperations)).To(BeFalse())
+		})
+	})
 })
 
 func expectErrorToContainSubstringAndStatusCode(err error, substring string, statusCode int) {
@@ -979,3 +1159,90 @@ func expectErrorToContainSubstringAndStatusCode(err error, substring string, sta
 	Expect(err.Error()).To(ContainSubstring(substring))
 	Expect(err.(*ServiceManagerError).StatusCode).To(Equal(statusCode))
 }
+
+var _ = Describe("Timeouts", func() {
+	var slowServer *httptest.Server
+
+	BeforeEach(func() {
+		slowServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+	})
+
+	AfterEach(func() {
+		slowServer.Close()
+	})
+
+	It("should cancel the calls that exceed the timeout of their budget", func() {
+		var timedOut []CallBudget
+		slowClient, err := NewClient(context.TODO(), &ClientConfig{
+			URL:       slowServer.URL,
+			Timeouts:  Timeouts{Poll: 50 * time.Millisecond},
+			OnTimeout: func(budget CallBudget) { timedOut = append(timedOut, budget) },
+		}, fakeAuthClient)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = slowClient.GetInstanceByID("instanceID", nil)
+		Expect(err).To(MatchError("the poll call to Service Manager timed out after 50ms"))
+		Expect(timedOut).To(Equal([]CallBudget{PollBudget}))
+	})
+
+	It("should fail a timed out call with a transient error, the request may have reached Service Manager", func() {
+		slowClient, err := NewClient(context.TODO(), &ClientConfig{URL: slowServer.URL, Timeouts: Timeouts{Bind: 50 * time.Millisecond}}, fakeAuthClient)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, _, err = slowClient.Bind(&types.ServiceBinding{Name: "binding"}, nil, "")
+		var smError *ServiceManagerError
+		Expect(errors.As(err, &smError)).To(BeTrue())
+		Expect(smError.StatusCode).To(Equal(http.StatusGatewayTimeout))
+	})
+
+	It("should fail the read of a response that exceeds the timeout", func() {
+		var timedOut []CallBudget
+		slowBodyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.(http.Flusher).Flush()
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		}))
+		defer slowBodyServer.Close()
+		slowClient, err := NewClient(context.TODO(), &ClientConfig{
+			URL:       slowBodyServer.URL,
+			Timeouts:  Timeouts{Bind: 50 * time.Millisecond},
+			OnTimeout: func(budget CallBudget) { timedOut = append(timedOut, budget) },
+		}, fakeAuthClient)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, _, err = slowClient.Bind(&types.ServiceBinding{Name: "binding"}, nil, "")
+		var smError *ServiceManagerError
+		Expect(errors.As(err, &smError)).To(BeTrue())
+		Expect(smError.StatusCode).To(Equal(http.StatusGatewayTimeout))
+		Expect(timedOut).To(Equal([]CallBudget{BindBudget}))
+	})
+
+	It("should cancel the calls with the context of the client", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		slowClient, err := NewClient(ctx, &ClientConfig{URL: slowServer.URL}, fakeAuthClient)
+		Expect(err).ToNot(HaveOccurred())
+		cancel()
+
+		_, err = slowClient.GetInstanceByID("instanceID", nil)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("should select the budget by the method and path of the call", func() {
+		Expect(callBudget(http.MethodGet, types.ServiceOfferingsURL)).To(Equal(CatalogBudget))
+		Expect(callBudget(http.MethodGet, types.ServicePlansURL)).To(Equal(CatalogBudget))
+		Expect(callBudget(http.MethodGet, types.ServiceInstancesURL+"/id/operations/op")).To(Equal(PollBudget))
+		Expect(callBudget(http.MethodPost, types.ServiceInstancesURL)).To(Equal(ProvisionBudget))
+		Expect(callBudget(http.MethodDelete, types.ServiceBindingsURL+"/id")).To(Equal(BindBudget))
+		Expect(callBudget(http.MethodPost, "/v1/other")).To(Equal(DefaultBudget))
+		Expect(Timeouts{Bind: time.Minute, Default: time.Second}.For(BindBudget)).To(Equal(time.Minute))
+	})
+})
@@ -44,6 +44,16 @@ type FakeClient struct {
 		result1 *http.Response
 		result2 error
 	}
+	CapabilitiesStub        func() *sm.Capabilities
+	capabilitiesMutex       sync.RWMutex
+	capabilitiesArgsForCall []struct {
+	}
+	capabilitiesReturns struct {
+		result1 *sm.Capabilities
+	}
+	capabilitiesReturnsOnCall map[int]struct {
+		result1 *sm.Capabilities
+	}
 	DeprovisionStub        func(string, *sm.Parameters, string) (string, error)
 	deprovisionMutex       sync.RWMutex
 	deprovisionArgsForCall []struct {
@@ -87,6 +97,20 @@ type FakeClient struct {
 		result1 *types.ServiceInstance
 		result2 error
 	}
+	GetInstanceParametersStub        func(string, *sm.Parameters) (map[string]interface{}, error)
+	getInstanceParametersMutex       sync.RWMutex
+	getInstanceParametersArgsForCall []struct {
+		arg1 string
+		arg2 *sm.Parameters
+	}
+	getInstanceParametersReturns struct {
+		result1 map[string]interface{}
+		result2 error
+	}
+	getInstanceParametersReturnsOnCall map[int]struct {
+		result1 map[string]interface{}
+		result2 error
+	}
 	ListBindingsStub        func(*sm.Parameters) (*types.ServiceBindings, error)
 	listBindingsMutex       sync.RWMutex
 	listBindingsArgsForCall []struct {
@@ -157,12 +181,13 @@ type FakeClient struct {
 		result1 *sm.ProvisionResponse
 		result2 error
 	}
-	RenameBindingStub        func(string, string, string) (*types.ServiceBinding, error)
+	RenameBindingStub        func(string, string, string, string) (*types.ServiceBinding, error)
 	renameBindingMutex       sync.RWMutex
 	renameBindingArgsForCall []struct {
 		arg1 string
 		arg2 string
 		arg3 string
+		arg4 string
 	}
 	renameBindingReturns struct {
 		result1 *types.ServiceBinding
@@ -246,6 +271,19 @@ type FakeClient struct {
 		result2 string
 		result3 error
 	}
+	UpdateInstanceLabelsStub        func(string, []*types.LabelChange, string) error
+	updateInstanceLabelsMutex       sync.RWMutex
+	updateInstanceLabelsArgsForCall []struct {
+		arg1 string
+		arg2 []*types.LabelChange
+		arg3 string
+	}
+	updateInstanceLabelsReturns struct {
+		result1 error
+	}
+	updateInstanceLabelsReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -386,6 +424,59 @@ func (fake *FakeClient) CallReturnsOnCall(i int, result1 *http.Response, result2
 	}{result1, result2}
 }
 
+func (fake *FakeClient) Capabilities() *sm.Capabilities {
+	fake.capabilitiesMutex.Lock()
+	ret, specificReturn := fake.capabilitiesReturnsOnCall[len(fake.capabilitiesArgsForCall)]
+	fake.capabilitiesArgsForCall = append(fake.capabilitiesArgsForCall, struct {
+	}{})
+	stub := fake.CapabilitiesStub
+	fakeReturns := fake.capabilitiesReturns
+	fake.recordInvocation("Capabilities", []interface{}{})
+	fake.capabilitiesMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeClient) CapabilitiesCallCount() int {
+	fake.capabilitiesMutex.RLock()
+	defer fake.capabilitiesMutex.RUnlock()
+	return len(fake.capabilitiesArgsForCall)
+}
+
+func (fake *FakeClient) CapabilitiesCalls(stub func() *sm.Capabilities) {
+	fake.capabilitiesMutex.Lock()
+	defer fake.capabilitiesMutex.Unlock()
+	fake.CapabilitiesStub = stub
+}
+
+func (fake *FakeClient) CapabilitiesReturns(result1 *sm.Capabilities) {
+	fake.capabilitiesMutex.Lock()
+	defer fake.capabilitiesMutex.Unlock()
+	fake.CapabilitiesStub = nil
+	fake.capabilitiesReturns = struct {
+		result1 *sm.Capabilities
+	}{result1}
+}
+
+func (fake *FakeClient) CapabilitiesReturnsOnCall(i int, result1 *sm.Capabilities) {
+	fake.capabilitiesMutex.Lock()
+	defer fake.capabilitiesMutex.Unlock()
+	fake.CapabilitiesStub = nil
+	if fake.capabilitiesReturnsOnCall == nil {
+		fake.capabilitiesReturnsOnCall = make(map[int]struct {
+			result1 *sm.Capabilities
+		})
+	}
+	fake.capabilitiesReturnsOnCall[i] = struct {
+		result1 *sm.Capabilities
+	}{result1}
+}
+
 func (fake *FakeClient) Deprovision(arg1 string, arg2 *sm.Parameters, arg3 string) (string, error) {
 	fake.deprovisionMutex.Lock()
 	ret, specificReturn := fake.deprovisionReturnsOnCall[len(fake.deprovisionArgsForCall)]
@@ -582,6 +673,71 @@ func (fake *FakeClient) GetInstanceByIDReturnsOnCall(i int, result1 *types.Servi
 	}{result1, result2}
 }
 
+func (fake *FakeClient) GetInstanceParameters(arg1 string, arg2 *sm.Parameters) (map[string]interface{}, error) {
+	fake.getInstanceParametersMutex.Lock()
+	ret, specificReturn := fake.getInstanceParametersReturnsOnCall[len(fake.getInstanceParametersArgsForCall)]
+	fake.getInstanceParametersArgsForCall = append(fake.getInstanceParametersArgsForCall, struct {
+		arg1 string
+		arg2 *sm.Parameters
+	}{arg1, arg2})
+	stub := fake.GetInstanceParametersStub
+	fakeReturns := fake.getInstanceParametersReturns
+	fake.recordInvocation("GetInstanceParameters", []interface{}{arg1, arg2})
+	fake.getInstanceParametersMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeClient) GetInstanceParametersCallCount() int {
+	fake.getInstanceParametersMutex.RLock()
+	defer fake.getInstanceParametersMutex.RUnlock()
+	return len(fake.getInstanceParametersArgsForCall)
+}
+
+func (fake *FakeClient) GetInstanceParametersCalls(stub func(string, *sm.Parameters) (map[string]interface{}, error)) {
+	fake.getInstanceParametersMutex.Lock()
+	defer fake.getInstanceParametersMutex.Unlock()
+	fake.GetInstanceParametersStub = stub
+}
+
+func (fake *FakeClient) GetInstanceParametersArgsForCall(i int) (string, *sm.Parameters) {
+	fake.getInstanceParametersMutex.RLock()
+	defer fake.getInstanceParametersMutex.RUnlock()
+	argsForCall := fake.getInstanceParametersArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeClient) GetInstanceParametersReturns(result1 map[string]interface{}, result2 error) {
+	fake.getInstanceParametersMutex.Lock()
+	defer fake.getInstanceParametersMutex.Unlock()
+	fake.GetInstanceParametersStub = nil
+	fake.getInstanceParametersReturns = struct {
+		result1 map[string]interface{}
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeClient) GetInstanceParametersReturnsOnCall(i int, result1 map[string]interface{}, result2 error) {
+	fake.getInstanceParametersMutex.Lock()
+	defer fake.getInstanceParametersMutex.Unlock()
+	fake.GetInstanceParametersStub = nil
+	if fake.getInstanceParametersReturnsOnCall == nil {
+		fake.getInstanceParametersReturnsOnCall = make(map[int]struct {
+			result1 map[string]interface{}
+			result2 error
+		})
+	}
+	fake.getInstanceParametersReturnsOnCall[i] = struct {
+		result1 map[string]interface{}
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeClient) ListBindings(arg1 *sm.Parameters) (*types.ServiceBindings, error) {
 	fake.listBindingsMutex.Lock()
 	ret, specificReturn := fake.listBindingsReturnsOnCall[len(fake.listBindingsArgsForCall)]
@@ -907,20 +1063,21 @@ func (fake *FakeClient) ProvisionReturnsOnCall(i int, result1 *sm.ProvisionRespo
 	}{result1, result2}
 }
 
-func (fake *FakeClient) RenameBinding(arg1 string, arg2 string, arg3 string) (*types.ServiceBinding, error) {
+func (fake *FakeClient) RenameBinding(arg1 string, arg2 string, arg3 string, arg4 string) (*types.ServiceBinding, error) {
 	fake.renameBindingMutex.Lock()
 	ret, specificReturn := fake.renameBindingReturnsOnCall[len(fake.renameBindingArgsForCall)]
 	fake.renameBindingArgsForCall = append(fake.renameBindingArgsForCall, struct {
 		arg1 string
 		arg2 string
 		arg3 string
-	}{arg1, arg2, arg3})
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
 	stub := fake.RenameBindingStub
 	fakeReturns := fake.renameBindingReturns
-	fake.recordInvocation("RenameBinding", []interface{}{arg1, arg2, arg3})
+	fake.recordInvocation("RenameBinding", []interface{}{arg1, arg2, arg3, arg4})
 	fake.renameBindingMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2, arg3)
+		return stub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -934,17 +1091,17 @@ func (fake *FakeClient) RenameBindingCallCount() int {
 	return len(fake.renameBindingArgsForCall)
 }
 
-func (fake *FakeClient) RenameBindingCalls(stub func(string, string, string) (*types.ServiceBinding, error)) {
+func (fake *FakeClient) RenameBindingCalls(stub func(string, string, string, string) (*types.ServiceBinding, error)) {
 	fake.renameBindingMutex.Lock()
 	defer fake.renameBindingMutex.Unlock()
 	fake.RenameBindingStub = stub
 }
 
-func (fake *FakeClient) RenameBindingArgsForCall(i int) (string, string, string) {
+func (fake *FakeClient) RenameBindingArgsForCall(i int) (string, string, string, string) {
 	fake.renameBindingMutex.RLock()
 	defer fake.renameBindingMutex.RUnlock()
 	argsForCall := fake.renameBindingArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeClient) RenameBindingReturns(result1 *types.ServiceBinding, result2 error) {
@@ -1301,6 +1458,74 @@ func (fake *FakeClient) UpdateInstanceReturnsOnCall(i int, result1 *types.Servic
 	}{result1, result2, result3}
 }
 
+func (fake *FakeClient) UpdateInstanceLabels(arg1 string, arg2 []*types.LabelChange, arg3 string) error {
+	var arg2Copy []*types.LabelChange
+	if arg2 != nil {
+		arg2Copy = make([]*types.LabelChange, len(arg2))
+		copy(arg2Copy, arg2)
+	}
+	fake.updateInstanceLabelsMutex.Lock()
+	ret, specificReturn := fake.updateInstanceLabelsReturnsOnCall[len(fake.updateInstanceLabelsArgsForCall)]
+	fake.updateInstanceLabelsArgsForCall = append(fake.updateInstanceLabelsArgsForCall, struct {
+		arg1 string
+		arg2 []*types.LabelChange
+		arg3 string
+	}{arg1, arg2Copy, arg3})
+	stub := fake.UpdateInstanceLabelsStub
+	fakeReturns := fake.updateInstanceLabelsReturns
+	fake.recordInvocation("UpdateInstanceLabels", []interface{}{arg1, arg2Copy, arg3})
+	fake.updateInstanceLabelsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeClient) UpdateInstanceLabelsCallCount() int {
+	fake.updateInstanceLabelsMutex.RLock()
+	defer fake.updateInstanceLabelsMutex.RUnlock()
+	return len(fake.updateInstanceLabelsArgsForCall)
+}
+
+func (fake *FakeClient) UpdateInstanceLabelsCalls(stub func(string, []*types.LabelChange, string) error) {
+	fake.updateInstanceLabelsMutex.Lock()
+	defer fake.updateInstanceLabelsMutex.Unlock()
+	fake.UpdateInstanceLabelsStub = stub
+}
+
+func (fake *FakeClient) UpdateInstanceLabelsArgsForCall(i int) (string, []*types.LabelChange, string) {
+	fake.updateInstanceLabelsMutex.RLock()
+	defer fake.updateInstanceLabelsMutex.RUnlock()
+	argsForCall := fake.updateInstanceLabelsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeClient) UpdateInstanceLabelsReturns(result1 error) {
+	fake.updateInstanceLabelsMutex.Lock()
+	defer fake.updateInstanceLabelsMutex.Unlock()
+	fake.UpdateInstanceLabelsStub = nil
+	fake.updateInstanceLabelsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeClient) UpdateInstanceLabelsReturnsOnCall(i int, result1 error) {
+	fake.updateInstanceLabelsMutex.Lock()
+	defer fake.updateInstanceLabelsMutex.Unlock()
+	fake.UpdateInstanceLabelsStub = nil
+	if fake.updateInstanceLabelsReturnsOnCall == nil {
+		fake.updateInstanceLabelsReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.updateInstanceLabelsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeClient) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -1308,12 +1533,16 @@ func (fake *FakeClient) Invocations() map[string][][]interface{} {
 	defer fake.bindMutex.RUnlock()
 	fake.callMutex.RLock()
 	defer fake.callMutex.RUnlock()
+	fake.capabilitiesMutex.RLock()
+	defer fake.capabilitiesMutex.RUnlock()
 	fake.deprovisionMutex.RLock()
 	defer fake.deprovisionMutex.RUnlock()
 	fake.getBindingByIDMutex.RLock()
 	defer fake.getBindingByIDMutex.RUnlock()
 	fake.getInstanceByIDMutex.RLock()
 	defer fake.getInstanceByIDMutex.RUnlock()
+	fake.getInstanceParametersMutex.RLock()
+	defer fake.getInstanceParametersMutex.RUnlock()
 	fake.listBindingsMutex.RLock()
 	defer fake.listBindingsMutex.RUnlock()
 	fake.listInstancesMutex.RLock()
@@ -1336,6 +1565,8 @@ func (fake *FakeClient) Invocations() map[string][][]interface{} {
 	defer fake.unbindMutex.RUnlock()
 	fake.updateInstanceMutex.RLock()
 	defer fake.updateInstanceMutex.RUnlock()
+	fake.updateInstanceLabelsMutex.RLock()
+	defer fake.updateInstanceLabelsMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -0,0 +1,97 @@
+package sm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/client/sm/types"
+)
+
+// CallBudget is the kind of a call to Service Manager, each kind is bounded by its own timeout
+type CallBudget string
+
+const (
+	// CatalogBudget bounds the lookups of service offerings and plans
+	CatalogBudget CallBudget = "catalog"
+	// ProvisionBudget bounds the creation, update and deletion of service instances
+	ProvisionBudget CallBudget = "provision"
+	// PollBudget bounds the reads of operations, instances and bindings
+	PollBudget CallBudget = "poll"
+	// BindBudget bounds the creation, update and deletion of service bindings
+	BindBudget CallBudget = "bind"
+	// DefaultBudget bounds the other calls
+	DefaultBudget CallBudget = "default"
+)
+
+// Timeouts are the maximal durations of the calls to Service Manager by budget, including the read of the response.
+// Zero means that the calls of the budget are not bounded.
+type Timeouts struct {
+	Catalog   time.Duration
+	Provision time.Duration
+	Poll      time.Duration
+	Bind      time.Duration
+	Default   time.Duration
+}
+
+// For returns the timeout of the calls of the budget
+func (t Timeouts) For(budget CallBudget) time.Duration {
+	switch budget {
+	case CatalogBudget:
+		return t.Catalog
+	case ProvisionBudget:
+		return t.Provision
+	case PollBudget:
+		return t.Poll
+	case BindBudget:
+		return t.Bind
+	default:
+		return t.Default
+	}
+}
+
+// callBudget returns the budget of a call by its method and path
+func callBudget(method string, smpath string) CallBudget {
+	switch {
+	case strings.HasPrefix(smpath, types.ServiceOfferingsURL), strings.HasPrefix(smpath, types.ServicePlansURL):
+		return CatalogBudget
+	case method == http.MethodGet:
+		return PollBudget
+	case strings.HasPrefix(smpath, types.ServiceInstancesURL):
+		return ProvisionBudget
+	case strings.HasPrefix(smpath, types.ServiceBindingsURL):
+		return BindBudget
+	default:
+		return DefaultBudget
+	}
+}
+
+// cancelOnClose releases the context of a call when its response is closed, so that the timeout covers the read of
+// the response. A read that exceeds the timeout of the call fails with the error of timedOut.
+type cancelOnClose struct {
+	io.ReadCloser
+	ctx      context.Context
+	cancel   context.CancelFunc
+	timedOut func() error
+	err      error
+}
+
+func (c *cancelOnClose) Read(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && c.timedOut != nil && errors.Is(c.ctx.Err(), context.DeadlineExceeded) {
+		c.err = c.timedOut()
+		return n, c.err
+	}
+	return n, err
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
@@ -42,13 +42,19 @@ type ServiceBinding struct {
 	Endpoints       json.RawMessage `json:"-" yaml:"-"`
 	Context         json.RawMessage `json:"context,omitempty" yaml:"context,omitempty"`
 	Parameters      json.RawMessage `json:"parameters,omitempty" yaml:"parameters,omitempty"`
-	BindResource    json.RawMessage `json:"-" yaml:"-"`
+	BindResource    *BindResource   `json:"bind_resource,omitempty" yaml:"bind_resource,omitempty"`
 
 	Ready bool `json:"ready,omitempty" yaml:"ready,omitempty"`
 
 	LastOperation *Operation `json:"last_operation,omitempty" yaml:"last_operation,omitempty"`
 }
 
+// BindResource is the bind_resource of the Open Service Broker API, the resource the credentials are issued for
+type BindResource struct {
+	AppGUID string `json:"app_guid,omitempty" yaml:"app_guid,omitempty"`
+	Route   string `json:"route,omitempty" yaml:"route,omitempty"`
+}
+
 // ServiceBindings wraps an array of service bindings
 type ServiceBindings struct {
 	ServiceBindings []ServiceBinding `json:"items" yaml:"items"`
@@ -36,8 +36,9 @@ type ServicePlan struct {
 	Bindable      bool   `json:"bindable,omitempty" yaml:"bindable,omitempty"`
 	PlanUpdatable bool   `json:"plan_updateable,omitempty" yaml:"plan_updateable,omitempty"`
 
-	Metadata json.RawMessage `json:"metadata,omitempty" yaml:"-"`
-	Schemas  json.RawMessage `json:"schemas,omitempty" yaml:"-"`
+	Metadata        json.RawMessage `json:"metadata,omitempty" yaml:"-"`
+	Schemas         json.RawMessage `json:"schemas,omitempty" yaml:"-"`
+	MaintenanceInfo json.RawMessage `json:"maintenance_info,omitempty" yaml:"-"`
 
 	ServiceOfferingID string `json:"service_offering_id,omitempty" yaml:"service_offering_id,omitempty"`
 	Labels            Labels `json:"labels,omitempty" yaml:"labels,omitempty"`
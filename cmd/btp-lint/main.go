@@ -0,0 +1,94 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// btp-lint validates ServiceInstance and ServiceBinding manifests offline, see package lint.
+//
+// Usage: btp-lint [-credentials <file>] [-strict] <file or directory|->...
+//
+// The exit code is 1 if errors are found, or warnings with -strict, and 2 if the manifests cannot be read.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/SAP/sap-btp-service-operator/pkg/lint"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func main() {
+	var credentialsFile string
+	var strict bool
+	flag.StringVar(&credentialsFile, "credentials", "", "JSON file with sample binding credentials to render secret templates with")
+	flag.BoolVar(&strict, "strict", false, "Fail on warnings as well")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <file or directory|->...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// the validations of the api package log like in the webhooks
+	logf.SetLogger(zap.New(zap.WriteTo(io.Discard)))
+
+	options := lint.Options{}
+	if len(credentialsFile) > 0 {
+		data, err := os.ReadFile(credentialsFile)
+		if err != nil {
+			fail(err)
+		}
+		if err := json.Unmarshal(data, &options.SampleCredentials); err != nil {
+			fail(fmt.Errorf("failed to parse %s: %s", credentialsFile, err.Error()))
+		}
+	}
+
+	var findings []lint.Finding
+	for _, path := range flag.Args() {
+		var pathFindings []lint.Finding
+		var err error
+		if path == "-" {
+			var manifests []byte
+			if manifests, err = io.ReadAll(os.Stdin); err == nil {
+				pathFindings, err = lint.Lint("<stdin>", manifests, options)
+			}
+		} else {
+			pathFindings, err = lint.LintPath(path, options)
+		}
+		if err != nil {
+			fail(err)
+		}
+		findings = append(findings, pathFindings...)
+	}
+
+	for _, finding := range findings {
+		fmt.Println(finding.String())
+	}
+	if lint.HasErrors(findings) || (strict && len(findings) > 0) {
+		os.Exit(1)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err.Error())
+	os.Exit(2)
+}
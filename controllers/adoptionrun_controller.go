@@ -0,0 +1,466 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	"github.com/SAP/sap-btp-service-operator/internal/secrets"
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// maxAdoptionFailures is the number of failures kept in the status of an adoption run
+	maxAdoptionFailures = 20
+	// adoptionBindingsQueryBatch is the number of instances whose bindings are listed with one call to SM
+	adoptionBindingsQueryBatch = 50
+)
+
+// AdoptionRunReconciler imports existing resources of a subaccount in SM as ServiceInstance and ServiceBinding
+// resources. The imported resources carry the ID of the resource in SM and are adopted by the instance and binding
+// controllers, as resources imported from the export of another cluster. Since a run imports the resources with the
+// credentials of the cluster, runs are accepted only in the release namespace and in the namespaces allowed by the
+// operator configuration.
+type AdoptionRunReconciler struct {
+	*BaseReconciler
+}
+
+// adoptionSummary collects the result of an adoption run
+type adoptionSummary struct {
+	instances servicesv1.AdoptionCounts
+	bindings  servicesv1.AdoptionCounts
+	approved  int
+	failures  []string
+}
+
+func (s *adoptionSummary) fail(counts *servicesv1.AdoptionCounts, format string, args ...interface{}) {
+	counts.Failed++
+	if len(s.failures) < maxAdoptionFailures {
+		s.failures = append(s.failures, fmt.Sprintf(format, args...))
+	}
+}
+
+// +kubebuilder:rbac:groups=services.cloud.sap.com,resources=adoptionruns,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=services.cloud.sap.com,resources=adoptionruns/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=services.cloud.sap.com,resources=serviceinstances,verbs=get;list;create
+// +kubebuilder:rbac:groups=services.cloud.sap.com,resources=servicebindings,verbs=get;list;create;update
+
+func (r *AdoptionRunReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("adoptionrun", req.NamespacedName).WithValues("correlation_id", uuid.New().String())
+	ctx = context.WithValue(ctx, LogKey{}, log)
+
+	run := &servicesv1.AdoptionRun{}
+	if err := r.Client.Get(ctx, req.NamespacedName, run); err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Error(err, "unable to fetch AdoptionRun")
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	run = run.DeepCopy()
+	if isMarkedForDeletion(run.ObjectMeta) || (run.Status.ObservedGeneration == run.Generation && run.Status.CompletionTime != nil) {
+		return ctrl.Result{}, nil
+	}
+
+	if !r.Config.FeatureGates.Enabled(config.Adoption) {
+		return ctrl.Result{}, r.completeAdoptionRun(ctx, run, nil, fmt.Errorf("adoption is disabled, enable the %s feature gate of the operator", config.Adoption))
+	}
+	if !r.adoptionRunAllowed(run.Namespace) {
+		return ctrl.Result{}, r.completeAdoptionRun(ctx, run, nil, fmt.Errorf("adoption runs are not allowed in namespace '%s'", run.Namespace))
+	}
+	if len(run.Spec.Landscape) > 0 && len(run.Spec.BTPAccessCredentialsSecret) > 0 {
+		return ctrl.Result{}, r.completeAdoptionRun(ctx, run, nil, fmt.Errorf("landscape and btpAccessCredentialsSecret cannot be specified together"))
+	}
+	if secrets.IsReservedLandscape(run.Spec.Landscape) {
+		return ctrl.Result{}, r.completeAdoptionRun(ctx, run, nil, fmt.Errorf("invalid landscape '%s': the name is reserved", run.Spec.Landscape))
+	}
+	for _, id := range run.Spec.InstanceIDs {
+		// the IDs are part of the field query of SM
+		if _, err := uuid.Parse(id); err != nil {
+			return ctrl.Result{}, r.completeAdoptionRun(ctx, run, nil, fmt.Errorf("invalid instance ID '%s': %s", id, err.Error()))
+		}
+	}
+
+	smClient, err := r.getSMClient(ctx, run, adoptionRunSecretName(run))
+	if err != nil {
+		return ctrl.Result{}, r.adoptionRunInProgress(ctx, run, err)
+	}
+
+	log.Info("importing the resources of the subaccount")
+	summary, err := r.adopt(ctx, smClient, run)
+	if err != nil {
+		return ctrl.Result{}, r.adoptionRunInProgress(ctx, run, err)
+	}
+	if run.Spec.ImportBindings && run.Spec.ApproveBindings {
+		if err := r.approveBindings(ctx, run, summary); err != nil {
+			return ctrl.Result{}, r.adoptionRunInProgress(ctx, run, err)
+		}
+	}
+	return ctrl.Result{}, r.completeAdoptionRun(ctx, run, summary, nil)
+}
+
+// adoptionRunAllowed checks whether runs are accepted in the namespace
+func (r *AdoptionRunReconciler) adoptionRunAllowed(namespace string) bool {
+	return namespace == r.Config.ReleaseNamespace || contains(r.Config.AdoptionRunNamespaces, namespace)
+}
+
+func (r *AdoptionRunReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&servicesv1.AdoptionRun{}).
+		Complete(r)
+}
+
+// adoptionRunInProgress reports an error the run is retried for
+func (r *AdoptionRunReconciler) adoptionRunInProgress(ctx context.Context, run *servicesv1.AdoptionRun, err error) error {
+	GetLogger(ctx).Error(err, "failed to import the resources of the subaccount, retrying")
+	meta.SetStatusCondition(&run.Status.Conditions, metav1.Condition{
+		Type:               api.ConditionSucceeded,
+		Status:             metav1.ConditionFalse,
+		Reason:             InProgress,
+		Message:            err.Error(),
+		ObservedGeneration: run.Generation,
+	})
+	if updateErr := r.Client.Status().Update(ctx, run); updateErr != nil {
+		return updateErr
+	}
+	return err
+}
+
+// completeAdoptionRun stores the summary of the run, or the error it failed with
+func (r *AdoptionRunReconciler) completeAdoptionRun(ctx context.Context, run *servicesv1.AdoptionRun, summary *adoptionSummary, err error) error {
+	condition := metav1.Condition{
+		Type:               api.ConditionSucceeded,
+		Status:             metav1.ConditionFalse,
+		Reason:             Failed,
+		ObservedGeneration: run.Generation,
+	}
+	if err != nil {
+		condition.Message = err.Error()
+		r.Recorder.Event(run, corev1.EventTypeWarning, Failed, condition.Message)
+	} else {
+		run.Status.Instances = summary.instances
+		run.Status.Bindings = summary.bindings
+		run.Status.Failures = summary.failures
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = Finished
+		condition.Message = fmt.Sprintf("imported %d service instances and %d service bindings", summary.instances.Imported, summary.bindings.Imported)
+		if summary.approved > 0 {
+			condition.Message += fmt.Sprintf(", approved %d service bindings", summary.approved)
+		}
+		if summary.instances.Failed+summary.bindings.Failed > 0 {
+			condition.Message += fmt.Sprintf(", %d resources could not be imported", summary.instances.Failed+summary.bindings.Failed)
+		}
+		r.Recorder.Event(run, corev1.EventTypeNormal, Finished, condition.Message)
+	}
+	GetLogger(ctx).Info(condition.Message)
+
+	meta.SetStatusCondition(&run.Status.Conditions, condition)
+	run.Status.ObservedGeneration = run.Generation
+	run.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+	return r.Client.Status().Update(ctx, run)
+}
+
+// adopt creates the resources for the instances of the subaccount that match the run and, if requested, for their bindings
+func (r *AdoptionRunReconciler) adopt(ctx context.Context, smClient sm.Client, run *servicesv1.AdoptionRun) (*adoptionSummary, error) {
+	plans, err := smClient.ListPlans(nil)
+	if err != nil {
+		return nil, err
+	}
+	offerings, err := smClient.ListOfferings(nil)
+	if err != nil {
+		return nil, err
+	}
+	offeringNames := make(map[string]string)
+	if offerings != nil {
+		for _, offering := range offerings.ServiceOfferings {
+			offeringNames[offering.ID] = offering.Name
+		}
+	}
+	plansByID := make(map[string]smClientTypes.ServicePlan)
+	if plans != nil {
+		for _, plan := range plans.ServicePlans {
+			plansByID[plan.ID] = plan
+		}
+	}
+
+	parameters := &sm.Parameters{LabelQuery: adoptionLabelQuery(run.Spec.Labels)}
+	if len(run.Spec.InstanceIDs) > 0 {
+		parameters.FieldQuery = []string{fmt.Sprintf("id in ('%s')", strings.Join(run.Spec.InstanceIDs, "','"))}
+	}
+	smInstances, err := smClient.ListInstances(parameters)
+	if err != nil {
+		return nil, err
+	}
+	if smInstances == nil {
+		smInstances = &smClientTypes.ServiceInstances{}
+	}
+
+	existingInstances := &servicesv1.ServiceInstanceList{}
+	if err := r.Client.List(ctx, existingInstances, client.InNamespace(run.Namespace)); err != nil {
+		return nil, err
+	}
+	instanceNames := make(map[string]string)
+	takenNames := make(map[string]bool)
+	for _, instance := range existingInstances.Items {
+		id := managedResourceID(&instance, instance.Status.InstanceID)
+		takenNames[instance.Name] = true
+		if len(id) > 0 {
+			instanceNames[id] = instance.Name
+		}
+	}
+
+	summary := &adoptionSummary{}
+	adopted := make(map[string]string)
+	listed := make(map[string]bool)
+	for _, smInstance := range smInstances.ServiceInstances {
+		if len(run.Spec.InstanceIDs) > 0 && !contains(run.Spec.InstanceIDs, smInstance.ID) {
+			continue
+		}
+		listed[smInstance.ID] = true
+		plan, found := plansByID[smInstance.ServicePlanID]
+		offeringName := offeringNames[plan.ServiceOfferingID]
+		if len(run.Spec.ServiceOfferingNames) > 0 && (!found || !contains(run.Spec.ServiceOfferingNames, offeringName)) {
+			continue
+		}
+
+		if name, managed := instanceNames[smInstance.ID]; managed {
+			summary.instances.AlreadyImported++
+			adopted[smInstance.ID] = name
+			continue
+		}
+		if clusterID := smClusterID(smInstance.Context); len(clusterID) > 0 {
+			if r.isOwnClusterID(clusterID) {
+				// managed by a ServiceInstance in another namespace of this cluster
+				summary.instances.AlreadyImported++
+			} else {
+				summary.instances.Skipped++
+			}
+			continue
+		}
+		if !found || len(offeringName) == 0 {
+			summary.fail(&summary.instances, "service instance '%s' (%s): its service plan '%s' was not found", smInstance.Name, smInstance.ID, smInstance.ServicePlanID)
+			continue
+		}
+
+		name := adoptionResourceName(smInstance.Name, smInstance.ID)
+		if takenNames[name] {
+			summary.fail(&summary.instances, "service instance '%s' (%s): the name '%s' is taken by another service instance", smInstance.Name, smInstance.ID, name)
+			continue
+		}
+
+		instance := &servicesv1.ServiceInstance{
+			ObjectMeta: adoptionObjectMeta(run, name, smInstance.ID, r.settings().ClusterID),
+			Spec: servicesv1.ServiceInstanceSpec{
+				ServiceOfferingName:        offeringName,
+				ServicePlanName:            plan.Name,
+				ServicePlanID:              plan.ID,
+				ExternalName:               smInstance.Name,
+				BTPAccessCredentialsSecret: run.Spec.BTPAccessCredentialsSecret,
+				Landscape:                  run.Spec.Landscape,
+			},
+		}
+		if smInstance.Shared {
+			shared := true
+			instance.Spec.Shared = &shared
+		}
+		if err := r.Client.Create(ctx, instance); err != nil {
+			summary.fail(&summary.instances, "service instance '%s' (%s): %s", smInstance.Name, smInstance.ID, err.Error())
+			continue
+		}
+		takenNames[name] = true
+		adopted[smInstance.ID] = name
+		summary.instances.Imported++
+	}
+	for _, id := range run.Spec.InstanceIDs {
+		if !listed[id] {
+			summary.fail(&summary.instances, "service instance '%s': it was not found in Service Manager or does not have the labels of the run", id)
+		}
+	}
+
+	if run.Spec.ImportBindings && len(adopted) > 0 {
+		if err := r.adoptBindings(ctx, smClient, run, adopted, summary); err != nil {
+			return nil, err
+		}
+	}
+	return summary, nil
+}
+
+// adoptBindings creates the resources for the bindings of the adopted instances, their credentials are stored
+// once the run approves the bindings
+func (r *AdoptionRunReconciler) adoptBindings(ctx context.Context, smClient sm.Client, run *servicesv1.AdoptionRun, instanceNames map[string]string, summary *adoptionSummary) error {
+	existingBindings := &servicesv1.ServiceBindingList{}
+	if err := r.Client.List(ctx, existingBindings, client.InNamespace(run.Namespace)); err != nil {
+		return err
+	}
+	bindingNames := make(map[string]string)
+	takenNames := make(map[string]bool)
+	for _, binding := range existingBindings.Items {
+		id := managedResourceID(&binding, binding.Status.BindingID)
+		takenNames[binding.Name] = true
+		if len(id) > 0 {
+			bindingNames[id] = binding.Name
+		}
+	}
+
+	instanceIDs := make([]string, 0, len(instanceNames))
+	for id := range instanceNames {
+		instanceIDs = append(instanceIDs, id)
+	}
+	sort.Strings(instanceIDs)
+
+	for start := 0; start < len(instanceIDs); start += adoptionBindingsQueryBatch {
+		end := start + adoptionBindingsQueryBatch
+		if end > len(instanceIDs) {
+			end = len(instanceIDs)
+		}
+		smBindings, err := smClient.ListBindings(&sm.Parameters{
+			FieldQuery: []string{fmt.Sprintf("service_instance_id in ('%s')", strings.Join(instanceIDs[start:end], "','"))},
+		})
+		if err != nil {
+			return err
+		}
+		if smBindings == nil {
+			continue
+		}
+
+		for _, smBinding := range smBindings.ServiceBindings {
+			if _, managed := bindingNames[smBinding.ID]; managed {
+				summary.bindings.AlreadyImported++
+				continue
+			}
+			if clusterID := smClusterID(smBinding.Context); len(clusterID) > 0 {
+				if r.isOwnClusterID(clusterID) {
+					summary.bindings.AlreadyImported++
+				} else {
+					summary.bindings.Skipped++
+				}
+				continue
+			}
+
+			name := adoptionResourceName(smBinding.Name, smBinding.ID)
+			if takenNames[name] {
+				summary.fail(&summary.bindings, "service binding '%s' (%s): the name '%s' is taken by another service binding", smBinding.Name, smBinding.ID, name)
+				continue
+			}
+
+			binding := &servicesv1.ServiceBinding{
+				ObjectMeta: adoptionObjectMeta(run, name, smBinding.ID, r.settings().ClusterID),
+				Spec: servicesv1.ServiceBindingSpec{
+					ServiceInstanceName: instanceNames[smBinding.ServiceInstanceID],
+					ExternalName:        smBinding.Name,
+				},
+			}
+			binding.Annotations[api.AdoptionPendingAnnotation] = "true"
+			if err := r.Client.Create(ctx, binding); err != nil {
+				summary.fail(&summary.bindings, "service binding '%s' (%s): %s", smBinding.Name, smBinding.ID, err.Error())
+				continue
+			}
+			takenNames[name] = true
+			summary.bindings.Imported++
+		}
+	}
+	return nil
+}
+
+// approveBindings removes the AdoptionPendingAnnotation from the bindings imported by the run, so that their
+// credentials are stored. Users cannot remove the annotation themselves, see the ServiceBinding defaulter.
+func (r *AdoptionRunReconciler) approveBindings(ctx context.Context, run *servicesv1.AdoptionRun, summary *adoptionSummary) error {
+	bindings := &servicesv1.ServiceBindingList{}
+	if err := r.Client.List(ctx, bindings, client.InNamespace(run.Namespace), client.MatchingLabels{api.AdoptionRunLabel: run.Name}); err != nil {
+		return err
+	}
+	for i := range bindings.Items {
+		binding := &bindings.Items[i]
+		if !adoptionPending(binding) {
+			continue
+		}
+		delete(binding.Annotations, api.AdoptionPendingAnnotation)
+		if err := r.Client.Update(ctx, binding); err != nil {
+			return err
+		}
+		summary.approved++
+	}
+	return nil
+}
+
+// adoptionObjectMeta returns the metadata of a resource imported by the run, the resource is adopted by its ID as
+// a resource exported from this cluster
+func adoptionObjectMeta(run *servicesv1.AdoptionRun, name, id, clusterID string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: run.Namespace,
+		Labels:    map[string]string{api.AdoptionRunLabel: run.Name},
+		Annotations: map[string]string{
+			api.DRResourceIDAnnotation:    id,
+			api.DRSourceClusterAnnotation: clusterID,
+		},
+	}
+}
+
+// managedResourceID returns the ID in SM of the resource managed or about to be adopted by an existing resource
+func managedResourceID(object api.SAPBTPResource, statusID string) string {
+	if len(statusID) > 0 {
+		return statusID
+	}
+	return adoptionID(object)
+}
+
+// adoptionResourceName derives a valid resource name from the name of a resource in SM
+func adoptionResourceName(smName, id string) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '.' {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(smName))
+	if len(name) > validation.DNS1123SubdomainMaxLength {
+		name = name[:validation.DNS1123SubdomainMaxLength]
+	}
+	name = strings.Trim(name, "-.")
+	if len(validation.IsDNS1123Subdomain(name)) > 0 {
+		return strings.ToLower(id)
+	}
+	return name
+}
+
+// adoptionLabelQuery converts the labels of the run to a label query of SM
+func adoptionLabelQuery(labels map[string]string) []string {
+	var query []string
+	for key, value := range labels {
+		query = append(query, fmt.Sprintf("%s eq '%s'", key, value))
+	}
+	sort.Strings(query)
+	return query
+}
+
+// smClusterID returns the ID of the cluster that created a resource in SM, if it was created by a cluster
+func smClusterID(context json.RawMessage) string {
+	var clusterContext struct {
+		ClusterID string `json:"clusterid"`
+	}
+	if len(context) == 0 || json.Unmarshal(context, &clusterContext) != nil {
+		return ""
+	}
+	return clusterContext.ClusterID
+}
+
+func adoptionRunSecretName(run *servicesv1.AdoptionRun) string {
+	if len(run.Spec.Landscape) > 0 {
+		return secrets.LandscapeSecretName(run.Spec.Landscape)
+	}
+	return run.Spec.BTPAccessCredentialsSecret
+}
@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	"github.com/SAP/sap-btp-service-operator/client/sm/smfakes"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Adoption run", func() {
+	const clusterID = "this-cluster"
+	var (
+		reconciler *AdoptionRunReconciler
+		smClient   *smfakes.FakeClient
+		run        *servicesv1.AdoptionRun
+		testCtx    context.Context
+	)
+
+	newReconciler := func(objects ...client.Object) *AdoptionRunReconciler {
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		gates := config.FeatureGates{}
+		Expect(gates.Decode("Adoption=true")).To(Succeed())
+		return &AdoptionRunReconciler{BaseReconciler: &BaseReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(objects...).WithStatusSubresource(&servicesv1.AdoptionRun{}).Build(),
+			Scheme:   testScheme,
+			Log:      ctrl.Log,
+			SMClient: func() sm.Client { return smClient },
+			Config:   config.Config{ClusterID: clusterID, ReleaseNamespace: "sap-btp-operator", AdoptionRunNamespaces: []string{"apps"}, FeatureGates: gates},
+			Recorder: record.NewFakeRecorder(10),
+		}}
+	}
+
+	expectFailure := func(updated *servicesv1.AdoptionRun, message string) {
+		condition := meta.FindStatusCondition(updated.Status.Conditions, api.ConditionSucceeded)
+		Expect(condition.Reason).To(Equal(Failed))
+		Expect(condition.Message).To(ContainSubstring(message))
+		Expect(smClient.ListInstancesCallCount()).To(BeZero())
+	}
+
+	reconcileRun := func() *servicesv1.AdoptionRun {
+		key := types.NamespacedName{Namespace: run.Namespace, Name: run.Name}
+		_, err := reconciler.Reconcile(testCtx, ctrl.Request{NamespacedName: key})
+		Expect(err).ToNot(HaveOccurred())
+		updated := &servicesv1.AdoptionRun{}
+		Expect(reconciler.Client.Get(testCtx, key, updated)).To(Succeed())
+		return updated
+	}
+
+	clusterContext := func(id string) json.RawMessage {
+		return json.RawMessage(`{"clusterid": "` + id + `"}`)
+	}
+
+	BeforeEach(func() {
+		testCtx = context.Background()
+		run = &servicesv1.AdoptionRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "import", Namespace: "apps", Generation: 1},
+			Spec:       servicesv1.AdoptionRunSpec{ServiceOfferingNames: []string{"xsuaa"}, ImportBindings: true},
+		}
+		smClient = &smfakes.FakeClient{}
+		smClient.ListOfferingsReturns(&smClientTypes.ServiceOfferings{ServiceOfferings: []smClientTypes.ServiceOffering{
+			{ID: "xsuaa-id", Name: "xsuaa"}, {ID: "db-id", Name: "db"},
+		}}, nil)
+		smClient.ListPlansReturns(&smClientTypes.ServicePlans{ServicePlans: []smClientTypes.ServicePlan{
+			{ID: "application-id", Name: "application", ServiceOfferingID: "xsuaa-id"}, {ID: "small-id", Name: "small", ServiceOfferingID: "db-id"},
+		}}, nil)
+		smClient.ListInstancesReturns(&smClientTypes.ServiceInstances{ServiceInstances: []smClientTypes.ServiceInstance{
+			{ID: "uaa-1", Name: "My_UAA", ServicePlanID: "application-id", Shared: true},
+			{ID: "uaa-2", Name: "managed-uaa", ServicePlanID: "application-id", Context: clusterContext("other-cluster")},
+			{ID: "db-1", Name: "db", ServicePlanID: "small-id"},
+		}}, nil)
+		smClient.ListBindingsReturns(&smClientTypes.ServiceBindings{ServiceBindings: []smClientTypes.ServiceBinding{
+			{ID: "binding-1", Name: "uaa-key", ServiceInstanceID: "uaa-1"},
+		}}, nil)
+	})
+
+	It("should import the matching instances and their bindings", func() {
+		reconciler = newReconciler(run)
+		updated := reconcileRun()
+
+		Expect(updated.Status.Instances).To(Equal(servicesv1.AdoptionCounts{Imported: 1, Skipped: 1}))
+		Expect(updated.Status.Bindings).To(Equal(servicesv1.AdoptionCounts{Imported: 1}))
+		Expect(updated.Status.CompletionTime).ToNot(BeNil())
+		Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, api.ConditionSucceeded)).To(BeTrue())
+
+		instance := &servicesv1.ServiceInstance{}
+		Expect(reconciler.Client.Get(testCtx, types.NamespacedName{Namespace: "apps", Name: "my-uaa"}, instance)).To(Succeed())
+		Expect(instance.Spec.ServiceOfferingName).To(Equal("xsuaa"))
+		Expect(instance.Spec.ServicePlanName).To(Equal("application"))
+		Expect(instance.Spec.ExternalName).To(Equal("My_UAA"))
+		Expect(*instance.Spec.Shared).To(BeTrue())
+		Expect(instance.Annotations).To(HaveKeyWithValue(api.DRResourceIDAnnotation, "uaa-1"))
+		Expect(instance.Labels).To(HaveKeyWithValue(api.AdoptionRunLabel, "import"))
+
+		binding := &servicesv1.ServiceBinding{}
+		Expect(reconciler.Client.Get(testCtx, types.NamespacedName{Namespace: "apps", Name: "uaa-key"}, binding)).To(Succeed())
+		Expect(binding.Spec.ServiceInstanceName).To(Equal("my-uaa"))
+		Expect(binding.Annotations).To(HaveKeyWithValue(api.DRResourceIDAnnotation, "binding-1"))
+		Expect(adoptionPending(binding)).To(BeTrue())
+
+		params := smClient.ListBindingsArgsForCall(0)
+		Expect(params.FieldQuery).To(ConsistOf("service_instance_id in ('uaa-1')"))
+	})
+
+	It("should not import the instances and bindings twice", func() {
+		existing := &servicesv1.ServiceInstance{ObjectMeta: metav1.ObjectMeta{Name: "uaa", Namespace: "apps"}}
+		existing.Status.InstanceID = "uaa-1"
+		reconciler = newReconciler(run, existing)
+		updated := reconcileRun()
+
+		Expect(updated.Status.Instances).To(Equal(servicesv1.AdoptionCounts{AlreadyImported: 1, Skipped: 1}))
+		Expect(updated.Status.Bindings).To(Equal(servicesv1.AdoptionCounts{Imported: 1}))
+		binding := &servicesv1.ServiceBinding{}
+		Expect(reconciler.Client.Get(testCtx, types.NamespacedName{Namespace: "apps", Name: "uaa-key"}, binding)).To(Succeed())
+		Expect(binding.Spec.ServiceInstanceName).To(Equal("uaa"))
+	})
+
+	It("should report instances whose name is taken", func() {
+		taken := &servicesv1.ServiceInstance{ObjectMeta: metav1.ObjectMeta{Name: "my-uaa", Namespace: "apps"}}
+		reconciler = newReconciler(run, taken)
+		run.Spec.ImportBindings = false
+		updated := reconcileRun()
+
+		Expect(updated.Status.Instances.Failed).To(Equal(1))
+		Expect(updated.Status.Failures).To(ConsistOf(ContainSubstring("the name 'my-uaa' is taken")))
+		Expect(smClient.ListBindingsCallCount()).To(BeZero())
+	})
+
+	It("should import only the instances with the given IDs", func() {
+		const uaaID, missingID = "0f4e1c3a-6b1d-4a52-9d36-8e6f4c1b2a71", "5d7a2b90-3c4e-4f61-a8b2-1e9c0d3f4a56"
+		smClient.ListInstancesReturns(&smClientTypes.ServiceInstances{ServiceInstances: []smClientTypes.ServiceInstance{
+			{ID: uaaID, Name: "My_UAA", ServicePlanID: "application-id"},
+		}}, nil)
+		run.Spec.InstanceIDs = []string{uaaID, missingID}
+		run.Spec.ImportBindings = false
+		reconciler = newReconciler(run)
+		updated := reconcileRun()
+
+		Expect(updated.Status.Instances.Imported).To(Equal(1))
+		Expect(updated.Status.Instances.Skipped).To(BeZero())
+		Expect(updated.Status.Failures).To(ConsistOf(ContainSubstring("service instance '" + missingID + "': it was not found")))
+		params := smClient.ListInstancesArgsForCall(0)
+		Expect(params.FieldQuery).To(ConsistOf("id in ('" + uaaID + "','" + missingID + "')"))
+	})
+
+	It("should fail with instance IDs that are not UUIDs", func() {
+		run.Spec.InstanceIDs = []string{"uaa-1') or name eq ('db"}
+		reconciler = newReconciler(run)
+		expectFailure(reconcileRun(), "invalid instance ID")
+	})
+
+	It("should run once per generation", func() {
+		reconciler = newReconciler(run)
+		reconcileRun()
+		reconcileRun()
+		Expect(smClient.ListInstancesCallCount()).To(Equal(1))
+	})
+
+	It("should fail when the landscape and the credentials secret are both given", func() {
+		run.Spec.Landscape = "eu10"
+		run.Spec.BTPAccessCredentialsSecret = "my-secret"
+		reconciler = newReconciler(run)
+		updated := reconcileRun()
+
+		expectFailure(updated, "cannot be specified together")
+	})
+
+	It("should fail while the adoption is disabled", func() {
+		reconciler = newReconciler(run)
+		reconciler.Config.FeatureGates = config.FeatureGates{}
+		expectFailure(reconcileRun(), "adoption is disabled")
+	})
+
+	It("should fail in namespaces that are not allowed", func() {
+		reconciler = newReconciler(run)
+		reconciler.Config.AdoptionRunNamespaces = nil
+		expectFailure(reconcileRun(), "adoption runs are not allowed in namespace 'apps'")
+	})
+
+	It("should not import instances of unknown plans when filtering by offering", func() {
+		smClient.ListInstancesReturns(&smClientTypes.ServiceInstances{ServiceInstances: []smClientTypes.ServiceInstance{
+			{ID: "unknown-1", Name: "unknown", ServicePlanID: "unknown-plan"},
+		}}, nil)
+		reconciler = newReconciler(run)
+		updated := reconcileRun()
+
+		Expect(updated.Status.Instances).To(Equal(servicesv1.AdoptionCounts{}))
+		Expect(updated.Status.Failures).To(BeEmpty())
+	})
+
+	It("should approve the imported bindings", func() {
+		reconciler = newReconciler(run)
+		reconcileRun()
+
+		approved := &servicesv1.AdoptionRun{}
+		Expect(reconciler.Client.Get(testCtx, types.NamespacedName{Namespace: run.Namespace, Name: run.Name}, approved)).To(Succeed())
+		approved.Spec.ApproveBindings = true
+		approved.Generation = 2
+		Expect(reconciler.Client.Update(testCtx, approved)).To(Succeed())
+		updated := reconcileRun()
+
+		Expect(updated.Status.Bindings).To(Equal(servicesv1.AdoptionCounts{AlreadyImported: 1}))
+		Expect(meta.FindStatusCondition(updated.Status.Conditions, api.ConditionSucceeded).Message).To(ContainSubstring("approved 1 service bindings"))
+		binding := &servicesv1.ServiceBinding{}
+		Expect(reconciler.Client.Get(testCtx, types.NamespacedName{Namespace: "apps", Name: "uaa-key"}, binding)).To(Succeed())
+		Expect(adoptionPending(binding)).To(BeFalse())
+	})
+
+	Context("helpers", func() {
+		It("should derive valid resource names", func() {
+			Expect(adoptionResourceName("My_UAA", "id")).To(Equal("my-uaa"))
+			Expect(adoptionResourceName("--db.", "id")).To(Equal("db"))
+			Expect(adoptionResourceName("___", "ABC-123")).To(Equal("abc-123"))
+		})
+
+		It("should build sorted label queries", func() {
+			Expect(adoptionLabelQuery(map[string]string{"b": "2", "a": "1"})).To(Equal([]string{"a eq '1'", "b eq '2'"}))
+		})
+
+		It("should read the cluster ID of the context", func() {
+			Expect(smClusterID(clusterContext("c1"))).To(Equal("c1"))
+			Expect(smClusterID(json.RawMessage(`{"platform": "cloudfoundry"}`))).To(BeEmpty())
+			Expect(smClusterID(nil)).To(BeEmpty())
+		})
+	})
+})
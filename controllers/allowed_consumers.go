@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// allowedConsumersLabel is the label of a shared instance in SM with the namespaces whose bindings may consume it, SM
+// shares an instance with the whole subaccount and the operator enforces the label when it binds a shared instance
+const allowedConsumersLabel = "allowed_consumers"
+
+// desiredAllowedConsumers returns the sorted allowed consumers of the spec without duplicates
+func desiredAllowedConsumers(serviceInstance *servicesv1.ServiceInstance) []string {
+	if len(serviceInstance.Spec.AllowedConsumers) == 0 {
+		return nil
+	}
+	return sets.List(sets.New(serviceInstance.Spec.AllowedConsumers...))
+}
+
+// allowedConsumersUpdateRequired checks whether the allowed consumers of a shared instance changed since they were
+// labeled in SM
+func allowedConsumersUpdateRequired(serviceInstance *servicesv1.ServiceInstance) bool {
+	if serviceInstance.Status.Ready != metav1.ConditionTrue || !serviceInstance.ShouldBeShared() ||
+		!meta.IsStatusConditionTrue(serviceInstance.GetConditions(), api.ConditionShared) {
+		return false
+	}
+	return !sets.New(desiredAllowedConsumers(serviceInstance)...).Equal(sets.New(serviceInstance.Status.AllowedConsumers...))
+}
+
+// updateAllowedConsumers labels the shared instance in SM with its allowed consumers. SM doesn't support removing and
+// adding a label in the same request, the label is replaced with two requests.
+func (r *ServiceInstanceReconciler) updateAllowedConsumers(ctx context.Context, smClient sm.Client, serviceInstance *servicesv1.ServiceInstance) error {
+	desired := desiredAllowedConsumers(serviceInstance)
+	if sets.New(desired...).Equal(sets.New(serviceInstance.Status.AllowedConsumers...)) {
+		return nil
+	}
+	user := buildUserInfo(ctx, serviceInstance.Spec.UserInfo)
+	if len(serviceInstance.Status.AllowedConsumers) > 0 {
+		removeLabel := []*smClientTypes.LabelChange{{Key: allowedConsumersLabel, Operation: smClientTypes.RemoveLabelOperation}}
+		if err := smClient.UpdateInstanceLabels(serviceInstance.Status.InstanceID, removeLabel, user); err != nil {
+			return err
+		}
+		serviceInstance.Status.AllowedConsumers = nil
+	}
+	if len(desired) > 0 {
+		addLabel := []*smClientTypes.LabelChange{{Key: allowedConsumersLabel, Operation: smClientTypes.AddLabelOperation, Values: desired}}
+		if err := smClient.UpdateInstanceLabels(serviceInstance.Status.InstanceID, addLabel, user); err != nil {
+			return err
+		}
+	}
+	GetLogger(ctx).Info("updated the allowed consumers of the shared instance", "allowedConsumers", desired)
+	serviceInstance.Status.AllowedConsumers = desired
+	return nil
+}
+
+// verifyAllowedConsumer checks whether the namespace of the binding is an allowed consumer of the shared instance, all
+// namespaces are allowed if the instance has no allowed consumers
+func verifyAllowedConsumer(smInstance *smClientTypes.ServiceInstance, binding *servicesv1.ServiceBinding) error {
+	consumers := smInstance.Labels[allowedConsumersLabel]
+	if len(consumers) == 0 || contains(consumers, binding.Namespace) {
+		return nil
+	}
+	return fmt.Errorf("namespace '%s' is not an allowed consumer of instance '%s'", binding.Namespace, smInstance.ID)
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm/smfakes"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("Allowed consumers", func() {
+	var instance *servicesv1.ServiceInstance
+
+	BeforeEach(func() {
+		instance = &servicesv1.ServiceInstance{}
+		instance.Spec.Shared = pointer.Bool(true)
+		instance.Spec.AllowedConsumers = []string{"team-b", "team-a", "team-b"}
+		instance.Status.InstanceID = "instance-id"
+		instance.Status.Ready = metav1.ConditionTrue
+		meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{Type: api.ConditionShared, Status: metav1.ConditionTrue, Reason: ShareSucceeded})
+	})
+
+	Context("allowedConsumersUpdateRequired", func() {
+		It("should require an update of changed consumers of a shared instance", func() {
+			Expect(allowedConsumersUpdateRequired(instance)).To(BeTrue())
+			instance.Status.AllowedConsumers = []string{"team-a", "team-b"}
+			Expect(allowedConsumersUpdateRequired(instance)).To(BeFalse())
+		})
+
+		It("should not require an update of instances which are not shared", func() {
+			instance.Spec.Shared = pointer.Bool(false)
+			Expect(allowedConsumersUpdateRequired(instance)).To(BeFalse())
+		})
+	})
+
+	Context("updateAllowedConsumers", func() {
+		var reconciler *ServiceInstanceReconciler
+		var smClient *smfakes.FakeClient
+		var ctx context.Context
+
+		BeforeEach(func() {
+			reconciler = &ServiceInstanceReconciler{BaseReconciler: &BaseReconciler{}}
+			smClient = &smfakes.FakeClient{}
+			ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		})
+
+		It("should label the instance with the sorted consumers", func() {
+			Expect(reconciler.updateAllowedConsumers(ctx, smClient, instance)).To(Succeed())
+			Expect(smClient.UpdateInstanceLabelsCallCount()).To(Equal(1))
+			id, changes, _ := smClient.UpdateInstanceLabelsArgsForCall(0)
+			Expect(id).To(Equal("instance-id"))
+			Expect(changes).To(ConsistOf(&smClientTypes.LabelChange{Key: allowedConsumersLabel, Operation: smClientTypes.AddLabelOperation, Values: []string{"team-a", "team-b"}}))
+			Expect(instance.Status.AllowedConsumers).To(Equal([]string{"team-a", "team-b"}))
+		})
+
+		It("should replace the label of changed consumers", func() {
+			instance.Status.AllowedConsumers = []string{"team-a"}
+			Expect(reconciler.updateAllowedConsumers(ctx, smClient, instance)).To(Succeed())
+			Expect(smClient.UpdateInstanceLabelsCallCount()).To(Equal(2))
+			_, changes, _ := smClient.UpdateInstanceLabelsArgsForCall(0)
+			Expect(changes[0].Operation).To(Equal(smClientTypes.RemoveLabelOperation))
+			_, changes, _ = smClient.UpdateInstanceLabelsArgsForCall(1)
+			Expect(changes[0].Values).To(Equal([]string{"team-a", "team-b"}))
+		})
+
+		It("should remove the label once all namespaces are allowed", func() {
+			instance.Spec.AllowedConsumers = nil
+			instance.Status.AllowedConsumers = []string{"team-a"}
+			Expect(reconciler.updateAllowedConsumers(ctx, smClient, instance)).To(Succeed())
+			Expect(smClient.UpdateInstanceLabelsCallCount()).To(Equal(1))
+			Expect(instance.Status.AllowedConsumers).To(BeEmpty())
+		})
+
+		It("should keep the labeled consumers if SM fails", func() {
+			instance.Status.AllowedConsumers = []string{"team-a"}
+			smClient.UpdateInstanceLabelsReturns(fmt.Errorf("unavailable"))
+			Expect(reconciler.updateAllowedConsumers(ctx, smClient, instance)).ToNot(Succeed())
+			Expect(instance.Status.AllowedConsumers).To(Equal([]string{"team-a"}))
+		})
+	})
+
+	Context("verifyAllowedConsumer", func() {
+		var binding *servicesv1.ServiceBinding
+
+		BeforeEach(func() {
+			binding = &servicesv1.ServiceBinding{}
+			binding.Namespace = "team-c"
+		})
+
+		It("should permit all namespaces without allowed consumers", func() {
+			Expect(verifyAllowedConsumer(&smClientTypes.ServiceInstance{ID: "instance-id"}, binding)).To(Succeed())
+		})
+
+		It("should permit the allowed consumers only", func() {
+			smInstance := &smClientTypes.ServiceInstance{ID: "instance-id", Labels: smClientTypes.Labels{allowedConsumersLabel: {"team-a", "team-b"}}}
+			Expect(verifyAllowedConsumer(smInstance, binding)).To(MatchError("namespace 'team-c' is not an allowed consumer of instance 'instance-id'"))
+			binding.Namespace = "team-b"
+			Expect(verifyAllowedConsumer(smInstance, binding)).To(Succeed())
+		})
+	})
+})
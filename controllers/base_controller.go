@@ -2,8 +2,11 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/SAP/sap-btp-service-operator/api"
 	"github.com/SAP/sap-btp-service-operator/client/sm"
@@ -18,15 +21,12 @@ import (
 	apimachinerytypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 const (
-	namespaceLabel = "_namespace"
-	k8sNameLabel   = "_k8sname"
-	clusterIDLabel = "_clusterid"
-
 	Created        = "Created"
 	Updated        = "Updated"
 	Deleted        = "Deleted"
@@ -48,9 +48,21 @@ const (
 	ShareNotSupported = "ShareNotSupported"
 	UnShareFailed     = "UnShareFailed"
 	UnShareSucceeded  = "UnShareSucceeded"
-
-	Blocked = "Blocked"
-	Unknown = "Unknown"
+	InvalidParameters = "InvalidParameters"
+
+	Blocked        = "Blocked"
+	Pending        = "Pending"
+	Unknown        = "Unknown"
+	DriftDetected  = "DriftDetected"
+	NotReadyInSM   = "NotReadyInSM"
+	AlreadyDeleted = "AlreadyDeleted"
+	RotationHeld   = "RotationHeld"
+	InstanceUpdate = "InstanceUpdate"
+
+	// Operator config
+	ConfigApplied = "Applied"
+	ConfigInvalid = "Invalid"
+	ConfigIgnored = "Ignored"
 
 	// Cred Rotation
 	CredPreparing = "Preparing"
@@ -68,13 +80,56 @@ type BaseReconciler struct {
 	Config         config.Config
 	SecretResolver *secrets.SecretResolver
 	Recorder       record.EventRecorder
+	// APIReader reads objects which are not cached by the manager, e.g. the config maps generated by secret templates
+	APIReader client.Reader
+	// Live holds the settings changed at runtime with the OperatorConfig, the settings of Config are used if it is nil
+	Live *config.Live
+
+	// descriptionUpdates holds the last time the description of an ongoing operation was persisted, by object UID
+	descriptionUpdates sync.Map
+	// startup is set when the staged startup is enabled
+	startup *stagedStartup
+	// smCredentials holds the access credentials secret the resources were last reconciled with, by resource
+	smCredentials sync.Map
+	// smRequests holds the time of the last call to SM, by resource
+	smRequests sync.Map
+	// operationStore keeps the ongoing operations of the resources, created on first use
+	operationStore     operationStore
+	operationStoreOnce sync.Once
+	// catalogLookups holds the plans resolved in the catalog of SM until they expire, by access credentials secret,
+	// offering and plan
+	catalogLookups sync.Map
+}
+
+// apiReader returns the reader for objects which are not cached, the client if none is configured
+func (r *BaseReconciler) apiReader() client.Reader {
+	if r.APIReader == nil {
+		return r.Client
+	}
+	return r.APIReader
+}
+
+// settings returns the current settings of the operator, changed at runtime with the OperatorConfig
+func (r *BaseReconciler) settings() config.Settings {
+	if r.Live == nil {
+		return config.NewLive(r.Config).Current()
+	}
+	return r.Live.Current()
+}
+
+func (r *BaseReconciler) pollInterval() time.Duration {
+	return r.settings().PollInterval
+}
+
+func (r *BaseReconciler) longPollInterval() time.Duration {
+	return r.settings().LongPollInterval
 }
 
 func GetLogger(ctx context.Context) logr.Logger {
 	return ctx.Value(LogKey{}).(logr.Logger)
 }
 
-func (r *BaseReconciler) getSMClient(ctx context.Context, object api.SAPBTPResource, subaccountID string) (sm.Client, error) {
+func (r *BaseReconciler) getSMClient(ctx context.Context, object client.Object, subaccountID string) (sm.Client, error) {
 	if r.SMClient != nil {
 		return r.SMClient(), nil
 	}
@@ -86,6 +141,7 @@ func (r *BaseReconciler) getSMClient(ctx context.Context, object api.SAPBTPResou
 	}
 
 	secretData := secret.Data
+	credentials := secret.Namespace + "/" + secret.Name
 	cfg := &sm.ClientConfig{
 		ClientID:       string(secretData["clientid"]),
 		ClientSecret:   string(secretData["clientsecret"]),
@@ -93,6 +149,17 @@ func (r *BaseReconciler) getSMClient(ctx context.Context, object api.SAPBTPResou
 		TokenURL:       string(secretData["tokenurl"]),
 		TokenURLSuffix: string(secretData["tokenurlsuffix"]),
 		SSLDisabled:    false,
+		OnRequest:      r.countSMCalls(apimachinerytypes.NamespacedName{Namespace: object.GetNamespace(), Name: object.GetName()}, credentials),
+		Timeouts: sm.Timeouts{
+			Catalog:   r.Config.SMCatalogTimeout,
+			Provision: r.Config.SMProvisionTimeout,
+			Poll:      r.Config.SMPollTimeout,
+			Bind:      r.Config.SMBindTimeout,
+			Default:   r.Config.SMRequestTimeout,
+		},
+		OnTimeout: func(budget sm.CallBudget) {
+			smCallTimeouts.WithLabelValues(string(budget)).Inc()
+		},
 	}
 
 	if len(cfg.ClientSecret) == 0 {
@@ -108,8 +175,13 @@ func (r *BaseReconciler) getSMClient(ctx context.Context, object api.SAPBTPResou
 		}
 	}
 
-	cl, err := sm.NewClient(ctx, cfg, nil)
-	return cl, err
+	httpClient, err := smClients.get(log, credentials, cfg, func() {
+		smCalls.record(credentials, time.Now(), r.Config.SMCallQuotaWindow)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sm.NewClient(ctx, cfg, httpClient)
 }
 
 func (r *BaseReconciler) removeFinalizer(ctx context.Context, object api.SAPBTPResource, finalizerName string) error {
@@ -127,6 +199,13 @@ func (r *BaseReconciler) removeFinalizer(ctx context.Context, object api.SAPBTPR
 			}
 		}
 		log.Info(fmt.Sprintf("removed finalizer %s from %s", finalizerName, object.GetControllerName()))
+		if object.GetDeletionTimestamp() != nil {
+			recordOperationSucceeded(smClientTypes.DELETE, object)
+		}
+		r.descriptionUpdates.Delete(object.GetUID())
+		if err := r.operations().forget(ctx, object); err != nil {
+			log.Error(err, "failed to forget the operation of the removed resource")
+		}
 		return nil
 	}
 	return nil
@@ -135,12 +214,14 @@ func (r *BaseReconciler) removeFinalizer(ctx context.Context, object api.SAPBTPR
 func (r *BaseReconciler) updateStatus(ctx context.Context, object api.SAPBTPResource) error {
 	log := GetLogger(ctx)
 	log.Info(fmt.Sprintf("updating %s status", object.GetObjectKind().GroupVersionKind().Kind))
+	setPhase(object)
+	defer r.operations().detach(object)()
 	return r.Client.Status().Update(ctx, object)
 }
 
 func (r *BaseReconciler) init(ctx context.Context, obj api.SAPBTPResource) error {
 	obj.SetReady(metav1.ConditionFalse)
-	setInProgressConditions(ctx, smClientTypes.CREATE, "Pending", obj)
+	setInProgressConditions(ctx, smClientTypes.CREATE, Pending, obj)
 	return r.updateStatus(ctx, obj)
 }
 
@@ -206,12 +287,45 @@ func setInProgressConditions(ctx context.Context, operationType smClientTypes.Op
 	}
 	meta.SetStatusCondition(&conditions, lastOpCondition)
 	meta.SetStatusCondition(&conditions, getReadyCondition(object))
+	meta.SetStatusCondition(&conditions, getProgressingCondition(object, metav1.ConditionTrue, lastOpCondition.Reason, message))
 
 	object.SetConditions(conditions)
 	log.Info(fmt.Sprintf("setting inProgress conditions: reason: %s, message:%s, generation: %d", lastOpCondition.Reason, message, object.GetGeneration()))
 }
 
+// descriptionUpdateRequired checks whether the description of an ongoing operation should be persisted in the status,
+// a change of the operation is persisted right away while description changes are persisted at most once per PollDescriptionInterval
+func (r *BaseReconciler) descriptionUpdateRequired(object api.SAPBTPResource, operationType smClientTypes.OperationCategory, description string) bool {
+	cond := meta.FindStatusCondition(object.GetConditions(), api.ConditionSucceeded)
+	if cond == nil || cond.Reason != getConditionReason(operationType, smClientTypes.INPROGRESS) || meta.IsStatusConditionTrue(object.GetConditions(), api.ConditionFailed) {
+		r.descriptionUpdates.Store(object.GetUID(), time.Now())
+		return true
+	}
+	if cond.Message == description {
+		return false
+	}
+
+	if lastUpdate, ok := r.descriptionUpdates.Load(object.GetUID()); ok && time.Since(lastUpdate.(time.Time)) < r.Config.PollDescriptionInterval {
+		suppressedDescriptionUpdates.WithLabelValues(string(object.GetControllerName())).Inc()
+		return false
+	}
+	r.descriptionUpdates.Store(object.GetUID(), time.Now())
+	return true
+}
+
 func setSuccessConditions(operationType smClientTypes.OperationCategory, object api.SAPBTPResource) {
+	recordOperationSucceeded(operationType, object)
+	setSucceededConditions(operationType, object)
+}
+
+// setRecoveredConditions sets the conditions of a resource whose operation succeeded before it was recovered from SM,
+// the operation is not timed as the resource may have been created long before
+func setRecoveredConditions(operationType smClientTypes.OperationCategory, object api.SAPBTPResource) {
+	recordOperationRecovered(operationType, object)
+	setSucceededConditions(operationType, object)
+}
+
+func setSucceededConditions(operationType smClientTypes.OperationCategory, object api.SAPBTPResource) {
 	var message string
 	if operationType == smClientTypes.CREATE {
 		message = fmt.Sprintf("%s provisioned successfully", object.GetControllerName())
@@ -241,12 +355,46 @@ func setSuccessConditions(operationType smClientTypes.OperationCategory, object
 	}
 	meta.SetStatusCondition(&conditions, lastOpCondition)
 	meta.SetStatusCondition(&conditions, readyCondition)
+	meta.SetStatusCondition(&conditions, getProgressingCondition(object, metav1.ConditionFalse, lastOpCondition.Reason, message))
 
 	object.SetConditions(conditions)
 	object.SetReady(metav1.ConditionTrue)
 
 }
 
+// setDegradedConditions marks a resource that SM reports as not ready although its last operation did not fail,
+// e.g. during maintenance of the broker, so that it is not flipped to failed
+func setDegradedConditions(object api.SAPBTPResource) {
+	message := fmt.Sprintf("%s is reported as not ready by Service Manager", object.GetControllerName())
+	conditions := object.GetConditions()
+	meta.SetStatusCondition(&conditions, metav1.Condition{
+		Type:               api.ConditionReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             NotReadyInSM,
+		Message:            message,
+		ObservedGeneration: object.GetObservedGeneration(),
+	})
+	meta.SetStatusCondition(&conditions, metav1.Condition{
+		Type:               api.ConditionDegraded,
+		Status:             metav1.ConditionTrue,
+		Reason:             NotReadyInSM,
+		Message:            message,
+		ObservedGeneration: object.GetObservedGeneration(),
+	})
+	object.SetConditions(conditions)
+	object.SetReady(metav1.ConditionFalse)
+}
+
+func removeDegradedCondition(object api.SAPBTPResource) {
+	conditions := object.GetConditions()
+	meta.RemoveStatusCondition(&conditions, api.ConditionDegraded)
+	object.SetConditions(conditions)
+}
+
+func isDegraded(object api.SAPBTPResource) bool {
+	return meta.IsStatusConditionTrue(object.GetConditions(), api.ConditionDegraded)
+}
+
 func setCredRotationInProgressConditions(reason, message string, object api.SAPBTPResource) {
 	if len(message) == 0 {
 		message = reason
@@ -273,6 +421,8 @@ func setFailureConditions(operationType smClientTypes.OperationCategory, errorMe
 		message = fmt.Sprintf("%s deletion failed: %s", object.GetControllerName(), errorMessage)
 	}
 
+	recordOperationFailed(operationType, message, object)
+
 	var reason string
 	if operationType != Unknown {
 		reason = getConditionReason(operationType, smClientTypes.FAILED)
@@ -299,6 +449,7 @@ func setFailureConditions(operationType smClientTypes.OperationCategory, errorMe
 	}
 	meta.SetStatusCondition(&conditions, failedCondition)
 	meta.SetStatusCondition(&conditions, getReadyCondition(object))
+	meta.SetStatusCondition(&conditions, getProgressingCondition(object, metav1.ConditionFalse, reason, message))
 
 	object.SetConditions(conditions)
 }
@@ -308,6 +459,10 @@ func setBlockedCondition(ctx context.Context, message string, object api.SAPBTPR
 	setInProgressConditions(ctx, Unknown, message, object)
 	lastOpCondition := meta.FindStatusCondition(object.GetConditions(), api.ConditionSucceeded)
 	lastOpCondition.Reason = Blocked
+	// the resource does not progress until the user acts
+	conditions := object.GetConditions()
+	meta.SetStatusCondition(&conditions, getProgressingCondition(object, metav1.ConditionFalse, Blocked, message))
+	object.SetConditions(conditions)
 }
 
 func isMarkedForDeletion(object metav1.ObjectMeta) bool {
@@ -326,6 +481,25 @@ func isConcurrentOperationError(smError *sm.ServiceManagerError) bool {
 	return smError.StatusCode == http.StatusUnprocessableEntity && smError.ErrorType == "ConcurrentOperationInProgress"
 }
 
+// isAlreadyDeletedError checks whether SM or the broker reports the resource to delete as gone, e.g. a binding the broker
+// deleted together with its instance, so that deleting it again succeeds. SM reports the answer of the broker in the
+// broker error of a 502 or 400 response, and the error may be wrapped by the caller.
+func isAlreadyDeletedError(err error) bool {
+	var smError *sm.ServiceManagerError
+	if !errors.As(err, &smError) {
+		return false
+	}
+	statusCode := smError.GetStatusCode()
+	return statusCode == http.StatusNotFound || statusCode == http.StatusGone
+}
+
+// recordAlreadyDeleted reports a resource that was deleted in SM before the operator deleted it
+func (r *BaseReconciler) recordAlreadyDeleted(ctx context.Context, resource api.SAPBTPResource, id string, err error) {
+	message := fmt.Sprintf("the %s '%s' was already deleted in Service Manager", resource.GetControllerName(), id)
+	GetLogger(ctx).Info(message, "error", err.Error())
+	r.Recorder.Event(resource, v1.EventTypeNormal, AlreadyDeleted, message)
+}
+
 func isTransientStatusCode(StatusCode int) bool {
 	return StatusCode == http.StatusTooManyRequests ||
 		StatusCode == http.StatusServiceUnavailable ||
@@ -376,6 +550,27 @@ func (r *BaseReconciler) markAsTransientError(ctx context.Context, operationType
 	return ctrl.Result{}, fmt.Errorf(errMsg)
 }
 
+// setupWatch watches the resources of the controller, with the staged startup the initial reconciles are ordered by the startup handler
+func (r *BaseReconciler) setupWatch(mgr ctrl.Manager, name string, object client.Object, newList func() client.ObjectList) (*builder.Builder, error) {
+	b := ctrl.NewControllerManagedBy(mgr)
+	if !r.Config.StagedStartup {
+		return b.For(object), nil
+	}
+
+	r.startup = newStagedStartup(mgr, name, newList, r.Config.StartupWindow)
+	if err := mgr.Add(r.startup); err != nil {
+		return nil, err
+	}
+	return b.Named(name).Watches(object, r.startup), nil
+}
+
+// startupReconciled records the reconcile of a request for the startup convergence metric
+func (r *BaseReconciler) startupReconciled(req ctrl.Request) {
+	if r.startup != nil {
+		r.startup.reconciled(req)
+	}
+}
+
 func isInProgress(object api.SAPBTPResource) bool {
 	conditions := object.GetConditions()
 	return meta.IsStatusConditionPresentAndEqual(conditions, api.ConditionSucceeded, metav1.ConditionFalse) &&
@@ -395,10 +590,30 @@ func isFailed(resource api.SAPBTPResource) bool {
 func getReadyCondition(object api.SAPBTPResource) metav1.Condition {
 	status := metav1.ConditionFalse
 	reason := NotProvisioned
+	message := fmt.Sprintf("%s is not provisioned", object.GetControllerName())
 	if object.GetReady() == metav1.ConditionTrue {
 		status = metav1.ConditionTrue
 		reason = Provisioned
+		message = fmt.Sprintf("%s is provisioned", object.GetControllerName())
 	}
 
-	return metav1.Condition{Type: api.ConditionReady, Status: status, Reason: reason}
+	return metav1.Condition{
+		Type:               api.ConditionReady,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: object.GetObservedGeneration(),
+	}
+}
+
+// getProgressingCondition returns the Progressing condition, which is true while an operation of the resource is in progress
+// so that tools like Argo CD can tell a resource that is still being reconciled from a resource that is ready or failed
+func getProgressingCondition(object api.SAPBTPResource, status metav1.ConditionStatus, reason, message string) metav1.Condition {
+	return metav1.Condition{
+		Type:               api.ConditionProgressing,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: object.GetObservedGeneration(),
+	}
 }
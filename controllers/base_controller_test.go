@@ -0,0 +1,283 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	v1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+var _ = Describe("Base Controller", func() {
+
+	Context("description update throttling", func() {
+		var (
+			reconciler *BaseReconciler
+			instance   *v1.ServiceInstance
+		)
+
+		BeforeEach(func() {
+			reconciler = &BaseReconciler{Config: config.Config{PollDescriptionInterval: time.Hour}}
+			instance = &v1.ServiceInstance{}
+			instance.UID = "throttled-instance"
+			ctx := context.WithValue(context.Background(), LogKey{}, ctrl.Log.WithName("baseControllerTest"))
+			setInProgressConditions(ctx, smClientTypes.UPDATE, "step 1", instance)
+		})
+
+		It("should persist a change of the operation", func() {
+			Expect(reconciler.descriptionUpdateRequired(instance, smClientTypes.DELETE, "step 1")).To(BeTrue())
+		})
+
+		It("should not persist an unchanged description", func() {
+			Expect(reconciler.descriptionUpdateRequired(instance, smClientTypes.UPDATE, "step 1")).To(BeFalse())
+		})
+
+		It("should persist the first description change and suppress further changes within the interval", func() {
+			suppressed := testutil.ToFloat64(suppressedDescriptionUpdates.WithLabelValues("ServiceInstance"))
+			Expect(reconciler.descriptionUpdateRequired(instance, smClientTypes.UPDATE, "step 2")).To(BeTrue())
+			Expect(reconciler.descriptionUpdateRequired(instance, smClientTypes.UPDATE, "step 3")).To(BeFalse())
+			Expect(testutil.ToFloat64(suppressedDescriptionUpdates.WithLabelValues("ServiceInstance"))).To(Equal(suppressed + 1))
+		})
+
+		It("should persist description changes once the interval passed", func() {
+			reconciler.descriptionUpdates.Store(instance.UID, time.Now().Add(-2*time.Hour))
+			Expect(reconciler.descriptionUpdateRequired(instance, smClientTypes.UPDATE, "step 2")).To(BeTrue())
+		})
+
+		It("should forget the object once its finalizer is removed", func() {
+			testScheme := runtime.NewScheme()
+			Expect(v1.AddToScheme(testScheme)).To(Succeed())
+			instance.Name = "throttled-instance"
+			instance.Namespace = "apps"
+			instance.Finalizers = []string{api.FinalizerName}
+			reconciler.Client = fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance).Build()
+			Expect(reconciler.Client.Get(context.Background(), client.ObjectKeyFromObject(instance), instance)).To(Succeed())
+			Expect(reconciler.descriptionUpdateRequired(instance, smClientTypes.UPDATE, "step 2")).To(BeTrue())
+
+			ctx := context.WithValue(context.Background(), LogKey{}, ctrl.Log.WithName("baseControllerTest"))
+			Expect(reconciler.removeFinalizer(ctx, instance, api.FinalizerName)).To(Succeed())
+			_, found := reconciler.descriptionUpdates.Load(instance.UID)
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	Context("conditions", func() {
+		var (
+			ctx      context.Context
+			instance *v1.ServiceInstance
+		)
+
+		BeforeEach(func() {
+			ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log.WithName("baseControllerTest"))
+			instance = &v1.ServiceInstance{}
+			instance.Generation = 2
+			instance.SetObservedGeneration(2)
+		})
+
+		expectConditionsOfGeneration := func(generation int64) {
+			for _, condition := range instance.GetConditions() {
+				Expect(condition.ObservedGeneration).To(Equal(generation), condition.Type)
+				Expect(condition.Reason).ToNot(BeEmpty(), condition.Type)
+			}
+		}
+
+		It("should be progressing while an operation is in progress", func() {
+			setInProgressConditions(ctx, smClientTypes.UPDATE, "", instance)
+			Expect(meta.IsStatusConditionTrue(instance.GetConditions(), api.ConditionProgressing)).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(instance.GetConditions(), api.ConditionReady)).To(BeTrue())
+			expectConditionsOfGeneration(2)
+		})
+
+		It("should be ready and not progressing once the operation succeeded", func() {
+			setInProgressConditions(ctx, smClientTypes.CREATE, "", instance)
+			setSuccessConditions(smClientTypes.CREATE, instance)
+			Expect(meta.IsStatusConditionFalse(instance.GetConditions(), api.ConditionProgressing)).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(instance.GetConditions(), api.ConditionReady)).To(BeTrue())
+			expectConditionsOfGeneration(2)
+		})
+
+		It("should not be progressing once the operation failed", func() {
+			setInProgressConditions(ctx, smClientTypes.CREATE, "", instance)
+			setFailureConditions(smClientTypes.CREATE, "invalid parameters", instance)
+			Expect(meta.FindStatusCondition(instance.GetConditions(), api.ConditionProgressing).Reason).To(Equal(CreateFailed))
+			Expect(meta.IsStatusConditionFalse(instance.GetConditions(), api.ConditionProgressing)).To(BeTrue())
+			expectConditionsOfGeneration(2)
+		})
+
+		It("should not be progressing while blocked", func() {
+			setBlockedCondition(ctx, "waiting for the user", instance)
+			Expect(meta.FindStatusCondition(instance.GetConditions(), api.ConditionProgressing).Reason).To(Equal(Blocked))
+			Expect(meta.IsStatusConditionFalse(instance.GetConditions(), api.ConditionProgressing)).To(BeTrue())
+		})
+	})
+
+	Context("staged startup", func() {
+		var (
+			startup  *stagedStartup
+			queue    workqueue.RateLimitingInterface
+			instance *v1.ServiceInstance
+		)
+
+		BeforeEach(func() {
+			startup = &stagedStartup{controller: "startup-test", window: time.Hour, log: ctrl.Log.WithName("startupTest")}
+			startup.expected.Store(-1)
+			queue = workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+			instance = &v1.ServiceInstance{}
+			instance.Name = "startup-instance"
+			instance.Namespace = "default"
+			instance.Generation = 1
+			instance.Status.ObservedGeneration = 1
+			instance.Status.Ready = metav1.ConditionTrue
+		})
+
+		AfterEach(func() {
+			queue.ShutDown()
+		})
+
+		It("should defer the reconcile of a resource in a final state", func() {
+			deferred := testutil.ToFloat64(startupDeferredReconciles.WithLabelValues("startup-test"))
+			startup.Create(context.Background(), event.CreateEvent{Object: instance}, queue)
+			Expect(queue.Len()).To(Equal(0))
+			Expect(testutil.ToFloat64(startupDeferredReconciles.WithLabelValues("startup-test"))).To(Equal(deferred + 1))
+		})
+
+		It("should reconcile a resource with pending changes immediately", func() {
+			instance.Generation = 2
+			startup.Create(context.Background(), event.CreateEvent{Object: instance}, queue)
+			Expect(queue.Len()).To(Equal(1))
+		})
+
+		It("should reconcile a resource which is not ready immediately", func() {
+			instance.Status.Ready = metav1.ConditionFalse
+			startup.Create(context.Background(), event.CreateEvent{Object: instance}, queue)
+			Expect(queue.Len()).To(Equal(1))
+		})
+
+		It("should start the startup window on the first event", func() {
+			startup.Create(context.Background(), event.CreateEvent{Object: instance}, queue)
+			Expect(startup.startedAt).NotTo(BeZero())
+			Expect(time.Since(startup.startedAt)).To(BeNumerically("<", time.Minute))
+		})
+
+		It("should not defer reconciles after the startup window", func() {
+			startup.start()
+			startup.startedAt = time.Now().Add(-2 * time.Hour)
+			startup.Create(context.Background(), event.CreateEvent{Object: instance}, queue)
+			Expect(queue.Len()).To(Equal(1))
+		})
+
+		It("should count the resources on start", func() {
+			testScheme := runtime.NewScheme()
+			Expect(v1.AddToScheme(testScheme)).To(Succeed())
+			startup.reader = fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance).Build()
+			startup.newList = func() client.ObjectList { return &v1.ServiceInstanceList{} }
+			Expect(startup.Start(context.Background())).To(Succeed())
+			Expect(startup.expected.Load()).To(Equal(int64(1)))
+			Expect(startup.startedAt).NotTo(BeZero())
+		})
+
+		It("should count the resources with a single LIST of the cache", func() {
+			testScheme := runtime.NewScheme()
+			Expect(v1.AddToScheme(testScheme)).To(Succeed())
+			second := instance.DeepCopy()
+			second.Name = "startup-instance-2"
+			lists := 0
+			startup.reader = fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance, second).WithInterceptorFuncs(interceptor.Funcs{
+				List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+					lists++
+					return c.List(ctx, list, opts...)
+				},
+			}).Build()
+			startup.newList = func() client.ObjectList { return &v1.ServiceInstanceList{} }
+			Expect(startup.Start(context.Background())).To(Succeed())
+			Expect(startup.expected.Load()).To(Equal(int64(2)))
+			Expect(lists).To(Equal(1))
+		})
+
+		It("should report the convergence once all initial resources were reconciled", func() {
+			startup.expected.Store(1)
+			startup.Create(context.Background(), event.CreateEvent{Object: instance}, queue)
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "startup-instance"}}
+			startup.reconciled(req)
+			Expect(startup.reconciledCount.Load()).To(Equal(int64(1)))
+			Expect(testutil.ToFloat64(startupConvergenceSeconds.WithLabelValues("startup-test"))).To(BeNumerically(">", 0))
+
+			startup.reconciled(req)
+			Expect(startup.reconciledCount.Load()).To(Equal(int64(1)))
+		})
+	})
+
+	Context("retry tracking rate limiter", func() {
+		var rateLimiter *retryTrackingRateLimiter
+
+		BeforeEach(func() {
+			rateLimiter = newRetryTrackingRateLimiter("ratelimiter-test", workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Second))
+		})
+
+		It("should track retried items until they are forgotten", func() {
+			retries := testutil.ToFloat64(reconcileRetries.WithLabelValues("ratelimiter-test"))
+			rateLimiter.When("first")
+			rateLimiter.When("first")
+			rateLimiter.When("second")
+			Expect(testutil.ToFloat64(reconcileRetries.WithLabelValues("ratelimiter-test"))).To(Equal(retries + 3))
+			Expect(rateLimiter.NumRequeues("first")).To(Equal(2))
+
+			count, oldest := rateLimiter.stats()
+			Expect(count).To(Equal(2))
+			first, _ := rateLimiter.retryingSince.Load("first")
+			Expect(oldest).To(Equal(first))
+
+			rateLimiter.Forget("first")
+			count, _ = rateLimiter.stats()
+			Expect(count).To(Equal(1))
+			Expect(rateLimiter.NumRequeues("first")).To(BeZero())
+		})
+	})
+
+	Context("isAlreadyDeletedError", func() {
+		It("should detect a resource SM doesn't find", func() {
+			err := &sm.ServiceManagerError{StatusCode: http.StatusNotFound, ErrorType: "NotFound", Description: "could not find such service_binding"}
+			Expect(isAlreadyDeletedError(err)).To(BeTrue())
+		})
+
+		It("should detect a resource the broker reports as gone", func() {
+			err := &sm.ServiceManagerError{
+				StatusCode:  http.StatusBadGateway,
+				ErrorType:   "BrokerError",
+				Description: "Service broker failed",
+				BrokerError: &api.HTTPStatusCodeError{StatusCode: http.StatusGone},
+			}
+			Expect(isAlreadyDeletedError(err)).To(BeTrue())
+			Expect(isAlreadyDeletedError(fmt.Errorf("failed to delete binding: %w", err))).To(BeTrue())
+		})
+
+		It("should not detect other failures", func() {
+			err := &sm.ServiceManagerError{
+				StatusCode:  http.StatusBadGateway,
+				ErrorType:   "BrokerError",
+				Description: "Service broker failed",
+				BrokerError: &api.HTTPStatusCodeError{StatusCode: http.StatusInternalServerError},
+			}
+			Expect(isAlreadyDeletedError(err)).To(BeFalse())
+			Expect(isAlreadyDeletedError(fmt.Errorf("not found"))).To(BeFalse())
+		})
+	})
+})
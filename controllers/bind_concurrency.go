@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// bindSlotWaitTimeout is the time after which a binding waiting for a bind slot no longer waits for earlier bindings
+const bindSlotWaitTimeout = 5 * time.Minute
+
+type instanceSnapshotKey struct{}
+
+// withInstanceSnapshot shares the instance read at the start of a reconcile with the later steps of the reconcile,
+// which then work on the same state of the instance instead of reading it again
+func withInstanceSnapshot(ctx context.Context, serviceInstance *servicesv1.ServiceInstance) context.Context {
+	return context.WithValue(ctx, instanceSnapshotKey{}, serviceInstance)
+}
+
+func instanceSnapshot(ctx context.Context, key types.NamespacedName) *servicesv1.ServiceInstance {
+	serviceInstance, ok := ctx.Value(instanceSnapshotKey{}).(*servicesv1.ServiceInstance)
+	if !ok || client.ObjectKeyFromObject(serviceInstance) != key {
+		return nil
+	}
+	return serviceInstance
+}
+
+// bindSlotAvailable limits the bindings of an instance with a pending create operation in SM to
+// BindConcurrency, so that brokers are not flooded with concurrent binds when many bindings of an
+// instance are applied together. The waiting bindings take the free slots in the order of their creation,
+// earlier bindings which are blocked are skipped, and once a binding waited for bindSlotWaitTimeout it
+// only waits for the pending operations, so that an earlier binding which never binds can't block it.
+func (r *ServiceBindingReconciler) bindSlotAvailable(ctx context.Context, serviceInstance *servicesv1.ServiceInstance, serviceBinding *servicesv1.ServiceBinding) (bool, string, error) {
+	if r.Config.BindConcurrency <= 0 {
+		return true, "", nil
+	}
+
+	bindingList := &servicesv1.ServiceBindingList{}
+	if err := r.Client.List(ctx, bindingList, client.MatchingFields{bindingInstanceIndex: client.ObjectKeyFromObject(serviceInstance).String()}); err != nil {
+		return false, "", err
+	}
+
+	waitingSince := time.Now()
+	if value, loaded := r.bindSlotWaits.LoadOrStore(serviceBinding.UID, waitingSince); loaded {
+		waitingSince = value.(time.Time)
+	}
+	waitedTooLong := time.Since(waitingSince) >= bindSlotWaitTimeout
+
+	pending := 0
+	var waiting []servicesv1.ServiceBinding
+	for _, binding := range bindingList.Items {
+		if binding.UID == serviceBinding.UID {
+			continue
+		}
+		if err := r.operations().load(ctx, &binding); err != nil {
+			return false, "", err
+		}
+		if binding.Status.OperationType == smClientTypes.CREATE && len(binding.Status.OperationURL) > 0 {
+			pending++
+		} else if !waitedTooLong && len(binding.Status.BindingID) == 0 && !isMarkedForDeletion(binding.ObjectMeta) &&
+			!isFailed(&binding) && !isBlocked(&binding) {
+			waiting = append(waiting, binding)
+		}
+	}
+	waiting = append(waiting, *serviceBinding)
+	sort.SliceStable(waiting, func(i, j int) bool {
+		if !waiting[i].CreationTimestamp.Equal(&waiting[j].CreationTimestamp) {
+			return waiting[i].CreationTimestamp.Before(&waiting[j].CreationTimestamp)
+		}
+		return waiting[i].Namespace+"/"+waiting[i].Name < waiting[j].Namespace+"/"+waiting[j].Name
+	})
+
+	for position, binding := range waiting {
+		if binding.UID == serviceBinding.UID {
+			if pending+position < r.Config.BindConcurrency {
+				r.bindSlotWaits.Delete(serviceBinding.UID)
+				return true, "", nil
+			}
+			return false, fmt.Sprintf("creation in progress, waiting for %d pending and %d earlier bindings of service instance '%s'",
+				pending, position, serviceInstance.Name), nil
+		}
+	}
+	return true, "", nil
+}
+
+// isBlocked checks whether the binding waits for an action of the user, e.g. the sharing of its instance
+func isBlocked(binding *servicesv1.ServiceBinding) bool {
+	condition := meta.FindStatusCondition(binding.Status.Conditions, api.ConditionSucceeded)
+	return condition != nil && condition.Reason == Blocked
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Bind concurrency", func() {
+	var ctx context.Context
+	var serviceInstance *servicesv1.ServiceInstance
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newBinding := func(name string, age time.Duration) *servicesv1.ServiceBinding {
+		return &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "app1", UID: types.UID(name + "-uid"), CreationTimestamp: metav1.NewTime(created.Add(-age))},
+			Spec:       servicesv1.ServiceBindingSpec{ServiceInstanceName: "my-instance"},
+		}
+	}
+	newReconciler := func(limit int, bindings ...client.Object) *ServiceBindingReconciler {
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		return &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(bindings...).
+				WithIndex(&servicesv1.ServiceBinding{}, bindingInstanceIndex, indexBindingInstance).Build(),
+			Scheme: testScheme,
+			Config: config.Config{BindConcurrency: limit},
+		}}
+	}
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		serviceInstance = &servicesv1.ServiceInstance{ObjectMeta: metav1.ObjectMeta{Name: "my-instance", Namespace: "app1"}}
+	})
+
+	It("should not limit the binds without a configured concurrency", func() {
+		binding := newBinding("binding", 0)
+		available, _, err := newReconciler(0).bindSlotAvailable(ctx, serviceInstance, binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(available).To(BeTrue())
+	})
+
+	It("should wait for the pending bind operations of the instance", func() {
+		pendingBinding := newBinding("pending", time.Hour)
+		pendingBinding.Status.OperationType = smClientTypes.CREATE
+		pendingBinding.Status.OperationURL = "/v1/service_bindings/1234/operations/5678"
+		binding := newBinding("binding", 0)
+		available, waitingFor, err := newReconciler(1, pendingBinding, binding).bindSlotAvailable(ctx, serviceInstance, binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(available).To(BeFalse())
+		Expect(waitingFor).To(ContainSubstring("waiting for 1 pending and 0 earlier bindings of service instance 'my-instance'"))
+	})
+
+	It("should bind the waiting bindings in the order of their creation", func() {
+		older := newBinding("older", time.Minute)
+		newer := newBinding("newer", 0)
+		reconciler := newReconciler(1, older, newer)
+		available, _, err := reconciler.bindSlotAvailable(ctx, serviceInstance, newer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(available).To(BeFalse())
+		available, _, err = reconciler.bindSlotAvailable(ctx, serviceInstance, older)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(available).To(BeTrue())
+	})
+
+	It("should skip earlier bindings which are blocked", func() {
+		blocked := newBinding("blocked", time.Minute)
+		setBlockedCondition(ctx, "the instance is not shared", blocked)
+		binding := newBinding("binding", 0)
+		available, _, err := newReconciler(1, blocked, binding).bindSlotAvailable(ctx, serviceInstance, binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(available).To(BeTrue())
+	})
+
+	It("should stop waiting for earlier bindings after the wait timeout", func() {
+		older := newBinding("older", time.Minute)
+		newer := newBinding("newer", 0)
+		reconciler := newReconciler(1, older, newer)
+		available, _, err := reconciler.bindSlotAvailable(ctx, serviceInstance, newer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(available).To(BeFalse())
+
+		reconciler.bindSlotWaits.Store(newer.UID, time.Now().Add(-bindSlotWaitTimeout))
+		available, _, err = reconciler.bindSlotAvailable(ctx, serviceInstance, newer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(available).To(BeTrue())
+		_, waiting := reconciler.bindSlotWaits.Load(newer.UID)
+		Expect(waiting).To(BeFalse())
+	})
+
+	It("should ignore the bindings of other instances and bound bindings", func() {
+		otherInstance := newBinding("other", time.Hour)
+		otherInstance.Spec.ServiceInstanceName = "other-instance"
+		bound := newBinding("bound", time.Hour)
+		bound.Status.BindingID = "1234"
+		binding := newBinding("binding", 0)
+		available, _, err := newReconciler(1, otherInstance, bound, binding).bindSlotAvailable(ctx, serviceInstance, binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(available).To(BeTrue())
+	})
+
+	It("should read the instance of the binding from the snapshot of the reconcile", func() {
+		binding := newBinding("binding", 0)
+		reconciler := newReconciler(0, binding)
+		snapshot, err := reconciler.getServiceInstanceForBinding(withInstanceSnapshot(ctx, serviceInstance), binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(snapshot.Name).To(Equal("my-instance"))
+		otherBinding := newBinding("other", 0)
+		otherBinding.Spec.ServiceInstanceName = "other-instance"
+		_, err = reconciler.getServiceInstanceForBinding(withInstanceSnapshot(ctx, serviceInstance), otherBinding)
+		Expect(err).To(HaveOccurred())
+	})
+})
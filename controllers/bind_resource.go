@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// bindResourceOf returns the bind resource sent with the binding to SM and whether the broker of the offering of the
+// instance accepts it. Brokers that do not issue credentials per bind resource may reject the unknown field, the
+// bind resource is only sent for the offerings configured in BindResourceOfferings.
+func (r *ServiceBindingReconciler) bindResourceOf(serviceInstance *servicesv1.ServiceInstance, serviceBinding *servicesv1.ServiceBinding) (*smClientTypes.BindResource, bool) {
+	bindResource := serviceBinding.Spec.BindResource
+	if bindResource == nil {
+		return nil, true
+	}
+	if !contains(r.Config.BindResourceOfferings, serviceInstance.Spec.ServiceOfferingName) {
+		return nil, false
+	}
+	return &smClientTypes.BindResource{AppGUID: bindResource.AppGUID, Route: bindResource.Route}, true
+}
+
+// markBindResourceNotSupported blocks a binding with a bind resource to an instance of an offering the bind resource is
+// not sent for. The creation is retried with the long poll interval, the offering may be added to the configuration.
+func (r *ServiceBindingReconciler) markBindResourceNotSupported(ctx context.Context, serviceInstance *servicesv1.ServiceInstance, serviceBinding *servicesv1.ServiceBinding) (ctrl.Result, error) {
+	message := fmt.Sprintf("the broker of offering '%s' is not configured to receive bind resources, remove spec.bindResource "+
+		"or add the offering to the bind resource offerings of the operator", serviceInstance.Spec.ServiceOfferingName)
+	GetLogger(ctx).Info(message)
+
+	setBlockedCondition(ctx, message, serviceBinding)
+	return ctrl.Result{RequeueAfter: r.longPollInterval()}, r.updateStatus(ctx, serviceBinding)
+}
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bind resource", func() {
+	var reconciler *ServiceBindingReconciler
+	var instance *servicesv1.ServiceInstance
+	var binding *servicesv1.ServiceBinding
+
+	BeforeEach(func() {
+		reconciler = &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{Config: config.Config{BindResourceOfferings: []string{"routing"}}}}
+		instance = &servicesv1.ServiceInstance{Spec: servicesv1.ServiceInstanceSpec{ServiceOfferingName: "routing"}}
+		binding = &servicesv1.ServiceBinding{Spec: servicesv1.ServiceBindingSpec{BindResource: &servicesv1.BindResource{AppGUID: "app-guid", Route: "https://my-app.example.com"}}}
+	})
+
+	It("should send the bind resource for the configured offerings", func() {
+		bindResource, supported := reconciler.bindResourceOf(instance, binding)
+		Expect(supported).To(BeTrue())
+		Expect(bindResource).To(Equal(&smClientTypes.BindResource{AppGUID: "app-guid", Route: "https://my-app.example.com"}))
+	})
+
+	It("should not support the bind resource for other offerings", func() {
+		instance.Spec.ServiceOfferingName = "xsuaa"
+		_, supported := reconciler.bindResourceOf(instance, binding)
+		Expect(supported).To(BeFalse())
+	})
+
+	It("should send no bind resource without one in the spec", func() {
+		binding.Spec.BindResource = nil
+		instance.Spec.ServiceOfferingName = "xsuaa"
+		bindResource, supported := reconciler.bindResourceOf(instance, binding)
+		Expect(supported).To(BeTrue())
+		Expect(bindResource).To(BeNil())
+	})
+})
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// objectNameHashLength is the length of the hash suffix of shortened object names
+const objectNameHashLength = 10
+
+// the roles and role bindings of the secrets namespace are permitted by a role in that namespace rendered by the chart,
+// they are read with the API reader as the operator is not permitted to watch them cluster-wide
+
+// bindingSecretKey returns the location of the secret of the binding. In the secrets namespace the name of the secret
+// is prefixed with the namespace of the binding, namespace names contain no dots so the prefixed names are unique.
+func (r *ServiceBindingReconciler) bindingSecretKey(binding *servicesv1.ServiceBinding) types.NamespacedName {
+	return r.bindingObjectKey(binding, binding.Spec.SecretName)
+}
+
+// bindingObjectKey returns the location of an object generated for the binding, e.g. a config map of the secret template.
+// Prefixed names exceeding the length of object names are shortened and suffixed with a hash of the full name.
+func (r *ServiceBindingReconciler) bindingObjectKey(binding *servicesv1.ServiceBinding, name string) types.NamespacedName {
+	if len(r.Config.BindingSecretsNamespace) == 0 {
+		return types.NamespacedName{Namespace: binding.Namespace, Name: name}
+	}
+	return types.NamespacedName{
+		Namespace: r.Config.BindingSecretsNamespace,
+		Name:      shortenObjectName(fmt.Sprintf("%s.%s", binding.Namespace, name)),
+	}
+}
+
+// shortenObjectName keeps names up to the maximal length of object names and replaces the end of longer names
+// with a hash of the full name, so that distinct names stay distinct
+func shortenObjectName(name string) string {
+	if len(name) <= validation.DNS1123SubdomainMaxLength {
+		return name
+	}
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(name)))[:objectNameHashLength]
+	prefix := strings.TrimRight(name[:validation.DNS1123SubdomainMaxLength-objectNameHashLength-1], ".-")
+	return fmt.Sprintf("%s-%s", prefix, hash)
+}
+
+// fenceBindingSecret grants the consumers of the binding, i.e. the service accounts listed in its secretConsumers, read
+// access to its secret, its TLS secret, its additional secrets and the config maps of its secret template in the secrets
+// namespace, the role and role binding are owned by the secret and deleted together with it
+func (r *ServiceBindingReconciler) fenceBindingSecret(ctx context.Context, binding *servicesv1.ServiceBinding, configMaps []*corev1.ConfigMap) error {
+	if len(r.Config.BindingSecretsNamespace) == 0 {
+		return nil
+	}
+
+	key := r.bindingSecretKey(binding)
+	secret, err := r.getSecret(ctx, key.Namespace, key.Name)
+	if err != nil {
+		return err
+	}
+
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+		Rules: []rbacv1.PolicyRule{{
+			APIGroups:     []string{corev1.GroupName},
+			Resources:     []string{"secrets"},
+			ResourceNames: []string{key.Name},
+			Verbs:         []string{"get", "watch"},
+		}},
+	}
+	role.Rules[0].ResourceNames = append(role.Rules[0].ResourceNames, r.generatedSecretNames(binding)...)
+	if len(configMaps) > 0 {
+		configMapNames := make([]string, 0, len(configMaps))
+		for _, configMap := range configMaps {
+			configMapNames = append(configMapNames, configMap.Name)
+		}
+		role.Rules = append(role.Rules, rbacv1.PolicyRule{
+			APIGroups:     []string{corev1.GroupName},
+			Resources:     []string{"configmaps"},
+			ResourceNames: configMapNames,
+			Verbs:         []string{"get", "watch"},
+		})
+	}
+	if err := r.createOrUpdateFencingRole(ctx, secret, role); err != nil {
+		return err
+	}
+	return r.bindSecretConsumers(ctx, binding, secret)
+}
+
+// bindSecretConsumers binds the role of the binding secret to the consumers of the binding,
+// the role binding is removed when the binding has no consumers
+func (r *ServiceBindingReconciler) bindSecretConsumers(ctx context.Context, binding *servicesv1.ServiceBinding, secret *corev1.Secret) error {
+	if len(binding.Spec.SecretConsumers) == 0 {
+		roleBinding := &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: secret.Name, Namespace: secret.Namespace}}
+		return client.IgnoreNotFound(r.Client.Delete(ctx, roleBinding))
+	}
+
+	subjects := make([]rbacv1.Subject, 0, len(binding.Spec.SecretConsumers))
+	for _, serviceAccount := range binding.Spec.SecretConsumers {
+		subjects = append(subjects, rbacv1.Subject{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      serviceAccount,
+			Namespace: binding.Namespace,
+		})
+	}
+	roleBinding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: secret.Name, Namespace: secret.Namespace},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     secret.Name,
+		},
+		Subjects: subjects,
+	}
+	return r.createOrUpdateFencingRoleBinding(ctx, secret, roleBinding)
+}
+
+func (r *ServiceBindingReconciler) createOrUpdateFencingRole(ctx context.Context, secret *corev1.Secret, role *rbacv1.Role) error {
+	if err := controllerutil.SetControllerReference(secret, role, r.Scheme); err != nil {
+		return err
+	}
+
+	dbRole := &rbacv1.Role{}
+	if err := r.apiReader().Get(ctx, types.NamespacedName{Name: role.Name, Namespace: role.Namespace}, dbRole); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		GetLogger(ctx).Info("Creating binding secret role", "name", role.Name)
+		return r.Client.Create(ctx, role)
+	}
+
+	dbRole.OwnerReferences = role.OwnerReferences
+	dbRole.Rules = role.Rules
+	return r.Client.Update(ctx, dbRole)
+}
+
+func (r *ServiceBindingReconciler) createOrUpdateFencingRoleBinding(ctx context.Context, secret *corev1.Secret, roleBinding *rbacv1.RoleBinding) error {
+	if err := controllerutil.SetControllerReference(secret, roleBinding, r.Scheme); err != nil {
+		return err
+	}
+
+	dbRoleBinding := &rbacv1.RoleBinding{}
+	if err := r.apiReader().Get(ctx, types.NamespacedName{Name: roleBinding.Name, Namespace: roleBinding.Namespace}, dbRoleBinding); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		GetLogger(ctx).Info("Creating binding secret role binding", "name", roleBinding.Name)
+		return r.Client.Create(ctx, roleBinding)
+	}
+
+	if dbRoleBinding.RoleRef != roleBinding.RoleRef {
+		// the role reference is immutable
+		if err := r.Client.Delete(ctx, dbRoleBinding); err != nil {
+			return err
+		}
+		return r.Client.Create(ctx, roleBinding)
+	}
+	dbRoleBinding.OwnerReferences = roleBinding.OwnerReferences
+	dbRoleBinding.Subjects = roleBinding.Subjects
+	return r.Client.Update(ctx, dbRoleBinding)
+}
+
+// annotateSecretBinding records the binding of a secret or config map in the secrets namespace,
+// owner references across namespaces are not supported
+func annotateSecretBinding(binding *servicesv1.ServiceBinding, object metav1.Object) {
+	annotations := object.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[api.SecretBindingUIDAnnotation] = string(binding.UID)
+	annotations[api.SecretBindingNamespaceAnnotation] = binding.Namespace
+	object.SetAnnotations(annotations)
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Binding secrets namespace", func() {
+	var (
+		reconciler *ServiceBindingReconciler
+		binding    *servicesv1.ServiceBinding
+		testCtx    context.Context
+	)
+
+	newReconciler := func(secretsNamespace string, objects ...runtime.Object) *ServiceBindingReconciler {
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		return &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(objects...).Build(),
+			Scheme: testScheme,
+			Config: config.Config{BindingSecretsNamespace: secretsNamespace},
+		}}
+	}
+
+	BeforeEach(func() {
+		testCtx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		binding = &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1", UID: "binding-uid"},
+			Spec:       servicesv1.ServiceBindingSpec{SecretName: "my-secret"},
+		}
+	})
+
+	It("should keep the secret in the namespace of the binding by default", func() {
+		reconciler = newReconciler("")
+		Expect(reconciler.bindingSecretKey(binding)).To(Equal(types.NamespacedName{Namespace: "app1", Name: "my-secret"}))
+		Expect(reconciler.fenceBindingSecret(testCtx, binding, nil)).To(Succeed())
+	})
+
+	It("should prefix the secret with the namespace of the binding in the secrets namespace", func() {
+		reconciler = newReconciler("binding-secrets")
+		Expect(reconciler.bindingSecretKey(binding)).To(Equal(types.NamespacedName{Namespace: "binding-secrets", Name: "app1.my-secret"}))
+	})
+
+	It("should record the binding instead of an owner", func() {
+		reconciler = newReconciler("binding-secrets")
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app1.my-secret", Namespace: "binding-secrets"}}
+		Expect(reconciler.setSecretOwner(testCtx, binding, secret)).To(Succeed())
+		Expect(secret.OwnerReferences).To(BeEmpty())
+		Expect(secret.Annotations[api.SecretBindingUIDAnnotation]).To(Equal("binding-uid"))
+		Expect(secret.Annotations[api.SecretBindingNamespaceAnnotation]).To(Equal("app1"))
+	})
+
+	It("should shorten prefixed names exceeding the length of object names", func() {
+		reconciler = newReconciler("binding-secrets")
+		binding.Spec.SecretName = strings.Repeat("a", 250)
+		key := reconciler.bindingSecretKey(binding)
+		Expect(len(key.Name)).To(Equal(validation.DNS1123SubdomainMaxLength))
+		Expect(validation.IsDNS1123Subdomain(key.Name)).To(BeEmpty())
+		Expect(key.Name).To(HavePrefix("app1.aaa"))
+
+		binding.Spec.SecretName = strings.Repeat("a", 249) + "b"
+		Expect(reconciler.bindingSecretKey(binding).Name).ToNot(Equal(key.Name))
+	})
+
+	It("should grant the consumers of the binding access to the secret only", func() {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app1.my-secret", Namespace: "binding-secrets", UID: "secret-uid"}}
+		reconciler = newReconciler("binding-secrets", secret)
+		binding.Spec.SecretConsumers = []string{"my-app"}
+		Expect(reconciler.fenceBindingSecret(testCtx, binding, nil)).To(Succeed())
+		// fencing is idempotent
+		Expect(reconciler.fenceBindingSecret(testCtx, binding, nil)).To(Succeed())
+
+		key := types.NamespacedName{Namespace: "binding-secrets", Name: "app1.my-secret"}
+		role := &rbacv1.Role{}
+		Expect(reconciler.Client.Get(testCtx, key, role)).To(Succeed())
+		Expect(role.Rules).To(ConsistOf(rbacv1.PolicyRule{
+			APIGroups:     []string{""},
+			Resources:     []string{"secrets"},
+			ResourceNames: []string{"app1.my-secret"},
+			Verbs:         []string{"get", "watch"},
+		}))
+		Expect(metav1.IsControlledBy(role, secret)).To(BeTrue())
+
+		roleBinding := &rbacv1.RoleBinding{}
+		Expect(reconciler.Client.Get(testCtx, key, roleBinding)).To(Succeed())
+		Expect(roleBinding.RoleRef).To(Equal(rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "app1.my-secret"}))
+		Expect(roleBinding.Subjects).To(ConsistOf(rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: "my-app", Namespace: "app1"}))
+		Expect(metav1.IsControlledBy(roleBinding, secret)).To(BeTrue())
+
+		// removing the consumers revokes the access
+		binding.Spec.SecretConsumers = nil
+		Expect(reconciler.fenceBindingSecret(testCtx, binding, nil)).To(Succeed())
+		err := reconciler.Client.Get(testCtx, key, &rbacv1.RoleBinding{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should store the config maps of the secret template in the secrets namespace and prune the removed ones", func() {
+		removed := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:        "app1.old-config",
+			Namespace:   "binding-secrets",
+			Labels:      map[string]string{api.BindingUIDLabel: "binding-uid"},
+			Annotations: map[string]string{api.SecretBindingUIDAnnotation: "binding-uid"},
+		}}
+		reconciler = newReconciler("binding-secrets", removed)
+		key := reconciler.bindingObjectKey(binding, "app-config")
+		Expect(key).To(Equal(types.NamespacedName{Namespace: "binding-secrets", Name: "app1.app-config"}))
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Data:       map[string]string{"instance": "my-instance"},
+		}
+		Expect(reconciler.createOrUpdateBindingConfigMaps(testCtx, binding, []*corev1.ConfigMap{configMap})).To(Succeed())
+
+		stored := &corev1.ConfigMap{}
+		Expect(reconciler.Client.Get(testCtx, key, stored)).To(Succeed())
+		Expect(stored.OwnerReferences).To(BeEmpty())
+		Expect(stored.Labels[api.BindingUIDLabel]).To(Equal("binding-uid"))
+		Expect(stored.Annotations[api.SecretBindingUIDAnnotation]).To(Equal("binding-uid"))
+		err := reconciler.Client.Get(testCtx, client.ObjectKeyFromObject(removed), &corev1.ConfigMap{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		_, err = reconciler.deleteSecretAndRemoveFinalizer(testCtx, binding)
+		Expect(err).ToNot(HaveOccurred())
+		err = reconciler.Client.Get(testCtx, key, &corev1.ConfigMap{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should fail when the secret was not stored", func() {
+		reconciler = newReconciler("binding-secrets")
+		Expect(reconciler.fenceBindingSecret(testCtx, binding, nil)).ToNot(Succeed())
+	})
+})
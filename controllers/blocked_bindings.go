@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// instanceCreated passes the creation events of instances only, the bindings blocked on an instance that did not exist
+// yet are retried when it is created, e.g. when both were applied together and the binding was reconciled first
+var instanceCreated = predicate.Funcs{
+	CreateFunc:  func(event.CreateEvent) bool { return true },
+	UpdateFunc:  func(event.UpdateEvent) bool { return false },
+	DeleteFunc:  func(event.DeleteEvent) bool { return false },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+}
+
+// blockedBindingsOf maps an instance to the bindings referencing it that are blocked, the bindings that are not
+// blocked do not wait for the instance and are not reconciled again, e.g. for the creation events of the initial list
+// of instances on startup
+func (r *ServiceBindingReconciler) blockedBindingsOf(ctx context.Context, instance client.Object) []reconcile.Request {
+	bindingList := &servicesv1.ServiceBindingList{}
+	if err := r.Client.List(ctx, bindingList, client.MatchingFields{bindingInstanceIndex: client.ObjectKeyFromObject(instance).String()}); err != nil {
+		r.Log.Error(err, "failed to list the bindings of the created instance", "serviceinstance", client.ObjectKeyFromObject(instance))
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range bindingList.Items {
+		binding := &bindingList.Items[i]
+		if cond := meta.FindStatusCondition(binding.Status.Conditions, api.ConditionSucceeded); cond != nil && cond.Reason == Blocked {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(binding)})
+		}
+	}
+	return requests
+}
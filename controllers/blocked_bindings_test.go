@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Bindings blocked on a missing instance", func() {
+	var reconciler *ServiceBindingReconciler
+	instance := &servicesv1.ServiceInstance{ObjectMeta: metav1.ObjectMeta{Name: "my-instance", Namespace: "app1"}}
+
+	newBinding := func(name, namespace, reason string) *servicesv1.ServiceBinding {
+		binding := &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       servicesv1.ServiceBindingSpec{ServiceInstanceName: "my-instance", ServiceInstanceNamespace: "app1"},
+		}
+		if len(reason) > 0 {
+			binding.Status.Conditions = []metav1.Condition{{Type: api.ConditionSucceeded, Status: metav1.ConditionFalse, Reason: reason}}
+		}
+		return binding
+	}
+
+	BeforeEach(func() {
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		reconciler = &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+				newBinding("blocked", "app1", Blocked),
+				newBinding("blocked-elsewhere", "app2", Blocked),
+				newBinding("created", "app1", Created),
+				newBinding("new", "app1", ""),
+			).WithIndex(&servicesv1.ServiceBinding{}, bindingInstanceIndex, indexBindingInstance).Build(),
+			Log: ctrl.Log,
+		}}
+	})
+
+	It("should retry the blocked bindings of a created instance", func() {
+		Expect(reconciler.blockedBindingsOf(context.Background(), instance)).To(ConsistOf(
+			reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "app1", Name: "blocked"}},
+			reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "app2", Name: "blocked-elsewhere"}},
+		))
+	})
+
+	It("should pass the creation events of instances only", func() {
+		Expect(instanceCreated.Create(event.CreateEvent{Object: instance})).To(BeTrue())
+		Expect(instanceCreated.Update(event.UpdateEvent{ObjectOld: instance, ObjectNew: instance})).To(BeFalse())
+		Expect(instanceCreated.Delete(event.DeleteEvent{Object: instance})).To(BeFalse())
+	})
+})
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+)
+
+type catalogLookup struct {
+	// plan is the plan of the instance, nil if it is not found or ambiguous
+	plan      *smClientTypes.ServicePlan
+	problem   string
+	expiresAt time.Time
+}
+
+// lookupCatalog resolves the offering and plan of the instance in the catalog of SM. The lookups are cached for
+// CatalogCacheTTL by access credentials secret, so that the instances of one plan and their bindings do not each query
+// the catalog. Failed lookups are not cached.
+func (r *BaseReconciler) lookupCatalog(smClient sm.Client, serviceInstance *servicesv1.ServiceInstance) (catalogLookup, error) {
+	key := strings.Join([]string{serviceInstance.Namespace, btpAccessSecretName(serviceInstance), serviceInstance.Spec.DataCenter,
+		serviceInstance.Spec.ServiceOfferingName, serviceInstance.Spec.ServicePlanName, serviceInstance.Spec.ServicePlanID}, "/")
+	if cached, ok := r.catalogLookups.Load(key); ok && time.Now().Before(cached.(catalogLookup).expiresAt) {
+		return cached.(catalogLookup), nil
+	}
+
+	plan, problem, err := lookupPlan(smClient, serviceInstance.Spec.ServiceOfferingName, serviceInstance.Spec.ServicePlanName,
+		serviceInstance.Spec.ServicePlanID, serviceInstance.Spec.DataCenter)
+	if err != nil {
+		return catalogLookup{}, err
+	}
+	lookup := catalogLookup{plan: plan, problem: problem, expiresAt: time.Now().Add(r.Config.CatalogCacheTTL)}
+	if r.Config.CatalogCacheTTL > 0 {
+		r.catalogLookups.Store(key, lookup)
+	}
+	return lookup, nil
+}
+
+// catalogProblem resolves the offering and plan of the instance in the catalog of SM before it is provisioned and
+// returns why the instance cannot be provisioned, empty if the plan is found. The instance is provisioned if the
+// catalog cannot be queried, SM reports the actual error of the create then.
+func (r *ServiceInstanceReconciler) catalogProblem(ctx context.Context, smClient sm.Client, serviceInstance *servicesv1.ServiceInstance) string {
+	lookup, err := r.lookupCatalog(smClient, serviceInstance)
+	if err != nil {
+		GetLogger(ctx).Error(err, "failed to resolve the plan in the catalog of SM, provisioning without the pre-flight check")
+		return ""
+	}
+	return lookup.problem
+}
+
+// catalogRecheckInterval is the delay until a blocked instance resolves its plan again
+func (r *ServiceInstanceReconciler) catalogRecheckInterval() time.Duration {
+	if r.Config.CatalogCacheTTL > 0 {
+		return r.Config.CatalogCacheTTL
+	}
+	return r.longPollInterval()
+}
+
+// lookupPlan looks up the plan the same way SM resolves it on provisioning. Offerings and plans the subaccount is not
+// entitled to are not visible in its catalog, so a missing plan is reported as not found or not entitled. A plan name
+// that matches more than one plan without a plan ID is left for SM to report.
+func lookupPlan(smClient sm.Client, offeringName, planName, planID, dataCenter string) (*smClientTypes.ServicePlan, string, error) {
+	offerings, err := smClient.ListOfferings(&sm.Parameters{
+		FieldQuery: []string{fmt.Sprintf("catalog_name eq '%s' and data_center eq '%s'", offeringName, dataCenter)},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if offerings == nil || len(offerings.ServiceOfferings) == 0 {
+		return nil, fmt.Sprintf("service offering '%s' not found in the catalog of SM, check that it exists and that the subaccount is entitled to it", offeringName), nil
+	}
+
+	offeringIDs := make([]string, 0, len(offerings.ServiceOfferings))
+	for _, offering := range offerings.ServiceOfferings {
+		offeringIDs = append(offeringIDs, offering.ID)
+	}
+	plans, err := smClient.ListPlans(&sm.Parameters{
+		FieldQuery: []string{fmt.Sprintf("catalog_name eq '%s'", planName), fmt.Sprintf("service_offering_id in ('%s')", strings.Join(offeringIDs, "', '"))},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if plans == nil || len(plans.ServicePlans) == 0 {
+		return nil, fmt.Sprintf("service plan '%s' of offering '%s' not found in the catalog of SM, check that it exists and that the subaccount is entitled to it", planName, offeringName), nil
+	}
+	if len(planID) == 0 {
+		if len(plans.ServicePlans) == 1 {
+			return &plans.ServicePlans[0], "", nil
+		}
+		return nil, "", nil
+	}
+	for i := range plans.ServicePlans {
+		if plans.ServicePlans[i].ID == planID {
+			return &plans.ServicePlans[i], "", nil
+		}
+	}
+	return nil, fmt.Sprintf("service plan ID '%s' does not match the plan '%s' of offering '%s' in the catalog of SM", planID, planName, offeringName), nil
+}
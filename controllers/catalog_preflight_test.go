@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	"github.com/SAP/sap-btp-service-operator/client/sm/smfakes"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Catalog pre-flight", func() {
+	var reconciler *ServiceInstanceReconciler
+	var smClient *smfakes.FakeClient
+	var instance *servicesv1.ServiceInstance
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		instance = &servicesv1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "instance", Namespace: "apps"},
+			Spec:       servicesv1.ServiceInstanceSpec{ServiceOfferingName: "offering", ServicePlanName: "plan"},
+		}
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		smClient = &smfakes.FakeClient{}
+		smClient.ListOfferingsReturns(&smClientTypes.ServiceOfferings{ServiceOfferings: []smClientTypes.ServiceOffering{{ID: "offering-id"}}}, nil)
+		smClient.ProvisionReturns(&sm.ProvisionResponse{InstanceID: "instance-id"}, nil)
+		gates := config.FeatureGates{}
+		Expect(gates.Decode("CatalogPreflight=true")).To(Succeed())
+		reconciler = &ServiceInstanceReconciler{BaseReconciler: &BaseReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance).WithStatusSubresource(instance).Build(),
+			Scheme:   testScheme,
+			Log:      ctrl.Log,
+			SMClient: func() sm.Client { return smClient },
+			Config:   config.Config{FeatureGates: gates, CatalogCacheTTL: time.Minute},
+		}}
+	})
+
+	It("should block the instance instead of provisioning a plan that is not in the catalog", func() {
+		result, err := reconciler.createInstance(ctx, smClient, instance)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(time.Minute))
+		Expect(smClient.ProvisionCallCount()).To(Equal(0))
+
+		updated := &servicesv1.ServiceInstance{}
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(instance), updated)).To(Succeed())
+		condition := meta.FindStatusCondition(updated.Status.Conditions, api.ConditionSucceeded)
+		Expect(condition.Reason).To(Equal(Blocked))
+		Expect(condition.Message).To(ContainSubstring("service plan 'plan' of offering 'offering' not found"))
+		Expect(condition.Message).To(ContainSubstring("entitled"))
+	})
+
+	It("should cache the lookups until they expire", func() {
+		smClient.ListPlansReturns(&smClientTypes.ServicePlans{ServicePlans: []smClientTypes.ServicePlan{{ID: "plan-id", CatalogName: "plan"}}}, nil)
+		Expect(reconciler.catalogProblem(ctx, smClient, instance)).To(BeEmpty())
+		Expect(reconciler.catalogProblem(ctx, smClient, instance)).To(BeEmpty())
+		Expect(smClient.ListPlansCallCount()).To(Equal(1))
+
+		instance.Spec.ServicePlanID = "other-plan-id"
+		Expect(reconciler.catalogProblem(ctx, smClient, instance)).To(ContainSubstring("does not match"))
+		Expect(smClient.ListPlansCallCount()).To(Equal(2))
+
+		reconciler.Config.CatalogCacheTTL = 0
+		instance.Namespace = "other-apps"
+		Expect(reconciler.catalogProblem(ctx, smClient, instance)).To(ContainSubstring("does not match"))
+		Expect(reconciler.catalogProblem(ctx, smClient, instance)).To(ContainSubstring("does not match"))
+		Expect(smClient.ListPlansCallCount()).To(Equal(4))
+	})
+
+	It("should provision the instance if the catalog cannot be queried", func() {
+		smClient.ListOfferingsReturns(nil, fmt.Errorf("sm unavailable"))
+		Expect(reconciler.catalogProblem(ctx, smClient, instance)).To(BeEmpty())
+		smClient.ListOfferingsReturns(&smClientTypes.ServiceOfferings{}, nil)
+		Expect(reconciler.catalogProblem(ctx, smClient, instance)).To(ContainSubstring("service offering 'offering' not found"))
+	})
+})
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// +kubebuilder:rbac:groups=services.cloud.sap.com,resources=serviceofferings;serviceplans,verbs=get;list;watch;create;update;patch;delete
+
+// CatalogSync mirrors the offerings and plans of the SM marketplace of the cluster credentials to the cluster scoped
+// ServiceOffering and ServicePlan resources every Interval, so that users can discover what they can provision with
+// kubectl
+type CatalogSync struct {
+	*BaseReconciler
+	Interval time.Duration
+}
+
+// Start syncs the catalog right away and then every Interval
+func (r *CatalogSync) Start(ctx context.Context) error {
+	ctx = context.WithValue(ctx, LogKey{}, r.Log)
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Sync(ctx); err != nil {
+			r.Log.Error(err, "failed to sync the catalog of SM")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection syncs the catalog on the leader only
+func (r *CatalogSync) NeedLeaderElection() bool {
+	return true
+}
+
+// Sync creates and updates a ServiceOffering and a ServicePlan for each offering and plan in the marketplace, and
+// deletes those which are no longer in it. Plans of offerings which are not in the marketplace are skipped.
+func (r *CatalogSync) Sync(ctx context.Context) error {
+	log := GetLogger(ctx)
+	// the release namespace resolves the credentials of the cluster
+	catalog := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Namespace: r.Config.ReleaseNamespace, Name: "catalog"}}
+	smClient, err := r.getSMClient(ctx, catalog, "")
+	if err != nil {
+		return err
+	}
+	offerings, err := smClient.ListOfferings(nil)
+	if err != nil {
+		return err
+	}
+	plans, err := smClient.ListPlans(nil)
+	if err != nil {
+		return err
+	}
+
+	offeringsByID := map[string]smClientTypes.ServiceOffering{}
+	synced := map[string]bool{}
+	if offerings != nil {
+		for _, offering := range offerings.ServiceOfferings {
+			offeringsByID[offering.ID] = offering
+			serviceOffering := &servicesv1.ServiceOffering{ObjectMeta: metav1.ObjectMeta{Name: catalogObjectName(offering.ID)}}
+			if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, serviceOffering, func() error {
+				serviceOffering.Spec = servicesv1.ServiceOfferingSpec{
+					ID:            offering.ID,
+					Name:          offering.CatalogName,
+					Description:   offering.Description,
+					Bindable:      offering.Bindable,
+					PlanUpdatable: offering.PlanUpdatable,
+					BrokerID:      offering.BrokerID,
+					BrokerName:    offering.BrokerName,
+					DataCenter:    offering.DataCenter,
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			synced[serviceOffering.Name] = true
+		}
+	}
+	existingOfferings := &servicesv1.ServiceOfferingList{}
+	if err := r.Client.List(ctx, existingOfferings); err != nil {
+		return err
+	}
+	for i := range existingOfferings.Items {
+		if !synced[existingOfferings.Items[i].Name] {
+			log.Info(fmt.Sprintf("service offering %s is no longer in the catalog, deleting it", existingOfferings.Items[i].Spec.Name))
+			if err := r.Client.Delete(ctx, &existingOfferings.Items[i]); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+		}
+	}
+
+	synced = map[string]bool{}
+	if plans != nil {
+		for _, plan := range plans.ServicePlans {
+			offering, found := offeringsByID[plan.ServiceOfferingID]
+			if !found {
+				continue
+			}
+			servicePlan := &servicesv1.ServicePlan{ObjectMeta: metav1.ObjectMeta{Name: catalogObjectName(plan.ID)}}
+			if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, servicePlan, func() error {
+				servicePlan.Spec = servicesv1.ServicePlanSpec{
+					ID:                  plan.ID,
+					Name:                plan.CatalogName,
+					Description:         plan.Description,
+					Free:                plan.Free,
+					Bindable:            plan.Bindable,
+					ServiceOfferingID:   plan.ServiceOfferingID,
+					ServiceOfferingName: offering.CatalogName,
+					DataCenter:          offering.DataCenter,
+				}
+				servicePlan.Spec.Schemas = normalizedSchemas(plan.Schemas)
+				// the plans of an offering can be listed with a label selector
+				if len(validation.IsValidLabelValue(offering.CatalogName)) == 0 {
+					if servicePlan.Labels == nil {
+						servicePlan.Labels = map[string]string{}
+					}
+					servicePlan.Labels[api.CatalogOfferingLabel] = offering.CatalogName
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			synced[servicePlan.Name] = true
+		}
+	}
+	existingPlans := &servicesv1.ServicePlanList{}
+	if err := r.Client.List(ctx, existingPlans); err != nil {
+		return err
+	}
+	for i := range existingPlans.Items {
+		if !synced[existingPlans.Items[i].Name] {
+			log.Info(fmt.Sprintf("service plan %s of offering %s is no longer in the catalog, deleting it",
+				existingPlans.Items[i].Spec.Name, existingPlans.Items[i].Spec.ServiceOfferingName))
+			if err := r.Client.Delete(ctx, &existingPlans.Items[i]); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// normalizedSchemas encodes the schemas the way the API server returns them, so that unchanged plans are not updated
+func normalizedSchemas(schemas []byte) *runtime.RawExtension {
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(schemas, &decoded); err != nil || decoded == nil {
+		return nil
+	}
+	raw, err := json.Marshal(decoded)
+	if err != nil {
+		return nil
+	}
+	return &runtime.RawExtension{Raw: raw}
+}
+
+// catalogObjectName is the name of the ServiceOffering or ServicePlan of the SM ID, the ID itself unless it is not a
+// valid name
+func catalogObjectName(id string) string {
+	name := strings.ToLower(id)
+	if len(validation.IsDNS1123Subdomain(name)) == 0 {
+		return name
+	}
+	hash := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(hash[:16])
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	"github.com/SAP/sap-btp-service-operator/client/sm/smfakes"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Catalog sync", func() {
+	var (
+		ctx         context.Context
+		smClient    *smfakes.FakeClient
+		catalogSync *CatalogSync
+	)
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		stale := &servicesv1.ServicePlan{
+			ObjectMeta: metav1.ObjectMeta{Name: "removed-plan-id"},
+			Spec:       servicesv1.ServicePlanSpec{ID: "removed-plan-id", Name: "removed", ServiceOfferingID: "offering-id"},
+		}
+		smClient = &smfakes.FakeClient{}
+		smClient.ListOfferingsReturns(&smClientTypes.ServiceOfferings{ServiceOfferings: []smClientTypes.ServiceOffering{
+			{ID: "offering-id", CatalogName: "hana", Bindable: true, BrokerName: "hana-broker", DataCenter: "eu10"},
+		}}, nil)
+		smClient.ListPlansReturns(&smClientTypes.ServicePlans{ServicePlans: []smClientTypes.ServicePlan{
+			{ID: "plan-id", CatalogName: "small", Free: true, ServiceOfferingID: "offering-id",
+				Schemas: []byte(`{"service_instance": {"create": {"parameters": {"type": "object"}}}}`)},
+			{ID: "other-plan-id", CatalogName: "hidden", ServiceOfferingID: "unknown-offering-id"},
+		}}, nil)
+		catalogSync = &CatalogSync{BaseReconciler: &BaseReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(stale).Build(),
+			Scheme:   testScheme,
+			Log:      ctrl.Log,
+			SMClient: func() sm.Client { return smClient },
+		}}
+	})
+
+	It("should mirror the offerings and plans of the marketplace", func() {
+		Expect(catalogSync.Sync(ctx)).To(Succeed())
+
+		offering := &servicesv1.ServiceOffering{}
+		Expect(catalogSync.Client.Get(ctx, client.ObjectKey{Name: "offering-id"}, offering)).To(Succeed())
+		Expect(offering.Spec).To(Equal(servicesv1.ServiceOfferingSpec{ID: "offering-id", Name: "hana", Bindable: true, BrokerName: "hana-broker", DataCenter: "eu10"}))
+
+		plans := &servicesv1.ServicePlanList{}
+		Expect(catalogSync.Client.List(ctx, plans)).To(Succeed())
+		Expect(plans.Items).To(HaveLen(1))
+		plan := plans.Items[0]
+		Expect(plan.Name).To(Equal("plan-id"))
+		Expect(plan.Labels).To(HaveKeyWithValue(api.CatalogOfferingLabel, "hana"))
+		Expect(plan.Spec.Name).To(Equal("small"))
+		Expect(plan.Spec.ServiceOfferingName).To(Equal("hana"))
+		Expect(plan.Spec.DataCenter).To(Equal("eu10"))
+		Expect(plan.Spec.Free).To(BeTrue())
+		Expect(string(plan.Spec.Schemas.Raw)).To(Equal(`{"service_instance":{"create":{"parameters":{"type":"object"}}}}`))
+	})
+
+	It("should delete the offerings and plans removed from the marketplace", func() {
+		Expect(catalogSync.Sync(ctx)).To(Succeed())
+		smClient.ListOfferingsReturns(&smClientTypes.ServiceOfferings{}, nil)
+		Expect(catalogSync.Sync(ctx)).To(Succeed())
+
+		offerings := &servicesv1.ServiceOfferingList{}
+		Expect(catalogSync.Client.List(ctx, offerings)).To(Succeed())
+		Expect(offerings.Items).To(BeEmpty())
+		plans := &servicesv1.ServicePlanList{}
+		Expect(catalogSync.Client.List(ctx, plans)).To(Succeed())
+		Expect(plans.Items).To(BeEmpty())
+	})
+
+	It("should name the resources after IDs which are not valid names by their hash", func() {
+		Expect(catalogObjectName("3A0B2E6C-aaaa")).To(Equal("3a0b2e6c-aaaa"))
+		Expect(catalogObjectName("plan_id")).To(HaveLen(32))
+		Expect(catalogObjectName("plan_id")).ToNot(Equal(catalogObjectName("plan_id2")))
+	})
+})
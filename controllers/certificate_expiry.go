@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// CertificateExpiring is the reason of the CertificateExpiring condition of bindings whose certificate expires within the window
+	CertificateExpiring = "CertificateExpiring"
+	// CertificateExpired is the reason of the CertificateExpiring condition of bindings whose certificate is expired
+	CertificateExpired = "CertificateExpired"
+)
+
+// certificateExpiry is the certificate expiring first in the secrets of a binding
+type certificateExpiry struct {
+	secret   string
+	key      string
+	notAfter time.Time
+}
+
+// CertificateExpiryScanner parses the certificates in the secrets of the bindings every Interval, exposes the time left
+// until they expire as metric and sets the CertificateExpiring condition on the bindings whose certificate expires
+// within Window, independently of their rotation policy
+type CertificateExpiryScanner struct {
+	*BaseReconciler
+	Interval time.Duration
+	Window   time.Duration
+	now      func() time.Time
+	// exposed are the bindings with a metric of the last scan
+	exposed map[types.NamespacedName]bool
+}
+
+// Start scans the secrets once it is started and every Interval after that
+func (s *CertificateExpiryScanner) Start(ctx context.Context) error {
+	ctx = context.WithValue(ctx, LogKey{}, s.Log)
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Scan(ctx); err != nil {
+			s.Log.Error(err, "failed to scan the certificates of the binding secrets")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection scans on the leader only, which updates the conditions of the bindings
+func (s *CertificateExpiryScanner) NeedLeaderElection() bool {
+	return true
+}
+
+// Scan updates the metric and the condition of every binding with the certificate expiring first in its secrets. The
+// metrics of all bindings are set before the ones of the bindings without a certificate are deleted, so that a scrape
+// during the scan doesn't miss any binding. A binding whose secrets can't be read keeps its metric.
+func (s *CertificateExpiryScanner) Scan(ctx context.Context) error {
+	log := GetLogger(ctx)
+	bindingList := &servicesv1.ServiceBindingList{}
+	if err := s.Client.List(ctx, bindingList); err != nil {
+		return err
+	}
+	now := time.Now()
+	if s.now != nil {
+		now = s.now()
+	}
+
+	expirySeconds := make(map[types.NamespacedName]float64)
+	kept := make(map[types.NamespacedName]bool)
+	defer func() { s.exposeCertificateExpiry(expirySeconds, kept) }()
+	for i := range bindingList.Items {
+		binding := &bindingList.Items[i]
+		if len(binding.Status.BindingID) == 0 || !binding.DeletionTimestamp.IsZero() {
+			continue
+		}
+		key := types.NamespacedName{Namespace: binding.Namespace, Name: binding.Name}
+		expiry, err := s.firstCertificateExpiry(ctx, binding)
+		if err != nil {
+			log.Error(err, "failed to scan the secrets of the binding", "namespace", binding.Namespace, "name", binding.Name)
+			kept[key] = s.exposed[key]
+			continue
+		}
+		if expiry != nil {
+			expirySeconds[key] = expiry.notAfter.Sub(now).Seconds()
+		}
+		if setCertificateExpiringCondition(binding, expiry, now, s.Window) {
+			// an operation still kept in the status is moved to the operations config map before it is taken out of it
+			if err := s.operations().load(ctx, binding); err != nil {
+				log.Error(err, "failed to load the operation of the binding", "namespace", binding.Namespace, "name", binding.Name)
+				continue
+			}
+			if err := s.updateStatus(ctx, binding); err != nil {
+				log.Error(err, "failed to update the certificate expiry of the binding", "namespace", binding.Namespace, "name", binding.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// exposeCertificateExpiry sets the metrics of the scanned bindings and deletes the metrics of the bindings exposed by
+// the previous scan which are neither scanned nor kept
+func (s *CertificateExpiryScanner) exposeCertificateExpiry(expirySeconds map[types.NamespacedName]float64, kept map[types.NamespacedName]bool) {
+	exposed := make(map[types.NamespacedName]bool, len(expirySeconds))
+	for key, seconds := range expirySeconds {
+		certificateExpirySeconds.WithLabelValues(key.Namespace, key.Name).Set(seconds)
+		exposed[key] = true
+	}
+	for key, wasExposed := range kept {
+		if wasExposed {
+			exposed[key] = true
+		}
+	}
+	for key := range s.exposed {
+		if !exposed[key] {
+			certificateExpirySeconds.DeleteLabelValues(key.Namespace, key.Name)
+		}
+	}
+	s.exposed = exposed
+}
+
+// firstCertificateExpiry returns the certificate expiring first in the binding secret and the secrets generated
+// alongside it, nil if they hold no certificate
+func (s *CertificateExpiryScanner) firstCertificateExpiry(ctx context.Context, binding *servicesv1.ServiceBinding) (*certificateExpiry, error) {
+	bindings := &ServiceBindingReconciler{BaseReconciler: s.BaseReconciler}
+	key := bindings.bindingSecretKey(binding)
+	names := append([]string{key.Name}, bindings.generatedSecretNames(binding)...)
+
+	var first *certificateExpiry
+	for _, name := range names {
+		secret, err := bindings.getSecret(ctx, key.Namespace, name)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		for dataKey, value := range secret.Data {
+			for _, cert := range parseCertificates(value) {
+				if first == nil || cert.NotAfter.Before(first.notAfter) {
+					first = &certificateExpiry{secret: name, key: dataKey, notAfter: cert.NotAfter}
+				}
+			}
+		}
+	}
+	return first, nil
+}
+
+// parseCertificates returns the PEM encoded certificates in the value, or in the strings of a JSON value, e.g. of the
+// credentials stored under a single key
+func parseCertificates(value []byte) []*x509.Certificate {
+	var certs []*x509.Certificate
+	rest := value
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+	if len(certs) > 0 || !json.Valid(value) {
+		return certs
+	}
+
+	var document interface{}
+	if err := json.Unmarshal(value, &document); err != nil {
+		return nil
+	}
+	var walk func(node interface{})
+	walk = func(node interface{}) {
+		switch v := node.(type) {
+		case string:
+			certs = append(certs, parseCertificates([]byte(v))...)
+		case map[string]interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(document)
+	return certs
+}
+
+// setCertificateExpiringCondition sets the CertificateExpiring condition if the certificate expires within the window
+// and removes it otherwise. Returns true if the conditions changed.
+func setCertificateExpiringCondition(binding *servicesv1.ServiceBinding, expiry *certificateExpiry, now time.Time, window time.Duration) bool {
+	current := meta.FindStatusCondition(binding.Status.Conditions, api.ConditionCertificateExpiring)
+	if expiry == nil || expiry.notAfter.After(now.Add(window)) {
+		if current == nil {
+			return false
+		}
+		meta.RemoveStatusCondition(&binding.Status.Conditions, api.ConditionCertificateExpiring)
+		return true
+	}
+
+	reason := CertificateExpiring
+	message := fmt.Sprintf("the certificate in key %s of secret %s expires at %s", expiry.key, expiry.secret, expiry.notAfter.UTC().Format(time.RFC3339))
+	if !expiry.notAfter.After(now) {
+		reason = CertificateExpired
+		message = fmt.Sprintf("the certificate in key %s of secret %s expired at %s", expiry.key, expiry.secret, expiry.notAfter.UTC().Format(time.RFC3339))
+	}
+	if current != nil && current.Reason == reason && current.Message == message {
+		return false
+	}
+	meta.SetStatusCondition(&binding.Status.Conditions, metav1.Condition{
+		Type:               api.ConditionCertificateExpiring,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: binding.Generation,
+	})
+	return true
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/certs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Certificate expiry", func() {
+	var scanner *CertificateExpiryScanner
+	var binding *servicesv1.ServiceBinding
+	var ctx context.Context
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	certificate := func(notAfter time.Time) []byte {
+		keyPair, err := certs.GenerateCA(notAfter.Add(-365*24*time.Hour), notAfter)
+		Expect(err).ToNot(HaveOccurred())
+		return keyPair.Cert
+	}
+
+	scan := func(data map[string][]byte) *servicesv1.ServiceBinding {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-secret", Namespace: "app1"}, Data: data}
+		Expect(scanner.Client.Create(ctx, secret)).To(Succeed())
+		Expect(scanner.Scan(ctx)).To(Succeed())
+		scanned := &servicesv1.ServiceBinding{}
+		Expect(scanner.Client.Get(ctx, client.ObjectKeyFromObject(binding), scanned)).To(Succeed())
+		return scanned
+	}
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		binding = &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1", UID: "binding-uid"},
+			Spec:       servicesv1.ServiceBindingSpec{ServiceInstanceName: "my-instance", SecretName: "my-secret"},
+			Status:     servicesv1.ServiceBindingStatus{BindingID: "1234"},
+		}
+		scanner = &CertificateExpiryScanner{
+			BaseReconciler: &BaseReconciler{
+				Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(binding).WithStatusSubresource(binding).Build(),
+				Scheme:   testScheme,
+				Log:      ctrl.Log,
+				Recorder: record.NewFakeRecorder(10),
+			},
+			Interval: time.Hour,
+			Window:   30 * 24 * time.Hour,
+			now:      func() time.Time { return now },
+		}
+	})
+
+	It("should set the condition on bindings whose certificate expires within the window", func() {
+		scanned := scan(map[string][]byte{
+			"certificate": append(certificate(now.Add(90*24*time.Hour)), certificate(now.Add(10*24*time.Hour))...),
+			"user":        []byte("my-user"),
+		})
+		condition := meta.FindStatusCondition(scanned.Status.Conditions, api.ConditionCertificateExpiring)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Reason).To(Equal(CertificateExpiring))
+		Expect(condition.Message).To(Equal("the certificate in key certificate of secret my-secret expires at 2024-05-11T12:00:00Z"))
+	})
+
+	It("should report expired certificates in the credentials stored as JSON", func() {
+		credentials, err := json.Marshal(map[string]interface{}{
+			"uaa": map[string]interface{}{"certificate": string(certificate(now.Add(-time.Hour)))},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		scanned := scan(map[string][]byte{"credentials": credentials})
+		condition := meta.FindStatusCondition(scanned.Status.Conditions, api.ConditionCertificateExpiring)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Reason).To(Equal(CertificateExpired))
+		Expect(condition.Message).To(ContainSubstring("in key credentials of secret my-secret expired at"))
+	})
+
+	It("should remove the condition once the certificate is valid beyond the window", func() {
+		scanned := scan(map[string][]byte{"certificate": certificate(now.Add(24 * time.Hour))})
+		Expect(meta.IsStatusConditionTrue(scanned.Status.Conditions, api.ConditionCertificateExpiring)).To(BeTrue())
+
+		secret := &corev1.Secret{}
+		Expect(scanner.Client.Get(ctx, types.NamespacedName{Name: "my-secret", Namespace: "app1"}, secret)).To(Succeed())
+		secret.Data["certificate"] = certificate(now.Add(90 * 24 * time.Hour))
+		Expect(scanner.Client.Update(ctx, secret)).To(Succeed())
+		Expect(scanner.Scan(ctx)).To(Succeed())
+		Expect(scanner.Client.Get(ctx, client.ObjectKeyFromObject(binding), scanned)).To(Succeed())
+		Expect(meta.FindStatusCondition(scanned.Status.Conditions, api.ConditionCertificateExpiring)).To(BeNil())
+	})
+
+	It("should expose the time left and delete the metric once the binding has no certificate", func() {
+		scan(map[string][]byte{"certificate": certificate(now.Add(24 * time.Hour))})
+		Expect(testutil.ToFloat64(certificateExpirySeconds.WithLabelValues("app1", "my-binding"))).To(Equal((24 * time.Hour).Seconds()))
+
+		secret := &corev1.Secret{}
+		Expect(scanner.Client.Get(ctx, types.NamespacedName{Name: "my-secret", Namespace: "app1"}, secret)).To(Succeed())
+		secret.Data = map[string][]byte{"user": []byte("my-user")}
+		Expect(scanner.Client.Update(ctx, secret)).To(Succeed())
+		Expect(scanner.Scan(ctx)).To(Succeed())
+		Expect(testutil.CollectAndCount(certificateExpirySeconds)).To(BeZero())
+	})
+
+	It("should ignore secrets without certificates", func() {
+		scanned := scan(map[string][]byte{"user": []byte("my-user"), "config": []byte(`{"url": "https://api.example.com"}`)})
+		Expect(scanned.Status.Conditions).To(BeEmpty())
+	})
+})
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// AwaitingCreation the deleted resource is deleted in SM once its pending creation completes
+	AwaitingCreation = "AwaitingCreation"
+)
+
+// requestCreateCancellation handles the deletion of a resource whose creation is still pending in SM. SM offers no
+// cancellation of pending operations, so the resource is deleted in SM as soon as its creation completes.
+func (r *BaseReconciler) requestCreateCancellation(ctx context.Context, object api.SAPBTPResource) (ctrl.Result, error) {
+	message := fmt.Sprintf("%s is deleted once its creation completes", object.GetControllerName())
+	GetLogger(ctx).Info(message)
+
+	conditions := object.GetConditions()
+	meta.SetStatusCondition(&conditions, metav1.Condition{
+		Type:               api.ConditionCancellationRequested,
+		Status:             metav1.ConditionTrue,
+		Reason:             AwaitingCreation,
+		Message:            message,
+		ObservedGeneration: object.GetObservedGeneration(),
+	})
+	object.SetConditions(conditions)
+	return ctrl.Result{Requeue: true}, r.updateStatus(ctx, object)
+}
+
+// cancellationRequested checks whether the resource was deleted while its creation was pending,
+// the resource is deleted in SM right after the creation operation ended
+func cancellationRequested(object api.SAPBTPResource) bool {
+	return meta.IsStatusConditionTrue(object.GetConditions(), api.ConditionCancellationRequested)
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/escrow"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// CredentialsEscrowed is the reason of the events of rotated bindings whose credentials were stored in the escrow
+	CredentialsEscrowed = "CredentialsEscrowed"
+	// CredentialsEscrowFailed is the reason of the events of rotated bindings whose credentials could not be stored in the escrow
+	CredentialsEscrowFailed = "CredentialsEscrowFailed"
+)
+
+// deleteStaleServiceBinding deletes a rotated binding, and with it its secret, once its credentials are stored in the escrow
+func (r *ServiceBindingReconciler) deleteStaleServiceBinding(ctx context.Context, staleBinding *servicesv1.ServiceBinding) error {
+	if err := r.escrowStaleCredentials(ctx, staleBinding); err != nil {
+		return err
+	}
+	return r.Client.Delete(ctx, staleBinding)
+}
+
+// escrowStaleCredentials pushes the credentials in the secret of a rotated binding to the escrow, if one is configured,
+// for a break-glass recovery after the binding is deleted. The binding is kept while the escrow fails, the events of the
+// binding record the audit trail.
+func (r *ServiceBindingReconciler) escrowStaleCredentials(ctx context.Context, staleBinding *servicesv1.ServiceBinding) error {
+	if r.Escrow == nil {
+		return nil
+	}
+	log := GetLogger(ctx)
+	key := r.bindingSecretKey(staleBinding)
+	secret, err := r.getSecret(ctx, key.Namespace, key.Name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("secret of the rotated binding not found, no credentials to escrow", "secretName", key.Name)
+			return nil
+		}
+		return err
+	}
+
+	location, err := r.Escrow.Store(ctx, escrow.Record{
+		Namespace:  staleBinding.Namespace,
+		Name:       staleBinding.Name,
+		RotationOf: staleBinding.Labels[api.StaleBindingRotationOfLabel],
+		BindingID:  staleBinding.Status.BindingID,
+		Data:       secret.Data,
+	})
+	if err != nil {
+		log.Error(err, "failed to store the credentials of the rotated binding in the escrow")
+		r.Recorder.Event(staleBinding, corev1.EventTypeWarning, CredentialsEscrowFailed,
+			fmt.Sprintf("the rotated binding is kept until its credentials are stored in the escrow: %s", err.Error()))
+		credentialsEscrows.WithLabelValues("failed").Inc()
+		return err
+	}
+
+	message := fmt.Sprintf("the credentials of the rotated binding were stored in the escrow at %s", location)
+	if ttl := r.Escrow.TTL(); ttl > 0 {
+		message = fmt.Sprintf("%s for %s", message, ttl)
+	}
+	log.Info(message)
+	r.Recorder.Event(staleBinding, corev1.EventTypeNormal, CredentialsEscrowed, message)
+	credentialsEscrows.WithLabelValues("stored").Inc()
+	return nil
+}
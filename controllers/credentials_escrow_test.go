@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/escrow"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type fakeEscrow struct {
+	records []escrow.Record
+	err     error
+}
+
+func (f *fakeEscrow) Store(_ context.Context, record escrow.Record) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	f.records = append(f.records, record)
+	return fmt.Sprintf("secret/escrow/%s/%s", record.Namespace, record.Name), nil
+}
+
+func (f *fakeEscrow) TTL() time.Duration {
+	return 24 * time.Hour
+}
+
+var _ = Describe("Credentials escrow", func() {
+	var reconciler *ServiceBindingReconciler
+	var staleBinding *servicesv1.ServiceBinding
+	var sink *fakeEscrow
+	var recorder *record.FakeRecorder
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		staleBinding = &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding-r1", Namespace: "app1",
+				Labels: map[string]string{api.StaleBindingIDLabel: "1234", api.StaleBindingRotationOfLabel: "my-binding"}},
+			Spec:   servicesv1.ServiceBindingSpec{SecretName: "my-secret-r1"},
+			Status: servicesv1.ServiceBindingStatus{BindingID: "1234"},
+		}
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-secret-r1", Namespace: "app1"},
+			Data: map[string][]byte{"password": []byte("old")}}
+		sink = &fakeEscrow{}
+		recorder = record.NewFakeRecorder(10)
+		reconciler = &ServiceBindingReconciler{
+			BaseReconciler: &BaseReconciler{
+				Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(staleBinding, secret).Build(),
+				Scheme:   testScheme,
+				Log:      ctrl.Log,
+				Recorder: recorder,
+			},
+			Escrow: sink,
+		}
+	})
+
+	It("should store the credentials of the rotated binding in the escrow before deleting it", func() {
+		Expect(reconciler.deleteStaleServiceBinding(ctx, staleBinding)).To(Succeed())
+		Expect(sink.records).To(Equal([]escrow.Record{{Namespace: "app1", Name: "my-binding-r1", RotationOf: "my-binding",
+			BindingID: "1234", Data: map[string][]byte{"password": []byte("old")}}}))
+		Expect(recorder.Events).To(Receive(ContainSubstring("stored in the escrow at secret/escrow/app1/my-binding-r1 for 24h0m0s")))
+		err := reconciler.Client.Get(ctx, client.ObjectKeyFromObject(staleBinding), &servicesv1.ServiceBinding{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should keep the rotated binding while the escrow fails", func() {
+		sink.err = fmt.Errorf("vault is sealed")
+		Expect(reconciler.deleteStaleServiceBinding(ctx, staleBinding)).To(MatchError("vault is sealed"))
+		Expect(recorder.Events).To(Receive(ContainSubstring(CredentialsEscrowFailed)))
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(staleBinding), &servicesv1.ServiceBinding{})).To(Succeed())
+	})
+
+	It("should delete the rotated binding without an escrow", func() {
+		reconciler.Escrow = nil
+		Expect(reconciler.deleteStaleServiceBinding(ctx, staleBinding)).To(Succeed())
+		Expect(recorder.Events).To(BeEmpty())
+	})
+})
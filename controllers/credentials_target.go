@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	"github.com/pkg/errors"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// CredentialsTargetWritten is the reason of the events of bindings whose credentials were written to their credentials target
+	CredentialsTargetWritten = "CredentialsTargetWritten"
+	// CredentialsTargetDrifted is the reason of the events of bindings whose secret in the credentials target was changed or deleted
+	CredentialsTargetDrifted = "CredentialsTargetDrifted"
+	// CredentialsTargetNotDeleted is the reason of the events of deleted bindings whose secret in the credentials target
+	// cannot be deleted, since the service account to log in with is gone
+	CredentialsTargetNotDeleted = "CredentialsTargetNotDeleted"
+
+	defaultVaultKVMount   = "secret"
+	defaultVaultAuthMount = "kubernetes"
+	// vaultLoginTokenExpiration is the lifetime of the service account tokens exchanged for Vault tokens, the minimum of
+	// the TokenRequest API
+	vaultLoginTokenExpiration = 10 * time.Minute
+	// vaultTokenRenewBefore is how long before it expires a Vault token is replaced by a new login
+	vaultTokenRenewBefore = time.Minute
+)
+
+// vaultToken is a Vault token of a login with a service account, reused until shortly before it expires
+type vaultToken struct {
+	token string
+	// expiresAt is zero for tokens which don't expire
+	expiresAt time.Time
+}
+
+func (t *vaultToken) valid(now time.Time) bool {
+	return t.expiresAt.IsZero() || now.Add(vaultTokenRenewBefore).Before(t.expiresAt)
+}
+
+func hasCredentialsTarget(binding *servicesv1.ServiceBinding) bool {
+	target := binding.Spec.CredentialsTarget
+	return target != nil && (target.Vault != nil || target.CSI != nil)
+}
+
+func hasVaultCredentialsTarget(binding *servicesv1.ServiceBinding) bool {
+	return binding.Spec.CredentialsTarget != nil && binding.Spec.CredentialsTarget.Vault != nil
+}
+
+// storeBindingCredentialsTarget writes the data of the binding secret to the credentials target instead of the cluster,
+// only the non-sensitive config maps of the binding are stored in the cluster. Credentials served to CSI volumes are
+// read from SM on each mount, so that nothing but the config maps is stored for them.
+func (r *ServiceBindingReconciler) storeBindingCredentialsTarget(ctx context.Context, binding *servicesv1.ServiceBinding, secret *corev1.Secret, credentials json.RawMessage, configMaps []*corev1.ConfigMap) error {
+	if hasVaultCredentialsTarget(binding) {
+		if err := r.encryptSecretKeys(ctx, binding, secret); err != nil {
+			GetLogger(ctx).Error(err, "Failed to encrypt binding secret keys")
+			return err
+		}
+		if err := r.writeCredentialsTarget(ctx, binding, desiredSecretData(secret)); err != nil {
+			return err
+		}
+	}
+
+	metadataConfigMap, err := r.bindingMetadataConfigMap(ctx, binding, credentials, configMaps)
+	if err != nil {
+		return err
+	}
+	if metadataConfigMap != nil {
+		configMaps = append(configMaps, metadataConfigMap)
+	}
+	binding.Status.Binding = nil
+	if err := r.createOrUpdateBindingConfigMaps(ctx, binding, configMaps); err != nil {
+		return err
+	}
+	r.secretRetries.Delete(binding.UID)
+	return nil
+}
+
+// writeCredentialsTarget writes the data as new version of the secret in Vault, the version is kept in the status to
+// detect changes of the secret by others
+func (r *ServiceBindingReconciler) writeCredentialsTarget(ctx context.Context, binding *servicesv1.ServiceBinding, data map[string][]byte) error {
+	values := make(map[string]string, len(data))
+	for key, value := range data {
+		values[key] = string(value)
+	}
+	mount, secretPath := vaultSecretPath(binding)
+	var version int64
+	err := r.withCredentialsTargetToken(ctx, binding, func(token string) (err error) {
+		version, err = r.CredentialsVault.Write(ctx, token, mount, secretPath, values)
+		return errors.Wrap(err, "failed to write the credentials to Vault")
+	})
+	if err != nil {
+		return err
+	}
+	binding.Status.CredentialsTargetVersion = version
+
+	message := fmt.Sprintf("the credentials were written to version %d of %s in Vault", version, path.Join(mount, secretPath))
+	GetLogger(ctx).Info(message)
+	r.Recorder.Event(binding, corev1.EventTypeNormal, CredentialsTargetWritten, message)
+	return nil
+}
+
+// credentialsTargetDrifted checks whether the secret in Vault was deleted or written by another actor since the
+// operator wrote the credentials
+func (r *ServiceBindingReconciler) credentialsTargetDrifted(ctx context.Context, binding *servicesv1.ServiceBinding) (bool, error) {
+	if !hasVaultCredentialsTarget(binding) {
+		return false, nil
+	}
+	mount, secretPath := vaultSecretPath(binding)
+	var version int64
+	err := r.withCredentialsTargetToken(ctx, binding, func(token string) (err error) {
+		version, err = r.CredentialsVault.Version(ctx, token, mount, secretPath)
+		return errors.Wrap(err, "failed to read the version of the credentials in Vault")
+	})
+	if err != nil {
+		return false, err
+	}
+	if version == binding.Status.CredentialsTargetVersion {
+		return false, nil
+	}
+
+	message := fmt.Sprintf("the credentials in %s in Vault were deleted or changed, version %d was written by the operator and version %d is current, writing them again",
+		path.Join(mount, secretPath), binding.Status.CredentialsTargetVersion, version)
+	GetLogger(ctx).Info(message)
+	r.Recorder.Event(binding, corev1.EventTypeWarning, CredentialsTargetDrifted, message)
+	return true, nil
+}
+
+// deleteCredentialsTarget deletes all versions of the secret in Vault. When the service account to log in with was
+// deleted, e.g. together with the namespace, the secret is kept in Vault so that the binding can be deleted.
+func (r *ServiceBindingReconciler) deleteCredentialsTarget(ctx context.Context, binding *servicesv1.ServiceBinding) error {
+	if !hasVaultCredentialsTarget(binding) || binding.Status.CredentialsTargetVersion == 0 {
+		return nil
+	}
+	mount, secretPath := vaultSecretPath(binding)
+	err := r.withCredentialsTargetToken(ctx, binding, func(token string) error {
+		return errors.Wrap(r.CredentialsVault.Delete(ctx, token, mount, secretPath), "failed to delete the credentials from Vault")
+	})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			message := fmt.Sprintf("the credentials in %s in Vault were not deleted, the service account to log in to Vault with was not found", path.Join(mount, secretPath))
+			GetLogger(ctx).Info(message)
+			r.Recorder.Event(binding, corev1.EventTypeWarning, CredentialsTargetNotDeleted, message)
+			return nil
+		}
+		return err
+	}
+	GetLogger(ctx).Info(fmt.Sprintf("deleted the credentials in %s in Vault", path.Join(mount, secretPath)))
+	return nil
+}
+
+// withCredentialsTargetToken calls Vault with a token of the service account of the credentials target. The token is
+// reused for the bindings logging in with the same service account and role until shortly before it expires, and
+// dropped if the call fails, e.g. because the token was revoked.
+func (r *ServiceBindingReconciler) withCredentialsTargetToken(ctx context.Context, binding *servicesv1.ServiceBinding, call func(token string) error) error {
+	if r.CredentialsVault == nil {
+		return fmt.Errorf("no Vault server is configured for the credentials targets of bindings or the %s feature gate is disabled", config.VaultCredentials)
+	}
+	serviceAccountName, authMount, role := vaultLogin(binding)
+	key := path.Join(binding.Namespace, serviceAccountName, authMount, role)
+	if cached, found := r.vaultTokens.Load(key); found && cached.(*vaultToken).valid(time.Now()) {
+		if err := call(cached.(*vaultToken).token); err != nil {
+			r.vaultTokens.Delete(key)
+			return err
+		}
+		return nil
+	}
+
+	token, err := r.credentialsTargetToken(ctx, binding)
+	if err != nil {
+		return err
+	}
+	r.vaultTokens.Store(key, token)
+	if err := call(token.token); err != nil {
+		r.vaultTokens.Delete(key)
+		return err
+	}
+	return nil
+}
+
+// credentialsTargetToken logs in to Vault with a token of the service account of the credentials target, so that Vault
+// authorizes the binding by its namespace rather than by the operator. The service account token is issued for the
+// audience of Vault, so that Vault cannot use it against the API server.
+func (r *ServiceBindingReconciler) credentialsTargetToken(ctx context.Context, binding *servicesv1.ServiceBinding) (*vaultToken, error) {
+	serviceAccountName, authMount, role := vaultLogin(binding)
+	expiration := int64(vaultLoginTokenExpiration.Seconds())
+	tokenRequest := &authenticationv1.TokenRequest{Spec: authenticationv1.TokenRequestSpec{
+		Audiences:         []string{r.Config.CredentialsVaultAudience},
+		ExpirationSeconds: &expiration,
+	}}
+	serviceAccount := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: serviceAccountName, Namespace: binding.Namespace}}
+	if err := r.Client.SubResource("token").Create(ctx, serviceAccount, tokenRequest); err != nil {
+		GetLogger(ctx).Error(err, "failed to request a token of the service account to log in to Vault", "serviceAccount", serviceAccountName)
+		return nil, err
+	}
+	token, ttl, err := r.CredentialsVault.Login(ctx, authMount, role, tokenRequest.Status.Token)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to log in to Vault")
+	}
+	loggedIn := &vaultToken{token: token}
+	if ttl > 0 {
+		loggedIn.expiresAt = time.Now().Add(ttl)
+	}
+	return loggedIn, nil
+}
+
+// vaultLogin returns the service account, the mount of the Kubernetes auth method and the role to log in to Vault with
+func vaultLogin(binding *servicesv1.ServiceBinding) (string, string, string) {
+	auth := binding.Spec.CredentialsTarget.Vault.Auth
+	serviceAccountName := auth.ServiceAccountName
+	if len(serviceAccountName) == 0 {
+		serviceAccountName = "default"
+	}
+	authMount := auth.MountPath
+	if len(authMount) == 0 {
+		authMount = defaultVaultAuthMount
+	}
+	return serviceAccountName, authMount, auth.Role
+}
+
+// vaultSecretPath returns the mount of the secrets engine and the path of the secret of the credentials target
+func vaultSecretPath(binding *servicesv1.ServiceBinding) (string, string) {
+	target := binding.Spec.CredentialsTarget.Vault
+	mount := target.Mount
+	if len(mount) == 0 {
+		mount = defaultVaultKVMount
+	}
+	return mount, target.Path
+}
@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	"github.com/SAP/sap-btp-service-operator/client/sm/smfakes"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	"github.com/SAP/sap-btp-service-operator/internal/secrets/encryption"
+	"github.com/SAP/sap-btp-service-operator/internal/vault"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+type fakeVault struct {
+	logins   []string
+	secrets  map[string]map[string]string
+	versions map[string]int64
+	// revoked are the tokens Vault rejects
+	revoked map[string]bool
+}
+
+func (f *fakeVault) Login(_ context.Context, authMount, role, jwt string) (string, time.Duration, error) {
+	f.logins = append(f.logins, fmt.Sprintf("%s/%s/%s", authMount, role, jwt))
+	return fmt.Sprintf("vault-token-%d", len(f.logins)), time.Hour, nil
+}
+
+func (f *fakeVault) WriteMetadata(_ context.Context, _, _, _ string, _ vault.Metadata) error {
+	return nil
+}
+
+func (f *fakeVault) Write(_ context.Context, _, mount, secretPath string, data map[string]string) (int64, error) {
+	key := mount + "/" + secretPath
+	f.secrets[key] = data
+	f.versions[key]++
+	return f.versions[key], nil
+}
+
+func (f *fakeVault) Version(_ context.Context, token, mount, secretPath string) (int64, error) {
+	if f.revoked[token] {
+		return 0, fmt.Errorf("vault responded with status 403: permission denied")
+	}
+	key := mount + "/" + secretPath
+	if _, found := f.secrets[key]; !found {
+		return 0, nil
+	}
+	return f.versions[key], nil
+}
+
+func (f *fakeVault) Delete(_ context.Context, _, mount, secretPath string) error {
+	delete(f.secrets, mount+"/"+secretPath)
+	return nil
+}
+
+var _ = Describe("Credentials target", func() {
+	var reconciler *ServiceBindingReconciler
+	var binding *servicesv1.ServiceBinding
+	var kv *fakeVault
+	var recorder *record.FakeRecorder
+	var smClient *smfakes.FakeClient
+	var serviceAccounts []string
+	var ctx context.Context
+	smBinding := &smClientTypes.ServiceBinding{ID: "1234", Credentials: []byte(`{"user": "my-user", "password": "secret", "url": "https://api.example.com"}`)}
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		instance := &servicesv1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-instance", Namespace: "app1"},
+			Spec:       servicesv1.ServiceInstanceSpec{ExternalName: "my-instance", ServiceOfferingName: "my-offering", ServicePlanName: "my-plan"},
+		}
+		binding = &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1", UID: "binding-uid", Finalizers: []string{api.FinalizerName}},
+			Spec: servicesv1.ServiceBindingSpec{ServiceInstanceName: "my-instance", SecretName: "my-secret", MetadataConfigMap: "my-metadata",
+				CredentialsTarget: &servicesv1.CredentialsTarget{Vault: &servicesv1.VaultCredentialsTarget{Path: "apps/my-binding",
+					Auth: servicesv1.VaultKubernetesAuth{Role: "my-role", ServiceAccountName: "my-app"}}}},
+			Status: servicesv1.ServiceBindingStatus{BindingID: "1234"},
+		}
+		serviceAccounts = []string{"my-app"}
+		tokenRequests := interceptor.Funcs{SubResourceCreate: func(_ context.Context, _ client.Client, subResource string, obj client.Object, request client.Object, _ ...client.SubResourceCreateOption) error {
+			Expect(subResource).To(Equal("token"))
+			Expect(request.(*authenticationv1.TokenRequest).Spec.Audiences).To(Equal([]string{"vault"}))
+			for _, name := range serviceAccounts {
+				if obj.GetName() == name {
+					request.(*authenticationv1.TokenRequest).Status.Token = obj.GetNamespace() + "-" + name + "-jwt"
+					return nil
+				}
+			}
+			return apierrors.NewNotFound(corev1.Resource("serviceaccounts"), obj.GetName())
+		}}
+		kv = &fakeVault{secrets: map[string]map[string]string{}, versions: map[string]int64{}, revoked: map[string]bool{}}
+		recorder = record.NewFakeRecorder(10)
+		smClient = &smfakes.FakeClient{}
+		smClient.GetBindingByIDReturns(smBinding, nil)
+		reconciler = &ServiceBindingReconciler{
+			BaseReconciler: &BaseReconciler{
+				Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance, binding).
+					WithStatusSubresource(binding).WithInterceptorFuncs(tokenRequests).Build(),
+				Scheme:   testScheme,
+				Log:      ctrl.Log,
+				Recorder: recorder,
+				SMClient: func() sm.Client { return smClient },
+				Config:   config.Config{CredentialsVaultAudience: "vault"},
+			},
+			CredentialsVault: kv,
+		}
+	})
+
+	It("should write the credentials to Vault instead of a secret", func() {
+		Expect(reconciler.storeBindingSecret(ctx, binding, smBinding)).To(Succeed())
+		Expect(kv.logins).To(Equal([]string{"kubernetes/my-role/app1-my-app-jwt"}))
+		Expect(kv.secrets).To(HaveKeyWithValue("secret/apps/my-binding", HaveKeyWithValue("password", "secret")))
+		Expect(kv.secrets["secret/apps/my-binding"]).To(HaveKeyWithValue("instance_name", "my-instance"))
+		Expect(binding.Status.CredentialsTargetVersion).To(Equal(int64(1)))
+		Expect(binding.Status.Binding).To(BeNil())
+
+		err := reconciler.Client.Get(ctx, types.NamespacedName{Name: "my-secret", Namespace: "app1"}, &corev1.Secret{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		configMap := &corev1.ConfigMap{}
+		Expect(reconciler.Client.Get(ctx, types.NamespacedName{Name: "my-metadata", Namespace: "app1"}, configMap)).To(Succeed())
+		Expect(configMap.Data).To(HaveKeyWithValue("url", "https://api.example.com"))
+		Expect(recorder.Events).To(Receive(ContainSubstring("the credentials were written to version 1 of secret/apps/my-binding in Vault")))
+	})
+
+	It("should request a refresh of the credentials once they drifted in Vault", func() {
+		binding.Status.Conditions = []metav1.Condition{{Type: api.ConditionReady, Status: metav1.ConditionTrue, Reason: Created}}
+		Expect(reconciler.storeBindingSecret(ctx, binding, smBinding)).To(Succeed())
+		_, err := reconciler.maintain(ctx, binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secretRefreshRequested(binding)).To(BeFalse())
+
+		_, err = kv.Write(ctx, "", "secret", "apps/my-binding", map[string]string{"password": "changed"})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = reconciler.maintain(ctx, binding)
+		Expect(err).ToNot(HaveOccurred())
+		stored := &servicesv1.ServiceBinding{}
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(binding), stored)).To(Succeed())
+		Expect(stored.Annotations).To(HaveKeyWithValue(api.RefreshSecretAnnotation, CredentialsTargetDrifted))
+		Expect(meta.IsStatusConditionTrue(binding.Status.Conditions, api.ConditionReady)).To(BeTrue())
+	})
+
+	It("should reuse the Vault token until Vault rejects it", func() {
+		binding.Status.Conditions = []metav1.Condition{{Type: api.ConditionReady, Status: metav1.ConditionTrue, Reason: Created}}
+		Expect(reconciler.storeBindingSecret(ctx, binding, smBinding)).To(Succeed())
+		_, err := reconciler.maintain(ctx, binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kv.logins).To(HaveLen(1))
+
+		kv.revoked["vault-token-1"] = true
+		_, err = reconciler.maintain(ctx, binding)
+		Expect(err).To(HaveOccurred())
+		_, err = reconciler.maintain(ctx, binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kv.logins).To(HaveLen(2))
+	})
+
+	It("should delete the credentials from Vault with the binding", func() {
+		Expect(reconciler.storeBindingSecret(ctx, binding, smBinding)).To(Succeed())
+		_, err := reconciler.deleteSecretAndRemoveFinalizer(ctx, binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kv.secrets).To(BeEmpty())
+	})
+
+	It("should delete the binding if the service account to log in with is gone", func() {
+		Expect(reconciler.storeBindingSecret(ctx, binding, smBinding)).To(Succeed())
+		serviceAccounts = nil
+		// the Vault token of the service account expired
+		reconciler.vaultTokens.Range(func(key, _ interface{}) bool {
+			reconciler.vaultTokens.Delete(key)
+			return true
+		})
+		_, err := reconciler.deleteSecretAndRemoveFinalizer(ctx, binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kv.secrets).To(HaveKey("secret/apps/my-binding"))
+		Expect(binding.Finalizers).To(BeEmpty())
+		Expect(recorder.Events).To(Receive(ContainSubstring(CredentialsTargetWritten)))
+		Expect(recorder.Events).To(Receive(ContainSubstring("the credentials in secret/apps/my-binding in Vault were not deleted")))
+	})
+
+	Context("csi", func() {
+		BeforeEach(func() {
+			binding.Spec.CredentialsTarget = &servicesv1.CredentialsTarget{CSI: &servicesv1.CSICredentialsTarget{}}
+			Expect(reconciler.Client.Update(ctx, binding)).To(Succeed())
+			binding.Status.Conditions = []metav1.Condition{{Type: api.ConditionReady, Status: metav1.ConditionTrue, Reason: Created}}
+			Expect(reconciler.Client.Status().Update(ctx, binding)).To(Succeed())
+		})
+
+		It("should store only the metadata of the binding", func() {
+			Expect(reconciler.storeBindingSecret(ctx, binding, smBinding)).To(Succeed())
+			Expect(kv.secrets).To(BeEmpty())
+			Expect(binding.Status.Binding).To(BeNil())
+			err := reconciler.Client.Get(ctx, types.NamespacedName{Name: "my-secret", Namespace: "app1"}, &corev1.Secret{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			configMap := &corev1.ConfigMap{}
+			Expect(reconciler.Client.Get(ctx, types.NamespacedName{Name: "my-metadata", Namespace: "app1"}, configMap)).To(Succeed())
+			Expect(configMap.Data).To(HaveKeyWithValue("url", "https://api.example.com"))
+
+			_, err = reconciler.maintain(ctx, binding)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(kv.logins).To(BeEmpty())
+		})
+
+		It("should read the credentials of mounted bindings from SM", func() {
+			data, version, err := reconciler.CSICredentials(context.Background(), "app1", "my-binding")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(HaveKeyWithValue("password", []byte("secret")))
+			Expect(data).To(HaveKeyWithValue("instance_name", []byte("my-instance")))
+			Expect(version).To(Equal(hashSecretData(data)))
+			id, _ := smClient.GetBindingByIDArgsForCall(0)
+			Expect(id).To(Equal("1234"))
+		})
+
+		It("should read the credentials from SM once per SM binding", func() {
+			_, version, err := reconciler.CSICredentials(context.Background(), "app1", "my-binding")
+			Expect(err).ToNot(HaveOccurred())
+			_, cachedVersion, err := reconciler.CSICredentials(context.Background(), "app1", "my-binding")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cachedVersion).To(Equal(version))
+			Expect(smClient.GetBindingByIDCallCount()).To(Equal(1))
+
+			// the credentials were rotated
+			binding.Status.BindingID = "5678"
+			Expect(reconciler.Client.Status().Update(ctx, binding)).To(Succeed())
+			_, _, err = reconciler.CSICredentials(context.Background(), "app1", "my-binding")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(smClient.GetBindingByIDCallCount()).To(Equal(2))
+			id, _ := smClient.GetBindingByIDArgsForCall(1)
+			Expect(id).To(Equal("5678"))
+		})
+
+		It("should keep the version of encrypted credentials", func() {
+			privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).ToNot(HaveOccurred())
+			der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+			Expect(err).ToNot(HaveOccurred())
+			keySecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "app-public-key", Namespace: "app1"},
+				Data:       map[string][]byte{"public.pem": pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})},
+			}
+			Expect(reconciler.Client.Create(ctx, keySecret)).To(Succeed())
+			binding.Spec.EncryptKeys = []string{"password"}
+			binding.Spec.EncryptionKeyRef = &servicesv1.EncryptionKeyReference{Kind: "Secret", Name: keySecret.Name, Key: "public.pem"}
+			Expect(reconciler.Client.Update(ctx, binding)).To(Succeed())
+
+			data, version, err := reconciler.CSICredentials(context.Background(), "app1", "my-binding")
+			Expect(err).ToNot(HaveOccurred())
+			decrypted, err := encryption.Decrypt(privateKey, data["password"])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(decrypted)).To(Equal("secret"))
+
+			remounted, remountedVersion, err := reconciler.CSICredentials(context.Background(), "app1", "my-binding")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(remounted["password"]).ToNot(Equal(data["password"]))
+			Expect(remountedVersion).To(Equal(version))
+		})
+
+		It("should not serve the credentials of bindings of other namespaces or targets", func() {
+			_, _, err := reconciler.CSICredentials(context.Background(), "app2", "my-binding")
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+			binding.Spec.CredentialsTarget = &servicesv1.CredentialsTarget{Vault: &servicesv1.VaultCredentialsTarget{Path: "apps/my-binding"}}
+			Expect(reconciler.Client.Update(ctx, binding)).To(Succeed())
+			_, _, err = reconciler.CSICredentials(context.Background(), "app1", "my-binding")
+			Expect(err).To(MatchError(ContainSubstring("does not serve its credentials to CSI volumes")))
+		})
+
+		It("should not serve the credentials of bindings that are not ready", func() {
+			binding.Status.Conditions = nil
+			Expect(reconciler.Client.Status().Update(ctx, binding)).To(Succeed())
+			_, _, err := reconciler.CSICredentials(context.Background(), "app1", "my-binding")
+			Expect(err).To(MatchError(ContainSubstring("is not ready")))
+			Expect(smClient.GetBindingByIDCallCount()).To(BeZero())
+		})
+	})
+})
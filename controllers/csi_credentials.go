@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// csiCredentials are the credentials of a binding read from SM. The credentials of an SM binding never change, a
+// rotation creates a new SM binding, so they are read once per SM binding instead of on every mount.
+type csiCredentials struct {
+	bindingID   string
+	credentials json.RawMessage
+}
+
+// CSICredentials reads the credentials of a binding with the csi credentials target from SM and returns the data of its
+// binding secret, shaped by its secret template and with its encrypted keys, together with a version of the plaintext
+// data. It is called on the credentials endpoint of the manager by the provider of the Secrets Store CSI driver, with
+// the namespace of the pod mounting the binding, so that pods mount only the bindings of their own namespace.
+func (r *ServiceBindingReconciler) CSICredentials(ctx context.Context, namespace, name string) (map[string][]byte, string, error) {
+	ctx = context.WithValue(ctx, LogKey{}, r.Log.WithValues("servicebinding", types.NamespacedName{Namespace: namespace, Name: name}))
+	binding := &servicesv1.ServiceBinding{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, binding); err != nil {
+		return nil, "", err
+	}
+	if binding.Spec.CredentialsTarget == nil || binding.Spec.CredentialsTarget.CSI == nil {
+		return nil, "", fmt.Errorf("binding %s in namespace %s does not serve its credentials to CSI volumes", name, namespace)
+	}
+	if !binding.DeletionTimestamp.IsZero() || len(binding.Status.BindingID) == 0 ||
+		!meta.IsStatusConditionTrue(binding.Status.Conditions, api.ConditionReady) {
+		return nil, "", fmt.Errorf("binding %s in namespace %s is not ready", name, namespace)
+	}
+
+	credentials, err := r.getCSICredentials(ctx, binding)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, _, _, err := r.buildBindingSecret(ctx, binding, credentials)
+	if err != nil {
+		return nil, "", err
+	}
+	// the encryption is randomized, the version is taken from the plaintext data so that it changes with the credentials only
+	version := hashSecretData(desiredSecretData(secret))
+	if err := r.encryptSecretKeys(ctx, binding, secret); err != nil {
+		return nil, "", err
+	}
+	return desiredSecretData(secret), version, nil
+}
+
+// getCSICredentials returns the credentials of the SM binding of the binding, read from SM once per SM binding
+func (r *ServiceBindingReconciler) getCSICredentials(ctx context.Context, binding *servicesv1.ServiceBinding) (json.RawMessage, error) {
+	if cached, found := r.csiCredentials.Load(binding.UID); found && cached.(*csiCredentials).bindingID == binding.Status.BindingID {
+		return cached.(*csiCredentials).credentials, nil
+	}
+
+	serviceInstance, err := r.getServiceInstanceForBinding(ctx, binding)
+	if err != nil {
+		return nil, err
+	}
+	smClient, err := r.getSMClient(ctx, binding, btpAccessSecretName(serviceInstance))
+	if err != nil {
+		return nil, err
+	}
+	smBinding, err := smClient.GetBindingByID(binding.Status.BindingID, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the credentials from Service Manager")
+	}
+	if smBinding.Credentials == nil {
+		return nil, fmt.Errorf("the credentials of binding %s cannot be read from Service Manager", binding.Status.BindingID)
+	}
+	r.csiCredentials.Store(binding.UID, &csiCredentials{bindingID: binding.Status.BindingID, credentials: smBinding.Credentials})
+	return smBinding.Credentials, nil
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// orphanInstance removes an instance with the Orphan deletion policy from the cluster and keeps it in SM
+func (r *ServiceInstanceReconciler) orphanInstance(ctx context.Context, serviceInstance *servicesv1.ServiceInstance) (ctrl.Result, error) {
+	message := fmt.Sprintf("the deletion policy is %s, the instance %s is kept in Service Manager", servicesv1.DeletionPolicyOrphan, serviceInstance.Status.InstanceID)
+	GetLogger(ctx).Info(message)
+	r.Recorder.Event(serviceInstance, corev1.EventTypeNormal, Orphaned, message)
+	return ctrl.Result{}, r.removeFinalizer(ctx, serviceInstance, api.FinalizerName)
+}
+
+// orphanRequested checks whether the binding is kept in SM when it is deleted. Rotated bindings are always deleted,
+// they hold the credentials replaced by the rotation.
+func orphanRequested(binding *servicesv1.ServiceBinding) bool {
+	return binding.Spec.DeletionPolicy == servicesv1.DeletionPolicyOrphan &&
+		len(binding.Labels[api.StaleBindingIDLabel]) == 0 &&
+		binding.Status.OperationType != smClientTypes.DELETE
+}
+
+// orphanBinding removes a binding with the Orphan deletion policy from the cluster, keeps it in SM and releases its
+// secret, generated secrets and config maps, which are then no longer garbage collected with the binding
+func (r *ServiceBindingReconciler) orphanBinding(ctx context.Context, serviceBinding *servicesv1.ServiceBinding) (ctrl.Result, error) {
+	log := GetLogger(ctx)
+	secretKey := r.bindingSecretKey(serviceBinding)
+	secret, err := r.getSecret(ctx, secretKey.Namespace, secretKey.Name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	if err == nil && (metav1.IsControlledBy(secret, serviceBinding) || secret.Annotations[api.SecretBindingUIDAnnotation] == string(serviceBinding.UID)) {
+		releaseFromBinding(secret, serviceBinding)
+		delete(secret.Annotations, api.SecretBindingUIDAnnotation)
+		if err := r.Client.Update(ctx, secret); err != nil {
+			log.Error(err, "failed to release the secret of the orphaned binding")
+			return ctrl.Result{}, err
+		}
+	}
+
+	configMaps := &corev1.ConfigMapList{}
+	if err := r.apiReader().List(ctx, configMaps,
+		client.InNamespace(secretKey.Namespace),
+		client.MatchingLabels{api.BindingUIDLabel: string(serviceBinding.UID)}); err != nil {
+		return ctrl.Result{}, err
+	}
+	for i := range configMaps.Items {
+		configMap := &configMaps.Items[i]
+		releaseFromBinding(configMap, serviceBinding)
+		delete(configMap.Labels, api.BindingUIDLabel)
+		delete(configMap.Annotations, api.SecretBindingUIDAnnotation)
+		if err := r.Client.Update(ctx, configMap); err != nil {
+			log.Error(err, "failed to release the config map of the orphaned binding", "name", configMap.Name)
+			return ctrl.Result{}, err
+		}
+	}
+
+	generatedSecrets := &corev1.SecretList{}
+	if err := r.Client.List(ctx, generatedSecrets,
+		client.InNamespace(secretKey.Namespace),
+		client.MatchingLabels{api.BindingUIDLabel: string(serviceBinding.UID)}); err != nil {
+		return ctrl.Result{}, err
+	}
+	for i := range generatedSecrets.Items {
+		generatedSecret := &generatedSecrets.Items[i]
+		releaseFromBinding(generatedSecret, serviceBinding)
+		delete(generatedSecret.Labels, api.BindingUIDLabel)
+		delete(generatedSecret.Annotations, api.SecretBindingUIDAnnotation)
+		if err := r.Client.Update(ctx, generatedSecret); err != nil {
+			log.Error(err, "failed to release the generated secret of the orphaned binding", "name", generatedSecret.Name)
+			return ctrl.Result{}, err
+		}
+	}
+
+	message := fmt.Sprintf("the deletion policy is %s, the binding %s is kept in Service Manager and its secret is kept", servicesv1.DeletionPolicyOrphan, serviceBinding.Status.BindingID)
+	log.Info(message)
+	r.Recorder.Event(serviceBinding, corev1.EventTypeNormal, Orphaned, message)
+	if err := r.removeFinalizer(ctx, serviceBinding, api.FinalizerName); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.forgetBinding(serviceBinding)
+	return ctrl.Result{}, nil
+}
+
+// releaseFromBinding removes the owner reference of the binding, so that the object outlives the binding
+func releaseFromBinding(object client.Object, binding *servicesv1.ServiceBinding) {
+	var owners []metav1.OwnerReference
+	for _, owner := range object.GetOwnerReferences() {
+		if owner.UID != binding.UID {
+			owners = append(owners, owner)
+		}
+	}
+	object.SetOwnerReferences(owners)
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Deletion policy", func() {
+	var testScheme *runtime.Scheme
+	var recorder *record.FakeRecorder
+	var ctx context.Context
+
+	BeforeEach(func() {
+		testScheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		recorder = record.NewFakeRecorder(10)
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+	})
+
+	It("should keep orphaned bindings and their secrets", func() {
+		binding := &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1", UID: "binding-uid", Finalizers: []string{api.FinalizerName}},
+			Spec:       servicesv1.ServiceBindingSpec{SecretName: "my-secret", DeletionPolicy: servicesv1.DeletionPolicyOrphan},
+			Status:     servicesv1.ServiceBindingStatus{BindingID: "1234"},
+		}
+		owner := metav1.OwnerReference{APIVersion: "services.cloud.sap.com/v1", Kind: "ServiceBinding", Name: binding.Name, UID: binding.UID, Controller: pointer.Bool(true)}
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-secret", Namespace: "app1", OwnerReferences: []metav1.OwnerReference{owner}}}
+		configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "my-config", Namespace: "app1", OwnerReferences: []metav1.OwnerReference{owner},
+			Labels: map[string]string{api.BindingUIDLabel: string(binding.UID)}}}
+		reconciler := &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(binding, secret, configMap).Build(),
+			Scheme:   testScheme,
+			Recorder: recorder,
+		}}
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(binding), binding)).To(Succeed())
+		Expect(orphanRequested(binding)).To(BeTrue())
+
+		_, err := reconciler.orphanBinding(ctx, binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(secret), secret)).To(Succeed())
+		Expect(secret.OwnerReferences).To(BeEmpty())
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(configMap), configMap)).To(Succeed())
+		Expect(configMap.OwnerReferences).To(BeEmpty())
+		Expect(configMap.Labels).ToNot(HaveKey(api.BindingUIDLabel))
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(binding), binding)
+		Expect(err == nil && len(binding.Finalizers) == 0 || apierrors.IsNotFound(err)).To(BeTrue())
+		Expect(<-recorder.Events).To(ContainSubstring("Normal Orphaned the deletion policy is Orphan, the binding 1234 is kept"))
+	})
+
+	It("should delete rotated bindings regardless of the policy", func() {
+		binding := &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding-stale", Namespace: "app1", Labels: map[string]string{api.StaleBindingIDLabel: "1234"}},
+			Spec:       servicesv1.ServiceBindingSpec{DeletionPolicy: servicesv1.DeletionPolicyOrphan},
+		}
+		Expect(orphanRequested(binding)).To(BeFalse())
+	})
+
+	It("should keep orphaned instances", func() {
+		instance := &servicesv1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-instance", Namespace: "app1", Finalizers: []string{api.FinalizerName}},
+			Spec:       servicesv1.ServiceInstanceSpec{DeletionPolicy: servicesv1.DeletionPolicyOrphan},
+			Status:     servicesv1.ServiceInstanceStatus{InstanceID: "5678"},
+		}
+		reconciler := &ServiceInstanceReconciler{BaseReconciler: &BaseReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance).Build(),
+			Scheme:   testScheme,
+			Recorder: recorder,
+		}}
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(instance), instance)).To(Succeed())
+
+		_, err := reconciler.deleteInstance(ctx, instance)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(instance), instance)).To(Succeed())
+		Expect(instance.Finalizers).To(BeEmpty())
+		Expect(<-recorder.Events).To(ContainSubstring("Normal Orphaned the deletion policy is Orphan, the instance 5678 is kept in Service Manager"))
+	})
+
+	It("should not update the instance in SM when the policy changes", func() {
+		instance := &servicesv1.ServiceInstance{Spec: servicesv1.ServiceInstanceSpec{ServicePlanName: "plan"}}
+		hash := getSpecHash(instance)
+		instance.Spec.DeletionPolicy = servicesv1.DeletionPolicyOrphan
+		Expect(getSpecHash(instance)).To(Equal(hash))
+	})
+})
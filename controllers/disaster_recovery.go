@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// Resources exported from a primary cluster with `kubectl sapbtp export` carry the ID of the resource in SM and the
+// cluster ID of the primary. On a standby cluster the operator adopts the existing resource by its ID instead of
+// looking it up by the cluster ID, which differs between the clusters. Users adopt a single existing instance, e.g. one
+// created in the SAP BTP cockpit, by its ID in spec.instanceID.
+
+const (
+	adoptionNotFoundErrorFormat = "the %s '%s' %s was not found in Service Manager"
+	adoptionConflictErrorFormat = "cannot adopt the %s '%s' %s: %s"
+)
+
+// adoptionID returns the ID of the resource in SM if the resource was imported from the export of another cluster or
+// references an existing instance
+func adoptionID(object api.SAPBTPResource) string {
+	if instance, ok := object.(*servicesv1.ServiceInstance); ok && len(instance.Spec.InstanceID) > 0 {
+		return instance.Spec.InstanceID
+	}
+	return object.GetAnnotations()[api.DRResourceIDAnnotation]
+}
+
+// adoptionRequested checks whether the instance references an existing instance by its spec.instanceID
+func adoptionRequested(object api.SAPBTPResource) bool {
+	instance, ok := object.(*servicesv1.ServiceInstance)
+	return ok && len(instance.Spec.InstanceID) > 0
+}
+
+// adoptionOrigin describes where the ID of the adopted resource comes from
+func adoptionOrigin(object api.SAPBTPResource) string {
+	if adoptionRequested(object) {
+		return "referenced by spec.instanceID"
+	}
+	return fmt.Sprintf("exported from cluster '%s'", object.GetAnnotations()[api.DRSourceClusterAnnotation])
+}
+
+// validateAdoption detects conflicts between the imported resource and the resource found in SM: the resource must have
+// the expected name and must belong to the exported cluster or to this cluster, i.e. not to a third cluster, and to the
+// namespace of the imported resource, so that a resource cannot be taken over from another namespace. A resource that
+// belongs to no cluster is adopted only when an adoption run imported it, the label of the run is set by the operator
+// only, or when the instance references it by its spec.instanceID.
+func validateAdoption(clusterID string, object api.SAPBTPResource, expectedName, smName string, smContext json.RawMessage) error {
+	sourceClusterID := object.GetAnnotations()[api.DRSourceClusterAnnotation]
+	conflict := func(reason string) error {
+		return fmt.Errorf(adoptionConflictErrorFormat, object.GetControllerName(), adoptionID(object), adoptionOrigin(object), reason)
+	}
+
+	if smName != expectedName {
+		return conflict(fmt.Sprintf("its name in Service Manager is '%s' instead of '%s'", smName, expectedName))
+	}
+
+	var smClusterContext struct {
+		ClusterID string `json:"clusterid"`
+		Namespace string `json:"namespace"`
+	}
+	if len(smContext) > 0 {
+		if err := json.Unmarshal(smContext, &smClusterContext); err != nil {
+			return conflict(fmt.Sprintf("failed to read its context: %s", err.Error()))
+		}
+	}
+	if len(smClusterContext.ClusterID) == 0 && len(object.GetLabels()[api.AdoptionRunLabel]) == 0 && !adoptionRequested(object) {
+		return conflict("it is not managed by a cluster, import it with an adoption run or spec.instanceID")
+	}
+	if len(smClusterContext.ClusterID) > 0 && smClusterContext.ClusterID != sourceClusterID && smClusterContext.ClusterID != clusterID {
+		return conflict(fmt.Sprintf("it belongs to cluster '%s'", smClusterContext.ClusterID))
+	}
+	if len(smClusterContext.ClusterID) > 0 && smClusterContext.Namespace != object.GetNamespace() {
+		return conflict(fmt.Sprintf("it belongs to namespace '%s'", smClusterContext.Namespace))
+	}
+	return nil
+}
+
+// adoptionDisabled fails imported resources while the Adoption feature gate is disabled
+func (r *BaseReconciler) adoptionDisabled(ctx context.Context, object api.SAPBTPResource) (ctrl.Result, error) {
+	message := fmt.Sprintf("cannot adopt the %s '%s': adoption is disabled, enable the %s feature gate of the operator", object.GetControllerName(), adoptionID(object), config.Adoption)
+	return r.markAsNonTransientError(ctx, smClientTypes.CREATE, message, object)
+}
+
+func adoptionNotFoundMessage(object api.SAPBTPResource) string {
+	return fmt.Sprintf(adoptionNotFoundErrorFormat, object.GetControllerName(), adoptionID(object), adoptionOrigin(object))
+}
+
+func isNotFoundError(err error) bool {
+	var smError *sm.ServiceManagerError
+	return errors.As(err, &smError) && smError.StatusCode == http.StatusNotFound
+}
+
+func (r *BaseReconciler) recordAdoption(object api.SAPBTPResource) {
+	message := fmt.Sprintf("adopted %s '%s' %s", object.GetControllerName(), adoptionID(object), adoptionOrigin(object))
+	if run := object.GetLabels()[api.AdoptionRunLabel]; len(run) > 0 {
+		message = fmt.Sprintf("adopted %s '%s' imported by adoption run '%s'", object.GetControllerName(), adoptionID(object), run)
+	}
+	r.Recorder.Event(object, corev1.EventTypeNormal, "Adopted", message)
+}
+
+// adoptInstance recovers the instance by the ID of the export or of its spec instead of looking it up
+func (r *ServiceInstanceReconciler) adoptInstance(ctx context.Context, smClient sm.Client, serviceInstance *servicesv1.ServiceInstance) (ctrl.Result, error) {
+	log := GetLogger(ctx)
+	if !r.Config.FeatureGates.Enabled(config.Adoption) {
+		return r.adoptionDisabled(ctx, serviceInstance)
+	}
+	log.Info(fmt.Sprintf("adopting instance %s %s", adoptionID(serviceInstance), adoptionOrigin(serviceInstance)))
+	smInstance, err := smClient.GetInstanceByID(adoptionID(serviceInstance), nil)
+	if err != nil {
+		if isNotFoundError(err) {
+			return r.markAsNonTransientError(ctx, smClientTypes.CREATE, adoptionNotFoundMessage(serviceInstance), serviceInstance)
+		}
+		log.Error(err, "failed to get the instance to adopt")
+		return r.handleError(ctx, smClientTypes.CREATE, err, serviceInstance)
+	}
+
+	if err := validateAdoption(r.settings().ClusterID, serviceInstance, serviceInstance.Spec.ExternalName, smInstance.Name, smInstance.Context); err != nil {
+		return r.markAsNonTransientError(ctx, smClientTypes.CREATE, err.Error(), serviceInstance)
+	}
+	r.recordAdoption(serviceInstance)
+	return r.recover(ctx, smClient, serviceInstance, smInstance)
+}
+
+// adoptionPending checks whether the binding was imported by an adoption run and waits to be bound explicitly
+func adoptionPending(serviceBinding *servicesv1.ServiceBinding) bool {
+	return len(adoptionID(serviceBinding)) > 0 && serviceBinding.Annotations[api.AdoptionPendingAnnotation] == "true"
+}
+
+// adoptBinding recovers the binding and its credentials by the ID of the export instead of looking it up
+func (r *ServiceBindingReconciler) adoptBinding(ctx context.Context, smClient sm.Client, serviceBinding *servicesv1.ServiceBinding) (ctrl.Result, error) {
+	log := GetLogger(ctx)
+	if !r.Config.FeatureGates.Enabled(config.Adoption) {
+		return r.adoptionDisabled(ctx, serviceBinding)
+	}
+	if adoptionPending(serviceBinding) {
+		message := fmt.Sprintf("the binding '%s' was imported by adoption run '%s', remove the annotation '%s' to store its credentials",
+			adoptionID(serviceBinding), serviceBinding.Labels[api.AdoptionRunLabel], api.AdoptionPendingAnnotation)
+		log.Info(message)
+		setBlockedCondition(ctx, message, serviceBinding)
+		return ctrl.Result{}, r.updateStatus(ctx, serviceBinding)
+	}
+	log.Info(fmt.Sprintf("adopting binding %s exported from another cluster", adoptionID(serviceBinding)))
+	smBinding, err := smClient.GetBindingByID(adoptionID(serviceBinding), nil)
+	if err != nil {
+		if isNotFoundError(err) {
+			return r.markAsNonTransientError(ctx, smClientTypes.CREATE, adoptionNotFoundMessage(serviceBinding), serviceBinding)
+		}
+		log.Error(err, "failed to get the binding to adopt")
+		return r.handleError(ctx, smClientTypes.CREATE, err, serviceBinding)
+	}
+
+	if err := validateAdoption(r.settings().ClusterID, serviceBinding, serviceBinding.Spec.ExternalName, smBinding.Name, smBinding.Context); err != nil {
+		return r.markAsNonTransientError(ctx, smClientTypes.CREATE, err.Error(), serviceBinding)
+	}
+	r.recordAdoption(serviceBinding)
+	return r.recover(ctx, serviceBinding, smBinding)
+}
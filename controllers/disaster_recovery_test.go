@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	v1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	"github.com/SAP/sap-btp-service-operator/client/sm/smfakes"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Disaster recovery", func() {
+	var instance *v1.ServiceInstance
+
+	BeforeEach(func() {
+		instance = &v1.ServiceInstance{}
+		instance.Namespace = "default"
+		instance.Annotations = map[string]string{
+			api.DRSourceClusterAnnotation: "primary-cluster",
+			api.DRResourceIDAnnotation:    "instance-id",
+		}
+	})
+
+	smContext := func(clusterID string) json.RawMessage {
+		return json.RawMessage(fmt.Sprintf(`{"clusterid":"%s","namespace":"default"}`, clusterID))
+	}
+
+	It("should return the ID of imported resources only", func() {
+		Expect(adoptionID(instance)).To(Equal("instance-id"))
+		Expect(adoptionID(&v1.ServiceInstance{})).To(BeEmpty())
+	})
+
+	It("should adopt resources of the exported cluster and of this cluster", func() {
+		Expect(validateAdoption("standby-cluster", instance, "my-instance", "my-instance", smContext("primary-cluster"))).To(Succeed())
+		Expect(validateAdoption("standby-cluster", instance, "my-instance", "my-instance", smContext("standby-cluster"))).To(Succeed())
+	})
+
+	It("should detect resources of another cluster", func() {
+		err := validateAdoption("standby-cluster", instance, "my-instance", "my-instance", smContext("other-cluster"))
+		Expect(err).To(MatchError(ContainSubstring("it belongs to cluster 'other-cluster'")))
+	})
+
+	It("should detect resources of another namespace", func() {
+		instance.Namespace = "other-namespace"
+		err := validateAdoption("standby-cluster", instance, "my-instance", "my-instance", smContext("primary-cluster"))
+		Expect(err).To(MatchError(ContainSubstring("it belongs to namespace 'default'")))
+	})
+
+	It("should detect resources with another name", func() {
+		err := validateAdoption("standby-cluster", instance, "my-instance", "other-instance", smContext("primary-cluster"))
+		Expect(err).To(MatchError(ContainSubstring("its name in Service Manager is 'other-instance' instead of 'my-instance'")))
+	})
+
+	It("should adopt resources managed by no cluster only when imported by an adoption run", func() {
+		err := validateAdoption("standby-cluster", instance, "my-instance", "my-instance", nil)
+		Expect(err).To(MatchError(ContainSubstring("it is not managed by a cluster")))
+
+		instance.Labels = map[string]string{api.AdoptionRunLabel: "import"}
+		Expect(validateAdoption("standby-cluster", instance, "my-instance", "my-instance", nil)).To(Succeed())
+	})
+
+	It("should adopt the instance referenced by its spec", func() {
+		referencing := &v1.ServiceInstance{}
+		referencing.Namespace = "default"
+		referencing.Spec.InstanceID = "0f4e1c3a-6b1d-4a52-9d36-8e6f4c1b2a71"
+		Expect(adoptionID(referencing)).To(Equal("0f4e1c3a-6b1d-4a52-9d36-8e6f4c1b2a71"))
+		Expect(adoptionNotFoundMessage(referencing)).To(Equal("the ServiceInstance '0f4e1c3a-6b1d-4a52-9d36-8e6f4c1b2a71' referenced by spec.instanceID was not found in Service Manager"))
+
+		// e.g. an instance created in the SAP BTP cockpit
+		Expect(validateAdoption("standby-cluster", referencing, "my-instance", "my-instance", nil)).To(Succeed())
+		Expect(validateAdoption("standby-cluster", referencing, "my-instance", "my-instance", smContext("standby-cluster"))).To(Succeed())
+		err := validateAdoption("standby-cluster", referencing, "my-instance", "my-instance", smContext("other-cluster"))
+		Expect(err).To(MatchError(ContainSubstring("it belongs to cluster 'other-cluster'")))
+		referencing.Namespace = "other-namespace"
+		err = validateAdoption("standby-cluster", referencing, "my-instance", "my-instance", smContext("standby-cluster"))
+		Expect(err).To(MatchError(ContainSubstring("it belongs to namespace 'default'")))
+	})
+
+	It("should adopt the instance referenced by its spec instead of provisioning it", func() {
+		testScheme := runtime.NewScheme()
+		Expect(v1.AddToScheme(testScheme)).To(Succeed())
+		referencing := &v1.ServiceInstance{}
+		referencing.Name, referencing.Namespace, referencing.Generation = "my-instance", "default", 1
+		referencing.Spec.ExternalName = "My Instance"
+		referencing.Spec.InstanceID = "0f4e1c3a-6b1d-4a52-9d36-8e6f4c1b2a71"
+		gates := config.FeatureGates{}
+		Expect(gates.Decode("Adoption=true")).To(Succeed())
+		reconciler := &ServiceInstanceReconciler{BaseReconciler: &BaseReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(referencing).WithStatusSubresource(referencing).Build(),
+			Scheme:   testScheme,
+			Recorder: record.NewFakeRecorder(10),
+			Config:   config.Config{ClusterID: "this-cluster", FeatureGates: gates},
+		}}
+		ctx := context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(referencing), referencing)).To(Succeed())
+		smClient := &smfakes.FakeClient{}
+		smClient.GetInstanceByIDReturns(&smClientTypes.ServiceInstance{ID: referencing.Spec.InstanceID, Name: "My Instance", ServicePlanID: "plan-id", Ready: true}, nil)
+
+		_, err := reconciler.adoptInstance(ctx, smClient, referencing)
+		Expect(err).ToNot(HaveOccurred())
+		id, _ := smClient.GetInstanceByIDArgsForCall(0)
+		Expect(id).To(Equal(referencing.Spec.InstanceID))
+		Expect(smClient.ProvisionCallCount()).To(BeZero())
+		Expect(referencing.Status.InstanceID).To(Equal(referencing.Spec.InstanceID))
+	})
+
+	It("should detect resources that do not exist", func() {
+		Expect(isNotFoundError(&sm.ServiceManagerError{StatusCode: http.StatusNotFound})).To(BeTrue())
+		Expect(isNotFoundError(&sm.ServiceManagerError{StatusCode: http.StatusBadGateway})).To(BeFalse())
+		Expect(adoptionNotFoundMessage(instance)).To(Equal("the ServiceInstance 'instance-id' exported from cluster 'primary-cluster' was not found in Service Manager"))
+	})
+
+	It("should not adopt resources while the adoption is disabled", func() {
+		testScheme := runtime.NewScheme()
+		Expect(v1.AddToScheme(testScheme)).To(Succeed())
+		instance.Name = "imported-instance"
+		reconciler := &ServiceInstanceReconciler{BaseReconciler: &BaseReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance).WithStatusSubresource(instance).Build(),
+			Scheme:   testScheme,
+			Recorder: record.NewFakeRecorder(10),
+		}}
+		ctx := context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(instance), instance)).To(Succeed())
+		smClient := &smfakes.FakeClient{}
+
+		_, err := reconciler.adoptInstance(ctx, smClient, instance)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(smClient.GetInstanceByIDCallCount()).To(BeZero())
+		failed := meta.FindStatusCondition(instance.GetConditions(), api.ConditionFailed)
+		Expect(failed).ToNot(BeNil())
+		Expect(failed.Message).To(ContainSubstring("adoption is disabled, enable the Adoption feature gate"))
+	})
+})
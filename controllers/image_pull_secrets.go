@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ImagePullSecretSkipped is the reason of the events of bindings whose secret cannot be used to pull images
+const ImagePullSecretSkipped = "ImagePullSecretSkipped"
+
+// syncImagePullSecrets references the binding secret in the imagePullSecrets of the service accounts listed in
+// .Spec.ImagePullServiceAccounts, restoring removed references, and drops it from the service accounts no longer listed.
+// The service accounts are not cached, they are read through the API reader. The service accounts are left as they are
+// while the ImagePullServiceAccounts feature gate is disabled, the operator may not be permitted to update them.
+// Returns true if the service accounts in the status of the binding changed.
+func (r *ServiceBindingReconciler) syncImagePullSecrets(ctx context.Context, binding *servicesv1.ServiceBinding, secret *corev1.Secret) (bool, error) {
+	if !r.Config.FeatureGates.Enabled(config.ImagePullServiceAccounts) {
+		return false, nil
+	}
+	var attached []string
+	if len(binding.Spec.ImagePullServiceAccounts) > 0 {
+		if reason := imagePullSecretUnusable(r.Config.BindingSecretsNamespace, secret); len(reason) > 0 {
+			r.Recorder.Event(binding, corev1.EventTypeWarning, ImagePullSecretSkipped, reason)
+		} else {
+			for _, serviceAccount := range binding.Spec.ImagePullServiceAccounts {
+				found, err := r.addImagePullSecret(ctx, binding.Namespace, serviceAccount, secret.Name)
+				if err != nil {
+					return false, err
+				}
+				if found {
+					attached = append(attached, serviceAccount)
+				}
+			}
+		}
+	}
+
+	for _, serviceAccount := range binding.Status.ImagePullServiceAccounts {
+		if !contains(attached, serviceAccount) {
+			if err := r.removeImagePullSecret(ctx, binding.Namespace, serviceAccount, secret.Name); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	if reflect.DeepEqual(attached, binding.Status.ImagePullServiceAccounts) {
+		return false, nil
+	}
+	binding.Status.ImagePullServiceAccounts = attached
+	return true, nil
+}
+
+// releaseImagePullSecrets drops the binding secret from the imagePullSecrets of the service accounts it was added to
+func (r *ServiceBindingReconciler) releaseImagePullSecrets(ctx context.Context, binding *servicesv1.ServiceBinding) error {
+	if !r.Config.FeatureGates.Enabled(config.ImagePullServiceAccounts) {
+		return nil
+	}
+	for _, serviceAccount := range binding.Status.ImagePullServiceAccounts {
+		if err := r.removeImagePullSecret(ctx, binding.Namespace, serviceAccount, binding.Spec.SecretName); err != nil {
+			return err
+		}
+	}
+	binding.Status.ImagePullServiceAccounts = nil
+	return nil
+}
+
+// imagePullSecretUnusable returns why pods cannot pull images with the secret, empty if they can
+func imagePullSecretUnusable(secretsNamespace string, secret *corev1.Secret) string {
+	if len(secretsNamespace) > 0 {
+		return fmt.Sprintf("the binding secret is stored in the namespace %s and cannot be referenced by the imagePullSecrets of service accounts", secretsNamespace)
+	}
+	if secret.Type != corev1.SecretTypeDockerConfigJson {
+		return fmt.Sprintf("the binding secret is of type %s and cannot be referenced by the imagePullSecrets of service accounts, use the type %s", secret.Type, corev1.SecretTypeDockerConfigJson)
+	}
+	return ""
+}
+
+// addImagePullSecret adds the secret to the imagePullSecrets of the service account, returns false if the service
+// account does not exist (yet)
+func (r *ServiceBindingReconciler) addImagePullSecret(ctx context.Context, namespace, name, secretName string) (bool, error) {
+	serviceAccount := &corev1.ServiceAccount{}
+	if err := r.apiReader().Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, serviceAccount); err != nil {
+		if apierrors.IsNotFound(err) {
+			GetLogger(ctx).Info("service account of the image pull secret not found", "serviceAccount", name)
+			return false, nil
+		}
+		return false, err
+	}
+	for _, ref := range serviceAccount.ImagePullSecrets {
+		if ref.Name == secretName {
+			return true, nil
+		}
+	}
+	GetLogger(ctx).Info("Adding the binding secret to the image pull secrets of the service account", "serviceAccount", name)
+	serviceAccount.ImagePullSecrets = append(serviceAccount.ImagePullSecrets, corev1.LocalObjectReference{Name: secretName})
+	return true, r.Client.Update(ctx, serviceAccount)
+}
+
+// removeImagePullSecret removes the secret from the imagePullSecrets of the service account
+func (r *ServiceBindingReconciler) removeImagePullSecret(ctx context.Context, namespace, name, secretName string) error {
+	serviceAccount := &corev1.ServiceAccount{}
+	if err := r.apiReader().Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, serviceAccount); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	var refs []corev1.LocalObjectReference
+	for _, ref := range serviceAccount.ImagePullSecrets {
+		if ref.Name != secretName {
+			refs = append(refs, ref)
+		}
+	}
+	if len(refs) == len(serviceAccount.ImagePullSecrets) {
+		return nil
+	}
+	GetLogger(ctx).Info("Removing the binding secret from the image pull secrets of the service account", "serviceAccount", name)
+	serviceAccount.ImagePullSecrets = refs
+	return r.Client.Update(ctx, serviceAccount)
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"context"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Image pull secrets", func() {
+	var reconciler *ServiceBindingReconciler
+	var binding *servicesv1.ServiceBinding
+	var secret *corev1.Secret
+	var recorder *record.FakeRecorder
+	var ctx context.Context
+
+	serviceAccount := func(name string) *corev1.ServiceAccount {
+		serviceAccount := &corev1.ServiceAccount{}
+		Expect(reconciler.Client.Get(ctx, types.NamespacedName{Name: name, Namespace: "app1"}, serviceAccount)).To(Succeed())
+		return serviceAccount
+	}
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		recorder = record.NewFakeRecorder(10)
+		builder := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "builder", Namespace: "app1"},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "other-registry"}}}
+		deployer := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "deployer", Namespace: "app1"}}
+		reconciler = &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(builder, deployer).Build(),
+			Scheme:   testScheme,
+			Log:      ctrl.Log,
+			Recorder: recorder,
+		}}
+		Expect(reconciler.Config.FeatureGates.Decode("ImagePullServiceAccounts=true")).To(Succeed())
+		binding = &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1", UID: "binding-uid"},
+			Spec:       servicesv1.ServiceBindingSpec{SecretName: "my-secret", ImagePullServiceAccounts: []string{"builder", "deployer", "missing"}},
+		}
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-secret", Namespace: "app1"}, Type: corev1.SecretTypeDockerConfigJson}
+	})
+
+	It("should reference the secret in the image pull secrets of the existing service accounts", func() {
+		changed, err := reconciler.syncImagePullSecrets(ctx, binding, secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		Expect(binding.Status.ImagePullServiceAccounts).To(Equal([]string{"builder", "deployer"}))
+		Expect(serviceAccount("builder").ImagePullSecrets).To(Equal([]corev1.LocalObjectReference{{Name: "other-registry"}, {Name: "my-secret"}}))
+
+		changed, err = reconciler.syncImagePullSecrets(ctx, binding, secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changed).To(BeFalse())
+		Expect(serviceAccount("deployer").ImagePullSecrets).To(HaveLen(1))
+	})
+
+	It("should drop the secret from the service accounts no longer listed and when the binding is deleted", func() {
+		_, err := reconciler.syncImagePullSecrets(ctx, binding, secret)
+		Expect(err).ToNot(HaveOccurred())
+
+		binding.Spec.ImagePullServiceAccounts = []string{"deployer"}
+		changed, err := reconciler.syncImagePullSecrets(ctx, binding, secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		Expect(serviceAccount("builder").ImagePullSecrets).To(Equal([]corev1.LocalObjectReference{{Name: "other-registry"}}))
+
+		Expect(reconciler.releaseImagePullSecrets(ctx, binding)).To(Succeed())
+		Expect(serviceAccount("deployer").ImagePullSecrets).To(BeEmpty())
+		Expect(binding.Status.ImagePullServiceAccounts).To(BeEmpty())
+	})
+
+	It("should skip secrets that cannot be used to pull images", func() {
+		secret.Type = corev1.SecretTypeOpaque
+		changed, err := reconciler.syncImagePullSecrets(ctx, binding, secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changed).To(BeFalse())
+		Expect(serviceAccount("deployer").ImagePullSecrets).To(BeEmpty())
+		Expect(recorder.Events).To(Receive(ContainSubstring(ImagePullSecretSkipped)))
+	})
+})
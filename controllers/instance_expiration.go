@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	ExpirationWarning = "ExpirationWarning"
+	Expired           = "Expired"
+)
+
+// bindingInstanceIndex is the field index of the bindings by the namespaced name of the instance they reference
+const bindingInstanceIndex = "spec.serviceInstanceName"
+
+// indexBindingInstance returns the namespaced name of the instance referenced by the binding,
+// the instance may be in another namespace than the binding
+func indexBindingInstance(obj client.Object) []string {
+	binding, ok := obj.(*servicesv1.ServiceBinding)
+	if !ok || len(binding.Spec.ServiceInstanceName) == 0 {
+		return nil
+	}
+	instanceNamespace := binding.Namespace
+	if len(binding.Spec.ServiceInstanceNamespace) > 0 {
+		instanceNamespace = binding.Spec.ServiceInstanceNamespace
+	}
+	return []string{types.NamespacedName{Namespace: instanceNamespace, Name: binding.Spec.ServiceInstanceName}.String()}
+}
+
+// expires checks whether the instance has an expiration time and is not protected from deletion
+func expires(serviceInstance *servicesv1.ServiceInstance) bool {
+	return serviceInstance.Spec.ExpiresAt != nil && strings.ToLower(serviceInstance.Annotations[api.PreventDeletion]) != "true"
+}
+
+// isExpired checks whether the expiration time of the instance has passed,
+// instances protected from deletion are kept
+func isExpired(serviceInstance *servicesv1.ServiceInstance) bool {
+	if !expires(serviceInstance) || isMarkedForDeletion(serviceInstance.ObjectMeta) {
+		return false
+	}
+	return !time.Now().Before(serviceInstance.Spec.ExpiresAt.Time)
+}
+
+// expirationWarningDue checks whether the instance expires within the warning period and was not warned about it yet,
+// instances protected from deletion do not expire and are not warned about
+func expirationWarningDue(serviceInstance *servicesv1.ServiceInstance, warningPeriod time.Duration) bool {
+	if !expires(serviceInstance) || warningPeriod <= 0 {
+		return false
+	}
+	expiresAt := serviceInstance.Spec.ExpiresAt
+	warningTime := expiresAt.Add(-warningPeriod)
+	if time.Now().Before(warningTime) {
+		return false
+	}
+	// the expiration may have been postponed since the last warning
+	lastWarning := serviceInstance.Status.LastExpirationWarning
+	return lastWarning == nil || lastWarning.Time.Before(warningTime)
+}
+
+// expirationDelay returns the time left until the next warning or the expiration of the instance is due,
+// zero if the instance does not expire
+func expirationDelay(serviceInstance *servicesv1.ServiceInstance, warningPeriod time.Duration) time.Duration {
+	if !expires(serviceInstance) {
+		return 0
+	}
+	expiresAt := serviceInstance.Spec.ExpiresAt
+	if warningPeriod > 0 {
+		if delay := time.Until(expiresAt.Add(-warningPeriod)); delay > 0 {
+			return delay
+		}
+	}
+	if delay := time.Until(expiresAt.Time); delay > 0 {
+		return delay
+	}
+	return 0
+}
+
+// nextExpirationCheck requeues the instance for its next expiration warning or its expiration,
+// nothing is requeued when the expiration of instances is disabled
+func (r *ServiceInstanceReconciler) nextExpirationCheck(serviceInstance *servicesv1.ServiceInstance) ctrl.Result {
+	if !r.Config.FeatureGates.Enabled(config.InstanceExpiration) {
+		return ctrl.Result{}
+	}
+	return ctrl.Result{RequeueAfter: expirationDelay(serviceInstance, r.Config.ExpirationWarningPeriod)}
+}
+
+func (r *ServiceInstanceReconciler) warnExpiration(ctx context.Context, serviceInstance *servicesv1.ServiceInstance) error {
+	expiresAt := serviceInstance.Spec.ExpiresAt
+	msg := fmt.Sprintf("the instance and its bindings will be deleted at %s (in %s)", expiresAt.UTC().Format(time.RFC3339), time.Until(expiresAt.Time).Round(time.Minute))
+	GetLogger(ctx).Info(msg)
+	r.Recorder.Event(serviceInstance, corev1.EventTypeWarning, ExpirationWarning, msg)
+
+	serviceInstance.Status.LastExpirationWarning = &metav1.Time{Time: time.Now()}
+	return r.updateStatus(ctx, serviceInstance)
+}
+
+// expireInstance deletes the bindings of the expired instance and then the instance itself,
+// the instance is not deprovisioned before its bindings are gone
+func (r *ServiceInstanceReconciler) expireInstance(ctx context.Context, serviceInstance *servicesv1.ServiceInstance) (ctrl.Result, error) {
+	log := GetLogger(ctx)
+	bindings, err := r.getBindingsOfInstance(ctx, serviceInstance)
+	if err != nil {
+		log.Error(err, "failed to list the bindings of the expired instance")
+		return ctrl.Result{}, err
+	}
+
+	if len(bindings) > 0 {
+		for i := range bindings {
+			binding := &bindings[i]
+			if isMarkedForDeletion(binding.ObjectMeta) {
+				continue
+			}
+			log.Info(fmt.Sprintf("instance expired, deleting binding %s/%s", binding.Namespace, binding.Name))
+			if err := r.Client.Delete(ctx, binding); client.IgnoreNotFound(err) != nil {
+				log.Error(err, "failed to delete the binding of the expired instance", "binding", binding.Name)
+				return ctrl.Result{}, err
+			}
+		}
+		log.Info(fmt.Sprintf("waiting for %d bindings of the expired instance to be deleted", len(bindings)))
+		return ctrl.Result{RequeueAfter: r.pollInterval()}, nil
+	}
+
+	msg := fmt.Sprintf("the instance expired at %s and is deleted", serviceInstance.Spec.ExpiresAt.UTC().Format(time.RFC3339))
+	log.Info(msg)
+	r.Recorder.Event(serviceInstance, corev1.EventTypeWarning, Expired, msg)
+	return ctrl.Result{}, client.IgnoreNotFound(r.Client.Delete(ctx, serviceInstance))
+}
+
+// getBindingsOfInstance returns the bindings referencing the instance, including bindings in other namespaces
+func (r *ServiceInstanceReconciler) getBindingsOfInstance(ctx context.Context, serviceInstance *servicesv1.ServiceInstance) ([]servicesv1.ServiceBinding, error) {
+	bindingList := &servicesv1.ServiceBindingList{}
+	instanceKey := types.NamespacedName{Namespace: serviceInstance.Namespace, Name: serviceInstance.Name}
+	if err := r.Client.List(ctx, bindingList, client.MatchingFields{bindingInstanceIndex: instanceKey.String()}); err != nil {
+		return nil, err
+	}
+	return bindingList.Items, nil
+}
+
+// recordExpiration exposes the expiration time of the instance as a metric
+func recordExpiration(key types.NamespacedName, serviceInstance *servicesv1.ServiceInstance) {
+	if serviceInstance == nil || serviceInstance.Spec.ExpiresAt == nil || isMarkedForDeletion(serviceInstance.ObjectMeta) {
+		instanceExpirationTimestamp.DeleteLabelValues(key.Namespace, key.Name)
+		return
+	}
+	instanceExpirationTimestamp.WithLabelValues(key.Namespace, key.Name).Set(float64(serviceInstance.Spec.ExpiresAt.Unix()))
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// InstanceInfoKeyConflict is the reason of the InstanceInfoConflict condition
+	InstanceInfoKeyConflict = "InstanceInfoKeyConflict"
+
+	// secretMetadataKey is the reserved key of the metadata of the credentials and the instance info in the secret
+	secretMetadataKey = ".metadata"
+	// instanceInfoKeyPrefix prefixes the instance info conflicting with the credentials with the prefixOperator policy
+	instanceInfoKeyPrefix = "btp_"
+)
+
+type instanceInfo struct {
+	key    string
+	value  []byte
+	format format
+}
+
+// addInstanceInfo adds the info of the service instance to the credentials, the conflicts with the keys of the
+// credentials are resolved with the instance info conflict policy of the binding. It returns the metadata of the added
+// instance info and the conflicting keys.
+func (r *ServiceBindingReconciler) addInstanceInfo(ctx context.Context, binding *servicesv1.ServiceBinding, credentialsMap map[string][]byte) ([]SecretMetadataProperty, []string, error) {
+	instance, err := r.getServiceInstanceForBinding(ctx, binding)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	infos := []instanceInfo{
+		{key: "instance_name", value: getInstanceNameForSecretCredentials(instance), format: TEXT},
+		{key: "instance_guid", value: []byte(instance.Status.InstanceID), format: TEXT},
+		{key: "plan", value: []byte(instance.Spec.ServicePlanName), format: TEXT},
+		{key: "label", value: []byte(instance.Spec.ServiceOfferingName), format: TEXT},
+		{key: "type", value: []byte(instance.Spec.ServiceOfferingName), format: TEXT},
+	}
+	if len(instance.Status.Tags) > 0 || len(instance.Spec.CustomTags) > 0 {
+		tagsBytes, err := json.Marshal(mergeInstanceTags(instance.Status.Tags, instance.Spec.CustomTags))
+		if err != nil {
+			return nil, nil, err
+		}
+		infos = append(infos, instanceInfo{key: "tags", value: tagsBytes, format: JSON})
+	}
+
+	metadata := make([]SecretMetadataProperty, 0, len(infos))
+	var conflicts []string
+	for _, info := range infos {
+		key := info.key
+		if _, ok := credentialsMap[key]; ok {
+			conflicts = append(conflicts, key)
+			switch instanceInfoConflictPolicy(binding) {
+			case servicesv1.InstanceInfoPreferBroker:
+				continue
+			case servicesv1.InstanceInfoPrefixOperator:
+				key = instanceInfoKeyPrefix + key
+			}
+		}
+		credentialsMap[key] = info.value
+		metadata = append(metadata, SecretMetadataProperty{Name: key, Format: string(info.format)})
+	}
+	return metadata, conflicts, nil
+}
+
+func instanceInfoConflictPolicy(binding *servicesv1.ServiceBinding) servicesv1.InstanceInfoConflictPolicy {
+	if len(binding.Spec.InstanceInfoConflictPolicy) == 0 {
+		return servicesv1.InstanceInfoPreferOperator
+	}
+	return binding.Spec.InstanceInfoConflictPolicy
+}
+
+// setInstanceInfoConflictCondition reports the keys of the credentials returned by the broker which conflict with the
+// instance info or the reserved metadata key, the condition is removed when there are no conflicts
+func setInstanceInfoConflictCondition(binding *servicesv1.ServiceBinding, conflicts []string) {
+	if len(conflicts) == 0 {
+		meta.RemoveStatusCondition(&binding.Status.Conditions, api.ConditionInstanceInfoConflict)
+		return
+	}
+	meta.SetStatusCondition(&binding.Status.Conditions, metav1.Condition{
+		Type:   api.ConditionInstanceInfoConflict,
+		Status: metav1.ConditionTrue,
+		Reason: InstanceInfoKeyConflict,
+		Message: fmt.Sprintf("the credentials returned by the broker contain the reserved keys %s, resolved with the %s policy",
+			strings.Join(conflicts, ", "), instanceInfoConflictPolicy(binding)),
+		ObservedGeneration: binding.Generation,
+	})
+}
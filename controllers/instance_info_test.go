@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Instance info conflicts", func() {
+	var reconciler *ServiceBindingReconciler
+	var binding *servicesv1.ServiceBinding
+	var ctx context.Context
+	credentials := json.RawMessage(`{"plan": "broker-plan", "type": "postgres", "user": "my-user"}`)
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		instance := &servicesv1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-instance", Namespace: "app1"},
+			Spec:       servicesv1.ServiceInstanceSpec{ExternalName: "my-instance", ServiceOfferingName: "my-offering", ServicePlanName: "my-plan"},
+		}
+		reconciler = &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance).Build(),
+			Scheme:   testScheme,
+			Log:      ctrl.Log,
+			Recorder: record.NewFakeRecorder(10),
+		}}
+		binding = &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1", UID: "binding-uid"},
+			Spec:       servicesv1.ServiceBindingSpec{ServiceInstanceName: "my-instance", SecretName: "my-secret"},
+		}
+	})
+
+	It("should replace the conflicting credentials with the instance info by default", func() {
+		secret, err := reconciler.createBindingSecret(ctx, binding, credentials)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secret.Data).To(HaveKeyWithValue("plan", []byte("my-plan")))
+		Expect(secret.Data).To(HaveKeyWithValue("type", []byte("my-offering")))
+		condition := meta.FindStatusCondition(binding.Status.Conditions, api.ConditionInstanceInfoConflict)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Reason).To(Equal(InstanceInfoKeyConflict))
+		Expect(condition.Message).To(ContainSubstring("reserved keys plan, type, resolved with the preferOperator policy"))
+	})
+
+	It("should keep the conflicting credentials with the preferBroker policy", func() {
+		binding.Spec.InstanceInfoConflictPolicy = servicesv1.InstanceInfoPreferBroker
+		secret, err := reconciler.createBindingSecret(ctx, binding, credentials)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secret.Data).To(HaveKeyWithValue("plan", []byte("broker-plan")))
+		Expect(secret.Data).To(HaveKeyWithValue("label", []byte("my-offering")))
+		metadata := map[string][]SecretMetadataProperty{}
+		Expect(json.Unmarshal(secret.Data[secretMetadataKey], &metadata)).To(Succeed())
+		Expect(metadata["metaDataProperties"]).To(ContainElement(SecretMetadataProperty{Name: "label", Format: string(TEXT)}))
+		Expect(metadata["metaDataProperties"]).ToNot(ContainElement(SecretMetadataProperty{Name: "plan", Format: string(TEXT)}))
+	})
+
+	It("should store the conflicting instance info with a prefix with the prefixOperator policy", func() {
+		binding.Spec.InstanceInfoConflictPolicy = servicesv1.InstanceInfoPrefixOperator
+		binding.Spec.SecretFileFormat = servicesv1.SecretFileFormatJSON
+		secret, err := reconciler.createBindingSecret(ctx, binding, credentials)
+		Expect(err).ToNot(HaveOccurred())
+		values := map[string]interface{}{}
+		Expect(json.Unmarshal(secret.Data["credentials.json"], &values)).To(Succeed())
+		Expect(values).To(HaveKeyWithValue("plan", "broker-plan"))
+		Expect(values).To(HaveKeyWithValue("btp_plan", "my-plan"))
+		Expect(values).To(HaveKeyWithValue("btp_type", "my-offering"))
+		Expect(values).To(HaveKeyWithValue("instance_name", "my-instance"))
+		Expect(meta.IsStatusConditionTrue(binding.Status.Conditions, api.ConditionInstanceInfoConflict)).To(BeTrue())
+	})
+
+	It("should remove the condition once the credentials no longer conflict", func() {
+		_, err := reconciler.createBindingSecret(ctx, binding, credentials)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = reconciler.createBindingSecret(ctx, binding, json.RawMessage(`{"user": "my-user", ".metadata": "broker"}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(meta.FindStatusCondition(binding.Status.Conditions, api.ConditionInstanceInfoConflict).Message).To(ContainSubstring("reserved keys .metadata,"))
+
+		_, err = reconciler.createBindingSecret(ctx, binding, json.RawMessage(`{"user": "my-user"}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(meta.FindStatusCondition(binding.Status.Conditions, api.ConditionInstanceInfoConflict)).To(BeNil())
+	})
+})
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	"github.com/SAP/sap-btp-service-operator/client/sm/smfakes"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Periodic maintenance check", func() {
+	var (
+		reconciler *ServiceInstanceReconciler
+		smClient   *smfakes.FakeClient
+		instance   *servicesv1.ServiceInstance
+		testCtx    context.Context
+	)
+
+	getInstance := func() *servicesv1.ServiceInstance {
+		updated := &servicesv1.ServiceInstance{}
+		Expect(reconciler.Client.Get(testCtx, types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}, updated)).To(Succeed())
+		return updated
+	}
+
+	BeforeEach(func() {
+		testCtx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		instance = &servicesv1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "instance", Namespace: "apps"},
+			Status:     servicesv1.ServiceInstanceStatus{InstanceID: "instance-id", Ready: metav1.ConditionTrue},
+		}
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		smClient = &smfakes.FakeClient{}
+		reconciler = &ServiceInstanceReconciler{BaseReconciler: &BaseReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance).WithStatusSubresource(instance).Build(),
+			Scheme:   testScheme,
+			SMClient: func() sm.Client { return smClient },
+			Config:   config.Config{MaintenanceCheckInterval: time.Hour},
+		}}
+		instance = getInstance()
+		Expect(maintenanceCheckRequired(instance, time.Hour)).To(BeTrue())
+	})
+
+	It("should report an upgrade offered by the plan", func() {
+		smClient.GetInstanceByIDReturns(&smClientTypes.ServiceInstance{ID: "instance-id", ServicePlanID: "plan-id", MaintenanceInfo: []byte(`{"version": "1.0.0"}`)}, nil)
+		smClient.ListPlansReturns(&smClientTypes.ServicePlans{ServicePlans: []smClientTypes.ServicePlan{
+			{ID: "plan-id", MaintenanceInfo: []byte(`{"version": "2.0.0"}`)},
+		}}, nil)
+
+		_, err := reconciler.checkMaintenanceInfo(testCtx, instance)
+		Expect(err).ToNot(HaveOccurred())
+		updated := getInstance()
+		Expect(updated.Status.UpgradeAvailable).To(BeTrue())
+		Expect(updated.Status.AvailableMaintenanceVersion).To(Equal("2.0.0"))
+		Expect(maintenanceCheckRequired(updated, time.Hour)).To(BeFalse())
+	})
+
+	It("should record a failed check so it is not retried before the next interval", func() {
+		smClient.GetInstanceByIDReturns(nil, fmt.Errorf("sm unavailable"))
+
+		result, err := reconciler.checkMaintenanceInfo(testCtx, instance)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(time.Hour))
+		updated := getInstance()
+		Expect(updated.Status.LastMaintenanceCheck).ToNot(BeNil())
+		Expect(maintenanceCheckRequired(updated, time.Hour)).To(BeFalse())
+	})
+})
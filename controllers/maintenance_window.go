@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// OutsideMaintenanceWindow an update issued by the operator waits for the maintenance window of the instance
+const OutsideMaintenanceWindow = "OutsideMaintenanceWindow"
+
+// deferToMaintenanceWindow checks whether an update the operator issues on its own must wait for the maintenance window
+// of the instance. If so, the Deferred condition and the start of the next window are stored and the instance is requeued
+// when the window opens.
+func (r *ServiceInstanceReconciler) deferToMaintenanceWindow(ctx context.Context, serviceInstance *servicesv1.ServiceInstance, update string) (bool, ctrl.Result, error) {
+	log := GetLogger(ctx)
+	window := serviceInstance.Spec.MaintenanceWindow
+	if window == nil {
+		clearDeferral(serviceInstance)
+		return false, ctrl.Result{}, nil
+	}
+
+	open, next, err := window.Next(time.Now())
+	if err != nil {
+		// windows are validated on admission, an invalid window does not block the update
+		log.Info(fmt.Sprintf("ignoring the maintenance window of the instance: %s", err.Error()))
+		clearDeferral(serviceInstance)
+		return false, ctrl.Result{}, nil
+	}
+	if open {
+		clearDeferral(serviceInstance)
+		return false, ctrl.Result{}, nil
+	}
+
+	message := fmt.Sprintf("%s is deferred to the maintenance window starting at %s", update, next.UTC().Format(time.RFC3339))
+	log.Info(message)
+	meta.SetStatusCondition(&serviceInstance.Status.Conditions, metav1.Condition{
+		Type:               api.ConditionDeferred,
+		Status:             metav1.ConditionTrue,
+		Reason:             OutsideMaintenanceWindow,
+		Message:            message,
+		ObservedGeneration: serviceInstance.Generation,
+	})
+	serviceInstance.Status.NextMaintenanceWindow = &metav1.Time{Time: next}
+	return true, ctrl.Result{RequeueAfter: time.Until(next)}, r.updateStatus(ctx, serviceInstance)
+}
+
+func clearDeferral(serviceInstance *servicesv1.ServiceInstance) {
+	meta.RemoveStatusCondition(&serviceInstance.Status.Conditions, api.ConditionDeferred)
+	serviceInstance.Status.NextMaintenanceWindow = nil
+}
+
+// clearStaleDeferral removes the deferral once neither a maintenance update nor the revert of drift waits for the window
+func clearStaleDeferral(serviceInstance *servicesv1.ServiceInstance) {
+	if !maintenanceUpdateRequired(serviceInstance) && !meta.IsStatusConditionTrue(serviceInstance.Status.Conditions, api.ConditionDrifted) {
+		clearDeferral(serviceInstance)
+	}
+}
+
+// maintenanceWindowOpened checks whether the maintenance window an update of the instance was deferred to has opened
+func maintenanceWindowOpened(serviceInstance *servicesv1.ServiceInstance) bool {
+	next := serviceInstance.Status.NextMaintenanceWindow
+	return next != nil && !time.Now().Before(next.Time)
+}
+
+// deferredDriftRevertDue checks whether the revert of the drift of the instance was deferred and its maintenance window is open
+func deferredDriftRevertDue(serviceInstance *servicesv1.ServiceInstance) bool {
+	window := serviceInstance.Spec.MaintenanceWindow
+	if window == nil || serviceInstance.Spec.EnforcementMode != servicesv1.EnforcementModeEnforce ||
+		!meta.IsStatusConditionTrue(serviceInstance.Status.Conditions, api.ConditionDrifted) {
+		return false
+	}
+	open, _, err := window.Next(time.Now())
+	return err == nil && open
+}
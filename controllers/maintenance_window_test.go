@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Maintenance window", func() {
+	var instance *servicesv1.ServiceInstance
+
+	// alwaysOpen and neverOpen are windows that are open, respectively closed, at any time of the test
+	alwaysOpen := func() *servicesv1.MaintenanceWindow {
+		start := time.Now().UTC().Add(-time.Hour).Format("15:04")
+		return &servicesv1.MaintenanceWindow{Start: start, Duration: "3h"}
+	}
+	neverOpen := func() *servicesv1.MaintenanceWindow {
+		start := time.Now().UTC().Add(2 * time.Hour).Format("15:04")
+		return &servicesv1.MaintenanceWindow{Start: start, Duration: "1h"}
+	}
+
+	BeforeEach(func() {
+		instance = &servicesv1.ServiceInstance{
+			Spec: servicesv1.ServiceInstanceSpec{EnforcementMode: servicesv1.EnforcementModeEnforce},
+		}
+		meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{Type: api.ConditionDrifted, Status: metav1.ConditionTrue, Reason: "Drifted"})
+	})
+
+	Context("deferredDriftRevertDue", func() {
+		It("should be due when the window is open", func() {
+			instance.Spec.MaintenanceWindow = alwaysOpen()
+			Expect(deferredDriftRevertDue(instance)).To(BeTrue())
+		})
+
+		It("should not be due when the window is closed", func() {
+			instance.Spec.MaintenanceWindow = neverOpen()
+			Expect(deferredDriftRevertDue(instance)).To(BeFalse())
+		})
+
+		It("should not be due without a window", func() {
+			Expect(deferredDriftRevertDue(instance)).To(BeFalse())
+		})
+
+		It("should not be due when the instance did not drift", func() {
+			instance.Spec.MaintenanceWindow = alwaysOpen()
+			meta.RemoveStatusCondition(&instance.Status.Conditions, api.ConditionDrifted)
+			Expect(deferredDriftRevertDue(instance)).To(BeFalse())
+		})
+
+		It("should not be due when drift is only reported", func() {
+			instance.Spec.MaintenanceWindow = alwaysOpen()
+			instance.Spec.EnforcementMode = servicesv1.EnforcementModeWarn
+			Expect(deferredDriftRevertDue(instance)).To(BeFalse())
+		})
+	})
+
+	Context("maintenanceWindowOpened", func() {
+		It("should detect that the window the update was deferred to opened", func() {
+			instance.Status.NextMaintenanceWindow = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+			Expect(maintenanceWindowOpened(instance)).To(BeTrue())
+		})
+
+		It("should not open before its start", func() {
+			instance.Status.NextMaintenanceWindow = &metav1.Time{Time: time.Now().Add(time.Hour)}
+			Expect(maintenanceWindowOpened(instance)).To(BeFalse())
+		})
+
+		It("should not open when no update was deferred", func() {
+			Expect(maintenanceWindowOpened(instance)).To(BeFalse())
+		})
+	})
+
+	Context("clearStaleDeferral", func() {
+		BeforeEach(func() {
+			meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{Type: api.ConditionDeferred, Status: metav1.ConditionTrue, Reason: OutsideMaintenanceWindow})
+			instance.Status.NextMaintenanceWindow = &metav1.Time{Time: time.Now().Add(time.Hour)}
+		})
+
+		It("should keep the deferral while the drift waits for the window", func() {
+			clearStaleDeferral(instance)
+			Expect(meta.FindStatusCondition(instance.Status.Conditions, api.ConditionDeferred)).ToNot(BeNil())
+			Expect(instance.Status.NextMaintenanceWindow).ToNot(BeNil())
+		})
+
+		It("should clear the deferral once nothing waits for the window", func() {
+			meta.RemoveStatusCondition(&instance.Status.Conditions, api.ConditionDrifted)
+			clearStaleDeferral(instance)
+			Expect(meta.FindStatusCondition(instance.Status.Conditions, api.ConditionDeferred)).To(BeNil())
+			Expect(instance.Status.NextMaintenanceWindow).To(BeNil())
+		})
+	})
+})
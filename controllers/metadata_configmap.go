@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const metadataConfigMapNameTakenErrorFormat = "the specified metadata config map name '%s' is already generated by the secret template. Choose another name and try again"
+
+// endpointCredentials are the credentials holding the endpoints of a service, they are added to the metadata config map
+// without the values that may be sensitive
+var endpointCredentials = []string{"endpoints", "url", "uri"}
+
+// sensitiveNameParts are the parts of the names of query parameters and keys whose values are credentials, e.g. the
+// sig of a SAS URL or the apikey of a service
+var sensitiveNameParts = []string{"token", "secret", "password", "passwd", "pwd", "key", "sig", "credential", "auth", "code", "session"}
+
+// bindingMetadataConfigMap returns the config map with the non-sensitive metadata of the binding requested by
+// spec.metadataConfigMap, or nil if none is requested. It is stored with the config maps of the secret template.
+func (r *ServiceBindingReconciler) bindingMetadataConfigMap(ctx context.Context, binding *servicesv1.ServiceBinding, credentials json.RawMessage, configMaps []*corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	if len(binding.Spec.MetadataConfigMap) == 0 {
+		return nil, nil
+	}
+	key := r.bindingObjectKey(binding, binding.Spec.MetadataConfigMap)
+	for _, configMap := range configMaps {
+		if configMap.Name == key.Name {
+			return nil, fmt.Errorf(metadataConfigMapNameTakenErrorFormat, binding.Spec.MetadataConfigMap)
+		}
+	}
+
+	instanceInfos := make(map[string][]byte)
+	if _, _, err := r.addInstanceInfo(ctx, binding, instanceInfos); err != nil {
+		return nil, errors.Wrap(err, "failed to add service instance info")
+	}
+	data := make(map[string]string, len(instanceInfos)+len(endpointCredentials))
+	for k, v := range instanceInfos {
+		data[k] = string(v)
+	}
+	endpoints, err := credentialEndpoints(credentials)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range endpoints {
+		data[k] = v
+	}
+
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        key.Name,
+			Namespace:   key.Namespace,
+			Annotations: map[string]string{"binding": binding.Name},
+		},
+		Data: data,
+	}, nil
+}
+
+// credentialEndpoints returns the endpoint credentials, objects are stored as JSON. Only URLs are kept, nested values
+// included, and URLs with user info or credentials in their query are skipped.
+func credentialEndpoints(credentials json.RawMessage) (map[string]string, error) {
+	endpoints := make(map[string]string)
+	if len(credentials) == 0 {
+		return endpoints, nil
+	}
+	credentialsMap := make(map[string]interface{})
+	if err := json.Unmarshal(credentials, &credentialsMap); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal given service binding credentials")
+	}
+	for _, key := range endpointCredentials {
+		value, ok := nonSensitiveEndpoints(credentialsMap[key])
+		if !ok {
+			continue
+		}
+		if endpoint, ok := value.(string); ok {
+			endpoints[key] = endpoint
+			continue
+		}
+		endpoint, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		endpoints[key] = string(endpoint)
+	}
+	return endpoints, nil
+}
+
+// nonSensitiveEndpoints returns the value without the strings which are not safe URLs and without the keys with
+// sensitive names, numbers and booleans are kept. Returns false if nothing remains.
+func nonSensitiveEndpoints(value interface{}) (interface{}, bool) {
+	switch value := value.(type) {
+	case string:
+		return value, isSafeURL(value)
+	case float64, bool:
+		return value, true
+	case map[string]interface{}:
+		kept := make(map[string]interface{})
+		for k, v := range value {
+			if isSensitiveName(k) {
+				continue
+			}
+			if v, ok := nonSensitiveEndpoints(v); ok {
+				kept[k] = v
+			}
+		}
+		return kept, len(kept) > 0
+	case []interface{}:
+		var kept []interface{}
+		for _, v := range value {
+			if v, ok := nonSensitiveEndpoints(v); ok {
+				kept = append(kept, v)
+			}
+		}
+		return kept, len(kept) > 0
+	default:
+		return nil, false
+	}
+}
+
+// isSafeURL checks whether the value is an absolute URL without user info and without query parameters or fragment
+// parameters with sensitive names
+func isSafeURL(value string) bool {
+	parsed, err := url.Parse(value)
+	if err != nil || len(parsed.Scheme) == 0 || len(parsed.Host) == 0 || parsed.User != nil {
+		return false
+	}
+	for _, raw := range []string{parsed.RawQuery, parsed.Fragment} {
+		params, err := url.ParseQuery(raw)
+		if err != nil {
+			return false
+		}
+		for name := range params {
+			if isSensitiveName(name) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isSensitiveName(name string) bool {
+	name = strings.ToLower(name)
+	for _, part := range sensitiveNameParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
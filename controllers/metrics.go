@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var suppressedDescriptionUpdates = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "sap_btp_operator_suppressed_description_updates_total",
+	Help: "Number of status updates skipped while polling an operation because only its description changed",
+}, []string{"controller"})
+
+var startupResources = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "sap_btp_operator_startup_resources",
+	Help: "Number of resources found on startup when the staged startup is enabled",
+}, []string{"controller"})
+
+var startupDeferredReconciles = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "sap_btp_operator_startup_deferred_reconciles_total",
+	Help: "Number of reconciles of resources in a final state deferred during the startup window",
+}, []string{"controller"})
+
+var startupConvergenceSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "sap_btp_operator_startup_convergence_seconds",
+	Help: "Time from startup until every resource found on startup was reconciled once",
+}, []string{"controller"})
+
+var reconcileRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "sap_btp_operator_reconcile_retries_total",
+	Help: "Number of reconciles retried with a backoff after an error or an explicit requeue",
+}, []string{"controller"})
+
+var secretWriteConflicts = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "sap_btp_operator_secret_write_conflicts_total",
+	Help: "Number of binding secret writes retried because the secret was written concurrently by another actor",
+})
+
+var secretWritesSkipped = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "sap_btp_operator_secret_writes_skipped_total",
+	Help: "Number of binding secret updates skipped because the secret already had the desired data, labels and annotations",
+})
+
+var smFeatureSupported = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "sap_btp_operator_sm_feature_supported",
+	Help: "Whether an optional feature is supported by the Service Manager of a tenant, as detected by the operator",
+}, []string{"sm_url", "api_version", "feature"})
+
+var instanceExpirationTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "sap_btp_operator_instance_expiration_timestamp_seconds",
+	Help: "Time at which a service instance with spec.expiresAt is deleted, in seconds since the epoch",
+}, []string{"namespace", "name"})
+
+var featureGateEnabled = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "sap_btp_operator_feature_gate_enabled",
+	Help: "Whether a feature gate of the operator is enabled (1) or disabled (0)",
+}, []string{"feature", "stage"})
+
+var smCallsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "sap_btp_operator_sm_calls_total",
+	Help: "Number of calls to Service Manager by access credentials secret",
+}, []string{"credentials"})
+
+var smCallsInWindow = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "sap_btp_operator_sm_calls_in_window",
+	Help: "Number of calls to Service Manager in the quota window by access credentials secret",
+}, []string{"credentials"})
+
+var smBackpressureDelays = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "sap_btp_operator_sm_backpressure_delays_total",
+	Help: "Number of requeues delayed because the access credentials secret is close to its Service Manager quota",
+}, []string{"credentials"})
+
+var smCallTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "sap_btp_operator_sm_call_timeouts_total",
+	Help: "Number of calls to Service Manager cancelled because they exceeded the timeout of their budget (catalog, provision, poll, bind, default)",
+}, []string{"budget"})
+
+var credentialsEscrows = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "sap_btp_operator_credentials_escrows_total",
+	Help: "Number of attempts to store the credentials of rotated bindings in the escrow before their deletion by result (stored, failed)",
+}, []string{"result"})
+
+var unbindFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "sap_btp_operator_unbind_failures_total",
+	Help: "Number of failed async unbind operations by the error type of the operation",
+}, []string{"class"})
+
+var operationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "sap_btp_operator_operations_total",
+	Help: "Number of completed operations by operation type (provision, update, bind, rotate, delete) and result",
+}, []string{"operation", "result"})
+
+var operationTimeToReadySeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "sap_btp_operator_operation_time_to_ready_seconds",
+	Help:    "Time from the request of a succeeded operation until the resource was ready, or deleted",
+	Buckets: []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800, 3600},
+}, []string{"operation"})
+
+var sloSuccessRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "sap_btp_operator_slo_success_ratio",
+	Help: "Share of succeeded operations in the window of the SLO report by operation type",
+}, []string{"operation"})
+
+var sloErrorBudgetRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "sap_btp_operator_slo_error_budget_remaining_ratio",
+	Help: "Share of the error budget of the SLO report left by operation type, negative when the objective is missed",
+}, []string{"operation"})
+
+var certificateExpirySeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "btp_binding_certificate_expiry_seconds",
+	Help: "Time left until the certificate expiring first in the secrets of a binding expires, negative when it is expired",
+}, []string{"namespace", "name"})
+
+func init() {
+	metrics.Registry.MustRegister(suppressedDescriptionUpdates, startupResources, startupDeferredReconciles, startupConvergenceSeconds, reconcileRetries, secretWriteConflicts, secretWritesSkipped, smFeatureSupported, instanceExpirationTimestamp, featureGateEnabled, smCallsTotal, smCallsInWindow, smBackpressureDelays, smCallTimeouts, unbindFailures,
+		credentialsEscrows, operationsTotal, operationTimeToReadySeconds, sloSuccessRatio, sloErrorBudgetRemaining,
+		certificateExpirySeconds)
+}
+
+// RecordFeatureGates exposes the state of every feature gate as a metric
+func RecordFeatureGates(gates config.FeatureGates) {
+	for feature, spec := range config.KnownFeatures() {
+		enabled := 0.0
+		if gates.Enabled(feature) {
+			enabled = 1
+		}
+		featureGateEnabled.WithLabelValues(string(feature), string(spec.Stage)).Set(enabled)
+	}
+}
@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// namespaceStatusWindow is the period of the counts of the NamespaceBTPStatus
+	namespaceStatusWindow = time.Hour
+	// maxRecentFailures is the number of failed operations listed in the NamespaceBTPStatus
+	maxRecentFailures = 10
+)
+
+// namespaceSMCalls counts the calls to SM of both controllers by the namespace of the resource that made them
+var namespaceSMCalls = newNamespaceSMCallTracker()
+
+// namespaceSMCallTracker keeps the times of the calls to SM of the last hour and the credentials they were made with
+// by namespace, the calls are lost on restart and each replica only tracks the calls it made
+type namespaceSMCallTracker struct {
+	mutex sync.Mutex
+	// calls holds the times of the calls, oldest first, by namespace
+	calls map[string][]time.Time
+	// credentials holds the time of the last call by credentials by namespace
+	credentials map[string]map[string]time.Time
+}
+
+func newNamespaceSMCallTracker() *namespaceSMCallTracker {
+	return &namespaceSMCallTracker{calls: make(map[string][]time.Time), credentials: make(map[string]map[string]time.Time)}
+}
+
+// record counts a call made for a resource of the namespace with the credentials at the given time
+func (t *namespaceSMCallTracker) record(namespace, credentials string, now time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.pruneLocked(namespace, now)
+	t.calls[namespace] = append(t.calls[namespace], now)
+	if t.credentials[namespace] == nil {
+		t.credentials[namespace] = make(map[string]time.Time)
+	}
+	t.credentials[namespace][credentials] = now
+}
+
+// usage returns the number of calls of the namespace in the window and the credentials they were made with, sorted
+func (t *namespaceSMCallTracker) usage(namespace string, now time.Time) (int, []string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.pruneLocked(namespace, now)
+	credentials := make([]string, 0, len(t.credentials[namespace]))
+	for name := range t.credentials[namespace] {
+		credentials = append(credentials, name)
+	}
+	sort.Strings(credentials)
+	return len(t.calls[namespace]), credentials
+}
+
+// namespaces returns the namespaces with calls in the window
+func (t *namespaceSMCallTracker) namespaces(now time.Time) []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	var namespaces []string
+	for namespace := range t.calls {
+		if t.pruneLocked(namespace, now) > 0 {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// pruneLocked drops the calls and credentials of the namespace that left the window and returns the remaining calls
+func (t *namespaceSMCallTracker) pruneLocked(namespace string, now time.Time) int {
+	calls := t.calls[namespace]
+	i := 0
+	for i < len(calls) && !calls[i].After(now.Add(-namespaceStatusWindow)) {
+		i++
+	}
+	calls = calls[i:]
+	for credentials, lastCall := range t.credentials[namespace] {
+		if !lastCall.After(now.Add(-namespaceStatusWindow)) {
+			delete(t.credentials[namespace], credentials)
+		}
+	}
+	if len(calls) == 0 {
+		delete(t.calls, namespace)
+		delete(t.credentials, namespace)
+		return 0
+	}
+	t.calls[namespace] = calls
+	return len(calls)
+}
+
+// +kubebuilder:rbac:groups=services.cloud.sap.com,resources=namespacebtpstatuses,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=services.cloud.sap.com,resources=namespacebtpstatuses/status,verbs=get;update;patch
+
+// publishNamespaceStatuses publishes the in-flight operations, the calls to SM, the failures and the throttling of the
+// last hour of each namespace into the NamespaceBTPStatus named sap-btp-operator of the namespace. The status is
+// created in the namespaces with instances, bindings or activity, and deleted once they have none.
+func (r *PeriodicReporter) publishNamespaceStatuses(ctx context.Context, snapshot *reportSnapshot) error {
+	statuses := make(map[string]*servicesv1.NamespaceBTPStatusStatus)
+	statusOf := func(namespace string) *servicesv1.NamespaceBTPStatusStatus {
+		if statuses[namespace] == nil {
+			statuses[namespace] = &servicesv1.NamespaceBTPStatusStatus{}
+		}
+		return statuses[namespace]
+	}
+
+	for i := range snapshot.instances {
+		status := statusOf(snapshot.instances[i].Namespace)
+		if len(snapshot.instances[i].Status.OperationURL) > 0 {
+			status.InFlightOperations++
+		}
+	}
+	for i := range snapshot.bindings {
+		status := statusOf(snapshot.bindings[i].Namespace)
+		if len(snapshot.bindings[i].Status.OperationURL) > 0 {
+			status.InFlightOperations++
+		}
+	}
+
+	// the outcomes are oldest first, the failures are listed most recent first
+	outcomes := snapshot.outcomesWithin(namespaceStatusWindow)
+	for i := len(outcomes) - 1; i >= 0; i-- {
+		outcome := outcomes[i]
+		if len(outcome.namespace) == 0 {
+			continue
+		}
+		status := statusOf(outcome.namespace)
+		status.OperationsLastHour++
+		if outcome.succeeded {
+			continue
+		}
+		status.FailuresLastHour++
+		if len(status.RecentFailures) < maxRecentFailures {
+			status.RecentFailures = append(status.RecentFailures, servicesv1.NamespaceOperationFailure{
+				Kind:      string(outcome.kind),
+				Name:      outcome.name,
+				Operation: outcome.operation,
+				Message:   outcome.message,
+				// the time is stored with a precision of seconds, truncating it keeps unchanged statuses equal
+				Time: metav1.NewTime(outcome.at.UTC().Truncate(time.Second)),
+			})
+		}
+	}
+
+	for _, namespace := range namespaceSMCalls.namespaces(snapshot.now) {
+		statusOf(namespace)
+	}
+	for namespace, status := range statuses {
+		var credentials []string
+		status.SMCallsLastHour, credentials = namespaceSMCalls.usage(namespace, snapshot.now)
+		for _, name := range credentials {
+			if smCalls.delay(name, snapshot.now, r.Config.SMCallQuotaWindow, r.Config.SMCallQuota) > 0 {
+				status.ThrottledCredentials = append(status.ThrottledCredentials, name)
+			}
+		}
+		status.Throttled = len(status.ThrottledCredentials) > 0
+		if err := r.publishNamespaceStatus(ctx, namespace, *status); err != nil {
+			return err
+		}
+	}
+
+	existing := &servicesv1.NamespaceBTPStatusList{}
+	if err := r.Client.List(ctx, existing); err != nil {
+		return err
+	}
+	for i := range existing.Items {
+		namespaceStatus := &existing.Items[i]
+		if _, active := statuses[namespaceStatus.Namespace]; active || namespaceStatus.Name != servicesv1.NamespaceBTPStatusName {
+			continue
+		}
+		GetLogger(ctx).Info("deleting the status of the namespace, it has no instances, bindings or activity", "namespace", namespaceStatus.Namespace)
+		if err := r.Client.Delete(ctx, namespaceStatus); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishNamespaceStatus creates the NamespaceBTPStatus of the namespace if it is missing and updates its status if it
+// changed
+func (r *PeriodicReporter) publishNamespaceStatus(ctx context.Context, namespace string, status servicesv1.NamespaceBTPStatusStatus) error {
+	namespaceStatus := &servicesv1.NamespaceBTPStatus{}
+	key := types.NamespacedName{Name: servicesv1.NamespaceBTPStatusName, Namespace: namespace}
+	if err := r.Client.Get(ctx, key, namespaceStatus); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		namespaceStatus = &servicesv1.NamespaceBTPStatus{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+		if err := r.Client.Create(ctx, namespaceStatus); err != nil {
+			return err
+		}
+	}
+
+	status.LastUpdated = namespaceStatus.Status.LastUpdated
+	if !namespaceStatus.Status.LastUpdated.IsZero() && equality.Semantic.DeepEqual(namespaceStatus.Status, status) {
+		return nil
+	}
+	status.LastUpdated = metav1.Now()
+	namespaceStatus.Status = status
+	return r.Client.Status().Update(ctx, namespaceStatus)
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Namespace status", func() {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	Context("SM calls", func() {
+		It("should count the calls and credentials of the last hour by namespace", func() {
+			tracker := newNamespaceSMCallTracker()
+			tracker.record("app1", "app1/sap-btp-service-operator", now.Add(-2*time.Hour))
+			tracker.record("app1", "sap-btp-operator/sap-btp-service-operator", now.Add(-time.Minute))
+			tracker.record("app1", "app1/sap-btp-service-operator", now)
+			tracker.record("app2", "app2/sap-btp-service-operator", now.Add(-90*time.Minute))
+
+			calls, credentials := tracker.usage("app1", now)
+			Expect(calls).To(Equal(2))
+			Expect(credentials).To(Equal([]string{"app1/sap-btp-service-operator", "sap-btp-operator/sap-btp-service-operator"}))
+			Expect(tracker.namespaces(now)).To(ConsistOf("app1"))
+
+			calls, credentials = tracker.usage("app1", now.Add(time.Hour))
+			Expect(calls).To(BeZero())
+			Expect(credentials).To(BeEmpty())
+		})
+	})
+
+	Context("reporter", func() {
+		var (
+			ctx                   context.Context
+			reporter              *PeriodicReporter
+			instance              *servicesv1.ServiceInstance
+			failedBinding         *servicesv1.ServiceBinding
+			savedOutcomes         *operationOutcomeTracker
+			savedNamespaceSMCalls *namespaceSMCallTracker
+			savedSMCalls          *smCallTracker
+		)
+		getStatus := func(namespace string) (*servicesv1.NamespaceBTPStatus, error) {
+			status := &servicesv1.NamespaceBTPStatus{}
+			err := reporter.Client.Get(ctx, types.NamespacedName{Name: servicesv1.NamespaceBTPStatusName, Namespace: namespace}, status)
+			return status, err
+		}
+
+		BeforeEach(func() {
+			ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+			savedOutcomes, savedNamespaceSMCalls, savedSMCalls = operationOutcomes, namespaceSMCalls, smCalls
+			operationOutcomes = &operationOutcomeTracker{retention: time.Hour}
+			namespaceSMCalls = newNamespaceSMCallTracker()
+			smCalls = newSMCallTracker()
+
+			testScheme := runtime.NewScheme()
+			Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+			Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+			instance = &servicesv1.ServiceInstance{ObjectMeta: metav1.ObjectMeta{Name: "my-instance", Namespace: "app1"}}
+			instance.Status.OperationURL = "/v1/service_instances/1234/operations/5678"
+			failedBinding = &servicesv1.ServiceBinding{ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1"}}
+			reporter = &PeriodicReporter{
+				BaseReconciler: &BaseReconciler{
+					Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance, failedBinding).
+						WithStatusSubresource(&servicesv1.NamespaceBTPStatus{}).Build(),
+					Scheme: testScheme,
+					Log:    ctrl.Log,
+					Config: config.Config{SMCallQuota: 5, SMCallQuotaWindow: time.Minute},
+				},
+				NamespaceStatusInterval: time.Minute,
+			}
+		})
+
+		AfterEach(func() {
+			operationOutcomes, namespaceSMCalls, smCalls = savedOutcomes, savedNamespaceSMCalls, savedSMCalls
+		})
+
+		It("should publish the operations, calls and throttling of each namespace", func() {
+			operationOutcomes.record(outcomeOf(sloProvision, instance, true, time.Minute, ""))
+			operationOutcomes.record(outcomeOf(sloBind, failedBinding, false, 0, "no access"))
+			operationOutcomes.record(outcomeOf(sloRotate, failedBinding, false, 0, "quota exceeded"))
+			onRequest := reporter.countSMCalls(types.NamespacedName{Namespace: "app1", Name: "my-binding"}, "app1/sap-btp-service-operator")
+			for i := 0; i < 4; i++ {
+				onRequest()
+			}
+			reporter.countSMCalls(types.NamespacedName{Namespace: "app2", Name: "other-binding"}, "app2/sap-btp-service-operator")()
+
+			Expect(reporter.Report(ctx)).To(Succeed())
+
+			status, err := getStatus("app1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Status.InFlightOperations).To(Equal(1))
+			Expect(status.Status.SMCallsLastHour).To(Equal(4))
+			Expect(status.Status.OperationsLastHour).To(Equal(3))
+			Expect(status.Status.FailuresLastHour).To(Equal(2))
+			Expect(status.Status.RecentFailures).To(HaveLen(2))
+			Expect(status.Status.RecentFailures[0].Kind).To(Equal(string(api.ServiceBindingController)))
+			Expect(status.Status.RecentFailures[0].Name).To(Equal("my-binding"))
+			Expect(status.Status.RecentFailures[0].Operation).To(Equal(sloRotate))
+			Expect(status.Status.RecentFailures[0].Message).To(Equal("quota exceeded"))
+			Expect(status.Status.RecentFailures[1].Operation).To(Equal(sloBind))
+			Expect(status.Status.Throttled).To(BeTrue())
+			Expect(status.Status.ThrottledCredentials).To(ConsistOf("app1/sap-btp-service-operator"))
+			Expect(status.Status.LastUpdated.IsZero()).To(BeFalse())
+
+			status, err = getStatus("app2")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Status.SMCallsLastHour).To(Equal(1))
+			Expect(status.Status.InFlightOperations).To(BeZero())
+			Expect(status.Status.Throttled).To(BeFalse())
+		})
+
+		It("should not update an unchanged status", func() {
+			operationOutcomes.record(outcomeOf(sloBind, failedBinding, false, 0, "no access"))
+			Expect(reporter.Report(ctx)).To(Succeed())
+			status, err := getStatus("app1")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(reporter.Report(ctx)).To(Succeed())
+			unchanged, err := getStatus("app1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unchanged.ResourceVersion).To(Equal(status.ResourceVersion))
+		})
+
+		It("should delete the status of namespaces without resources or activity", func() {
+			reporter.countSMCalls(types.NamespacedName{Namespace: "app2", Name: "other-binding"}, "app2/sap-btp-service-operator")()
+			Expect(reporter.Report(ctx)).To(Succeed())
+			_, err := getStatus("app2")
+			Expect(err).ToNot(HaveOccurred())
+
+			namespaceSMCalls = newNamespaceSMCallTracker()
+			Expect(reporter.Report(ctx)).To(Succeed())
+			_, err = getStatus("app2")
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			_, err = getStatus("app1")
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,345 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// OperationStoreStatus keeps the ongoing operations of the resources in their status
+	OperationStoreStatus = "status"
+	// OperationStoreConfigMap keeps the ongoing operations of the resources of a namespace in a config map
+	OperationStoreConfigMap = "configmap"
+
+	// operationsConfigMapName is the config map of a namespace holding the ongoing operations of its resources with the
+	// configmap operation store
+	operationsConfigMapName = "sap-btp-operator-operations"
+)
+
+// ValidateOperationStore rejects unknown operation stores on startup
+func ValidateOperationStore(store string) error {
+	if store != OperationStoreStatus && store != OperationStoreConfigMap {
+		return fmt.Errorf("invalid operation store '%s', expected %s or %s", store, OperationStoreStatus, OperationStoreConfigMap)
+	}
+	return nil
+}
+
+// operationStore keeps the bookkeeping of the SM operations of resources while they are polled: the URL and the type of
+// the ongoing operation and its description. The reconcilers work with the operation in the status of the resources,
+// the store loads it after a resource was read, records it when it starts and finishes, and takes it out of the status
+// before the status is written. Starting and finishing an operation does not write the status, the reconcilers write
+// it with the conditions of the operation.
+type operationStore interface {
+	// load sets the ongoing operation kept for the resource in its status
+	load(ctx context.Context, object api.SAPBTPResource) error
+	// start sets the ongoing operation of the resource and keeps it
+	start(ctx context.Context, object api.SAPBTPResource, url string, operationType smClientTypes.OperationCategory) error
+	// finish clears the ongoing operation of the resource and drops it
+	finish(ctx context.Context, object api.SAPBTPResource) error
+	// detach takes what the store keeps out of the status of the resource, which is about to be written. The returned
+	// function puts it back once the status is written.
+	detach(object api.SAPBTPResource) func()
+	// describe keeps the description of the ongoing operation of the resource, false if the store keeps it in the
+	// conditions of the status
+	describe(ctx context.Context, object api.SAPBTPResource, description string) (bool, error)
+	// forget drops the operation of a resource which is removed from the cluster
+	forget(ctx context.Context, object api.SAPBTPResource) error
+}
+
+// operations returns the operation store configured with OperationStore
+func (r *BaseReconciler) operations() operationStore {
+	r.operationStoreOnce.Do(func() {
+		if r.Config.OperationStore == OperationStoreConfigMap {
+			r.operationStore = newConfigMapOperationStore(r.Client, r.apiReader())
+		} else {
+			r.operationStore = statusOperationStore{}
+		}
+	})
+	return r.operationStore
+}
+
+// statusOperationStore keeps the operations in the status, every change of an operation and its description is a
+// write of the status
+type statusOperationStore struct{}
+
+func (statusOperationStore) load(context.Context, api.SAPBTPResource) error {
+	return nil
+}
+
+func (statusOperationStore) start(_ context.Context, object api.SAPBTPResource, url string, operationType smClientTypes.OperationCategory) error {
+	setOperation(object, url, operationType)
+	return nil
+}
+
+func (statusOperationStore) finish(_ context.Context, object api.SAPBTPResource) error {
+	setOperation(object, "", "")
+	return nil
+}
+
+func (statusOperationStore) detach(api.SAPBTPResource) func() {
+	return func() {}
+}
+
+func (statusOperationStore) describe(context.Context, api.SAPBTPResource, string) (bool, error) {
+	return false, nil
+}
+
+func (statusOperationStore) forget(context.Context, api.SAPBTPResource) error {
+	return nil
+}
+
+// storedOperation is an ongoing operation in the operations config map
+type storedOperation struct {
+	URL         string                          `json:"url"`
+	Type        smClientTypes.OperationCategory `json:"type"`
+	Description string                          `json:"description,omitempty"`
+}
+
+// configMapOperationStore keeps the operations of the resources of a namespace in a single config map, so that
+// describing operations does not write the status of thousands of resources during a mass provisioning and does not
+// notify everyone watching them. The status shows the conditions only, the operations of a namespace are listed in its
+// sap-btp-operator-operations config map.
+type configMapOperationStore struct {
+	client client.Client
+	reader client.Reader
+
+	// namespaces holds the *namespaceOperations of the config maps read or written last, by namespace
+	namespaces sync.Map
+}
+
+// namespaceOperations are the cached operations of the config map of a namespace, the lock is never held over API
+// calls, concurrent writes of the config map are resolved with its resource version
+type namespaceOperations struct {
+	mutex sync.Mutex
+	// operations is nil until the config map is read, and after a failed write
+	operations map[string]storedOperation
+	// writes counts the writes of the config map, a config map read during a write is not cached
+	writes int
+}
+
+func newConfigMapOperationStore(client client.Client, reader client.Reader) *configMapOperationStore {
+	return &configMapOperationStore{client: client, reader: reader}
+}
+
+func (s *configMapOperationStore) load(ctx context.Context, object api.SAPBTPResource) error {
+	key := operationKey(object)
+	if url, operationType := getOperation(object); len(url) > 0 {
+		// written to the status before the store was changed, it is kept in the config map before it is taken out of
+		// the status
+		if _, found, err := s.get(ctx, object.GetNamespace(), key); err != nil || found {
+			return err
+		}
+		return s.update(ctx, object.GetNamespace(), func(operations map[string]storedOperation) {
+			if _, found := operations[key]; !found {
+				operations[key] = storedOperation{URL: url, Type: operationType}
+			}
+		})
+	}
+	operation, found, err := s.get(ctx, object.GetNamespace(), key)
+	if err != nil {
+		return err
+	}
+	if found {
+		setOperation(object, operation.URL, operation.Type)
+	}
+	return nil
+}
+
+func (s *configMapOperationStore) start(ctx context.Context, object api.SAPBTPResource, url string, operationType smClientTypes.OperationCategory) error {
+	key := operationKey(object)
+	if err := s.update(ctx, object.GetNamespace(), func(operations map[string]storedOperation) {
+		operations[key] = storedOperation{URL: url, Type: operationType}
+	}); err != nil {
+		return err
+	}
+	setOperation(object, url, operationType)
+	return nil
+}
+
+func (s *configMapOperationStore) finish(ctx context.Context, object api.SAPBTPResource) error {
+	if err := s.forget(ctx, object); err != nil {
+		return err
+	}
+	setOperation(object, "", "")
+	return nil
+}
+
+func (s *configMapOperationStore) detach(object api.SAPBTPResource) func() {
+	url, operationType := getOperation(object)
+	setOperation(object, "", "")
+	return func() { setOperation(object, url, operationType) }
+}
+
+func (s *configMapOperationStore) describe(ctx context.Context, object api.SAPBTPResource, description string) (bool, error) {
+	key := operationKey(object)
+	stored, found, err := s.get(ctx, object.GetNamespace(), key)
+	if err != nil {
+		return true, err
+	}
+	if !found || stored.Description == description {
+		return true, nil
+	}
+	return true, s.update(ctx, object.GetNamespace(), func(operations map[string]storedOperation) {
+		if operation, found := operations[key]; found {
+			operation.Description = description
+			operations[key] = operation
+		}
+	})
+}
+
+func (s *configMapOperationStore) forget(ctx context.Context, object api.SAPBTPResource) error {
+	key := operationKey(object)
+	if _, found, err := s.get(ctx, object.GetNamespace(), key); err != nil || !found {
+		return err
+	}
+	return s.update(ctx, object.GetNamespace(), func(operations map[string]storedOperation) {
+		delete(operations, key)
+	})
+}
+
+// cached returns the cached operations of the namespace
+func (s *configMapOperationStore) cached(namespace string) *namespaceOperations {
+	cached, _ := s.namespaces.LoadOrStore(namespace, &namespaceOperations{})
+	return cached.(*namespaceOperations)
+}
+
+// get returns the operation of the key in the config map of the namespace, the config map is read if it is not cached
+func (s *configMapOperationStore) get(ctx context.Context, namespace, key string) (storedOperation, bool, error) {
+	cached := s.cached(namespace)
+	cached.mutex.Lock()
+	operations, writes := cached.operations, cached.writes
+	cached.mutex.Unlock()
+	if operations == nil {
+		configMap := &corev1.ConfigMap{}
+		if err := s.reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: operationsConfigMapName}, configMap); client.IgnoreNotFound(err) != nil {
+			return storedOperation{}, false, err
+		}
+		var err error
+		if operations, err = decodeOperations(configMap); err != nil {
+			return storedOperation{}, false, err
+		}
+		cached.mutex.Lock()
+		if cached.operations == nil && cached.writes == writes {
+			cached.operations = operations
+		}
+		cached.mutex.Unlock()
+	}
+	operation, found := operations[key]
+	return operation, found, nil
+}
+
+// update changes the operations in the config map of the namespace as it is read from the API server, the config map
+// is deleted with its last operation. The change is applied to the cache once it is written, the cache is invalidated
+// if the write failed.
+func (s *configMapOperationStore) update(ctx context.Context, namespace string, change func(operations map[string]storedOperation)) error {
+	cached := s.cached(namespace)
+	cached.mutex.Lock()
+	cached.writes++
+	cached.mutex.Unlock()
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap := &corev1.ConfigMap{}
+		err := s.reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: operationsConfigMapName}, configMap)
+		if client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		exists := err == nil
+		operations, err := decodeOperations(configMap)
+		if err != nil {
+			return err
+		}
+		change(operations)
+		if configMap.Data, err = encodeOperations(operations); err != nil {
+			return err
+		}
+
+		switch {
+		case !exists && len(operations) == 0:
+		case !exists:
+			configMap.ObjectMeta = metav1.ObjectMeta{Name: operationsConfigMapName, Namespace: namespace,
+				Labels: map[string]string{"app.kubernetes.io/managed-by": "sap-btp-operator"}}
+			err = s.client.Create(ctx, configMap)
+		case len(operations) == 0:
+			err = client.IgnoreNotFound(s.client.Delete(ctx, configMap, client.Preconditions{ResourceVersion: &configMap.ResourceVersion}))
+		default:
+			err = s.client.Update(ctx, configMap)
+		}
+		if apierrors.IsAlreadyExists(err) {
+			return apierrors.NewConflict(corev1.Resource("configmaps"), operationsConfigMapName, err)
+		}
+		return err
+	})
+	cached.mutex.Lock()
+	defer cached.mutex.Unlock()
+	if err != nil {
+		cached.operations = nil
+		return err
+	}
+	if cached.operations != nil {
+		change(cached.operations)
+	}
+	return nil
+}
+
+func decodeOperations(configMap *corev1.ConfigMap) (map[string]storedOperation, error) {
+	operations := make(map[string]storedOperation, len(configMap.Data))
+	for key, value := range configMap.Data {
+		operation := storedOperation{}
+		if err := json.Unmarshal([]byte(value), &operation); err != nil {
+			return nil, fmt.Errorf("invalid operation %s in config map %s/%s: %w", key, configMap.Namespace, configMap.Name, err)
+		}
+		operations[key] = operation
+	}
+	return operations, nil
+}
+
+func encodeOperations(operations map[string]storedOperation) (map[string]string, error) {
+	data := make(map[string]string, len(operations))
+	for key, operation := range operations {
+		value, err := json.Marshal(operation)
+		if err != nil {
+			return nil, err
+		}
+		data[key] = string(value)
+	}
+	return data, nil
+}
+
+// operationKey is the key of the operation of the resource in the config map of its namespace, e.g.
+// serviceinstance.my-instance
+func operationKey(object api.SAPBTPResource) string {
+	return strings.ToLower(string(object.GetControllerName())) + "." + object.GetName()
+}
+
+func getOperation(object api.SAPBTPResource) (string, smClientTypes.OperationCategory) {
+	switch resource := object.(type) {
+	case *servicesv1.ServiceInstance:
+		return resource.Status.OperationURL, resource.Status.OperationType
+	case *servicesv1.ServiceBinding:
+		return resource.Status.OperationURL, resource.Status.OperationType
+	}
+	return "", ""
+}
+
+func setOperation(object api.SAPBTPResource, url string, operationType smClientTypes.OperationCategory) {
+	switch resource := object.(type) {
+	case *servicesv1.ServiceInstance:
+		resource.Status.OperationURL = url
+		resource.Status.OperationType = operationType
+	case *servicesv1.ServiceBinding:
+		resource.Status.OperationURL = url
+		resource.Status.OperationType = operationType
+	}
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Operation stores", func() {
+	var reconciler *BaseReconciler
+	var instance *servicesv1.ServiceInstance
+	var ctx context.Context
+	const operationURL = "/v1/service_instances/1234/operations/5678"
+
+	getConfigMap := func() (*corev1.ConfigMap, error) {
+		configMap := &corev1.ConfigMap{}
+		err := reconciler.Client.Get(ctx, client.ObjectKey{Namespace: "app1", Name: operationsConfigMapName}, configMap)
+		return configMap, err
+	}
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		instance = &servicesv1.ServiceInstance{ObjectMeta: metav1.ObjectMeta{Name: "my-instance", Namespace: "app1"}}
+		reconciler = &BaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance).WithStatusSubresource(instance).Build(),
+			Scheme: testScheme,
+			Log:    ctrl.Log,
+			Config: config.Config{OperationStore: OperationStoreConfigMap},
+		}
+	})
+
+	It("should keep the operations in a config map of the namespace", func() {
+		Expect(reconciler.operations().start(ctx, instance, operationURL, smClientTypes.CREATE)).To(Succeed())
+		Expect(instance.Status.OperationURL).To(Equal(operationURL))
+		configMap, err := getConfigMap()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(configMap.Data).To(HaveKeyWithValue("serviceinstance.my-instance", `{"url":"`+operationURL+`","type":"create"}`))
+
+		Expect(reconciler.updateStatus(ctx, instance)).To(Succeed())
+		Expect(instance.Status.OperationURL).To(Equal(operationURL))
+		stored := &servicesv1.ServiceInstance{}
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(instance), stored)).To(Succeed())
+		Expect(stored.Status.OperationURL).To(BeEmpty())
+
+		Expect(newConfigMapOperationStore(reconciler.Client, reconciler.Client).load(ctx, stored)).To(Succeed())
+		Expect(stored.Status.OperationURL).To(Equal(operationURL))
+		Expect(stored.Status.OperationType).To(Equal(smClientTypes.CREATE))
+
+		described, err := reconciler.operations().describe(ctx, stored, "creating the database")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(described).To(BeTrue())
+		configMap, _ = getConfigMap()
+		Expect(configMap.Data["serviceinstance.my-instance"]).To(ContainSubstring(`"description":"creating the database"`))
+
+		Expect(reconciler.operations().finish(ctx, instance)).To(Succeed())
+		Expect(instance.Status.OperationURL).To(BeEmpty())
+		_, err = getConfigMap()
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should not write the status when operations start and finish", func() {
+		Expect(reconciler.operations().start(ctx, instance, operationURL, smClientTypes.CREATE)).To(Succeed())
+		Expect(reconciler.operations().finish(ctx, instance)).To(Succeed())
+		stored := &servicesv1.ServiceInstance{}
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(instance), stored)).To(Succeed())
+		Expect(stored.ResourceVersion).To(Equal(instance.ResourceVersion))
+	})
+
+	It("should read the config map again after it was changed by someone else", func() {
+		store := newConfigMapOperationStore(reconciler.Client, reconciler.Client)
+		Expect(store.start(ctx, instance, operationURL, smClientTypes.CREATE)).To(Succeed())
+		configMap, err := getConfigMap()
+		Expect(err).ToNot(HaveOccurred())
+		configMap.Data = map[string]string{"serviceinstance.my-instance": `{"url":"/v1/service_instances/1234/operations/9999","type":"update"}`}
+		Expect(reconciler.Client.Update(ctx, configMap)).To(Succeed())
+
+		Expect(store.describe(ctx, instance, "updating the database")).To(BeTrue())
+		configMap, _ = getConfigMap()
+		Expect(configMap.Data["serviceinstance.my-instance"]).To(ContainSubstring(`"type":"update"`))
+		Expect(configMap.Data["serviceinstance.my-instance"]).To(ContainSubstring(`"description":"updating the database"`))
+	})
+
+	It("should move operations kept in the status to the config map", func() {
+		instance.Status.OperationURL = operationURL
+		instance.Status.OperationType = smClientTypes.DELETE
+		Expect(reconciler.operations().load(ctx, instance)).To(Succeed())
+		Expect(instance.Status.OperationURL).To(Equal(operationURL))
+		configMap, err := getConfigMap()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(configMap.Data).To(HaveKey("serviceinstance.my-instance"))
+
+		Expect(reconciler.operations().forget(ctx, instance)).To(Succeed())
+		_, err = getConfigMap()
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should keep the operations in the status by default", func() {
+		reconciler.Config.OperationStore = ""
+		instance.Status.OperationURL = operationURL
+		Expect(reconciler.updateStatus(ctx, instance)).To(Succeed())
+		described, err := reconciler.operations().describe(ctx, instance, "creating the database")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(described).To(BeFalse())
+
+		stored := &servicesv1.ServiceInstance{}
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(instance), stored)).To(Succeed())
+		Expect(stored.Status.OperationURL).To(Equal(operationURL))
+		_, err = getConfigMap()
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should reject unknown operation stores", func() {
+		Expect(ValidateOperationStore(OperationStoreStatus)).To(Succeed())
+		Expect(ValidateOperationStore(OperationStoreConfigMap)).To(Succeed())
+		Expect(ValidateOperationStore("lease")).ToNot(Succeed())
+	})
+})
@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// OperatorConfigReconciler applies the OperatorConfig named sap-btp-operator in the release namespace to the live
+// settings of the operator. A new cluster ID is staged, it applies to the resources created after the change, while the
+// resources created before keep the ID they were created with. The history of the changes is kept in the status of the
+// OperatorConfig so that it survives restarts of the operator.
+type OperatorConfigReconciler struct {
+	*BaseReconciler
+}
+
+// +kubebuilder:rbac:groups=services.cloud.sap.com,resources=operatorconfigs,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=services.cloud.sap.com,resources=operatorconfigs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get
+
+func (r *OperatorConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("operatorconfig", req.NamespacedName).WithValues("correlation_id", uuid.New().String())
+	ctx = context.WithValue(ctx, LogKey{}, log)
+
+	operatorConfig := &servicesv1.OperatorConfig{}
+	if err := r.Client.Get(ctx, req.NamespacedName, operatorConfig); err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Error(err, "unable to fetch OperatorConfig")
+			return ctrl.Result{}, err
+		}
+		if r.isOperatorConfig(req.NamespacedName) && r.Live.Apply(r.Live.Base(), appliedNow()) {
+			log.Info("the OperatorConfig was deleted, restored the settings of the installation")
+		}
+		return ctrl.Result{}, nil
+	}
+	operatorConfig = operatorConfig.DeepCopy()
+
+	if !r.isOperatorConfig(client.ObjectKeyFromObject(operatorConfig)) {
+		message := fmt.Sprintf("only the OperatorConfig named %s in namespace %s is applied", servicesv1.OperatorConfigName, r.Config.ReleaseNamespace)
+		return ctrl.Result{}, r.updateOperatorConfigStatus(ctx, operatorConfig, ConfigIgnored, message, false)
+	}
+	if isMarkedForDeletion(operatorConfig.ObjectMeta) {
+		return ctrl.Result{}, nil
+	}
+
+	settings, err := r.operatorConfigSettings(ctx, operatorConfig)
+	if err != nil {
+		log.Error(err, "invalid OperatorConfig, keeping the current settings")
+		return ctrl.Result{}, r.updateOperatorConfigStatus(ctx, operatorConfig, ConfigInvalid, err.Error(), false)
+	}
+	if r.Live.Apply(settings, appliedNow()) {
+		message := fmt.Sprintf("applied cluster ID '%s', management namespace '%s', poll interval %s and long poll interval %s",
+			settings.ClusterID, settings.ManagementNamespace, settings.PollInterval, settings.LongPollInterval)
+		log.Info(message)
+		r.Recorder.Event(operatorConfig, corev1.EventTypeNormal, ConfigApplied, message)
+	}
+	return ctrl.Result{}, r.updateOperatorConfigStatus(ctx, operatorConfig, ConfigApplied, "the settings are applied", true)
+}
+
+// isOperatorConfig returns whether the key is the key of the OperatorConfig the operator applies
+func (r *OperatorConfigReconciler) isOperatorConfig(key types.NamespacedName) bool {
+	return key.Name == servicesv1.OperatorConfigName && key.Namespace == r.Config.ReleaseNamespace
+}
+
+// operatorConfigSettings merges the spec of the OperatorConfig over the settings of the installation and validates them
+func (r *OperatorConfigReconciler) operatorConfigSettings(ctx context.Context, operatorConfig *servicesv1.OperatorConfig) (config.Settings, error) {
+	settings := r.Live.Base()
+	spec := operatorConfig.Spec
+	if len(spec.ClusterID) > 0 {
+		if errs := validation.IsValidLabelValue(spec.ClusterID); len(errs) > 0 {
+			return settings, fmt.Errorf("invalid clusterID '%s': %v", spec.ClusterID, errs)
+		}
+		settings.ClusterID = spec.ClusterID
+	}
+	if len(spec.ManagementNamespace) > 0 {
+		if errs := validation.IsDNS1123Label(spec.ManagementNamespace); len(errs) > 0 {
+			return settings, fmt.Errorf("invalid managementNamespace '%s': %v", spec.ManagementNamespace, errs)
+		}
+		if !r.Config.AllowClusterAccess && spec.ManagementNamespace != r.Config.ReleaseNamespace && !contains(r.Config.AllowedNamespaces, spec.ManagementNamespace) {
+			return settings, fmt.Errorf("invalid managementNamespace '%s': the operator is not allowed to access the namespace", spec.ManagementNamespace)
+		}
+		if err := r.apiReader().Get(ctx, types.NamespacedName{Name: spec.ManagementNamespace}, &corev1.Namespace{}); err != nil {
+			if apierrors.IsNotFound(err) {
+				return settings, fmt.Errorf("invalid managementNamespace '%s': the namespace does not exist", spec.ManagementNamespace)
+			}
+			return settings, err
+		}
+		settings.ManagementNamespace = spec.ManagementNamespace
+	}
+	if spec.PollInterval != nil {
+		settings.PollInterval = spec.PollInterval.Duration
+	}
+	if spec.LongPollInterval != nil {
+		settings.LongPollInterval = spec.LongPollInterval.Duration
+	}
+	if settings.PollInterval < time.Second {
+		return settings, fmt.Errorf("invalid pollInterval %s: the interval must be at least 1s", settings.PollInterval)
+	}
+	if settings.LongPollInterval < settings.PollInterval {
+		return settings, fmt.Errorf("invalid longPollInterval %s: the interval must be at least the poll interval %s", settings.LongPollInterval, settings.PollInterval)
+	}
+	return settings, nil
+}
+
+func (r *OperatorConfigReconciler) updateOperatorConfigStatus(ctx context.Context, operatorConfig *servicesv1.OperatorConfig, reason, message string, ready bool) error {
+	status := metav1.ConditionFalse
+	if ready {
+		status = metav1.ConditionTrue
+	}
+	meta.SetStatusCondition(&operatorConfig.Status.Conditions, metav1.Condition{
+		Type:               api.ConditionReady,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: operatorConfig.Generation,
+	})
+	operatorConfig.Status.ObservedGeneration = operatorConfig.Generation
+	if reason != ConfigIgnored {
+		operatorConfig.Status.History = toOperatorConfigHistory(r.Live.History())
+		operatorConfig.Status.ClusterIDHistory = toClusterIDHistory(r.Live.ClusterIDHistory())
+	}
+	return r.Client.Status().Update(ctx, operatorConfig)
+}
+
+func (r *OperatorConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&servicesv1.OperatorConfig{}).
+		Complete(r)
+}
+
+// RestoreOperatorConfigHistory restores the history of the changes of the settings recorded in the status of the
+// OperatorConfig, before the operator starts reconciling resources with the cluster IDs they were created with
+func RestoreOperatorConfigHistory(ctx context.Context, reader client.Reader, live *config.Live, namespace string) error {
+	operatorConfig := &servicesv1.OperatorConfig{}
+	if err := reader.Get(ctx, types.NamespacedName{Name: servicesv1.OperatorConfigName, Namespace: namespace}, operatorConfig); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	history := make([]config.AppliedSettings, 0, len(operatorConfig.Status.History))
+	for _, applied := range operatorConfig.Status.History {
+		history = append(history, config.AppliedSettings{
+			Settings: config.Settings{
+				ClusterID:           applied.ClusterID,
+				ManagementNamespace: applied.ManagementNamespace,
+				PollInterval:        applied.PollInterval.Duration,
+				LongPollInterval:    applied.LongPollInterval.Duration,
+			},
+			AppliedAt: applied.AppliedAt.Time,
+		})
+	}
+	clusterIDs := make([]config.ClusterIDChange, 0, len(operatorConfig.Status.ClusterIDHistory))
+	for _, applied := range operatorConfig.Status.ClusterIDHistory {
+		clusterIDs = append(clusterIDs, config.ClusterIDChange{ClusterID: applied.ClusterID, AppliedAt: applied.AppliedAt.Time})
+	}
+	live.Restore(history, clusterIDs)
+	return nil
+}
+
+func toOperatorConfigHistory(history []config.AppliedSettings) []servicesv1.AppliedOperatorConfig {
+	result := make([]servicesv1.AppliedOperatorConfig, 0, len(history))
+	for _, applied := range history {
+		result = append(result, servicesv1.AppliedOperatorConfig{
+			ClusterID:           applied.ClusterID,
+			ManagementNamespace: applied.ManagementNamespace,
+			PollInterval:        metav1.Duration{Duration: applied.PollInterval},
+			LongPollInterval:    metav1.Duration{Duration: applied.LongPollInterval},
+			AppliedAt:           metav1.NewTime(applied.AppliedAt),
+		})
+	}
+	return result
+}
+
+func toClusterIDHistory(clusterIDs []config.ClusterIDChange) []servicesv1.AppliedClusterID {
+	result := make([]servicesv1.AppliedClusterID, 0, len(clusterIDs))
+	for _, change := range clusterIDs {
+		result = append(result, servicesv1.AppliedClusterID{ClusterID: change.ClusterID, AppliedAt: metav1.NewTime(change.AppliedAt)})
+	}
+	return result
+}
+
+// appliedNow is the time settings are applied at, in the precision of the creation timestamps of the resources
+func appliedNow() time.Time {
+	return time.Now().Truncate(time.Second)
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("OperatorConfig controller", func() {
+	var testScheme *runtime.Scheme
+	var operatorConfig *servicesv1.OperatorConfig
+	var live *config.Live
+	var ctx context.Context
+
+	newReconciler := func(objects ...client.Object) *OperatorConfigReconciler {
+		objects = append(objects, operatorConfig, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "new-management"}})
+		return &OperatorConfigReconciler{BaseReconciler: &BaseReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(objects...).WithStatusSubresource(operatorConfig).Build(),
+			Scheme:   testScheme,
+			Log:      ctrl.Log,
+			Recorder: record.NewFakeRecorder(10),
+			Config:   config.Config{ReleaseNamespace: "sap-btp-operator", AllowClusterAccess: true},
+			Live:     live,
+		}}
+	}
+
+	reconcile := func(reconciler *OperatorConfigReconciler) *servicesv1.OperatorConfig {
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(operatorConfig)})
+		Expect(err).ToNot(HaveOccurred())
+		updated := &servicesv1.OperatorConfig{}
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(operatorConfig), updated)).To(Succeed())
+		return updated
+	}
+
+	BeforeEach(func() {
+		testScheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		live = config.NewLive(config.Config{ClusterID: "cluster-1", ManagementNamespace: "sap-btp-operator", PollInterval: 10 * time.Second, LongPollInterval: 5 * time.Minute})
+		operatorConfig = &servicesv1.OperatorConfig{ObjectMeta: metav1.ObjectMeta{Name: servicesv1.OperatorConfigName, Namespace: "sap-btp-operator"}}
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+	})
+
+	It("should stage the new cluster ID and apply the other settings right away", func() {
+		operatorConfig.Spec = servicesv1.OperatorConfigSpec{ClusterID: "cluster-2", ManagementNamespace: "new-management", PollInterval: &metav1.Duration{Duration: 5 * time.Second}}
+		created := metav1.NewTime(time.Now().Add(-time.Hour))
+		reconciler := newReconciler()
+
+		updated := reconcile(reconciler)
+		condition := meta.FindStatusCondition(updated.Status.Conditions, api.ConditionReady)
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(ConfigApplied))
+		Expect(updated.Status.History).To(HaveLen(1))
+		Expect(updated.Status.History[0].ClusterID).To(Equal("cluster-2"))
+
+		Expect(reconciler.pollInterval()).To(Equal(5 * time.Second))
+		Expect(reconciler.longPollInterval()).To(Equal(5 * time.Minute))
+		Expect(reconciler.settings().ManagementNamespace).To(Equal("new-management"))
+		Expect(reconciler.clusterIDOf(&servicesv1.ServiceInstance{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created}}, "")).To(Equal("cluster-1"))
+		Expect(reconciler.clusterIDOf(&servicesv1.ServiceInstance{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.Now()}}, "")).To(Equal("cluster-2"))
+		Expect(reconciler.isOwnClusterID("cluster-1")).To(BeTrue())
+		Expect(reconciler.isOwnClusterID("cluster-3")).To(BeFalse())
+
+		restored := config.NewLive(config.Config{ClusterID: "cluster-1"})
+		Expect(RestoreOperatorConfigHistory(ctx, reconciler.Client, restored, "sap-btp-operator")).To(Succeed())
+		Expect(restored.Current()).To(Equal(live.Current()))
+	})
+
+	It("should keep the current settings when the config is invalid", func() {
+		operatorConfig.Spec = servicesv1.OperatorConfigSpec{ManagementNamespace: "missing", PollInterval: &metav1.Duration{Duration: 5 * time.Second}}
+		updated := reconcile(newReconciler())
+		condition := meta.FindStatusCondition(updated.Status.Conditions, api.ConditionReady)
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(ConfigInvalid))
+		Expect(condition.Message).To(ContainSubstring("the namespace does not exist"))
+		Expect(live.History()).To(BeEmpty())
+
+		operatorConfig.Spec = servicesv1.OperatorConfigSpec{LongPollInterval: &metav1.Duration{Duration: time.Second}}
+		updated = reconcile(newReconciler())
+		Expect(meta.FindStatusCondition(updated.Status.Conditions, api.ConditionReady).Message).To(ContainSubstring("invalid longPollInterval"))
+		Expect(live.History()).To(BeEmpty())
+	})
+
+	It("should ignore other OperatorConfigs", func() {
+		operatorConfig.Name = "other"
+		operatorConfig.Spec = servicesv1.OperatorConfigSpec{ClusterID: "cluster-2"}
+		updated := reconcile(newReconciler())
+		Expect(meta.FindStatusCondition(updated.Status.Conditions, api.ConditionReady).Reason).To(Equal(ConfigIgnored))
+		Expect(live.Current().ClusterID).To(Equal("cluster-1"))
+	})
+})
@@ -3,11 +3,18 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"sort"
+	"strings"
 
 	"fmt"
 
 	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	jsonpatch "github.com/evanphx/json-patch"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
 
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -17,42 +24,42 @@ import (
 )
 
 // buildParameters generates the parameters JSON structure to be passed
-// to the broker.
+// to the broker, merging the sources according to the merge strategy.
 // The first return value is a map of parameters to send to the Broker, including
 // secret values.
 // The second return value is parameters marshalled to byt array
 // The third return value is any error that caused the function to fail.
-func buildParameters(kubeClient client.Client, namespace string, parametersFrom []servicesv1.ParametersFromSource, parameters *runtime.RawExtension) (map[string]interface{}, []byte, error) {
+func buildParameters(reader parametersSources, namespace string, parametersFrom []servicesv1.ParametersFromSource, parameters *runtime.RawExtension, strategy servicesv1.ParametersMergeStrategy) (map[string]interface{}, []byte, error) {
 	params := make(map[string]interface{})
 	if len(parametersFrom) > 0 {
 		for _, p := range parametersFrom {
-			fps, err := fetchParametersFromSource(kubeClient, namespace, &p)
+			fps, err := reader.fetch(namespace, &p)
 			if err != nil {
 				return nil, nil, err
 			}
-			for k, v := range fps {
-				// we don't want to add shared param because sm api does not support updating
-				// shared param with other params, for sharing we have different function.
-				if k == "shared" {
-					continue
-				}
-				if _, ok := params[k]; ok {
-					return nil, nil, fmt.Errorf("conflict: duplicate entry for parameter %q", k)
-				}
-				params[k] = v
+			// we don't want to add shared param because sm api does not support updating
+			// shared param with other params, for sharing we have different function.
+			delete(fps, "shared")
+			if err := mergeParameters(params, fps, strategy == servicesv1.ParametersMergeDeep); err != nil {
+				return nil, nil, err
 			}
 		}
 	}
 	if parameters != nil {
-		pp, err := UnmarshalRawParameters(parameters.Raw)
-		if err != nil {
-			return nil, nil, err
-		}
-		for k, v := range pp {
-			if _, ok := params[k]; ok {
-				return nil, nil, fmt.Errorf("conflict: duplicate entry for parameter %q", k)
+		if strategy == servicesv1.ParametersMergeJSONPatch {
+			patched, err := patchParameters(params, parameters.Raw)
+			if err != nil {
+				return nil, nil, err
+			}
+			params = patched
+		} else {
+			pp, err := UnmarshalRawParameters(parameters.Raw)
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := mergeParameters(params, pp, strategy == servicesv1.ParametersMergeDeep); err != nil {
+				return nil, nil, err
 			}
-			params[k] = v
 		}
 	}
 	// Replace empty map with nil so that the params are omitted from the request
@@ -67,12 +74,158 @@ func buildParameters(kubeClient client.Client, namespace string, parametersFrom
 	return params, parametersRaw, nil
 }
 
-// fetchParametersFromSource fetches data from a specified external source and
+// checkParametersLimits verifies the merged parameters against the configured limits of SM, the sources of
+// parametersFrom are not known on admission. The limits are enforced only when the spec changed, so that resources
+// accepted before the limits were lowered keep being reconciled.
+func (r *BaseReconciler) checkParametersLimits(specChanged bool, parametersRaw []byte) error {
+	if !specChanged || len(parametersRaw) == 0 {
+		return nil
+	}
+	if err := servicesv1.ValidateParametersLimits(parametersRaw, r.Config.ParametersMaxSize, r.Config.ParametersMaxDepth); err != nil {
+		return fmt.Errorf("the merged parameters of spec.parameters and spec.parametersFrom are too large: %w", err)
+	}
+	return nil
+}
+
+// mergeParameters merges the parameters of a source into params. A parameter that is already set is a conflict unless
+// merged deeply, then nested objects are merged recursively and other values are overridden by the source.
+func mergeParameters(params, source map[string]interface{}, deep bool) error {
+	for k, v := range source {
+		existing, ok := params[k]
+		if !ok {
+			params[k] = v
+			continue
+		}
+		if !deep {
+			return fmt.Errorf("conflict: duplicate entry for parameter %q", k)
+		}
+		existingObject, existingIsObject := existing.(map[string]interface{})
+		object, isObject := v.(map[string]interface{})
+		if existingIsObject && isObject {
+			if err := mergeParameters(existingObject, object, true); err != nil {
+				return err
+			}
+			continue
+		}
+		params[k] = v
+	}
+	return nil
+}
+
+// patchParameters applies the JSON patch to the parameters and returns the patched parameters
+func patchParameters(params map[string]interface{}, rawPatch []byte) (map[string]interface{}, error) {
+	patchJSON, err := yaml.YAMLToJSON(rawPatch)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := jsonpatch.DecodePatch(patchJSON)
+	if err != nil {
+		return nil, fmt.Errorf("parameters are not a JSON patch: %v", err)
+	}
+	doc, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	patched, err := patch.Apply(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply the parameters patch: %v", err)
+	}
+	return unmarshalJSON(patched)
+}
+
+// addInstanceParameters adds the fields of the status of the instance referenced by parametersFromInstance to the
+// parameters built by buildParameters and returns them marshalled
+func addInstanceParameters(params map[string]interface{}, instance *servicesv1.ServiceInstance, parametersFromInstance map[string]servicesv1.InstanceStatusField) ([]byte, error) {
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	for name, field := range parametersFromInstance {
+		if _, ok := params[name]; ok {
+			return nil, fmt.Errorf("conflict: duplicate entry for parameter %q", name)
+		}
+		value, err := instanceStatusValue(instance, field)
+		if err != nil {
+			return nil, err
+		}
+		params[name] = value
+	}
+	return MarshalRawParameters(params)
+}
+
+// pendingInstanceParameter returns a message describing the first field referenced by parametersFromInstance
+// that is not set yet in the status of the instance, or an empty message if all of them are set
+func pendingInstanceParameter(instance *servicesv1.ServiceInstance, parametersFromInstance map[string]servicesv1.InstanceStatusField) string {
+	names := make([]string, 0, len(parametersFromInstance))
+	for name := range parametersFromInstance {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field := parametersFromInstance[name]
+		value, err := instanceStatusValue(instance, field)
+		if err != nil {
+			// reported when the parameters are built
+			continue
+		}
+		if value == nil {
+			return fmt.Sprintf("waiting for %s of service instance '%s' to be set for parameter %q", field, instance.Name, name)
+		}
+	}
+	return ""
+}
+
+// instanceStatusValue returns the value of the field of the status of the instance, nil if the field is not set
+func instanceStatusValue(instance *servicesv1.ServiceInstance, field servicesv1.InstanceStatusField) (interface{}, error) {
+	switch field {
+	case servicesv1.InstanceStatusFieldInstanceID:
+		if len(instance.Status.InstanceID) > 0 {
+			return instance.Status.InstanceID, nil
+		}
+	case servicesv1.InstanceStatusFieldSubaccountID:
+		if len(instance.Status.SubaccountID) > 0 {
+			return instance.Status.SubaccountID, nil
+		}
+	case servicesv1.InstanceStatusFieldTags:
+		// an instance may have no tags at all, they are passed as an empty array then
+		tags := mergeInstanceTags(instance.Status.Tags, instance.Spec.CustomTags)
+		if tags == nil {
+			tags = []string{}
+		}
+		return tags, nil
+	default:
+		return nil, fmt.Errorf("unsupported service instance field %q in parametersFromInstance", field)
+	}
+	return nil, nil
+}
+
+// parametersSources reads the secrets, config maps and objects referenced by parametersFrom
+type parametersSources struct {
+	client client.Client
+	// allowedResources are the resources whose fields resourceFieldRef may select, e.g. ingresses.networking.k8s.io
+	allowedResources []string
+}
+
+// parametersSources returns the reader of the sources of parametersFrom, restricted to the resources allowed by the
+// configuration
+func (r *BaseReconciler) parametersSources() parametersSources {
+	return parametersSources{client: r.Client, allowedResources: r.Config.ParametersFromResources}
+}
+
+// fetch fetches data from a specified external source and
 // represents it in the parameters map format
-func fetchParametersFromSource(kubeClient client.Client, namespace string, parametersFrom *servicesv1.ParametersFromSource) (map[string]interface{}, error) {
+func (s parametersSources) fetch(namespace string, parametersFrom *servicesv1.ParametersFromSource) (map[string]interface{}, error) {
 	var params map[string]interface{}
+	references := 0
+	for _, set := range []bool{parametersFrom.SecretKeyRef != nil, parametersFrom.ConfigMapKeyRef != nil, parametersFrom.ResourceFieldRef != nil} {
+		if set {
+			references++
+		}
+	}
+	if references > 1 {
+		return nil, fmt.Errorf("a parametersFrom source references either a secret, a config map or a resource field, not several")
+	}
 	if parametersFrom.SecretKeyRef != nil {
-		data, err := fetchSecretKeyValue(kubeClient, namespace, parametersFrom.SecretKeyRef)
+		data, err := fetchSecretKeyValue(s.client, namespace, parametersFrom.SecretKeyRef)
 		if err != nil {
 			return nil, err
 		}
@@ -83,9 +236,41 @@ func fetchParametersFromSource(kubeClient client.Client, namespace string, param
 		params = p
 
 	}
+	if parametersFrom.ConfigMapKeyRef != nil {
+		data, err := fetchConfigMapKeyValue(s.client, namespace, parametersFrom.ConfigMapKeyRef)
+		if err != nil {
+			return nil, err
+		}
+		p, err := unmarshalJSON(data)
+		if err != nil {
+			return nil, err
+		}
+		params = p
+	}
+	if parametersFrom.ResourceFieldRef != nil {
+		value, err := s.fetchResourceFieldValue(namespace, parametersFrom.ResourceFieldRef)
+		if err != nil {
+			return nil, err
+		}
+		params = map[string]interface{}{parametersFrom.ResourceFieldRef.Parameter: value}
+	}
 	return params, nil
 }
 
+// templateParameters returns the parameters of an instance or binding exposed to secret templates, the inline
+// parameters only. The values of ParametersFrom are read from secrets and are not exposed, neither is a JSON patch,
+// which is no parameters object on its own.
+func templateParameters(parameters *runtime.RawExtension, strategy servicesv1.ParametersMergeStrategy) map[string]interface{} {
+	if parameters == nil || strategy == servicesv1.ParametersMergeJSONPatch {
+		return map[string]interface{}{}
+	}
+	params, err := UnmarshalRawParameters(parameters.Raw)
+	if err != nil {
+		return map[string]interface{}{}
+	}
+	return params
+}
+
 // UnmarshalRawParameters produces a map structure from a given raw YAML/JSON input
 func UnmarshalRawParameters(in []byte) (map[string]interface{}, error) {
 	parameters := make(map[string]interface{})
@@ -124,3 +309,64 @@ func fetchSecretKeyValue(kubeClient client.Client, namespace string, secretKeyRe
 	}
 	return secret.Data[secretKeyRef.Key], nil
 }
+
+// fetchConfigMapKeyValue requests and returns the contents of the given config map key
+func fetchConfigMapKeyValue(kubeClient client.Client, namespace string, configMapKeyRef *servicesv1.ConfigMapKeyReference) ([]byte, error) {
+	configMap := &corev1.ConfigMap{}
+	err := kubeClient.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: configMapKeyRef.Name}, configMap)
+
+	if err != nil {
+		return nil, err
+	}
+	return []byte(configMap.Data[configMapKeyRef.Key]), nil
+}
+
+// fetchResourceFieldValue returns the value selected by the JSONPath of the reference in the referenced object, a
+// JSONPath selecting several values returns them as an array. Only the objects of the allowed resources are read, so
+// that the parameters cannot expose objects the cluster admin did not intend to.
+func (s parametersSources) fetchResourceFieldValue(namespace string, resourceFieldRef *servicesv1.ResourceFieldReference) (interface{}, error) {
+	groupVersion, err := schema.ParseGroupVersion(resourceFieldRef.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+	gvk := groupVersion.WithKind(resourceFieldRef.Kind)
+	mapping, err := s.client.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the resource of resourceFieldRef %s %s: %w", resourceFieldRef.APIVersion, resourceFieldRef.Kind, err)
+	}
+	resource := mapping.Resource.GroupResource().String()
+	if !contains(s.allowedResources, resource) {
+		return nil, fmt.Errorf("resourceFieldRef cannot select fields of %s, the resources allowed in the operator configuration are [%s]",
+			resource, strings.Join(s.allowedResources, ", "))
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return nil, fmt.Errorf("resourceFieldRef cannot select fields of %s, the resource is not namespaced", resource)
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	if err := s.client.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: resourceFieldRef.Name}, obj); err != nil {
+		return nil, err
+	}
+	path := jsonpath.New("resourceFieldRef")
+	if err := path.Parse(resourceFieldRef.Template()); err != nil {
+		return nil, fmt.Errorf("invalid jsonPath of resourceFieldRef: %w", err)
+	}
+	results, err := path.FindResults(obj.UnstructuredContent())
+	if err != nil {
+		return nil, fmt.Errorf("failed to select %s in %s '%s': %w", resourceFieldRef.JSONPath, resourceFieldRef.Kind, resourceFieldRef.Name, err)
+	}
+	var values []interface{}
+	for _, result := range results {
+		for _, value := range result {
+			values = append(values, value.Interface())
+		}
+	}
+	switch len(values) {
+	case 0:
+		return nil, fmt.Errorf("%s selects no value in %s '%s'", resourceFieldRef.JSONPath, resourceFieldRef.Kind, resourceFieldRef.Name)
+	case 1:
+		return values[0], nil
+	}
+	return values, nil
+}
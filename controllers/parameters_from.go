@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	parametersFromSecretIndex    = "spec.parametersFrom.secretKeyRef.name"
+	parametersFromConfigMapIndex = "spec.parametersFrom.configMapKeyRef.name"
+	parametersFromResourceIndex  = "spec.parametersFrom.resourceFieldRef"
+)
+
+func parametersFromSecretNames(parametersFrom []servicesv1.ParametersFromSource) []string {
+	var names []string
+	for _, p := range parametersFrom {
+		if p.SecretKeyRef != nil && len(p.SecretKeyRef.Name) > 0 {
+			names = append(names, p.SecretKeyRef.Name)
+		}
+	}
+	return names
+}
+
+// indexInstanceParametersFrom returns the names of the secrets the instance reads parameters from,
+// they are in the namespace of the instance
+func indexInstanceParametersFrom(obj client.Object) []string {
+	instance, ok := obj.(*servicesv1.ServiceInstance)
+	if !ok {
+		return nil
+	}
+	return parametersFromSecretNames(instance.Spec.ParametersFrom)
+}
+
+// indexBindingParametersFrom returns the names of the secrets the binding reads parameters from,
+// they are in the namespace of the binding
+func indexBindingParametersFrom(obj client.Object) []string {
+	binding, ok := obj.(*servicesv1.ServiceBinding)
+	if !ok {
+		return nil
+	}
+	return parametersFromSecretNames(binding.Spec.ParametersFrom)
+}
+
+// parametersFromOf returns the parametersFrom of an instance or a binding
+func parametersFromOf(obj client.Object) []servicesv1.ParametersFromSource {
+	switch resource := obj.(type) {
+	case *servicesv1.ServiceInstance:
+		return resource.Spec.ParametersFrom
+	case *servicesv1.ServiceBinding:
+		return resource.Spec.ParametersFrom
+	}
+	return nil
+}
+
+// indexParametersFromConfigMaps returns the names of the config maps the resource reads parameters from,
+// they are in the namespace of the resource
+func indexParametersFromConfigMaps(obj client.Object) []string {
+	var names []string
+	for _, p := range parametersFromOf(obj) {
+		if p.ConfigMapKeyRef != nil && len(p.ConfigMapKeyRef.Name) > 0 {
+			names = append(names, p.ConfigMapKeyRef.Name)
+		}
+	}
+	return names
+}
+
+// hashParametersFrom returns the hash of the parameters read from the secrets and config maps referenced by
+// parametersFrom, or an empty hash if there are none. The sources are hashed before they are merged, so the hash does
+// not depend on the merge strategy.
+func hashParametersFrom(reader parametersSources, namespace string, parametersFrom []servicesv1.ParametersFromSource) (string, error) {
+	if len(parametersFrom) == 0 {
+		return "", nil
+	}
+	sources := make([]map[string]interface{}, 0, len(parametersFrom))
+	for i := range parametersFrom {
+		params, err := reader.fetch(namespace, &parametersFrom[i])
+		if err != nil {
+			return "", err
+		}
+		sources = append(sources, params)
+	}
+	sourcesBytes, err := json.Marshal(sources)
+	if err != nil {
+		return "", err
+	}
+	return generateEncodedMD5Hash(string(sourcesBytes)), nil
+}
+
+// parametersFromChanged checks whether the parameters read from the secrets referenced by parametersFrom differ from
+// the ones last sent to SM. Resources without a recorded hash, e.g. recovered ones, are not considered changed and
+// secrets that cannot be read are left to the next update of the resource to report.
+func (r *BaseReconciler) parametersFromChanged(ctx context.Context, namespace string, parametersFrom []servicesv1.ParametersFromSource, hashed string) bool {
+	if len(parametersFrom) == 0 || len(hashed) == 0 {
+		return false
+	}
+	hash, err := hashParametersFrom(r.parametersSources(), namespace, parametersFrom)
+	if err != nil {
+		GetLogger(ctx).Info(fmt.Sprintf("failed to read the parameters of parametersFrom, skipping the check for changes: %s", err.Error()))
+		return false
+	}
+	return hash != hashed
+}
+
+// enqueueParametersFromReferences maps a secret or a config map to the resources of the list type that reference it in
+// parametersFrom, the index is the one of the referenced kind
+func (r *BaseReconciler) enqueueParametersFromReferences(newList func() client.ObjectList, index string) handler.EventHandler {
+	return r.enqueueIndexedReferences(newList, index, client.Object.GetName)
+}
+
+// watchParametersFromConfigMaps indexes the resources by the config maps they read parameters from and watches the
+// config maps, so that changes of their parameters are detected without waiting for the next reconciliation
+func (r *BaseReconciler) watchParametersFromConfigMaps(mgr ctrl.Manager, b *builder.Builder, object client.Object, newList func() client.ObjectList) (*builder.Builder, error) {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), object, parametersFromConfigMapIndex, indexParametersFromConfigMaps); err != nil {
+		return nil, err
+	}
+	return b.Watches(&corev1.ConfigMap{}, r.enqueueParametersFromReferences(newList, parametersFromConfigMapIndex)), nil
+}
+
+// enqueueIndexedReferences maps an object to the resources of the list type in its namespace whose index contains the
+// value of the object
+func (r *BaseReconciler) enqueueIndexedReferences(newList func() client.ObjectList, index string, indexValue func(client.Object) string) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		list := newList()
+		if err := r.Client.List(ctx, list, client.InNamespace(obj.GetNamespace()), client.MatchingFields{index: indexValue(obj)}); err != nil {
+			r.Log.Error(err, "failed to list the resources referencing object in parametersFrom", "object", obj.GetName(), "namespace", obj.GetNamespace())
+			return nil
+		}
+		objects, err := meta.ExtractList(list)
+		if err != nil {
+			return nil
+		}
+		requests := make([]reconcile.Request, 0, len(objects))
+		for _, object := range objects {
+			if accessor, err := meta.Accessor(object); err == nil {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: accessor.GetNamespace(), Name: accessor.GetName()}})
+			}
+		}
+		return requests
+	})
+}
+
+// resourceFieldRefKey is the value of the resourceFieldRef index of a referenced object, e.g. Ingress.networking.k8s.io/my-app
+func resourceFieldRefKey(groupKind schema.GroupKind, name string) string {
+	return groupKind.String() + "/" + name
+}
+
+// indexParametersFromResources returns the keys of the objects the resource selects fields of in parametersFrom,
+// they are in the namespace of the resource
+func indexParametersFromResources(obj client.Object) []string {
+	var keys []string
+	for _, p := range parametersFromOf(obj) {
+		if p.ResourceFieldRef == nil {
+			continue
+		}
+		groupVersion, err := schema.ParseGroupVersion(p.ResourceFieldRef.APIVersion)
+		if err != nil {
+			continue
+		}
+		keys = append(keys, resourceFieldRefKey(groupVersion.WithKind(p.ResourceFieldRef.Kind).GroupKind(), p.ResourceFieldRef.Name))
+	}
+	return keys
+}
+
+// watchParametersFromResources indexes the resources by the objects they select fields of in parametersFrom and
+// watches the metadata of the objects of the allowed resources, so that changes of the objects are sent to SM
+func (r *BaseReconciler) watchParametersFromResources(mgr ctrl.Manager, b *builder.Builder, object client.Object, newList func() client.ObjectList) (*builder.Builder, error) {
+	if len(r.Config.ParametersFromResources) == 0 {
+		return b, nil
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), object, parametersFromResourceIndex, indexParametersFromResources); err != nil {
+		return nil, err
+	}
+	for _, allowed := range r.Config.ParametersFromResources {
+		groupResource := schema.ParseGroupResource(allowed)
+		gvk, err := mgr.GetRESTMapper().KindFor(groupResource.WithVersion(""))
+		if err != nil {
+			// e.g. the CRD of the resource is not installed, references to it fail until the operator is restarted
+			r.Log.Error(err, "failed to resolve the resource allowed in resourceFieldRef, its objects are not watched", "resource", allowed)
+			continue
+		}
+		watched := &metav1.PartialObjectMetadata{}
+		watched.SetGroupVersionKind(gvk)
+		b = b.Watches(watched, r.enqueueIndexedReferences(newList, parametersFromResourceIndex, func(obj client.Object) string {
+			return resourceFieldRefKey(gvk.GroupKind(), obj.GetName())
+		}), builder.OnlyMetadata)
+	}
+	return b, nil
+}
@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Parameters from secrets", func() {
+	var reconciler *BaseReconciler
+	var secret *corev1.Secret
+	var binding *servicesv1.ServiceBinding
+	var ctx context.Context
+
+	parametersFrom := []servicesv1.ParametersFromSource{{SecretKeyRef: &servicesv1.SecretKeyReference{Name: "param-secret", Key: "secret-parameter"}}}
+
+	BeforeEach(func() {
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "param-secret", Namespace: "app1"},
+			Data:       map[string][]byte{"secret-parameter": []byte(`{"key": "value"}`)},
+		}
+		binding = &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1"},
+			Spec:       servicesv1.ServiceBindingSpec{ServiceInstanceName: "my-instance", ParametersFrom: parametersFrom},
+			Status:     servicesv1.ServiceBindingStatus{BindingID: "1234"},
+		}
+		reconciler = &BaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(secret, binding,
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "param-configmap", Namespace: "app1"}, Data: map[string]string{"configmap-parameter": `{"plan": "small"}`}},
+				&servicesv1.ServiceBinding{ObjectMeta: metav1.ObjectMeta{Name: "other-binding", Namespace: "app1"}},
+				&servicesv1.ServiceBinding{ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app2"}, Spec: servicesv1.ServiceBindingSpec{ParametersFrom: parametersFrom}},
+			).WithIndex(&servicesv1.ServiceBinding{}, parametersFromSecretIndex, indexBindingParametersFrom).
+				WithIndex(&servicesv1.ServiceBinding{}, parametersFromConfigMapIndex, indexParametersFromConfigMaps).Build(),
+			Scheme: testScheme,
+			Log:    ctrl.Log,
+		}
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+	})
+
+	updateSecret := func(data string) {
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(secret), secret)).To(Succeed())
+		secret.Data = map[string][]byte{"secret-parameter": []byte(data)}
+		Expect(reconciler.Client.Update(ctx, secret)).To(Succeed())
+	}
+
+	It("should detect changes of the parameters in the secrets", func() {
+		hash, err := hashParametersFrom(reconciler.parametersSources(), "app1", parametersFrom)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hash).ToNot(BeEmpty())
+		Expect(reconciler.parametersFromChanged(ctx, "app1", parametersFrom, hash)).To(BeFalse())
+
+		// the formatting of the parameters is not a change
+		updateSecret(`{ "key" : "value" }`)
+		Expect(reconciler.parametersFromChanged(ctx, "app1", parametersFrom, hash)).To(BeFalse())
+
+		updateSecret(`{"key": "new-value"}`)
+		Expect(reconciler.parametersFromChanged(ctx, "app1", parametersFrom, hash)).To(BeTrue())
+	})
+
+	It("should merge the parameters of config maps and secrets", func() {
+		sources := append([]servicesv1.ParametersFromSource{
+			{ConfigMapKeyRef: &servicesv1.ConfigMapKeyReference{Name: "param-configmap", Key: "configmap-parameter"}},
+		}, parametersFrom...)
+		params, _, err := buildParameters(reconciler.parametersSources(), "app1", sources, nil, "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(params).To(Equal(map[string]interface{}{"plan": "small", "key": "value"}))
+
+		sources[0].SecretKeyRef = parametersFrom[0].SecretKeyRef
+		_, _, err = buildParameters(reconciler.parametersSources(), "app1", sources, nil, "")
+		Expect(err).To(MatchError(ContainSubstring("either a secret, a config map or a resource field")))
+	})
+
+	It("should not consider resources without a recorded hash or readable secrets changed", func() {
+		Expect(reconciler.parametersFromChanged(ctx, "app1", parametersFrom, "")).To(BeFalse())
+		Expect(reconciler.parametersFromChanged(ctx, "app3", parametersFrom, "hash")).To(BeFalse())
+		hash, err := hashParametersFrom(reconciler.parametersSources(), "app1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hash).To(BeEmpty())
+	})
+
+	It("should report bindings whose secrets changed as outdated", func() {
+		recorder := record.NewFakeRecorder(10)
+		bindingReconciler := &ServiceBindingReconciler{BaseReconciler: reconciler}
+		bindingReconciler.Recorder = recorder
+		var err error
+		binding.Status.HashedParametersFrom, err = hashParametersFrom(reconciler.parametersSources(), "app1", parametersFrom)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bindingReconciler.bindingParametersFromChanged(ctx, binding)).To(BeFalse())
+
+		updateSecret(`{"key": "new-value"}`)
+		Expect(bindingReconciler.bindingParametersFromChanged(ctx, binding)).To(BeTrue())
+		Expect(meta.IsStatusConditionTrue(binding.Status.Conditions, api.ConditionParametersOutdated)).To(BeTrue())
+		Expect(meta.FindStatusCondition(binding.Status.Conditions, api.ConditionCredRotationInProgress)).To(BeNil())
+		Expect(recorder.Events).To(Receive(ContainSubstring("ParametersOutdated")))
+
+		// the condition is reported once
+		Expect(bindingReconciler.bindingParametersFromChanged(ctx, binding)).To(BeFalse())
+		Expect(recorder.Events).ToNot(Receive())
+
+		updateSecret(`{"key": "value"}`)
+		Expect(bindingReconciler.bindingParametersFromChanged(ctx, binding)).To(BeTrue())
+		Expect(meta.FindStatusCondition(binding.Status.Conditions, api.ConditionParametersOutdated)).To(BeNil())
+	})
+
+	It("should rotate the credentials of bindings whose secrets changed if the rotation policy opts in", func() {
+		recorder := record.NewFakeRecorder(10)
+		bindingReconciler := &ServiceBindingReconciler{BaseReconciler: reconciler}
+		bindingReconciler.Recorder = recorder
+		binding.Spec.CredRotationPolicy = &servicesv1.CredentialsRotationPolicy{RotateOnParametersChange: true}
+		var err error
+		binding.Status.HashedParametersFrom, err = hashParametersFrom(reconciler.parametersSources(), "app1", parametersFrom)
+		Expect(err).ToNot(HaveOccurred())
+
+		updateSecret(`{"key": "new-value"}`)
+		Expect(bindingReconciler.bindingParametersFromChanged(ctx, binding)).To(BeTrue())
+		condition := meta.FindStatusCondition(binding.Status.Conditions, api.ConditionCredRotationInProgress)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Reason).To(Equal(CredPreparing))
+		Expect(recorder.Events).To(Receive(ContainSubstring("ParametersChanged")))
+
+		// a rotation in progress is not started again
+		Expect(bindingReconciler.bindingParametersFromChanged(ctx, binding)).To(BeFalse())
+	})
+
+	It("should enqueue the resources referencing the secret", func() {
+		queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+		defer queue.ShutDown()
+		handler := reconciler.enqueueParametersFromReferences(func() client.ObjectList { return &servicesv1.ServiceBindingList{} }, parametersFromSecretIndex)
+		handler.Update(ctx, event.UpdateEvent{ObjectOld: secret, ObjectNew: secret}, queue)
+		Expect(queue.Len()).To(Equal(1))
+		item, _ := queue.Get()
+		Expect(item).To(Equal(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "app1", Name: "my-binding"}}))
+	})
+
+	It("should enqueue the resources referencing the config map", func() {
+		configMapBinding := &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "configmap-binding", Namespace: "app1"},
+			Spec: servicesv1.ServiceBindingSpec{ParametersFrom: []servicesv1.ParametersFromSource{
+				{ConfigMapKeyRef: &servicesv1.ConfigMapKeyReference{Name: "param-configmap", Key: "configmap-parameter"}}}},
+		}
+		Expect(reconciler.Client.Create(ctx, configMapBinding)).To(Succeed())
+		configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "param-configmap", Namespace: "app1"}}
+		queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+		defer queue.ShutDown()
+		handler := reconciler.enqueueParametersFromReferences(func() client.ObjectList { return &servicesv1.ServiceBindingList{} }, parametersFromConfigMapIndex)
+		handler.Update(ctx, event.UpdateEvent{ObjectOld: configMap, ObjectNew: configMap}, queue)
+		Expect(queue.Len()).To(Equal(1))
+		item, _ := queue.Get()
+		Expect(item).To(Equal(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "app1", Name: "configmap-binding"}}))
+	})
+})
+
+var _ = Describe("Parameters from resource fields", func() {
+	var reconciler *BaseReconciler
+	var ingress *networkingv1.Ingress
+	var ctx context.Context
+
+	hostname := []servicesv1.ParametersFromSource{{ResourceFieldRef: &servicesv1.ResourceFieldReference{
+		APIVersion: "networking.k8s.io/v1", Kind: "Ingress", Name: "my-app", JSONPath: ".spec.rules[0].host", Parameter: "hostname"}}}
+
+	BeforeEach(func() {
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		ingress = &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-app", Namespace: "app1"},
+			Spec:       networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{Host: "my-app.example.com"}, {Host: "my-app.example.org"}}},
+		}
+		instance := &servicesv1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-instance", Namespace: "app1"},
+			Spec:       servicesv1.ServiceInstanceSpec{ParametersFrom: hostname},
+		}
+		mapper := meta.NewDefaultRESTMapper(nil)
+		for _, gvk := range []schema.GroupVersionKind{corev1.SchemeGroupVersion.WithKind("ConfigMap"), corev1.SchemeGroupVersion.WithKind("Secret"),
+			networkingv1.SchemeGroupVersion.WithKind("Ingress")} {
+			mapper.Add(gvk, meta.RESTScopeNamespace)
+		}
+		reconciler = &BaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithRESTMapper(mapper).WithObjects(ingress, instance,
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "region", Namespace: "app1"}, Data: map[string]string{"region": "eu10"}},
+				&servicesv1.ServiceInstance{ObjectMeta: metav1.ObjectMeta{Name: "other-instance", Namespace: "app1"}},
+			).WithIndex(&servicesv1.ServiceInstance{}, parametersFromResourceIndex, indexParametersFromResources).Build(),
+			Scheme: testScheme,
+			Log:    ctrl.Log,
+		}
+		reconciler.Config.ParametersFromResources = []string{"configmaps", "ingresses.networking.k8s.io"}
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+	})
+
+	It("should pass the selected fields as parameters", func() {
+		sources := append([]servicesv1.ParametersFromSource{
+			{ResourceFieldRef: &servicesv1.ResourceFieldReference{APIVersion: "v1", Kind: "ConfigMap", Name: "region", JSONPath: "{.data.region}", Parameter: "region"}},
+			{ResourceFieldRef: &servicesv1.ResourceFieldReference{APIVersion: "networking.k8s.io/v1", Kind: "Ingress", Name: "my-app", JSONPath: "{.spec.rules[*].host}", Parameter: "hosts"}},
+		}, hostname...)
+		params, _, err := buildParameters(reconciler.parametersSources(), "app1", sources, nil, "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(params).To(Equal(map[string]interface{}{
+			"region":   "eu10",
+			"hosts":    []interface{}{"my-app.example.com", "my-app.example.org"},
+			"hostname": "my-app.example.com",
+		}))
+	})
+
+	It("should fail on missing fields and resources that are not allowed", func() {
+		_, _, err := buildParameters(reconciler.parametersSources(), "app1", []servicesv1.ParametersFromSource{{ResourceFieldRef: &servicesv1.ResourceFieldReference{
+			APIVersion: "networking.k8s.io/v1", Kind: "Ingress", Name: "my-app", JSONPath: "{.spec.tls[0].secretName}", Parameter: "tls"}}}, nil, "")
+		Expect(err).To(MatchError(ContainSubstring("failed to select {.spec.tls[0].secretName} in Ingress 'my-app'")))
+
+		_, _, err = buildParameters(reconciler.parametersSources(), "app1", []servicesv1.ParametersFromSource{{ResourceFieldRef: &servicesv1.ResourceFieldReference{
+			APIVersion: "v1", Kind: "Secret", Name: "my-secret", JSONPath: "{.data.password}", Parameter: "password"}}}, nil, "")
+		Expect(err).To(MatchError(ContainSubstring("resourceFieldRef cannot select fields of secrets")))
+
+		_, _, err = buildParameters(reconciler.parametersSources(), "app2", hostname, nil, "")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should detect changes of the selected fields", func() {
+		hash, err := hashParametersFrom(reconciler.parametersSources(), "app1", hostname)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reconciler.parametersFromChanged(ctx, "app1", hostname, hash)).To(BeFalse())
+
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(ingress), ingress)).To(Succeed())
+		ingress.Spec.Rules[0].Host = "my-app.example.net"
+		Expect(reconciler.Client.Update(ctx, ingress)).To(Succeed())
+		Expect(reconciler.parametersFromChanged(ctx, "app1", hostname, hash)).To(BeTrue())
+	})
+
+	It("should enqueue the instances referencing the object", func() {
+		queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+		defer queue.ShutDown()
+		ingressKind := schema.GroupKind{Group: "networking.k8s.io", Kind: "Ingress"}
+		handler := reconciler.enqueueIndexedReferences(func() client.ObjectList { return &servicesv1.ServiceInstanceList{} },
+			parametersFromResourceIndex, func(obj client.Object) string { return resourceFieldRefKey(ingressKind, obj.GetName()) })
+		handler.Update(ctx, event.UpdateEvent{ObjectOld: ingress, ObjectNew: ingress}, queue)
+		Expect(queue.Len()).To(Equal(1))
+		item, _ := queue.Get()
+		Expect(item).To(Equal(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "app1", Name: "my-instance"}}))
+	})
+})
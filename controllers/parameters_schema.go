@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/kube-openapi/pkg/validation/strfmt"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	schemaServiceInstance = "service_instance"
+	schemaServiceBinding  = "service_binding"
+	schemaCreate          = "create"
+	schemaUpdate          = "update"
+)
+
+// planSchemas are the schemas of the parameters a broker publishes for the operations of a plan, by resource and
+// operation, e.g. service_instance.create.parameters
+type planSchemas map[string]map[string]struct {
+	Parameters json.RawMessage `json:"parameters"`
+}
+
+// checkParametersSchema validates the parameters of an operation on a resource of the instance against the schema
+// of its plan if the ParametersSchemaValidation feature gate is enabled. The parameters are sent to SM if the plan
+// cannot be resolved, SM reports the actual error then.
+func (r *BaseReconciler) checkParametersSchema(ctx context.Context, smClient sm.Client, serviceInstance *servicesv1.ServiceInstance, resource, operation string, parameters []byte) error {
+	if !r.Config.FeatureGates.Enabled(config.ParametersSchemaValidation) {
+		return nil
+	}
+	lookup, err := r.lookupCatalog(smClient, serviceInstance)
+	if err != nil {
+		GetLogger(ctx).Error(err, "failed to resolve the plan in the catalog of SM, sending the parameters without validating them")
+		return nil
+	}
+	return validateParametersSchema(lookup.plan, resource, operation, parameters)
+}
+
+// validateParametersSchema validates the parameters against the JSON schema the broker of the plan publishes for the
+// operation. Plans without a schema, or with one that cannot be interpreted, accept any parameters, and schemas with
+// references are not validated since the references are not resolved.
+func validateParametersSchema(plan *smClientTypes.ServicePlan, resource, operation string, parameters []byte) error {
+	if plan == nil || len(plan.Schemas) == 0 {
+		return nil
+	}
+	schemas := planSchemas{}
+	if err := json.Unmarshal(plan.Schemas, &schemas); err != nil {
+		return nil
+	}
+	rawSchema := schemas[resource][operation].Parameters
+	if len(rawSchema) == 0 || bytes.Contains(rawSchema, []byte(`"$ref"`)) {
+		return nil
+	}
+	schema := &spec.Schema{}
+	if err := json.Unmarshal(rawSchema, schema); err != nil {
+		return nil
+	}
+
+	var data interface{} = map[string]interface{}{}
+	if len(parameters) > 0 {
+		if err := json.Unmarshal(parameters, &data); err != nil {
+			return err
+		}
+	}
+	result := validate.NewSchemaValidator(schema, nil, "parameters", strfmt.Default).Validate(data)
+	if !result.HasErrors() {
+		return nil
+	}
+	violations := make([]string, 0, len(result.Errors))
+	for _, violation := range result.Errors {
+		violations = append(violations, violation.Error())
+	}
+	sort.Strings(violations)
+	return fmt.Errorf("the parameters do not match the schema of plan '%s': %s", plan.CatalogName, strings.Join(violations, ", "))
+}
+
+// markAsInvalidParameters fails the operation with the InvalidParameters reason without sending it to SM, it is not
+// retried until the parameters change
+func (r *BaseReconciler) markAsInvalidParameters(ctx context.Context, operationType smClientTypes.OperationCategory, errMsg string, object api.SAPBTPResource) (ctrl.Result, error) {
+	log := GetLogger(ctx)
+	log.Info(fmt.Sprintf("operation %s of %s has invalid parameters %s, giving up operation", operationType, object.GetControllerName(), errMsg))
+	setFailureConditions(operationType, errMsg, object)
+	failedReason := getConditionReason(operationType, smClientTypes.FAILED)
+	conditions := object.GetConditions()
+	for i := range conditions {
+		if conditions[i].Reason == failedReason {
+			conditions[i].Reason = InvalidParameters
+		}
+	}
+	object.SetConditions(conditions)
+	object.SetObservedGeneration(object.GetGeneration())
+	return ctrl.Result{}, r.updateStatus(ctx, object)
+}
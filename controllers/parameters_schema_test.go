@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	"github.com/SAP/sap-btp-service-operator/client/sm/smfakes"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Parameters schema validation", func() {
+	plan := &smClientTypes.ServicePlan{ID: "plan-id", CatalogName: "plan", Schemas: []byte(`{
+		"service_instance": {
+			"create": {"parameters": {
+				"$schema": "http://json-schema.org/draft-04/schema#",
+				"type": "object",
+				"required": ["size"],
+				"additionalProperties": false,
+				"properties": {
+					"size": {"type": "integer", "minimum": 1, "maximum": 10},
+					"region": {"type": "string", "enum": ["eu", "us"]},
+					"tags": {"type": "array", "items": {"type": "string", "pattern": "^[a-z]+$"}, "maxItems": 2}
+				}
+			}}
+		}
+	}`)}
+
+	It("should accept parameters that match the schema of the operation", func() {
+		Expect(validateParametersSchema(plan, schemaServiceInstance, schemaCreate, []byte(`{"size": 3, "region": "eu", "tags": ["a"]}`))).To(Succeed())
+		// the plan has no schema for the update of instances and for bindings
+		Expect(validateParametersSchema(plan, schemaServiceInstance, schemaUpdate, []byte(`{"other": true}`))).To(Succeed())
+		Expect(validateParametersSchema(plan, schemaServiceBinding, schemaCreate, nil)).To(Succeed())
+		Expect(validateParametersSchema(nil, schemaServiceInstance, schemaCreate, nil)).To(Succeed())
+	})
+
+	It("should report all violations of the schema", func() {
+		err := validateParametersSchema(plan, schemaServiceInstance, schemaCreate, []byte(`{"size": 1.5, "region": "asia", "tags": ["a", "B", "c"], "other": 1}`))
+		Expect(err).To(MatchError("the parameters do not match the schema of plan 'plan': parameters.other in body is a forbidden property, " +
+			"parameters.region in body should be one of [eu us], parameters.size in body must be of type integer: \"number\", " +
+			"parameters.tags in body should have at most 2 items, parameters.tags[1] in body should match '^[a-z]+$'"))
+
+		Expect(validateParametersSchema(plan, schemaServiceInstance, schemaCreate, nil)).To(MatchError(ContainSubstring("parameters.size in body is required")))
+		Expect(validateParametersSchema(plan, schemaServiceInstance, schemaCreate, []byte(`{"size": 11}`))).To(MatchError(ContainSubstring("parameters.size in body should be less than or equal to 10")))
+	})
+
+	It("should not validate schemas with references", func() {
+		withRef := &smClientTypes.ServicePlan{Schemas: []byte(`{"service_instance": {"create": {"parameters": {"type": "object", "properties": {"size": {"$ref": "#/definitions/size"}}}}}}`)}
+		Expect(validateParametersSchema(withRef, schemaServiceInstance, schemaCreate, []byte(`{"size": "any"}`))).To(Succeed())
+	})
+
+	It("should fail the creation of instances with invalid parameters", func() {
+		ctx := context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		instance := &servicesv1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "instance", Namespace: "apps"},
+			Spec: servicesv1.ServiceInstanceSpec{ServiceOfferingName: "offering", ServicePlanName: "plan",
+				Parameters: &runtime.RawExtension{Raw: []byte(`{"size": 0}`)}},
+		}
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		smClient := &smfakes.FakeClient{}
+		smClient.ListOfferingsReturns(&smClientTypes.ServiceOfferings{ServiceOfferings: []smClientTypes.ServiceOffering{{ID: "offering-id"}}}, nil)
+		smClient.ListPlansReturns(&smClientTypes.ServicePlans{ServicePlans: []smClientTypes.ServicePlan{*plan}}, nil)
+		gates := config.FeatureGates{}
+		Expect(gates.Decode("ParametersSchemaValidation=true")).To(Succeed())
+		reconciler := &ServiceInstanceReconciler{BaseReconciler: &BaseReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance).WithStatusSubresource(instance).Build(),
+			Scheme:   testScheme,
+			Log:      ctrl.Log,
+			SMClient: func() sm.Client { return smClient },
+			Config:   config.Config{FeatureGates: gates, CatalogCacheTTL: time.Minute},
+		}}
+
+		_, err := reconciler.createInstance(ctx, smClient, instance)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(smClient.ProvisionCallCount()).To(Equal(0))
+		updated := &servicesv1.ServiceInstance{}
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(instance), updated)).To(Succeed())
+		condition := meta.FindStatusCondition(updated.Status.Conditions, api.ConditionFailed)
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(InvalidParameters))
+		Expect(condition.Message).To(ContainSubstring("parameters.size in body should be greater than or equal to 1"))
+		Expect(isInProgress(updated)).To(BeFalse())
+	})
+})
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var _ = Describe("Parameters from the instance", func() {
+	var instance *servicesv1.ServiceInstance
+
+	BeforeEach(func() {
+		instance = &servicesv1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-instance"},
+			Spec:       servicesv1.ServiceInstanceSpec{CustomTags: []string{"custom"}},
+			Status:     servicesv1.ServiceInstanceStatus{InstanceID: "instance-id", Tags: []string{"offering"}},
+		}
+	})
+
+	It("should add the fields of the instance status to the parameters", func() {
+		params, err := addInstanceParameters(map[string]interface{}{"key": "value"}, instance, map[string]servicesv1.InstanceStatusField{
+			"instance_guid": servicesv1.InstanceStatusFieldInstanceID,
+			"tags":          servicesv1.InstanceStatusFieldTags,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(params).To(MatchJSON(`{"key":"value","instance_guid":"instance-id","tags":["offering","custom"]}`))
+	})
+
+	It("should fail on parameters that are already set", func() {
+		_, err := addInstanceParameters(map[string]interface{}{"instance_guid": "value"}, instance, map[string]servicesv1.InstanceStatusField{
+			"instance_guid": servicesv1.InstanceStatusFieldInstanceID,
+		})
+		Expect(err).To(MatchError(ContainSubstring("duplicate entry")))
+	})
+
+	It("should wait for fields that are not set yet", func() {
+		parametersFromInstance := map[string]servicesv1.InstanceStatusField{
+			"instance_guid": servicesv1.InstanceStatusFieldInstanceID,
+			"subaccount":    servicesv1.InstanceStatusFieldSubaccountID,
+		}
+		Expect(pendingInstanceParameter(instance, parametersFromInstance)).To(ContainSubstring("waiting for subaccountID of service instance 'my-instance'"))
+
+		instance.Status.SubaccountID = "subaccount-id"
+		Expect(pendingInstanceParameter(instance, parametersFromInstance)).To(BeEmpty())
+	})
+
+	It("should not wait for an instance without tags", func() {
+		instance.Spec.CustomTags, instance.Status.Tags = nil, nil
+		parametersFromInstance := map[string]servicesv1.InstanceStatusField{"tags": servicesv1.InstanceStatusFieldTags}
+		Expect(pendingInstanceParameter(instance, parametersFromInstance)).To(BeEmpty())
+		params, err := addInstanceParameters(nil, instance, parametersFromInstance)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(params).To(MatchJSON(`{"tags":[]}`))
+	})
+})
+
+var _ = Describe("Parameters merge strategies", func() {
+	raw := func(parameters string) *runtime.RawExtension {
+		return &runtime.RawExtension{Raw: []byte(parameters)}
+	}
+
+	It("should reject parameters set by more than one source when merged shallowly", func() {
+		_, _, err := buildParameters(parametersSources{}, "", nil, raw(`{"a": 1}`), servicesv1.ParametersMergeShallow)
+		Expect(err).ToNot(HaveOccurred())
+		params := map[string]interface{}{"a": map[string]interface{}{"b": 1.0}}
+		Expect(mergeParameters(params, map[string]interface{}{"a": map[string]interface{}{"c": 2.0}}, false)).To(MatchError(ContainSubstring(`duplicate entry for parameter "a"`)))
+	})
+
+	It("should merge nested objects and override other values when merged deeply", func() {
+		params := map[string]interface{}{"a": map[string]interface{}{"b": 1.0, "c": 1.0}, "d": []interface{}{1.0}}
+		Expect(mergeParameters(params, map[string]interface{}{"a": map[string]interface{}{"c": 2.0}, "d": []interface{}{2.0}}, true)).To(Succeed())
+		Expect(params).To(Equal(map[string]interface{}{"a": map[string]interface{}{"b": 1.0, "c": 2.0}, "d": []interface{}{2.0}}))
+	})
+
+	It("should apply the parameters as a JSON patch", func() {
+		params, parametersRaw, err := buildParameters(parametersSources{}, "", nil, raw(`[{"op": "add", "path": "/plan", "value": "large"}]`), servicesv1.ParametersMergeJSONPatch)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(params).To(Equal(map[string]interface{}{"plan": "large"}))
+		Expect(string(parametersRaw)).To(Equal(`{"plan":"large"}`))
+
+		_, _, err = buildParameters(parametersSources{}, "", nil, raw(`[{"op": "remove", "path": "/missing"}]`), servicesv1.ParametersMergeJSONPatch)
+		Expect(err).To(MatchError(ContainSubstring("failed to apply the parameters patch")))
+	})
+
+	It("should reject merged parameters beyond the limits of SM", func() {
+		reconciler := &BaseReconciler{Config: config.Config{ParametersMaxSize: 64 * 1024, ParametersMaxDepth: 32}}
+		_, large, err := buildParameters(parametersSources{}, "", nil, raw(fmt.Sprintf(`{"certificate": %q}`, strings.Repeat("a", 64*1024))), "")
+		Expect(err).ToNot(HaveOccurred())
+		err = reconciler.checkParametersLimits(true, large)
+		Expect(err).To(MatchError(ContainSubstring("the merged parameters of spec.parameters and spec.parametersFrom are too large")))
+		Expect(err).To(MatchError(ContainSubstring("the limit is 65536 bytes")))
+		// resources accepted before the limits were lowered are reconciled until their spec changes
+		Expect(reconciler.checkParametersLimits(false, large)).To(Succeed())
+
+		nested := strings.Repeat(`{"a": `, 32) + "1" + strings.Repeat("}", 32)
+		_, parametersRaw, err := buildParameters(parametersSources{}, "", nil, raw(nested), "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reconciler.checkParametersLimits(true, parametersRaw)).To(Succeed())
+		_, parametersRaw, err = buildParameters(parametersSources{}, "", nil, raw(`{"b": `+nested+`}`), "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reconciler.checkParametersLimits(true, parametersRaw)).To(MatchError(ContainSubstring("nested 33 levels deep")))
+
+		reconciler.Config.ParametersMaxDepth = 0
+		Expect(reconciler.checkParametersLimits(true, parametersRaw)).To(Succeed())
+	})
+
+})
+
+var _ = Describe("Parameters of secret templates", func() {
+	It("should expose the inline parameters", func() {
+		Expect(templateParameters(&runtime.RawExtension{Raw: []byte(`{"region":"eu10","plan":{"size":2}}`)}, servicesv1.ParametersMergeShallow)).
+			To(Equal(map[string]interface{}{"region": "eu10", "plan": map[string]interface{}{"size": float64(2)}}))
+	})
+
+	It("should not expose JSON patches or invalid parameters", func() {
+		Expect(templateParameters(&runtime.RawExtension{Raw: []byte(`[{"op":"add","path":"/region","value":"eu10"}]`)}, servicesv1.ParametersMergeJSONPatch)).To(BeEmpty())
+		Expect(templateParameters(&runtime.RawExtension{Raw: []byte(`[1, 2]`)}, servicesv1.ParametersMergeShallow)).To(BeEmpty())
+		Expect(templateParameters(nil, servicesv1.ParametersMergeShallow)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// getPhase derives the phase of the resource from its conditions. A blocked resource waits for the user or for other
+// resources and is pending rather than failed, a ready resource with an operation in progress is provisioning.
+func getPhase(object api.SAPBTPResource) servicesv1.Phase {
+	if !object.GetDeletionTimestamp().IsZero() {
+		return servicesv1.PhaseDeleting
+	}
+	lastOpCondition := meta.FindStatusCondition(object.GetConditions(), api.ConditionSucceeded)
+	if lastOpCondition == nil || (lastOpCondition.Status == metav1.ConditionFalse && (lastOpCondition.Reason == Blocked || lastOpCondition.Message == Pending)) {
+		return servicesv1.PhasePending
+	}
+	if isFailed(object) {
+		return servicesv1.PhaseFailed
+	}
+	if isInProgress(object) {
+		return servicesv1.PhaseProvisioning
+	}
+	if object.GetReady() == metav1.ConditionTrue {
+		return servicesv1.PhaseReady
+	}
+	return servicesv1.PhasePending
+}
+
+// setPhase records the phase derived from the conditions in the status of the resource
+func setPhase(object api.SAPBTPResource) {
+	switch resource := object.(type) {
+	case *servicesv1.ServiceInstance:
+		resource.Status.Phase = getPhase(resource)
+	case *servicesv1.ServiceBinding:
+		resource.Status.Phase = getPhase(resource)
+	}
+}
+
+// phaseOutdated checks whether the recorded phase of the resource differs from the one derived from its conditions,
+// e.g. for resources whose status was last written before phases were recorded
+func phaseOutdated(object api.SAPBTPResource) bool {
+	switch resource := object.(type) {
+	case *servicesv1.ServiceInstance:
+		return resource.Status.Phase != getPhase(resource)
+	case *servicesv1.ServiceBinding:
+		return resource.Status.Phase != getPhase(resource)
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("Phase", func() {
+	var instance *servicesv1.ServiceInstance
+	var ctx context.Context
+
+	BeforeEach(func() {
+		instance = &servicesv1.ServiceInstance{ObjectMeta: metav1.ObjectMeta{Name: "my-instance", Namespace: "app1"}}
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+	})
+
+	It("should be pending before the instance is acted on and while it is blocked", func() {
+		Expect(getPhase(instance)).To(Equal(servicesv1.PhasePending))
+		setInProgressConditions(ctx, smClientTypes.CREATE, Pending, instance)
+		Expect(getPhase(instance)).To(Equal(servicesv1.PhasePending))
+		setBlockedCondition(ctx, "waiting", instance)
+		Expect(getPhase(instance)).To(Equal(servicesv1.PhasePending))
+	})
+
+	It("should be provisioning while an operation is in progress", func() {
+		setInProgressConditions(ctx, smClientTypes.CREATE, "", instance)
+		Expect(getPhase(instance)).To(Equal(servicesv1.PhaseProvisioning))
+
+		instance.Status.Ready = metav1.ConditionTrue
+		setInProgressConditions(ctx, smClientTypes.UPDATE, "", instance)
+		Expect(getPhase(instance)).To(Equal(servicesv1.PhaseProvisioning))
+	})
+
+	It("should be ready or failed after the operation", func() {
+		instance.Status.Ready = metav1.ConditionTrue
+		setSuccessConditions(smClientTypes.CREATE, instance)
+		Expect(getPhase(instance)).To(Equal(servicesv1.PhaseReady))
+
+		setFailureConditions(smClientTypes.UPDATE, "update failed", instance)
+		Expect(getPhase(instance)).To(Equal(servicesv1.PhaseFailed))
+	})
+
+	It("should be deleting once the instance is marked for deletion", func() {
+		now := metav1.Now()
+		instance.DeletionTimestamp = &now
+		Expect(getPhase(instance)).To(Equal(servicesv1.PhaseDeleting))
+	})
+
+	It("should record the phase of instances and bindings", func() {
+		binding := &servicesv1.ServiceBinding{Status: servicesv1.ServiceBindingStatus{
+			Ready:      metav1.ConditionTrue,
+			Conditions: []metav1.Condition{{Type: api.ConditionSucceeded, Status: metav1.ConditionTrue, Reason: Created}},
+		}}
+		Expect(phaseOutdated(binding)).To(BeTrue())
+		setPhase(binding)
+		Expect(binding.Status.Phase).To(Equal(servicesv1.PhaseReady))
+		Expect(phaseOutdated(binding)).To(BeFalse())
+	})
+})
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// serviceBindingProvider is the provider entry of the secrets in the serviceBinding layout
+const serviceBindingProvider = "sap-btp"
+
+// serviceBindingAliases are the well-known entries of the Service Binding for Kubernetes specification and the
+// credentials they are copied from when the broker returns them under another name, in order of preference
+var serviceBindingAliases = []struct {
+	entry   string
+	sources []string
+}{
+	{entry: "host", sources: []string{"hostname"}},
+	{entry: "uri", sources: []string{"url"}},
+	{entry: "username", sources: []string{"user"}},
+	{entry: "certificates", sources: []string{"certificate"}},
+	{entry: "private-key", sources: []string{"privateKey", "private_key"}},
+}
+
+// isServiceBindingLayout checks whether the secret of the binding is stored in the layout of the Service Binding for
+// Kubernetes specification
+func isServiceBindingLayout(binding *servicesv1.ServiceBinding) bool {
+	return binding.Spec.SecretFileFormat == servicesv1.SecretFileFormatServiceBinding
+}
+
+// addServiceBindingEntries adds the provider entry and the well-known entries missing in the credentials, the type
+// entry is the offering of the instance, which is part of the instance info. It returns the metadata of the added entries.
+func addServiceBindingEntries(credentialsMap map[string][]byte) []SecretMetadataProperty {
+	var added []SecretMetadataProperty
+	if _, ok := credentialsMap["provider"]; !ok {
+		credentialsMap["provider"] = []byte(serviceBindingProvider)
+		added = append(added, SecretMetadataProperty{Name: "provider", Format: string(TEXT)})
+	}
+	for _, alias := range serviceBindingAliases {
+		if _, ok := credentialsMap[alias.entry]; ok {
+			continue
+		}
+		for _, source := range alias.sources {
+			if value, ok := credentialsMap[source]; ok {
+				credentialsMap[alias.entry] = value
+				added = append(added, SecretMetadataProperty{Name: alias.entry, Format: string(TEXT)})
+				break
+			}
+		}
+	}
+	return added
+}
+
+// provisionedServiceBinding returns the binding of the ProvisionedService status of the binding, the secret of a
+// binding in the serviceBinding layout in its own namespace, which is where the servicebinding.io reconciler reads it
+func provisionedServiceBinding(binding *servicesv1.ServiceBinding, secret *corev1.Secret) *servicesv1.ProvisionedServiceBinding {
+	if !isServiceBindingLayout(binding) || secret.Namespace != binding.Namespace {
+		return nil
+	}
+	return &servicesv1.ProvisionedServiceBinding{Name: secret.Name}
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Provisioned services", func() {
+	var binding *servicesv1.ServiceBinding
+
+	BeforeEach(func() {
+		binding = &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1"},
+			Spec: servicesv1.ServiceBindingSpec{ServiceInstanceName: "my-instance", SecretName: "my-secret",
+				SecretFileFormat: servicesv1.SecretFileFormatServiceBinding},
+		}
+	})
+
+	It("should store the secret in the layout of the Service Binding for Kubernetes specification", func() {
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		instance := &servicesv1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-instance", Namespace: "app1"},
+			Spec:       servicesv1.ServiceInstanceSpec{ExternalName: "my-instance", ServiceOfferingName: "postgresql-db", ServicePlanName: "trial"},
+			Status:     servicesv1.ServiceInstanceStatus{InstanceID: "instance-id"},
+		}
+		reconciler := &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance).Build(),
+			Scheme: testScheme,
+			Log:    ctrl.Log,
+		}}
+		ctx := context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+
+		secret, err := reconciler.createBindingSecret(ctx, binding, json.RawMessage(`{"hostname": "db.example.com", "port": 5432, "uri": "postgres://db.example.com:5432", "url": "https://ignored", "user": "admin", "password": "secret"}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secret.Data).To(HaveKeyWithValue("type", []byte("postgresql-db")))
+		Expect(secret.Data).To(HaveKeyWithValue("provider", []byte("sap-btp")))
+		Expect(secret.Data).To(HaveKeyWithValue("host", []byte("db.example.com")))
+		Expect(secret.Data).To(HaveKeyWithValue("port", []byte("5432")))
+		Expect(secret.Data).To(HaveKeyWithValue("uri", []byte("postgres://db.example.com:5432")))
+		Expect(secret.Data).To(HaveKeyWithValue("username", []byte("admin")))
+		Expect(secret.Data).To(HaveKeyWithValue("password", []byte("secret")))
+		Expect(secret.Data).ToNot(HaveKey("certificates"))
+	})
+
+	It("should reference the secret of the binding in its namespace only", func() {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-secret", Namespace: "app1"}}
+		Expect(provisionedServiceBinding(binding, secret)).To(Equal(&servicesv1.ProvisionedServiceBinding{Name: "my-secret"}))
+
+		secret.Namespace = "binding-secrets"
+		Expect(provisionedServiceBinding(binding, secret)).To(BeNil())
+
+		secret.Namespace = "app1"
+		binding.Spec.SecretFileFormat = servicesv1.SecretFileFormatKeyPerValue
+		Expect(provisionedServiceBinding(binding, secret)).To(BeNil())
+	})
+})
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// retryTrackingRateLimiter wraps the rate limiter of a controller to expose how many resources are retried with a backoff
+// and for how long the oldest of them has been retried, the workqueue metrics of controller-runtime only count the retries
+type retryTrackingRateLimiter struct {
+	workqueue.RateLimiter
+
+	controller string
+	// retryingSince holds the time of the first retry of the items that were not reconciled successfully since
+	retryingSince sync.Map
+}
+
+func newRetryTrackingRateLimiter(controller string, rateLimiter workqueue.RateLimiter) *retryTrackingRateLimiter {
+	r := &retryTrackingRateLimiter{RateLimiter: rateLimiter, controller: controller}
+	labels := prometheus.Labels{"controller": controller}
+	r.register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "sap_btp_operator_retrying_resources",
+		Help:        "Number of resources whose reconcile is retried with a backoff",
+		ConstLabels: labels,
+	}, func() float64 {
+		count, _ := r.stats()
+		return float64(count)
+	}))
+	r.register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "sap_btp_operator_oldest_retry_age_seconds",
+		Help:        "Time since the first retry of the resource that has been retried the longest",
+		ConstLabels: labels,
+	}, func() float64 {
+		_, oldest := r.stats()
+		if oldest.IsZero() {
+			return 0
+		}
+		return time.Since(oldest).Seconds()
+	}))
+	return r
+}
+
+func (r *retryTrackingRateLimiter) When(item interface{}) time.Duration {
+	r.retryingSince.LoadOrStore(item, time.Now())
+	reconcileRetries.WithLabelValues(r.controller).Inc()
+	return r.RateLimiter.When(item)
+}
+
+func (r *retryTrackingRateLimiter) Forget(item interface{}) {
+	r.retryingSince.Delete(item)
+	r.RateLimiter.Forget(item)
+}
+
+// stats returns the number of retried items and the time of the first retry of the oldest one
+func (r *retryTrackingRateLimiter) stats() (int, time.Time) {
+	count := 0
+	var oldest time.Time
+	r.retryingSince.Range(func(_, value interface{}) bool {
+		count++
+		if since := value.(time.Time); oldest.IsZero() || since.Before(oldest) {
+			oldest = since
+		}
+		return true
+	})
+	return count, oldest
+}
+
+func (r *retryTrackingRateLimiter) register(collector prometheus.Collector) {
+	// the metrics of a controller are registered once, e.g. when the reconciler is set up again in tests
+	if err := metrics.Registry.Register(collector); err != nil && !errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// maxRequeueReasonLength limits the requeue reason recorded in the status, the errors of SM may be long
+	maxRequeueReasonLength = 512
+
+	// minRequeueReasonInterval is the minimal delay between two writes of a changed requeue reason, every write
+	// triggers a reconcile of the resource, which must not bypass the backoff of errors that differ on every attempt
+	minRequeueReasonInterval = 10 * time.Second
+)
+
+// withReconcileTelemetry records in the status of the resource when it was last reconciled, when it last called SM and
+// why it was requeued, so that users can tell a resource the operator never looked at from a resource it retries
+// without access to the operator logs. The times are written at most once per TelemetryInterval, the requeue reason
+// when it changes.
+func (r *BaseReconciler) withReconcileTelemetry(newObject func() api.SAPBTPResource, reconciler reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+		result, err := reconciler.Reconcile(ctx, req)
+		if r.Config.TelemetryInterval > 0 {
+			r.recordReconcileTelemetry(ctx, req, newObject(), result, err)
+		}
+		return result, err
+	})
+}
+
+func (r *BaseReconciler) recordReconcileTelemetry(ctx context.Context, req ctrl.Request, object api.SAPBTPResource, result ctrl.Result, reconcileErr error) {
+	if err := r.Client.Get(ctx, req.NamespacedName, object); err != nil {
+		return
+	}
+	lastReconcile, lastReason := getReconcileTelemetry(object)
+	reason := requeueReason(object, result, reconcileErr)
+	now := metav1.Now()
+	if lastReconcile != nil {
+		sinceLast := now.Sub(lastReconcile.Time)
+		if sinceLast < r.Config.TelemetryInterval && (reason == lastReason || sinceLast < minRequeueReasonInterval) {
+			return
+		}
+	}
+
+	base := object.DeepCopyObject().(client.Object)
+	var lastSMRequest *metav1.Time
+	if smRequest, found := r.smRequests.Load(req.NamespacedName); found {
+		requestTime := metav1.NewTime(smRequest.(time.Time))
+		lastSMRequest = &requestTime
+	}
+	setReconcileTelemetry(object, &now, lastSMRequest, reason)
+	if err := r.Client.Status().Patch(ctx, object, client.MergeFrom(base)); err != nil {
+		r.Log.Info("failed to record the reconcile telemetry", "resource", req.NamespacedName, "error", err.Error())
+	}
+}
+
+// requeueReason describes why the resource was requeued, the error of a failed reconcile or the reason of the
+// condition of the last operation, empty if it was not requeued
+func requeueReason(object api.SAPBTPResource, result ctrl.Result, err error) string {
+	reason := ""
+	switch {
+	case err != nil:
+		reason = err.Error()
+	case result.Requeue || result.RequeueAfter > 0:
+		reason = "Requeued"
+		if condition := meta.FindStatusCondition(object.GetConditions(), api.ConditionSucceeded); condition != nil && len(condition.Reason) > 0 {
+			reason = condition.Reason
+		}
+	}
+	if len(reason) > maxRequeueReasonLength {
+		reason = reason[:maxRequeueReasonLength]
+	}
+	return reason
+}
+
+// getReconcileTelemetry returns the recorded time of the last reconcile and the requeue reason of the resource
+func getReconcileTelemetry(object api.SAPBTPResource) (*metav1.Time, string) {
+	switch resource := object.(type) {
+	case *servicesv1.ServiceInstance:
+		return resource.Status.LastReconcileTime, resource.Status.LastRequeueReason
+	case *servicesv1.ServiceBinding:
+		return resource.Status.LastReconcileTime, resource.Status.LastRequeueReason
+	}
+	return nil, ""
+}
+
+// setReconcileTelemetry records the reconcile telemetry in the status of the resource, the time of the last call to
+// SM is kept if the resource did not call SM since the operator started
+func setReconcileTelemetry(object api.SAPBTPResource, lastReconcile, lastSMRequest *metav1.Time, reason string) {
+	switch resource := object.(type) {
+	case *servicesv1.ServiceInstance:
+		resource.Status.LastReconcileTime = lastReconcile
+		resource.Status.LastRequeueReason = reason
+		if lastSMRequest != nil {
+			resource.Status.LastSMRequestTime = lastSMRequest
+		}
+	case *servicesv1.ServiceBinding:
+		resource.Status.LastReconcileTime = lastReconcile
+		resource.Status.LastRequeueReason = reason
+		if lastSMRequest != nil {
+			resource.Status.LastSMRequestTime = lastSMRequest
+		}
+	}
+}
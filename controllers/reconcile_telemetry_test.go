@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Reconcile telemetry", func() {
+	var reconciler *BaseReconciler
+	var instance *servicesv1.ServiceInstance
+	var ctx context.Context
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "app1", Name: "my-instance"}}
+	newInstance := func() api.SAPBTPResource { return &servicesv1.ServiceInstance{} }
+
+	reconcileWith := func(result ctrl.Result, err error) {
+		wrapped := reconciler.withReconcileTelemetry(newInstance, reconcile.Func(func(context.Context, ctrl.Request) (ctrl.Result, error) {
+			return result, err
+		}))
+		_, _ = wrapped.Reconcile(ctx, req)
+	}
+	getStatus := func() servicesv1.ServiceInstanceStatus {
+		updated := &servicesv1.ServiceInstance{}
+		Expect(reconciler.Client.Get(ctx, req.NamespacedName, updated)).To(Succeed())
+		return updated.Status
+	}
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		testScheme := runtime.NewScheme()
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		instance = &servicesv1.ServiceInstance{ObjectMeta: metav1.ObjectMeta{Name: req.Name, Namespace: req.Namespace}}
+		setInProgressConditions(ctx, smClientTypes.CREATE, "", instance)
+		reconciler = &BaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance).WithStatusSubresource(instance).Build(),
+			Scheme: testScheme,
+			Log:    ctrl.Log,
+			Config: config.Config{TelemetryInterval: time.Minute},
+		}
+	})
+
+	It("should record the reconcile, the last SM request and the requeue reason", func() {
+		reconciler.countSMCalls(req.NamespacedName, "app1/sap-btp-service-operator")()
+		reconcileWith(ctrl.Result{RequeueAfter: time.Second}, nil)
+
+		status := getStatus()
+		Expect(status.LastReconcileTime).ToNot(BeNil())
+		Expect(status.LastSMRequestTime).ToNot(BeNil())
+		Expect(status.LastRequeueReason).To(Equal(CreateInProgress))
+	})
+
+	It("should rate limit the updates of an unchanged reason", func() {
+		reconcileWith(ctrl.Result{}, fmt.Errorf("no access"))
+		lastReconcile := getStatus().LastReconcileTime
+		Expect(getStatus().LastRequeueReason).To(Equal("no access"))
+
+		reconcileWith(ctrl.Result{}, fmt.Errorf("no access"))
+		Expect(getStatus().LastReconcileTime).To(Equal(lastReconcile))
+	})
+
+	It("should record a changed reason", func() {
+		lastReconcile := metav1.NewTime(time.Now().Add(-30 * time.Second))
+		instance.Status.LastReconcileTime = &lastReconcile
+		instance.Status.LastRequeueReason = "no access"
+		Expect(reconciler.Client.Status().Update(ctx, instance)).To(Succeed())
+
+		reconcileWith(ctrl.Result{}, nil)
+		status := getStatus()
+		Expect(status.LastRequeueReason).To(BeEmpty())
+		Expect(status.LastReconcileTime.After(lastReconcile.Time)).To(BeTrue())
+	})
+
+	It("should not record the telemetry when it is disabled", func() {
+		reconciler.Config.TelemetryInterval = 0
+		reconcileWith(ctrl.Result{}, fmt.Errorf("no access"))
+		Expect(getStatus().LastReconcileTime).To(BeNil())
+	})
+
+	It("should not fail for a deleted resource", func() {
+		Expect(reconciler.Client.Delete(ctx, instance)).To(Succeed())
+		reconcileWith(ctrl.Result{}, nil)
+		Expect(client.IgnoreNotFound(reconciler.Client.Get(ctx, req.NamespacedName, instance))).To(Succeed())
+	})
+})
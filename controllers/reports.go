@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/dashboard"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// PeriodicReporter publishes the periodic reports of the operator, each at its own interval: the ResourceSummary events of the
+// namespaces, the OperatorSLOReport and the NamespaceBTPStatuses. The reports due at the same time are built from a
+// single snapshot of the instances, the bindings and the operation outcomes. A zero interval disables a report.
+type PeriodicReporter struct {
+	*BaseReconciler
+	SummaryEventInterval    time.Duration
+	SLOReportInterval       time.Duration
+	SLOWindow               time.Duration
+	NamespaceStatusInterval time.Duration
+}
+
+// reportSnapshot is the state the reports are built from
+type reportSnapshot struct {
+	now       time.Time
+	instances []servicesv1.ServiceInstance
+	bindings  []servicesv1.ServiceBinding
+	// outcomes are the operation outcomes within the retention of the tracker, oldest first
+	outcomes []operationOutcome
+}
+
+// outcomesWithin returns the outcomes of the snapshot recorded within the window
+func (s *reportSnapshot) outcomesWithin(window time.Duration) []operationOutcome {
+	first := sort.Search(len(s.outcomes), func(i int) bool {
+		return !s.outcomes[i].at.Before(s.now.Add(-window))
+	})
+	return s.outcomes[first:]
+}
+
+// dueReports are the reports published by a pass
+type dueReports struct {
+	summaryEvents     bool
+	sloReport         bool
+	namespaceStatuses bool
+}
+
+// Start publishes the reports at their intervals. It ticks at the greatest common divisor of the intervals, so that
+// reports whose intervals are multiples of each other are published by the same pass.
+func (r *PeriodicReporter) Start(ctx context.Context) error {
+	ctx = context.WithValue(ctx, LogKey{}, r.Log)
+	if r.SLOReportInterval > 0 {
+		operationOutcomes.extendRetention(r.SLOWindow)
+	}
+	if r.NamespaceStatusInterval > 0 {
+		operationOutcomes.extendRetention(namespaceStatusWindow)
+	}
+	tick := r.tickInterval()
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for ticks := int64(1); ; ticks++ {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.report(ctx, r.due(tick, ticks)); err != nil {
+				r.Log.Error(err, "failed to publish the reports")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection publishes the reports on the leader only, which reconciles the resources
+func (r *PeriodicReporter) NeedLeaderElection() bool {
+	return true
+}
+
+// Report publishes all enabled reports
+func (r *PeriodicReporter) Report(ctx context.Context) error {
+	return r.report(ctx, dueReports{
+		summaryEvents:     r.SummaryEventInterval > 0,
+		sloReport:         r.SLOReportInterval > 0,
+		namespaceStatuses: r.NamespaceStatusInterval > 0,
+	})
+}
+
+// tickInterval returns the greatest common divisor of the enabled intervals
+func (r *PeriodicReporter) tickInterval() time.Duration {
+	var tick time.Duration
+	for _, interval := range []time.Duration{r.SummaryEventInterval, r.SLOReportInterval, r.NamespaceStatusInterval} {
+		if interval <= 0 {
+			continue
+		}
+		for interval > 0 {
+			tick, interval = interval, tick%interval
+		}
+	}
+	return tick
+}
+
+// due returns the reports whose interval elapsed at the tick
+func (r *PeriodicReporter) due(tick time.Duration, ticks int64) dueReports {
+	elapsed := func(interval time.Duration) bool {
+		return interval > 0 && ticks%int64(interval/tick) == 0
+	}
+	return dueReports{
+		summaryEvents:     elapsed(r.SummaryEventInterval),
+		sloReport:         elapsed(r.SLOReportInterval),
+		namespaceStatuses: elapsed(r.NamespaceStatusInterval),
+	}
+}
+
+// report builds the snapshot once and publishes the due reports from it, a failed report does not keep the others from
+// being published
+func (r *PeriodicReporter) report(ctx context.Context, reports dueReports) error {
+	if !reports.summaryEvents && !reports.sloReport && !reports.namespaceStatuses {
+		return nil
+	}
+	snapshot, err := r.takeSnapshot(ctx, reports)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if reports.summaryEvents {
+		dashboard.RecordSummaries(r.Recorder, dashboard.BuildReports(snapshot.instances, snapshot.bindings, snapshot.now.Add(-r.SummaryEventInterval)))
+	}
+	if reports.sloReport {
+		if err := r.publishSLOReport(ctx, snapshot); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if reports.namespaceStatuses {
+		if err := r.publishNamespaceStatuses(ctx, snapshot); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if reports.sloReport || reports.namespaceStatuses {
+		if err := r.saveOperationOutcomes(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// takeSnapshot lists the instances and bindings, with their operations if the namespace statuses are due, and reads the
+// operation outcomes if a report of the outcomes is due
+func (r *PeriodicReporter) takeSnapshot(ctx context.Context, reports dueReports) (*reportSnapshot, error) {
+	instances := &servicesv1.ServiceInstanceList{}
+	if err := r.Client.List(ctx, instances); err != nil {
+		return nil, err
+	}
+	bindings := &servicesv1.ServiceBindingList{}
+	if err := r.Client.List(ctx, bindings); err != nil {
+		return nil, err
+	}
+	if reports.namespaceStatuses {
+		for i := range instances.Items {
+			if err := r.operations().load(ctx, &instances.Items[i]); err != nil {
+				return nil, err
+			}
+		}
+		for i := range bindings.Items {
+			if err := r.operations().load(ctx, &bindings.Items[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	snapshot := &reportSnapshot{now: operationOutcomes.currentTime(), instances: instances.Items, bindings: bindings.Items}
+	if reports.sloReport || reports.namespaceStatuses {
+		if err := r.restoreOperationOutcomes(ctx); err != nil {
+			return nil, err
+		}
+		snapshot.outcomes = operationOutcomes.since(operationOutcomes.retentionPeriod())
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = Describe("Periodic reports", func() {
+	Context("schedule", func() {
+		It("should tick at the greatest common divisor of the enabled intervals", func() {
+			reporter := &PeriodicReporter{SummaryEventInterval: time.Hour, SLOReportInterval: 5 * time.Minute, NamespaceStatusInterval: 2 * time.Minute}
+			Expect(reporter.tickInterval()).To(Equal(time.Minute))
+			reporter.SLOReportInterval = 0
+			Expect(reporter.tickInterval()).To(Equal(2 * time.Minute))
+		})
+
+		It("should publish each report when its interval elapsed", func() {
+			reporter := &PeriodicReporter{SummaryEventInterval: time.Hour, NamespaceStatusInterval: 2 * time.Minute}
+			tick := reporter.tickInterval()
+			Expect(reporter.due(tick, 1)).To(Equal(dueReports{namespaceStatuses: true}))
+			Expect(reporter.due(tick, 29)).To(Equal(dueReports{namespaceStatuses: true}))
+			Expect(reporter.due(tick, 30)).To(Equal(dueReports{summaryEvents: true, namespaceStatuses: true}))
+		})
+	})
+
+	Context("report", func() {
+		var (
+			ctx                   context.Context
+			reporter              *PeriodicReporter
+			lists                 map[string]int
+			savedOutcomes         *operationOutcomeTracker
+			savedNamespaceSMCalls *namespaceSMCallTracker
+		)
+
+		BeforeEach(func() {
+			ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+			savedOutcomes, savedNamespaceSMCalls = operationOutcomes, namespaceSMCalls
+			operationOutcomes = &operationOutcomeTracker{retention: time.Hour}
+			namespaceSMCalls = newNamespaceSMCallTracker()
+
+			testScheme := runtime.NewScheme()
+			Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+			Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+			instance := &servicesv1.ServiceInstance{ObjectMeta: metav1.ObjectMeta{Name: "my-instance", Namespace: "app1"}}
+			binding := &servicesv1.ServiceBinding{ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1"}}
+			lists = map[string]int{}
+			countLists := interceptor.Funcs{List: func(ctx context.Context, client client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				switch list.(type) {
+				case *servicesv1.ServiceInstanceList:
+					lists["instances"]++
+				case *servicesv1.ServiceBindingList:
+					lists["bindings"]++
+				}
+				return client.List(ctx, list, opts...)
+			}}
+			reporter = &PeriodicReporter{
+				BaseReconciler: &BaseReconciler{
+					Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance, binding).WithInterceptorFuncs(countLists).
+						WithStatusSubresource(&servicesv1.OperatorSLOReport{}, &servicesv1.NamespaceBTPStatus{}).Build(),
+					Scheme:   testScheme,
+					Log:      ctrl.Log,
+					Recorder: record.NewFakeRecorder(10),
+					Config:   config.Config{ReleaseNamespace: "sap-btp-operator"},
+				},
+				SummaryEventInterval:    time.Hour,
+				SLOReportInterval:       time.Minute,
+				SLOWindow:               time.Hour,
+				NamespaceStatusInterval: time.Minute,
+			}
+		})
+
+		AfterEach(func() {
+			operationOutcomes, namespaceSMCalls = savedOutcomes, savedNamespaceSMCalls
+		})
+
+		It("should list the instances and bindings once for all reports", func() {
+			Expect(reporter.Report(ctx)).To(Succeed())
+			Expect(lists).To(Equal(map[string]int{"instances": 1, "bindings": 1}))
+			Expect(reporter.Recorder.(*record.FakeRecorder).Events).To(HaveLen(1))
+		})
+
+		It("should not list anything when no report is due", func() {
+			Expect(reporter.report(ctx, dueReports{})).To(Succeed())
+			Expect(lists).To(BeEmpty())
+		})
+	})
+})
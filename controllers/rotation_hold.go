@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// deferRotation defers a credentials rotation which has not started yet while running pods that consume the secret of
+// the binding hold it with the services.cloud.sap.com/hold-rotation annotation, e.g. long-running batch jobs. The
+// rotation waits with the RotationDeferred condition until the holds are released, or at most RotationMaxDefer.
+func (r *ServiceBindingReconciler) deferRotation(ctx context.Context, binding *servicesv1.ServiceBinding) (bool, error) {
+	log := GetLogger(ctx)
+	rotationCondition := meta.FindStatusCondition(binding.Status.Conditions, api.ConditionCredRotationInProgress)
+	if rotationCondition == nil || rotationCondition.Reason == CredRotating || len(binding.Status.BindingID) == 0 {
+		return false, nil
+	}
+
+	holdingPods, err := r.rotationHoldingPods(ctx, binding)
+	if err != nil {
+		return false, err
+	}
+	deferredCondition := meta.FindStatusCondition(binding.Status.Conditions, api.ConditionRotationDeferred)
+	if len(holdingPods) == 0 {
+		if deferredCondition != nil {
+			log.Info("Credentials rotation - the hold of the pods was released, rotating")
+			meta.RemoveStatusCondition(&binding.Status.Conditions, api.ConditionRotationDeferred)
+		}
+		return false, nil
+	}
+	if deferredCondition != nil && r.Config.RotationMaxDefer > 0 && time.Since(deferredCondition.LastTransitionTime.Time) >= r.Config.RotationMaxDefer {
+		message := fmt.Sprintf("rotating the credentials although pods %s hold the rotation, it was deferred for more than %s",
+			strings.Join(holdingPods, ", "), r.Config.RotationMaxDefer)
+		log.Info(fmt.Sprintf("Credentials rotation - %s", message))
+		r.Recorder.Event(binding, corev1.EventTypeWarning, "RotationHoldExpired", message)
+		meta.RemoveStatusCondition(&binding.Status.Conditions, api.ConditionRotationDeferred)
+		return false, nil
+	}
+
+	message := fmt.Sprintf("the credentials rotation is deferred while pods %s hold it with the %s annotation",
+		strings.Join(holdingPods, ", "), api.HoldRotationAnnotation)
+	if deferredCondition == nil || deferredCondition.Message != message {
+		log.Info(fmt.Sprintf("Credentials rotation - %s", message))
+		meta.SetStatusCondition(&binding.Status.Conditions, metav1.Condition{
+			Type:               api.ConditionRotationDeferred,
+			Status:             metav1.ConditionTrue,
+			Reason:             RotationHeld,
+			Message:            message,
+			ObservedGeneration: binding.Generation,
+		})
+		if err := r.updateStatus(ctx, binding); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// rotationHoldCheckInterval is the delay until the holds of a deferred rotation are checked again
+func (r *ServiceBindingReconciler) rotationHoldCheckInterval(binding *servicesv1.ServiceBinding) time.Duration {
+	interval := r.longPollInterval()
+	deferredCondition := meta.FindStatusCondition(binding.Status.Conditions, api.ConditionRotationDeferred)
+	if deferredCondition != nil && r.Config.RotationMaxDefer > 0 {
+		if untilExpiry := time.Until(deferredCondition.LastTransitionTime.Add(r.Config.RotationMaxDefer)); untilExpiry < interval {
+			interval = untilExpiry
+		}
+	}
+	if interval < time.Second {
+		interval = time.Second
+	}
+	return interval
+}
+
+// rotationHoldingPods returns the names of the pods which are not terminated, consume the secret of the binding and
+// hold its rotation. The pods are read from the API server, they are not cached by the operator.
+func (r *ServiceBindingReconciler) rotationHoldingPods(ctx context.Context, binding *servicesv1.ServiceBinding) ([]string, error) {
+	secretKey := r.bindingSecretKey(binding)
+	pods := &corev1.PodList{}
+	if err := r.apiReader().List(ctx, pods, client.InNamespace(secretKey.Namespace)); err != nil {
+		return nil, err
+	}
+	var holdingPods []string
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if hold, ok := pod.Annotations[api.HoldRotationAnnotation]; !ok || strings.EqualFold(hold, "false") {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if podConsumesSecret(pod, secretKey.Name) {
+			holdingPods = append(holdingPods, pod.Name)
+		}
+	}
+	sort.Strings(holdingPods)
+	return holdingPods, nil
+}
+
+// podConsumesSecret returns whether the pod mounts the secret or reads it into the environment of a container
+func podConsumesSecret(pod *corev1.Pod, secretName string) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Secret != nil && volume.Secret.SecretName == secretName {
+			return true
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.Secret != nil && source.Secret.Name == secretName {
+					return true
+				}
+			}
+		}
+	}
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for _, container := range containers {
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.SecretRef != nil && envFrom.SecretRef.Name == secretName {
+				return true
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil && env.ValueFrom.SecretKeyRef.Name == secretName {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Rotation holds", func() {
+	var testScheme *runtime.Scheme
+	var binding *servicesv1.ServiceBinding
+	var recorder *record.FakeRecorder
+	var ctx context.Context
+
+	newReconciler := func(objects ...client.Object) *ServiceBindingReconciler {
+		objects = append(objects, binding)
+		return &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(objects...).WithStatusSubresource(binding).Build(),
+			Scheme:   testScheme,
+			Log:      ctrl.Log,
+			Recorder: recorder,
+			Config:   config.Config{LongPollInterval: 5 * time.Minute, RotationMaxDefer: time.Hour},
+		}}
+	}
+
+	newPod := func(name string, annotations map[string]string, volumes ...corev1.Volume) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "app1", Annotations: annotations},
+			Spec:       corev1.PodSpec{Volumes: volumes, Containers: []corev1.Container{{Name: "app"}}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	secretVolume := corev1.Volume{Name: "creds", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "my-secret"}}}
+	hold := map[string]string{api.HoldRotationAnnotation: "true"}
+
+	BeforeEach(func() {
+		testScheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		recorder = record.NewFakeRecorder(10)
+		binding = &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1"},
+			Spec:       servicesv1.ServiceBindingSpec{SecretName: "my-secret"},
+			Status:     servicesv1.ServiceBindingStatus{BindingID: "1234"},
+		}
+		setCredRotationInProgressConditions(CredPreparing, "", binding)
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+	})
+
+	It("should defer the rotation while pods consuming the secret hold it", func() {
+		envPod := newPod("env-pod", hold)
+		envPod.Spec.Containers[0].EnvFrom = []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "my-secret"}}}}
+		completedPod := newPod("completed-pod", hold, secretVolume)
+		completedPod.Status.Phase = corev1.PodSucceeded
+		reconciler := newReconciler(newPod("volume-pod", hold, secretVolume), envPod, completedPod,
+			newPod("released-pod", map[string]string{api.HoldRotationAnnotation: "false"}, secretVolume),
+			newPod("other-pod", hold))
+
+		deferred, err := reconciler.deferRotation(ctx, binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deferred).To(BeTrue())
+		updated := &servicesv1.ServiceBinding{}
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(binding), updated)).To(Succeed())
+		condition := meta.FindStatusCondition(updated.Status.Conditions, api.ConditionRotationDeferred)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Reason).To(Equal(RotationHeld))
+		Expect(condition.Message).To(ContainSubstring("pods env-pod, volume-pod hold it"))
+		Expect(reconciler.rotationHoldCheckInterval(binding)).To(Equal(5 * time.Minute))
+	})
+
+	It("should rotate once the holds are released", func() {
+		meta.SetStatusCondition(&binding.Status.Conditions, metav1.Condition{Type: api.ConditionRotationDeferred, Status: metav1.ConditionTrue, Reason: RotationHeld})
+		reconciler := newReconciler(newPod("other-pod", hold))
+
+		deferred, err := reconciler.deferRotation(ctx, binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deferred).To(BeFalse())
+		Expect(meta.FindStatusCondition(binding.Status.Conditions, api.ConditionRotationDeferred)).To(BeNil())
+	})
+
+	It("should rotate once the rotation was deferred for the max defer period", func() {
+		meta.SetStatusCondition(&binding.Status.Conditions, metav1.Condition{Type: api.ConditionRotationDeferred, Status: metav1.ConditionTrue, Reason: RotationHeld,
+			LastTransitionTime: metav1.NewTime(time.Now().Add(-2 * time.Hour))})
+		reconciler := newReconciler(newPod("volume-pod", hold, secretVolume))
+
+		deferred, err := reconciler.deferRotation(ctx, binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deferred).To(BeFalse())
+		Expect(meta.FindStatusCondition(binding.Status.Conditions, api.ConditionRotationDeferred)).To(BeNil())
+		Expect(recorder.Events).To(Receive(ContainSubstring("RotationHoldExpired")))
+	})
+
+	It("should not defer a rotation which already started", func() {
+		setCredRotationInProgressConditions(CredRotating, "", binding)
+		reconciler := newReconciler(newPod("volume-pod", hold, secretVolume))
+
+		Expect(reconciler.deferRotation(ctx, binding)).To(BeFalse())
+	})
+})
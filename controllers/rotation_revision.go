@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// maxRotationRevisionAttempts is the number of taken revisions skipped before a rotation gives up
+const maxRotationRevisionAttempts = 10
+
+func rotationSuffix(revision int64) string {
+	return fmt.Sprintf("-r%d", revision)
+}
+
+// nextRotationRevision returns the revision the rotated binding of the current rotation is suffixed with, the revision
+// following the last rotation unless the binding, its secret or the binding in SM of that revision already exist.
+// The binding itself is not a collision in SM, it is renamed already if a previous attempt failed after renaming it.
+func (r *ServiceBindingReconciler) nextRotationRevision(ctx context.Context, smClient sm.Client, binding *servicesv1.ServiceBinding) (int64, error) {
+	log := GetLogger(ctx)
+	first := binding.Status.CredentialsRotationRevision + 1
+	for revision := first; revision < first+maxRotationRevisionAttempts; revision++ {
+		suffix := rotationSuffix(revision)
+		taken, err := r.rotatedNameTaken(ctx, smClient, binding, suffix)
+		if err != nil {
+			return 0, err
+		}
+		if !taken {
+			return revision, nil
+		}
+		log.Info(fmt.Sprintf("Credentials rotation - names with suffix %s are taken, skipping revision %d", suffix, revision))
+	}
+	return 0, fmt.Errorf("names of the rotated binding are taken for revisions %d to %d", first, first+maxRotationRevisionAttempts-1)
+}
+
+func (r *ServiceBindingReconciler) rotatedNameTaken(ctx context.Context, smClient sm.Client, binding *servicesv1.ServiceBinding, suffix string) (bool, error) {
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: binding.Namespace, Name: binding.Name + suffix}, &servicesv1.ServiceBinding{}); err == nil {
+		return true, nil
+	} else if !apierrors.IsNotFound(err) {
+		return false, err
+	}
+
+	if err := r.Client.Get(ctx, r.bindingObjectKey(binding, binding.Spec.SecretName+suffix), &corev1.Secret{}); err == nil {
+		return true, nil
+	} else if !apierrors.IsNotFound(err) {
+		return false, err
+	}
+
+	bindings, err := smClient.ListBindings(&sm.Parameters{
+		FieldQuery: []string{
+			fmt.Sprintf("name eq '%s'", binding.Spec.ExternalName+suffix),
+			fmt.Sprintf("service_instance_id eq '%s'", binding.Status.InstanceID),
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	if bindings != nil {
+		for _, smBinding := range bindings.ServiceBindings {
+			if smBinding.ID != binding.Status.BindingID {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
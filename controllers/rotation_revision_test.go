@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"context"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm/smfakes"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Rotation revisions", func() {
+	var testScheme *runtime.Scheme
+	var smClient *smfakes.FakeClient
+	var binding *servicesv1.ServiceBinding
+	var ctx context.Context
+
+	newReconciler := func(objects ...client.Object) *ServiceBindingReconciler {
+		return &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(objects...).Build(),
+			Scheme: testScheme,
+		}}
+	}
+
+	BeforeEach(func() {
+		testScheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		smClient = &smfakes.FakeClient{}
+		smClient.ListBindingsReturns(&smClientTypes.ServiceBindings{}, nil)
+		binding = &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1"},
+			Spec:       servicesv1.ServiceBindingSpec{SecretName: "my-secret", ExternalName: "my-external-name"},
+			Status:     servicesv1.ServiceBindingStatus{BindingID: "1234", InstanceID: "5678", CredentialsRotationRevision: 2},
+		}
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+	})
+
+	It("should continue after the revision of the last rotation", func() {
+		revision, err := newReconciler().nextRotationRevision(ctx, smClient, binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(revision).To(Equal(int64(3)))
+		Expect(rotationSuffix(revision)).To(Equal("-r3"))
+		params := smClient.ListBindingsArgsForCall(0)
+		Expect(params.FieldQuery).To(ConsistOf("name eq 'my-external-name-r3'", "service_instance_id eq '5678'"))
+	})
+
+	It("should skip revisions whose binding or secret exist in the cluster", func() {
+		reconciler := newReconciler(
+			&servicesv1.ServiceBinding{ObjectMeta: metav1.ObjectMeta{Name: "my-binding-r3", Namespace: "app1"}},
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-secret-r4", Namespace: "app1"}},
+		)
+		revision, err := reconciler.nextRotationRevision(ctx, smClient, binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(revision).To(Equal(int64(5)))
+	})
+
+	It("should skip revisions whose name is taken by another binding in SM", func() {
+		smClient.ListBindingsReturnsOnCall(0, &smClientTypes.ServiceBindings{ServiceBindings: []smClientTypes.ServiceBinding{{ID: "other"}}}, nil)
+		smClient.ListBindingsReturnsOnCall(1, &smClientTypes.ServiceBindings{ServiceBindings: []smClientTypes.ServiceBinding{{ID: "1234"}}}, nil)
+		revision, err := newReconciler().nextRotationRevision(ctx, smClient, binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(revision).To(Equal(int64(4)))
+	})
+
+	It("should fail when all revisions are taken", func() {
+		smClient.ListBindingsReturns(&smClientTypes.ServiceBindings{ServiceBindings: []smClientTypes.ServiceBinding{{ID: "other"}}}, nil)
+		_, err := newReconciler().nextRotationRevision(ctx, smClient, binding)
+		Expect(err).To(MatchError(ContainSubstring("revisions 3 to 12")))
+	})
+})
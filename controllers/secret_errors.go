@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// nonTransientSecretErrorReasons are the API server error reasons which are not resolved by retrying the secret operation,
+// other reasons (timeouts, throttling, conflicts, internal errors of the API server or of admission webhooks) are transient
+var nonTransientSecretErrorReasons = map[metav1.StatusReason]bool{
+	metav1.StatusReasonInvalid:               true,
+	metav1.StatusReasonBadRequest:            true,
+	metav1.StatusReasonForbidden:             true,
+	metav1.StatusReasonMethodNotAllowed:      true,
+	metav1.StatusReasonNotAcceptable:         true,
+	metav1.StatusReasonUnsupportedMediaType:  true,
+	metav1.StatusReasonRequestEntityTooLarge: true,
+}
+
+// isTransientSecretError classifies an error of a secret operation in strict mode.
+// Errors which are not returned by the API server (e.g. a secret name taken by another binding) are not transient.
+// transientMessages are substrings of error messages which are transient regardless of their reason,
+// e.g. denials of flaky admission webhooks.
+func isTransientSecretError(err error, transientMessages []string) bool {
+	var apiStatus apierrors.APIStatus
+	if !errors.As(err, &apiStatus) {
+		return false
+	}
+
+	for _, message := range transientMessages {
+		if len(message) > 0 && strings.Contains(err.Error(), message) {
+			return true
+		}
+	}
+
+	status := apiStatus.Status()
+	switch status.Reason {
+	case metav1.StatusReasonForbidden:
+		// resource quotas may be freed, other forbidden errors require a change of the permissions
+		return strings.Contains(status.Message, "exceeded quota")
+	case metav1.StatusReasonUnknown:
+		// admission webhooks deny requests without a reason, the code tells whether the request itself was rejected
+		return status.Code == 0 || status.Code == http.StatusTooManyRequests || status.Code >= http.StatusInternalServerError
+	default:
+		return !nonTransientSecretErrorReasons[status.Reason]
+	}
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Secret errors", func() {
+	secretsResource := schema.GroupResource{Resource: "secrets"}
+
+	webhookDenial := func(code int32) error {
+		return &apierrors.StatusError{ErrStatus: metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    code,
+			Message: `admission webhook "secrets.example.com" denied the request: policy violation`,
+		}}
+	}
+
+	It("should not retry errors of the operator", func() {
+		Expect(isTransientSecretError(fmt.Errorf(secretNameTakenErrorFormat, "my-secret"), nil)).To(BeFalse())
+	})
+
+	It("should retry timeouts, throttling and internal errors", func() {
+		Expect(isTransientSecretError(apierrors.NewTimeoutError("timeout", 1), nil)).To(BeTrue())
+		Expect(isTransientSecretError(apierrors.NewTooManyRequests("throttled", 1), nil)).To(BeTrue())
+		Expect(isTransientSecretError(apierrors.NewInternalError(fmt.Errorf("failed calling webhook: context deadline exceeded")), nil)).To(BeTrue())
+		Expect(isTransientSecretError(apierrors.NewConflict(secretsResource, "my-secret", fmt.Errorf("conflict")), nil)).To(BeTrue())
+	})
+
+	It("should retry wrapped API server errors", func() {
+		Expect(isTransientSecretError(errors.Wrap(apierrors.NewServiceUnavailable("unavailable"), "failed to store secret"), nil)).To(BeTrue())
+	})
+
+	It("should not retry invalid secrets", func() {
+		Expect(isTransientSecretError(apierrors.NewInvalid(schema.GroupKind{Kind: "Secret"}, "my-secret", nil), nil)).To(BeFalse())
+		Expect(isTransientSecretError(apierrors.NewRequestEntityTooLargeError("too large"), nil)).To(BeFalse())
+	})
+
+	It("should retry exceeded quotas only", func() {
+		Expect(isTransientSecretError(apierrors.NewForbidden(secretsResource, "my-secret", fmt.Errorf("exceeded quota: secrets")), nil)).To(BeTrue())
+		Expect(isTransientSecretError(apierrors.NewForbidden(secretsResource, "my-secret", fmt.Errorf("not allowed")), nil)).To(BeFalse())
+	})
+
+	It("should classify errors without a reason by their code", func() {
+		Expect(isTransientSecretError(webhookDenial(http.StatusForbidden), nil)).To(BeFalse())
+		Expect(isTransientSecretError(webhookDenial(http.StatusGatewayTimeout), nil)).To(BeTrue())
+	})
+
+	It("should retry errors matching the configured messages", func() {
+		Expect(isTransientSecretError(webhookDenial(http.StatusForbidden), []string{"secrets.example.com"})).To(BeTrue())
+	})
+
+	It("should forget the retries of a binding once it is deleted", func() {
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		binding := &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1", UID: "binding-uid", Finalizers: []string{api.FinalizerName}},
+			Spec:       servicesv1.ServiceBindingSpec{SecretName: "my-secret"},
+		}
+		reconciler := &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(binding).Build(),
+			Scheme: testScheme,
+		}}
+		ctx := context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(binding), binding)).To(Succeed())
+		reconciler.secretRetries.Store(binding.UID, 2)
+
+		_, err := reconciler.deleteSecretAndRemoveFinalizer(ctx, binding)
+		Expect(err).ToNot(HaveOccurred())
+		_, found := reconciler.secretRetries.Load(binding.UID)
+		Expect(found).To(BeFalse())
+	})
+})
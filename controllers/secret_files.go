@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// secretFileNames are the keys of the binding secret the single file formats are stored under, unless .Spec.SecretKey is set
+var secretFileNames = map[servicesv1.SecretFileFormat]string{
+	servicesv1.SecretFileFormatJSON:       "credentials.json",
+	servicesv1.SecretFileFormatYAML:       "credentials.yaml",
+	servicesv1.SecretFileFormatDotenv:     ".env",
+	servicesv1.SecretFileFormatProperties: "credentials.properties",
+}
+
+// isSecretFile checks whether the credentials of the binding are stored as a single file in its secret
+func isSecretFile(binding *servicesv1.ServiceBinding) bool {
+	_, ok := secretFileNames[binding.Spec.SecretFileFormat]
+	return ok
+}
+
+// createBindingSecretFile stores the credentials and the service instance info as a single file in the format of
+// .Spec.SecretFileFormat, so that applications read them with their usual configuration libraries
+func (r *ServiceBindingReconciler) createBindingSecretFile(ctx context.Context, k8sBinding *servicesv1.ServiceBinding, credentials json.RawMessage) (*corev1.Secret, error) {
+	values := make(map[string]interface{})
+	if len(credentials) > 0 {
+		if err := json.Unmarshal(credentials, &values); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal given service binding credentials")
+		}
+	}
+
+	// the instance info is added next to the keys of the credentials to detect the conflicts with them
+	instanceInfo := make(map[string][]byte, len(values))
+	for key := range values {
+		instanceInfo[key] = nil
+	}
+	metaDataProperties, conflicts, err := r.addInstanceInfo(ctx, k8sBinding, instanceInfo)
+	if err != nil {
+		GetLogger(ctx).Error(err, "failed to enrich binding with service instance info")
+	}
+	setInstanceInfoConflictCondition(k8sBinding, conflicts)
+	for _, property := range metaDataProperties {
+		var value interface{} = string(instanceInfo[property.Name])
+		if property.Format == string(JSON) {
+			if err := json.Unmarshal(instanceInfo[property.Name], &value); err != nil {
+				return nil, err
+			}
+		}
+		values[property.Name] = value
+	}
+	values = mapSecretValues(k8sBinding, values)
+
+	format := k8sBinding.Spec.SecretFileFormat
+	file, err := encodeSecretFile(format, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store the credentials in format %s: %w", format, err)
+	}
+	fileName := secretFileNames[format]
+	if k8sBinding.Spec.SecretKey != nil {
+		fileName = *k8sBinding.Spec.SecretKey
+	}
+
+	secretKey := r.bindingSecretKey(k8sBinding)
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        secretKey.Name,
+			Annotations: map[string]string{"binding": k8sBinding.Name},
+			Namespace:   secretKey.Namespace,
+		},
+		Data: map[string][]byte{fileName: file},
+	}, nil
+}
+
+func encodeSecretFile(format servicesv1.SecretFileFormat, values map[string]interface{}) ([]byte, error) {
+	switch format {
+	case servicesv1.SecretFileFormatJSON:
+		return json.MarshalIndent(values, "", "  ")
+	case servicesv1.SecretFileFormatYAML:
+		return yaml.Marshal(values)
+	case servicesv1.SecretFileFormatDotenv:
+		return encodeLines(values, func(key, value string) string {
+			return fmt.Sprintf("%s=%s", dotenvKey(key), dotenvValue(value))
+		})
+	case servicesv1.SecretFileFormatProperties:
+		return encodeLines(values, func(key, value string) string {
+			return fmt.Sprintf("%s=%s", escapeProperty(key, true), escapeProperty(value, false))
+		})
+	}
+	return nil, fmt.Errorf("unknown secret file format '%s'", format)
+}
+
+// encodeLines encodes the values line by line in the order of their keys, nested values are encoded as JSON
+func encodeLines(values map[string]interface{}, line func(key, value string) string) ([]byte, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, key := range keys {
+		value, _, err := serialize(values[key])
+		if err != nil {
+			return nil, err
+		}
+		sb.WriteString(line(key, string(value)))
+		sb.WriteString("\n")
+	}
+	return []byte(sb.String()), nil
+}
+
+// dotenvKey turns a credential name into an environment variable name, e.g. client-id into CLIENT_ID
+func dotenvKey(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_':
+			return r
+		}
+		return '_'
+	}, key)
+	if len(name) == 0 || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// dotenvValue quotes a value, escaping the characters that dotenv libraries interpret in double quoted values
+func dotenvValue(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// escapeProperty escapes a key or a value of a Java properties file, characters outside of ISO 8859-1 are escaped as
+// unicode, since properties files are read in that encoding
+func escapeProperty(s string, isKey bool) string {
+	var sb strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			sb.WriteString(`\\`)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r == '\f':
+			sb.WriteString(`\f`)
+		case r == ' ' && (isKey || i == 0):
+			sb.WriteString(`\ `)
+		case (r == '=' || r == ':') && isKey:
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		case (r == '#' || r == '!') && isKey && i == 0:
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		case r > 0xffff:
+			r1, r2 := utf16.EncodeRune(r)
+			sb.WriteString(fmt.Sprintf(`\u%04x\u%04x`, r1, r2))
+		case r < 0x20 || r > 0x7e:
+			sb.WriteString(fmt.Sprintf(`\u%04x`, r))
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
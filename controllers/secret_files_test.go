@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Secret files", func() {
+	values := map[string]interface{}{
+		"client-id": "my-client",
+		"secret":    "p=\"a$$\"\nword",
+		"uaa":       map[string]interface{}{"url": "https://uaa"},
+		"port":      float64(443),
+	}
+
+	It("should encode the credentials as dotenv", func() {
+		file, err := encodeSecretFile(servicesv1.SecretFileFormatDotenv, values)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(file)).To(Equal("CLIENT_ID=\"my-client\"\nPORT=\"443\"\nSECRET=\"p=\\\"a\\$\\$\\\"\\nword\"\nUAA=\"{\\\"url\\\":\\\"https://uaa\\\"}\"\n"))
+	})
+
+	It("should encode the credentials as Java properties", func() {
+		file, err := encodeSecretFile(servicesv1.SecretFileFormatProperties, map[string]interface{}{"a key:1": " välue\n", "#b": "c"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(file)).To(Equal("\\#b=c\na\\ key\\:1=\\ v\\u00e4lue\\n\n"))
+	})
+
+	It("should encode the credentials as JSON and YAML documents", func() {
+		file, err := encodeSecretFile(servicesv1.SecretFileFormatJSON, values)
+		Expect(err).ToNot(HaveOccurred())
+		var decoded map[string]interface{}
+		Expect(json.Unmarshal(file, &decoded)).To(Succeed())
+		Expect(decoded).To(Equal(values))
+
+		file, err = encodeSecretFile(servicesv1.SecretFileFormatYAML, values)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(file)).To(ContainSubstring("uaa:\n  url: https://uaa\n"))
+	})
+
+	It("should store the credentials and the instance info as a single file", func() {
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		instance := &servicesv1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-instance", Namespace: "app1"},
+			Spec:       servicesv1.ServiceInstanceSpec{ExternalName: "my-instance", ServiceOfferingName: "xsuaa", ServicePlanName: "application"},
+			Status:     servicesv1.ServiceInstanceStatus{InstanceID: "instance-id", Tags: []string{"xsuaa"}},
+		}
+		binding := &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1"},
+			Spec:       servicesv1.ServiceBindingSpec{ServiceInstanceName: "my-instance", SecretName: "my-secret", SecretFileFormat: servicesv1.SecretFileFormatDotenv},
+		}
+		reconciler := &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance).Build(),
+			Scheme: testScheme,
+			Log:    ctrl.Log,
+		}}
+		ctx := context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+
+		secret, err := reconciler.createBindingSecret(ctx, binding, json.RawMessage(`{"clientid": "my-client"}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secret.Name).To(Equal("my-secret"))
+		Expect(secret.Data).To(HaveLen(1))
+		Expect(string(secret.Data[".env"])).To(Equal("CLIENTID=\"my-client\"\nINSTANCE_GUID=\"instance-id\"\nINSTANCE_NAME=\"my-instance\"\n" +
+			"LABEL=\"xsuaa\"\nPLAN=\"application\"\nTAGS=\"[\\\"xsuaa\\\"]\"\nTYPE=\"xsuaa\"\n"))
+
+		binding.Spec.SecretFileFormat = servicesv1.SecretFileFormatJSON
+		binding.Spec.SecretKey = &[]string{"vcap.json"}[0]
+		secret, err = reconciler.createBindingSecret(ctx, binding, json.RawMessage(`{"clientid": "my-client"}`))
+		Expect(err).ToNot(HaveOccurred())
+		var decoded map[string]interface{}
+		Expect(json.Unmarshal(secret.Data["vcap.json"], &decoded)).To(Succeed())
+		Expect(decoded).To(HaveKeyWithValue("tags", []interface{}{"xsuaa"}))
+		Expect(decoded).To(HaveKeyWithValue("clientid", "my-client"))
+	})
+})
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+)
+
+// hasSecretKeyMapping checks whether the binding renames or excludes keys of its secret
+func hasSecretKeyMapping(binding *servicesv1.ServiceBinding) bool {
+	return len(binding.Spec.SecretKeyMapping) > 0 || len(binding.Spec.SecretExcludeKeys) > 0
+}
+
+// mappedSecretKey returns the key a credential or service instance info is stored under in the secret of the binding,
+// false if it is excluded or replaced by another key renamed to it
+func mappedSecretKey(binding *servicesv1.ServiceBinding, key string) (string, bool) {
+	for _, excluded := range binding.Spec.SecretExcludeKeys {
+		if key == excluded {
+			return "", false
+		}
+	}
+	if name, ok := binding.Spec.SecretKeyMapping[key]; ok {
+		return name, true
+	}
+	for _, name := range binding.Spec.SecretKeyMapping {
+		if name == key {
+			return "", false
+		}
+	}
+	return key, true
+}
+
+// mapSecretData applies .Spec.SecretExcludeKeys and .Spec.SecretKeyMapping to the data of the secret
+func mapSecretData(binding *servicesv1.ServiceBinding, data map[string][]byte) map[string][]byte {
+	if !hasSecretKeyMapping(binding) {
+		return data
+	}
+	mapped := make(map[string][]byte, len(data))
+	for key, value := range data {
+		if name, ok := mappedSecretKey(binding, key); ok {
+			mapped[name] = value
+		}
+	}
+	return mapped
+}
+
+// mapSecretValues applies .Spec.SecretExcludeKeys and .Spec.SecretKeyMapping to the top-level entries of a secret file
+func mapSecretValues(binding *servicesv1.ServiceBinding, values map[string]interface{}) map[string]interface{} {
+	if !hasSecretKeyMapping(binding) {
+		return values
+	}
+	mapped := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if name, ok := mappedSecretKey(binding, key); ok {
+			mapped[name] = value
+		}
+	}
+	return mapped
+}
+
+// mapSecretMetadata renames the properties of the .metadata entry of the secret like their keys
+func mapSecretMetadata(binding *servicesv1.ServiceBinding, properties []SecretMetadataProperty) []SecretMetadataProperty {
+	if !hasSecretKeyMapping(binding) {
+		return properties
+	}
+	mapped := make([]SecretMetadataProperty, 0, len(properties))
+	for _, property := range properties {
+		if name, ok := mappedSecretKey(binding, property.Name); ok {
+			property.Name = name
+			mapped = append(mapped, property)
+		}
+	}
+	return mapped
+}
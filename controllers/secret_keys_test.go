@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Secret key mapping", func() {
+	var reconciler *ServiceBindingReconciler
+	var binding *servicesv1.ServiceBinding
+	var ctx context.Context
+	credentials := json.RawMessage(`{"uri": "postgres://db.example.com:5432", "DATABASE_URL": "ignored", "password": "secret", "certificate": "-----BEGIN CERTIFICATE-----"}`)
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		instance := &servicesv1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-instance", Namespace: "app1"},
+			Spec:       servicesv1.ServiceInstanceSpec{ExternalName: "my-instance", ServiceOfferingName: "postgresql-db", ServicePlanName: "trial"},
+			Status:     servicesv1.ServiceInstanceStatus{InstanceID: "instance-id"},
+		}
+		reconciler = &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance).Build(),
+			Scheme: testScheme,
+			Log:    ctrl.Log,
+		}}
+		binding = &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1"},
+			Spec: servicesv1.ServiceBindingSpec{ServiceInstanceName: "my-instance", SecretName: "my-secret",
+				SecretKeyMapping:  map[string]string{"uri": "DATABASE_URL", "plan": "PLAN"},
+				SecretExcludeKeys: []string{"certificate"}},
+		}
+	})
+
+	It("should rename and exclude the keys of the secret", func() {
+		secret, err := reconciler.createBindingSecret(ctx, binding, credentials)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secret.Data).To(HaveKeyWithValue("DATABASE_URL", []byte("postgres://db.example.com:5432")))
+		Expect(secret.Data).To(HaveKeyWithValue("PLAN", []byte("trial")))
+		Expect(secret.Data).To(HaveKeyWithValue("password", []byte("secret")))
+		Expect(secret.Data).ToNot(HaveKey("uri"))
+		Expect(secret.Data).ToNot(HaveKey("plan"))
+		Expect(secret.Data).ToNot(HaveKey("certificate"))
+
+		metadata := map[string][]SecretMetadataProperty{}
+		Expect(json.Unmarshal(secret.Data[".metadata"], &metadata)).To(Succeed())
+		Expect(metadata["credentialProperties"]).To(ConsistOf(
+			SecretMetadataProperty{Name: "DATABASE_URL", Format: string(TEXT)},
+			SecretMetadataProperty{Name: "password", Format: string(TEXT)},
+		))
+		Expect(metadata["metaDataProperties"]).To(ContainElement(SecretMetadataProperty{Name: "PLAN", Format: string(TEXT)}))
+	})
+
+	It("should rename and exclude the entries of a secret file", func() {
+		binding.Spec.SecretFileFormat = servicesv1.SecretFileFormatJSON
+		secret, err := reconciler.createBindingSecret(ctx, binding, credentials)
+		Expect(err).ToNot(HaveOccurred())
+		values := map[string]interface{}{}
+		Expect(json.Unmarshal(secret.Data["credentials.json"], &values)).To(Succeed())
+		Expect(values).To(HaveKeyWithValue("DATABASE_URL", "postgres://db.example.com:5432"))
+		Expect(values).To(HaveKeyWithValue("PLAN", "trial"))
+		Expect(values).ToNot(HaveKey("uri"))
+		Expect(values).ToNot(HaveKey("certificate"))
+	})
+})
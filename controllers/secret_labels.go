@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// setSecretTypeAndLabels applies .Spec.SecretType, .Spec.SecretLabels and .Spec.SecretAnnotations to the binding secret,
+// the labels and annotations take precedence over those generated by a secret template. Untyped secrets holding a
+// docker config are of type kubernetes.io/dockerconfigjson.
+func setSecretTypeAndLabels(binding *servicesv1.ServiceBinding, secret *corev1.Secret) {
+	if len(binding.Spec.SecretType) > 0 {
+		secret.Type = binding.Spec.SecretType
+	} else if len(secret.Type) == 0 && len(secret.Data[corev1.DockerConfigJsonKey]) > 0 {
+		// the docker config of the dockerconfigjson format makes the secret usable as image pull secret
+		secret.Type = corev1.SecretTypeDockerConfigJson
+	}
+	mergeSecretLabels(binding, secret)
+}
+
+// mergeSecretLabels adds .Spec.SecretLabels and .Spec.SecretAnnotations to the secret, keeping the labels and
+// annotations set by other actors
+func mergeSecretLabels(binding *servicesv1.ServiceBinding, secret *corev1.Secret) {
+	if len(binding.Spec.SecretLabels) > 0 {
+		labels := secret.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		for key, value := range binding.Spec.SecretLabels {
+			labels[key] = value
+		}
+		secret.SetLabels(labels)
+	}
+	if len(binding.Spec.SecretAnnotations) > 0 {
+		annotations := secret.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		for key, value := range binding.Spec.SecretAnnotations {
+			annotations[key] = value
+		}
+		secret.SetAnnotations(annotations)
+	}
+}
+
+// secretTypeChanged reports whether the existing secret has to be recreated since the type of a secret is immutable
+func secretTypeChanged(dbSecret, secret *corev1.Secret) bool {
+	desiredType := secret.Type
+	if len(desiredType) == 0 {
+		desiredType = corev1.SecretTypeOpaque
+	}
+	existingType := dbSecret.Type
+	if len(existingType) == 0 {
+		existingType = corev1.SecretTypeOpaque
+	}
+	return desiredType != existingType
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Secret type and labels", func() {
+	var reconciler *ServiceBindingReconciler
+	var binding *servicesv1.ServiceBinding
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		existing := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-secret", Namespace: "app1", Labels: map[string]string{"team": "payments"}},
+			Data:       map[string][]byte{"username": []byte("old")},
+		}
+		reconciler = &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(existing).Build(),
+			Scheme:   testScheme,
+			Log:      ctrl.Log,
+			Recorder: record.NewFakeRecorder(10),
+		}}
+		binding = &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1", UID: "binding-uid"},
+			Spec: servicesv1.ServiceBindingSpec{SecretName: "my-secret",
+				SecretLabels:      map[string]string{"app.kubernetes.io/part-of": "my-app"},
+				SecretAnnotations: map[string]string{"reloader.stakater.com/match": "true"}},
+		}
+	})
+
+	It("should add the labels and annotations to an existing secret", func() {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-secret", Namespace: "app1"},
+			Data: map[string][]byte{"username": []byte("user"), "password": []byte("pass")}}
+		setSecretTypeAndLabels(binding, secret)
+		Expect(reconciler.createOrUpdateBindingSecret(ctx, binding, secret)).To(Succeed())
+
+		dbSecret := &corev1.Secret{}
+		Expect(reconciler.Client.Get(ctx, types.NamespacedName{Name: "my-secret", Namespace: "app1"}, dbSecret)).To(Succeed())
+		Expect(dbSecret.Labels).To(HaveKeyWithValue("team", "payments"))
+		Expect(dbSecret.Labels).To(HaveKeyWithValue("app.kubernetes.io/part-of", "my-app"))
+		Expect(dbSecret.Annotations).To(HaveKeyWithValue("reloader.stakater.com/match", "true"))
+	})
+
+	It("should set the owner and the binding of an existing secret", func() {
+		controller := true
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-secret", Namespace: "app1",
+			Annotations:     map[string]string{api.SecretBindingUIDAnnotation: "binding-uid"},
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "my-app", UID: "app-uid", Controller: &controller}}},
+			Data: map[string][]byte{"username": []byte("old")}}
+		Expect(reconciler.createOrUpdateBindingSecret(ctx, binding, secret)).To(Succeed())
+
+		dbSecret := &corev1.Secret{}
+		Expect(reconciler.Client.Get(ctx, types.NamespacedName{Name: "my-secret", Namespace: "app1"}, dbSecret)).To(Succeed())
+		Expect(dbSecret.OwnerReferences).To(HaveLen(1))
+		Expect(dbSecret.OwnerReferences[0].Name).To(Equal("my-app"))
+		Expect(dbSecret.Annotations).To(HaveKeyWithValue(api.SecretBindingUIDAnnotation, "binding-uid"))
+	})
+
+	It("should recreate the secret when its type changes", func() {
+		binding.Spec.SecretType = corev1.SecretTypeBasicAuth
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-secret", Namespace: "app1"},
+			Data: map[string][]byte{"username": []byte("user"), "password": []byte("pass")}}
+		setSecretTypeAndLabels(binding, secret)
+		Expect(reconciler.createOrUpdateBindingSecret(ctx, binding, secret)).To(Succeed())
+
+		dbSecret := &corev1.Secret{}
+		Expect(reconciler.Client.Get(ctx, types.NamespacedName{Name: "my-secret", Namespace: "app1"}, dbSecret)).To(Succeed())
+		Expect(dbSecret.Type).To(Equal(corev1.SecretTypeBasicAuth))
+		Expect(dbSecret.Data).To(HaveKeyWithValue("username", []byte("user")))
+		Expect(dbSecret.Labels).To(HaveKeyWithValue("app.kubernetes.io/part-of", "my-app"))
+	})
+
+	It("should recreate immutable secrets when their data changes", func() {
+		immutable := true
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-secret", Namespace: "app1"},
+			Data: map[string][]byte{"username": []byte("user")}, Immutable: &immutable}
+		Expect(reconciler.createOrUpdateBindingSecret(ctx, binding, secret.DeepCopy())).To(Succeed())
+
+		dbSecret := &corev1.Secret{}
+		Expect(reconciler.Client.Get(ctx, types.NamespacedName{Name: "my-secret", Namespace: "app1"}, dbSecret)).To(Succeed())
+		Expect(isSecretImmutable(dbSecret)).To(BeTrue())
+		Expect(reconciler.createOrUpdateBindingSecret(ctx, binding, secret.DeepCopy())).To(Succeed())
+
+		secret.Data = map[string][]byte{"username": []byte("rotated")}
+		Expect(reconciler.createOrUpdateBindingSecret(ctx, binding, secret.DeepCopy())).To(Succeed())
+		Expect(reconciler.Client.Get(ctx, types.NamespacedName{Name: "my-secret", Namespace: "app1"}, dbSecret)).To(Succeed())
+		Expect(isSecretImmutable(dbSecret)).To(BeTrue())
+		Expect(dbSecret.Data).To(HaveKeyWithValue("username", []byte("rotated")))
+
+		secret.Immutable = nil
+		Expect(reconciler.createOrUpdateBindingSecret(ctx, binding, secret.DeepCopy())).To(Succeed())
+		Expect(reconciler.Client.Get(ctx, types.NamespacedName{Name: "my-secret", Namespace: "app1"}, dbSecret)).To(Succeed())
+		Expect(isSecretImmutable(dbSecret)).To(BeFalse())
+	})
+
+	It("should type secrets holding a docker config as image pull secrets", func() {
+		secret := &corev1.Secret{Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths": {}}`)}}
+		setSecretTypeAndLabels(binding, secret)
+		Expect(secret.Type).To(Equal(corev1.SecretTypeDockerConfigJson))
+
+		secret = &corev1.Secret{Type: corev1.SecretTypeOpaque, Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths": {}}`)}}
+		setSecretTypeAndLabels(binding, secret)
+		Expect(secret.Type).To(Equal(corev1.SecretTypeOpaque))
+	})
+})
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/secrets/template"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const secretOutputNameTakenErrorFormat = "the specified secret name '%s' of spec.secrets is already taken. Choose another name and try again"
+
+// createOrUpdateBindingSecretOutputs stores the additional secrets of spec.secrets and deletes the ones no longer requested.
+// They are owned by the binding like its TLS secret.
+func (r *ServiceBindingReconciler) createOrUpdateBindingSecretOutputs(ctx context.Context, binding *servicesv1.ServiceBinding, credentials json.RawMessage) error {
+	for _, output := range binding.Spec.Secrets {
+		secret, err := r.secretOutput(ctx, binding, output, credentials)
+		if err != nil {
+			return err
+		}
+		taken, err := r.writeGeneratedBindingSecret(ctx, binding, secret)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return fmt.Errorf(secretOutputNameTakenErrorFormat, output.Name)
+		}
+	}
+	return r.pruneGeneratedBindingSecrets(ctx, binding, r.generatedSecretNames(binding))
+}
+
+// secretOutput returns the additional secret with the credentials selected by the output, or generated by its template
+func (r *ServiceBindingReconciler) secretOutput(ctx context.Context, binding *servicesv1.ServiceBinding, output servicesv1.SecretOutput, credentials json.RawMessage) (*corev1.Secret, error) {
+	var secret *corev1.Secret
+	if len(output.Template) > 0 {
+		parameters, err := r.secretTemplateParameters(ctx, binding, credentials)
+		if err != nil {
+			return nil, err
+		}
+		templateName := fmt.Sprintf("%s/%s/%s", binding.Namespace, binding.Name, output.Name)
+		if secret, err = template.CreateSecretFromTemplate(templateName, output.Template, parameters); err != nil {
+			return nil, errors.Wrapf(err, "failed to create secret '%s' from template", output.Name)
+		}
+		secret.Data = desiredSecretData(secret)
+		secret.StringData = nil
+	} else {
+		data, err := secretOutputData(output, credentials)
+		if err != nil {
+			return nil, err
+		}
+		secret = &corev1.Secret{Type: output.Type, Data: data}
+	}
+
+	key := r.bindingObjectKey(binding, output.Name)
+	secret.SetNamespace(key.Namespace)
+	secret.SetName(key.Name)
+	if len(secret.Type) == 0 {
+		secret.Type = corev1.SecretTypeOpaque
+	}
+	return secret, nil
+}
+
+// secretOutputData returns the credentials whose keys start with the key prefix, without the prefix, and the included ones
+func secretOutputData(output servicesv1.SecretOutput, credentials json.RawMessage) (map[string][]byte, error) {
+	data := make(map[string][]byte)
+	if len(credentials) == 0 {
+		return data, nil
+	}
+	credentialsMap, _, err := normalizeCredentials(credentials)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal given service binding credentials")
+	}
+	if len(output.KeyPrefix) > 0 {
+		for key, value := range credentialsMap {
+			if strings.HasPrefix(key, output.KeyPrefix) && len(key) > len(output.KeyPrefix) {
+				data[strings.TrimPrefix(key, output.KeyPrefix)] = value
+			}
+		}
+	}
+	for _, key := range output.IncludeKeys {
+		value, ok := credentialsMap[key]
+		if !ok {
+			return nil, fmt.Errorf("the binding credentials have no '%s' credential for the secret '%s'", key, output.Name)
+		}
+		data[key] = value
+	}
+	return data, nil
+}
+
+// generatedSecretNames returns the names of the secrets written alongside the binding secret, i.e. its TLS secret and
+// its additional secrets
+func (r *ServiceBindingReconciler) generatedSecretNames(binding *servicesv1.ServiceBinding) []string {
+	var names []string
+	if binding.Spec.TLSSecret != nil && len(binding.Spec.TLSSecret.Name) > 0 {
+		names = append(names, r.bindingObjectKey(binding, binding.Spec.TLSSecret.Name).Name)
+	}
+	for _, output := range binding.Spec.Secrets {
+		names = append(names, r.bindingObjectKey(binding, output.Name).Name)
+	}
+	return names
+}
+
+// writeGeneratedBindingSecret creates or updates a secret written alongside the binding secret, labeled with the UID of
+// the binding so that it is pruned when no longer requested. It returns true, without writing the secret, if a secret
+// with its name is not written for the binding.
+func (r *ServiceBindingReconciler) writeGeneratedBindingSecret(ctx context.Context, binding *servicesv1.ServiceBinding, secret *corev1.Secret) (bool, error) {
+	log := GetLogger(ctx)
+	labels := secret.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[api.BindingUIDLabel] = string(binding.UID)
+	secret.SetLabels(labels)
+	annotations := secret.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations["binding"] = binding.Name
+	secret.SetAnnotations(annotations)
+	if len(r.Config.BindingSecretsNamespace) > 0 {
+		annotateSecretBinding(binding, secret)
+	} else if err := controllerutil.SetControllerReference(binding, secret, r.Scheme); err != nil {
+		return false, err
+	}
+
+	dbSecret, err := r.getSecret(ctx, secret.Namespace, secret.Name)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+		log.Info("Creating generated binding secret", "name", secret.Name)
+		return false, r.Client.Create(ctx, secret)
+	}
+
+	if !isGeneratedBindingSecret(binding, dbSecret) {
+		return true, nil
+	}
+	if dbSecret.Type != secret.Type {
+		// the type of a secret is immutable
+		if err := r.Client.Delete(ctx, dbSecret); client.IgnoreNotFound(err) != nil {
+			return false, err
+		}
+		log.Info("Recreating generated binding secret", "name", secret.Name)
+		return false, r.Client.Create(ctx, secret)
+	}
+	if !secretChanged(dbSecret, secret) {
+		log.Info("generated binding secret is up to date, skipping the update", "name", secret.Name)
+		return false, nil
+	}
+	log.Info("Updating existing generated binding secret", "name", secret.Name)
+	dbSecret = dbSecret.DeepCopy()
+	dbSecret.OwnerReferences = secret.OwnerReferences
+	dbSecret.Labels = secret.Labels
+	dbSecret.Annotations = secret.Annotations
+	dbSecret.Data = secret.Data
+	return false, r.Client.Update(ctx, dbSecret)
+}
+
+// isGeneratedBindingSecret returns true if the secret is written for the binding alongside the binding secret
+func isGeneratedBindingSecret(binding *servicesv1.ServiceBinding, secret *corev1.Secret) bool {
+	if secret.Labels[api.BindingUIDLabel] != string(binding.UID) {
+		return false
+	}
+	return metav1.IsControlledBy(secret, binding) || secret.Annotations[api.SecretBindingUIDAnnotation] == string(binding.UID)
+}
+
+// pruneGeneratedBindingSecrets deletes the secrets written for the binding alongside the binding secret except the ones to keep
+func (r *ServiceBindingReconciler) pruneGeneratedBindingSecrets(ctx context.Context, binding *servicesv1.ServiceBinding, keep []string) error {
+	kept := make(map[string]bool, len(keep))
+	for _, name := range keep {
+		kept[name] = true
+	}
+	secrets := &corev1.SecretList{}
+	if err := r.Client.List(ctx, secrets,
+		client.InNamespace(r.bindingSecretKey(binding).Namespace),
+		client.MatchingLabels{api.BindingUIDLabel: string(binding.UID)}); err != nil {
+		return err
+	}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if kept[secret.Name] || !isGeneratedBindingSecret(binding, secret) {
+			continue
+		}
+		GetLogger(ctx).Info("Deleting generated binding secret which is not requested anymore", "name", secret.Name)
+		if err := r.Client.Delete(ctx, secret); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Secret outputs", func() {
+	var reconciler *ServiceBindingReconciler
+	var binding *servicesv1.ServiceBinding
+	var ctx context.Context
+	credentials := []byte(`{"certificate": "cert-pem", "key": "key-pem", "config_url": "https://example.com", "config_timeout": 30, "user": "my-user"}`)
+
+	getSecret := func(name string) (*corev1.Secret, error) {
+		secret := &corev1.Secret{}
+		err := reconciler.Client.Get(ctx, types.NamespacedName{Name: name, Namespace: "app1"}, secret)
+		return secret, err
+	}
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		instance := &servicesv1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-instance", Namespace: "app1"},
+			Spec:       servicesv1.ServiceInstanceSpec{ServiceOfferingName: "my-offering", ServicePlanName: "my-plan"},
+		}
+		reconciler = &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance).Build(),
+			Scheme:   testScheme,
+			Log:      ctrl.Log,
+			Recorder: record.NewFakeRecorder(10),
+		}}
+		binding = &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1", UID: "binding-uid"},
+			Spec: servicesv1.ServiceBindingSpec{
+				ServiceInstanceName: "my-instance",
+				SecretName:          "my-secret",
+				TLSSecret:           &servicesv1.TLSSecret{Name: "my-tls"},
+				Secrets: []servicesv1.SecretOutput{
+					{Name: "app-config", KeyPrefix: "config_", IncludeKeys: []string{"user"}},
+					{Name: "basic-auth", Template: "apiVersion: v1\nkind: Secret\ntype: kubernetes.io/basic-auth\n" +
+						"stringData:\n  username: {{ .smBindingCredentials.user }}\n  password: {{ .serviceInstanceInfos.plan }}\n"},
+				},
+			},
+		}
+	})
+
+	It("should populate the secrets with the selected credentials and the rendered templates", func() {
+		Expect(reconciler.createOrUpdateBindingSecretOutputs(ctx, binding, credentials)).To(Succeed())
+		appConfig, err := getSecret("app-config")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(appConfig.Type).To(Equal(corev1.SecretTypeOpaque))
+		Expect(appConfig.Data).To(Equal(map[string][]byte{"url": []byte("https://example.com"), "timeout": []byte("30"), "user": []byte("my-user")}))
+		Expect(appConfig.Labels).To(HaveKeyWithValue(api.BindingUIDLabel, "binding-uid"))
+		Expect(metav1.IsControlledBy(appConfig, binding)).To(BeTrue())
+
+		basicAuth, err := getSecret("basic-auth")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(basicAuth.Type).To(Equal(corev1.SecretTypeBasicAuth))
+		Expect(basicAuth.Data).To(Equal(map[string][]byte{"username": []byte("my-user"), "password": []byte("my-plan")}))
+	})
+
+	It("should keep the TLS secret and delete the secrets no longer requested", func() {
+		Expect(reconciler.createOrUpdateBindingTLSSecret(ctx, binding, credentials)).To(Succeed())
+		Expect(reconciler.createOrUpdateBindingSecretOutputs(ctx, binding, credentials)).To(Succeed())
+
+		binding.Spec.Secrets = binding.Spec.Secrets[:1]
+		binding.Spec.Secrets[0].Type = corev1.SecretTypeBasicAuth
+		Expect(reconciler.createOrUpdateBindingSecretOutputs(ctx, binding, credentials)).To(Succeed())
+		_, err := getSecret("basic-auth")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		_, err = getSecret("my-tls")
+		Expect(err).ToNot(HaveOccurred())
+		appConfig, err := getSecret("app-config")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(appConfig.Type).To(Equal(corev1.SecretTypeBasicAuth))
+
+		Expect(reconciler.pruneGeneratedBindingSecrets(ctx, binding, nil)).To(Succeed())
+		_, err = getSecret("app-config")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		_, err = getSecret("my-tls")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should fail if a credential is missing or the secret belongs to another owner", func() {
+		binding.Spec.Secrets = []servicesv1.SecretOutput{{Name: "app-config", IncludeKeys: []string{"password"}}}
+		err := reconciler.createOrUpdateBindingSecretOutputs(ctx, binding, credentials)
+		Expect(err).To(MatchError(ContainSubstring("no 'password' credential for the secret 'app-config'")))
+
+		Expect(reconciler.Client.Create(ctx, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "app1"}})).To(Succeed())
+		binding.Spec.Secrets = []servicesv1.SecretOutput{{Name: "app-config", KeyPrefix: "config_"}}
+		err = reconciler.createOrUpdateBindingSecretOutputs(ctx, binding, credentials)
+		Expect(err).To(MatchError(ContainSubstring("the specified secret name 'app-config' of spec.secrets is already taken")))
+	})
+})
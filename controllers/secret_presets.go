@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	"github.com/SAP/sap-btp-service-operator/internal/secrets/formatter"
+	"github.com/SAP/sap-btp-service-operator/internal/secrets/template"
+)
+
+// ValidateSecretPresets rejects secret presets with unknown formats or templates that cannot be parsed on startup,
+// they would otherwise fail the secrets of all bindings of the offering
+func ValidateSecretPresets(presets config.SecretPresets) error {
+	offerings := make([]string, 0, len(presets))
+	for offering := range presets {
+		offerings = append(offerings, offering)
+	}
+	sort.Strings(offerings)
+	for _, offering := range offerings {
+		preset := presets[offering]
+		if !formatter.IsValid(preset.SecretFormat) {
+			return fmt.Errorf("invalid secret preset of offering '%s': unknown secret format '%s', supported formats are %v", offering, preset.SecretFormat, append(formatter.Names(), formatter.None))
+		}
+		if _, err := template.ParseTemplate(offering, preset.SecretTemplate, template.Delimiters{}); err != nil {
+			return fmt.Errorf("invalid secret preset of offering '%s': %w", offering, err)
+		}
+	}
+	return nil
+}
+
+// secretTemplateOf returns the secret template of the binding, or the template preset for the offering of its instance
+// if the binding does not shape its secret with a template, a secret key, a root key, a secret file format or a key mapping
+func (r *ServiceBindingReconciler) secretTemplateOf(ctx context.Context, binding *servicesv1.ServiceBinding) (string, error) {
+	if len(binding.Spec.SecretTemplate) > 0 || binding.Spec.SecretKey != nil || binding.Spec.SecretRootKey != nil || hasSecretKeyMapping(binding) || len(r.Config.SecretPresets) == 0 ||
+		(len(binding.Spec.SecretFileFormat) > 0 && binding.Spec.SecretFileFormat != servicesv1.SecretFileFormatKeyPerValue) {
+		return binding.Spec.SecretTemplate, nil
+	}
+	instance, err := r.getServiceInstanceForBinding(ctx, binding)
+	if err != nil {
+		return "", err
+	}
+	return r.Config.SecretPresets.For(instance.Spec.ServiceOfferingName).SecretTemplate, nil
+}
+
+// secretFormatOf returns the secret format of the binding, or the format preset for the offering if the binding has none
+func (r *ServiceBindingReconciler) secretFormatOf(binding *servicesv1.ServiceBinding, offering string) string {
+	if len(binding.Spec.SecretFormat) > 0 {
+		return binding.Spec.SecretFormat
+	}
+	return r.Config.SecretPresets.For(offering).SecretFormat
+}
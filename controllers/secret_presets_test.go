@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Secret presets", func() {
+	const presetTemplate = `{{ .smBindingCredentials.clientid }}`
+	var reconciler *ServiceBindingReconciler
+	var binding *servicesv1.ServiceBinding
+	var ctx context.Context
+
+	BeforeEach(func() {
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		instance := &servicesv1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-instance", Namespace: "app1"},
+			Spec:       servicesv1.ServiceInstanceSpec{ServiceOfferingName: "xsuaa", ServicePlanName: "application"},
+		}
+		binding = &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1"},
+			Spec:       servicesv1.ServiceBindingSpec{ServiceInstanceName: "my-instance", SecretName: "my-secret"},
+		}
+		reconciler = &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance, binding).Build(),
+			Scheme: testScheme,
+			Config: config.Config{SecretPresets: config.SecretPresets{"xsuaa": {SecretFormat: "xsuaa", SecretTemplate: presetTemplate}}},
+		}}
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+	})
+
+	It("should apply the preset template of the offering to bindings without one", func() {
+		secretTemplate, err := reconciler.secretTemplateOf(ctx, binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secretTemplate).To(Equal(presetTemplate))
+	})
+
+	It("should keep the shape requested by the binding", func() {
+		binding.Spec.SecretTemplate = `{{ .smBindingCredentials.url }}`
+		Expect(reconciler.secretTemplateOf(ctx, binding)).To(Equal(binding.Spec.SecretTemplate))
+
+		binding.Spec.SecretTemplate = ""
+		binding.Spec.SecretKey = pointer.String("credentials")
+		Expect(reconciler.secretTemplateOf(ctx, binding)).To(BeEmpty())
+	})
+
+	It("should apply the preset format of the offering to bindings without one", func() {
+		Expect(reconciler.secretFormatOf(binding, "xsuaa")).To(Equal("xsuaa"))
+		Expect(reconciler.secretFormatOf(binding, "hana")).To(BeEmpty())
+		binding.Spec.SecretFormat = "none"
+		Expect(reconciler.secretFormatOf(binding, "xsuaa")).To(Equal("none"))
+	})
+
+	It("should reject invalid presets", func() {
+		Expect(ValidateSecretPresets(config.SecretPresets{"xsuaa": {SecretFormat: "xsuaa", SecretTemplate: presetTemplate}})).To(Succeed())
+		Expect(ValidateSecretPresets(config.SecretPresets{"hana": {SecretFormat: "unknown"}})).To(MatchError(ContainSubstring("unknown secret format 'unknown'")))
+		Expect(ValidateSecretPresets(config.SecretPresets{"hana": {SecretTemplate: "{{ .smBindingCredentials"}})).To(MatchError(ContainSubstring("invalid secret preset of offering 'hana'")))
+	})
+})
@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SecretReflectionSkipped is the reason of the events of bindings whose secret is not replicated to a target namespace
+const SecretReflectionSkipped = "SecretReflectionSkipped"
+
+// syncSecretReflections replicates the binding secret to the namespaces listed in .Spec.SecretTargetNamespaces that
+// permit it, and deletes the replicas in the namespaces no longer listed or no longer permitting it. The replicas are
+// named like the binding secret and tracked by the services.cloud.sap.com/bindingUID annotation, owner references
+// across namespaces are not supported.
+// Returns true if the namespaces in the status of the binding changed.
+func (r *ServiceBindingReconciler) syncSecretReflections(ctx context.Context, binding *servicesv1.ServiceBinding, secret *corev1.Secret) (bool, error) {
+	var reflected []string
+	for _, namespace := range binding.Spec.SecretTargetNamespaces {
+		reason, err := r.secretReflectionDenied(ctx, binding, namespace)
+		if err != nil {
+			return false, err
+		}
+		if len(reason) == 0 {
+			reason, err = r.createOrUpdateSecretReflection(ctx, binding, secret, namespace)
+			if err != nil {
+				return false, err
+			}
+		}
+		if len(reason) > 0 {
+			r.Recorder.Event(binding, corev1.EventTypeWarning, SecretReflectionSkipped, reason)
+			continue
+		}
+		reflected = append(reflected, namespace)
+	}
+
+	for _, namespace := range binding.Status.SecretTargetNamespaces {
+		if !contains(reflected, namespace) {
+			if err := r.deleteSecretReflection(ctx, binding, namespace); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	if reflect.DeepEqual(reflected, binding.Status.SecretTargetNamespaces) {
+		return false, nil
+	}
+	binding.Status.SecretTargetNamespaces = reflected
+	return true, nil
+}
+
+// releaseSecretReflections deletes the replicas of the binding secret
+func (r *ServiceBindingReconciler) releaseSecretReflections(ctx context.Context, binding *servicesv1.ServiceBinding) error {
+	for _, namespace := range binding.Status.SecretTargetNamespaces {
+		if err := r.deleteSecretReflection(ctx, binding, namespace); err != nil {
+			return err
+		}
+	}
+	binding.Status.SecretTargetNamespaces = nil
+	return nil
+}
+
+// secretReflectionDenied returns why the binding secret may not be replicated to the namespace, empty if it may.
+// The namespace permits the replication with an annotation listing the namespaces of the bindings, or "*".
+func (r *ServiceBindingReconciler) secretReflectionDenied(ctx context.Context, binding *servicesv1.ServiceBinding, namespace string) (string, error) {
+	if namespace == r.bindingSecretKey(binding).Namespace {
+		return fmt.Sprintf("the secret is not replicated to the namespace %s, it is the namespace of the binding secret", namespace), nil
+	}
+	targetNamespace := &corev1.Namespace{}
+	if err := r.apiReader().Get(ctx, types.NamespacedName{Name: namespace}, targetNamespace); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Sprintf("the secret is not replicated to the namespace %s, it does not exist", namespace), nil
+		}
+		return "", err
+	}
+	for _, allowed := range strings.Split(targetNamespace.Annotations[api.AllowSecretReflectionAnnotation], ",") {
+		if allowed = strings.TrimSpace(allowed); allowed == "*" || allowed == binding.Namespace {
+			return "", nil
+		}
+	}
+	return fmt.Sprintf("the secret is not replicated to the namespace %s, its %s annotation does not permit the namespace %s",
+		namespace, api.AllowSecretReflectionAnnotation, binding.Namespace), nil
+}
+
+// createOrUpdateSecretReflection writes the replica of the binding secret to the namespace, returns why it was not
+// written if the name is taken by a secret which is not a replica of the binding
+func (r *ServiceBindingReconciler) createOrUpdateSecretReflection(ctx context.Context, binding *servicesv1.ServiceBinding, secret *corev1.Secret, namespace string) (string, error) {
+	log := GetLogger(ctx)
+	replica := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      binding.Spec.SecretName,
+			Namespace: namespace,
+		},
+		Type: secret.Type,
+		Data: secret.Data,
+	}
+	mergeSecretLabels(binding, replica)
+	annotateSecretBinding(binding, replica)
+
+	dbReplica, err := r.getSecretReflection(ctx, namespace, replica.Name)
+	if err != nil {
+		if apierrors.IsForbidden(err) {
+			// e.g. the operator watches the allowed namespaces of the chart only
+			return fmt.Sprintf("the secret is not replicated to the namespace %s, the operator is not permitted to manage its secrets", namespace), nil
+		}
+		if !apierrors.IsNotFound(err) {
+			return "", err
+		}
+		log.Info("Creating replica of the binding secret", "namespace", namespace)
+		return "", r.Client.Create(ctx, replica)
+	}
+
+	if !isSecretReflection(binding, dbReplica) {
+		return fmt.Sprintf("the secret is not replicated to the namespace %s, the name %s is already taken", namespace, replica.Name), nil
+	}
+	if secretTypeChanged(dbReplica, replica) {
+		log.Info("Recreating replica of the binding secret with a new type", "namespace", namespace)
+		if err := r.Client.Delete(ctx, dbReplica); client.IgnoreNotFound(err) != nil {
+			return "", err
+		}
+		return "", r.Client.Create(ctx, replica)
+	}
+	if !secretChanged(dbReplica, replica) {
+		return "", nil
+	}
+	log.Info("Updating replica of the binding secret", "namespace", namespace)
+	dbReplica = dbReplica.DeepCopy()
+	dbReplica.Labels = replica.Labels
+	dbReplica.Annotations = replica.Annotations
+	dbReplica.Data = replica.Data
+	return "", r.Client.Update(ctx, dbReplica)
+}
+
+// deleteSecretReflection deletes the replica of the binding secret in the namespace, secrets that are not replicas of
+// the binding are kept
+func (r *ServiceBindingReconciler) deleteSecretReflection(ctx context.Context, binding *servicesv1.ServiceBinding, namespace string) error {
+	replica, err := r.getSecretReflection(ctx, namespace, binding.Spec.SecretName)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !isSecretReflection(binding, replica) {
+		return nil
+	}
+	GetLogger(ctx).Info("Deleting replica of the binding secret", "namespace", namespace)
+	return client.IgnoreNotFound(r.Client.Delete(ctx, replica))
+}
+
+// getSecretReflection reads the replica through the API reader, the target namespaces may be outside of the namespaces
+// whose secrets are cached
+func (r *ServiceBindingReconciler) getSecretReflection(ctx context.Context, namespace string, name string) (*corev1.Secret, error) {
+	replica := &corev1.Secret{}
+	err := r.apiReader().Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, replica)
+	return replica, err
+}
+
+// isSecretReflection returns true if the secret is a replica of the binding secret
+func isSecretReflection(binding *servicesv1.ServiceBinding, secret *corev1.Secret) bool {
+	return secret.Annotations[api.SecretBindingUIDAnnotation] == string(binding.UID)
+}
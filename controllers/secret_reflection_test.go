@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = Describe("Secret reflection", func() {
+	var reconciler *ServiceBindingReconciler
+	var binding *servicesv1.ServiceBinding
+	var secret *corev1.Secret
+	var recorder *record.FakeRecorder
+	var ctx context.Context
+
+	namespace := func(name, allowed string) *corev1.Namespace {
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if len(allowed) > 0 {
+			namespace.Annotations = map[string]string{api.AllowSecretReflectionAnnotation: allowed}
+		}
+		return namespace
+	}
+
+	getReplica := func(namespace string) (*corev1.Secret, error) {
+		replica := &corev1.Secret{}
+		err := reconciler.apiReader().Get(ctx, types.NamespacedName{Name: "my-secret", Namespace: namespace}, replica)
+		return replica, err
+	}
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		recorder = record.NewFakeRecorder(10)
+		taken := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-secret", Namespace: "taken"}}
+		reconciler = &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+				namespace("team-a", "other, app1"),
+				namespace("team-b", "*"),
+				namespace("private", "other"),
+				namespace("taken", "*"),
+				taken,
+			).Build(),
+			Scheme:   testScheme,
+			Log:      ctrl.Log,
+			Recorder: recorder,
+		}}
+		binding = &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1", UID: "binding-uid"},
+			Spec: servicesv1.ServiceBindingSpec{SecretName: "my-secret", SecretLabels: map[string]string{"team": "payments"},
+				SecretTargetNamespaces: []string{"team-a", "team-b"}},
+		}
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-secret", Namespace: "app1"},
+			Data: map[string][]byte{"username": []byte("user")}}
+	})
+
+	It("should replicate the secret to the target namespaces permitting it", func() {
+		changed, err := reconciler.syncSecretReflections(ctx, binding, secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		Expect(binding.Status.SecretTargetNamespaces).To(Equal([]string{"team-a", "team-b"}))
+		replica, err := getReplica("team-a")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(replica.Data).To(HaveKeyWithValue("username", []byte("user")))
+		Expect(replica.Labels).To(HaveKeyWithValue("team", "payments"))
+		Expect(replica.Annotations).To(HaveKeyWithValue(api.SecretBindingUIDAnnotation, "binding-uid"))
+
+		secret.Data = map[string][]byte{"username": []byte("rotated")}
+		changed, err = reconciler.syncSecretReflections(ctx, binding, secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changed).To(BeFalse())
+		replica, err = getReplica("team-b")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(replica.Data).To(HaveKeyWithValue("username", []byte("rotated")))
+	})
+
+	It("should delete the replicas of the namespaces no longer listed and when the binding is deleted", func() {
+		_, err := reconciler.syncSecretReflections(ctx, binding, secret)
+		Expect(err).ToNot(HaveOccurred())
+
+		binding.Spec.SecretTargetNamespaces = []string{"team-b"}
+		changed, err := reconciler.syncSecretReflections(ctx, binding, secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		_, err = getReplica("team-a")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		Expect(reconciler.releaseSecretReflections(ctx, binding)).To(Succeed())
+		_, err = getReplica("team-b")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		Expect(binding.Status.SecretTargetNamespaces).To(BeEmpty())
+	})
+
+	It("should read the replicas in namespaces whose secrets are not cached", func() {
+		cached := reconciler.Client
+		reconciler.APIReader = cached
+		reconciler.Client = interceptor.NewClient(cached.(client.WithWatch), interceptor.Funcs{Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			if _, ok := obj.(*corev1.Secret); ok && key.Namespace != "app1" {
+				return fmt.Errorf("unable to get: %s because of unknown namespace for the cache", key)
+			}
+			return c.Get(ctx, key, obj, opts...)
+		}})
+		_, err := reconciler.syncSecretReflections(ctx, binding, secret)
+		Expect(err).ToNot(HaveOccurred())
+
+		secret.Data = map[string][]byte{"username": []byte("rotated")}
+		_, err = reconciler.syncSecretReflections(ctx, binding, secret)
+		Expect(err).ToNot(HaveOccurred())
+		replica, err := getReplica("team-a")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(replica.Data).To(HaveKeyWithValue("username", []byte("rotated")))
+
+		Expect(reconciler.releaseSecretReflections(ctx, binding)).To(Succeed())
+		_, err = getReplica("team-a")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should skip namespaces not permitting the replication and secrets of others", func() {
+		binding.Spec.SecretTargetNamespaces = []string{"private", "taken", "missing"}
+		changed, err := reconciler.syncSecretReflections(ctx, binding, secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changed).To(BeFalse())
+		_, err = getReplica("private")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		taken, err := getReplica("taken")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(taken.Data).To(BeEmpty())
+		Expect(recorder.Events).To(HaveLen(3))
+		Expect(recorder.Events).To(Receive(ContainSubstring("does not permit the namespace app1")))
+	})
+})
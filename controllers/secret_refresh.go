@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	SecretRefreshed     = "SecretRefreshed"
+	SecretRefreshFailed = "SecretRefreshFailed"
+)
+
+func secretRefreshRequested(binding *servicesv1.ServiceBinding) bool {
+	_, ok := binding.Annotations[api.RefreshSecretAnnotation]
+	return ok
+}
+
+// refreshSecret reads the credentials of the binding from SM again and renders its secret with them, which repairs
+// edits of the secret and applies changes of the template without the new credentials of a rotation
+func (r *ServiceBindingReconciler) refreshSecret(ctx context.Context, binding *servicesv1.ServiceBinding, btpAccessCredentialsSecret string) (ctrl.Result, error) {
+	log := GetLogger(ctx)
+	log.Info("refreshing the binding secret with the credentials from SM")
+
+	smClient, err := r.getSMClient(ctx, binding, btpAccessCredentialsSecret)
+	if err != nil {
+		return r.markAsTransientError(ctx, Unknown, err.Error(), binding)
+	}
+	smBinding, err := smClient.GetBindingByID(binding.Status.BindingID, nil)
+	if err != nil {
+		log.Error(err, "failed to get binding from SM for the secret refresh")
+		return ctrl.Result{}, err
+	}
+
+	if smBinding.Credentials == nil {
+		// bindings of services whose credentials are not retrievable have to be rotated
+		message := fmt.Sprintf("the credentials of binding %s cannot be read from Service Manager, rotate the credentials to renew the secret", binding.Status.BindingID)
+		log.Info(message)
+		r.Recorder.Event(binding, corev1.EventTypeWarning, SecretRefreshFailed, message)
+	} else {
+		if err := r.storeBindingSecret(ctx, binding, smBinding); err != nil {
+			return r.handleSecretError(ctx, smClientTypes.CREATE, err, binding)
+		}
+		// the version of the credentials written to the credentials target is kept in the status
+		if hasCredentialsTarget(binding) {
+			if err := r.updateStatus(ctx, binding); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		r.Recorder.Event(binding, corev1.EventTypeNormal, SecretRefreshed, "the secret was refreshed with the credentials from Service Manager")
+	}
+
+	delete(binding.Annotations, api.RefreshSecretAnnotation)
+	if err := r.Client.Update(ctx, binding); err != nil {
+		log.Error(err, "failed to remove the secret refresh annotation")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/secrets/template"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// SecretTemplateFieldsMissing is the reason of the event recorded when the credentials lack fields the secret template relies on
+const SecretTemplateFieldsMissing = "SecretTemplateFieldsMissing"
+
+// recordSecretTemplateFields records the credential fields referenced by the secret template that the credentials
+// contain, once the secret of the binding was rendered for the first time
+func recordSecretTemplateFields(ctx context.Context, binding *servicesv1.ServiceBinding, templateName, secretTemplate string, delims template.Delimiters, credentials map[string]interface{}) {
+	if len(binding.Status.SecretTemplateFields) > 0 {
+		return
+	}
+	fields, err := template.ReferencedFields(templateName, secretTemplate, delims, secretTemplateSmBindingKey)
+	if err != nil {
+		GetLogger(ctx).Error(err, "failed to read the fields referenced by the secret template")
+		return
+	}
+	for _, field := range fields {
+		if credentialFieldPresent(credentials, field) {
+			binding.Status.SecretTemplateFields = append(binding.Status.SecretTemplateFields, field)
+		}
+	}
+}
+
+// checkSecretTemplateFields warns when the credentials, e.g. after a rotation, lack fields that the secret template
+// referenced when the secret was first rendered, which would otherwise render empty values silently
+func (r *ServiceBindingReconciler) checkSecretTemplateFields(ctx context.Context, binding *servicesv1.ServiceBinding, credentials map[string]interface{}) {
+	var missing []string
+	for _, field := range binding.Status.SecretTemplateFields {
+		if !credentialFieldPresent(credentials, field) {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+	message := fmt.Sprintf("the credentials no longer contain the fields referenced by the secret template: %s", strings.Join(missing, ", "))
+	GetLogger(ctx).Info(message)
+	r.Recorder.Event(binding, corev1.EventTypeWarning, SecretTemplateFieldsMissing, message)
+}
+
+// credentialFieldPresent checks whether the credentials contain the field with the dotted path
+func credentialFieldPresent(credentials map[string]interface{}, path string) bool {
+	var value interface{} = credentials
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if value, ok = object[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"context"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/secrets/template"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("Secret template fields", func() {
+	var (
+		binding     *servicesv1.ServiceBinding
+		credentials map[string]interface{}
+		ctx         context.Context
+	)
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		binding = &servicesv1.ServiceBinding{}
+		binding.Spec.SecretTemplate = `{{ .smBindingCredentials.clientid }}{{ .smBindingCredentials.uaa.url }}{{ .smBindingCredentials.optional }}`
+		credentials = map[string]interface{}{"clientid": "id", "uaa": map[string]interface{}{"url": "https://uaa"}}
+	})
+
+	It("should record the referenced fields the credentials contain on the first render", func() {
+		recordSecretTemplateFields(ctx, binding, "binding", binding.Spec.SecretTemplate, template.Delimiters{}, credentials)
+		Expect(binding.Status.SecretTemplateFields).To(Equal([]string{"clientid", "uaa.url"}))
+
+		recordSecretTemplateFields(ctx, binding, "binding", binding.Spec.SecretTemplate, template.Delimiters{}, map[string]interface{}{"optional": "value"})
+		Expect(binding.Status.SecretTemplateFields).To(Equal([]string{"clientid", "uaa.url"}))
+	})
+
+	It("should warn when the credentials lack recorded fields", func() {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{Recorder: recorder}}
+		binding.Status.SecretTemplateFields = []string{"clientid", "uaa.url"}
+
+		reconciler.checkSecretTemplateFields(ctx, binding, credentials)
+		Expect(recorder.Events).To(BeEmpty())
+
+		reconciler.checkSecretTemplateFields(ctx, binding, map[string]interface{}{"clientid": "id", "uaa": "flat"})
+		Expect(recorder.Events).To(Receive(ContainSubstring("SecretTemplateFieldsMissing the credentials no longer contain the fields referenced by the secret template: uaa.url")))
+	})
+})
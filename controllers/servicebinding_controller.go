@@ -18,21 +18,37 @@ package controllers
 
 import (
 	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"fmt"
 
+	"k8s.io/client-go/util/retry"
 	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 
 	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	"github.com/SAP/sap-btp-service-operator/internal/escrow"
+	"github.com/SAP/sap-btp-service-operator/internal/secrets/encryption"
+	"github.com/SAP/sap-btp-service-operator/internal/secrets/formatter"
 	"github.com/SAP/sap-btp-service-operator/internal/secrets/template"
+	"github.com/SAP/sap-btp-service-operator/internal/vault"
 	"github.com/pkg/errors"
 
 	"github.com/SAP/sap-btp-service-operator/api"
 
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/google/uuid"
@@ -53,13 +69,43 @@ import (
 const (
 	secretNameTakenErrorFormat         = "the specified secret name '%s' is already taken. Choose another name and try again"
 	secretAlreadyOwnedErrorFormat      = "secret %s belongs to another binding %s, choose a different name"
+	secretRevertsEventThreshold        = 2
+	configMapNameTakenErrorFormat      = "the config map name '%s' generated by the secret template is already taken. Choose another name and try again"
+	encryptKeyNotFoundErrorFormat      = "the key '%s' to encrypt is not part of the binding secret"
 	secretTemplateSmBindingKey         = "smBindingCredentials"
 	secretTemplateServiceInstanceInfos = "serviceInstanceInfos"
+	secretTemplateInstanceParameters   = "instanceParameters"
+	secretTemplateBindingParameters    = "bindingParameters"
+
+	// defaultRotatedBindingTTL is the rotatedBindingTTL set by the mutating webhook
+	defaultRotatedBindingTTL = 48 * time.Hour
 )
 
 // ServiceBindingReconciler reconciles a ServiceBinding object
 type ServiceBindingReconciler struct {
 	*BaseReconciler
+
+	// Escrow stores the credentials of rotated bindings before they are deleted, nil if no escrow is configured
+	Escrow escrow.Sink
+	// CredentialsVault writes the credentials of bindings with a Vault credentials target, nil if no Vault server is configured
+	CredentialsVault vault.KV
+
+	// secretRetries holds the number of transient secret errors of a binding since its secret was last stored, by UID
+	secretRetries sync.Map
+	// secretWrites holds the hash of the keys the operator last wrote to the secret of a binding, by UID.
+	// It is kept in memory only, so the hash of the credentials is not readable by the consumers of the secret.
+	secretWrites sync.Map
+	// secretReverts holds the number of times the keys of the secret of a binding were modified by another actor, by UID
+	secretReverts sync.Map
+	// unbindFailures holds the number of failed async unbind operations of a binding, by UID
+	unbindFailures sync.Map
+	// bindSlotWaits holds the time since which a binding waits for a bind slot of its instance, by UID
+	bindSlotWaits sync.Map
+	// vaultTokens holds the Vault tokens of the logins of credentials targets, by namespace, service account, auth mount
+	// and role
+	vaultTokens sync.Map
+	// csiCredentials holds the credentials of the bindings with the csi credentials target read from SM, by UID
+	csiCredentials sync.Map
 }
 
 // +kubebuilder:rbac:groups=services.cloud.sap.com,resources=servicebindings,verbs=get;list;watch;create;update;patch;delete
@@ -67,21 +113,35 @@ type ServiceBindingReconciler struct {
 // +kubebuilder:rbac:groups=services.cloud.sap.com,resources=serviceinstances,verbs=get;list
 // +kubebuilder:rbac:groups=services.cloud.sap.com,resources=serviceinstances/status,verbs=get
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=list
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;update
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts/token,verbs=create
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;create;update
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get
 
 func (r *ServiceBindingReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("servicebinding", req.NamespacedName).WithValues("correlation_id", uuid.New().String(), req.Name, req.Namespace)
 	ctx = context.WithValue(ctx, LogKey{}, log)
+	defer r.startupReconciled(req)
 
 	serviceBinding := &servicesv1.ServiceBinding{}
 	if err := r.Client.Get(ctx, req.NamespacedName, serviceBinding); err != nil {
 		if !apierrors.IsNotFound(err) {
 			log.Error(err, "unable to fetch ServiceBinding")
+		} else {
+			r.forgetSMCredentials(req.NamespacedName)
 		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 	serviceBinding = serviceBinding.DeepCopy()
+	if err := r.operations().load(ctx, serviceBinding); err != nil {
+		log.Error(err, "failed to load the ongoing operation")
+		return ctrl.Result{}, err
+	}
 	serviceBinding.SetObservedGeneration(serviceBinding.Generation)
 
 	if len(serviceBinding.GetConditions()) == 0 {
@@ -95,9 +155,12 @@ func (r *ServiceBindingReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		log.Error(err, "failed to get service instance for binding")
 		return ctrl.Result{}, err
 	}
+	if err == nil {
+		ctx = withInstanceSnapshot(ctx, serviceInstance)
+	}
 
 	if isMarkedForDeletion(serviceBinding.ObjectMeta) {
-		return r.delete(ctx, serviceBinding, serviceInstance.Spec.BTPAccessCredentialsSecret)
+		return r.delete(ctx, serviceBinding, btpAccessSecretName(serviceInstance), serviceInstance.Spec.ServiceOfferingName)
 	}
 
 	if err != nil { // instance not found
@@ -112,9 +175,10 @@ func (r *ServiceBindingReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 
 	if len(serviceBinding.Status.OperationURL) > 0 {
 		// ongoing operation - poll status from SM
-		return r.poll(ctx, serviceBinding, serviceInstance.Spec.BTPAccessCredentialsSecret)
+		return r.poll(ctx, serviceBinding, btpAccessSecretName(serviceInstance))
 	}
 
+	// the finalizer is added by the mutating webhook on creation, this covers resources created while webhooks were disabled
 	if !controllerutil.ContainsFinalizer(serviceBinding, api.FinalizerName) {
 		controllerutil.AddFinalizer(serviceBinding, api.FinalizerName)
 		log.Info(fmt.Sprintf("added finalizer '%s' to service binding", api.FinalizerName))
@@ -123,19 +187,41 @@ func (r *ServiceBindingReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		}
 	}
 
+	if isDegraded(serviceBinding) && len(serviceBinding.Status.BindingID) > 0 {
+		return r.resyncDegradedBinding(ctx, serviceBinding, btpAccessSecretName(serviceInstance))
+	}
+
+	if len(serviceBinding.Status.BindingID) > 0 {
+		if paused, err := r.pauseForUpstreamMaintenance(ctx, serviceBinding, serviceInstance); err != nil {
+			return ctrl.Result{}, err
+		} else if paused {
+			// the bindings are enqueued when the instance is ready again, this covers missed events
+			return ctrl.Result{RequeueAfter: r.longPollInterval()}, nil
+		}
+	}
+
 	isBindingReady := meta.IsStatusConditionPresentAndEqual(serviceBinding.Status.Conditions, api.ConditionReady, metav1.ConditionTrue)
 	if isBindingReady {
 		if isStaleServiceBinding(serviceBinding) {
 			return r.handleStaleServiceBinding(ctx, serviceBinding)
 		}
 
-		if initCredRotationIfRequired(serviceBinding) {
+		if initCredRotationIfRequired(serviceBinding) || r.bindingParametersFromChanged(ctx, serviceBinding) {
 			return ctrl.Result{}, r.updateStatus(ctx, serviceBinding)
 		}
+
+		if secretRefreshRequested(serviceBinding) {
+			return r.refreshSecret(ctx, serviceBinding, btpAccessSecretName(serviceInstance))
+		}
 	}
 
 	if meta.IsStatusConditionTrue(serviceBinding.Status.Conditions, api.ConditionCredRotationInProgress) {
-		if err := r.rotateCredentials(ctx, serviceBinding, serviceInstance.Spec.BTPAccessCredentialsSecret); err != nil {
+		if deferred, err := r.deferRotation(ctx, serviceBinding); err != nil {
+			return ctrl.Result{}, err
+		} else if deferred {
+			return ctrl.Result{RequeueAfter: r.rotationHoldCheckInterval(serviceBinding)}, nil
+		}
+		if err := r.rotateCredentials(ctx, serviceBinding, btpAccessSecretName(serviceInstance), serviceInstance.Spec.ServiceOfferingName); err != nil {
 			return ctrl.Result{}, err
 		}
 	}
@@ -162,7 +248,35 @@ func (r *ServiceBindingReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		log.Info(fmt.Sprintf("Service instance with k8s name %s is not ready for binding yet", serviceInstance.Name))
 		setInProgressConditions(ctx, smClientTypes.CREATE, fmt.Sprintf("creation in progress, waiting for service instance '%s' to be ready", serviceBinding.Spec.ServiceInstanceName), serviceBinding)
 
-		return ctrl.Result{Requeue: true, RequeueAfter: r.Config.PollInterval}, r.updateStatus(ctx, serviceBinding)
+		return ctrl.Result{Requeue: true, RequeueAfter: r.pollInterval()}, r.updateStatus(ctx, serviceBinding)
+	}
+
+	if serviceBinding.Status.BindingID == "" && len(serviceBinding.Spec.ReadinessGates) > 0 {
+		pendingGate, err := r.getPendingReadinessGate(ctx, serviceBinding)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				setBlockedCondition(ctx, fmt.Sprintf("invalid readiness gate: %s", err.Error()), serviceBinding)
+				return ctrl.Result{}, r.updateStatus(ctx, serviceBinding)
+			}
+			if apierrors.IsForbidden(err) {
+				setBlockedCondition(ctx, fmt.Sprintf("the operator is not permitted to read the readiness gate, grant its service account 'get' permissions for the kind: %s", err.Error()), serviceBinding)
+				return ctrl.Result{RequeueAfter: r.longPollInterval()}, r.updateStatus(ctx, serviceBinding)
+			}
+			return r.markAsTransientError(ctx, smClientTypes.CREATE, err.Error(), serviceBinding)
+		}
+		if len(pendingGate) > 0 {
+			log.Info(pendingGate)
+			setInProgressConditions(ctx, smClientTypes.CREATE, pendingGate, serviceBinding)
+			return ctrl.Result{Requeue: true, RequeueAfter: r.pollInterval()}, r.updateStatus(ctx, serviceBinding)
+		}
+	}
+
+	if serviceBinding.Status.BindingID == "" && len(serviceBinding.Spec.ParametersFromInstance) > 0 {
+		if pending := pendingInstanceParameter(serviceInstance, serviceBinding.Spec.ParametersFromInstance); len(pending) > 0 {
+			log.Info(pending)
+			setInProgressConditions(ctx, smClientTypes.CREATE, pending, serviceBinding)
+			return ctrl.Result{Requeue: true, RequeueAfter: r.pollInterval()}, r.updateStatus(ctx, serviceBinding)
+		}
 	}
 
 	//set owner instance only for original bindings (not rotated)
@@ -182,12 +296,30 @@ func (r *ServiceBindingReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 			return ctrl.Result{}, r.updateStatus(ctx, serviceBinding)
 		}
 
-		smClient, err := r.getSMClient(ctx, serviceBinding, serviceInstance.Spec.BTPAccessCredentialsSecret)
+		smClient, err := r.getSMClient(ctx, serviceBinding, btpAccessSecretName(serviceInstance))
 		if err != nil {
 			return r.markAsTransientError(ctx, Unknown, err.Error(), serviceBinding)
 		}
 
-		smBinding, err := r.getBindingForRecovery(ctx, smClient, serviceBinding)
+		if len(adoptionID(serviceBinding)) > 0 {
+			return r.adoptBinding(ctx, smClient, serviceBinding)
+		}
+
+		if len(serviceBinding.Spec.ServiceInstanceID) > 0 {
+			notPermitted, err := r.resolveSharedInstance(ctx, smClient, serviceInstance, serviceBinding)
+			if err != nil {
+				return r.markAsTransientError(ctx, smClientTypes.CREATE, err.Error(), serviceBinding)
+			}
+			if notPermitted != nil {
+				return r.markAsSharingNotPermitted(ctx, serviceInstance, serviceBinding, notPermitted)
+			}
+		}
+
+		smBinding, err := r.getBindingForRecovery(ctx, smClient, serviceBinding, serviceInstance.Spec.ServiceOfferingName, true)
+		if errors.Is(err, errRecoveryWithoutLastOperation) {
+			log.Error(err, "failed to recover binding")
+			return r.markAsNonTransientError(ctx, smClientTypes.CREATE, err.Error(), serviceBinding)
+		}
 		if err != nil {
 			log.Error(err, "failed to check binding recovery")
 			return r.markAsTransientError(ctx, smClientTypes.CREATE, err.Error(), serviceBinding)
@@ -196,6 +328,16 @@ func (r *ServiceBindingReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 			return r.recover(ctx, serviceBinding, smBinding)
 		}
 
+		available, waitingFor, err := r.bindSlotAvailable(ctx, serviceInstance, serviceBinding)
+		if err != nil {
+			return r.markAsTransientError(ctx, smClientTypes.CREATE, err.Error(), serviceBinding)
+		}
+		if !available {
+			log.Info(waitingFor)
+			setInProgressConditions(ctx, smClientTypes.CREATE, waitingFor, serviceBinding)
+			return ctrl.Result{Requeue: true, RequeueAfter: r.pollInterval()}, r.updateStatus(ctx, serviceBinding)
+		}
+
 		return r.createBinding(ctx, smClient, serviceInstance, serviceBinding)
 	}
 
@@ -204,37 +346,75 @@ func (r *ServiceBindingReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 }
 
 func (r *ServiceBindingReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&servicesv1.ServiceBinding{}).
-		WithOptions(controller.Options{RateLimiter: workqueue.NewItemExponentialFailureRateLimiter(r.Config.RetryBaseDelay, r.Config.RetryMaxDelay)}).
-		Complete(r)
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &servicesv1.ServiceBinding{}, parametersFromSecretIndex, indexBindingParametersFrom); err != nil {
+		return err
+	}
+	newList := func() client.ObjectList { return &servicesv1.ServiceBindingList{} }
+	b, err := r.setupWatch(mgr, "servicebinding", &servicesv1.ServiceBinding{}, newList)
+	if err != nil {
+		return err
+	}
+	if !r.Config.DisableSecretsCache {
+		// without the secrets cache there is no informer to watch the secrets with
+		b = b.Watches(&corev1.Secret{}, r.enqueueParametersFromReferences(newList, parametersFromSecretIndex))
+	}
+	if b, err = r.watchParametersFromConfigMaps(mgr, b, &servicesv1.ServiceBinding{}, newList); err != nil {
+		return err
+	}
+	if b, err = r.watchParametersFromResources(mgr, b, &servicesv1.ServiceBinding{}, newList); err != nil {
+		return err
+	}
+	return b.
+		Watches(&servicesv1.ServiceInstance{}, handler.EnqueueRequestsFromMapFunc(r.blockedBindingsOf), builder.WithPredicates(instanceCreated)).
+		Watches(&servicesv1.ServiceInstance{}, handler.EnqueueRequestsFromMapFunc(r.maintainedBindingsOf), builder.WithPredicates(instanceMaintenanceChanged)).
+		WithOptions(controller.Options{RateLimiter: newRetryTrackingRateLimiter("servicebinding", workqueue.NewItemExponentialFailureRateLimiter(r.Config.RetryBaseDelay, r.Config.RetryMaxDelay))}).
+		Complete(r.withSMBackpressure(r.withReconcileTelemetry(func() api.SAPBTPResource { return &servicesv1.ServiceBinding{} }, r)))
 }
 
 func (r *ServiceBindingReconciler) createBinding(ctx context.Context, smClient sm.Client, serviceInstance *servicesv1.ServiceInstance, serviceBinding *servicesv1.ServiceBinding) (ctrl.Result, error) {
 	log := GetLogger(ctx)
 	log.Info("Creating smBinding in SM")
 	serviceBinding.Status.InstanceID = serviceInstance.Status.InstanceID
-	_, bindingParameters, err := buildParameters(r.Client, serviceBinding.Namespace, serviceBinding.Spec.ParametersFrom, serviceBinding.Spec.Parameters)
+	bindResource, supported := r.bindResourceOf(serviceInstance, serviceBinding)
+	if !supported {
+		return r.markBindResourceNotSupported(ctx, serviceInstance, serviceBinding)
+	}
+	params, bindingParameters, err := buildParameters(r.parametersSources(), serviceBinding.Namespace, serviceBinding.Spec.ParametersFrom, serviceBinding.Spec.Parameters, serviceBinding.Spec.ParametersMergeStrategy)
+	if err == nil {
+		// a rotation binds with the spec of the rotated binding
+		err = r.checkParametersLimits(!meta.IsStatusConditionTrue(serviceBinding.Status.Conditions, api.ConditionCredRotationInProgress), bindingParameters)
+	}
+	if err == nil {
+		serviceBinding.Status.HashedParametersFrom, err = hashParametersFrom(r.parametersSources(), serviceBinding.Namespace, serviceBinding.Spec.ParametersFrom)
+		meta.RemoveStatusCondition(&serviceBinding.Status.Conditions, api.ConditionParametersOutdated)
+	}
+	if err == nil && len(serviceBinding.Spec.ParametersFromInstance) > 0 {
+		bindingParameters, err = addInstanceParameters(params, serviceInstance, serviceBinding.Spec.ParametersFromInstance)
+	}
 	if err != nil {
 		log.Error(err, "failed to parse smBinding parameters")
 		return r.markAsNonTransientError(ctx, smClientTypes.CREATE, err.Error(), serviceBinding)
 	}
+	if err := r.checkParametersSchema(ctx, smClient, serviceInstance, schemaServiceBinding, schemaCreate, bindingParameters); err != nil {
+		return r.markAsInvalidParameters(ctx, smClientTypes.CREATE, err.Error(), serviceBinding)
+	}
 
 	smBinding, operationURL, bindErr := smClient.Bind(&smClientTypes.ServiceBinding{
-		Name: serviceBinding.Spec.ExternalName,
-		Labels: smClientTypes.Labels{
-			namespaceLabel: []string{serviceBinding.Namespace},
-			k8sNameLabel:   []string{serviceBinding.Name},
-			clusterIDLabel: []string{r.Config.ClusterID},
-		},
+		Name:              serviceBinding.Spec.ExternalName,
+		Labels:            smLabels(r.Config.SMLabels.For(serviceInstance.Spec.ServiceOfferingName), serviceBinding.Namespace, serviceBinding.Name, r.clusterIDOf(serviceBinding, serviceBinding.Spec.ClusterIDOverride)),
 		ServiceInstanceID: serviceInstance.Status.InstanceID,
 		Parameters:        bindingParameters,
+		BindResource:      bindResource,
 	}, nil, buildUserInfo(ctx, serviceBinding.Spec.UserInfo))
 
 	if bindErr != nil {
 		log.Error(err, "failed to create service binding", "serviceInstanceID", serviceInstance.Status.InstanceID)
+		if isSharingNotPermittedError(bindErr) {
+			return r.markAsSharingNotPermitted(ctx, serviceInstance, serviceBinding, bindErr)
+		}
 		return r.handleError(ctx, smClientTypes.CREATE, bindErr, serviceBinding)
 	}
+	meta.RemoveStatusCondition(&serviceBinding.Status.Conditions, api.ConditionSharingNotPermitted)
 
 	if operationURL != "" {
 		var bindingID string
@@ -244,14 +424,15 @@ func (r *ServiceBindingReconciler) createBinding(ctx context.Context, smClient s
 		serviceBinding.Status.BindingID = bindingID
 
 		log.Info("Create smBinding request is async")
-		serviceBinding.Status.OperationURL = operationURL
-		serviceBinding.Status.OperationType = smClientTypes.CREATE
+		if err := r.operations().start(ctx, serviceBinding, operationURL, smClientTypes.CREATE); err != nil {
+			return ctrl.Result{}, err
+		}
 		setInProgressConditions(ctx, smClientTypes.CREATE, "", serviceBinding)
 		if err := r.updateStatus(ctx, serviceBinding); err != nil {
 			log.Error(err, "unable to update ServiceBinding status")
 			return ctrl.Result{}, err
 		}
-		return ctrl.Result{Requeue: true, RequeueAfter: r.Config.PollInterval}, nil
+		return ctrl.Result{Requeue: true, RequeueAfter: r.pollInterval()}, nil
 	}
 
 	log.Info("Binding created successfully")
@@ -274,9 +455,12 @@ func (r *ServiceBindingReconciler) createBinding(ctx context.Context, smClient s
 	return ctrl.Result{}, r.updateStatus(ctx, serviceBinding)
 }
 
-func (r *ServiceBindingReconciler) delete(ctx context.Context, serviceBinding *servicesv1.ServiceBinding, btpAccessCredentialsSecret string) (ctrl.Result, error) {
+func (r *ServiceBindingReconciler) delete(ctx context.Context, serviceBinding *servicesv1.ServiceBinding, btpAccessCredentialsSecret, serviceOfferingName string) (ctrl.Result, error) {
 	log := GetLogger(ctx)
 	if controllerutil.ContainsFinalizer(serviceBinding, api.FinalizerName) {
+		if orphanRequested(serviceBinding) {
+			return r.orphanBinding(ctx, serviceBinding)
+		}
 		smClient, err := r.getSMClient(ctx, serviceBinding, btpAccessCredentialsSecret)
 		if err != nil {
 			return r.markAsTransientError(ctx, Unknown, err.Error(), serviceBinding)
@@ -284,7 +468,7 @@ func (r *ServiceBindingReconciler) delete(ctx context.Context, serviceBinding *s
 
 		if len(serviceBinding.Status.BindingID) == 0 {
 			log.Info("No binding id found validating binding does not exists in SM before removing finalizer")
-			smBinding, err := r.getBindingForRecovery(ctx, smClient, serviceBinding)
+			smBinding, err := r.getBindingForRecovery(ctx, smClient, serviceBinding, serviceOfferingName, false)
 			if err != nil {
 				return ctrl.Result{}, err
 			}
@@ -296,15 +480,8 @@ func (r *ServiceBindingReconciler) delete(ctx context.Context, serviceBinding *s
 			}
 
 			// make sure there's no secret stored for the binding
-			if err := r.deleteBindingSecret(ctx, serviceBinding); err != nil {
-				return ctrl.Result{}, err
-			}
-
 			log.Info("Binding does not exists in SM, removing finalizer")
-			if err := r.removeFinalizer(ctx, serviceBinding, api.FinalizerName); err != nil {
-				return ctrl.Result{}, err
-			}
-			return ctrl.Result{}, nil
+			return r.deleteSecretAndRemoveFinalizer(ctx, serviceBinding)
 		}
 
 		if len(serviceBinding.Status.OperationURL) > 0 && serviceBinding.Status.OperationType == smClientTypes.DELETE {
@@ -312,22 +489,38 @@ func (r *ServiceBindingReconciler) delete(ctx context.Context, serviceBinding *s
 			return r.poll(ctx, serviceBinding, btpAccessCredentialsSecret)
 		}
 
+		if len(serviceBinding.Status.OperationURL) > 0 && serviceBinding.Status.OperationType == smClientTypes.CREATE {
+			// the binding is deleted while it is being created - wait for the creation to complete
+			if !cancellationRequested(serviceBinding) {
+				return r.requestCreateCancellation(ctx, serviceBinding)
+			}
+			return r.poll(ctx, serviceBinding, btpAccessCredentialsSecret)
+		}
+
+		if refused, result, err := r.verifyBindingOwnership(ctx, smClient, smClientTypes.DELETE, serviceBinding, serviceOfferingName); refused {
+			return result, err
+		}
 		log.Info(fmt.Sprintf("Deleting binding with id %v from SM", serviceBinding.Status.BindingID))
-		operationURL, unbindErr := smClient.Unbind(serviceBinding.Status.BindingID, nil, buildUserInfo(ctx, serviceBinding.Spec.UserInfo))
+		operationURL, unbindErr := smClient.Unbind(serviceBinding.Status.BindingID, r.unbindParameters(serviceBinding), buildUserInfo(ctx, serviceBinding.Spec.UserInfo))
 		if unbindErr != nil {
+			if isAlreadyDeletedError(unbindErr) {
+				r.recordAlreadyDeleted(ctx, serviceBinding, serviceBinding.Status.BindingID, unbindErr)
+				return r.deleteSecretAndRemoveFinalizer(ctx, serviceBinding)
+			}
 			// delete will proceed anyway
 			return r.markAsNonTransientError(ctx, smClientTypes.DELETE, unbindErr.Error(), serviceBinding)
 		}
 
 		if operationURL != "" {
 			log.Info("Deleting binding async")
-			serviceBinding.Status.OperationURL = operationURL
-			serviceBinding.Status.OperationType = smClientTypes.DELETE
+			if err := r.operations().start(ctx, serviceBinding, operationURL, smClientTypes.DELETE); err != nil {
+				return ctrl.Result{}, err
+			}
 			setInProgressConditions(ctx, smClientTypes.DELETE, "", serviceBinding)
 			if err := r.updateStatus(ctx, serviceBinding); err != nil {
 				return ctrl.Result{}, err
 			}
-			return ctrl.Result{Requeue: true, RequeueAfter: r.Config.PollInterval}, nil
+			return ctrl.Result{Requeue: true, RequeueAfter: r.pollInterval()}, nil
 		}
 
 		log.Info("Binding was deleted successfully")
@@ -369,34 +562,44 @@ func (r *ServiceBindingReconciler) poll(ctx context.Context, serviceBinding *ser
 	case smClientTypes.INPROGRESS:
 		fallthrough
 	case smClientTypes.PENDING:
-		if len(status.Description) != 0 {
-			setInProgressConditions(ctx, status.Type, status.Description, serviceBinding)
-			if err := r.updateStatus(ctx, serviceBinding); err != nil {
-				log.Error(err, "unable to update ServiceBinding polling description")
+		if len(status.Description) != 0 && r.descriptionUpdateRequired(serviceBinding, status.Type, status.Description) {
+			described, err := r.operations().describe(ctx, serviceBinding, status.Description)
+			if err != nil {
+				log.Error(err, "unable to store ServiceBinding polling description")
 				return ctrl.Result{}, err
 			}
+			if !described {
+				setInProgressConditions(ctx, status.Type, status.Description, serviceBinding)
+				if err := r.updateStatus(ctx, serviceBinding); err != nil {
+					log.Error(err, "unable to update ServiceBinding polling description")
+					return ctrl.Result{}, err
+				}
+			}
 		}
-		return ctrl.Result{Requeue: true, RequeueAfter: r.Config.PollInterval}, nil
+		return ctrl.Result{Requeue: true, RequeueAfter: r.pollInterval()}, nil
 	case smClientTypes.FAILED:
+		r.descriptionUpdates.Delete(serviceBinding.GetUID())
 		// non transient error - should not retry
 		setFailureConditions(status.Type, status.Description, serviceBinding)
 		if serviceBinding.Status.OperationType == smClientTypes.DELETE {
-			serviceBinding.Status.OperationURL = ""
-			serviceBinding.Status.OperationType = ""
-			if err := r.updateStatus(ctx, serviceBinding); err != nil {
-				log.Error(err, "unable to update ServiceBinding status")
+			if err := r.operations().finish(ctx, serviceBinding); err != nil {
 				return ctrl.Result{}, err
 			}
 			errMsg := "Async unbind operation failed"
 			if status.Errors != nil {
 				errMsg = fmt.Sprintf("Async unbind operation failed, errors: %s", string(status.Errors))
 			}
-			return ctrl.Result{}, fmt.Errorf(errMsg)
+			return r.handleUnbindFailure(ctx, serviceBinding, status.Errors, errMsg)
 		}
 	case smClientTypes.SUCCEEDED:
+		r.descriptionUpdates.Delete(serviceBinding.GetUID())
 		setSuccessConditions(status.Type, serviceBinding)
 		switch serviceBinding.Status.OperationType {
 		case smClientTypes.CREATE:
+			if isMarkedForDeletion(serviceBinding.ObjectMeta) && cancellationRequested(serviceBinding) {
+				// the binding is deleted in SM right away, its credentials are not stored
+				break
+			}
 			smBinding, err := smClient.GetBindingByID(serviceBinding.Status.BindingID, nil)
 			if err != nil {
 				log.Error(err, fmt.Sprintf("binding %s succeeded but could not fetch it from SM", serviceBinding.Status.BindingID))
@@ -415,34 +618,43 @@ func (r *ServiceBindingReconciler) poll(ctx context.Context, serviceBinding *ser
 		}
 	}
 
-	serviceBinding.Status.OperationURL = ""
-	serviceBinding.Status.OperationType = ""
+	if err := r.operations().finish(ctx, serviceBinding); err != nil {
+		return ctrl.Result{}, err
+	}
 
-	return ctrl.Result{}, r.updateStatus(ctx, serviceBinding)
+	// a deleted binding is deleted in SM right after its creation ended
+	return ctrl.Result{Requeue: isMarkedForDeletion(serviceBinding.ObjectMeta) && cancellationRequested(serviceBinding)}, r.updateStatus(ctx, serviceBinding)
 }
 
-func (r *ServiceBindingReconciler) getBindingForRecovery(ctx context.Context, smClient sm.Client, serviceBinding *servicesv1.ServiceBinding) (*smClientTypes.ServiceBinding, error) {
+// getBindingForRecovery looks up the binding in SM, a binding found without its last operation is only returned if the
+// last operation is not required
+func (r *ServiceBindingReconciler) getBindingForRecovery(ctx context.Context, smClient sm.Client, serviceBinding *servicesv1.ServiceBinding, serviceOfferingName string, requireLastOperation bool) (*smClientTypes.ServiceBinding, error) {
 	log := GetLogger(ctx)
 	nameQuery := fmt.Sprintf("name eq '%s'", serviceBinding.Spec.ExternalName)
-	clusterIDQuery := fmt.Sprintf("context/clusterid eq '%s'", r.Config.ClusterID)
+	clusterIDQuery := fmt.Sprintf("context/clusterid eq '%s'", r.clusterIDOf(serviceBinding, serviceBinding.Spec.ClusterIDOverride))
 	namespaceQuery := fmt.Sprintf("context/namespace eq '%s'", serviceBinding.Namespace)
-	k8sNameQuery := fmt.Sprintf("%s eq '%s'", k8sNameLabel, serviceBinding.Name)
-	parameters := sm.Parameters{
-		FieldQuery:    []string{nameQuery, clusterIDQuery, namespaceQuery},
-		LabelQuery:    []string{k8sNameQuery},
-		GeneralParams: []string{"attach_last_operations=true"},
-	}
-	log.Info(fmt.Sprintf("binding recovery query params: %s, %s, %s, %s", nameQuery, clusterIDQuery, namespaceQuery, k8sNameQuery))
+	generalParams, withLastOperation := recoveryParams(ctx, smClient)
+	for _, k8sNameQuery := range k8sNameLabelQueries(r.Config.SMLabels.For(serviceOfferingName), serviceBinding.Name) {
+		parameters := sm.Parameters{
+			FieldQuery:    []string{nameQuery, clusterIDQuery, namespaceQuery},
+			LabelQuery:    k8sNameQuery,
+			GeneralParams: generalParams,
+		}
+		log.Info(fmt.Sprintf("binding recovery query params: %s, %s, %s, %v", nameQuery, clusterIDQuery, namespaceQuery, k8sNameQuery))
 
-	bindings, err := smClient.ListBindings(&parameters)
-	if err != nil {
-		log.Error(err, "failed to list bindings in SM")
-		return nil, err
-	}
-	if bindings != nil {
-		log.Info(fmt.Sprintf("found %d bindings", len(bindings.ServiceBindings)))
-		if len(bindings.ServiceBindings) == 1 {
-			return &bindings.ServiceBindings[0], nil
+		bindings, err := smClient.ListBindings(&parameters)
+		if err != nil {
+			log.Error(err, "failed to list bindings in SM")
+			return nil, err
+		}
+		if bindings != nil {
+			log.Info(fmt.Sprintf("found %d bindings", len(bindings.ServiceBindings)))
+			if len(bindings.ServiceBindings) == 1 {
+				if requireLastOperation && !withLastOperation {
+					return nil, errRecoveryWithoutLastOperation
+				}
+				return &bindings.ServiceBindings[0], nil
+			}
 		}
 	}
 	return nil, nil
@@ -451,13 +663,51 @@ func (r *ServiceBindingReconciler) getBindingForRecovery(ctx context.Context, sm
 func (r *ServiceBindingReconciler) maintain(ctx context.Context, binding *servicesv1.ServiceBinding) (ctrl.Result, error) {
 	log := GetLogger(ctx)
 	shouldUpdateStatus := false
-	if binding.Generation != binding.Status.ObservedGeneration {
+	generationChanged := binding.Generation != binding.Status.ObservedGeneration
+	if generationChanged {
 		binding.SetObservedGeneration(binding.Generation)
 		shouldUpdateStatus = true
 	}
 
-	if !isFailed(binding) {
-		if _, err := r.getSecret(ctx, binding.Namespace, binding.Spec.SecretName); err != nil {
+	if !isFailed(binding) && hasCredentialsTarget(binding) {
+		// the credentials are read from SM and written again when they were changed in the credentials target
+		drifted, err := r.credentialsTargetDrifted(ctx, binding)
+		if err != nil {
+			log.Error(err, "failed to check the credentials target of the binding")
+			return ctrl.Result{}, err
+		}
+		if drifted {
+			if binding.Annotations == nil {
+				binding.Annotations = map[string]string{}
+			}
+			binding.Annotations[api.RefreshSecretAnnotation] = CredentialsTargetDrifted
+			return ctrl.Result{}, r.Client.Update(ctx, binding)
+		}
+	} else if !isFailed(binding) {
+		secretKey := r.bindingSecretKey(binding)
+		if secret, err := r.getSecret(ctx, secretKey.Namespace, secretKey.Name); err == nil {
+			// the consumers of a secret in the secrets namespace may change after the secret was stored
+			if generationChanged && len(r.Config.BindingSecretsNamespace) > 0 {
+				if err := r.bindSecretConsumers(ctx, binding, secret); err != nil {
+					log.Error(err, "failed to update the consumers of the binding secret")
+					return ctrl.Result{}, err
+				}
+			}
+			// the references of the service accounts are restored when they were removed
+			imagePullSecretsChanged, err := r.syncImagePullSecrets(ctx, binding, secret)
+			if err != nil {
+				log.Error(err, "failed to update the image pull secrets of the service accounts")
+				return ctrl.Result{}, err
+			}
+			shouldUpdateStatus = shouldUpdateStatus || imagePullSecretsChanged
+			// the replicas are restored and the target namespaces may permit the replication later
+			reflectionsChanged, err := r.syncSecretReflections(ctx, binding, secret)
+			if err != nil {
+				log.Error(err, "failed to replicate the binding secret to the target namespaces")
+				return ctrl.Result{}, err
+			}
+			shouldUpdateStatus = shouldUpdateStatus || reflectionsChanged
+		} else {
 			if apierrors.IsNotFound(err) && !isMarkedForDeletion(binding.ObjectMeta) {
 				log.Info(fmt.Sprintf("secret not found recovering binding %s", binding.Name))
 				binding.Status.BindingID = ""
@@ -471,23 +721,52 @@ func (r *ServiceBindingReconciler) maintain(ctx context.Context, binding *servic
 		}
 	}
 
+	nextRotation := getNextCredRotationTime(binding)
+	if !nextRotation.Equal(binding.Status.NextCredentialsRotationTime) {
+		binding.Status.NextCredentialsRotationTime = nextRotation
+		shouldUpdateStatus = true
+	}
+
+	if phaseOutdated(binding) {
+		shouldUpdateStatus = true
+	}
+
 	if shouldUpdateStatus {
 		log.Info(fmt.Sprintf("maintanance required for binding %s", binding.Name))
 		return ctrl.Result{}, r.updateStatus(ctx, binding)
 	}
 
-	return ctrl.Result{}, nil
+	return requeueForRotation(nextRotation), nil
+}
+
+// requeueForRotation requeues the binding at its next rotation. A rotation that is due is requeued with the backoff of
+// the controller, since a zero RequeueAfter does not requeue and the rotation may not be due yet at the truncated time.
+func requeueForRotation(nextRotation *metav1.Time) ctrl.Result {
+	if nextRotation == nil {
+		return ctrl.Result{}
+	}
+	if requeueAfter := time.Until(nextRotation.Time); requeueAfter > 0 {
+		return ctrl.Result{RequeueAfter: requeueAfter}
+	}
+	return ctrl.Result{Requeue: true}
 }
 
 func (r *ServiceBindingReconciler) getServiceInstanceForBinding(ctx context.Context, binding *servicesv1.ServiceBinding) (*servicesv1.ServiceInstance, error) {
 	log := GetLogger(ctx)
+	if len(binding.Spec.ServiceInstanceID) > 0 {
+		return sharedInstanceOf(binding), nil
+	}
 	serviceInstance := &servicesv1.ServiceInstance{}
 	namespace := binding.Namespace
 	if len(binding.Spec.ServiceInstanceNamespace) > 0 {
 		namespace = binding.Spec.ServiceInstanceNamespace
 	}
+	key := types.NamespacedName{Name: binding.Spec.ServiceInstanceName, Namespace: namespace}
+	if snapshot := instanceSnapshot(ctx, key); snapshot != nil {
+		return snapshot.DeepCopy(), nil
+	}
 	log.Info(fmt.Sprintf("getting service instance named %s in namespace %s for binding %s in namespace %s", binding.Spec.ServiceInstanceName, namespace, binding.Name, binding.Namespace))
-	if err := r.Client.Get(ctx, types.NamespacedName{Name: binding.Spec.ServiceInstanceName, Namespace: namespace}, serviceInstance); err != nil {
+	if err := r.Client.Get(ctx, key, serviceInstance); err != nil {
 		return nil, err
 	}
 
@@ -508,12 +787,12 @@ func (r *ServiceBindingReconciler) setOwner(ctx context.Context, serviceInstance
 	return nil
 }
 
-func (r *ServiceBindingReconciler) resyncBindingStatus(ctx context.Context, k8sBinding *servicesv1.ServiceBinding, smBinding *smClientTypes.ServiceBinding) {
+// resyncBindingStatus sets the status of the binding to the state of the binding in SM, recovered is set when the
+// binding is recovered from SM
+func (r *ServiceBindingReconciler) resyncBindingStatus(ctx context.Context, k8sBinding *servicesv1.ServiceBinding, smBinding *smClientTypes.ServiceBinding, recovered bool) error {
 	k8sBinding.Status.ObservedGeneration = k8sBinding.Generation
 	k8sBinding.Status.BindingID = smBinding.ID
 	k8sBinding.Status.InstanceID = smBinding.ServiceInstanceID
-	k8sBinding.Status.OperationURL = ""
-	k8sBinding.Status.OperationType = ""
 
 	bindingStatus := smClientTypes.SUCCEEDED
 	operationType := smClientTypes.CREATE
@@ -522,52 +801,245 @@ func (r *ServiceBindingReconciler) resyncBindingStatus(ctx context.Context, k8sB
 		bindingStatus = smBinding.LastOperation.State
 		operationType = smBinding.LastOperation.Type
 		description = smBinding.LastOperation.Description
-	} else if !smBinding.Ready {
-		bindingStatus = smClientTypes.FAILED
 	}
 	switch bindingStatus {
 	case smClientTypes.PENDING:
 		fallthrough
 	case smClientTypes.INPROGRESS:
-		k8sBinding.Status.OperationURL = sm.BuildOperationURL(smBinding.LastOperation.ID, smBinding.ID, smClientTypes.ServiceBindingsURL)
-		k8sBinding.Status.OperationType = smBinding.LastOperation.Type
 		setInProgressConditions(ctx, smBinding.LastOperation.Type, smBinding.LastOperation.Description, k8sBinding)
+		return r.operations().start(ctx, k8sBinding, sm.BuildOperationURL(smBinding.LastOperation.ID, smBinding.ID, smClientTypes.ServiceBindingsURL), smBinding.LastOperation.Type)
 	case smClientTypes.SUCCEEDED:
-		setSuccessConditions(operationType, k8sBinding)
+		if recovered {
+			setRecoveredConditions(operationType, k8sBinding)
+		} else {
+			setSuccessConditions(operationType, k8sBinding)
+		}
+		if smBinding.Ready {
+			removeDegradedCondition(k8sBinding)
+		} else {
+			setDegradedConditions(k8sBinding)
+		}
 	case smClientTypes.FAILED:
+		removeDegradedCondition(k8sBinding)
 		setFailureConditions(operationType, description, k8sBinding)
 	}
+	return r.operations().finish(ctx, k8sBinding)
 }
 
-func (r *ServiceBindingReconciler) storeBindingSecret(ctx context.Context, k8sBinding *servicesv1.ServiceBinding, smBinding *smClientTypes.ServiceBinding) error {
+// resyncDegradedBinding reads a binding that SM reported as not ready until it is ready again or its last operation failed
+func (r *ServiceBindingReconciler) resyncDegradedBinding(ctx context.Context, serviceBinding *servicesv1.ServiceBinding, btpAccessCredentialsSecret string) (ctrl.Result, error) {
 	log := GetLogger(ctx)
-	logger := log.WithValues("bindingName", k8sBinding.Name, "secretName", k8sBinding.Spec.SecretName)
+	smClient, err := r.getSMClient(ctx, serviceBinding, btpAccessCredentialsSecret)
+	if err != nil {
+		log.Error(err, "failed to get sm client")
+		return ctrl.Result{}, err
+	}
+
+	smBinding, err := smClient.GetBindingByID(serviceBinding.Status.BindingID, nil)
+	if err != nil {
+		log.Error(err, "failed to get degraded binding from SM")
+		return ctrl.Result{}, err
+	}
+
+	if err := r.resyncBindingStatus(ctx, serviceBinding, smBinding, false); err != nil {
+		return ctrl.Result{}, err
+	}
+	// the credentials may not have been available while the binding was not ready
+	if !isDegraded(serviceBinding) && smBinding.Credentials != nil {
+		if err := r.storeBindingSecret(ctx, serviceBinding, smBinding); err != nil {
+			return r.handleSecretError(ctx, smClientTypes.CREATE, err, serviceBinding)
+		}
+	}
+	if err := r.updateStatus(ctx, serviceBinding); err != nil {
+		return ctrl.Result{}, err
+	}
+	if isDegraded(serviceBinding) {
+		log.Info("binding is still not ready in SM")
+		return ctrl.Result{RequeueAfter: r.pollInterval()}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// buildBindingSecret shapes the credentials read from SM into the binding secret and the config maps of its secret
+// template, the formatted credentials are returned for the metadata config map
+func (r *ServiceBindingReconciler) buildBindingSecret(ctx context.Context, k8sBinding *servicesv1.ServiceBinding, smCredentials json.RawMessage) (*corev1.Secret, json.RawMessage, []*corev1.ConfigMap, error) {
 	var secret *corev1.Secret
-	var err error
+	var configMaps []*corev1.ConfigMap
+
+	credentials, err := r.formatCredentials(ctx, k8sBinding, smCredentials)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
-	if k8sBinding.Spec.SecretTemplate != "" {
-		secret, err = r.createBindingSecretFromSecretTemplate(ctx, k8sBinding, smBinding.Credentials)
+	secretTemplate, err := r.secretTemplateOf(ctx, k8sBinding)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if secretTemplate != "" {
+		// the template references the instance info by its own keys
+		setInstanceInfoConflictCondition(k8sBinding, nil)
+		secret, configMaps, err = r.createBindingSecretFromSecretTemplate(ctx, k8sBinding, secretTemplate, credentials)
 	} else {
-		secret, err = r.createBindingSecret(ctx, k8sBinding, smBinding.Credentials)
+		secret, err = r.createBindingSecret(ctx, k8sBinding, credentials)
+	}
+	if err != nil {
+		return nil, nil, nil, err
 	}
+	return secret, credentials, configMaps, nil
+}
 
+func (r *ServiceBindingReconciler) storeBindingSecret(ctx context.Context, k8sBinding *servicesv1.ServiceBinding, smBinding *smClientTypes.ServiceBinding) error {
+	log := GetLogger(ctx)
+	logger := log.WithValues("bindingName", k8sBinding.Name, "secretName", k8sBinding.Spec.SecretName)
+	secret, credentials, configMaps, err := r.buildBindingSecret(ctx, k8sBinding, smBinding.Credentials)
 	if err != nil {
 		return err
 	}
+	if hasCredentialsTarget(k8sBinding) {
+		return r.storeBindingCredentialsTarget(ctx, k8sBinding, secret, credentials, configMaps)
+	}
+	setSecretTypeAndLabels(k8sBinding, secret)
+	if isTLSBindingSecret(k8sBinding) {
+		if err := setTLSSecretData(k8sBinding, secret, smBinding.Credentials); err != nil {
+			return err
+		}
+	}
+	if k8sBinding.Spec.SecretImmutable {
+		immutable := true
+		secret.Immutable = &immutable
+	}
+
+	if err := r.encryptSecretKeys(ctx, k8sBinding, secret); err != nil {
+		logger.Error(err, "Failed to encrypt binding secret keys")
+		return err
+	}
 
-	if err := controllerutil.SetControllerReference(k8sBinding, secret, r.Scheme); err != nil {
+	if err := r.setSecretOwner(ctx, k8sBinding, secret); err != nil {
 		logger.Error(err, "Failed to set secret owner")
 		return err
 	}
 
-	return r.createOrUpdateBindingSecret(ctx, k8sBinding, secret)
+	if err := r.createOrUpdateBindingSecret(ctx, k8sBinding, secret); err != nil {
+		return err
+	}
+	if _, err := r.syncImagePullSecrets(ctx, k8sBinding, secret); err != nil {
+		logger.Error(err, "Failed to update the image pull secrets of the service accounts")
+		return err
+	}
+	if _, err := r.syncSecretReflections(ctx, k8sBinding, secret); err != nil {
+		logger.Error(err, "Failed to replicate the binding secret to the target namespaces")
+		return err
+	}
+	metadataConfigMap, err := r.bindingMetadataConfigMap(ctx, k8sBinding, credentials, configMaps)
+	if err != nil {
+		return err
+	}
+	if metadataConfigMap != nil {
+		configMaps = append(configMaps, metadataConfigMap)
+	}
+	k8sBinding.Status.Binding = provisionedServiceBinding(k8sBinding, secret)
+	if err := r.fenceBindingSecret(ctx, k8sBinding, configMaps); err != nil {
+		logger.Error(err, "Failed to grant access to the binding secret")
+		return err
+	}
+	if err := r.createOrUpdateBindingConfigMaps(ctx, k8sBinding, configMaps); err != nil {
+		return err
+	}
+	if err := r.createOrUpdateBindingTLSSecret(ctx, k8sBinding, smBinding.Credentials); err != nil {
+		return err
+	}
+	if err := r.createOrUpdateBindingSecretOutputs(ctx, k8sBinding, credentials); err != nil {
+		return err
+	}
+	r.secretRetries.Delete(k8sBinding.UID)
+	return nil
 }
 
-// createBindingSecretFromSecretTemplate executes the template of .Spec.SecretTemplate
-func (r *ServiceBindingReconciler) createBindingSecretFromSecretTemplate(ctx context.Context, k8sBinding *servicesv1.ServiceBinding, inputSmCredentials json.RawMessage) (*corev1.Secret, error) {
+// formatCredentials shapes the credentials using the formatter requested in .Spec.SecretFormat, the one preset for the instance offering
+// or the one registered for it
+func (r *ServiceBindingReconciler) formatCredentials(ctx context.Context, k8sBinding *servicesv1.ServiceBinding, credentials json.RawMessage) (json.RawMessage, error) {
+	log := GetLogger(ctx)
+	if len(credentials) == 0 {
+		return credentials, nil
+	}
+	if !r.Config.FeatureGates.Enabled(config.SecretFormatters) {
+		if len(k8sBinding.Spec.SecretFormat) > 0 {
+			log.Info(fmt.Sprintf("secret formatters are disabled, ignoring secret format %s", k8sBinding.Spec.SecretFormat))
+		}
+		return credentials, nil
+	}
+
+	instance, err := r.getServiceInstanceForBinding(ctx, k8sBinding)
+	if err != nil {
+		return nil, err
+	}
+
+	secretFormatter, explicit, err := formatter.Resolve(r.secretFormatOf(k8sBinding, instance.Spec.ServiceOfferingName), instance.Spec.ServiceOfferingName)
+	if err != nil {
+		return nil, err
+	}
+	// credentials that do not match a preset format are stored as is, like those of the formats registered for offerings
+	explicit = explicit && len(k8sBinding.Spec.SecretFormat) > 0
+	if secretFormatter == nil {
+		return credentials, nil
+	}
+
+	var credentialsMap map[string]interface{}
+	if err := json.Unmarshal(credentials, &credentialsMap); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal given service binding credentials")
+	}
+
+	formatted, err := secretFormatter.Format(credentialsMap)
+	if err != nil {
+		if explicit {
+			return nil, err
+		}
+		log.Info(fmt.Sprintf("credentials do not match the format of offering %s, storing them as is: %s", instance.Spec.ServiceOfferingName, err.Error()))
+		return credentials, nil
+	}
+
+	return json.Marshal(formatted)
+}
+
+// createBindingSecretFromSecretTemplate executes the secret template of the binding and returns the secret and the config maps it generates
+func (r *ServiceBindingReconciler) createBindingSecretFromSecretTemplate(ctx context.Context, k8sBinding *servicesv1.ServiceBinding, secretTemplate string, inputSmCredentials json.RawMessage) (*corev1.Secret, []*corev1.ConfigMap, error) {
 	log := GetLogger(ctx)
 	log.Info("Create Object using SecretTemplate from ServiceBinding Specs")
 
+	parameters, err := r.secretTemplateParameters(ctx, k8sBinding, inputSmCredentials)
+	if err != nil {
+		return nil, nil, err
+	}
+	smBindingCredentials := parameters[secretTemplateSmBindingKey].(map[string]interface{})
+
+	// the delimiters apply to the template of the binding, the template presets use the default delimiters
+	var delims template.Delimiters
+	if len(k8sBinding.Spec.SecretTemplate) > 0 {
+		delims = k8sBinding.Spec.SecretTemplateDelimiters.Delimiters()
+	}
+	templateName := fmt.Sprintf("%s/%s", k8sBinding.Namespace, k8sBinding.Name)
+	r.checkSecretTemplateFields(ctx, k8sBinding, smBindingCredentials)
+	secret, configMaps, err := template.CreateObjectsFromTemplate(templateName, secretTemplate, delims, parameters)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create secret from template")
+	}
+	recordSecretTemplateFields(ctx, k8sBinding, templateName, secretTemplate, delims, smBindingCredentials)
+
+	secretKey := r.bindingSecretKey(k8sBinding)
+	secret.SetNamespace(secretKey.Namespace)
+	secret.SetName(secretKey.Name)
+	for _, configMap := range configMaps {
+		configMapKey := r.bindingObjectKey(k8sBinding, configMap.Name)
+		configMap.SetNamespace(configMapKey.Namespace)
+		configMap.SetName(configMapKey.Name)
+	}
+
+	return secret, configMaps, nil
+}
+
+// secretTemplateParameters returns the data secret templates are executed with: the credentials, the infos of the instance
+// and the parameters of the instance and of the binding
+func (r *ServiceBindingReconciler) secretTemplateParameters(ctx context.Context, k8sBinding *servicesv1.ServiceBinding, inputSmCredentials json.RawMessage) (map[string]interface{}, error) {
 	smBindingCredentials := make(map[string]interface{})
 	if inputSmCredentials != nil {
 		err := json.Unmarshal(inputSmCredentials, &smBindingCredentials)
@@ -576,36 +1048,33 @@ func (r *ServiceBindingReconciler) createBindingSecretFromSecretTemplate(ctx con
 		}
 	}
 
-	instanceInfos := make(map[string][]byte)
-	_, err := r.addInstanceInfo(ctx, k8sBinding, instanceInfos)
+	instance, err := r.getServiceInstanceForBinding(ctx, k8sBinding)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to add service instance info")
 	}
+	instanceInfos := make(map[string][]byte)
+	if _, _, err := r.addInstanceInfo(ctx, k8sBinding, instanceInfos); err != nil {
+		return nil, errors.Wrap(err, "failed to add service instance info")
+	}
 
-	//convert the bytes to string to ensure, that the secret can be created later by CreateSecretFromTemplate
+	//convert the bytes to string to ensure, that the secret can be created later by CreateObjectsFromTemplate
 	convertedInstanceInfos := make(map[string]string)
 	for k, v := range instanceInfos {
 		convertedInstanceInfos[k] = string(v)
 	}
 
-	parameters := map[string]interface{}{
+	return map[string]interface{}{
 		secretTemplateSmBindingKey:         smBindingCredentials,
 		secretTemplateServiceInstanceInfos: convertedInstanceInfos,
-	}
-
-	templateName := fmt.Sprintf("%s/%s", k8sBinding.Namespace, k8sBinding.Name)
-	secret, err := template.CreateSecretFromTemplate(templateName, k8sBinding.Spec.SecretTemplate, parameters)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create secret from template")
-	}
-
-	secret.SetNamespace(k8sBinding.Namespace)
-	secret.SetName(k8sBinding.Spec.SecretName)
-
-	return secret, nil
+		secretTemplateInstanceParameters:   templateParameters(instance.Spec.Parameters, instance.Spec.ParametersMergeStrategy),
+		secretTemplateBindingParameters:    templateParameters(k8sBinding.Spec.Parameters, k8sBinding.Spec.ParametersMergeStrategy),
+	}, nil
 }
 
 func (r *ServiceBindingReconciler) createBindingSecret(ctx context.Context, k8sBinding *servicesv1.ServiceBinding, credentials json.RawMessage) (*corev1.Secret, error) {
+	if isSecretFile(k8sBinding) {
+		return r.createBindingSecretFile(ctx, k8sBinding, credentials)
+	}
 	log := GetLogger(ctx)
 	logger := log.WithValues("bindingName", k8sBinding.Name, "secretName", k8sBinding.Spec.SecretName)
 	var credentialsMap map[string][]byte
@@ -634,10 +1103,16 @@ func (r *ServiceBindingReconciler) createBindingSecret(ctx context.Context, k8sB
 		}
 	}
 
-	metaDataProperties, err := r.addInstanceInfo(ctx, k8sBinding, credentialsMap)
+	metaDataProperties, conflicts, err := r.addInstanceInfo(ctx, k8sBinding, credentialsMap)
 	if err != nil {
 		log.Error(err, "failed to enrich binding with service instance info")
 	}
+	if isServiceBindingLayout(k8sBinding) {
+		metaDataProperties = append(metaDataProperties, addServiceBindingEntries(credentialsMap)...)
+	}
+	credentialsMap = mapSecretData(k8sBinding, credentialsMap)
+	credentialProperties = mapSecretMetadata(k8sBinding, credentialProperties)
+	metaDataProperties = mapSecretMetadata(k8sBinding, metaDataProperties)
 
 	if k8sBinding.Spec.SecretRootKey != nil {
 		var err error
@@ -654,15 +1129,21 @@ func (r *ServiceBindingReconciler) createBindingSecret(ctx context.Context, k8sB
 		if err != nil {
 			log.Error(err, "failed to enrich binding with metadata")
 		} else {
-			credentialsMap[".metadata"] = metadataByte
+			// the key is reserved for the metadata, a credential of the broker with this key is always replaced
+			if _, ok := credentialsMap[secretMetadataKey]; ok {
+				conflicts = append(conflicts, secretMetadataKey)
+			}
+			credentialsMap[secretMetadataKey] = metadataByte
 		}
 	}
+	setInstanceInfoConflictCondition(k8sBinding, conflicts)
 
+	secretKey := r.bindingSecretKey(k8sBinding)
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:        k8sBinding.Spec.SecretName,
+			Name:        secretKey.Name,
 			Annotations: map[string]string{"binding": k8sBinding.Name},
-			Namespace:   k8sBinding.Namespace,
+			Namespace:   secretKey.Namespace,
 		},
 		Data: credentialsMap,
 	}
@@ -670,43 +1151,402 @@ func (r *ServiceBindingReconciler) createBindingSecret(ctx context.Context, k8sB
 	return secret, nil
 }
 
+// setSecretOwner sets the binding as the controller of the secret, or the object referenced by SecretOwnerRef
+// in which case the secret is annotated with the UID of the binding. The operator is permitted to read only the kinds
+// accepted by the webhook. Secrets in the secrets namespace have no owner and are deleted together with the binding.
+func (r *ServiceBindingReconciler) setSecretOwner(ctx context.Context, binding *servicesv1.ServiceBinding, secret *corev1.Secret) error {
+	if len(r.Config.BindingSecretsNamespace) > 0 {
+		annotateSecretBinding(binding, secret)
+		return nil
+	}
+
+	ownerRef := binding.Spec.SecretOwnerRef
+	if ownerRef == nil {
+		return controllerutil.SetControllerReference(binding, secret, r.Scheme)
+	}
+
+	owner := &unstructured.Unstructured{}
+	owner.SetAPIVersion(ownerRef.APIVersion)
+	owner.SetKind(ownerRef.Kind)
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: binding.Namespace, Name: ownerRef.Name}, owner); err != nil {
+		if apierrors.IsForbidden(err) {
+			return fmt.Errorf("the operator is not permitted to read the secret owner %s '%s', supported kinds are Deployment, StatefulSet and Job: %w", ownerRef.Kind, ownerRef.Name, err)
+		}
+		return fmt.Errorf("failed to get the secret owner %s '%s': %w", ownerRef.Kind, ownerRef.Name, err)
+	}
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[api.SecretBindingUIDAnnotation] = string(binding.UID)
+	return controllerutil.SetControllerReference(owner, secret, r.Scheme)
+}
+
+// mergeSecretOwner sets the owner set by setSecretOwner on an existing secret, e.g. one written before secretOwnerRef was
+// supported, and records the binding in its annotations. Owner references of other actors are kept.
+func mergeSecretOwner(dbSecret, secret *corev1.Secret) {
+	ownerRefs := make([]metav1.OwnerReference, 0, len(dbSecret.OwnerReferences)+len(secret.OwnerReferences))
+	for _, ownerRef := range dbSecret.OwnerReferences {
+		if ownerRef.Controller == nil || !*ownerRef.Controller {
+			ownerRefs = append(ownerRefs, ownerRef)
+		}
+	}
+	dbSecret.OwnerReferences = append(ownerRefs, secret.OwnerReferences...)
+
+	for _, key := range []string{api.SecretBindingUIDAnnotation, api.SecretBindingNamespaceAnnotation} {
+		value, ok := secret.Annotations[key]
+		if !ok {
+			delete(dbSecret.Annotations, key)
+			continue
+		}
+		if dbSecret.Annotations == nil {
+			dbSecret.Annotations = map[string]string{}
+		}
+		dbSecret.Annotations[key] = value
+	}
+}
+
+// encryptSecretKeys replaces the values of the keys in .Spec.EncryptKeys with their encryption by the public key
+// referenced in .Spec.EncryptionKeyRef, the encrypted keys are listed in an annotation of the secret
+func (r *ServiceBindingReconciler) encryptSecretKeys(ctx context.Context, binding *servicesv1.ServiceBinding, secret *corev1.Secret) error {
+	if len(binding.Spec.EncryptKeys) == 0 || binding.Spec.EncryptionKeyRef == nil {
+		return nil
+	}
+
+	publicKey, err := r.getEncryptionKey(ctx, binding)
+	if err != nil {
+		return err
+	}
+
+	secret.Data = desiredSecretData(secret)
+	secret.StringData = nil
+	for _, key := range binding.Spec.EncryptKeys {
+		value, ok := secret.Data[key]
+		if !ok {
+			return fmt.Errorf(encryptKeyNotFoundErrorFormat, key)
+		}
+		if secret.Data[key], err = encryption.Encrypt(publicKey, value); err != nil {
+			return errors.Wrapf(err, "failed to encrypt key '%s'", key)
+		}
+	}
+
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[api.SecretEncryptedKeysAnnotation] = strings.Join(binding.Spec.EncryptKeys, ",")
+	return nil
+}
+
+// getEncryptionKey reads the public key referenced in .Spec.EncryptionKeyRef from a config map or a secret
+func (r *ServiceBindingReconciler) getEncryptionKey(ctx context.Context, binding *servicesv1.ServiceBinding) (*rsa.PublicKey, error) {
+	keyRef := binding.Spec.EncryptionKeyRef
+	objectKey := types.NamespacedName{Namespace: binding.Namespace, Name: keyRef.Name}
+
+	var keyPEM []byte
+	if keyRef.Kind == "Secret" {
+		keySecret := &corev1.Secret{}
+		if err := r.Client.Get(ctx, objectKey, keySecret); err != nil {
+			return nil, err
+		}
+		keyPEM = keySecret.Data[keyRef.Key]
+	} else {
+		configMap := &corev1.ConfigMap{}
+		if err := r.Client.Get(ctx, objectKey, configMap); err != nil {
+			return nil, err
+		}
+		keyPEM = []byte(configMap.Data[keyRef.Key])
+		if len(keyPEM) == 0 {
+			keyPEM = configMap.BinaryData[keyRef.Key]
+		}
+	}
+
+	if len(keyPEM) == 0 {
+		return nil, fmt.Errorf("the key '%s' was not found in %s '%s'", keyRef.Key, keyRef.Kind, keyRef.Name)
+	}
+	publicKey, err := encryption.ParsePublicKey(keyPEM)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid public key in %s '%s'", keyRef.Kind, keyRef.Name)
+	}
+	return publicKey, nil
+}
+
+// createOrUpdateBindingSecret writes the secret and retries with a backoff when it was written concurrently by another actor.
+// Keys added to an existing secret by other actors are kept, the keys written by the operator are recorded in an annotation.
 func (r *ServiceBindingReconciler) createOrUpdateBindingSecret(ctx context.Context, binding *servicesv1.ServiceBinding, secret *corev1.Secret) error {
 	log := GetLogger(ctx)
-	dbSecret := &corev1.Secret{}
-	create := false
-	if err := r.Client.Get(ctx, types.NamespacedName{Name: binding.Spec.SecretName, Namespace: binding.Namespace}, dbSecret); err != nil {
-		if !apierrors.IsNotFound(err) {
+	data := desiredSecretData(secret)
+	secret.Data = data
+	secret.StringData = nil
+	setSecretManagedKeys(secret, data)
+
+	isConflict := func(err error) bool {
+		if apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err) {
+			log.Info("binding secret was written concurrently, retrying", "name", secret.Name)
+			secretWriteConflicts.Inc()
+			return true
+		}
+		return false
+	}
+	return retry.OnError(retry.DefaultBackoff, isConflict, func() error {
+		dbSecret := &corev1.Secret{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}, dbSecret); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+			log.Info("Creating binding secret", "name", secret.Name)
+			if err := r.Client.Create(ctx, secret.DeepCopy()); err != nil {
+				return err
+			}
+			r.secretWrites.Store(binding.UID, hashSecretData(data))
+			r.Recorder.Event(binding, corev1.EventTypeNormal, "SecretCreated", "SecretCreated")
+			return nil
+		}
+
+		r.checkSecretReverted(binding, dbSecret)
+		if secretTypeChanged(dbSecret, secret) {
+			log.Info("Recreating binding secret with a new type", "name", secret.Name, "type", secret.Type)
+			return r.recreateBindingSecret(ctx, binding, dbSecret, secret, data)
+		}
+		currentSecret := dbSecret.DeepCopy()
+		mergeSecretOwner(dbSecret, secret)
+		mergeSecretLabels(binding, dbSecret)
+		dbSecret.Data = mergeSecretData(dbSecret, data)
+		dbSecret.StringData = nil
+		setSecretManagedKeys(dbSecret, data)
+		if isSecretImmutable(currentSecret) && (!isSecretImmutable(secret) || !equality.Semantic.DeepEqual(currentSecret.Data, dbSecret.Data)) {
+			// the data of immutable secrets cannot be updated, their labels and annotations can
+			log.Info("Recreating immutable binding secret", "name", secret.Name)
+			return r.recreateBindingSecret(ctx, binding, dbSecret, secret, data)
+		}
+		dbSecret.Immutable = secret.Immutable
+		if !secretChanged(currentSecret, dbSecret) && isSecretImmutable(currentSecret) == isSecretImmutable(dbSecret) {
+			// every pod watching the secret would be woken up by a write of identical data
+			log.Info("binding secret is up to date, skipping the update", "name", secret.Name)
+			secretWritesSkipped.Inc()
+			r.secretWrites.Store(binding.UID, hashSecretData(data))
+			return nil
+		}
+		log.Info("Updating existing binding secret", "name", secret.Name)
+		if err := r.Client.Update(ctx, dbSecret); err != nil {
 			return err
 		}
-		create = true
+		r.secretWrites.Store(binding.UID, hashSecretData(data))
+		return nil
+	})
+}
+
+// recreateBindingSecret deletes the secret and creates it again, for changes that cannot be updated
+func (r *ServiceBindingReconciler) recreateBindingSecret(ctx context.Context, binding *servicesv1.ServiceBinding, dbSecret, secret *corev1.Secret, data map[string][]byte) error {
+	if err := r.Client.Delete(ctx, dbSecret); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err := r.Client.Create(ctx, secret.DeepCopy()); err != nil {
+		return err
 	}
+	r.secretWrites.Store(binding.UID, hashSecretData(data))
+	return nil
+}
+
+// isSecretImmutable returns true if the data of the secret cannot be updated
+func isSecretImmutable(secret *corev1.Secret) bool {
+	return secret.Immutable != nil && *secret.Immutable
+}
 
-	if create {
-		log.Info("Creating binding secret", "name", secret.Name)
-		if err := r.Client.Create(ctx, secret); err != nil {
-			if !apierrors.IsAlreadyExists(err) {
+// checkSecretReverted records an event when the keys written by the operator were modified by another actor more than once,
+// the writes of the operator are tracked since it started
+func (r *ServiceBindingReconciler) checkSecretReverted(binding *servicesv1.ServiceBinding, dbSecret *corev1.Secret) {
+	writtenHash, ok := r.secretWrites.Load(binding.UID)
+	if !ok {
+		return
+	}
+	managedData := make(map[string][]byte)
+	for _, key := range secretManagedKeys(dbSecret) {
+		if value, ok := dbSecret.Data[key]; ok {
+			managedData[key] = value
+		}
+	}
+	if hashSecretData(managedData) == writtenHash {
+		return
+	}
+
+	reverts := 1
+	if value, ok := r.secretReverts.Load(binding.UID); ok {
+		reverts = value.(int) + 1
+	}
+	r.secretReverts.Store(binding.UID, reverts)
+	if reverts >= secretRevertsEventThreshold {
+		r.Recorder.Event(binding, corev1.EventTypeWarning, "SecretReverted",
+			fmt.Sprintf("the keys of secret %s written by the operator were modified by another actor %d times", dbSecret.Name, reverts))
+	}
+}
+
+// secretChanged reports whether the data, labels or annotations of the secret differ from the current ones
+func secretChanged(current, desired *corev1.Secret) bool {
+	return !equality.Semantic.DeepEqual(current.Data, desired.Data) ||
+		!equality.Semantic.DeepEqual(current.OwnerReferences, desired.OwnerReferences) ||
+		!equality.Semantic.DeepEqual(current.Labels, desired.Labels) ||
+		!equality.Semantic.DeepEqual(current.Annotations, desired.Annotations)
+}
+
+// desiredSecretData returns the data of the secret with its string data merged in, like the API server stores it
+func desiredSecretData(secret *corev1.Secret) map[string][]byte {
+	data := make(map[string][]byte, len(secret.Data)+len(secret.StringData))
+	for key, value := range secret.Data {
+		data[key] = value
+	}
+	for key, value := range secret.StringData {
+		data[key] = []byte(value)
+	}
+	return data
+}
+
+// mergeSecretData returns the data written by the operator together with the keys that other actors added to the secret,
+// secrets without the managed keys annotation were written by an older version and their data is replaced
+func mergeSecretData(dbSecret *corev1.Secret, data map[string][]byte) map[string][]byte {
+	merged := make(map[string][]byte, len(data))
+	for key, value := range data {
+		merged[key] = value
+	}
+	if _, ok := dbSecret.Annotations[api.SecretManagedKeysAnnotation]; !ok {
+		return merged
+	}
+
+	previouslyManaged := make(map[string]bool)
+	for _, key := range secretManagedKeys(dbSecret) {
+		previouslyManaged[key] = true
+	}
+	for key, value := range dbSecret.Data {
+		if _, ok := merged[key]; !ok && !previouslyManaged[key] {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+func setSecretManagedKeys(secret *corev1.Secret, data map[string][]byte) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[api.SecretManagedKeysAnnotation] = strings.Join(keys, ",")
+}
+
+func secretManagedKeys(secret *corev1.Secret) []string {
+	keys := secret.Annotations[api.SecretManagedKeysAnnotation]
+	if len(keys) == 0 {
+		return nil
+	}
+	return strings.Split(keys, ",")
+}
+
+func hashSecretData(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write(data[key])
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// createOrUpdateBindingConfigMaps stores the config maps generated by the secret template and deletes the ones
+// no longer generated, they are owned by the binding and garbage collected together with it. The config maps are not
+// cached, so they are read through the API reader.
+func (r *ServiceBindingReconciler) createOrUpdateBindingConfigMaps(ctx context.Context, binding *servicesv1.ServiceBinding, configMaps []*corev1.ConfigMap) error {
+	log := GetLogger(ctx)
+	generated := make(map[string]bool, len(configMaps))
+	for _, configMap := range configMaps {
+		generated[configMap.Name] = true
+		if err := r.setConfigMapOwner(binding, configMap); err != nil {
+			return err
+		}
+
+		dbConfigMap := &corev1.ConfigMap{}
+		if err := r.apiReader().Get(ctx, types.NamespacedName{Name: configMap.Name, Namespace: configMap.Namespace}, dbConfigMap); err != nil {
+			if !apierrors.IsNotFound(err) {
 				return err
 			}
-			return nil
+			log.Info("Creating binding config map", "name", configMap.Name)
+			if err := r.Client.Create(ctx, configMap); err != nil {
+				return err
+			}
+			continue
 		}
-		r.Recorder.Event(binding, corev1.EventTypeNormal, "SecretCreated", "SecretCreated")
+
+		if !isBindingConfigMap(binding, dbConfigMap) {
+			return fmt.Errorf(configMapNameTakenErrorFormat, configMap.Name)
+		}
+		log.Info("Updating existing binding config map", "name", configMap.Name)
+		dbConfigMap.OwnerReferences = configMap.OwnerReferences
+		dbConfigMap.Labels = configMap.Labels
+		dbConfigMap.Annotations = configMap.Annotations
+		dbConfigMap.Data = configMap.Data
+		dbConfigMap.BinaryData = configMap.BinaryData
+		if err := r.Client.Update(ctx, dbConfigMap); err != nil {
+			return err
+		}
+	}
+	return r.pruneBindingConfigMaps(ctx, binding, generated)
+}
+
+// setConfigMapOwner sets the binding as the controller of a generated config map and labels it with the UID of the
+// binding, config maps in the secrets namespace have no owner and are deleted together with the binding
+func (r *ServiceBindingReconciler) setConfigMapOwner(binding *servicesv1.ServiceBinding, configMap *corev1.ConfigMap) error {
+	labels := configMap.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[api.BindingUIDLabel] = string(binding.UID)
+	configMap.SetLabels(labels)
+
+	if len(r.Config.BindingSecretsNamespace) > 0 {
+		annotateSecretBinding(binding, configMap)
 		return nil
 	}
+	return controllerutil.SetControllerReference(binding, configMap, r.Scheme)
+}
 
-	log.Info("Updating existing binding secret", "name", secret.Name)
-	dbSecret.Data = secret.Data
-	dbSecret.StringData = secret.StringData
-	return r.Client.Update(ctx, dbSecret)
+// isBindingConfigMap returns true if the config map was generated for the binding
+func isBindingConfigMap(binding *servicesv1.ServiceBinding, configMap *corev1.ConfigMap) bool {
+	return metav1.IsControlledBy(configMap, binding) || configMap.Annotations[api.SecretBindingUIDAnnotation] == string(binding.UID)
+}
+
+// pruneBindingConfigMaps deletes the config maps of the binding which are not generated by its secret template, or as
+// its metadata config map, anymore
+func (r *ServiceBindingReconciler) pruneBindingConfigMaps(ctx context.Context, binding *servicesv1.ServiceBinding, generated map[string]bool) error {
+	configMaps := &corev1.ConfigMapList{}
+	if err := r.apiReader().List(ctx, configMaps,
+		client.InNamespace(r.bindingSecretKey(binding).Namespace),
+		client.MatchingLabels{api.BindingUIDLabel: string(binding.UID)}); err != nil {
+		return err
+	}
+	for i := range configMaps.Items {
+		configMap := &configMaps.Items[i]
+		if generated[configMap.Name] || !isBindingConfigMap(binding, configMap) {
+			continue
+		}
+		GetLogger(ctx).Info("Deleting binding config map which is not generated anymore", "name", configMap.Name)
+		if err := r.Client.Delete(ctx, configMap); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (r *ServiceBindingReconciler) deleteBindingSecret(ctx context.Context, binding *servicesv1.ServiceBinding) error {
 	log := GetLogger(ctx)
 	log.Info("Deleting binding secret")
 	bindingSecret := &corev1.Secret{}
-	if err := r.Client.Get(ctx, types.NamespacedName{
-		Namespace: binding.Namespace,
-		Name:      binding.Spec.SecretName,
-	}, bindingSecret); err != nil {
+	if err := r.Client.Get(ctx, r.bindingSecretKey(binding), bindingSecret); err != nil {
 		if !apierrors.IsNotFound(err) {
 			log.Error(err, "unable to fetch binding secret")
 			return err
@@ -728,12 +1568,46 @@ func (r *ServiceBindingReconciler) deleteBindingSecret(ctx context.Context, bind
 }
 
 func (r *ServiceBindingReconciler) deleteSecretAndRemoveFinalizer(ctx context.Context, serviceBinding *servicesv1.ServiceBinding) (ctrl.Result, error) {
+	if err := r.releaseImagePullSecrets(ctx, serviceBinding); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.releaseSecretReflections(ctx, serviceBinding); err != nil {
+		return ctrl.Result{}, err
+	}
 	// delete binding secret if exist
 	if err := r.deleteBindingSecret(ctx, serviceBinding); err != nil {
 		return ctrl.Result{}, err
 	}
+	if hasCredentialsTarget(serviceBinding) {
+		if err := r.deleteCredentialsTarget(ctx, serviceBinding); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if len(r.Config.BindingSecretsNamespace) > 0 {
+		// the config maps and generated secrets in the secrets namespace are not garbage collected with the binding
+		if err := r.pruneBindingConfigMaps(ctx, serviceBinding, nil); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.pruneGeneratedBindingSecrets(ctx, serviceBinding, nil); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
 
-	return ctrl.Result{}, r.removeFinalizer(ctx, serviceBinding, api.FinalizerName)
+	if err := r.removeFinalizer(ctx, serviceBinding, api.FinalizerName); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.forgetBinding(serviceBinding)
+	return ctrl.Result{}, nil
+}
+
+// forgetBinding drops the in-memory state of a binding that was removed from the cluster
+func (r *ServiceBindingReconciler) forgetBinding(serviceBinding *servicesv1.ServiceBinding) {
+	r.secretRetries.Delete(serviceBinding.UID)
+	r.secretWrites.Delete(serviceBinding.UID)
+	r.secretReverts.Delete(serviceBinding.UID)
+	r.unbindFailures.Delete(serviceBinding.UID)
+	r.bindSlotWaits.Delete(serviceBinding.UID)
+	r.csiCredentials.Delete(serviceBinding.UID)
 }
 
 func (r *ServiceBindingReconciler) getSecret(ctx context.Context, namespace string, name string) (*corev1.Secret, error) {
@@ -743,12 +1617,17 @@ func (r *ServiceBindingReconciler) getSecret(ctx context.Context, namespace stri
 }
 
 func (r *ServiceBindingReconciler) validateSecretNameIsAvailable(ctx context.Context, binding *servicesv1.ServiceBinding) error {
-	currentSecret, err := r.getSecret(ctx, binding.Namespace, binding.Spec.SecretName)
+	if hasCredentialsTarget(binding) {
+		// the credentials are not stored in a secret
+		return nil
+	}
+	secretKey := r.bindingSecretKey(binding)
+	currentSecret, err := r.getSecret(ctx, secretKey.Namespace, secretKey.Name)
 	if err != nil {
 		return client.IgnoreNotFound(err)
 	}
 
-	if metav1.IsControlledBy(currentSecret, binding) {
+	if metav1.IsControlledBy(currentSecret, binding) || currentSecret.Annotations[api.SecretBindingUIDAnnotation] == string(binding.UID) {
 		return nil
 	}
 
@@ -770,62 +1649,32 @@ func (r *ServiceBindingReconciler) validateSecretNameIsAvailable(ctx context.Con
 func (r *ServiceBindingReconciler) handleSecretError(ctx context.Context, op smClientTypes.OperationCategory, err error, binding *servicesv1.ServiceBinding) (ctrl.Result, error) {
 	log := GetLogger(ctx)
 	log.Error(err, fmt.Sprintf("failed to store secret %s for binding %s", binding.Spec.SecretName, binding.Name))
-	if apierrors.ReasonForError(err) == metav1.StatusReasonUnknown {
-		return r.markAsNonTransientError(ctx, op, err.Error(), binding)
-	}
-	return r.markAsTransientError(ctx, op, err.Error(), binding)
-}
-
-func (r *ServiceBindingReconciler) addInstanceInfo(ctx context.Context, binding *servicesv1.ServiceBinding, credentialsMap map[string][]byte) ([]SecretMetadataProperty, error) {
-	instance, err := r.getServiceInstanceForBinding(ctx, binding)
-	if err != nil {
-		return nil, err
-	}
-
-	credentialsMap["instance_name"] = getInstanceNameForSecretCredentials(instance)
-	credentialsMap["instance_guid"] = []byte(instance.Status.InstanceID)
-	credentialsMap["plan"] = []byte(instance.Spec.ServicePlanName)
-	credentialsMap["label"] = []byte(instance.Spec.ServiceOfferingName)
-	credentialsMap["type"] = []byte(instance.Spec.ServiceOfferingName)
-	if len(instance.Status.Tags) > 0 || len(instance.Spec.CustomTags) > 0 {
-		tagsBytes, err := json.Marshal(mergeInstanceTags(instance.Status.Tags, instance.Spec.CustomTags))
-		if err != nil {
-			return nil, err
+	if !r.Config.StrictSecretErrors {
+		if apierrors.ReasonForError(err) == metav1.StatusReasonUnknown {
+			return r.markAsNonTransientError(ctx, op, err.Error(), binding)
 		}
-		credentialsMap["tags"] = tagsBytes
+		return r.markAsTransientError(ctx, op, err.Error(), binding)
 	}
 
-	metadata := []SecretMetadataProperty{
-		{
-			Name:   "instance_name",
-			Format: string(TEXT),
-		},
-		{
-			Name:   "instance_guid",
-			Format: string(TEXT),
-		},
-		{
-			Name:   "plan",
-			Format: string(TEXT),
-		},
-		{
-			Name:   "label",
-			Format: string(TEXT),
-		},
-		{
-			Name:   "type",
-			Format: string(TEXT),
-		},
+	if !isTransientSecretError(err, r.Config.TransientSecretErrors) {
+		r.secretRetries.Delete(binding.UID)
+		return r.markAsNonTransientError(ctx, op, err.Error(), binding)
 	}
-	if _, ok := credentialsMap["tags"]; ok {
-		metadata = append(metadata, SecretMetadataProperty{Name: "tags", Format: string(JSON)})
+	if r.Config.SecretRetryBudget > 0 {
+		retries := 1
+		if value, ok := r.secretRetries.Load(binding.UID); ok {
+			retries = value.(int) + 1
+		}
+		if retries > r.Config.SecretRetryBudget {
+			r.secretRetries.Delete(binding.UID)
+			return r.markAsNonTransientError(ctx, op, fmt.Sprintf("%s (giving up after %d retries)", err.Error(), r.Config.SecretRetryBudget), binding)
+		}
+		r.secretRetries.Store(binding.UID, retries)
 	}
-
-	return metadata, nil
+	return r.markAsTransientError(ctx, op, err.Error(), binding)
 }
 
-func (r *ServiceBindingReconciler) rotateCredentials(ctx context.Context, binding *servicesv1.ServiceBinding, btpAccessCredentialsSecret string) error {
-	suffix := "-" + RandStringRunes(6)
+func (r *ServiceBindingReconciler) rotateCredentials(ctx context.Context, binding *servicesv1.ServiceBinding, btpAccessCredentialsSecret, serviceOfferingName string) error {
 	log := GetLogger(ctx)
 	if binding.Annotations != nil {
 		if _, ok := binding.Annotations[api.ForceRotateAnnotation]; ok {
@@ -844,9 +1693,11 @@ func (r *ServiceBindingReconciler) rotateCredentials(ctx context.Context, bindin
 			log.Info("Credentials rotation - finished successfully")
 			now := metav1.NewTime(time.Now())
 			binding.Status.LastCredentialsRotationTime = &now
+			recordRotation(binding, true)
 			return r.stopRotation(ctx, binding)
 		} else if isFailed(binding) {
 			log.Info("Credentials rotation - binding failed stopping rotation")
+			recordRotation(binding, false)
 			return r.stopRotation(ctx, binding)
 		}
 		log.Info("Credentials rotation - waiting to finish")
@@ -870,9 +1721,24 @@ func (r *ServiceBindingReconciler) rotateCredentials(ctx context.Context, bindin
 			return err
 		}
 
+		revision, err := r.nextRotationRevision(ctx, smClient, binding)
+		if err != nil {
+			log.Error(err, "Credentials rotation - failed to find a revision for the old binding")
+			setCredRotationInProgressConditions(CredPreparing, err.Error(), binding)
+			if errStatus := r.updateStatus(ctx, binding); errStatus != nil {
+				return errStatus
+			}
+			return err
+		}
+		suffix := rotationSuffix(revision)
+
+		if refused, err := r.refuseRotationOnOwnershipMismatch(ctx, smClient, binding, serviceOfferingName); refused {
+			return err
+		}
+
 		// rename current binding
 		log.Info("Credentials rotation - renaming binding to old in SM", "current", binding.Spec.ExternalName)
-		if _, errRenaming := smClient.RenameBinding(binding.Status.BindingID, binding.Spec.ExternalName+suffix, binding.Name+suffix); errRenaming != nil {
+		if _, errRenaming := smClient.RenameBinding(binding.Status.BindingID, binding.Spec.ExternalName+suffix, r.Config.SMLabels.For(serviceOfferingName).K8SName, binding.Name+suffix); errRenaming != nil {
 			log.Error(errRenaming, "Credentials rotation - failed renaming binding to old in SM", "binding", binding.Spec.ExternalName)
 			setCredRotationInProgressConditions(CredPreparing, errRenaming.Error(), binding)
 			if errStatus := r.updateStatus(ctx, binding); errStatus != nil {
@@ -891,6 +1757,7 @@ func (r *ServiceBindingReconciler) rotateCredentials(ctx context.Context, bindin
 			}
 			return err
 		}
+		binding.Status.CredentialsRotationRevision = revision
 	}
 
 	binding.Status.BindingID = ""
@@ -903,6 +1770,7 @@ func (r *ServiceBindingReconciler) rotateCredentials(ctx context.Context, bindin
 func (r *ServiceBindingReconciler) stopRotation(ctx context.Context, binding *servicesv1.ServiceBinding) error {
 	conditions := binding.GetConditions()
 	meta.RemoveStatusCondition(&conditions, api.ConditionCredRotationInProgress)
+	meta.RemoveStatusCondition(&conditions, api.ConditionRotationDeferred)
 	binding.Status.Conditions = conditions
 	return r.updateStatus(ctx, binding)
 }
@@ -917,47 +1785,101 @@ func (r *ServiceBindingReconciler) createOldBinding(ctx context.Context, suffix
 		api.StaleBindingIDLabel:         binding.Status.BindingID,
 		api.StaleBindingRotationOfLabel: binding.Name,
 	}
+	// the TTL of the stale binding is measured with the clock of the operator
+	oldBinding.Annotations = map[string]string{
+		api.StaleBindingSinceAnnotation: time.Now().UTC().Format(time.RFC3339),
+	}
 	spec := binding.Spec.DeepCopy()
-	spec.CredRotationPolicy.Enabled = false
+	// bindings are also rotated without a rotation policy when the secrets of parametersFrom change
+	if spec.CredRotationPolicy != nil {
+		spec.CredRotationPolicy.Enabled = false
+	}
 	spec.SecretName = spec.SecretName + suffix
 	spec.ExternalName = spec.ExternalName + suffix
+	if spec.TLSSecret != nil && len(spec.TLSSecret.Name) > 0 {
+		spec.TLSSecret.Name = spec.TLSSecret.Name + suffix
+	}
+	for i := range spec.Secrets {
+		spec.Secrets[i].Name = spec.Secrets[i].Name + suffix
+	}
+	// the service accounts keep pulling images with the secret of the binding, which holds the new credentials
+	spec.ImagePullServiceAccounts = nil
+	// the replicas in the target namespaces are updated with the new credentials
+	spec.SecretTargetNamespaces = nil
+	// the metadata does not change with the credentials
+	spec.MetadataConfigMap = ""
+	// the old credentials are kept next to the new ones in Vault until the rotated binding is deleted
+	if spec.CredentialsTarget != nil && spec.CredentialsTarget.Vault != nil {
+		spec.CredentialsTarget.Vault.Path = spec.CredentialsTarget.Vault.Path + suffix
+	}
 	oldBinding.Spec = *spec
 	return r.Client.Create(ctx, oldBinding)
 }
 
 func (r *ServiceBindingReconciler) handleStaleServiceBinding(ctx context.Context, serviceBinding *servicesv1.ServiceBinding) (ctrl.Result, error) {
 	log := GetLogger(ctx)
-	originalBindingName, ok := serviceBinding.Labels[api.StaleBindingRotationOfLabel]
-	if !ok {
-		//if the user removed the "rotationOf" label the stale binding should be deleted otherwise it will remain forever
-		log.Info("missing rotationOf label, unable to fetch original binding, deleting stale")
-		return ctrl.Result{}, r.Client.Delete(ctx, serviceBinding)
-	}
-	origBinding := &servicesv1.ServiceBinding{}
-	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: serviceBinding.Namespace, Name: originalBindingName}, origBinding); err != nil {
-		if apierrors.IsNotFound(err) {
+	expiration, rotated := staleBindingExpiration(ctx, serviceBinding)
+	if !rotated {
+		origBinding, err := r.getOriginalBinding(ctx, serviceBinding)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if origBinding == nil {
+			//if the original binding was deleted or the user removed the "rotationOf" label the stale binding would otherwise remain forever
 			log.Info("original binding not found, deleting stale binding")
-			return ctrl.Result{}, r.Client.Delete(ctx, serviceBinding)
+			return ctrl.Result{}, r.deleteStaleServiceBinding(ctx, serviceBinding)
 		}
-		return ctrl.Result{}, err
-	}
-	if meta.IsStatusConditionTrue(origBinding.Status.Conditions, api.ConditionReady) {
-		return ctrl.Result{}, r.Client.Delete(ctx, serviceBinding)
+
+		readyCondition := meta.FindStatusCondition(origBinding.Status.Conditions, api.ConditionReady)
+		if readyCondition == nil || readyCondition.Status != metav1.ConditionTrue {
+			log.Info("not deleting stale binding since original binding is not ready")
+			if !meta.IsStatusConditionPresentAndEqual(serviceBinding.Status.Conditions, api.ConditionPendingTermination, metav1.ConditionTrue) {
+				pendingTerminationCondition := metav1.Condition{
+					Type:               api.ConditionPendingTermination,
+					Status:             metav1.ConditionTrue,
+					Reason:             api.ConditionPendingTermination,
+					Message:            "waiting for new credentials to be ready",
+					ObservedGeneration: serviceBinding.GetGeneration(),
+				}
+				meta.SetStatusCondition(&serviceBinding.Status.Conditions, pendingTerminationCondition)
+				return ctrl.Result{}, r.updateStatus(ctx, serviceBinding)
+			}
+			return r.maintain(ctx, serviceBinding)
+		}
+		return ctrl.Result{}, r.recordRotation(ctx, serviceBinding, readyCondition.LastTransitionTime.Time)
 	}
 
-	log.Info("not deleting stale binding since original binding is not ready")
-	if !meta.IsStatusConditionPresentAndEqual(serviceBinding.Status.Conditions, api.ConditionPendingTermination, metav1.ConditionTrue) {
-		pendingTerminationCondition := metav1.Condition{
-			Type:               api.ConditionPendingTermination,
-			Status:             metav1.ConditionTrue,
-			Reason:             api.ConditionPendingTermination,
-			Message:            "waiting for new credentials to be ready",
-			ObservedGeneration: serviceBinding.GetGeneration(),
+	if remaining := time.Until(expiration); remaining > 0 {
+		log.Info(fmt.Sprintf("keeping stale binding for %s", remaining.Round(time.Second)))
+		result, err := r.maintain(ctx, serviceBinding)
+		if err == nil && !result.Requeue && (result.RequeueAfter == 0 || result.RequeueAfter > remaining) {
+			result.RequeueAfter = remaining
 		}
-		meta.SetStatusCondition(&serviceBinding.Status.Conditions, pendingTerminationCondition)
-		return ctrl.Result{}, r.updateStatus(ctx, serviceBinding)
+		return result, err
 	}
-	return ctrl.Result{}, nil
+	return ctrl.Result{}, r.deleteStaleServiceBinding(ctx, serviceBinding)
+}
+
+// recordRotation records on the stale binding when the new credentials of the original binding became ready, the TTL
+// of the stale binding counts from then. It is recorded once, so later rotations of the original binding do not extend it.
+func (r *ServiceBindingReconciler) recordRotation(ctx context.Context, staleBinding *servicesv1.ServiceBinding, readySince time.Time) error {
+	rotatedAt := staleBindingSince(ctx, staleBinding)
+	if readySince.After(rotatedAt) {
+		rotatedAt = readySince
+	}
+	GetLogger(ctx).Info(fmt.Sprintf("new credentials are ready since %s, counting the TTL of the stale binding", rotatedAt.UTC().Format(time.RFC3339)))
+	if staleBinding.Annotations == nil {
+		staleBinding.Annotations = map[string]string{}
+	}
+	staleBinding.Annotations[api.StaleBindingRotatedAtAnnotation] = rotatedAt.UTC().Format(time.RFC3339)
+	if err := r.Client.Update(ctx, staleBinding); err != nil {
+		return err
+	}
+	if meta.FindStatusCondition(staleBinding.Status.Conditions, api.ConditionPendingTermination) == nil {
+		return nil
+	}
+	meta.RemoveStatusCondition(&staleBinding.Status.Conditions, api.ConditionPendingTermination)
+	return r.updateStatus(ctx, staleBinding)
 }
 
 func (r *ServiceBindingReconciler) recover(ctx context.Context, serviceBinding *servicesv1.ServiceBinding, smBinding *smClientTypes.ServiceBinding) (ctrl.Result, error) {
@@ -973,27 +1895,79 @@ func (r *ServiceBindingReconciler) recover(ctx context.Context, serviceBinding *
 			return r.handleSecretError(ctx, operationType, err, serviceBinding)
 		}
 	}
-	r.resyncBindingStatus(ctx, serviceBinding, smBinding)
+	if err := r.resyncBindingStatus(ctx, serviceBinding, smBinding, true); err != nil {
+		return ctrl.Result{}, err
+	}
 
 	return ctrl.Result{}, r.updateStatus(ctx, serviceBinding)
 }
 
+// isStaleServiceBinding checks whether the binding holds the old credentials of a rotated binding
 func isStaleServiceBinding(binding *servicesv1.ServiceBinding) bool {
 	if isMarkedForDeletion(binding.ObjectMeta) {
 		return false
 	}
+	_, ok := binding.Labels[api.StaleBindingIDLabel]
+	return ok
+}
 
-	if binding.Labels != nil {
-		if _, ok := binding.Labels[api.StaleBindingIDLabel]; ok {
-			if binding.Spec.CredRotationPolicy != nil {
-				keepFor, _ := time.ParseDuration(binding.Spec.CredRotationPolicy.RotatedBindingTTL)
-				if time.Since(binding.CreationTimestamp.Time) > keepFor {
-					return true
-				}
-			}
+// rotatedBindingTTL returns how long the old credentials of a rotated binding are kept. The TTL is validated on admission,
+// the default TTL is used for stale bindings created before so that they are neither deleted immediately nor kept forever.
+func rotatedBindingTTL(ctx context.Context, staleBinding *servicesv1.ServiceBinding) time.Duration {
+	if staleBinding.Spec.CredRotationPolicy == nil {
+		return defaultRotatedBindingTTL
+	}
+	ttl, err := time.ParseDuration(staleBinding.Spec.CredRotationPolicy.RotatedBindingTTL)
+	if err != nil || ttl < 0 {
+		GetLogger(ctx).Info(fmt.Sprintf("invalid rotatedBindingTTL '%s', keeping the stale binding for %s", staleBinding.Spec.CredRotationPolicy.RotatedBindingTTL, defaultRotatedBindingTTL))
+		return defaultRotatedBindingTTL
+	}
+	return ttl
+}
+
+// getOriginalBinding returns the binding the stale binding was rotated from, nil if the binding or its rotationOf label are gone
+func (r *ServiceBindingReconciler) getOriginalBinding(ctx context.Context, staleBinding *servicesv1.ServiceBinding) (*servicesv1.ServiceBinding, error) {
+	originalBindingName, ok := staleBinding.Labels[api.StaleBindingRotationOfLabel]
+	if !ok {
+		GetLogger(ctx).Info("missing rotationOf label, unable to fetch original binding")
+		return nil, nil
+	}
+	origBinding := &servicesv1.ServiceBinding{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: staleBinding.Namespace, Name: originalBindingName}, origBinding); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	return origBinding, nil
+}
+
+// staleBindingSince returns when the stale binding was created, which is recorded with the clock of the operator in its
+// staleSince annotation so that a clock skew between the operator and the API server does not matter. The creation time of
+// the stale binding is used only for stale bindings created without the annotation.
+func staleBindingSince(ctx context.Context, staleBinding *servicesv1.ServiceBinding) time.Time {
+	staleSince := staleBinding.CreationTimestamp.Time
+	if value, ok := staleBinding.Annotations[api.StaleBindingSinceAnnotation]; ok {
+		if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+			staleSince = parsed
+		} else {
+			GetLogger(ctx).Info(fmt.Sprintf("invalid %s annotation '%s', using the creation of the stale binding", api.StaleBindingSinceAnnotation, value))
 		}
 	}
-	return false
+	return staleSince
+}
+
+// staleBindingExpiration returns the time at which the stale binding is deleted and false if the new credentials of the
+// original binding are not ready yet. The TTL counts from the time the new credentials became ready, an invalid rotatedAt
+// annotation is recorded again.
+func staleBindingExpiration(ctx context.Context, staleBinding *servicesv1.ServiceBinding) (time.Time, bool) {
+	value, ok := staleBinding.Annotations[api.StaleBindingRotatedAtAnnotation]
+	if !ok {
+		return time.Time{}, false
+	}
+	rotatedAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		GetLogger(ctx).Info(fmt.Sprintf("invalid %s annotation '%s'", api.StaleBindingRotatedAtAnnotation, value))
+		return time.Time{}, false
+	}
+	return rotatedAt.Add(rotatedBindingTTL(ctx, staleBinding)), true
 }
 
 func initCredRotationIfRequired(binding *servicesv1.ServiceBinding) bool {
@@ -1017,6 +1991,55 @@ func initCredRotationIfRequired(binding *servicesv1.ServiceBinding) bool {
 	return false
 }
 
+// bindingParametersFromChanged handles a change of the parameters read by parametersFrom since the binding was created.
+// Bindings cannot be updated in SM, if the rotation policy opts in a credentials rotation creates a binding with the new
+// parameters, otherwise the binding reports the ParametersOutdated condition. Returns whether the status changed.
+func (r *ServiceBindingReconciler) bindingParametersFromChanged(ctx context.Context, binding *servicesv1.ServiceBinding) bool {
+	if isFailed(binding) || meta.IsStatusConditionTrue(binding.Status.Conditions, api.ConditionCredRotationInProgress) {
+		return false
+	}
+	outdated := meta.IsStatusConditionTrue(binding.Status.Conditions, api.ConditionParametersOutdated)
+	if !r.parametersFromChanged(ctx, binding.Namespace, binding.Spec.ParametersFrom, binding.Status.HashedParametersFrom) {
+		meta.RemoveStatusCondition(&binding.Status.Conditions, api.ConditionParametersOutdated)
+		return outdated
+	}
+	if binding.Spec.CredRotationPolicy != nil && binding.Spec.CredRotationPolicy.RotateOnParametersChange {
+		GetLogger(ctx).Info("the parameters read by parametersFrom changed, rotating the credentials of the binding")
+		r.Recorder.Event(binding, corev1.EventTypeNormal, "ParametersChanged", "the parameters read by parametersFrom changed, rotating the credentials")
+		setCredRotationInProgressConditions(CredPreparing, "", binding)
+		return true
+	}
+	if outdated {
+		return false
+	}
+	GetLogger(ctx).Info("the parameters read by parametersFrom changed, the binding keeps its credentials until it is rotated")
+	r.Recorder.Event(binding, corev1.EventTypeWarning, "ParametersOutdated", "the parameters read by parametersFrom changed, they apply with the next rotation of the credentials")
+	meta.SetStatusCondition(&binding.Status.Conditions, metav1.Condition{
+		Type:               api.ConditionParametersOutdated,
+		Status:             metav1.ConditionTrue,
+		Reason:             "ParametersChanged",
+		Message:            "the parameters read by parametersFrom changed since the credentials were issued, rotate the credentials to apply them",
+		ObservedGeneration: binding.Generation,
+	})
+	return true
+}
+
+// getNextCredRotationTime returns when the credentials of the binding are rotated next, or nil if rotation is disabled
+func getNextCredRotationTime(binding *servicesv1.ServiceBinding) *metav1.Time {
+	if !credRotationEnabled(binding) {
+		return nil
+	}
+
+	lastCredentialRotationTime := binding.CreationTimestamp.Time
+	if binding.Status.LastCredentialsRotationTime != nil {
+		lastCredentialRotationTime = binding.Status.LastCredentialsRotationTime.Time
+	}
+	rotationInterval, _ := time.ParseDuration(binding.Spec.CredRotationPolicy.RotationFrequency)
+	// status times are persisted with seconds precision
+	next := metav1.NewTime(lastCredentialRotationTime.Add(rotationInterval).Truncate(time.Second))
+	return &next
+}
+
 func credRotationEnabled(binding *servicesv1.ServiceBinding) bool {
 	return binding.Spec.CredRotationPolicy != nil && binding.Spec.CredRotationPolicy.Enabled
 }
@@ -1062,6 +2085,36 @@ func bindingAlreadyOwnedByInstance(instance *servicesv1.ServiceInstance, binding
 	return false
 }
 
+// getPendingReadinessGate returns a message describing the first readiness gate of the binding that is not satisfied,
+// or an empty message if all of them are satisfied. The gated objects are not watched, they are read again every PollInterval.
+func (r *ServiceBindingReconciler) getPendingReadinessGate(ctx context.Context, binding *servicesv1.ServiceBinding) (string, error) {
+	for _, gate := range binding.Spec.ReadinessGates {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(gate.APIVersion)
+		obj.SetKind(gate.Kind)
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: binding.Namespace, Name: gate.Name}, obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				return fmt.Sprintf("waiting for readiness gate, %s '%s' not found", gate.Kind, gate.Name), nil
+			}
+			return "", err
+		}
+		if !isUnstructuredConditionTrue(obj, gate.ConditionType) {
+			return fmt.Sprintf("waiting for readiness gate, condition '%s' of %s '%s' is not true", gate.ConditionType, gate.Kind, gate.Name), nil
+		}
+	}
+	return "", nil
+}
+
+func isUnstructuredConditionTrue(obj *unstructured.Unstructured, conditionType string) bool {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		if condition, ok := c.(map[string]interface{}); ok && condition["type"] == conditionType {
+			return condition["status"] == string(metav1.ConditionTrue)
+		}
+	}
+	return false
+}
+
 func serviceNotUsable(instance *servicesv1.ServiceInstance) bool {
 	if isMarkedForDeletion(instance.ObjectMeta) {
 		return true
@@ -2,29 +2,41 @@ package controllers
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"k8s.io/utils/pointer"
 	"net/http"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"strings"
+	"time"
 
 	"github.com/SAP/sap-btp-service-operator/api"
 	v1 "github.com/SAP/sap-btp-service-operator/api/v1"
 	"github.com/SAP/sap-btp-service-operator/client/sm"
 	"github.com/SAP/sap-btp-service-operator/client/sm/smfakes"
 	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/secrets/encryption"
 	"github.com/google/uuid"
 	"github.com/lithammer/dedent"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 
 	"fmt"
 )
@@ -164,6 +176,7 @@ var _ = Describe("ServiceBinding controller", func() {
 		instanceExternalName = instanceName + "-external"
 
 		fakeClient = &smfakes.FakeClient{}
+		fakeClient.CapabilitiesReturns(&sm.Capabilities{Features: map[sm.Feature]bool{sm.FeatureAttachLastOperations: true, sm.FeatureSharedInstances: true}})
 		fakeClient.ProvisionReturns(&sm.ProvisionResponse{InstanceID: "12345678", Tags: []byte("[\"test\"]")}, nil)
 		fakeClient.BindReturns(&smClientTypes.ServiceBinding{ID: fakeBindingID, Credentials: json.RawMessage(`{"secret_key": "secret_value", "escaped": "{\"escaped_key\":\"escaped_val\"}"}`)}, "", nil)
 
@@ -270,6 +283,32 @@ var _ = Describe("ServiceBinding controller", func() {
 				})
 			})
 
+			It("should create the config maps generated by the secret template owned by the binding", func() {
+				ctx := context.Background()
+				configMapName := "app-config-" + testUUID
+				secretTemplate := dedent.Dedent(`
+				                                       apiVersion: v1
+				                                       kind: Secret
+				                                       stringData:
+				                                         secret_key: {{ .smBindingCredentials.secret_key }}
+				                                       ---
+				                                       apiVersion: v1
+				                                       kind: ConfigMap
+				                                       metadata:
+				                                         name: ` + configMapName + `
+				                                       data:
+				                                         instance: {{ .serviceInstanceInfos.instance_name }}`)
+
+				binding := createBinding(ctx, bindingName, bindingTestNamespace, instanceName, "", "", secretTemplate)
+				bindingSecret := getSecret(ctx, binding.Spec.SecretName, bindingTestNamespace, true)
+				Expect(bindingSecret.Data).To(HaveKey("secret_key"))
+
+				configMap := &corev1.ConfigMap{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: configMapName, Namespace: bindingTestNamespace}, configMap)).To(Succeed())
+				Expect(configMap.Data).To(Equal(map[string]string{"instance": instanceName}))
+				Expect(metav1.IsControlledBy(configMap, binding)).To(BeTrue())
+			})
+
 			It("should fail to create the secret if forbidden field is provided under spec.secretTemplate.metadata", func() {
 				ctx := context.Background()
 				secretTemplate := dedent.Dedent(`
@@ -449,6 +488,26 @@ var _ = Describe("ServiceBinding controller", func() {
 				})
 			})
 
+			When("secret refresh is requested", func() {
+				It("should render the secret with the credentials from SM and remove the annotation", func() {
+					createdBinding = createBinding(ctx, bindingName, bindingTestNamespace, instanceName, "", "binding-external-name", "")
+					fakeClient.GetBindingByIDReturns(&smClientTypes.ServiceBinding{ID: fakeBindingID, Credentials: json.RawMessage(`{"secret_key": "refreshed_value"}`)}, nil)
+					createdBinding.Annotations = map[string]string{api.RefreshSecretAnnotation: "true"}
+					Expect(k8sClient.Update(ctx, createdBinding)).To(Succeed())
+
+					Eventually(func() bool {
+						secret := getSecret(ctx, createdBinding.Spec.SecretName, createdBinding.Namespace, false)
+						return string(secret.Data["secret_key"]) == "refreshed_value"
+					}, timeout, interval).Should(BeTrue())
+					Eventually(func() bool {
+						err := k8sClient.Get(ctx, getResourceNamespacedName(createdBinding), createdBinding)
+						_, found := createdBinding.Annotations[api.RefreshSecretAnnotation]
+						return err == nil && !found
+					}, timeout, interval).Should(BeTrue())
+					Expect(fakeClient.BindCallCount()).To(Equal(1))
+				})
+			})
+
 			When("bind call to SM returns error", func() {
 				var errorMessage string
 
@@ -534,6 +593,36 @@ var _ = Describe("ServiceBinding controller", func() {
 					})
 				})
 
+				When("SM returned that the shared instance is not shared with the subaccount", func() {
+					BeforeEach(func() {
+						errorMessage = "the referenced instance is not shared"
+						fakeClient.BindReturns(nil, "", &sm.ServiceManagerError{
+							StatusCode:  http.StatusBadRequest,
+							ErrorType:   "InstanceNotShared",
+							Description: errorMessage,
+						})
+					})
+
+					It("should set the SharingNotPermitted condition and succeed once the instance is shared", func() {
+						createdBinding, _ := createBindingWithoutAssertions(ctx, bindingName, bindingTestNamespace, instanceName, "", "binding-external-name", "")
+						waitForResourceCondition(ctx, createdBinding, api.ConditionSharingNotPermitted, metav1.ConditionTrue, SharingNotPermitted, "must share it")
+						Expect(meta.FindStatusCondition(createdBinding.GetConditions(), api.ConditionFailed)).To(BeNil())
+
+						fakeClient.BindReturns(&smClientTypes.ServiceBinding{ID: fakeBindingID,
+							Credentials: json.RawMessage("{\"secret_key\": \"secret_value\"}")}, "", nil)
+						// the creation is retried with the long poll interval, a change of the binding retries it right away
+						Eventually(func() error {
+							if err := k8sClient.Get(ctx, getResourceNamespacedName(createdBinding), createdBinding); err != nil {
+								return err
+							}
+							createdBinding.Annotations = map[string]string{"shared": "true"}
+							return k8sClient.Update(ctx, createdBinding)
+						}, timeout, interval).Should(Succeed())
+						waitForResourceToBeReady(ctx, createdBinding)
+						Expect(meta.FindStatusCondition(createdBinding.GetConditions(), api.ConditionSharingNotPermitted)).To(BeNil())
+					})
+				})
+
 			})
 
 			When("SM returned invalid credentials json", func() {
@@ -573,6 +662,24 @@ var _ = Describe("ServiceBinding controller", func() {
 				})
 			})
 
+			When("deleting while bind is in progress", func() {
+				It("should be deleted once the creation completes without storing the credentials", func() {
+					fakeClient.StatusReturns(&smClientTypes.Operation{ResourceID: fakeBindingID, Type: smClientTypes.CREATE, State: smClientTypes.INPROGRESS}, nil)
+					binding, err := createBindingWithoutAssertions(ctx, bindingName, bindingTestNamespace, instanceName, "", "", "")
+					Expect(err).ToNot(HaveOccurred())
+					waitForResourceCondition(ctx, binding, api.ConditionSucceeded, metav1.ConditionFalse, CreateInProgress, "")
+
+					Expect(k8sClient.Delete(ctx, binding)).To(Succeed())
+					waitForResourceCondition(ctx, binding, api.ConditionCancellationRequested, metav1.ConditionTrue, AwaitingCreation, "")
+					Expect(fakeClient.UnbindCallCount()).To(BeZero())
+
+					fakeClient.StatusReturns(&smClientTypes.Operation{ResourceID: fakeBindingID, Type: smClientTypes.CREATE, State: smClientTypes.SUCCEEDED}, nil)
+					waitForResourceToBeDeleted(ctx, getResourceNamespacedName(binding), binding)
+					Expect(fakeClient.UnbindCallCount()).To(Equal(1))
+					Expect(fakeClient.GetBindingByIDCallCount()).To(BeZero())
+				})
+			})
+
 			// TODO redefine test
 			XWhen("bind polling returns error", func() {
 				BeforeEach(func() {
@@ -661,6 +768,111 @@ var _ = Describe("ServiceBinding controller", func() {
 				waitForResourceToBeReady(ctx, createdBinding)
 			})
 		})
+
+		When("readiness gates are provided", func() {
+			var gateInstanceName string
+
+			BeforeEach(func() {
+				gateInstanceName = "gate-instance-" + testUUID
+			})
+
+			AfterEach(func() {
+				deleteAndWait(ctx, types.NamespacedName{Name: gateInstanceName, Namespace: bindingTestNamespace}, &v1.ServiceInstance{})
+			})
+
+			It("should wait for the readiness gates before binding", func() {
+				binding := generateBasicBindingTemplate(bindingName, bindingTestNamespace, instanceName, "", "binding-external-name", "")
+				binding.Spec.ReadinessGates = []v1.ReadinessGate{{
+					APIVersion:    "services.cloud.sap.com/v1",
+					Kind:          "ServiceInstance",
+					Name:          gateInstanceName,
+					ConditionType: api.ConditionReady,
+				}}
+				Expect(k8sClient.Create(ctx, binding)).To(Succeed())
+				createdBinding = binding
+				waitForResourceCondition(ctx, binding, api.ConditionSucceeded, metav1.ConditionFalse, "", fmt.Sprintf("ServiceInstance '%s' not found", gateInstanceName))
+				Expect(fakeClient.BindCallCount()).To(BeZero())
+
+				createInstance(ctx, gateInstanceName, bindingTestNamespace, "")
+				waitForResourceToBeReady(ctx, binding)
+				Expect(fakeClient.BindCallCount()).To(Equal(1))
+			})
+		})
+
+		When("parameters from the instance are provided", func() {
+			It("should pass the fields of the instance status as parameters", func() {
+				binding := generateBasicBindingTemplate(bindingName, bindingTestNamespace, instanceName, "", "binding-external-name", "")
+				binding.Spec.ParametersFromInstance = map[string]v1.InstanceStatusField{"instance_guid": v1.InstanceStatusFieldInstanceID}
+				Expect(k8sClient.Create(ctx, binding)).To(Succeed())
+				createdBinding = binding
+				waitForResourceToBeReady(ctx, binding)
+
+				smBinding, _, _ := fakeClient.BindArgsForCall(0)
+				Expect(smBinding.Parameters).To(ContainSubstring(fmt.Sprintf("\"instance_guid\":\"%s\"", createdInstance.Status.InstanceID)))
+				Expect(smBinding.Parameters).To(ContainSubstring("\"key\":\"value\""))
+			})
+
+			It("should fail when a parameter is also set by the parameters", func() {
+				binding := generateBasicBindingTemplate(bindingName, bindingTestNamespace, instanceName, "", "binding-external-name", "")
+				binding.Spec.ParametersFromInstance = map[string]v1.InstanceStatusField{"key": v1.InstanceStatusFieldInstanceID}
+				Expect(k8sClient.Create(ctx, binding)).To(Succeed())
+				createdBinding = binding
+				waitForResourceCondition(ctx, binding, api.ConditionFailed, metav1.ConditionTrue, "", "duplicate entry for parameter \"key\"")
+				Expect(fakeClient.BindCallCount()).To(BeZero())
+			})
+		})
+
+		When("secret owner is provided", func() {
+			var owner *appsv1.Deployment
+
+			BeforeEach(func() {
+				labels := map[string]string{"app": "secret-owner"}
+				owner = &appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "secret-owner-" + testUUID, Namespace: bindingTestNamespace},
+					Spec: appsv1.DeploymentSpec{
+						Selector: &metav1.LabelSelector{MatchLabels: labels},
+						Template: corev1.PodTemplateSpec{
+							ObjectMeta: metav1.ObjectMeta{Labels: labels},
+							Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app"}}},
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, owner)).To(Succeed())
+			})
+
+			AfterEach(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, owner))).To(Succeed())
+			})
+
+			It("should set the referenced object as the owner of the secret and track the binding", func() {
+				binding := generateBasicBindingTemplate(bindingName, bindingTestNamespace, instanceName, "", "binding-external-name", "")
+				binding.Spec.SecretOwnerRef = &v1.SecretOwnerReference{APIVersion: "apps/v1", Kind: "Deployment", Name: owner.Name}
+				Expect(k8sClient.Create(ctx, binding)).To(Succeed())
+				createdBinding = binding
+				waitForResourceToBeReady(ctx, binding)
+
+				bindingSecret := getSecret(ctx, binding.Spec.SecretName, bindingTestNamespace, true)
+				Expect(metav1.IsControlledBy(bindingSecret, owner)).To(BeTrue())
+				Expect(bindingSecret.Annotations[api.SecretBindingUIDAnnotation]).To(Equal(string(binding.UID)))
+			})
+
+			It("should retry until the referenced object exists", func() {
+				binding := generateBasicBindingTemplate(bindingName, bindingTestNamespace, instanceName, "", "binding-external-name", "")
+				binding.Spec.SecretOwnerRef = &v1.SecretOwnerReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "missing-owner-" + testUUID}
+				Expect(k8sClient.Create(ctx, binding)).To(Succeed())
+				createdBinding = binding
+				Eventually(func() string {
+					if err := k8sClient.Get(ctx, getResourceNamespacedName(binding), binding); err != nil {
+						return ""
+					}
+					if cond := meta.FindStatusCondition(binding.Status.Conditions, api.ConditionSucceeded); cond != nil {
+						return cond.Message
+					}
+					return ""
+				}, timeout, interval).Should(ContainSubstring("not found"))
+				Expect(isResourceReady(binding)).To(BeFalse())
+			})
+		})
 	})
 
 	Context("Update", func() {
@@ -781,6 +993,26 @@ var _ = Describe("ServiceBinding controller", func() {
 				})
 			})
 
+			When("the binding was already deleted in SM", func() {
+				BeforeEach(func() {
+					// the broker deleted the binding together with its instance
+					fakeClient.UnbindReturns("", &sm.ServiceManagerError{
+						StatusCode:  http.StatusBadGateway,
+						ErrorType:   "BrokerError",
+						Description: "Service broker failed: binding does not exist",
+						BrokerError: &api.HTTPStatusCodeError{StatusCode: http.StatusGone},
+					})
+				})
+				AfterEach(func() {
+					fakeClient.UnbindReturns("", nil)
+				})
+
+				It("should delete the k8s binding and secret", func() {
+					deleteAndValidate(createdBinding)
+					Expect(fakeClient.UnbindCallCount()).To(Equal(1))
+				})
+			})
+
 			When("delete in SM fails with transient error", func() {
 				BeforeEach(func() {
 					fakeClient.UnbindReturnsOnCall(0, "", &sm.ServiceManagerError{StatusCode: http.StatusTooManyRequests})
@@ -870,6 +1102,7 @@ var _ = Describe("ServiceBinding controller", func() {
 					ID:          fakeBindingID,
 					Name:        "fake-binding-external-name",
 					Credentials: json.RawMessage("{\"secret_key\": \"secret_value\"}"),
+					Ready:       state == smClientTypes.SUCCEEDED,
 					LastOperation: &smClientTypes.Operation{
 						Type:        testCase.lastOpType,
 						State:       state,
@@ -933,11 +1166,13 @@ var _ = Describe("ServiceBinding controller", func() {
 		}
 
 		When("binding exists in SM without last operation", func() {
+			var smBinding *smClientTypes.ServiceBinding
 			BeforeEach(func() {
-				smBinding := &smClientTypes.ServiceBinding{
+				smBinding = &smClientTypes.ServiceBinding{
 					ID:          fakeBindingID,
 					Name:        "fake-binding-external-name",
 					Credentials: json.RawMessage("{\"secret_key\": \"secret_value\"}"),
+					Ready:       true,
 				}
 				fakeClient.ListBindingsReturns(
 					&smClientTypes.ServiceBindings{
@@ -949,6 +1184,42 @@ var _ = Describe("ServiceBinding controller", func() {
 				var err error
 				createdBinding, err = createBindingWithoutAssertionsAndWait(ctx, bindingName, bindingTestNamespace, instanceName, "", "fake-binding-external-name", "", false)
 				Expect(err).ToNot(HaveOccurred())
+				waitForResourceToBeReady(ctx, createdBinding)
+			})
+
+			When("binding is not ready in SM", func() {
+				BeforeEach(func() {
+					smBinding.Ready = false
+					fakeClient.ListBindingsReturns(
+						&smClientTypes.ServiceBindings{
+							ServiceBindings: []smClientTypes.ServiceBinding{*smBinding},
+						}, nil)
+					fakeClient.GetBindingByIDReturns(smBinding, nil)
+				})
+
+				It("should mark the binding as degraded instead of failed and resync until it is ready", func() {
+					var err error
+					createdBinding, err = createBindingWithoutAssertionsAndWait(ctx, bindingName, bindingTestNamespace, instanceName, "", "fake-binding-external-name", "", false)
+					Expect(err).ToNot(HaveOccurred())
+					waitForResourceCondition(ctx, createdBinding, api.ConditionDegraded, metav1.ConditionTrue, NotReadyInSM, "")
+					Expect(isFailed(createdBinding)).To(BeFalse())
+					Expect(createdBinding.Status.Ready).To(Equal(metav1.ConditionFalse))
+
+					fakeClient.GetBindingByIDReturns(&smClientTypes.ServiceBinding{ID: fakeBindingID, Ready: true, Credentials: smBinding.Credentials}, nil)
+					waitForResourceToBeReady(ctx, createdBinding)
+					Expect(meta.FindStatusCondition(createdBinding.GetConditions(), api.ConditionDegraded)).To(BeNil())
+				})
+
+				It("should fail the binding when the last operation failed", func() {
+					var err error
+					createdBinding, err = createBindingWithoutAssertionsAndWait(ctx, bindingName, bindingTestNamespace, instanceName, "", "fake-binding-external-name", "", false)
+					Expect(err).ToNot(HaveOccurred())
+					waitForResourceCondition(ctx, createdBinding, api.ConditionDegraded, metav1.ConditionTrue, NotReadyInSM, "")
+
+					fakeClient.GetBindingByIDReturns(&smClientTypes.ServiceBinding{ID: fakeBindingID, LastOperation: &smClientTypes.Operation{Type: smClientTypes.CREATE, State: smClientTypes.FAILED, Description: "broker failure"}}, nil)
+					waitForResourceCondition(ctx, createdBinding, api.ConditionFailed, metav1.ConditionTrue, CreateFailed, "broker failure")
+					Expect(meta.FindStatusCondition(createdBinding.GetConditions(), api.ConditionDegraded)).To(BeNil())
+				})
 			})
 		})
 	})
@@ -1002,7 +1273,7 @@ var _ = Describe("ServiceBinding controller", func() {
 			myBinding := &v1.ServiceBinding{}
 			Eventually(func() bool {
 				err := k8sClient.Get(ctx, defaultLookupKey, myBinding)
-				return err == nil && myBinding.Status.LastCredentialsRotationTime != nil && len(myBinding.Status.Conditions) == 2
+				return err == nil && myBinding.Status.LastCredentialsRotationTime != nil && len(myBinding.Status.Conditions) == 3
 			}, timeout, interval).Should(BeTrue())
 
 			secret = getSecret(ctx, myBinding.Spec.SecretName, bindingTestNamespace, true)
@@ -1043,6 +1314,13 @@ var _ = Describe("ServiceBinding controller", func() {
 
 			_, ok := myBinding.Annotations[api.ForceRotateAnnotation]
 			Expect(ok).To(BeFalse())
+
+			By("showing the next scheduled rotation in the status")
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, defaultLookupKey, myBinding)
+				return err == nil && myBinding.Status.NextCredentialsRotationTime != nil &&
+					myBinding.Status.NextCredentialsRotationTime.Time.Equal(myBinding.Status.LastCredentialsRotationTime.Add(time.Hour))
+			}, timeout, interval).Should(BeTrue())
 		})
 
 		When("original binding ready=true", func() {
@@ -1061,6 +1339,26 @@ var _ = Describe("ServiceBinding controller", func() {
 				Expect(k8sClient.Create(ctx, staleBinding)).To(Succeed())
 				waitForResourceToBeDeleted(ctx, getResourceNamespacedName(staleBinding), staleBinding)
 			})
+
+			It("should keep old binding until the TTL passed", func() {
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: createdBinding.Name, Namespace: bindingTestNamespace}, createdBinding)).To(Succeed())
+				staleBinding := generateBasicStaleBinding(createdBinding)
+				staleBinding.Labels = map[string]string{
+					api.StaleBindingIDLabel:         createdBinding.Status.BindingID,
+					api.StaleBindingRotationOfLabel: createdBinding.Name,
+				}
+				staleBinding.Spec.CredRotationPolicy = &v1.CredentialsRotationPolicy{
+					Enabled:           false,
+					RotatedBindingTTL: "1h",
+					RotationFrequency: "0ns",
+				}
+				Expect(k8sClient.Create(ctx, staleBinding)).To(Succeed())
+				waitForResourceToBeReady(ctx, staleBinding)
+				Consistently(func() error {
+					return k8sClient.Get(ctx, getResourceNamespacedName(staleBinding), staleBinding)
+				}, time.Second, interval).Should(Succeed())
+				deleteAndWait(ctx, getResourceNamespacedName(staleBinding), staleBinding)
+			})
 		})
 
 		When("original binding ready=false (rotation failed)", func() {
@@ -1108,6 +1406,27 @@ var _ = Describe("ServiceBinding controller", func() {
 		})
 	})
 
+	Context("Instance expiration", func() {
+		It("should delete the bindings before the expired instance", func() {
+			fakeClient.UnbindReturns("", nil)
+			fakeClient.DeprovisionReturns("", nil)
+			createdBinding = createBinding(ctx, bindingName, bindingTestNamespace, instanceName, "", "binding-external-name", "")
+
+			Eventually(func() error {
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: instanceName, Namespace: bindingTestNamespace}, createdInstance); err != nil {
+					return err
+				}
+				createdInstance.Spec.ExpiresAt = &metav1.Time{Time: time.Now().Add(2 * time.Second)}
+				return k8sClient.Update(ctx, createdInstance)
+			}, timeout, interval).Should(Succeed())
+
+			waitForResourceToBeDeleted(ctx, defaultLookupKey, &v1.ServiceBinding{})
+			waitForResourceToBeDeleted(ctx, types.NamespacedName{Name: instanceName, Namespace: bindingTestNamespace}, &v1.ServiceInstance{})
+			Expect(fakeClient.UnbindCallCount()).To(Equal(1))
+			Expect(fakeClient.DeprovisionCallCount()).To(Equal(1))
+		})
+	})
+
 	Context("Cross Namespace", func() {
 		var crossBinding *v1.ServiceBinding
 		var paramsSecret *corev1.Secret
@@ -1194,7 +1513,7 @@ var _ = Describe("ServiceBinding controller", func() {
 				myBinding := &v1.ServiceBinding{}
 				Eventually(func() bool {
 					err := k8sClient.Get(ctx, key, myBinding)
-					return err == nil && myBinding.Status.LastCredentialsRotationTime != nil && len(myBinding.Status.Conditions) == 2
+					return err == nil && myBinding.Status.LastCredentialsRotationTime != nil && len(myBinding.Status.Conditions) == 3
 				}, timeout, interval).Should(BeTrue())
 
 				secret = getSecret(ctx, myBinding.Spec.SecretName, testNamespace, true)
@@ -1215,6 +1534,349 @@ var _ = Describe("ServiceBinding controller", func() {
 			})
 		})
 	})
+
+	Context("Unit Tests", func() {
+		Context("getNextCredRotationTime", func() {
+			var binding *v1.ServiceBinding
+			BeforeEach(func() {
+				binding = &v1.ServiceBinding{
+					ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))},
+					Spec: v1.ServiceBindingSpec{CredRotationPolicy: &v1.CredentialsRotationPolicy{
+						Enabled:           true,
+						RotationFrequency: "72h",
+						RotatedBindingTTL: "48h",
+					}},
+				}
+			})
+
+			It("should be scheduled from the creation time before the first rotation", func() {
+				Expect(getNextCredRotationTime(binding).Time).To(Equal(time.Date(2023, 1, 4, 0, 0, 0, 0, time.UTC)))
+			})
+
+			It("should be scheduled from the last rotation", func() {
+				binding.Status.LastCredentialsRotationTime = &metav1.Time{Time: time.Date(2023, 2, 1, 12, 0, 0, 0, time.UTC)}
+				Expect(getNextCredRotationTime(binding).Time).To(Equal(time.Date(2023, 2, 4, 12, 0, 0, 0, time.UTC)))
+			})
+
+			It("should be nil when rotation is disabled", func() {
+				binding.Spec.CredRotationPolicy.Enabled = false
+				Expect(getNextCredRotationTime(binding)).To(BeNil())
+			})
+		})
+
+		Context("requeueForRotation", func() {
+			It("should requeue at the next rotation", func() {
+				result := requeueForRotation(&metav1.Time{Time: time.Now().Add(time.Hour)})
+				Expect(result.Requeue).To(BeFalse())
+				Expect(result.RequeueAfter).To(BeNumerically("~", time.Hour, time.Minute))
+			})
+
+			It("should requeue a rotation that is due", func() {
+				Expect(requeueForRotation(&metav1.Time{Time: time.Now().Truncate(time.Second)})).To(Equal(ctrl.Result{Requeue: true}))
+				Expect(requeueForRotation(&metav1.Time{Time: time.Now().Add(-time.Hour)})).To(Equal(ctrl.Result{Requeue: true}))
+			})
+
+			It("should not requeue without rotation", func() {
+				Expect(requeueForRotation(nil)).To(Equal(ctrl.Result{}))
+			})
+		})
+
+		Context("staleBindingExpiration", func() {
+			var staleBinding *v1.ServiceBinding
+			rotationTime := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+			BeforeEach(func() {
+				staleBinding = &v1.ServiceBinding{
+					ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(rotationTime)},
+					Spec: v1.ServiceBindingSpec{CredRotationPolicy: &v1.CredentialsRotationPolicy{
+						RotationFrequency: "72h",
+						RotatedBindingTTL: "1h",
+					}},
+				}
+			})
+
+			It("should not expire before the new credentials are ready", func() {
+				staleBinding.Annotations = map[string]string{api.StaleBindingSinceAnnotation: rotationTime.Format(time.RFC3339)}
+				_, rotated := staleBindingExpiration(ctx, staleBinding)
+				Expect(rotated).To(BeFalse())
+			})
+
+			It("should count the TTL from the time the new credentials became ready", func() {
+				staleBinding.Annotations = map[string]string{
+					api.StaleBindingSinceAnnotation:     rotationTime.Format(time.RFC3339),
+					api.StaleBindingRotatedAtAnnotation: rotationTime.Add(10 * time.Minute).Format(time.RFC3339),
+				}
+				expiration, rotated := staleBindingExpiration(ctx, staleBinding)
+				Expect(rotated).To(BeTrue())
+				Expect(expiration).To(Equal(rotationTime.Add(70 * time.Minute)))
+			})
+
+			It("should record the rotation again if the rotatedAt annotation is invalid", func() {
+				staleBinding.Annotations = map[string]string{api.StaleBindingRotatedAtAnnotation: "yesterday"}
+				_, rotated := staleBindingExpiration(ctx, staleBinding)
+				Expect(rotated).To(BeFalse())
+			})
+
+			It("should keep the credentials for the default TTL if the TTL cannot be parsed", func() {
+				staleBinding.Annotations = map[string]string{api.StaleBindingRotatedAtAnnotation: rotationTime.Format(time.RFC3339)}
+				staleBinding.Spec.CredRotationPolicy.RotatedBindingTTL = "2d"
+				Expect(staleBindingExpiration(ctx, staleBinding)).To(Equal(rotationTime.Add(defaultRotatedBindingTTL)))
+				staleBinding.Spec.CredRotationPolicy = nil
+				Expect(staleBindingExpiration(ctx, staleBinding)).To(Equal(rotationTime.Add(defaultRotatedBindingTTL)))
+			})
+		})
+
+		Context("staleBindingSince", func() {
+			var staleBinding *v1.ServiceBinding
+			rotationTime := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+			BeforeEach(func() {
+				staleBinding = &v1.ServiceBinding{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(rotationTime)}}
+			})
+
+			It("should use the creation of the stale binding without the staleSince annotation", func() {
+				Expect(staleBindingSince(ctx, staleBinding)).To(Equal(rotationTime))
+			})
+
+			It("should not depend on the API server clock", func() {
+				// the stale binding seems to be created hours before the operator created it
+				staleBinding.CreationTimestamp = metav1.NewTime(rotationTime.Add(-5 * time.Hour))
+				staleBinding.Annotations = map[string]string{api.StaleBindingSinceAnnotation: rotationTime.Format(time.RFC3339)}
+				Expect(staleBindingSince(ctx, staleBinding)).To(Equal(rotationTime))
+			})
+
+			It("should fall back to the creation time if the staleSince annotation is invalid", func() {
+				staleBinding.Annotations = map[string]string{api.StaleBindingSinceAnnotation: "yesterday"}
+				Expect(staleBindingSince(ctx, staleBinding)).To(Equal(rotationTime))
+			})
+		})
+
+		Context("isUnstructuredConditionTrue", func() {
+			job := &unstructured.Unstructured{Object: map[string]interface{}{
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Complete", "status": "True"},
+						map[string]interface{}{"type": "Failed", "status": "False"},
+					},
+				},
+			}}
+
+			It("should be true only for a condition with status True", func() {
+				Expect(isUnstructuredConditionTrue(job, "Complete")).To(BeTrue())
+				Expect(isUnstructuredConditionTrue(job, "Failed")).To(BeFalse())
+				Expect(isUnstructuredConditionTrue(job, "Suspended")).To(BeFalse())
+			})
+
+			It("should be false for an object without conditions", func() {
+				Expect(isUnstructuredConditionTrue(&unstructured.Unstructured{Object: map[string]interface{}{}}, "Complete")).To(BeFalse())
+			})
+		})
+	})
+})
+
+var _ = Describe("Binding secret data", func() {
+	var dbSecret *corev1.Secret
+
+	BeforeEach(func() {
+		dbSecret = &corev1.Secret{Data: map[string][]byte{"password": []byte("old"), "removed": []byte("old"), "added": []byte("other")}}
+		setSecretManagedKeys(dbSecret, map[string][]byte{"password": []byte("old"), "removed": []byte("old")})
+	})
+
+	It("should keep keys added by other actors and drop keys no longer written by the operator", func() {
+		merged := mergeSecretData(dbSecret, map[string][]byte{"password": []byte("new")})
+		Expect(merged).To(Equal(map[string][]byte{"password": []byte("new"), "added": []byte("other")}))
+	})
+
+	It("should replace the data of secrets without managed keys", func() {
+		dbSecret.Annotations = nil
+		merged := mergeSecretData(dbSecret, map[string][]byte{"password": []byte("new")})
+		Expect(merged).To(Equal(map[string][]byte{"password": []byte("new")}))
+	})
+
+	It("should merge string data", func() {
+		secret := &corev1.Secret{Data: map[string][]byte{"a": []byte("1")}, StringData: map[string]string{"b": "2"}}
+		Expect(desiredSecretData(secret)).To(Equal(map[string][]byte{"a": []byte("1"), "b": []byte("2")}))
+	})
+
+	It("should record an event when the managed keys are modified repeatedly", func() {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{Recorder: recorder}}
+		binding := &v1.ServiceBinding{}
+		binding.UID = "reverted-binding"
+		reconciler.secretWrites.Store(binding.UID, hashSecretData(map[string][]byte{"password": []byte("old"), "removed": []byte("old")}))
+
+		reconciler.checkSecretReverted(binding, dbSecret)
+		Expect(recorder.Events).To(BeEmpty())
+
+		dbSecret.Data["password"] = []byte("reverted")
+		reconciler.checkSecretReverted(binding, dbSecret)
+		Expect(recorder.Events).To(BeEmpty())
+		reconciler.checkSecretReverted(binding, dbSecret)
+		Expect(recorder.Events).To(Receive(ContainSubstring("SecretReverted")))
+	})
+
+	It("should detect changes of the data, labels and annotations only", func() {
+		desired := dbSecret.DeepCopy()
+		desired.Data["added"] = []byte("other")
+		Expect(secretChanged(dbSecret, desired)).To(BeFalse())
+		desired.Labels = map[string]string{}
+		Expect(secretChanged(dbSecret, desired)).To(BeFalse())
+		desired.Labels["app"] = "my-app"
+		Expect(secretChanged(dbSecret, desired)).To(BeTrue())
+		desired = dbSecret.DeepCopy()
+		desired.Data["password"] = []byte("new")
+		Expect(secretChanged(dbSecret, desired)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Binding secret encryption", func() {
+	var (
+		ctx        context.Context
+		reconciler *ServiceBindingReconciler
+		binding    *v1.ServiceBinding
+		keySecret  *corev1.Secret
+		privateKey *rsa.PrivateKey
+	)
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log.WithName("bindingEncryptionTest"))
+		reconciler = &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{Client: k8sClient}}
+
+		var err error
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).ToNot(HaveOccurred())
+		der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+		Expect(err).ToNot(HaveOccurred())
+		keySecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-public-key-" + uuid.New().String(), Namespace: bindingTestNamespace},
+			Data:       map[string][]byte{"public.pem": pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})},
+		}
+		Expect(k8sClient.Create(ctx, keySecret)).To(Succeed())
+
+		binding = newBindingObject("encrypted-binding", bindingTestNamespace)
+		binding.Spec.EncryptKeys = []string{"password"}
+		binding.Spec.EncryptionKeyRef = &v1.EncryptionKeyReference{Kind: "Secret", Name: keySecret.Name, Key: "public.pem"}
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.Delete(ctx, keySecret)).To(Succeed())
+	})
+
+	It("should encrypt the selected keys only", func() {
+		secret := &corev1.Secret{Data: map[string][]byte{"user": []byte("admin")}, StringData: map[string]string{"password": "secret"}}
+		Expect(reconciler.encryptSecretKeys(ctx, binding, secret)).To(Succeed())
+
+		Expect(string(secret.Data["user"])).To(Equal("admin"))
+		Expect(string(secret.Data["password"])).ToNot(ContainSubstring("secret"))
+		Expect(secret.Annotations[api.SecretEncryptedKeysAnnotation]).To(Equal("password"))
+
+		decrypted, err := encryption.Decrypt(privateKey, secret.Data["password"])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(decrypted)).To(Equal("secret"))
+	})
+
+	It("should fail if a key to encrypt is missing", func() {
+		secret := &corev1.Secret{Data: map[string][]byte{"user": []byte("admin")}}
+		Expect(reconciler.encryptSecretKeys(ctx, binding, secret)).To(MatchError(fmt.Sprintf(encryptKeyNotFoundErrorFormat, "password")))
+	})
+
+	It("should fail if the public key is missing", func() {
+		binding.Spec.EncryptionKeyRef.Key = "other.pem"
+		secret := &corev1.Secret{Data: map[string][]byte{"password": []byte("secret")}}
+		Expect(reconciler.encryptSecretKeys(ctx, binding, secret)).To(MatchError(ContainSubstring("the key 'other.pem' was not found")))
+	})
+})
+
+var _ = Describe("ServiceBinding creation round trips", func() {
+	var (
+		ctx        context.Context
+		smClient   *smfakes.FakeClient
+		reconciler *ServiceBindingReconciler
+		instance   *v1.ServiceInstance
+		binding    *v1.ServiceBinding
+		updates    int
+		writes     int
+	)
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log.WithName("bindingRoundTripsTest"))
+		smClient = &smfakes.FakeClient{}
+		smClient.BindReturns(&smClientTypes.ServiceBinding{ID: fakeBindingID, Credentials: json.RawMessage(`{"secret_key": "secret_value"}`)}, "", nil)
+		instance = &v1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "round-trips-instance", Namespace: bindingTestNamespace, Generation: 1},
+			Spec:       v1.ServiceInstanceSpec{ExternalName: "round-trips-instance", ServiceOfferingName: "an-offering", ServicePlanName: "a-plan"},
+			Status: v1.ServiceInstanceStatus{InstanceID: "round-trips-instance-id", Ready: metav1.ConditionTrue, ObservedGeneration: 1,
+				Conditions: []metav1.Condition{{Type: api.ConditionReady, Status: metav1.ConditionTrue, Reason: Created}}},
+		}
+		binding = &v1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "round-trips-binding", Namespace: bindingTestNamespace, Generation: 1},
+			Spec:       v1.ServiceBindingSpec{ServiceInstanceName: instance.Name, SecretName: "round-trips-secret", ExternalName: "round-trips-binding"},
+		}
+		updates, writes = 0, 0
+	})
+
+	newReconciler := func() *ServiceBindingReconciler {
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(v1.AddToScheme(testScheme)).To(Succeed())
+		return &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance, binding).WithStatusSubresource(instance, binding).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+						if _, ok := obj.(*v1.ServiceBinding); ok {
+							updates++
+							writes++
+						}
+						return c.Update(ctx, obj, opts...)
+					},
+					SubResourceUpdate: func(ctx context.Context, c client.Client, subResource string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+						if _, ok := obj.(*v1.ServiceBinding); ok {
+							writes++
+						}
+						return c.SubResource(subResource).Update(ctx, obj, opts...)
+					},
+				}).Build(),
+			Scheme:   testScheme,
+			Log:      ctrl.Log,
+			Recorder: record.NewFakeRecorder(100),
+			SMClient: func() sm.Client { return smClient },
+		}}
+	}
+
+	// reconcileCreation reconciles the created binding until a reconcile writes nothing, the writes of a reconcile
+	// trigger one more reconcile, and returns the number of reconciles
+	reconcileCreation := func() int {
+		reconciles := 0
+		for last := -1; writes != last; {
+			Expect(reconciles).To(BeNumerically("<", 10))
+			last = writes
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(binding)})
+			Expect(err).ToNot(HaveOccurred())
+			reconciles++
+		}
+		stored := &v1.ServiceBinding{}
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(binding), stored)).To(Succeed())
+		Expect(stored.Status.BindingID).To(Equal(fakeBindingID))
+		Expect(stored.Finalizers).To(ContainElement(api.FinalizerName))
+		return reconciles
+	}
+
+	It("should not update a binding whose finalizer was added on creation", func() {
+		binding.Finalizers = []string{api.FinalizerName}
+		reconciler = newReconciler()
+		reconciles := reconcileCreation()
+		// the only update sets the instance as the owner of the binding, no update adds the finalizer
+		Expect(updates).To(Equal(1))
+		Expect(writes).To(Equal(3))
+		Expect(smClient.BindCallCount()).To(Equal(1))
+		Expect(reconciles).To(Equal(2))
+	})
+
+	It("should add the finalizer of a binding created while the webhooks were disabled", func() {
+		reconciler = newReconciler()
+		reconciles := reconcileCreation()
+		Expect(updates).To(Equal(2))
+		Expect(writes).To(Equal(4))
+		Expect(reconciles).To(Equal(2))
+	})
 })
 
 func generateBasicBindingTemplate(name, namespace, instanceName, instanceNamespace, externalName, secretTemplate string) *v1.ServiceBinding {
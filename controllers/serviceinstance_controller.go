@@ -21,13 +21,19 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 
 	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/utils/pointer"
 
@@ -37,6 +43,8 @@ import (
 
 	"github.com/SAP/sap-btp-service-operator/client/sm"
 	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/secrets"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -57,6 +65,7 @@ type ServiceInstanceReconciler struct {
 func (r *ServiceInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("serviceinstance", req.NamespacedName).WithValues("correlation_id", uuid.New().String())
 	ctx = context.WithValue(ctx, LogKey{}, log)
+	defer r.startupReconciled(req)
 
 	serviceInstance := &servicesv1.ServiceInstance{}
 	if err := r.Client.Get(ctx, req.NamespacedName, serviceInstance); err != nil {
@@ -66,9 +75,23 @@ func (r *ServiceInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		// we'll ignore not-found errors, since they can't be fixed by an immediate
 		// requeue (we'll need to wait for a new notification), and we can get them
 		// on deleted requests.
+		if apierrors.IsNotFound(err) {
+			recordExpiration(req.NamespacedName, nil)
+			r.forgetSMCredentials(req.NamespacedName)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 	serviceInstance = serviceInstance.DeepCopy()
+	if err := r.operations().load(ctx, serviceInstance); err != nil {
+		log.Error(err, "failed to load the ongoing operation")
+		return ctrl.Result{}, err
+	}
+	expirationEnabled := r.Config.FeatureGates.Enabled(config.InstanceExpiration)
+	if expirationEnabled {
+		recordExpiration(req.NamespacedName, serviceInstance)
+	} else {
+		recordExpiration(req.NamespacedName, nil)
+	}
 
 	if len(serviceInstance.GetConditions()) == 0 {
 		err := r.init(ctx, serviceInstance)
@@ -77,12 +100,37 @@ func (r *ServiceInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		}
 	}
 
+	if expirationEnabled && isExpired(serviceInstance) {
+		return r.expireInstance(ctx, serviceInstance)
+	}
+	if expirationEnabled && expirationWarningDue(serviceInstance, r.Config.ExpirationWarningPeriod) && !isMarkedForDeletion(serviceInstance.ObjectMeta) {
+		if err := r.warnExpiration(ctx, serviceInstance); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
 	if isFinalState(ctx, serviceInstance) {
 		if len(serviceInstance.Status.HashedSpec) == 0 {
 			updateHashedSpecValue(serviceInstance)
 			return ctrl.Result{}, r.Client.Status().Update(ctx, serviceInstance)
 		}
-		return ctrl.Result{}, nil
+		if phaseOutdated(serviceInstance) {
+			return ctrl.Result{}, r.updateStatus(ctx, serviceInstance)
+		}
+		if isDegraded(serviceInstance) {
+			return r.resyncDegradedInstance(ctx, serviceInstance)
+		}
+		if maintenanceCheckRequired(serviceInstance, r.Config.MaintenanceCheckInterval) ||
+			(maintenanceUpdateRequired(serviceInstance) && maintenanceWindowOpened(serviceInstance)) {
+			return r.checkMaintenanceInfo(ctx, serviceInstance)
+		}
+		if r.Config.FeatureGates.Enabled(config.DriftDetection) && driftCheckEnabled(serviceInstance, r.Config.DriftCheckInterval) {
+			if delay := driftCheckDelay(serviceInstance, r.Config.DriftCheckInterval); delay > 0 {
+				return ctrl.Result{RequeueAfter: delay}, nil
+			}
+			return r.checkDrift(ctx, serviceInstance)
+		}
+		return r.nextExpirationCheck(serviceInstance), nil
 	}
 
 	if isMarkedForDeletion(serviceInstance.ObjectMeta) {
@@ -96,6 +144,7 @@ func (r *ServiceInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		return r.poll(ctx, serviceInstance)
 	}
 
+	// the finalizer is added by the mutating webhook on creation, this covers resources created while webhooks were disabled
 	if !controllerutil.ContainsFinalizer(serviceInstance, api.FinalizerName) {
 		controllerutil.AddFinalizer(serviceInstance, api.FinalizerName)
 		log.Info(fmt.Sprintf("added finalizer '%s' to service instance", api.FinalizerName))
@@ -107,15 +156,23 @@ func (r *ServiceInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	log.Info(fmt.Sprintf("instance is not in final state, handling... (generation: %d, observedGen: %d", serviceInstance.Generation, serviceInstance.Status.ObservedGeneration))
 	serviceInstance.SetObservedGeneration(serviceInstance.Generation)
 
-	smClient, err := r.getSMClient(ctx, serviceInstance, serviceInstance.Spec.BTPAccessCredentialsSecret)
+	smClient, err := r.getSMClient(ctx, serviceInstance, btpAccessSecretName(serviceInstance))
 	if err != nil {
 		log.Error(err, "failed to get sm client")
 		return r.markAsTransientError(ctx, Unknown, err.Error(), serviceInstance)
 	}
 
 	if serviceInstance.Status.InstanceID == "" {
+		if len(adoptionID(serviceInstance)) > 0 {
+			return r.adoptInstance(ctx, smClient, serviceInstance)
+		}
+
 		log.Info("Instance ID is empty, checking if instance exist in SM")
-		smInstance, err := r.getInstanceForRecovery(ctx, smClient, serviceInstance)
+		smInstance, err := r.getInstanceForRecovery(ctx, smClient, serviceInstance, true)
+		if errors.Is(err, errRecoveryWithoutLastOperation) {
+			log.Error(err, "failed to recover instance")
+			return r.markAsNonTransientError(ctx, smClientTypes.CREATE, err.Error(), serviceInstance)
+		}
 		if err != nil {
 			log.Error(err, "failed to check instance recovery")
 			return r.markAsTransientError(ctx, Unknown, err.Error(), serviceInstance)
@@ -128,8 +185,13 @@ func (r *ServiceInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		return r.createInstance(ctx, smClient, serviceInstance)
 	}
 
+	// Retry provisioning with the updated spec
+	if provisionRetryRequired(serviceInstance) {
+		return r.retryProvision(ctx, smClient, serviceInstance)
+	}
+
 	// Update
-	if updateRequired(serviceInstance) {
+	if updateRequired(serviceInstance) || r.instanceParametersFromChanged(ctx, serviceInstance) {
 		if res, err := r.updateInstance(ctx, smClient, serviceInstance); err != nil {
 			log.Info("got error while trying to update instance")
 			return ctrl.Result{}, err
@@ -138,41 +200,82 @@ func (r *ServiceInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		}
 	}
 
+	// Apply maintenance update if accepted
+	if maintenanceUpdateRequired(serviceInstance) {
+		return r.applyMaintenanceUpdate(ctx, smClient, serviceInstance)
+	}
+
 	// Handle instance share if needed
-	if sharingUpdateRequired(serviceInstance) {
+	if sharingUpdateRequired(serviceInstance) || allowedConsumersUpdateRequired(serviceInstance) {
 		return r.handleInstanceSharing(ctx, serviceInstance, smClient)
 	}
 
-	return ctrl.Result{}, nil
+	return r.nextExpirationCheck(serviceInstance), nil
 }
 
 func (r *ServiceInstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&servicesv1.ServiceInstance{}).
-		WithOptions(controller.Options{RateLimiter: workqueue.NewItemExponentialFailureRateLimiter(r.Config.RetryBaseDelay, r.Config.RetryMaxDelay)}).
-		Complete(r)
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &servicesv1.ServiceBinding{}, bindingInstanceIndex, indexBindingInstance); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &servicesv1.ServiceInstance{}, parametersFromSecretIndex, indexInstanceParametersFrom); err != nil {
+		return err
+	}
+	newList := func() client.ObjectList { return &servicesv1.ServiceInstanceList{} }
+	b, err := r.setupWatch(mgr, "serviceinstance", &servicesv1.ServiceInstance{}, newList)
+	if err != nil {
+		return err
+	}
+	if !r.Config.DisableSecretsCache {
+		// without the secrets cache there is no informer to watch the secrets with
+		b = b.Watches(&corev1.Secret{}, r.enqueueParametersFromReferences(newList, parametersFromSecretIndex)).
+			Watches(&corev1.Secret{}, evictSMClients)
+	}
+	if b, err = r.watchParametersFromConfigMaps(mgr, b, &servicesv1.ServiceInstance{}, newList); err != nil {
+		return err
+	}
+	if b, err = r.watchParametersFromResources(mgr, b, &servicesv1.ServiceInstance{}, newList); err != nil {
+		return err
+	}
+	return b.
+		WithOptions(controller.Options{RateLimiter: newRetryTrackingRateLimiter("serviceinstance", workqueue.NewItemExponentialFailureRateLimiter(r.Config.RetryBaseDelay, r.Config.RetryMaxDelay))}).
+		Complete(r.withSMBackpressure(r.withReconcileTelemetry(func() api.SAPBTPResource { return &servicesv1.ServiceInstance{} }, r)))
 }
 
 func (r *ServiceInstanceReconciler) createInstance(ctx context.Context, smClient sm.Client, serviceInstance *servicesv1.ServiceInstance) (ctrl.Result, error) {
 	log := GetLogger(ctx)
 	log.Info("Creating instance in SM")
 	updateHashedSpecValue(serviceInstance)
-	_, instanceParameters, err := buildParameters(r.Client, serviceInstance.Namespace, serviceInstance.Spec.ParametersFrom, serviceInstance.Spec.Parameters)
+	_, instanceParameters, err := buildParameters(r.parametersSources(), serviceInstance.Namespace, serviceInstance.Spec.ParametersFrom, serviceInstance.Spec.Parameters, serviceInstance.Spec.ParametersMergeStrategy)
+	if err == nil {
+		err = r.checkParametersLimits(true, instanceParameters)
+	}
+	if err == nil {
+		serviceInstance.Status.HashedParametersFrom, err = hashParametersFrom(r.parametersSources(), serviceInstance.Namespace, serviceInstance.Spec.ParametersFrom)
+	}
 	if err != nil {
 		// if parameters are invalid there is nothing we can do, the user should fix it according to the error message in the condition
 		log.Error(err, "failed to parse instance parameters")
 		return r.markAsNonTransientError(ctx, smClientTypes.CREATE, err.Error(), serviceInstance)
 	}
 
+	if r.Config.FeatureGates.Enabled(config.CatalogPreflight) {
+		if problem := r.catalogProblem(ctx, smClient, serviceInstance); len(problem) > 0 {
+			// the create would fail in the broker, the plan is resolved again once the lookup expires or the spec changes
+			log.Info(fmt.Sprintf("instance is not provisioned: %s", problem))
+			setBlockedCondition(ctx, problem, serviceInstance)
+			return ctrl.Result{RequeueAfter: r.catalogRecheckInterval()}, r.updateStatus(ctx, serviceInstance)
+		}
+	}
+
+	if err := r.checkParametersSchema(ctx, smClient, serviceInstance, schemaServiceInstance, schemaCreate, instanceParameters); err != nil {
+		return r.markAsInvalidParameters(ctx, smClientTypes.CREATE, err.Error(), serviceInstance)
+	}
+
 	provision, provisionErr := smClient.Provision(&smClientTypes.ServiceInstance{
 		Name:          serviceInstance.Spec.ExternalName,
 		ServicePlanID: serviceInstance.Spec.ServicePlanID,
 		Parameters:    instanceParameters,
-		Labels: smClientTypes.Labels{
-			namespaceLabel: []string{serviceInstance.Namespace},
-			k8sNameLabel:   []string{serviceInstance.Name},
-			clusterIDLabel: []string{r.Config.ClusterID},
-		},
+		Labels:        smLabels(r.Config.SMLabels.For(serviceInstance.Spec.ServiceOfferingName), serviceInstance.Namespace, serviceInstance.Name, r.clusterIDOf(serviceInstance, serviceInstance.Spec.ClusterIDOverride)),
 	}, serviceInstance.Spec.ServiceOfferingName, serviceInstance.Spec.ServicePlanName, nil, buildUserInfo(ctx, serviceInstance.Spec.UserInfo), serviceInstance.Spec.DataCenter)
 
 	if provisionErr != nil {
@@ -183,6 +286,10 @@ func (r *ServiceInstanceReconciler) createInstance(ctx context.Context, smClient
 
 	serviceInstance.Status.InstanceID = provision.InstanceID
 	serviceInstance.Status.SubaccountID = provision.SubaccountID
+	serviceInstance.Status.ServicePlanID = provision.PlanID
+	serviceInstance.Status.HashedPlan = getPlanHash(serviceInstance)
+	serviceInstance.Status.ServiceOfferingID = provision.OfferingID
+	serviceInstance.Status.BrokerID = provision.BrokerID
 	if len(provision.Tags) > 0 {
 		tags, err := getTags(provision.Tags)
 		if err != nil {
@@ -194,16 +301,21 @@ func (r *ServiceInstanceReconciler) createInstance(ctx context.Context, smClient
 
 	if provision.Location != "" {
 		log.Info("Provision request is in progress (async)")
-		serviceInstance.Status.OperationURL = provision.Location
-		serviceInstance.Status.OperationType = smClientTypes.CREATE
+		if err := r.operations().start(ctx, serviceInstance, provision.Location, smClientTypes.CREATE); err != nil {
+			return ctrl.Result{}, err
+		}
 		setInProgressConditions(ctx, smClientTypes.CREATE, "", serviceInstance)
 
-		return ctrl.Result{Requeue: true, RequeueAfter: r.Config.PollInterval}, r.updateStatus(ctx, serviceInstance)
+		return ctrl.Result{Requeue: true, RequeueAfter: r.pollInterval()}, r.updateStatus(ctx, serviceInstance)
 	}
 
 	log.Info(fmt.Sprintf("Instance provisioned successfully, instanceID: %s, subaccountID: %s", serviceInstance.Status.InstanceID,
 		serviceInstance.Status.SubaccountID))
 	setSuccessConditions(smClientTypes.CREATE, serviceInstance)
+	if serviceInstance.ShouldBeShared() {
+		// share the new instance right away, consumers should not find it ready but not yet shared
+		return r.handleInstanceSharing(ctx, serviceInstance, smClient)
+	}
 	return ctrl.Result{}, r.updateStatus(ctx, serviceInstance)
 }
 
@@ -211,54 +323,341 @@ func (r *ServiceInstanceReconciler) updateInstance(ctx context.Context, smClient
 	log := GetLogger(ctx)
 	log.Info(fmt.Sprintf("updating instance %s in SM", serviceInstance.Status.InstanceID))
 
+	if refused, result, err := r.verifyInstanceOwnership(ctx, smClient, smClientTypes.UPDATE, serviceInstance); refused {
+		return result, err
+	}
+	specChanged := getSpecHash(serviceInstance) != serviceInstance.Status.HashedSpec
 	updateHashedSpecValue(serviceInstance)
 
-	_, instanceParameters, err := buildParameters(r.Client, serviceInstance.Namespace, serviceInstance.Spec.ParametersFrom, serviceInstance.Spec.Parameters)
+	_, instanceParameters, err := buildParameters(r.parametersSources(), serviceInstance.Namespace, serviceInstance.Spec.ParametersFrom, serviceInstance.Spec.Parameters, serviceInstance.Spec.ParametersMergeStrategy)
+	if err == nil {
+		err = r.checkParametersLimits(specChanged, instanceParameters)
+	}
+	if err == nil {
+		serviceInstance.Status.HashedParametersFrom, err = hashParametersFrom(r.parametersSources(), serviceInstance.Namespace, serviceInstance.Spec.ParametersFrom)
+	}
 	if err != nil {
 		log.Error(err, "failed to parse instance parameters")
 		return r.markAsNonTransientError(ctx, smClientTypes.UPDATE, fmt.Sprintf("failed to parse parameters: %v", err.Error()), serviceInstance)
 	}
+	// an update without parameters keeps the parameters of the instance
+	if len(instanceParameters) > 0 {
+		if err := r.checkParametersSchema(ctx, smClient, serviceInstance, schemaServiceInstance, schemaUpdate, instanceParameters); err != nil {
+			return r.markAsInvalidParameters(ctx, smClientTypes.UPDATE, err.Error(), serviceInstance)
+		}
+	}
 
-	_, operationURL, err := smClient.UpdateInstance(serviceInstance.Status.InstanceID, &smClientTypes.ServiceInstance{
+	updatedInstance := &smClientTypes.ServiceInstance{
 		Name:          serviceInstance.Spec.ExternalName,
 		ServicePlanID: serviceInstance.Spec.ServicePlanID,
 		Parameters:    instanceParameters,
-	}, serviceInstance.Spec.ServiceOfferingName, serviceInstance.Spec.ServicePlanName, nil, buildUserInfo(ctx, serviceInstance.Spec.UserInfo), serviceInstance.Spec.DataCenter)
+	}
+	// the recorded plan is sent by its ID, the names of the spec are resolved only once the plan of the spec changed
+	planName := serviceInstance.Spec.ServicePlanName
+	if planResolved(serviceInstance) {
+		updatedInstance.ServicePlanID = serviceInstance.Status.ServicePlanID
+		planName = ""
+	}
+	_, operationURL, err := smClient.UpdateInstance(serviceInstance.Status.InstanceID, updatedInstance, serviceInstance.Spec.ServiceOfferingName, planName, nil, buildUserInfo(ctx, serviceInstance.Spec.UserInfo), serviceInstance.Spec.DataCenter)
 
 	if err != nil {
 		log.Error(err, fmt.Sprintf("failed to update service instance with ID %s", serviceInstance.Status.InstanceID))
 		return r.handleError(ctx, smClientTypes.UPDATE, err, serviceInstance)
 	}
+	// the plan ID is resolved by the client, the offering of a plan does not change
+	if len(updatedInstance.ServicePlanID) > 0 {
+		serviceInstance.Status.ServicePlanID = updatedInstance.ServicePlanID
+		serviceInstance.Status.HashedPlan = getPlanHash(serviceInstance)
+	}
 
 	if operationURL != "" {
 		log.Info(fmt.Sprintf("Update request accepted, operation URL: %s", operationURL))
-		serviceInstance.Status.OperationURL = operationURL
-		serviceInstance.Status.OperationType = smClientTypes.UPDATE
+		if err := r.operations().start(ctx, serviceInstance, operationURL, smClientTypes.UPDATE); err != nil {
+			return ctrl.Result{}, err
+		}
 		setInProgressConditions(ctx, smClientTypes.UPDATE, "", serviceInstance)
 
 		if err := r.updateStatus(ctx, serviceInstance); err != nil {
 			return ctrl.Result{}, err
 		}
 
-		return ctrl.Result{Requeue: true, RequeueAfter: r.Config.PollInterval}, nil
+		return ctrl.Result{Requeue: true, RequeueAfter: r.pollInterval()}, nil
 	}
 	log.Info("Instance updated successfully")
 	setSuccessConditions(smClientTypes.UPDATE, serviceInstance)
 	return ctrl.Result{}, r.updateStatus(ctx, serviceInstance)
 }
 
+// checkMaintenanceInfo refreshes the upgrade availability of the instance and applies the upgrade if it was accepted
+func (r *ServiceInstanceReconciler) checkMaintenanceInfo(ctx context.Context, serviceInstance *servicesv1.ServiceInstance) (ctrl.Result, error) {
+	log := GetLogger(ctx)
+	log.Info(fmt.Sprintf("checking maintenance info of instance %s", serviceInstance.Status.InstanceID))
+
+	smClient, err := r.getSMClient(ctx, serviceInstance, btpAccessSecretName(serviceInstance))
+	if err != nil {
+		log.Error(err, "failed to get sm client")
+		return r.maintenanceCheckFailed(ctx, serviceInstance)
+	}
+
+	smInstance, err := smClient.GetInstanceByID(serviceInstance.Status.InstanceID, nil)
+	if err != nil {
+		log.Error(err, "failed to get instance from SM")
+		return r.maintenanceCheckFailed(ctx, serviceInstance)
+	}
+	plan, err := getPlan(smClient, smInstance.ServicePlanID)
+	if err != nil {
+		log.Error(err, "failed to get plan from SM")
+		return r.maintenanceCheckFailed(ctx, serviceInstance)
+	}
+	// a plan which differs from the recorded plan is a drift, it is reported by the drift check and not recorded
+	if planRecordable(serviceInstance, plan) {
+		if err := recordCatalogIDs(smClient, serviceInstance, plan); err != nil {
+			log.Error(err, "failed to get the offering of the instance from SM")
+		}
+	}
+
+	planVersion := getMaintenanceInfoVersion(plan.MaintenanceInfo)
+	serviceInstance.Status.UpgradeAvailable = len(planVersion) > 0 && planVersion != getMaintenanceInfoVersion(smInstance.MaintenanceInfo)
+	serviceInstance.Status.AvailableMaintenanceVersion = ""
+	if serviceInstance.Status.UpgradeAvailable {
+		log.Info(fmt.Sprintf("maintenance update to version %s is available", planVersion))
+		serviceInstance.Status.AvailableMaintenanceVersion = planVersion
+	}
+	serviceInstance.Status.LastMaintenanceCheck = &metav1.Time{Time: time.Now()}
+
+	if maintenanceUpdateRequired(serviceInstance) {
+		return r.applyMaintenanceUpdate(ctx, smClient, serviceInstance)
+	}
+	clearStaleDeferral(serviceInstance)
+	return ctrl.Result{}, r.updateStatus(ctx, serviceInstance)
+}
+
+// maintenanceCheckFailed records the failed check, so it is retried with the next periodic check instead of right away
+func (r *ServiceInstanceReconciler) maintenanceCheckFailed(ctx context.Context, serviceInstance *servicesv1.ServiceInstance) (ctrl.Result, error) {
+	serviceInstance.Status.LastMaintenanceCheck = &metav1.Time{Time: time.Now()}
+	return ctrl.Result{RequeueAfter: r.Config.MaintenanceCheckInterval}, r.updateStatus(ctx, serviceInstance)
+}
+
+// applyMaintenanceUpdate upgrades the instance to the maintenance info currently offered by the plan
+func (r *ServiceInstanceReconciler) applyMaintenanceUpdate(ctx context.Context, smClient sm.Client, serviceInstance *servicesv1.ServiceInstance) (ctrl.Result, error) {
+	log := GetLogger(ctx)
+	if deferred, result, err := r.deferToMaintenanceWindow(ctx, serviceInstance, fmt.Sprintf("maintenance update %s", serviceInstance.Status.AvailableMaintenanceVersion)); deferred {
+		return result, err
+	}
+	log.Info(fmt.Sprintf("applying maintenance update %s to instance %s", serviceInstance.Status.AvailableMaintenanceVersion, serviceInstance.Status.InstanceID))
+
+	smInstance, err := smClient.GetInstanceByID(serviceInstance.Status.InstanceID, nil)
+	if err != nil {
+		log.Error(err, "failed to get instance from SM")
+		return r.handleError(ctx, smClientTypes.UPDATE, err, serviceInstance)
+	}
+	if r.Config.StrictOwnership {
+		if refused, result, err := r.refuseOnOwnershipMismatch(ctx, smClientTypes.UPDATE, serviceInstance, r.Config.SMLabels.For(serviceInstance.Spec.ServiceOfferingName),
+			serviceInstance.Spec.ClusterIDOverride, smInstance.Labels); refused {
+			return result, err
+		}
+	}
+	plan, err := getPlan(smClient, smInstance.ServicePlanID)
+	if err != nil {
+		log.Error(err, "failed to get plan from SM")
+		return r.markAsTransientError(ctx, smClientTypes.UPDATE, err.Error(), serviceInstance)
+	}
+
+	// the plan does not change, it is sent by its ID without resolving the names of the spec again
+	_, operationURL, err := smClient.UpdateInstance(serviceInstance.Status.InstanceID, &smClientTypes.ServiceInstance{
+		ServicePlanID:   plan.ID,
+		MaintenanceInfo: plan.MaintenanceInfo,
+	}, "", "", nil, buildUserInfo(ctx, serviceInstance.Spec.UserInfo), serviceInstance.Spec.DataCenter)
+	if err != nil {
+		log.Error(err, fmt.Sprintf("failed to apply maintenance update to service instance with ID %s", serviceInstance.Status.InstanceID))
+		return r.handleError(ctx, smClientTypes.UPDATE, err, serviceInstance)
+	}
+
+	serviceInstance.Status.UpgradeAvailable = false
+	serviceInstance.Status.AvailableMaintenanceVersion = ""
+	if operationURL != "" {
+		log.Info(fmt.Sprintf("Maintenance update request accepted, operation URL: %s", operationURL))
+		if err := r.operations().start(ctx, serviceInstance, operationURL, smClientTypes.UPDATE); err != nil {
+			return ctrl.Result{}, err
+		}
+		setInProgressConditions(ctx, smClientTypes.UPDATE, "applying maintenance update", serviceInstance)
+		return ctrl.Result{Requeue: true, RequeueAfter: r.pollInterval()}, r.updateStatus(ctx, serviceInstance)
+	}
+
+	log.Info("Maintenance update applied successfully")
+	setSuccessConditions(smClientTypes.UPDATE, serviceInstance)
+	return ctrl.Result{}, r.updateStatus(ctx, serviceInstance)
+}
+
+// checkDrift compares the plan, labels and parameters of the instance in SM with the desired state,
+// in Enforce mode the drift is reverted and in Warn mode it is only reported
+func (r *ServiceInstanceReconciler) checkDrift(ctx context.Context, serviceInstance *servicesv1.ServiceInstance) (ctrl.Result, error) {
+	log := GetLogger(ctx)
+	log.Info(fmt.Sprintf("checking drift of instance %s", serviceInstance.Status.InstanceID))
+
+	smClient, err := r.getSMClient(ctx, serviceInstance, btpAccessSecretName(serviceInstance))
+	if err != nil {
+		log.Error(err, "failed to get sm client")
+		return ctrl.Result{}, err
+	}
+
+	smInstance, err := smClient.GetInstanceByID(serviceInstance.Status.InstanceID, nil)
+	if err != nil {
+		log.Error(err, "failed to get instance from SM")
+		return ctrl.Result{}, err
+	}
+	smParameters, err := smClient.GetInstanceParameters(serviceInstance.Status.InstanceID, nil)
+	if err != nil {
+		if !parametersNotRetrievable(err) {
+			log.Error(err, "failed to get instance parameters from SM")
+			return ctrl.Result{}, err
+		}
+		// the parameters are unknown, only the labels are checked
+		log.Info(fmt.Sprintf("parameters of the instance cannot be fetched from SM: %s", err.Error()))
+		smParameters = nil
+	}
+	desiredParameters, _, err := buildParameters(r.parametersSources(), serviceInstance.Namespace, serviceInstance.Spec.ParametersFrom, serviceInstance.Spec.Parameters, serviceInstance.Spec.ParametersMergeStrategy)
+	if err != nil {
+		log.Error(err, "failed to parse instance parameters")
+		return ctrl.Result{}, err
+	}
+
+	actualPlan, err := getPlanDrift(smClient, serviceInstance, smInstance)
+	if err != nil {
+		log.Error(err, "failed to get the plan of the instance from SM")
+		return ctrl.Result{}, err
+	}
+
+	labelChanges := getLabelsDrift(smLabels(r.Config.SMLabels.For(serviceInstance.Spec.ServiceOfferingName), serviceInstance.Namespace, serviceInstance.Name, r.clusterIDOf(serviceInstance, serviceInstance.Spec.ClusterIDOverride)), smInstance.Labels)
+	driftedParameters := getParametersDrift(desiredParameters, smParameters)
+	serviceInstance.Status.LastDriftCheck = &metav1.Time{Time: time.Now()}
+
+	if len(labelChanges) == 0 && len(driftedParameters) == 0 && len(actualPlan) == 0 {
+		meta.RemoveStatusCondition(&serviceInstance.Status.Conditions, api.ConditionDrifted)
+		clearStaleDeferral(serviceInstance)
+		return ctrl.Result{RequeueAfter: r.Config.DriftCheckInterval}, r.updateStatus(ctx, serviceInstance)
+	}
+
+	driftMsg := getDriftMessage(labelChanges, driftedParameters, serviceInstance.Spec.ServicePlanName, actualPlan)
+	log.Info(driftMsg)
+	r.Recorder.Event(serviceInstance, corev1.EventTypeWarning, DriftDetected, driftMsg)
+	if serviceInstance.Spec.EnforcementMode != servicesv1.EnforcementModeEnforce {
+		meta.SetStatusCondition(&serviceInstance.Status.Conditions, metav1.Condition{
+			Type:               api.ConditionDrifted,
+			Status:             metav1.ConditionTrue,
+			Reason:             DriftDetected,
+			Message:            driftMsg,
+			ObservedGeneration: serviceInstance.Generation,
+		})
+		return ctrl.Result{RequeueAfter: r.Config.DriftCheckInterval}, r.updateStatus(ctx, serviceInstance)
+	}
+
+	// the drift stays reported while its revert is deferred, it is checked again once the window opens
+	meta.SetStatusCondition(&serviceInstance.Status.Conditions, metav1.Condition{
+		Type:               api.ConditionDrifted,
+		Status:             metav1.ConditionTrue,
+		Reason:             DriftDetected,
+		Message:            driftMsg,
+		ObservedGeneration: serviceInstance.Generation,
+	})
+	if deferred, result, err := r.deferToMaintenanceWindow(ctx, serviceInstance, "reverting the drift"); deferred {
+		return result, err
+	}
+	// the labels of an instance owned by another cluster are not reverted to the labels of this cluster
+	if r.Config.StrictOwnership {
+		if refused, result, err := r.refuseOnOwnershipMismatch(ctx, smClientTypes.UPDATE, serviceInstance, r.Config.SMLabels.For(serviceInstance.Spec.ServiceOfferingName),
+			serviceInstance.Spec.ClusterIDOverride, smInstance.Labels); refused {
+			return result, err
+		}
+	}
+
+	meta.RemoveStatusCondition(&serviceInstance.Status.Conditions, api.ConditionDrifted)
+	if len(labelChanges) > 0 {
+		log.Info("reverting drifted labels of the instance")
+		if err := smClient.UpdateInstanceLabels(serviceInstance.Status.InstanceID, labelChanges, buildUserInfo(ctx, serviceInstance.Spec.UserInfo)); err != nil {
+			log.Error(err, fmt.Sprintf("failed to update labels of service instance with ID %s", serviceInstance.Status.InstanceID))
+			return r.handleError(ctx, smClientTypes.UPDATE, err, serviceInstance)
+		}
+	}
+	if len(driftedParameters) > 0 || len(actualPlan) > 0 {
+		log.Info("reverting drifted plan and parameters of the instance")
+		return r.updateInstance(ctx, smClient, serviceInstance)
+	}
+	return ctrl.Result{RequeueAfter: r.Config.DriftCheckInterval}, r.updateStatus(ctx, serviceInstance)
+}
+
+// retryProvision deletes the instance that failed to be created from SM, once it is gone
+// the instance is provisioned again with the same external name and the updated spec
+func (r *ServiceInstanceReconciler) retryProvision(ctx context.Context, smClient sm.Client, serviceInstance *servicesv1.ServiceInstance) (ctrl.Result, error) {
+	log := GetLogger(ctx)
+	log.Info(fmt.Sprintf("instance %s failed to be created and spec was changed, deleting it from SM before retrying provisioning", serviceInstance.Status.InstanceID))
+
+	operationURL, deprovisionErr := smClient.Deprovision(serviceInstance.Status.InstanceID, nil, buildUserInfo(ctx, serviceInstance.Spec.UserInfo))
+	if deprovisionErr != nil && isAlreadyDeletedError(deprovisionErr) {
+		log.Info("the failed instance was already deleted from SM")
+		return r.resetForProvisionRetry(ctx, serviceInstance)
+	}
+	if deprovisionErr != nil {
+		log.Error(deprovisionErr, "failed to delete the failed instance from SM")
+		if smError, ok := deprovisionErr.(*sm.ServiceManagerError); ok && isTransientError(smError, log) {
+			// the failed create and the spec hash are kept, so the deletion is retried with backoff
+			return ctrl.Result{}, deprovisionErr
+		}
+		return ctrl.Result{}, r.provisionRetryFailed(ctx, serviceInstance, deprovisionErr.Error())
+	}
+
+	if operationURL != "" {
+		log.Info("Deleting failed instance async")
+		if err := r.operations().start(ctx, serviceInstance, operationURL, smClientTypes.DELETE); err != nil {
+			return ctrl.Result{}, err
+		}
+		setInProgressConditions(ctx, smClientTypes.CREATE, "deleting the failed instance before retrying provisioning", serviceInstance)
+		return ctrl.Result{Requeue: true, RequeueAfter: r.pollInterval()}, r.updateStatus(ctx, serviceInstance)
+	}
+
+	return r.resetForProvisionRetry(ctx, serviceInstance)
+}
+
+// provisionRetryFailed keeps the instance in create failed state when the failed instance could not be deleted,
+// the spec it was retried with is recorded so only another spec change triggers a new retry
+func (r *ServiceInstanceReconciler) provisionRetryFailed(ctx context.Context, serviceInstance *servicesv1.ServiceInstance, errMsg string) error {
+	setFailureConditions(smClientTypes.CREATE, fmt.Sprintf("failed to delete the failed instance before retrying provisioning: %s", errMsg), serviceInstance)
+	updateHashedSpecValue(serviceInstance)
+	if err := r.operations().finish(ctx, serviceInstance); err != nil {
+		return err
+	}
+	serviceInstance.Status.ObservedGeneration = serviceInstance.Generation
+	return r.updateStatus(ctx, serviceInstance)
+}
+
+// resetForProvisionRetry clears the identity of the deleted SM instance so the next reconcile provisions a new one
+func (r *ServiceInstanceReconciler) resetForProvisionRetry(ctx context.Context, serviceInstance *servicesv1.ServiceInstance) (ctrl.Result, error) {
+	log := GetLogger(ctx)
+	log.Info(fmt.Sprintf("failed instance %s was deleted from SM, retrying provisioning", serviceInstance.Status.InstanceID))
+
+	serviceInstance.Status.InstanceID = ""
+	if err := r.operations().finish(ctx, serviceInstance); err != nil {
+		return ctrl.Result{}, err
+	}
+	setInProgressConditions(ctx, smClientTypes.CREATE, "retrying provisioning", serviceInstance)
+	return ctrl.Result{Requeue: true}, r.updateStatus(ctx, serviceInstance)
+}
+
 func (r *ServiceInstanceReconciler) deleteInstance(ctx context.Context, serviceInstance *servicesv1.ServiceInstance) (ctrl.Result, error) {
 	log := GetLogger(ctx)
 
 	if controllerutil.ContainsFinalizer(serviceInstance, api.FinalizerName) {
-		smClient, err := r.getSMClient(ctx, serviceInstance, serviceInstance.Spec.BTPAccessCredentialsSecret)
+		if serviceInstance.Spec.DeletionPolicy == servicesv1.DeletionPolicyOrphan && serviceInstance.Status.OperationType != smClientTypes.DELETE {
+			return r.orphanInstance(ctx, serviceInstance)
+		}
+		smClient, err := r.getSMClient(ctx, serviceInstance, btpAccessSecretName(serviceInstance))
 		if err != nil {
 			log.Error(err, "failed to get sm client")
 			return r.markAsTransientError(ctx, Unknown, err.Error(), serviceInstance)
 		}
 		if len(serviceInstance.Status.InstanceID) == 0 {
 			log.Info("No instance id found validating instance does not exists in SM before removing finalizer")
-			smInstance, err := r.getInstanceForRecovery(ctx, smClient, serviceInstance)
+			smInstance, err := r.getInstanceForRecovery(ctx, smClient, serviceInstance, false)
 			if err != nil {
 				return ctrl.Result{}, err
 			}
@@ -277,9 +676,24 @@ func (r *ServiceInstanceReconciler) deleteInstance(ctx context.Context, serviceI
 			return r.poll(ctx, serviceInstance)
 		}
 
+		if len(serviceInstance.Status.OperationURL) > 0 && serviceInstance.Status.OperationType == smClientTypes.CREATE {
+			// the instance is deleted while it is being created - wait for the creation to complete
+			if !cancellationRequested(serviceInstance) {
+				return r.requestCreateCancellation(ctx, serviceInstance)
+			}
+			return r.poll(ctx, serviceInstance)
+		}
+
+		if refused, result, err := r.verifyInstanceOwnership(ctx, smClient, smClientTypes.DELETE, serviceInstance); refused {
+			return result, err
+		}
 		log.Info(fmt.Sprintf("Deleting instance with id %v from SM", serviceInstance.Status.InstanceID))
 		operationURL, deprovisionErr := smClient.Deprovision(serviceInstance.Status.InstanceID, nil, buildUserInfo(ctx, serviceInstance.Spec.UserInfo))
 		if deprovisionErr != nil {
+			if isAlreadyDeletedError(deprovisionErr) {
+				r.recordAlreadyDeleted(ctx, serviceInstance, serviceInstance.Status.InstanceID, deprovisionErr)
+				return ctrl.Result{}, r.removeFinalizer(ctx, serviceInstance, api.FinalizerName)
+			}
 			// delete will proceed anyway
 			return r.markAsNonTransientError(ctx, smClientTypes.DELETE, deprovisionErr.Error(), serviceInstance)
 		}
@@ -300,11 +714,29 @@ func (r *ServiceInstanceReconciler) handleInstanceSharing(ctx context.Context, s
 	log := GetLogger(ctx)
 	log.Info("Handling change in instance sharing")
 
+	if refused, result, err := r.verifyInstanceOwnership(ctx, smClient, smClientTypes.UPDATE, serviceInstance); refused {
+		return result, err
+	}
+
 	if serviceInstance.ShouldBeShared() {
 		log.Info("Service instance is shouldBeShared, sharing the instance")
-		err := smClient.ShareInstance(serviceInstance.Status.InstanceID, buildUserInfo(ctx, serviceInstance.Spec.UserInfo))
-		if err != nil {
-			log.Error(err, "failed to share instance")
+		// the instance is shared if the detection failed, the Service Manager answers whether it supports the sharing
+		if capabilities := smCapabilities(smClient); capabilities.Detected(sm.FeatureSharedInstances) && !capabilities.Supports(sm.FeatureSharedInstances) {
+			log.Info("Service Manager does not support instance sharing", "apiVersion", capabilities.APIVersion)
+			setSharedCondition(serviceInstance, metav1.ConditionFalse, ShareNotSupported,
+				fmt.Sprintf("instance sharing is not supported by the Service Manager (API version %s)", capabilities.APIVersion))
+			return ctrl.Result{}, r.updateStatus(ctx, serviceInstance)
+		}
+		// an instance which is already shared is only labeled with its changed allowed consumers
+		if !meta.IsStatusConditionTrue(serviceInstance.GetConditions(), api.ConditionShared) {
+			err := smClient.ShareInstance(serviceInstance.Status.InstanceID, buildUserInfo(ctx, serviceInstance.Spec.UserInfo))
+			if err != nil {
+				log.Error(err, "failed to share instance")
+				return r.handleInstanceSharingError(ctx, serviceInstance, metav1.ConditionFalse, ShareFailed, err)
+			}
+		}
+		if err := r.updateAllowedConsumers(ctx, smClient, serviceInstance); err != nil {
+			log.Error(err, "failed to update the allowed consumers of the instance")
 			return r.handleInstanceSharingError(ctx, serviceInstance, metav1.ConditionFalse, ShareFailed, err)
 		}
 		log.Info("instance shared successfully")
@@ -333,7 +765,7 @@ func (r *ServiceInstanceReconciler) handleInstanceSharing(ctx context.Context, s
 func (r *ServiceInstanceReconciler) poll(ctx context.Context, serviceInstance *servicesv1.ServiceInstance) (ctrl.Result, error) {
 	log := GetLogger(ctx)
 	log.Info(fmt.Sprintf("resource is in progress, found operation url %s", serviceInstance.Status.OperationURL))
-	smClient, err := r.getSMClient(ctx, serviceInstance, serviceInstance.Spec.BTPAccessCredentialsSecret)
+	smClient, err := r.getSMClient(ctx, serviceInstance, btpAccessSecretName(serviceInstance))
 	if err != nil {
 		log.Error(err, "failed to get sm client")
 		return r.markAsTransientError(ctx, Unknown, err.Error(), serviceInstance)
@@ -363,14 +795,18 @@ func (r *ServiceInstanceReconciler) poll(ctx context.Context, serviceInstance *s
 	case smClientTypes.INPROGRESS:
 		fallthrough
 	case smClientTypes.PENDING:
-		return ctrl.Result{Requeue: true, RequeueAfter: r.Config.PollInterval}, nil
+		return ctrl.Result{Requeue: true, RequeueAfter: r.pollInterval()}, nil
 	case smClientTypes.FAILED:
 		errMsg := getErrorMsgFromLastOperation(status)
+		if isProvisionRetryCleanup(serviceInstance) {
+			return ctrl.Result{}, r.provisionRetryFailed(ctx, serviceInstance, errMsg)
+		}
 		setFailureConditions(status.Type, errMsg, serviceInstance)
 		// in order to delete eventually the object we need return with error
 		if serviceInstance.Status.OperationType == smClientTypes.DELETE {
-			serviceInstance.Status.OperationURL = ""
-			serviceInstance.Status.OperationType = ""
+			if err := r.operations().finish(ctx, serviceInstance); err != nil {
+				return ctrl.Result{}, err
+			}
 			if err := r.updateStatus(ctx, serviceInstance); err != nil {
 				return ctrl.Result{}, err
 			}
@@ -387,6 +823,8 @@ func (r *ServiceInstanceReconciler) poll(ctx context.Context, serviceInstance *s
 				serviceInstance.Status.SubaccountID = smInstance.Labels["subaccount_id"][0]
 			}
 			serviceInstance.Status.Ready = metav1.ConditionTrue
+		} else if isProvisionRetryCleanup(serviceInstance) {
+			return r.resetForProvisionRetry(ctx, serviceInstance)
 		} else if serviceInstance.Status.OperationType == smClientTypes.DELETE {
 			// delete was successful - remove our finalizer from the list and update it.
 			if err := r.removeFinalizer(ctx, serviceInstance, api.FinalizerName); err != nil {
@@ -396,44 +834,59 @@ func (r *ServiceInstanceReconciler) poll(ctx context.Context, serviceInstance *s
 		setSuccessConditions(status.Type, serviceInstance)
 	}
 
-	serviceInstance.Status.OperationURL = ""
-	serviceInstance.Status.OperationType = ""
+	if err := r.operations().finish(ctx, serviceInstance); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if status.State == smClientTypes.SUCCEEDED && status.Type == smClientTypes.CREATE &&
+		serviceInstance.ShouldBeShared() && !isMarkedForDeletion(serviceInstance.ObjectMeta) {
+		return r.handleInstanceSharing(ctx, serviceInstance, smClient)
+	}
 
-	return ctrl.Result{}, r.updateStatus(ctx, serviceInstance)
+	// a deleted instance is deleted in SM right after its creation ended
+	return ctrl.Result{Requeue: isMarkedForDeletion(serviceInstance.ObjectMeta) && cancellationRequested(serviceInstance)}, r.updateStatus(ctx, serviceInstance)
 }
 
 func (r *ServiceInstanceReconciler) handleAsyncDelete(ctx context.Context, serviceInstance *servicesv1.ServiceInstance, opURL string) (ctrl.Result, error) {
-	serviceInstance.Status.OperationURL = opURL
-	serviceInstance.Status.OperationType = smClientTypes.DELETE
+	if err := r.operations().start(ctx, serviceInstance, opURL, smClientTypes.DELETE); err != nil {
+		return ctrl.Result{}, err
+	}
 	setInProgressConditions(ctx, smClientTypes.DELETE, "", serviceInstance)
 
 	if err := r.updateStatus(ctx, serviceInstance); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{Requeue: true, RequeueAfter: r.Config.PollInterval}, nil
+	return ctrl.Result{Requeue: true, RequeueAfter: r.pollInterval()}, nil
 }
 
-func (r *ServiceInstanceReconciler) getInstanceForRecovery(ctx context.Context, smClient sm.Client, serviceInstance *servicesv1.ServiceInstance) (*smClientTypes.ServiceInstance, error) {
+// getInstanceForRecovery looks up the instance in SM, an instance found without its last operation is only returned if
+// the last operation is not required
+func (r *ServiceInstanceReconciler) getInstanceForRecovery(ctx context.Context, smClient sm.Client, serviceInstance *servicesv1.ServiceInstance, requireLastOperation bool) (*smClientTypes.ServiceInstance, error) {
 	log := GetLogger(ctx)
-	parameters := sm.Parameters{
-		FieldQuery: []string{
-			fmt.Sprintf("name eq '%s'", serviceInstance.Spec.ExternalName),
-			fmt.Sprintf("context/clusterid eq '%s'", r.Config.ClusterID),
-			fmt.Sprintf("context/namespace eq '%s'", serviceInstance.Namespace)},
-		LabelQuery: []string{
-			fmt.Sprintf("%s eq '%s'", k8sNameLabel, serviceInstance.Name)},
-		GeneralParams: []string{"attach_last_operations=true"},
-	}
+	generalParams, withLastOperation := recoveryParams(ctx, smClient)
+	for _, labelQuery := range k8sNameLabelQueries(r.Config.SMLabels.For(serviceInstance.Spec.ServiceOfferingName), serviceInstance.Name) {
+		parameters := sm.Parameters{
+			FieldQuery: []string{
+				fmt.Sprintf("name eq '%s'", serviceInstance.Spec.ExternalName),
+				fmt.Sprintf("context/clusterid eq '%s'", r.clusterIDOf(serviceInstance, serviceInstance.Spec.ClusterIDOverride)),
+				fmt.Sprintf("context/namespace eq '%s'", serviceInstance.Namespace)},
+			LabelQuery:    labelQuery,
+			GeneralParams: generalParams,
+		}
 
-	instances, err := smClient.ListInstances(&parameters)
-	if err != nil {
-		log.Error(err, "failed to list instances in SM")
-		return nil, err
-	}
+		instances, err := smClient.ListInstances(&parameters)
+		if err != nil {
+			log.Error(err, "failed to list instances in SM")
+			return nil, err
+		}
 
-	if instances != nil && len(instances.ServiceInstances) > 0 {
-		return &instances.ServiceInstances[0], nil
+		if instances != nil && len(instances.ServiceInstances) > 0 {
+			if requireLastOperation && !withLastOperation {
+				return nil, errRecoveryWithoutLastOperation
+			}
+			return &instances.ServiceInstances[0], nil
+		}
 	}
 	log.Info("instance not found in SM")
 	return nil, nil
@@ -459,8 +912,8 @@ func (r *ServiceInstanceReconciler) recover(ctx context.Context, smClient sm.Cli
 		setSharedCondition(k8sInstance, metav1.ConditionTrue, ShareSucceeded, "Instance shared successfully")
 	}
 	k8sInstance.Status.InstanceID = smInstance.ID
-	k8sInstance.Status.OperationURL = ""
-	k8sInstance.Status.OperationType = ""
+	// the IDs of the offering and the broker are recorded with the next maintenance check
+	k8sInstance.Status.ServicePlanID = smInstance.ServicePlanID
 	tags, err := getOfferingTags(smClient, smInstance.ServicePlanID)
 	if err != nil {
 		log.Error(err, "could not recover offering tags")
@@ -469,6 +922,43 @@ func (r *ServiceInstanceReconciler) recover(ctx context.Context, smClient sm.Cli
 		k8sInstance.Status.Tags = tags
 	}
 
+	if err := r.resyncInstanceStatus(ctx, k8sInstance, smInstance, true); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, r.updateStatus(ctx, k8sInstance)
+}
+
+// resyncDegradedInstance reads an instance that SM reported as not ready until it is ready again or its last operation failed
+func (r *ServiceInstanceReconciler) resyncDegradedInstance(ctx context.Context, serviceInstance *servicesv1.ServiceInstance) (ctrl.Result, error) {
+	log := GetLogger(ctx)
+	smClient, err := r.getSMClient(ctx, serviceInstance, btpAccessSecretName(serviceInstance))
+	if err != nil {
+		log.Error(err, "failed to get sm client")
+		return ctrl.Result{}, err
+	}
+
+	smInstance, err := smClient.GetInstanceByID(serviceInstance.Status.InstanceID, nil)
+	if err != nil {
+		log.Error(err, "failed to get degraded instance from SM")
+		return ctrl.Result{}, err
+	}
+
+	if err := r.resyncInstanceStatus(ctx, serviceInstance, smInstance, false); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.updateStatus(ctx, serviceInstance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if isDegraded(serviceInstance) {
+		log.Info("instance is still not ready in SM")
+		return ctrl.Result{RequeueAfter: r.pollInterval()}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// resyncInstanceStatus sets the status of the instance to the state of the instance in SM, recovered is set when the
+// instance is recovered from SM
+func (r *ServiceInstanceReconciler) resyncInstanceStatus(ctx context.Context, k8sInstance *servicesv1.ServiceInstance, smInstance *smClientTypes.ServiceInstance, recovered bool) error {
 	instanceState := smClientTypes.SUCCEEDED
 	operationType := smClientTypes.CREATE
 	description := ""
@@ -476,24 +966,30 @@ func (r *ServiceInstanceReconciler) recover(ctx context.Context, smClient sm.Cli
 		instanceState = smInstance.LastOperation.State
 		operationType = smInstance.LastOperation.Type
 		description = smInstance.LastOperation.Description
-	} else if !smInstance.Ready {
-		instanceState = smClientTypes.FAILED
 	}
 
 	switch instanceState {
 	case smClientTypes.PENDING:
 		fallthrough
 	case smClientTypes.INPROGRESS:
-		k8sInstance.Status.OperationURL = sm.BuildOperationURL(smInstance.LastOperation.ID, smInstance.ID, smClientTypes.ServiceInstancesURL)
-		k8sInstance.Status.OperationType = smInstance.LastOperation.Type
 		setInProgressConditions(ctx, smInstance.LastOperation.Type, smInstance.LastOperation.Description, k8sInstance)
+		return r.operations().start(ctx, k8sInstance, sm.BuildOperationURL(smInstance.LastOperation.ID, smInstance.ID, smClientTypes.ServiceInstancesURL), smInstance.LastOperation.Type)
 	case smClientTypes.SUCCEEDED:
-		setSuccessConditions(operationType, k8sInstance)
+		if recovered {
+			setRecoveredConditions(operationType, k8sInstance)
+		} else {
+			setSuccessConditions(operationType, k8sInstance)
+		}
+		if smInstance.Ready {
+			removeDegradedCondition(k8sInstance)
+		} else {
+			setDegradedConditions(k8sInstance)
+		}
 	case smClientTypes.FAILED:
+		removeDegradedCondition(k8sInstance)
 		setFailureConditions(operationType, description, k8sInstance)
 	}
-
-	return ctrl.Result{}, r.updateStatus(ctx, k8sInstance)
+	return r.operations().finish(ctx, k8sInstance)
 }
 
 func (r *ServiceInstanceReconciler) handleInstanceSharingError(ctx context.Context, object api.SAPBTPResource, status metav1.ConditionStatus, reason string, err error) (ctrl.Result, error) {
@@ -571,6 +1067,172 @@ func updateRequired(serviceInstance *servicesv1.ServiceInstance) bool {
 	return getSpecHash(serviceInstance) != serviceInstance.Status.HashedSpec
 }
 
+// instanceParametersFromChanged checks whether the secrets referenced by parametersFrom of a ready instance changed
+// since its parameters were last sent to SM
+func (r *ServiceInstanceReconciler) instanceParametersFromChanged(ctx context.Context, serviceInstance *servicesv1.ServiceInstance) bool {
+	if serviceInstance.Status.Ready != metav1.ConditionTrue {
+		return false
+	}
+	if r.parametersFromChanged(ctx, serviceInstance.Namespace, serviceInstance.Spec.ParametersFrom, serviceInstance.Status.HashedParametersFrom) {
+		GetLogger(ctx).Info("the secrets referenced by parametersFrom changed, updating the instance")
+		return true
+	}
+	return false
+}
+
+// provisionRetryRequired checks whether the instance failed to be created in SM and its spec was changed since,
+// in which case the failed instance is replaced instead of updated
+func provisionRetryRequired(serviceInstance *servicesv1.ServiceInstance) bool {
+	if len(serviceInstance.Status.InstanceID) == 0 || serviceInstance.Status.Ready == metav1.ConditionTrue {
+		return false
+	}
+
+	cond := meta.FindStatusCondition(serviceInstance.Status.Conditions, api.ConditionSucceeded)
+	if cond == nil || cond.Reason != CreateFailed {
+		return false
+	}
+
+	return getSpecHash(serviceInstance) != serviceInstance.Status.HashedSpec
+}
+
+// isProvisionRetryCleanup checks whether the ongoing delete operation removes a failed instance before retrying provisioning
+func isProvisionRetryCleanup(serviceInstance *servicesv1.ServiceInstance) bool {
+	return serviceInstance.Status.OperationType == smClientTypes.DELETE && !isMarkedForDeletion(serviceInstance.ObjectMeta)
+}
+
+// maintenanceCheckRequired checks whether the maintenance info of a ready instance should be compared with its plan again
+func maintenanceCheckRequired(serviceInstance *servicesv1.ServiceInstance, interval time.Duration) bool {
+	if interval <= 0 || serviceInstance.Status.Ready != metav1.ConditionTrue || len(serviceInstance.Status.InstanceID) == 0 {
+		return false
+	}
+
+	lastCheck := serviceInstance.Status.LastMaintenanceCheck
+	return lastCheck == nil || time.Since(lastCheck.Time) >= interval
+}
+
+// driftCheckEnabled checks whether a ready instance should be periodically compared with its state in SM
+func driftCheckEnabled(serviceInstance *servicesv1.ServiceInstance, interval time.Duration) bool {
+	mode := serviceInstance.Spec.EnforcementMode
+	if interval <= 0 || (mode != servicesv1.EnforcementModeEnforce && mode != servicesv1.EnforcementModeWarn) {
+		return false
+	}
+	return serviceInstance.Status.Ready == metav1.ConditionTrue && len(serviceInstance.Status.InstanceID) > 0
+}
+
+// driftCheckDelay returns the time left until the next drift check of the instance is due
+func driftCheckDelay(serviceInstance *servicesv1.ServiceInstance, interval time.Duration) time.Duration {
+	lastCheck := serviceInstance.Status.LastDriftCheck
+	if lastCheck == nil || deferredDriftRevertDue(serviceInstance) {
+		return 0
+	}
+	return interval - time.Since(lastCheck.Time)
+}
+
+// getLabelsDrift returns the changes reverting the desired labels in SM, labels not managed by the operator are ignored
+func getLabelsDrift(desired, actual smClientTypes.Labels) []*smClientTypes.LabelChange {
+	keys := make([]string, 0, len(desired))
+	for key := range desired {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var labelChanges []*smClientTypes.LabelChange
+	for _, key := range keys {
+		var missing, unexpected []string
+		for _, value := range desired[key] {
+			if !contains(actual[key], value) {
+				missing = append(missing, value)
+			}
+		}
+		for _, value := range actual[key] {
+			if !contains(desired[key], value) {
+				unexpected = append(unexpected, value)
+			}
+		}
+		if len(unexpected) > 0 {
+			labelChanges = append(labelChanges, &smClientTypes.LabelChange{Key: key, Operation: smClientTypes.RemoveLabelOperation, Values: unexpected})
+		}
+		if len(missing) > 0 {
+			labelChanges = append(labelChanges, &smClientTypes.LabelChange{Key: key, Operation: smClientTypes.AddLabelOperation, Values: missing})
+		}
+	}
+	return labelChanges
+}
+
+// getParametersDrift returns the names of the desired parameters whose value in SM is different,
+// parameters that are not part of the spec are ignored and parameters missing in SM are unknown, not drifted,
+// as brokers may redact them
+func getParametersDrift(desired, actual map[string]interface{}) []string {
+	var drifted []string
+	for key, value := range desired {
+		if actualValue, ok := actual[key]; ok && !reflect.DeepEqual(value, actualValue) {
+			drifted = append(drifted, key)
+		}
+	}
+	sort.Strings(drifted)
+	return drifted
+}
+
+// getPlanDrift returns the plan of the instance in SM if it differs from the desired plan. The plan is compared by
+// the ID the spec sets or the operator recorded, which stays valid when the plan is renamed in the catalog, and by
+// its catalog name otherwise. It is not compared if SM does not report it.
+func getPlanDrift(smClient sm.Client, serviceInstance *servicesv1.ServiceInstance, smInstance *smClientTypes.ServiceInstance) (string, error) {
+	if len(smInstance.ServicePlanID) == 0 {
+		return "", nil
+	}
+	desiredPlanID := serviceInstance.Spec.ServicePlanID
+	if len(desiredPlanID) == 0 && planResolved(serviceInstance) {
+		desiredPlanID = serviceInstance.Status.ServicePlanID
+	}
+	if len(desiredPlanID) > 0 && smInstance.ServicePlanID == desiredPlanID {
+		return "", nil
+	}
+	plans, err := smClient.ListPlans(&sm.Parameters{FieldQuery: []string{fmt.Sprintf("id eq '%s'", smInstance.ServicePlanID)}})
+	if err != nil {
+		return "", err
+	}
+	if plans == nil || len(plans.ServicePlans) == 0 {
+		if len(desiredPlanID) > 0 {
+			return smInstance.ServicePlanID, nil
+		}
+		return "", nil
+	}
+	plan := plans.ServicePlans[0]
+	if len(desiredPlanID) > 0 || plan.CatalogName != serviceInstance.Spec.ServicePlanName {
+		return plan.CatalogName, nil
+	}
+	return "", nil
+}
+
+// parametersNotRetrievable checks whether SM rejected fetching the parameters because the broker does not support it
+func parametersNotRetrievable(err error) bool {
+	smError, ok := err.(*sm.ServiceManagerError)
+	if !ok {
+		return false
+	}
+	return smError.StatusCode == http.StatusBadRequest || smError.StatusCode == http.StatusNotFound || smError.StatusCode == http.StatusNotImplemented
+}
+
+func getDriftMessage(labelChanges []*smClientTypes.LabelChange, driftedParameters []string, desiredPlan, actualPlan string) string {
+	var drifted []string
+	if len(actualPlan) > 0 {
+		drifted = append(drifted, fmt.Sprintf("plan %s (desired %s)", actualPlan, desiredPlan))
+	}
+	for _, labelChange := range labelChanges {
+		if !contains(drifted, "label "+labelChange.Key) {
+			drifted = append(drifted, "label "+labelChange.Key)
+		}
+	}
+	for _, parameter := range driftedParameters {
+		drifted = append(drifted, "parameter "+parameter)
+	}
+	return fmt.Sprintf("instance in SM differs from the desired state: %s", strings.Join(drifted, ", "))
+}
+
+func maintenanceUpdateRequired(serviceInstance *servicesv1.ServiceInstance) bool {
+	return serviceInstance.Status.Ready == metav1.ConditionTrue && serviceInstance.Status.UpgradeAvailable && serviceInstance.Spec.AcceptMaintenanceUpdate
+}
+
 // TODO unit test
 func sharingUpdateRequired(serviceInstance *servicesv1.ServiceInstance) bool {
 	//relevant only for non-shared instances - sharing instance is possible only for usable instances
@@ -600,7 +1262,7 @@ func sharingUpdateRequired(serviceInstance *servicesv1.ServiceInstance) bool {
 	return sharedCondition.Status == metav1.ConditionTrue
 }
 
-func getOfferingTags(smClient sm.Client, planID string) ([]string, error) {
+func getPlan(smClient sm.Client, planID string) (*smClientTypes.ServicePlan, error) {
 	planQuery := &sm.Parameters{
 		FieldQuery: []string{fmt.Sprintf("id eq '%s'", planID)},
 	}
@@ -612,9 +1274,12 @@ func getOfferingTags(smClient sm.Client, planID string) ([]string, error) {
 	if plans == nil || len(plans.ServicePlans) != 1 {
 		return nil, fmt.Errorf("could not find plan with id %s", planID)
 	}
+	return &plans.ServicePlans[0], nil
+}
 
+func getOffering(smClient sm.Client, offeringID string) (*smClientTypes.ServiceOffering, error) {
 	offeringQuery := &sm.Parameters{
-		FieldQuery: []string{fmt.Sprintf("id eq '%s'", plans.ServicePlans[0].ServiceOfferingID)},
+		FieldQuery: []string{fmt.Sprintf("id eq '%s'", offeringID)},
 	}
 
 	offerings, err := smClient.ListOfferings(offeringQuery)
@@ -622,16 +1287,68 @@ func getOfferingTags(smClient sm.Client, planID string) ([]string, error) {
 		return nil, err
 	}
 	if offerings == nil || len(offerings.ServiceOfferings) != 1 {
-		return nil, fmt.Errorf("could not find offering with id %s", plans.ServicePlans[0].ServiceOfferingID)
+		return nil, fmt.Errorf("could not find offering with id %s", offeringID)
+	}
+	return &offerings.ServiceOfferings[0], nil
+}
+
+func getOfferingTags(smClient sm.Client, planID string) ([]string, error) {
+	plan, err := getPlan(smClient, planID)
+	if err != nil {
+		return nil, err
+	}
+	offering, err := getOffering(smClient, plan.ServiceOfferingID)
+	if err != nil {
+		return nil, err
 	}
 
 	var tags []string
-	if err := json.Unmarshal(offerings.ServiceOfferings[0].Tags, &tags); err != nil {
+	if err := json.Unmarshal(offering.Tags, &tags); err != nil {
 		return nil, err
 	}
 	return tags, nil
 }
 
+// recordCatalogIDs records the IDs of the plan of the instance in SM and of its offering and broker, the offering is
+// read from SM only if its IDs were not recorded yet, e.g. for instances provisioned by earlier versions, or changed
+func recordCatalogIDs(smClient sm.Client, serviceInstance *servicesv1.ServiceInstance, plan *smClientTypes.ServicePlan) error {
+	serviceInstance.Status.ServicePlanID = plan.ID
+	serviceInstance.Status.HashedPlan = getPlanHash(serviceInstance)
+	if serviceInstance.Status.ServiceOfferingID == plan.ServiceOfferingID && len(serviceInstance.Status.BrokerID) > 0 {
+		return nil
+	}
+	offering, err := getOffering(smClient, plan.ServiceOfferingID)
+	if err != nil {
+		return err
+	}
+	serviceInstance.Status.ServiceOfferingID = offering.ID
+	serviceInstance.Status.BrokerID = offering.BrokerID
+	return nil
+}
+
+// planRecordable checks whether the plan of the instance in SM is its desired plan, the recorded plan if it was
+// resolved from the current spec, or the plan of the spec otherwise, e.g. for instances provisioned by earlier versions
+func planRecordable(serviceInstance *servicesv1.ServiceInstance, plan *smClientTypes.ServicePlan) bool {
+	if planResolved(serviceInstance) {
+		return plan.ID == serviceInstance.Status.ServicePlanID
+	}
+	if len(serviceInstance.Spec.ServicePlanID) > 0 {
+		return plan.ID == serviceInstance.Spec.ServicePlanID
+	}
+	return plan.CatalogName == serviceInstance.Spec.ServicePlanName
+}
+
+// planResolved checks whether the recorded plan ID was resolved from the offering and plan of the current spec
+func planResolved(serviceInstance *servicesv1.ServiceInstance) bool {
+	return len(serviceInstance.Status.ServicePlanID) > 0 && serviceInstance.Status.HashedPlan == getPlanHash(serviceInstance)
+}
+
+// getPlanHash hashes the fields of the spec the plan of the instance is resolved from
+func getPlanHash(serviceInstance *servicesv1.ServiceInstance) string {
+	spec := serviceInstance.Spec
+	return generateEncodedMD5Hash(strings.Join([]string{spec.ServiceOfferingName, spec.ServicePlanName, spec.ServicePlanID, spec.DataCenter}, "/"))
+}
+
 func getTags(tags []byte) ([]string, error) {
 	var tagsArr []string
 	if err := json.Unmarshal(tags, &tagsArr); err != nil {
@@ -640,9 +1357,31 @@ func getTags(tags []byte) ([]string, error) {
 	return tagsArr, nil
 }
 
+func getMaintenanceInfoVersion(maintenanceInfo json.RawMessage) string {
+	if len(maintenanceInfo) == 0 {
+		return ""
+	}
+	info := struct {
+		Version string `json:"version"`
+	}{}
+	if err := json.Unmarshal(maintenanceInfo, &info); err != nil {
+		return ""
+	}
+	return info.Version
+}
+
+// getSpecHash hashes the fields of the spec that are sent to SM, the fields handled by the operator (sharing, allowed
+// consumers, maintenance, enforcement, expiration, maintenance window and deletion policy) don't require an update of
+// the instance
 func getSpecHash(serviceInstance *servicesv1.ServiceInstance) string {
 	spec := serviceInstance.Spec
 	spec.Shared = pointer.Bool(false)
+	spec.AllowedConsumers = nil
+	spec.AcceptMaintenanceUpdate = false
+	spec.EnforcementMode = ""
+	spec.ExpiresAt = nil
+	spec.MaintenanceWindow = nil
+	spec.DeletionPolicy = ""
 	specBytes, _ := json.Marshal(spec)
 	s := string(specBytes)
 	return generateEncodedMD5Hash(s)
@@ -664,15 +1403,12 @@ func setSharedCondition(object api.SAPBTPResource, status metav1.ConditionStatus
 	}
 
 	shareCondition := metav1.Condition{
-		Type:    api.ConditionShared,
-		Status:  status,
-		Reason:  reason,
-		Message: msg,
-		// shared condition does not contain observed generation
+		Type:               api.ConditionShared,
+		Status:             status,
+		Reason:             reason,
+		Message:            msg,
+		ObservedGeneration: object.GetGeneration(),
 	}
-
-	// remove shared condition and add it as new (in case it has observed generation)
-	meta.RemoveStatusCondition(&conditions, api.ConditionShared)
 	meta.SetStatusCondition(&conditions, shareCondition)
 
 	object.SetConditions(conditions)
@@ -700,3 +1436,12 @@ func getErrorMsgFromLastOperation(status *smClientTypes.Operation) string {
 	}
 	return errMsg
 }
+
+// btpAccessSecretName returns the name of the secret in the management namespace with the access credentials of the instance,
+// an empty name selects the credentials of the namespace of the instance
+func btpAccessSecretName(serviceInstance *servicesv1.ServiceInstance) string {
+	if len(serviceInstance.Spec.Landscape) > 0 {
+		return secrets.LandscapeSecretName(serviceInstance.Spec.Landscape)
+	}
+	return serviceInstance.Spec.BTPAccessCredentialsSecret
+}
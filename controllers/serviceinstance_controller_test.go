@@ -19,10 +19,15 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 	"net/http"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 	"strings"
+	"time"
 )
 
 // +kubebuilder:docs-gen:collapse=Imports
@@ -129,6 +134,7 @@ var _ = Describe("ServiceInstance controller", func() {
 		defaultLookupKey = types.NamespacedName{Name: fakeInstanceName, Namespace: testNamespace}
 
 		fakeClient = &smfakes.FakeClient{}
+		fakeClient.CapabilitiesReturns(&sm.Capabilities{Features: map[sm.Feature]bool{sm.FeatureAttachLastOperations: true, sm.FeatureSharedInstances: true}})
 		fakeClient.ProvisionReturns(&sm.ProvisionResponse{InstanceID: fakeInstanceID, SubaccountID: fakeSubaccountID}, nil)
 		fakeClient.DeprovisionReturns("", nil)
 		fakeClient.GetInstanceByIDReturns(&smclientTypes.ServiceInstance{ID: fakeInstanceID, Ready: true, LastOperation: &smClientTypes.Operation{State: smClientTypes.SUCCEEDED, Type: smClientTypes.CREATE}}, nil)
@@ -211,6 +217,7 @@ var _ = Describe("ServiceInstance controller", func() {
 				It("should provision instance of the provided offering and plan name successfully", func() {
 					serviceInstance = createInstance(ctx, instanceSpec, true)
 					Expect(serviceInstance.Status.InstanceID).To(Equal(fakeInstanceID))
+					Expect(serviceInstance.Finalizers).To(ContainElement(api.FinalizerName))
 					Expect(serviceInstance.Status.SubaccountID).To(Equal(fakeSubaccountID))
 					Expect(serviceInstance.Spec.ExternalName).To(Equal(fakeInstanceExternalName))
 					Expect(serviceInstance.Name).To(Equal(fakeInstanceName))
@@ -323,6 +330,117 @@ var _ = Describe("ServiceInstance controller", func() {
 					}, nil)
 					waitForInstanceConditionAndMessage(ctx, defaultLookupKey, api.ConditionFailed, "broker-failure")
 				})
+
+				When("spec is changed after the failure", func() {
+					It("should delete the failed instance and provision it again", func() {
+						serviceInstance = createInstance(ctx, instanceSpec, false)
+						fakeClient.StatusReturns(&smclientTypes.Operation{
+							ID:     "1234",
+							Type:   smClientTypes.CREATE,
+							State:  smClientTypes.FAILED,
+							Errors: []byte(`{"error": "brokerError","description":"broker-failure"}`),
+						}, nil)
+						waitForInstanceConditionAndMessage(ctx, defaultLookupKey, api.ConditionFailed, "broker-failure")
+
+						fakeClient.ProvisionReturns(&sm.ProvisionResponse{InstanceID: fakeInstanceID, SubaccountID: fakeSubaccountID}, nil)
+						Eventually(func() bool {
+							if err := k8sClient.Get(ctx, defaultLookupKey, serviceInstance); err != nil {
+								return false
+							}
+							serviceInstance.Spec.Parameters = &runtime.RawExtension{Raw: []byte(`{"key": "fixed-value"}`)}
+							return k8sClient.Update(ctx, serviceInstance) == nil
+						}, timeout, interval).Should(BeTrue())
+
+						waitForResourceCondition(ctx, serviceInstance, api.ConditionSucceeded, metav1.ConditionTrue, Created, "")
+						Expect(fakeClient.DeprovisionCallCount()).To(BeNumerically(">", 0))
+						instanceID, _, _ := fakeClient.DeprovisionArgsForCall(0)
+						Expect(instanceID).To(Equal(fakeInstanceID))
+						Expect(fakeClient.ProvisionCallCount()).To(Equal(2))
+						Expect(fakeClient.UpdateInstanceCallCount()).To(Equal(0))
+						smInstance, _, _, _, _, _ := fakeClient.ProvisionArgsForCall(1)
+						Expect(smInstance.Name).To(Equal(fakeInstanceExternalName))
+					})
+
+					It("should retry the deletion of the failed instance after a transient error", func() {
+						serviceInstance = createInstance(ctx, instanceSpec, false)
+						fakeClient.StatusReturns(&smclientTypes.Operation{
+							ID:     "1234",
+							Type:   smClientTypes.CREATE,
+							State:  smClientTypes.FAILED,
+							Errors: []byte(`{"error": "brokerError","description":"broker-failure"}`),
+						}, nil)
+						waitForInstanceConditionAndMessage(ctx, defaultLookupKey, api.ConditionFailed, "broker-failure")
+
+						fakeClient.DeprovisionReturnsOnCall(0, "", &sm.ServiceManagerError{StatusCode: http.StatusBadGateway, Description: "bad gateway"})
+						fakeClient.ProvisionReturns(&sm.ProvisionResponse{InstanceID: fakeInstanceID, SubaccountID: fakeSubaccountID}, nil)
+						Eventually(func() bool {
+							if err := k8sClient.Get(ctx, defaultLookupKey, serviceInstance); err != nil {
+								return false
+							}
+							serviceInstance.Spec.Parameters = &runtime.RawExtension{Raw: []byte(`{"key": "fixed-value"}`)}
+							return k8sClient.Update(ctx, serviceInstance) == nil
+						}, timeout, interval).Should(BeTrue())
+
+						waitForResourceCondition(ctx, serviceInstance, api.ConditionSucceeded, metav1.ConditionTrue, Created, "")
+						Expect(fakeClient.DeprovisionCallCount()).To(BeNumerically(">", 1))
+						Expect(fakeClient.ProvisionCallCount()).To(Equal(2))
+					})
+
+					It("should delete the failed instance asynchronously and provision it again", func() {
+						serviceInstance = createInstance(ctx, instanceSpec, false)
+						fakeClient.StatusReturns(&smclientTypes.Operation{
+							ID:     "1234",
+							Type:   smClientTypes.CREATE,
+							State:  smClientTypes.FAILED,
+							Errors: []byte(`{"error": "brokerError","description":"broker-failure"}`),
+						}, nil)
+						waitForInstanceConditionAndMessage(ctx, defaultLookupKey, api.ConditionFailed, "broker-failure")
+
+						fakeClient.DeprovisionReturns("/v1/service_instances/id/operations/delete-1234", nil)
+						fakeClient.StatusReturns(&smclientTypes.Operation{
+							ID:    "delete-1234",
+							Type:  smClientTypes.DELETE,
+							State: smClientTypes.SUCCEEDED,
+						}, nil)
+						fakeClient.ProvisionReturns(&sm.ProvisionResponse{InstanceID: fakeInstanceID, SubaccountID: fakeSubaccountID}, nil)
+						Eventually(func() bool {
+							if err := k8sClient.Get(ctx, defaultLookupKey, serviceInstance); err != nil {
+								return false
+							}
+							serviceInstance.Spec.Parameters = &runtime.RawExtension{Raw: []byte(`{"key": "fixed-value"}`)}
+							return k8sClient.Update(ctx, serviceInstance) == nil
+						}, timeout, interval).Should(BeTrue())
+
+						waitForResourceCondition(ctx, serviceInstance, api.ConditionSucceeded, metav1.ConditionTrue, Created, "")
+						Expect(fakeClient.DeprovisionCallCount()).To(Equal(1))
+						Expect(fakeClient.ProvisionCallCount()).To(Equal(2))
+						Expect(serviceInstance.Status.OperationURL).To(BeEmpty())
+					})
+
+					It("should not retry deleting the failed instance when the deletion failed", func() {
+						serviceInstance = createInstance(ctx, instanceSpec, false)
+						fakeClient.StatusReturns(&smclientTypes.Operation{
+							ID:     "1234",
+							Type:   smClientTypes.CREATE,
+							State:  smClientTypes.FAILED,
+							Errors: []byte(`{"error": "brokerError","description":"broker-failure"}`),
+						}, nil)
+						waitForInstanceConditionAndMessage(ctx, defaultLookupKey, api.ConditionFailed, "broker-failure")
+
+						fakeClient.DeprovisionReturns("", &sm.ServiceManagerError{StatusCode: http.StatusBadRequest, Description: "delete-failure"})
+						Eventually(func() bool {
+							if err := k8sClient.Get(ctx, defaultLookupKey, serviceInstance); err != nil {
+								return false
+							}
+							serviceInstance.Spec.Parameters = &runtime.RawExtension{Raw: []byte(`{"key": "fixed-value"}`)}
+							return k8sClient.Update(ctx, serviceInstance) == nil
+						}, timeout, interval).Should(BeTrue())
+
+						waitForInstanceConditionAndMessage(ctx, defaultLookupKey, api.ConditionFailed, "failed to delete the failed instance before retrying provisioning")
+						Consistently(fakeClient.DeprovisionCallCount, "1s", interval).Should(Equal(1))
+						Expect(fakeClient.ProvisionCallCount()).To(Equal(1))
+					})
+				})
 			})
 
 			When("updating during create", func() {
@@ -354,28 +472,30 @@ var _ = Describe("ServiceInstance controller", func() {
 			})
 
 			When("deleting while create is in progress", func() {
-				It("should be deleted successfully", func() {
+				It("should be deleted once the creation completes", func() {
 					serviceInstance = createInstance(ctx, instanceSpec, false)
 
 					By("waiting for instance to be CreateInProgress")
 					waitForResourceCondition(ctx, serviceInstance, api.ConditionSucceeded, metav1.ConditionFalse, CreateInProgress, "")
 
-					fakeClient.DeprovisionReturns("/v1/service_instances/id/operations/1234", nil)
+					deleteInstance(ctx, serviceInstance, false)
+					waitForResourceCondition(ctx, serviceInstance, api.ConditionCancellationRequested, metav1.ConditionTrue, AwaitingCreation, "")
+					Expect(fakeClient.DeprovisionCallCount()).To(BeZero())
+
+					fakeClient.DeprovisionReturns("/v1/service_instances/id/operations/5678", nil)
 					fakeClient.StatusReturns(&smclientTypes.Operation{
 						ID:    "1234",
-						Type:  smClientTypes.DELETE,
-						State: smClientTypes.INPROGRESS,
+						Type:  smClientTypes.CREATE,
+						State: smClientTypes.SUCCEEDED,
 					}, nil)
-
-					deleteInstance(ctx, serviceInstance, false)
 					waitForResourceCondition(ctx, serviceInstance, api.ConditionSucceeded, metav1.ConditionFalse, DeleteInProgress, "")
+					Expect(fakeClient.DeprovisionCallCount()).To(Equal(1))
 
 					fakeClient.StatusReturns(&smclientTypes.Operation{
-						ID:    "1234",
+						ID:    "5678",
 						Type:  smClientTypes.DELETE,
 						State: smClientTypes.SUCCEEDED,
 					}, nil)
-
 					waitForResourceToBeDeleted(ctx, getResourceNamespacedName(serviceInstance), serviceInstance)
 				})
 			})
@@ -641,6 +761,18 @@ var _ = Describe("ServiceInstance controller", func() {
 					waitForResourceCondition(ctx, serviceInstance, api.ConditionFailed, metav1.ConditionTrue, DeleteFailed, errMsg)
 				})
 			})
+
+			When("the instance was already deleted in SM", func() {
+				It("should delete the k8s instance", func() {
+					fakeClient.DeprovisionReturns("", &sm.ServiceManagerError{
+						StatusCode:  http.StatusBadRequest,
+						ErrorType:   "BrokerError",
+						Description: "Service broker failed: instance does not exist",
+						BrokerError: &api.HTTPStatusCodeError{StatusCode: http.StatusNotFound},
+					})
+					deleteInstance(ctx, serviceInstance, true)
+				})
+			})
 		})
 
 		Context("Async", func() {
@@ -773,6 +905,19 @@ var _ = Describe("ServiceInstance controller", func() {
 				Expect(smCallArgs.FieldQuery[2]).To(ContainSubstring("context/namespace"))
 			})
 
+			When("the Service Manager does not support attach_last_operations", func() {
+				BeforeEach(func() {
+					fakeClient.CapabilitiesReturns(&sm.Capabilities{Features: map[sm.Feature]bool{sm.FeatureAttachLastOperations: false}})
+				})
+
+				It("should fail without recovering the instance", func() {
+					serviceInstance = createInstance(ctx, instanceSpec, false)
+					waitForResourceCondition(ctx, serviceInstance, api.ConditionSucceeded, metav1.ConditionFalse, CreateFailed, errRecoveryWithoutLastOperation.Error())
+					Expect(serviceInstance.Status.InstanceID).To(BeEmpty())
+					Expect(fakeClient.ProvisionCallCount()).To(BeZero())
+				})
+			})
+
 			Context("last operation", func() {
 				When("last operation state is PENDING and ends with success", func() {
 					BeforeEach(func() {
@@ -832,11 +977,18 @@ var _ = Describe("ServiceInstance controller", func() {
 						BeforeEach(func() {
 							recoveredInstance.Ready = false
 						})
-						It("should recover the instance with status Ready=false", func() {
+						It("should recover the instance as degraded and resync until it is ready", func() {
+							fakeClient.GetInstanceByIDReturns(&smclientTypes.ServiceInstance{ID: fakeInstanceID}, nil)
 							serviceInstance = createInstance(ctx, instanceSpec, false)
-							waitForResourceCondition(ctx, serviceInstance, api.ConditionFailed, metav1.ConditionTrue, CreateFailed, "")
+							waitForResourceCondition(ctx, serviceInstance, api.ConditionDegraded, metav1.ConditionTrue, NotReadyInSM, "")
+							Expect(isFailed(serviceInstance)).To(BeFalse())
+							Expect(serviceInstance.Status.Ready).To(Equal(metav1.ConditionFalse))
 							Expect(fakeClient.ProvisionCallCount()).To(Equal(0))
 							Expect(serviceInstance.Status.InstanceID).To(Equal(fakeInstanceID))
+
+							fakeClient.GetInstanceByIDReturns(&smclientTypes.ServiceInstance{ID: fakeInstanceID, Ready: true}, nil)
+							waitForResourceToBeReady(ctx, serviceInstance)
+							Expect(meta.FindStatusCondition(serviceInstance.GetConditions(), api.ConditionDegraded)).To(BeNil())
 						})
 					})
 
@@ -853,6 +1005,17 @@ var _ = Describe("ServiceInstance controller", func() {
 					serviceInstance = createInstance(ctx, sharedInstanceSpec, true)
 					waitForInstanceToBeShared(ctx, serviceInstance)
 				})
+
+				It("should share the instance once the async provisioning succeeded", func() {
+					fakeClient.ShareInstanceReturns(nil)
+					fakeClient.ProvisionReturns(&sm.ProvisionResponse{InstanceID: fakeInstanceID, Location: "/v1/service_instances/fakeid/operations/1234"}, nil)
+					fakeClient.StatusReturns(&smclientTypes.Operation{ID: "1234", Type: smClientTypes.CREATE, State: smClientTypes.SUCCEEDED}, nil)
+					fakeClient.GetInstanceByIDReturns(&smclientTypes.ServiceInstance{ID: fakeInstanceID}, nil)
+					serviceInstance = createInstance(ctx, sharedInstanceSpec, false)
+					waitForInstanceToBeShared(ctx, serviceInstance)
+					Expect(serviceInstance.Status.Ready).To(Equal(metav1.ConditionTrue))
+					Expect(fakeClient.ShareInstanceCallCount()).To(Equal(1))
+				})
 			})
 
 			Context("sharing an existing instance", func() {
@@ -961,6 +1124,30 @@ var _ = Describe("ServiceInstance controller", func() {
 						waitForResourceCondition(ctx, serviceInstance, api.ConditionShared, metav1.ConditionFalse, ShareNotSupported, "")
 					})
 				})
+
+				When("sharing is not supported by the Service Manager", func() {
+					It("should have a final shared status without sharing the instance", func() {
+						fakeClient.CapabilitiesReturns(&sm.Capabilities{
+							APIVersion: "1.0",
+							Features:   map[sm.Feature]bool{sm.FeatureSharedInstances: false},
+						})
+						serviceInstance.Spec.Shared = pointer.Bool(true)
+						updateInstance(ctx, serviceInstance)
+						waitForResourceCondition(ctx, serviceInstance, api.ConditionShared, metav1.ConditionFalse, ShareNotSupported, "instance sharing is not supported by the Service Manager (API version 1.0)")
+						Expect(fakeClient.ShareInstanceCallCount()).To(BeZero())
+					})
+				})
+
+				When("the support of sharing could not be detected", func() {
+					It("should share the instance", func() {
+						fakeClient.CapabilitiesReturns(&sm.Capabilities{Features: map[sm.Feature]bool{}})
+						fakeClient.ShareInstanceReturns(nil)
+						serviceInstance.Spec.Shared = pointer.Bool(true)
+						updateInstance(ctx, serviceInstance)
+						waitForInstanceToBeShared(ctx, serviceInstance)
+						Expect(fakeClient.ShareInstanceCallCount()).To(Equal(1))
+					})
+				})
 			})
 		})
 
@@ -989,7 +1176,7 @@ var _ = Describe("ServiceInstance controller", func() {
 							_ = k8sClient.Get(ctx, defaultLookupKey, serviceInstance)
 							return meta.FindStatusCondition(serviceInstance.GetConditions(), api.ConditionShared) == nil
 						}, timeout, interval).Should(BeTrue())
-						Expect(len(serviceInstance.Status.Conditions)).To(Equal(2))
+						Expect(len(serviceInstance.Status.Conditions)).To(Equal(3))
 					})
 				})
 
@@ -1024,7 +1211,402 @@ var _ = Describe("ServiceInstance controller", func() {
 		})
 	})
 
+	Describe("Maintenance update", func() {
+		BeforeEach(func() {
+			fakeClient.ListPlansReturns(&smclientTypes.ServicePlans{ServicePlans: []smclientTypes.ServicePlan{
+				{ID: "plan-id", MaintenanceInfo: []byte(`{"version": "2.0.0"}`)},
+			}}, nil)
+			fakeClient.UpdateInstanceReturns(nil, "", nil)
+			serviceInstance = createInstance(ctx, instanceSpec, true)
+			serviceInstance.Status.UpgradeAvailable = true
+			serviceInstance.Status.AvailableMaintenanceVersion = "2.0.0"
+			serviceInstance = updateInstanceStatus(ctx, serviceInstance)
+		})
+
+		When("maintenance update is accepted", func() {
+			It("should update the instance with the maintenance info of the plan", func() {
+				serviceInstance.Spec.AcceptMaintenanceUpdate = true
+				serviceInstance = updateInstance(ctx, serviceInstance)
+				Eventually(func() bool {
+					return fakeClient.UpdateInstanceCallCount() > 0
+				}, timeout, interval).Should(BeTrue())
+				_, smInstance, offeringName, planName, _, _, _ := fakeClient.UpdateInstanceArgsForCall(0)
+				Expect(string(smInstance.MaintenanceInfo)).To(Equal(`{"version": "2.0.0"}`))
+				Expect(smInstance.Parameters).To(BeEmpty())
+				Expect(smInstance.ServicePlanID).To(Equal("plan-id"))
+				Expect(offeringName).To(BeEmpty())
+				Expect(planName).To(BeEmpty())
+
+				Eventually(func() bool {
+					err := k8sClient.Get(ctx, defaultLookupKey, serviceInstance)
+					return err == nil && !serviceInstance.Status.UpgradeAvailable
+				}, timeout, interval).Should(BeTrue())
+			})
+		})
+	})
+
+	Describe("Drift detection", func() {
+		BeforeEach(func() {
+			fakeClient.GetInstanceByIDReturns(&smclientTypes.ServiceInstance{ID: fakeInstanceID, Ready: true, Labels: smclientTypes.Labels{
+				"_namespace": []string{"other-namespace"},
+			}}, nil)
+			fakeClient.GetInstanceParametersReturns(map[string]interface{}{"key": "changed"}, nil)
+			fakeClient.UpdateInstanceReturns(nil, "", nil)
+		})
+
+		When("enforcement mode is Warn", func() {
+			It("should report the drift without changing the instance", func() {
+				spec := instanceSpec.DeepCopy()
+				spec.EnforcementMode = v1.EnforcementModeWarn
+				serviceInstance = createInstance(ctx, *spec, true)
+				waitForResourceCondition(ctx, serviceInstance, api.ConditionDrifted, metav1.ConditionTrue, DriftDetected, "parameter key")
+				Expect(serviceInstance.Status.LastDriftCheck).ToNot(BeNil())
+				Expect(fakeClient.UpdateInstanceLabelsCallCount()).To(BeZero())
+				Expect(fakeClient.UpdateInstanceCallCount()).To(BeZero())
+			})
+
+			It("should report a changed plan", func() {
+				fakeClient.GetInstanceByIDReturns(&smclientTypes.ServiceInstance{ID: fakeInstanceID, Ready: true, ServicePlanID: "other-plan-id"}, nil)
+				fakeClient.ListPlansReturns(&smclientTypes.ServicePlans{ServicePlans: []smclientTypes.ServicePlan{{ID: "other-plan-id", CatalogName: "other-plan"}}}, nil)
+				spec := instanceSpec.DeepCopy()
+				spec.EnforcementMode = v1.EnforcementModeWarn
+				serviceInstance = createInstance(ctx, *spec, true)
+				waitForResourceCondition(ctx, serviceInstance, api.ConditionDrifted, metav1.ConditionTrue, DriftDetected, fmt.Sprintf("plan other-plan (desired %s)", fakePlanName))
+			})
+		})
+
+		When("enforcement mode is Enforce", func() {
+			It("should revert the drifted labels and parameters", func() {
+				spec := instanceSpec.DeepCopy()
+				spec.EnforcementMode = v1.EnforcementModeEnforce
+				serviceInstance = createInstance(ctx, *spec, true)
+				Eventually(func() bool {
+					return fakeClient.UpdateInstanceLabelsCallCount() > 0 && fakeClient.UpdateInstanceCallCount() > 0
+				}, timeout, interval).Should(BeTrue())
+
+				_, labelChanges, _ := fakeClient.UpdateInstanceLabelsArgsForCall(0)
+				Expect(labelChanges).To(ContainElement(&smclientTypes.LabelChange{Key: "_namespace", Operation: smclientTypes.RemoveLabelOperation, Values: []string{"other-namespace"}}))
+				Expect(labelChanges).To(ContainElement(&smclientTypes.LabelChange{Key: "_namespace", Operation: smclientTypes.AddLabelOperation, Values: []string{testNamespace}}))
+				_, smInstance, _, _, _, _, _ := fakeClient.UpdateInstanceArgsForCall(0)
+				Expect(string(smInstance.Parameters)).To(ContainSubstring(`"key":"value"`))
+			})
+
+			It("should not update the instance when the broker does not return its parameters", func() {
+				fakeClient.GetInstanceParametersReturns(nil, &sm.ServiceManagerError{StatusCode: http.StatusNotImplemented, Description: "not supported"})
+				spec := instanceSpec.DeepCopy()
+				spec.EnforcementMode = v1.EnforcementModeEnforce
+				serviceInstance = createInstance(ctx, *spec, true)
+				Eventually(func() bool {
+					return fakeClient.UpdateInstanceLabelsCallCount() > 0
+				}, timeout, interval).Should(BeTrue())
+				Consistently(fakeClient.UpdateInstanceCallCount, time.Second, interval).Should(BeZero())
+			})
+		})
+
+		When("enforcement mode is Ignore", func() {
+			It("should not check the instance for drift", func() {
+				serviceInstance = createInstance(ctx, instanceSpec, true)
+				Consistently(func() int {
+					return fakeClient.GetInstanceParametersCallCount()
+				}, time.Second, interval).Should(BeZero())
+			})
+		})
+	})
+
+	Describe("Expiration", func() {
+		When("the instance expires within the warning period", func() {
+			It("should warn about the expiration", func() {
+				serviceInstance = createInstance(ctx, instanceSpec, true)
+				serviceInstance.Spec.ExpiresAt = &metav1.Time{Time: time.Now().Add(time.Hour)}
+				serviceInstance = updateInstance(ctx, serviceInstance)
+				Eventually(func() bool {
+					err := k8sClient.Get(ctx, defaultLookupKey, serviceInstance)
+					return err == nil && serviceInstance.Status.LastExpirationWarning != nil
+				}, timeout, interval).Should(BeTrue())
+				Expect(fakeClient.DeprovisionCallCount()).To(BeZero())
+				Expect(fakeClient.UpdateInstanceCallCount()).To(BeZero())
+			})
+		})
+
+		When("the instance expired", func() {
+			It("should delete the instance", func() {
+				serviceInstance = createInstance(ctx, instanceSpec, true)
+				serviceInstance.Spec.ExpiresAt = &metav1.Time{Time: time.Now().Add(2 * time.Second)}
+				serviceInstance = updateInstance(ctx, serviceInstance)
+				waitForResourceToBeDeleted(ctx, defaultLookupKey, serviceInstance)
+				Expect(fakeClient.DeprovisionCallCount()).To(Equal(1))
+			})
+		})
+	})
+
 	Context("Unit Tests", func() {
+		Context("getLabelsDrift", func() {
+			It("should return no changes when the labels match", func() {
+				desired := smclientTypes.Labels{"_namespace": []string{"ns"}}
+				Expect(getLabelsDrift(desired, smclientTypes.Labels{"_namespace": []string{"ns"}, "custom": []string{"value"}})).To(BeEmpty())
+			})
+
+			It("should replace drifted values", func() {
+				desired := smclientTypes.Labels{"_namespace": []string{"ns"}, "_k8sname": []string{"name"}}
+				labelChanges := getLabelsDrift(desired, smclientTypes.Labels{"_namespace": []string{"other"}})
+				Expect(labelChanges).To(Equal([]*smclientTypes.LabelChange{
+					{Key: "_k8sname", Operation: smclientTypes.AddLabelOperation, Values: []string{"name"}},
+					{Key: "_namespace", Operation: smclientTypes.RemoveLabelOperation, Values: []string{"other"}},
+					{Key: "_namespace", Operation: smclientTypes.AddLabelOperation, Values: []string{"ns"}},
+				}))
+			})
+		})
+
+		Context("getParametersDrift", func() {
+			It("should return the drifted parameters and ignore parameters not in the spec", func() {
+				desired := map[string]interface{}{"a": "1", "b": map[string]interface{}{"c": float64(2)}, "d": true}
+				actual := map[string]interface{}{"a": "1", "b": map[string]interface{}{"c": float64(3)}, "e": "extra"}
+				Expect(getParametersDrift(desired, actual)).To(Equal([]string{"b"}))
+			})
+
+			It("should not report parameters missing in SM as drifted", func() {
+				desired := map[string]interface{}{"password": "secret"}
+				Expect(getParametersDrift(desired, map[string]interface{}{})).To(BeEmpty())
+				Expect(getParametersDrift(desired, nil)).To(BeEmpty())
+			})
+		})
+
+		Context("getPlanDrift", func() {
+			var smClient *smfakes.FakeClient
+			var instance *v1.ServiceInstance
+
+			BeforeEach(func() {
+				smClient = &smfakes.FakeClient{}
+				smClient.ListPlansReturns(&smclientTypes.ServicePlans{ServicePlans: []smclientTypes.ServicePlan{{ID: "plan-id", CatalogName: "other-plan"}}}, nil)
+				instance = &v1.ServiceInstance{Spec: v1.ServiceInstanceSpec{ServicePlanName: "my-plan"}}
+			})
+
+			It("should return the plan of the instance in SM when its name differs", func() {
+				plan, err := getPlanDrift(smClient, instance, &smclientTypes.ServiceInstance{ServicePlanID: "plan-id"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(plan).To(Equal("other-plan"))
+				Expect(smClient.ListPlansArgsForCall(0).FieldQuery).To(ConsistOf("id eq 'plan-id'"))
+			})
+
+			It("should compare the plan ID when the spec sets it", func() {
+				instance.Spec.ServicePlanID = "plan-id"
+				plan, err := getPlanDrift(smClient, instance, &smclientTypes.ServiceInstance{ServicePlanID: "plan-id"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(plan).To(BeEmpty())
+			})
+
+			It("should not report the plan recorded for the instance", func() {
+				instance.Status.ServicePlanID = "plan-id"
+				instance.Status.HashedPlan = getPlanHash(instance)
+				plan, err := getPlanDrift(smClient, instance, &smclientTypes.ServiceInstance{ServicePlanID: "plan-id"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(plan).To(BeEmpty())
+				Expect(smClient.ListPlansCallCount()).To(BeZero())
+			})
+
+			It("should report a plan which differs from the recorded plan, even with the name of the spec", func() {
+				smClient.ListPlansReturns(&smclientTypes.ServicePlans{ServicePlans: []smclientTypes.ServicePlan{{ID: "plan-id", CatalogName: "my-plan"}}}, nil)
+				instance.Status.ServicePlanID = "recorded-plan-id"
+				instance.Status.HashedPlan = getPlanHash(instance)
+				plan, err := getPlanDrift(smClient, instance, &smclientTypes.ServiceInstance{ServicePlanID: "plan-id"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(plan).To(Equal("my-plan"))
+			})
+
+			It("should compare the plan name once the plan of the spec changed", func() {
+				instance.Status.ServicePlanID = "plan-id"
+				instance.Status.HashedPlan = getPlanHash(instance)
+				instance.Spec.ServicePlanName = "new-plan"
+				plan, err := getPlanDrift(smClient, instance, &smclientTypes.ServiceInstance{ServicePlanID: "plan-id"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(plan).To(Equal("other-plan"))
+			})
+
+			It("should not compare the plan when SM does not report it", func() {
+				plan, err := getPlanDrift(smClient, instance, &smclientTypes.ServiceInstance{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(plan).To(BeEmpty())
+				Expect(smClient.ListPlansCallCount()).To(BeZero())
+			})
+		})
+
+		Context("recordCatalogIDs", func() {
+			var smClient *smfakes.FakeClient
+
+			BeforeEach(func() {
+				smClient = &smfakes.FakeClient{}
+				smClient.ListOfferingsReturns(&smclientTypes.ServiceOfferings{ServiceOfferings: []smclientTypes.ServiceOffering{{ID: "offering-id", BrokerID: "broker-id"}}}, nil)
+			})
+
+			It("should record the IDs of the plan, the offering and the broker", func() {
+				instance := &v1.ServiceInstance{}
+				Expect(recordCatalogIDs(smClient, instance, &smclientTypes.ServicePlan{ID: "plan-id", ServiceOfferingID: "offering-id"})).To(Succeed())
+				Expect(instance.Status.ServicePlanID).To(Equal("plan-id"))
+				Expect(instance.Status.ServiceOfferingID).To(Equal("offering-id"))
+				Expect(instance.Status.BrokerID).To(Equal("broker-id"))
+				Expect(smClient.ListOfferingsArgsForCall(0).FieldQuery).To(ConsistOf("id eq 'offering-id'"))
+			})
+
+			It("should record only the desired plan", func() {
+				instance := &v1.ServiceInstance{Spec: v1.ServiceInstanceSpec{ServicePlanName: "my-plan"}}
+				Expect(planRecordable(instance, &smclientTypes.ServicePlan{ID: "plan-id", CatalogName: "my-plan"})).To(BeTrue())
+				Expect(planRecordable(instance, &smclientTypes.ServicePlan{ID: "other-plan-id", CatalogName: "other-plan"})).To(BeFalse())
+
+				instance.Status.ServicePlanID = "plan-id"
+				instance.Status.HashedPlan = getPlanHash(instance)
+				Expect(planRecordable(instance, &smclientTypes.ServicePlan{ID: "plan-id", CatalogName: "renamed-plan"})).To(BeTrue())
+				Expect(planRecordable(instance, &smclientTypes.ServicePlan{ID: "other-plan-id", CatalogName: "my-plan"})).To(BeFalse())
+			})
+
+			It("should not read the offering again once its IDs are recorded", func() {
+				instance := &v1.ServiceInstance{Status: v1.ServiceInstanceStatus{ServiceOfferingID: "offering-id", BrokerID: "broker-id"}}
+				Expect(recordCatalogIDs(smClient, instance, &smclientTypes.ServicePlan{ID: "other-plan-id", ServiceOfferingID: "offering-id"})).To(Succeed())
+				Expect(instance.Status.ServicePlanID).To(Equal("other-plan-id"))
+				Expect(smClient.ListOfferingsCallCount()).To(BeZero())
+			})
+		})
+
+		Context("parametersNotRetrievable", func() {
+			It("should be true for brokers that do not support fetching parameters", func() {
+				Expect(parametersNotRetrievable(&sm.ServiceManagerError{StatusCode: http.StatusNotImplemented})).To(BeTrue())
+				Expect(parametersNotRetrievable(&sm.ServiceManagerError{StatusCode: http.StatusBadRequest})).To(BeTrue())
+			})
+
+			It("should be false for other errors", func() {
+				Expect(parametersNotRetrievable(&sm.ServiceManagerError{StatusCode: http.StatusBadGateway})).To(BeFalse())
+				Expect(parametersNotRetrievable(fmt.Errorf("connection refused"))).To(BeFalse())
+			})
+		})
+
+		Context("driftCheckEnabled", func() {
+			var instance *v1.ServiceInstance
+			BeforeEach(func() {
+				instance = &v1.ServiceInstance{
+					Spec:   v1.ServiceInstanceSpec{EnforcementMode: v1.EnforcementModeWarn},
+					Status: v1.ServiceInstanceStatus{InstanceID: fakeInstanceID, Ready: metav1.ConditionTrue},
+				}
+			})
+
+			It("should return true for a ready instance in Warn mode", func() {
+				Expect(driftCheckEnabled(instance, time.Hour)).To(BeTrue())
+				Expect(driftCheckDelay(instance, time.Hour)).To(BeZero())
+			})
+
+			It("should return false in Ignore mode", func() {
+				instance.Spec.EnforcementMode = v1.EnforcementModeIgnore
+				Expect(driftCheckEnabled(instance, time.Hour)).To(BeFalse())
+			})
+
+			It("should return false when disabled", func() {
+				Expect(driftCheckEnabled(instance, 0)).To(BeFalse())
+			})
+
+			It("should delay the check until the interval passed", func() {
+				instance.Status.LastDriftCheck = &metav1.Time{Time: time.Now()}
+				Expect(driftCheckDelay(instance, time.Hour)).To(BeNumerically(">", 59*time.Minute))
+			})
+		})
+
+		Context("expiration", func() {
+			var instance *v1.ServiceInstance
+			BeforeEach(func() {
+				instance = &v1.ServiceInstance{Spec: v1.ServiceInstanceSpec{ExpiresAt: &metav1.Time{Time: time.Now().Add(2 * time.Hour)}}}
+			})
+
+			It("should not expire instances without expiration time", func() {
+				instance.Spec.ExpiresAt = nil
+				Expect(isExpired(instance)).To(BeFalse())
+				Expect(expirationWarningDue(instance, time.Hour)).To(BeFalse())
+				Expect(expirationDelay(instance, time.Hour)).To(BeZero())
+			})
+
+			It("should delay the warning until the warning period", func() {
+				Expect(isExpired(instance)).To(BeFalse())
+				Expect(expirationWarningDue(instance, time.Hour)).To(BeFalse())
+				Expect(expirationDelay(instance, time.Hour)).To(BeNumerically("~", time.Hour, time.Minute))
+			})
+
+			It("should warn once within the warning period", func() {
+				Expect(expirationWarningDue(instance, 3*time.Hour)).To(BeTrue())
+				instance.Status.LastExpirationWarning = &metav1.Time{Time: time.Now()}
+				Expect(expirationWarningDue(instance, 3*time.Hour)).To(BeFalse())
+				Expect(expirationDelay(instance, 3*time.Hour)).To(BeNumerically("~", 2*time.Hour, time.Minute))
+			})
+
+			It("should warn again when the expiration was postponed", func() {
+				instance.Status.LastExpirationWarning = &metav1.Time{Time: time.Now().Add(-2 * time.Hour)}
+				Expect(expirationWarningDue(instance, 3*time.Hour)).To(BeTrue())
+			})
+
+			It("should not warn when the warning is disabled", func() {
+				Expect(expirationWarningDue(instance, 0)).To(BeFalse())
+			})
+
+			It("should expire the instance at the expiration time", func() {
+				instance.Spec.ExpiresAt = &metav1.Time{Time: time.Now().Add(-time.Second)}
+				Expect(isExpired(instance)).To(BeTrue())
+			})
+
+			It("should not expire instances protected from deletion", func() {
+				instance.Spec.ExpiresAt = &metav1.Time{Time: time.Now().Add(-time.Second)}
+				instance.Annotations = map[string]string{api.PreventDeletion: "true"}
+				Expect(isExpired(instance)).To(BeFalse())
+			})
+
+			It("should not warn about instances protected from deletion", func() {
+				instance.Annotations = map[string]string{api.PreventDeletion: "true"}
+				Expect(expirationWarningDue(instance, 3*time.Hour)).To(BeFalse())
+				Expect(expirationDelay(instance, 3*time.Hour)).To(BeZero())
+			})
+
+			It("should index the bindings by the namespaced name of their instance", func() {
+				binding := &v1.ServiceBinding{ObjectMeta: metav1.ObjectMeta{Name: "binding", Namespace: "binding-ns"}, Spec: v1.ServiceBindingSpec{ServiceInstanceName: "instance"}}
+				Expect(indexBindingInstance(binding)).To(ConsistOf("binding-ns/instance"))
+				binding.Spec.ServiceInstanceNamespace = "instance-ns"
+				Expect(indexBindingInstance(binding)).To(ConsistOf("instance-ns/instance"))
+			})
+		})
+
+		Context("maintenanceCheckRequired", func() {
+			var instance *v1.ServiceInstance
+			BeforeEach(func() {
+				instance = &v1.ServiceInstance{Status: v1.ServiceInstanceStatus{InstanceID: fakeInstanceID, Ready: metav1.ConditionTrue}}
+			})
+
+			It("should return true when never checked", func() {
+				Expect(maintenanceCheckRequired(instance, time.Hour)).To(BeTrue())
+			})
+
+			It("should return false when checked within the interval", func() {
+				instance.Status.LastMaintenanceCheck = &metav1.Time{Time: time.Now()}
+				Expect(maintenanceCheckRequired(instance, time.Hour)).To(BeFalse())
+			})
+
+			It("should return true when the last check is older than the interval", func() {
+				instance.Status.LastMaintenanceCheck = &metav1.Time{Time: time.Now().Add(-2 * time.Hour)}
+				Expect(maintenanceCheckRequired(instance, time.Hour)).To(BeTrue())
+			})
+
+			It("should return false when the instance is not ready", func() {
+				instance.Status.Ready = metav1.ConditionFalse
+				Expect(maintenanceCheckRequired(instance, time.Hour)).To(BeFalse())
+			})
+
+			It("should return false when disabled", func() {
+				Expect(maintenanceCheckRequired(instance, 0)).To(BeFalse())
+			})
+		})
+
+		Context("getMaintenanceInfoVersion", func() {
+			It("should return the version", func() {
+				Expect(getMaintenanceInfoVersion([]byte(`{"version": "1.2.3", "description": "desc"}`))).To(Equal("1.2.3"))
+			})
+
+			It("should return empty version when maintenance info is missing", func() {
+				Expect(getMaintenanceInfoVersion(nil)).To(BeEmpty())
+			})
+		})
+
 		Context("isFinalState", func() {
 			When("Succeeded condition is not for current generation", func() {
 				It("should be false", func() {
@@ -1234,12 +1816,12 @@ func waitForInstanceConditionAndMessage(ctx context.Context, key types.Namespace
 
 func waitForInstanceToBeShared(ctx context.Context, serviceInstance *v1.ServiceInstance) {
 	waitForResourceCondition(ctx, serviceInstance, api.ConditionShared, metav1.ConditionTrue, "", "")
-	Expect(len(serviceInstance.Status.Conditions)).To(Equal(3))
+	Expect(len(serviceInstance.Status.Conditions)).To(Equal(4))
 }
 
 func waitForInstanceToBeUnShared(ctx context.Context, serviceInstance *v1.ServiceInstance) {
 	waitForResourceCondition(ctx, serviceInstance, api.ConditionShared, metav1.ConditionFalse, "", "")
-	Expect(len(serviceInstance.Status.Conditions)).To(Equal(3))
+	Expect(len(serviceInstance.Status.Conditions)).To(Equal(4))
 }
 
 func updateInstance(ctx context.Context, serviceInstance *v1.ServiceInstance) *v1.ServiceInstance {
@@ -1280,3 +1862,85 @@ func updateInstanceStatus(ctx context.Context, instance *v1.ServiceInstance) *v1
 	}, timeout, interval).Should(BeTrue())
 	return si
 }
+
+var _ = Describe("ServiceInstance creation round trips", func() {
+	var (
+		ctx        context.Context
+		smClient   *smfakes.FakeClient
+		reconciler *ServiceInstanceReconciler
+		instance   *v1.ServiceInstance
+		updates    int
+		writes     int
+	)
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log.WithName("instanceRoundTripsTest"))
+		smClient = &smfakes.FakeClient{}
+		smClient.ProvisionReturns(&sm.ProvisionResponse{InstanceID: fakeInstanceID, SubaccountID: fakeSubaccountID}, nil)
+		instance = &v1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "round-trips-instance", Namespace: testNamespace, Generation: 1},
+			Spec:       v1.ServiceInstanceSpec{ExternalName: fakeInstanceExternalName, ServiceOfferingName: fakeOfferingName, ServicePlanName: fakePlanName},
+		}
+		updates, writes = 0, 0
+	})
+
+	newReconciler := func() *ServiceInstanceReconciler {
+		testScheme := runtime.NewScheme()
+		Expect(v1.AddToScheme(testScheme)).To(Succeed())
+		return &ServiceInstanceReconciler{BaseReconciler: &BaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance).WithStatusSubresource(instance).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+						updates++
+						writes++
+						return c.Update(ctx, obj, opts...)
+					},
+					SubResourceUpdate: func(ctx context.Context, c client.Client, subResource string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+						writes++
+						return c.SubResource(subResource).Update(ctx, obj, opts...)
+					},
+				}).Build(),
+			Scheme:   testScheme,
+			Log:      ctrl.Log,
+			Recorder: record.NewFakeRecorder(100),
+			SMClient: func() sm.Client { return smClient },
+		}}
+	}
+
+	// reconcileCreation reconciles the created instance until a reconcile writes nothing, the writes of a reconcile
+	// trigger one more reconcile, and returns the number of reconciles
+	reconcileCreation := func() int {
+		reconciles := 0
+		for last := -1; writes != last; {
+			Expect(reconciles).To(BeNumerically("<", 10))
+			last = writes
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(instance)})
+			Expect(err).ToNot(HaveOccurred())
+			reconciles++
+		}
+		stored := &v1.ServiceInstance{}
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(instance), stored)).To(Succeed())
+		Expect(stored.Status.InstanceID).To(Equal(fakeInstanceID))
+		Expect(stored.Finalizers).To(ContainElement(api.FinalizerName))
+		return reconciles
+	}
+
+	It("should not update an instance whose finalizer was added on creation", func() {
+		instance.Finalizers = []string{api.FinalizerName}
+		reconciler = newReconciler()
+		reconciles := reconcileCreation()
+		// the status is initialized and the provisioning recorded, no update adds the finalizer
+		Expect(updates).To(BeZero())
+		Expect(writes).To(Equal(2))
+		Expect(smClient.ProvisionCallCount()).To(Equal(1))
+		Expect(reconciles).To(Equal(2))
+	})
+
+	It("should add the finalizer of an instance created while the webhooks were disabled", func() {
+		reconciler = newReconciler()
+		reconciles := reconcileCreation()
+		Expect(updates).To(Equal(1))
+		Expect(writes).To(Equal(3))
+		Expect(reconciles).To(Equal(2))
+	})
+})
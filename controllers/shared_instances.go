@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// sharedInstanceOf returns the shared instance a binding consumes by its ID in SM. The instance has no ServiceInstance
+// in the cluster, it is described by what was resolved in SM when the binding was created and is accessed with the
+// access credentials of the namespace of the binding.
+func sharedInstanceOf(binding *servicesv1.ServiceBinding) *servicesv1.ServiceInstance {
+	instance := &servicesv1.ServiceInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: binding.Spec.ServiceInstanceID},
+		Status: servicesv1.ServiceInstanceStatus{
+			InstanceID: binding.Spec.ServiceInstanceID,
+			Ready:      metav1.ConditionTrue,
+		},
+	}
+	if shared := binding.Status.SharedInstance; shared != nil {
+		instance.Spec.ExternalName = shared.Name
+		instance.Spec.ServiceOfferingName = shared.ServiceOfferingName
+		instance.Spec.ServicePlanName = shared.ServicePlanName
+	}
+	return instance
+}
+
+// resolveSharedInstance looks up the shared instance a binding consumes by its ID before the binding is created, and
+// records its name, offering and plan in the status of the binding for the labels and the secret of the binding.
+// It returns an error as notPermitted if the instance is not shared or not visible to the subaccount of the binding.
+func (r *ServiceBindingReconciler) resolveSharedInstance(ctx context.Context, smClient sm.Client, serviceInstance *servicesv1.ServiceInstance, binding *servicesv1.ServiceBinding) (notPermitted error, err error) {
+	instanceID := binding.Spec.ServiceInstanceID
+	smInstance, err := smClient.GetInstanceByID(instanceID, nil)
+	if err != nil {
+		if isNotFoundError(err) {
+			return fmt.Errorf("instance '%s' was not found in the subaccount of the binding", instanceID), nil
+		}
+		return nil, err
+	}
+	if !smInstance.Shared {
+		return fmt.Errorf("instance '%s' is not shared", instanceID), nil
+	}
+	if err := verifyAllowedConsumer(smInstance, binding); err != nil {
+		return err, nil
+	}
+	if !smInstance.Ready {
+		return nil, fmt.Errorf("the shared instance '%s' is not ready", instanceID)
+	}
+
+	shared := &servicesv1.SharedInstanceInfo{Name: smInstance.Name}
+	plans, err := smClient.ListPlans(&sm.Parameters{FieldQuery: []string{fmt.Sprintf("id eq '%s'", smInstance.ServicePlanID)}})
+	if err != nil {
+		return nil, err
+	}
+	if plans != nil && len(plans.ServicePlans) > 0 {
+		plan := plans.ServicePlans[0]
+		shared.ServicePlanName = plan.CatalogName
+		offerings, err := smClient.ListOfferings(&sm.Parameters{FieldQuery: []string{fmt.Sprintf("id eq '%s'", plan.ServiceOfferingID)}})
+		if err != nil {
+			return nil, err
+		}
+		if offerings != nil && len(offerings.ServiceOfferings) > 0 {
+			shared.ServiceOfferingName = offerings.ServiceOfferings[0].CatalogName
+		}
+	}
+	GetLogger(ctx).Info(fmt.Sprintf("binding the shared instance '%s' of offering '%s' and plan '%s'", smInstance.Name, shared.ServiceOfferingName, shared.ServicePlanName))
+	binding.Status.SharedInstance = shared
+	*serviceInstance = *sharedInstanceOf(binding)
+	return nil, nil
+}
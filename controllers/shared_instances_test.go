@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	"github.com/SAP/sap-btp-service-operator/client/sm/smfakes"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("Shared instances", func() {
+	var binding *servicesv1.ServiceBinding
+	var smClient *smfakes.FakeClient
+	var reconciler *ServiceBindingReconciler
+	var ctx context.Context
+
+	BeforeEach(func() {
+		binding = &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1"},
+			Spec:       servicesv1.ServiceBindingSpec{ServiceInstanceID: "shared-id"},
+		}
+		smClient = &smfakes.FakeClient{}
+		smClient.ListPlansReturns(&smClientTypes.ServicePlans{ServicePlans: []smClientTypes.ServicePlan{{ID: "plan-id", CatalogName: "standard", ServiceOfferingID: "offering-id"}}}, nil)
+		smClient.ListOfferingsReturns(&smClientTypes.ServiceOfferings{ServiceOfferings: []smClientTypes.ServiceOffering{{ID: "offering-id", CatalogName: "xsuaa"}}}, nil)
+		reconciler = &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{Log: ctrl.Log}}
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+	})
+
+	It("should resolve the offering and plan of a shared instance", func() {
+		smClient.GetInstanceByIDReturns(&smClientTypes.ServiceInstance{ID: "shared-id", Name: "shared-xsuaa", ServicePlanID: "plan-id", Shared: true, Ready: true}, nil)
+		instance := sharedInstanceOf(binding)
+		Expect(instance.Status.InstanceID).To(Equal("shared-id"))
+		Expect(isInProgress(instance) || serviceNotUsable(instance)).To(BeFalse())
+
+		notPermitted, err := reconciler.resolveSharedInstance(ctx, smClient, instance, binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(notPermitted).ToNot(HaveOccurred())
+		Expect(binding.Status.SharedInstance).To(Equal(&servicesv1.SharedInstanceInfo{Name: "shared-xsuaa", ServiceOfferingName: "xsuaa", ServicePlanName: "standard"}))
+		Expect(instance.Spec.ServiceOfferingName).To(Equal("xsuaa"))
+		Expect(instance.Spec.ExternalName).To(Equal("shared-xsuaa"))
+		Expect(smClient.ListPlansArgsForCall(0).FieldQuery).To(ConsistOf("id eq 'plan-id'"))
+	})
+
+	It("should not permit binding an instance that is not shared", func() {
+		smClient.GetInstanceByIDReturns(&smClientTypes.ServiceInstance{ID: "shared-id", Ready: true}, nil)
+		notPermitted, err := reconciler.resolveSharedInstance(ctx, smClient, sharedInstanceOf(binding), binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(notPermitted).To(MatchError("instance 'shared-id' is not shared"))
+		Expect(binding.Status.SharedInstance).To(BeNil())
+	})
+
+	It("should not permit binding an instance that is not visible to the subaccount", func() {
+		smClient.GetInstanceByIDReturns(nil, &sm.ServiceManagerError{StatusCode: http.StatusNotFound})
+		notPermitted, err := reconciler.resolveSharedInstance(ctx, smClient, sharedInstanceOf(binding), binding)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(notPermitted).To(MatchError(ContainSubstring("was not found in the subaccount of the binding")))
+	})
+
+	It("should retry while the shared instance is not ready", func() {
+		smClient.GetInstanceByIDReturns(&smClientTypes.ServiceInstance{ID: "shared-id", Shared: true}, nil)
+		notPermitted, err := reconciler.resolveSharedInstance(ctx, smClient, sharedInstanceOf(binding), binding)
+		Expect(notPermitted).ToNot(HaveOccurred())
+		Expect(err).To(MatchError(ContainSubstring("is not ready")))
+	})
+})
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// SharingNotPermitted the shared instance of the binding is not shared with, or not visible to, the subaccount of the binding
+	SharingNotPermitted = "SharingNotPermitted"
+
+	// referencedInstanceIDParameter is the parameter of reference instances holding the ID of the shared instance they consume
+	referencedInstanceIDParameter = "referenced_instance_id"
+)
+
+// smErrorCode identifies an SM or broker error by its status code and machine-readable error type
+type smErrorCode struct {
+	StatusCode int
+	ErrorType  string
+}
+
+// sharingErrorCodes are the errors returned by SM, or by the broker through SM, when the instance a binding consumes
+// was not shared by the subaccount that owns it, or is not visible to the subaccount of the binding
+var sharingErrorCodes = []smErrorCode{
+	{StatusCode: http.StatusBadRequest, ErrorType: "InstanceNotShared"},
+	{StatusCode: http.StatusForbidden, ErrorType: "InstanceNotShared"},
+	{StatusCode: http.StatusNotFound, ErrorType: "ReferencedInstanceNotFound"},
+}
+
+// isSharingNotPermittedError checks whether an SM error is caused by missing sharing or visibility of a shared instance
+func isSharingNotPermittedError(err error) bool {
+	var smError *sm.ServiceManagerError
+	if !errors.As(err, &smError) {
+		return false
+	}
+	code := smErrorCode{StatusCode: smError.StatusCode, ErrorType: smError.ErrorType}
+	if smError.BrokerError != nil {
+		code = smErrorCode{StatusCode: smError.BrokerError.StatusCode}
+		if smError.BrokerError.ErrorMessage != nil {
+			code.ErrorType = *smError.BrokerError.ErrorMessage
+		}
+	}
+	for _, sharingErrorCode := range sharingErrorCodes {
+		if code == sharingErrorCode {
+			return true
+		}
+	}
+	return false
+}
+
+// markAsSharingNotPermitted blocks a binding whose creation was rejected because its shared instance is not consumable
+// by the subaccount of the binding, with a hint how the sharing is granted. The creation is retried with
+// the long poll interval, sharing may be granted at any time.
+func (r *ServiceBindingReconciler) markAsSharingNotPermitted(ctx context.Context, serviceInstance *servicesv1.ServiceInstance, serviceBinding *servicesv1.ServiceBinding, err error) (ctrl.Result, error) {
+	message := fmt.Sprintf("binding to the shared instance is not permitted: %s. %s", err.Error(), sharingRemediation(serviceInstance))
+	GetLogger(ctx).Info(message)
+
+	setBlockedCondition(ctx, message, serviceBinding)
+	meta.SetStatusCondition(&serviceBinding.Status.Conditions, metav1.Condition{
+		Type:               api.ConditionSharingNotPermitted,
+		Status:             metav1.ConditionTrue,
+		Reason:             SharingNotPermitted,
+		Message:            message,
+		ObservedGeneration: serviceBinding.Generation,
+	})
+	return ctrl.Result{RequeueAfter: r.longPollInterval()}, r.updateStatus(ctx, serviceBinding)
+}
+
+// sharingRemediation describes who must act for the binding to the shared instance to be permitted. The message is
+// readable by everyone who can read the binding, it must not reveal the subaccount that owns the shared instance.
+func sharingRemediation(serviceInstance *servicesv1.ServiceInstance) string {
+	shared := "the shared instance"
+	if id := referencedInstanceID(serviceInstance); len(id) > 0 {
+		shared = fmt.Sprintf("instance '%s' referenced by service instance '%s'", id, serviceInstance.Name)
+	}
+	return fmt.Sprintf("The subaccount that owns %s must share it (e.g. with 'shared: true' on its ServiceInstance) "+
+		"and the service plan must be entitled in it", shared)
+}
+
+// referencedInstanceID returns the ID of the shared instance consumed by a reference instance, if given in its parameters
+func referencedInstanceID(serviceInstance *servicesv1.ServiceInstance) string {
+	if serviceInstance.Spec.Parameters == nil {
+		return ""
+	}
+	var parameters map[string]interface{}
+	if err := json.Unmarshal(serviceInstance.Spec.Parameters.Raw, &parameters); err != nil {
+		return ""
+	}
+	id, _ := parameters[referencedInstanceIDParameter].(string)
+	return id
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var _ = Describe("Sharing errors", func() {
+	Context("isSharingNotPermittedError", func() {
+		It("should detect SM errors about instances that are not shared", func() {
+			err := &sm.ServiceManagerError{StatusCode: http.StatusBadRequest, ErrorType: "InstanceNotShared", Description: "The referenced instance is not shared"}
+			Expect(isSharingNotPermittedError(err)).To(BeTrue())
+		})
+
+		It("should detect broker errors about instances that are not shared", func() {
+			errorMessage := "InstanceNotShared"
+			err := &sm.ServiceManagerError{StatusCode: http.StatusBadGateway, BrokerError: &api.HTTPStatusCodeError{StatusCode: http.StatusForbidden, ErrorMessage: &errorMessage}}
+			Expect(isSharingNotPermittedError(err)).To(BeTrue())
+		})
+
+		It("should ignore other SM errors, even if they mention sharing", func() {
+			err := &sm.ServiceManagerError{StatusCode: http.StatusBadRequest, ErrorType: "BadRequest", Description: "the instance is not shared"}
+			Expect(isSharingNotPermittedError(err)).To(BeFalse())
+			err = &sm.ServiceManagerError{StatusCode: http.StatusInternalServerError, ErrorType: "InstanceNotShared"}
+			Expect(isSharingNotPermittedError(err)).To(BeFalse())
+		})
+
+		It("should ignore errors that are not returned by SM", func() {
+			Expect(isSharingNotPermittedError(fmt.Errorf("instance is not shared"))).To(BeFalse())
+		})
+	})
+
+	Context("sharingRemediation", func() {
+		It("should name the referenced instance but not the subaccount that owns it", func() {
+			instance := &servicesv1.ServiceInstance{}
+			instance.Name = "my-reference"
+			instance.Spec.Parameters = &runtime.RawExtension{Raw: []byte(`{"referenced_instance_id": "shared-id"}`)}
+			instance.Status.SubaccountID = "owner-subaccount"
+			hint := sharingRemediation(instance)
+			Expect(hint).To(HavePrefix("The subaccount that owns instance 'shared-id' referenced by service instance 'my-reference' must share it"))
+			Expect(hint).ToNot(ContainSubstring("owner-subaccount"))
+		})
+
+		It("should fall back to a generic hint", func() {
+			hint := sharingRemediation(&servicesv1.ServiceInstance{})
+			Expect(hint).To(HavePrefix("The subaccount that owns the shared instance must share it"))
+		})
+	})
+})
@@ -0,0 +1,468 @@
+package controllers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// the operation types of the SLO report
+const (
+	sloProvision = "provision"
+	sloUpdate    = "update"
+	sloBind      = "bind"
+	sloRotate    = "rotate"
+	sloDelete    = "delete"
+
+	defaultSLOObjective  = "99.9"
+	defaultSLOWindow     = 24 * time.Hour
+	maxOperationOutcomes = 100000
+
+	// operationOutcomesConfigMapName is the config map in the release namespace the outcomes are kept in, so that the
+	// reports do not start over after a restart or a change of the leader
+	operationOutcomesConfigMapName = "sap-btp-operator-operation-outcomes"
+	operationOutcomesKey           = "outcomes.json.gz"
+	// the most recent outcomes are kept in the config map, with their messages shortened, up to the size of a config map
+	maxPersistedOutcomes      = 20000
+	maxPersistedMessageLength = 256
+	maxPersistedOutcomesSize  = 900 * 1024
+)
+
+var sloOperations = []string{sloProvision, sloUpdate, sloBind, sloRotate, sloDelete}
+
+// operationOutcomes records the outcomes of the operations of the controllers for the SLO report
+var operationOutcomes = &operationOutcomeTracker{retention: defaultSLOWindow}
+
+type operationOutcome struct {
+	operation string
+	succeeded bool
+	// timeToReady is zero if it was not measured
+	timeToReady time.Duration
+	at          time.Time
+	// the resource of the operation and the error it failed with, reported in the NamespaceBTPStatus of its namespace
+	namespace string
+	kind      api.ControllerName
+	name      string
+	message   string
+}
+
+// outcomeOf returns the outcome of an operation of the resource
+func outcomeOf(operation string, object api.SAPBTPResource, succeeded bool, timeToReady time.Duration, message string) operationOutcome {
+	return operationOutcome{operation: operation, succeeded: succeeded, timeToReady: timeToReady,
+		namespace: object.GetNamespace(), kind: object.GetControllerName(), name: object.GetName(), message: message}
+}
+
+// operationOutcomeTracker keeps the outcomes of the operations of the last retention period in memory, the leader
+// keeps them in the operation outcomes config map and restores them from it after a restart or a change of the leader
+type operationOutcomeTracker struct {
+	mu        sync.Mutex
+	retention time.Duration
+	outcomes  []operationOutcome
+	now       func() time.Time
+	// restored is set once the outcomes of the config map were restored, they are not saved before
+	restored bool
+}
+
+func (t *operationOutcomeTracker) currentTime() time.Time {
+	if t.now == nil {
+		return time.Now()
+	}
+	return t.now()
+}
+
+func (t *operationOutcomeTracker) retentionPeriod() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.retention
+}
+
+// extendRetention keeps the outcomes for at least the retention, the reports reading the outcomes may have different windows
+func (t *operationOutcomeTracker) extendRetention(retention time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if retention > t.retention {
+		t.retention = retention
+	}
+}
+
+func (t *operationOutcomeTracker) record(outcome operationOutcome) {
+	result := "succeeded"
+	if !outcome.succeeded {
+		result = "failed"
+	}
+	operationsTotal.WithLabelValues(outcome.operation, result).Inc()
+	if outcome.succeeded && outcome.timeToReady > 0 {
+		operationTimeToReadySeconds.WithLabelValues(outcome.operation).Observe(outcome.timeToReady.Seconds())
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.currentTime()
+	outcome.at = now
+	t.outcomes = append(t.outcomes, outcome)
+	t.pruneLocked(now)
+}
+
+// pruneLocked drops the outcomes older than the retention and the oldest outcomes beyond maxOperationOutcomes
+func (t *operationOutcomeTracker) pruneLocked(now time.Time) {
+	first := sort.Search(len(t.outcomes), func(i int) bool {
+		return !t.outcomes[i].at.Before(now.Add(-t.retention))
+	})
+	if overflow := len(t.outcomes) - first - maxOperationOutcomes; overflow > 0 {
+		first += overflow
+	}
+	if first > 0 {
+		t.outcomes = append([]operationOutcome(nil), t.outcomes[first:]...)
+	}
+}
+
+// restore adds the outcomes recorded before the restart, the outcomes recorded since are kept
+func (t *operationOutcomeTracker) restore(outcomes []operationOutcome) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.outcomes) > 0 {
+		first := t.outcomes[0].at
+		outcomes = outcomes[:sort.Search(len(outcomes), func(i int) bool { return !outcomes[i].at.Before(first) })]
+	}
+	t.outcomes = append(append([]operationOutcome(nil), outcomes...), t.outcomes...)
+	t.restored = true
+	t.pruneLocked(t.currentTime())
+}
+
+func (t *operationOutcomeTracker) isRestored() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.restored
+}
+
+// since returns the outcomes recorded within the window
+func (t *operationOutcomeTracker) since(window time.Duration) []operationOutcome {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.currentTime()
+	t.pruneLocked(now)
+	var result []operationOutcome
+	for _, outcome := range t.outcomes {
+		if !outcome.at.Before(now.Add(-window)) {
+			result = append(result, outcome)
+		}
+	}
+	return result
+}
+
+// persistedOutcome is an outcome in the operation outcomes config map
+type persistedOutcome struct {
+	Operation   string             `json:"operation"`
+	Succeeded   bool               `json:"succeeded,omitempty"`
+	TimeToReady time.Duration      `json:"timeToReady,omitempty"`
+	At          time.Time          `json:"at"`
+	Namespace   string             `json:"namespace,omitempty"`
+	Kind        api.ControllerName `json:"kind,omitempty"`
+	Name        string             `json:"name,omitempty"`
+	Message     string             `json:"message,omitempty"`
+}
+
+// encodeOutcomes compresses the most recent outcomes that fit into a config map
+func encodeOutcomes(outcomes []operationOutcome) ([]byte, error) {
+	if len(outcomes) > maxPersistedOutcomes {
+		outcomes = outcomes[len(outcomes)-maxPersistedOutcomes:]
+	}
+	for {
+		persisted := make([]persistedOutcome, 0, len(outcomes))
+		for _, outcome := range outcomes {
+			message := outcome.message
+			if len(message) > maxPersistedMessageLength {
+				message = message[:maxPersistedMessageLength]
+			}
+			persisted = append(persisted, persistedOutcome{Operation: outcome.operation, Succeeded: outcome.succeeded, TimeToReady: outcome.timeToReady,
+				At: outcome.at, Namespace: outcome.namespace, Kind: outcome.kind, Name: outcome.name, Message: message})
+		}
+		var data bytes.Buffer
+		writer := gzip.NewWriter(&data)
+		if err := json.NewEncoder(writer).Encode(persisted); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		if data.Len() <= maxPersistedOutcomesSize || len(outcomes) <= 1 {
+			return data.Bytes(), nil
+		}
+		outcomes = outcomes[len(outcomes)/2:]
+	}
+}
+
+func decodeOutcomes(data []byte) ([]operationOutcome, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	var persisted []persistedOutcome
+	if err := json.NewDecoder(reader).Decode(&persisted); err != nil {
+		return nil, err
+	}
+	outcomes := make([]operationOutcome, 0, len(persisted))
+	for _, outcome := range persisted {
+		outcomes = append(outcomes, operationOutcome{operation: outcome.Operation, succeeded: outcome.Succeeded, timeToReady: outcome.TimeToReady,
+			at: outcome.At, namespace: outcome.Namespace, kind: outcome.Kind, name: outcome.Name, message: outcome.Message})
+	}
+	sort.SliceStable(outcomes, func(i, j int) bool { return outcomes[i].at.Before(outcomes[j].at) })
+	return outcomes, nil
+}
+
+// restoreOperationOutcomes restores the outcomes of the operation outcomes config map once
+func (r *BaseReconciler) restoreOperationOutcomes(ctx context.Context) error {
+	if operationOutcomes.isRestored() {
+		return nil
+	}
+	configMap := &corev1.ConfigMap{}
+	err := r.apiReader().Get(ctx, types.NamespacedName{Name: operationOutcomesConfigMapName, Namespace: r.Config.ReleaseNamespace}, configMap)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	var outcomes []operationOutcome
+	if data := configMap.BinaryData[operationOutcomesKey]; len(data) > 0 {
+		if outcomes, err = decodeOutcomes(data); err != nil {
+			// the outcomes are dropped rather than blocking the reports
+			GetLogger(ctx).Error(err, "failed to restore the operation outcomes")
+			outcomes = nil
+		}
+	}
+	operationOutcomes.restore(outcomes)
+	return nil
+}
+
+// saveOperationOutcomes keeps the outcomes in the operation outcomes config map
+func (r *BaseReconciler) saveOperationOutcomes(ctx context.Context) error {
+	if !operationOutcomes.isRestored() {
+		return nil
+	}
+	data, err := encodeOutcomes(operationOutcomes.since(operationOutcomes.retentionPeriod()))
+	if err != nil {
+		return err
+	}
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: operationOutcomesConfigMapName, Namespace: r.Config.ReleaseNamespace}}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+		configMap.BinaryData = map[string][]byte{operationOutcomesKey: data}
+		return nil
+	})
+	return err
+}
+
+// sloOperation returns the operation type an operation of the resource is reported as, empty if it is not reported.
+// The new binding of a credentials rotation is reported as part of the rotation.
+func sloOperation(operationType smClientTypes.OperationCategory, object api.SAPBTPResource) string {
+	switch operationType {
+	case smClientTypes.DELETE:
+		return sloDelete
+	case smClientTypes.UPDATE:
+		return sloUpdate
+	case smClientTypes.CREATE:
+		if object.GetControllerName() == api.ServiceInstanceController {
+			return sloProvision
+		}
+		if meta.IsStatusConditionTrue(object.GetConditions(), api.ConditionCredRotationInProgress) {
+			return ""
+		}
+		return sloBind
+	}
+	return ""
+}
+
+// operationTimeToReady returns the time since the operation of the resource was requested, zero if it is not known
+func operationTimeToReady(operationType smClientTypes.OperationCategory, object api.SAPBTPResource, now time.Time) time.Duration {
+	switch operationType {
+	case smClientTypes.CREATE:
+		return now.Sub(object.GetCreationTimestamp().Time)
+	case smClientTypes.DELETE:
+		if deletionTimestamp := object.GetDeletionTimestamp(); deletionTimestamp != nil {
+			return now.Sub(deletionTimestamp.Time)
+		}
+	case smClientTypes.UPDATE:
+		if inProgress := meta.FindStatusCondition(object.GetConditions(), api.ConditionSucceeded); inProgress != nil && inProgress.Status == metav1.ConditionFalse {
+			return now.Sub(inProgress.LastTransitionTime.Time)
+		}
+	}
+	return 0
+}
+
+// recordOperationSucceeded records the success of the operation of the resource unless it was recorded before, it is
+// called before the conditions of the resource are set
+func recordOperationSucceeded(operationType smClientTypes.OperationCategory, object api.SAPBTPResource) {
+	recordSucceeded(operationType, object, operationTimeToReady(operationType, object, time.Now()))
+}
+
+// recordOperationRecovered records the success of the operation of a resource recovered from SM without a time to ready
+func recordOperationRecovered(operationType smClientTypes.OperationCategory, object api.SAPBTPResource) {
+	recordSucceeded(operationType, object, 0)
+}
+
+func recordSucceeded(operationType smClientTypes.OperationCategory, object api.SAPBTPResource, timeToReady time.Duration) {
+	operation := sloOperation(operationType, object)
+	if len(operation) == 0 {
+		return
+	}
+	succeeded := meta.FindStatusCondition(object.GetConditions(), api.ConditionSucceeded)
+	if succeeded != nil && succeeded.Status == metav1.ConditionTrue && succeeded.Reason == getConditionReason(operationType, smClientTypes.SUCCEEDED) &&
+		succeeded.ObservedGeneration == object.GetObservedGeneration() {
+		return
+	}
+	operationOutcomes.record(outcomeOf(operation, object, true, timeToReady, ""))
+}
+
+// recordOperationFailed records the failure of the operation of the resource unless it was recorded before with the
+// same message, it is called before the conditions of the resource are set
+func recordOperationFailed(operationType smClientTypes.OperationCategory, message string, object api.SAPBTPResource) {
+	operation := sloOperation(operationType, object)
+	if len(operation) == 0 {
+		return
+	}
+	failed := meta.FindStatusCondition(object.GetConditions(), api.ConditionFailed)
+	if failed != nil && failed.Status == metav1.ConditionTrue && failed.Message == message && failed.ObservedGeneration == object.GetObservedGeneration() {
+		return
+	}
+	operationOutcomes.record(outcomeOf(operation, object, false, 0, message))
+}
+
+// recordRotation records the outcome of a credentials rotation, timed from the start of the rotation
+func recordRotation(binding *servicesv1.ServiceBinding, succeeded bool) {
+	var timeToReady time.Duration
+	if rotation := meta.FindStatusCondition(binding.Status.Conditions, api.ConditionCredRotationInProgress); rotation != nil && succeeded {
+		timeToReady = time.Since(rotation.LastTransitionTime.Time)
+	}
+	var message string
+	if failed := meta.FindStatusCondition(binding.Status.Conditions, api.ConditionFailed); failed != nil && !succeeded {
+		message = failed.Message
+	}
+	operationOutcomes.record(outcomeOf(sloRotate, binding, succeeded, timeToReady, message))
+}
+
+// +kubebuilder:rbac:groups=services.cloud.sap.com,resources=operatorsloreports,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=services.cloud.sap.com,resources=operatorsloreports/status,verbs=get;update;patch
+
+// publishSLOReport updates the status of the OperatorSLOReport named sap-btp-operator in the release namespace with the
+// success rates and times to ready of the operations of the last SLOWindow, and creates the report if it is missing
+func (r *PeriodicReporter) publishSLOReport(ctx context.Context, snapshot *reportSnapshot) error {
+	report := &servicesv1.OperatorSLOReport{}
+	key := types.NamespacedName{Name: servicesv1.OperatorSLOReportName, Namespace: r.Config.ReleaseNamespace}
+	if err := r.Client.Get(ctx, key, report); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		report = &servicesv1.OperatorSLOReport{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+		if err := r.Client.Create(ctx, report); err != nil {
+			return err
+		}
+	}
+
+	objective := report.Spec.Objective
+	if len(objective) == 0 {
+		objective = defaultSLOObjective
+	}
+	target, err := strconv.ParseFloat(objective, 64)
+	if err != nil || target < 0 || target > 100 {
+		GetLogger(ctx).Info("invalid objective in the SLO report, using the default", "objective", objective)
+		objective = defaultSLOObjective
+		target, _ = strconv.ParseFloat(defaultSLOObjective, 64)
+	}
+
+	report.Status = servicesv1.OperatorSLOReportStatus{
+		Window:      metav1.Duration{Duration: r.SLOWindow},
+		Objective:   objective,
+		GeneratedAt: metav1.Now(),
+		Operations:  buildOperationSLOs(snapshot.outcomesWithin(r.SLOWindow), target),
+	}
+	for _, operation := range report.Status.Operations {
+		successRate, _ := strconv.ParseFloat(operation.SuccessRate, 64)
+		errorBudget, _ := strconv.ParseFloat(operation.ErrorBudgetRemaining, 64)
+		sloSuccessRatio.WithLabelValues(operation.Operation).Set(successRate / 100)
+		sloErrorBudgetRemaining.WithLabelValues(operation.Operation).Set(errorBudget / 100)
+	}
+	return r.Client.Status().Update(ctx, report)
+}
+
+// buildOperationSLOs summarizes the outcomes by operation type against the objective in percent
+func buildOperationSLOs(outcomes []operationOutcome, objective float64) []servicesv1.OperationSLO {
+	byOperation := make(map[string][]operationOutcome)
+	for _, outcome := range outcomes {
+		byOperation[outcome.operation] = append(byOperation[outcome.operation], outcome)
+	}
+
+	result := make([]servicesv1.OperationSLO, 0, len(sloOperations))
+	for _, operation := range sloOperations {
+		slo := servicesv1.OperationSLO{Operation: operation}
+		var timesToReady []time.Duration
+		for _, outcome := range byOperation[operation] {
+			slo.Total++
+			if !outcome.succeeded {
+				slo.Failed++
+				continue
+			}
+			slo.Succeeded++
+			if outcome.timeToReady > 0 {
+				timesToReady = append(timesToReady, outcome.timeToReady)
+			}
+		}
+
+		successRate := 100.0
+		if slo.Total > 0 {
+			successRate = 100 * float64(slo.Succeeded) / float64(slo.Total)
+		}
+		slo.SuccessRate = formatPercent(successRate)
+		slo.ErrorBudgetRemaining = formatPercent(errorBudgetRemaining(slo.Total, slo.Failed, objective))
+		if len(timesToReady) > 0 {
+			sort.Slice(timesToReady, func(i, j int) bool { return timesToReady[i] < timesToReady[j] })
+			slo.TimeToReady = &servicesv1.TimeToReady{
+				P50: metav1.Duration{Duration: percentile(timesToReady, 50)},
+				P90: metav1.Duration{Duration: percentile(timesToReady, 90)},
+				P99: metav1.Duration{Duration: percentile(timesToReady, 99)},
+			}
+		}
+		result = append(result, slo)
+	}
+	return result
+}
+
+// errorBudgetRemaining returns the share of the failures allowed by the objective that are left in percent, an objective
+// of 100 has no budget and is missed by any failure
+func errorBudgetRemaining(total, failed int, objective float64) float64 {
+	allowed := float64(total) * (100 - objective) / 100
+	if allowed == 0 {
+		if failed == 0 {
+			return 100
+		}
+		return -100
+	}
+	return 100 * (allowed - float64(failed)) / allowed
+}
+
+// percentile returns the nearest-rank percentile of the sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1].Round(time.Millisecond)
+}
+
+func formatPercent(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("SLO report", func() {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	Context("outcomes", func() {
+		var tracker *operationOutcomeTracker
+
+		BeforeEach(func() {
+			tracker = &operationOutcomeTracker{retention: time.Hour, now: func() time.Time { return now }}
+		})
+
+		It("should keep the outcomes of the retention period only", func() {
+			tracker.now = func() time.Time { return now.Add(-2 * time.Hour) }
+			tracker.record(operationOutcome{operation: sloBind, succeeded: true, timeToReady: time.Second})
+			tracker.now = func() time.Time { return now.Add(-30 * time.Minute) }
+			tracker.record(operationOutcome{operation: sloBind})
+			tracker.now = func() time.Time { return now }
+			tracker.record(operationOutcome{operation: sloProvision, succeeded: true, timeToReady: time.Minute})
+
+			Expect(tracker.since(time.Hour)).To(HaveLen(2))
+			Expect(tracker.since(10 * time.Minute)).To(ConsistOf(operationOutcome{operation: sloProvision, succeeded: true, timeToReady: time.Minute, at: now}))
+		})
+
+		It("should summarize the outcomes by operation type against the objective", func() {
+			var outcomes []operationOutcome
+			for i := 1; i <= 99; i++ {
+				outcomes = append(outcomes, operationOutcome{operation: sloProvision, succeeded: true, timeToReady: time.Duration(i) * time.Second})
+			}
+			outcomes = append(outcomes, operationOutcome{operation: sloProvision, succeeded: false})
+
+			slos := buildOperationSLOs(outcomes, 99.5)
+			Expect(slos).To(HaveLen(len(sloOperations)))
+			provision := slos[0]
+			Expect(provision.Operation).To(Equal(sloProvision))
+			Expect(provision.Total).To(Equal(100))
+			Expect(provision.Failed).To(Equal(1))
+			Expect(provision.SuccessRate).To(Equal("99.00"))
+			Expect(provision.ErrorBudgetRemaining).To(Equal("-100.00"))
+			Expect(provision.TimeToReady.P50.Duration).To(Equal(50 * time.Second))
+			Expect(provision.TimeToReady.P90.Duration).To(Equal(90 * time.Second))
+			Expect(provision.TimeToReady.P99.Duration).To(Equal(99 * time.Second))
+
+			bind := slos[2]
+			Expect(bind.Operation).To(Equal(sloBind))
+			Expect(bind.SuccessRate).To(Equal("100.00"))
+			Expect(bind.ErrorBudgetRemaining).To(Equal("100.00"))
+			Expect(bind.TimeToReady).To(BeNil())
+		})
+
+		It("should restore the outcomes recorded before the restart", func() {
+			tracker.record(operationOutcome{operation: sloBind, succeeded: true})
+			tracker.restore([]operationOutcome{
+				{operation: sloProvision, succeeded: true, at: now.Add(-2 * time.Hour)},
+				{operation: sloProvision, at: now.Add(-10 * time.Minute)},
+				{operation: sloDelete, at: now},
+			})
+			Expect(tracker.isRestored()).To(BeTrue())
+			Expect(tracker.since(time.Hour)).To(Equal([]operationOutcome{{operation: sloProvision, at: now.Add(-10 * time.Minute)}, {operation: sloBind, succeeded: true, at: now}}))
+		})
+
+		It("should encode the outcomes for the config map", func() {
+			outcomes := []operationOutcome{
+				{operation: sloProvision, succeeded: true, timeToReady: time.Minute, at: now, namespace: "app1", kind: api.ServiceInstanceController, name: "my-instance"},
+				{operation: sloBind, at: now, namespace: "app1", kind: api.ServiceBindingController, name: "my-binding", message: strings.Repeat("x", 1000)},
+			}
+			data, err := encodeOutcomes(outcomes)
+			Expect(err).ToNot(HaveOccurred())
+			decoded, err := decodeOutcomes(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded).To(HaveLen(2))
+			Expect(decoded[0]).To(Equal(outcomes[0]))
+			Expect(decoded[1].message).To(HaveLen(maxPersistedMessageLength))
+		})
+
+		It("should compute the remaining error budget", func() {
+			Expect(errorBudgetRemaining(1000, 0, 99.9)).To(BeNumerically("~", 100))
+			Expect(errorBudgetRemaining(1000, 1, 99.8)).To(BeNumerically("~", 50))
+			Expect(errorBudgetRemaining(10, 1, 100)).To(BeNumerically("~", -100))
+		})
+	})
+
+	Context("operations", func() {
+		It("should report the new binding of a rotation as part of the rotation", func() {
+			binding := &servicesv1.ServiceBinding{}
+			Expect(sloOperation(smClientTypes.CREATE, binding)).To(Equal(sloBind))
+			binding.Status.Conditions = []metav1.Condition{{Type: api.ConditionCredRotationInProgress, Status: metav1.ConditionTrue}}
+			Expect(sloOperation(smClientTypes.CREATE, binding)).To(BeEmpty())
+			Expect(sloOperation(smClientTypes.CREATE, &servicesv1.ServiceInstance{})).To(Equal(sloProvision))
+			Expect(sloOperation(smClientTypes.DELETE, binding)).To(Equal(sloDelete))
+		})
+
+		It("should time creations from the creation of the resource", func() {
+			instance := &servicesv1.ServiceInstance{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(now.Add(-time.Minute))}}
+			Expect(operationTimeToReady(smClientTypes.CREATE, instance, now)).To(Equal(time.Minute))
+			Expect(operationTimeToReady(smClientTypes.DELETE, instance, now)).To(BeZero())
+		})
+
+		It("should not time recovered resources", func() {
+			saved := operationOutcomes
+			defer func() { operationOutcomes = saved }()
+			operationOutcomes = &operationOutcomeTracker{retention: time.Hour}
+			instance := &servicesv1.ServiceInstance{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(time.Now().Add(-30 * 24 * time.Hour))}}
+			setRecoveredConditions(smClientTypes.CREATE, instance)
+			outcomes := operationOutcomes.since(time.Hour)
+			Expect(outcomes).To(HaveLen(1))
+			Expect(outcomes[0].succeeded).To(BeTrue())
+			Expect(outcomes[0].timeToReady).To(BeZero())
+		})
+	})
+
+	Context("reporter", func() {
+		var (
+			ctx      context.Context
+			reporter *PeriodicReporter
+			saved    *operationOutcomeTracker
+		)
+
+		BeforeEach(func() {
+			saved = operationOutcomes
+			operationOutcomes = &operationOutcomeTracker{retention: time.Hour}
+			ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+			testScheme := runtime.NewScheme()
+			Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+			Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+			reporter = &PeriodicReporter{
+				BaseReconciler: &BaseReconciler{
+					Client: fake.NewClientBuilder().WithScheme(testScheme).WithStatusSubresource(&servicesv1.OperatorSLOReport{}).Build(),
+					Scheme: testScheme,
+					Log:    ctrl.Log,
+					Config: config.Config{ReleaseNamespace: "sap-btp-operator"},
+				},
+				SLOReportInterval: time.Minute,
+				SLOWindow:         time.Hour,
+			}
+		})
+
+		AfterEach(func() {
+			operationOutcomes = saved
+		})
+
+		It("should create the report and publish the outcomes", func() {
+			Expect(reporter.Report(ctx)).To(Succeed())
+
+			report := &servicesv1.OperatorSLOReport{}
+			Expect(reporter.Client.Get(ctx, types.NamespacedName{Name: servicesv1.OperatorSLOReportName, Namespace: "sap-btp-operator"}, report)).To(Succeed())
+			Expect(report.Status.Objective).To(Equal(defaultSLOObjective))
+			Expect(report.Status.Window.Duration).To(Equal(time.Hour))
+			Expect(report.Status.Operations).To(HaveLen(len(sloOperations)))
+		})
+
+		It("should keep the outcomes in a config map over restarts", func() {
+			data, err := encodeOutcomes([]operationOutcome{{operation: sloProvision, succeeded: true, at: time.Now().Add(-time.Minute)}})
+			Expect(err).ToNot(HaveOccurred())
+			configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: operationOutcomesConfigMapName, Namespace: "sap-btp-operator"},
+				BinaryData: map[string][]byte{operationOutcomesKey: data}}
+			Expect(reporter.Client.Create(ctx, configMap)).To(Succeed())
+			operationOutcomes.record(operationOutcome{operation: sloProvision})
+
+			Expect(reporter.Report(ctx)).To(Succeed())
+			report := &servicesv1.OperatorSLOReport{}
+			Expect(reporter.Client.Get(ctx, types.NamespacedName{Name: servicesv1.OperatorSLOReportName, Namespace: "sap-btp-operator"}, report)).To(Succeed())
+			Expect(report.Status.Operations[0].Total).To(Equal(2))
+			Expect(report.Status.Operations[0].Failed).To(Equal(1))
+
+			Expect(reporter.Client.Get(ctx, client.ObjectKeyFromObject(configMap), configMap)).To(Succeed())
+			saved, err := decodeOutcomes(configMap.BinaryData[operationOutcomesKey])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(saved).To(HaveLen(2))
+		})
+	})
+})
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+)
+
+// smCapabilities returns the capabilities of the Service Manager of the client and exposes them as metrics
+func smCapabilities(smClient sm.Client) *sm.Capabilities {
+	capabilities := smClient.Capabilities()
+	if capabilities == nil {
+		return nil
+	}
+	for feature, supported := range capabilities.Features {
+		value := 0.0
+		if supported {
+			value = 1
+		}
+		smFeatureSupported.WithLabelValues(capabilities.URL, capabilities.APIVersion, string(feature)).Set(value)
+	}
+	return capabilities
+}
+
+// errRecoveryWithoutLastOperation is returned when an existing resource is found in a Service Manager which rejects
+// attach_last_operations, the state of the resource is unknown and it is not recovered
+var errRecoveryWithoutLastOperation = errors.New("the resource exists in Service Manager but cannot be recovered because the Service Manager does not support attach_last_operations")
+
+// recoveryParams returns the general parameters of the list requests used to recover resources and whether the last
+// operations are attached. They are attached unless the Service Manager rejected attach_last_operations, a failed
+// detection does not prevent the recovery with the last operation.
+func recoveryParams(ctx context.Context, smClient sm.Client) ([]string, bool) {
+	capabilities := smCapabilities(smClient)
+	if capabilities.Supports(sm.FeatureAttachLastOperations) || !capabilities.Detected(sm.FeatureAttachLastOperations) {
+		return []string{"attach_last_operations=true"}, true
+	}
+	GetLogger(ctx).Info("Service Manager does not support attach_last_operations, existing resources are not recovered", "apiVersion", capabilities.APIVersion)
+	return nil, false
+}
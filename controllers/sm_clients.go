@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	"github.com/SAP/sap-btp-service-operator/internal/auth"
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+const (
+	// tokenURLCheckTimeout limits the connection to a token endpoint in the token URL check
+	tokenURLCheckTimeout = 5 * time.Second
+	// tokenURLCheckInterval is how long the result of the token URL check is reused
+	tokenURLCheckInterval = time.Minute
+)
+
+// smClients holds the authenticated clients of both controllers, the credentials are shared by instances and bindings
+var smClients = newSMClientCache()
+
+// smClientCache reuses the authenticated client of an access credentials secret, so that the token of the tenant is
+// fetched once and refreshed when it expires rather than on every reconcile. The client is replaced when the
+// credentials change, e.g. when the token URL is rotated in the secret or the files of the credentials directory, dropped
+// when the secret is deleted, and dropped when its token is rejected or cannot be fetched, so that the next reconcile
+// logs in again without a restart of the operator.
+type smClientCache struct {
+	mutex sync.Mutex
+	// clients holds the clients by credentials
+	clients map[string]*cachedSMClient
+
+	// checkedAt and checkErr are the time and the result of the last token URL check
+	checkedAt time.Time
+	checkErr  error
+}
+
+type cachedSMClient struct {
+	// digest is the digest of the configuration the client was created with
+	digest     string
+	tokenURL   string
+	httpClient auth.HTTPClient
+}
+
+func newSMClientCache() *smClientCache {
+	return &smClientCache{clients: make(map[string]*cachedSMClient)}
+}
+
+// get returns the authenticated client of the credentials, a new one if the configuration changed. onTokenRequest is
+// called for each request to the token endpoint.
+func (c *smClientCache) get(log logr.Logger, credentials string, cfg *sm.ClientConfig, onTokenRequest func()) (auth.HTTPClient, error) {
+	tokenURL := cfg.TokenURL + cfg.TokenURLSuffix
+	digest := configDigest(cfg)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	cached, found := c.clients[credentials]
+	if found && cached.digest == digest {
+		return cached.httpClient, nil
+	}
+	if found && cached.tokenURL != tokenURL {
+		log.Info("the token URL of the access credentials changed, logging in again", "credentials", credentials, "tokenURL", tokenURL)
+	} else if found {
+		log.Info("the access credentials changed, logging in again", "credentials", credentials)
+	}
+
+	authClient, err := sm.NewAuthClient(cfg, onTokenRequest)
+	if err != nil {
+		return nil, err
+	}
+	cached = &cachedSMClient{digest: digest, tokenURL: tokenURL}
+	cached.httpClient = &recoveringHTTPClient{HTTPClient: authClient, onFailure: func() { c.invalidate(credentials, cached) }}
+	c.clients[credentials] = cached
+	return cached.httpClient, nil
+}
+
+// configDigest returns the digest of the settings of the configuration the client logs in with. The credentials read
+// from files have no resource version, the digest detects their rotation as well.
+func configDigest(cfg *sm.ClientConfig) string {
+	hash := sha256.New()
+	for _, setting := range []string{cfg.URL, cfg.TokenURL + cfg.TokenURLSuffix, cfg.ClientID, cfg.ClientSecret, cfg.TLSCertKey, cfg.TLSPrivateKey} {
+		hash.Write([]byte(setting))
+		hash.Write([]byte{0})
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+// invalidate drops the client of the credentials if it was not replaced already
+func (c *smClientCache) invalidate(credentials string, client *cachedSMClient) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.clients[credentials] == client {
+		delete(c.clients, credentials)
+	}
+}
+
+// evict drops the client of the credentials
+func (c *smClientCache) evict(credentials string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.clients, credentials)
+}
+
+// tokenURLs returns the token URLs of the cached clients
+func (c *smClientCache) tokenURLs() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	known := make(map[string]bool)
+	var urls []string
+	for _, client := range c.clients {
+		if !known[client.tokenURL] {
+			known[client.tokenURL] = true
+			urls = append(urls, client.tokenURL)
+		}
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// checkTokenURLs verifies that the token endpoints of the cached clients accept connections, the result is reused for
+// tokenURLCheckInterval so that frequent probes do not open connections to the token endpoints each time
+func (c *smClientCache) checkTokenURLs(now time.Time, dial func(address string) error) error {
+	c.mutex.Lock()
+	if now.Sub(c.checkedAt) < tokenURLCheckInterval {
+		defer c.mutex.Unlock()
+		return c.checkErr
+	}
+	c.mutex.Unlock()
+
+	var unreachable []string
+	for _, tokenURL := range c.tokenURLs() {
+		if err := dialTokenURL(tokenURL, dial); err != nil {
+			unreachable = append(unreachable, err.Error())
+		}
+	}
+	var checkErr error
+	if len(unreachable) > 0 {
+		checkErr = fmt.Errorf("token URLs are not reachable: %s", strings.Join(unreachable, "; "))
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.checkedAt, c.checkErr = now, checkErr
+	return checkErr
+}
+
+func dialTokenURL(tokenURL string, dial func(address string) error) error {
+	parsed, err := url.Parse(tokenURL)
+	if err != nil || len(parsed.Hostname()) == 0 {
+		return fmt.Errorf("invalid token URL %s", tokenURL)
+	}
+	port := parsed.Port()
+	if len(port) == 0 {
+		port = "443"
+		if parsed.Scheme == "http" {
+			port = "80"
+		}
+	}
+	if err := dial(net.JoinHostPort(parsed.Hostname(), port)); err != nil {
+		return fmt.Errorf("%s: %w", tokenURL, err)
+	}
+	return nil
+}
+
+// TokenURLCheck is a readiness check which fails while the token endpoint of an access credentials secret the operator
+// logged in with does not accept connections, e.g. after its URL was rotated without the secret being updated
+func TokenURLCheck(_ *http.Request) error {
+	return smClients.checkTokenURLs(time.Now(), func(address string) error {
+		conn, err := net.DialTimeout("tcp", address, tokenURLCheckTimeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+}
+
+// evictSMClients drops the client of a deleted access credentials secret
+var evictSMClients = handler.Funcs{
+	DeleteFunc: func(_ context.Context, e event.DeleteEvent, _ workqueue.RateLimitingInterface) {
+		smClients.evict(e.Object.GetNamespace() + "/" + e.Object.GetName())
+	},
+}
+
+// recoveringHTTPClient calls onFailure when a request fails or is rejected as unauthorized, e.g. because the token
+// could not be fetched from a rotated token endpoint or the credentials were revoked
+type recoveringHTTPClient struct {
+	auth.HTTPClient
+	onFailure func()
+}
+
+func (c *recoveringHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil || resp.StatusCode == http.StatusUnauthorized {
+		c.onFailure()
+	}
+	return resp, err
+}
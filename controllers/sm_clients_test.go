@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	"github.com/SAP/sap-btp-service-operator/internal/auth/authfakes"
+	"github.com/SAP/sap-btp-service-operator/internal/secrets"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("SM clients", func() {
+	const credentials = "app1/sap-btp-service-operator"
+	var cache *smClientCache
+	var cfg *sm.ClientConfig
+
+	BeforeEach(func() {
+		cache = newSMClientCache()
+		cfg = &sm.ClientConfig{ClientID: "client", ClientSecret: "secret", URL: "https://sm.example.com", TokenURL: "https://auth.example.com", TokenURLSuffix: "/oauth/token"}
+	})
+
+	It("should reuse the client until the credentials change", func() {
+		first, err := cache.get(ctrl.Log, credentials, cfg, nil)
+		Expect(err).ToNot(HaveOccurred())
+		second, err := cache.get(ctrl.Log, credentials, cfg, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second).To(BeIdenticalTo(first))
+
+		rotated := *cfg
+		rotated.TokenURL = "https://auth2.example.com"
+		third, err := cache.get(ctrl.Log, credentials, &rotated, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(third).ToNot(BeIdenticalTo(first))
+		Expect(cache.tokenURLs()).To(Equal([]string{"https://auth2.example.com/oauth/token"}))
+	})
+
+	It("should drop the client of a deleted secret", func() {
+		first, err := cache.get(ctrl.Log, credentials, cfg, nil)
+		Expect(err).ToNot(HaveOccurred())
+		cache.evict(credentials)
+		Expect(cache.tokenURLs()).To(BeEmpty())
+		second, err := cache.get(ctrl.Log, credentials, cfg, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second).ToNot(BeIdenticalTo(first))
+	})
+
+	It("should log in again once the token is rejected", func() {
+		first, err := cache.get(ctrl.Log, credentials, cfg, nil)
+		Expect(err).ToNot(HaveOccurred())
+		fakeClient := &authfakes.FakeHTTPClient{}
+		fakeClient.DoReturns(&http.Response{StatusCode: http.StatusUnauthorized}, nil)
+		first.(*recoveringHTTPClient).HTTPClient = fakeClient
+
+		_, err = first.Do(&http.Request{})
+		Expect(err).ToNot(HaveOccurred())
+		second, err := cache.get(ctrl.Log, credentials, cfg, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second).ToNot(BeIdenticalTo(first))
+	})
+
+	It("should check the token URLs of the clients", func() {
+		_, err := cache.get(ctrl.Log, credentials, cfg, nil)
+		Expect(err).ToNot(HaveOccurred())
+		var dialed []string
+		unreachable := func(address string) error {
+			dialed = append(dialed, address)
+			return fmt.Errorf("no such host")
+		}
+
+		now := time.Now()
+		Expect(cache.checkTokenURLs(now, unreachable)).To(MatchError(ContainSubstring("https://auth.example.com/oauth/token: no such host")))
+		Expect(dialed).To(Equal([]string{"auth.example.com:443"}))
+		Expect(cache.checkTokenURLs(now.Add(time.Second), func(string) error { return nil })).To(HaveOccurred())
+		Expect(cache.checkTokenURLs(now.Add(2*tokenURLCheckInterval), func(string) error { return nil })).To(Succeed())
+	})
+
+	It("should log in again once the credentials files change", func() {
+		saved := smClients
+		defer func() { smClients = saved }()
+		smClients = cache
+		dir, err := os.MkdirTemp("", "credentials")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+		writeCredentials := func(clientSecret string) {
+			for key, value := range map[string]string{"clientid": "client", "clientsecret": clientSecret, "sm_url": "https://sm.example.com", "tokenurl": "https://auth.example.com"} {
+				Expect(os.WriteFile(filepath.Join(dir, key), []byte(value), 0600)).To(Succeed())
+			}
+		}
+		reconciler := &BaseReconciler{
+			Log: ctrl.Log,
+			SecretResolver: &secrets.SecretResolver{
+				ReleaseNamespace: "sap-btp-operator",
+				CredentialsDir:   dir,
+				Client:           fake.NewClientBuilder().Build(),
+				Log:              ctrl.Log,
+			},
+		}
+		ctx := context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		instance := &servicesv1.ServiceInstance{ObjectMeta: metav1.ObjectMeta{Name: "my-instance", Namespace: "app1"}}
+		login := func() interface{} {
+			_, err := reconciler.getSMClient(ctx, instance, "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cache.clients).To(HaveLen(1))
+			for _, cached := range cache.clients {
+				return cached.httpClient
+			}
+			return nil
+		}
+
+		writeCredentials("secret")
+		first := login()
+		Expect(login()).To(BeIdenticalTo(first))
+
+		writeCredentials("rotated")
+		Expect(login()).ToNot(BeIdenticalTo(first))
+	})
+})
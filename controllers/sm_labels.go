@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"fmt"
+
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// smLabels returns the implicit labels of an instance or binding in SM according to the label scheme of the offering,
+// disabled labels are omitted
+func smLabels(labelKeys config.LabelKeys, namespace, name, clusterID string) smClientTypes.Labels {
+	labels := smClientTypes.Labels{}
+	for key, value := range map[string]string{
+		labelKeys.Namespace: namespace,
+		labelKeys.K8SName:   name,
+		labelKeys.ClusterID: clusterID,
+	} {
+		if len(key) > 0 {
+			labels[key] = []string{value}
+		}
+	}
+	return labels
+}
+
+// clusterIDOf returns the cluster ID a resource is labeled and recovered with in SM, the ID of the cluster that
+// created the resource if the resource was taken over from another cluster. Resources created before the cluster ID
+// was changed with the OperatorConfig keep the ID they were created with.
+func (r *BaseReconciler) clusterIDOf(object metav1.Object, clusterIDOverride string) string {
+	if len(clusterIDOverride) > 0 && r.Config.FeatureGates.Enabled(config.ClusterIDOverride) {
+		return clusterIDOverride
+	}
+	if r.Live == nil {
+		return r.Config.ClusterID
+	}
+	return r.Live.ClusterIDAt(object.GetCreationTimestamp().Time)
+}
+
+// isOwnClusterID returns whether the cluster ID is the ID of this cluster, or was its ID before it was changed with the
+// OperatorConfig
+func (r *BaseReconciler) isOwnClusterID(clusterID string) bool {
+	if r.Live == nil {
+		return clusterID == r.Config.ClusterID
+	}
+	return contains(r.Live.ClusterIDs(), clusterID)
+}
+
+// k8sNameLabelQueries returns the label queries of the recovery by the name of the k8s resource, one per key the label
+// had in the current and earlier label schemes. Without the k8s name label the recovery relies on the field queries only.
+func k8sNameLabelQueries(labelKeys config.LabelKeys, name string) [][]string {
+	if len(labelKeys.K8SName) == 0 {
+		return [][]string{nil}
+	}
+	queries := make([][]string, 0, len(labelKeys.K8SNameLookups))
+	for _, key := range labelKeys.K8SNameLookups {
+		queries = append(queries, []string{fmt.Sprintf("%s eq '%s'", key, name)})
+	}
+	return queries
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SM labels", func() {
+	var smLabelsConfig config.SMLabels
+
+	BeforeEach(func() {
+		smLabelsConfig = config.SMLabels{}
+		Expect(smLabelsConfig.Decode(`{"k8sname": "k8s-name", "offerings": {"strict-offering": {"namespace": "", "k8sname": "", "clusterid": ""}, "other-offering": {"clusterid": "cluster"}}}`)).To(Succeed())
+	})
+
+	It("should send the default labels without a label scheme", func() {
+		Expect(smLabels(config.SMLabels{}.For("offering"), "ns", "name", "cluster-id")).To(Equal(smClientTypes.Labels{
+			"_namespace": []string{"ns"},
+			"_k8sname":   []string{"name"},
+			"_clusterid": []string{"cluster-id"},
+		}))
+		Expect(k8sNameLabelQueries(config.SMLabels{}.For("offering"), "name")).To(Equal([][]string{{"_k8sname eq 'name'"}}))
+	})
+
+	It("should rename the labels of all offerings", func() {
+		Expect(smLabels(smLabelsConfig.For("offering"), "ns", "name", "cluster-id")).To(Equal(smClientTypes.Labels{
+			"_namespace": []string{"ns"},
+			"k8s-name":   []string{"name"},
+			"_clusterid": []string{"cluster-id"},
+		}))
+		Expect(k8sNameLabelQueries(smLabelsConfig.For("offering"), "name")).To(Equal([][]string{{"k8s-name eq 'name'"}, {"_k8sname eq 'name'"}}))
+	})
+
+	It("should inherit the labels which are not overridden by the offering", func() {
+		Expect(smLabels(smLabelsConfig.For("other-offering"), "ns", "name", "cluster-id")).To(Equal(smClientTypes.Labels{
+			"_namespace": []string{"ns"},
+			"k8s-name":   []string{"name"},
+			"cluster":    []string{"cluster-id"},
+		}))
+	})
+
+	It("should not send disabled labels and recover by the field queries only", func() {
+		Expect(smLabels(smLabelsConfig.For("strict-offering"), "ns", "name", "cluster-id")).To(BeEmpty())
+		Expect(k8sNameLabelQueries(smLabelsConfig.For("strict-offering"), "name")).To(Equal([][]string{nil}))
+	})
+
+	It("should recover by the keys of earlier label schemes", func() {
+		Expect(smLabelsConfig.Decode(`{"k8sname": "k8s-name", "previousK8snames": ["old-name"]}`)).To(Succeed())
+		Expect(k8sNameLabelQueries(smLabelsConfig.For("offering"), "name")).To(Equal([][]string{{"k8s-name eq 'name'"}, {"_k8sname eq 'name'"}, {"old-name eq 'name'"}}))
+	})
+
+	It("should reject label schemes with duplicate or invalid keys", func() {
+		Expect((&config.SMLabels{}).Decode(`{"k8sname": "_namespace"}`)).To(MatchError(ContainSubstring("the label key '_namespace' is used for more than one label")))
+		Expect((&config.SMLabels{}).Decode(`{"offerings": {"my-offering": {"clusterid": "_k8sname"}}}`)).To(MatchError(ContainSubstring("invalid labels of offering 'my-offering'")))
+		Expect((&config.SMLabels{}).Decode(`{"namespace": " "}`)).To(MatchError(ContainSubstring("invalid label key ' '")))
+	})
+
+	It("should label and recover resources with the overridden cluster ID only when the feature gate is enabled", func() {
+		r := &BaseReconciler{Config: config.Config{ClusterID: "this-cluster"}}
+		Expect(r.clusterIDOf(&servicesv1.ServiceInstance{}, "primary-cluster")).To(Equal("this-cluster"))
+
+		Expect(r.Config.FeatureGates.Decode("ClusterIDOverride=true")).To(Succeed())
+		Expect(r.clusterIDOf(&servicesv1.ServiceInstance{}, "primary-cluster")).To(Equal("primary-cluster"))
+		Expect(r.clusterIDOf(&servicesv1.ServiceInstance{}, "")).To(Equal("this-cluster"))
+	})
+})
@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// OwnershipMismatch the labels of the resource in SM do not identify the k8s resource as its owner
+const OwnershipMismatch = "OwnershipMismatch"
+
+// ownershipMismatches returns the implicit labels of a resource in SM which do not carry the namespace, name or
+// cluster ID of the k8s resource, disabled labels are not verified. The k8s name is accepted under the keys of
+// earlier label schemes, the cluster ID of a resource taken over from another cluster or created with an earlier
+// ID of this cluster is accepted as well.
+func (r *BaseReconciler) ownershipMismatches(labelKeys config.LabelKeys, object metav1.Object, clusterIDOverride string, labels smClientTypes.Labels) []string {
+	var mismatches []string
+	if len(labelKeys.Namespace) > 0 && !contains(labels[labelKeys.Namespace], object.GetNamespace()) {
+		mismatches = append(mismatches, labelKeys.Namespace)
+	}
+	if len(labelKeys.K8SName) > 0 {
+		named := false
+		for _, key := range labelKeys.K8SNameLookups {
+			named = named || contains(labels[key], object.GetName())
+		}
+		if !named {
+			mismatches = append(mismatches, labelKeys.K8SName)
+		}
+	}
+	if len(labelKeys.ClusterID) > 0 {
+		clusterIDs := labels[labelKeys.ClusterID]
+		owned := contains(clusterIDs, r.clusterIDOf(object, clusterIDOverride))
+		for _, clusterID := range clusterIDs {
+			owned = owned || r.isOwnClusterID(clusterID)
+		}
+		if !owned {
+			mismatches = append(mismatches, labelKeys.ClusterID)
+		}
+	}
+	sort.Strings(mismatches)
+	return mismatches
+}
+
+// refuseOnOwnershipMismatch verifies in strict ownership mode that the resource in SM is owned by the k8s resource
+// before it is updated or deleted. Otherwise the operation fails with the OwnershipMismatch condition and is retried
+// with the long poll interval, after the labels in SM or the configuration of the cluster were corrected.
+func (r *BaseReconciler) refuseOnOwnershipMismatch(ctx context.Context, operationType smClientTypes.OperationCategory, object api.SAPBTPResource, labelKeys config.LabelKeys, clusterIDOverride string, labels smClientTypes.Labels) (bool, ctrl.Result, error) {
+	conditions := object.GetConditions()
+	mismatches := r.ownershipMismatches(labelKeys, object, clusterIDOverride, labels)
+	if len(mismatches) == 0 {
+		meta.RemoveStatusCondition(&conditions, api.ConditionOwnershipMismatch)
+		object.SetConditions(conditions)
+		return false, ctrl.Result{}, nil
+	}
+
+	message := ownershipMismatchMessage(object, mismatches)
+	GetLogger(ctx).Info(fmt.Sprintf("refusing to %s the resource in SM: %s", strings.ToLower(string(operationType)), message))
+
+	setFailureConditions(operationType, message, object)
+	setOwnershipMismatchCondition(object, message)
+	return true, ctrl.Result{RequeueAfter: r.longPollInterval()}, r.updateStatus(ctx, object)
+}
+
+func ownershipMismatchMessage(object api.SAPBTPResource, mismatches []string) string {
+	return fmt.Sprintf("the resource in SM is not owned by this %s, labels %s do not match its namespace, name or cluster ID. "+
+		"Correct the labels in SM or the cluster ID of the operator", object.GetControllerName(), strings.Join(mismatches, ", "))
+}
+
+func setOwnershipMismatchCondition(object api.SAPBTPResource, message string) {
+	conditions := object.GetConditions()
+	meta.SetStatusCondition(&conditions, metav1.Condition{
+		Type:               api.ConditionOwnershipMismatch,
+		Status:             metav1.ConditionTrue,
+		Reason:             OwnershipMismatch,
+		Message:            message,
+		ObservedGeneration: object.GetGeneration(),
+	})
+	object.SetConditions(conditions)
+}
+
+// verifyInstanceOwnership verifies the ownership of the instance in SM in strict ownership mode, an instance which is
+// already deleted from SM is left to the operation
+func (r *ServiceInstanceReconciler) verifyInstanceOwnership(ctx context.Context, smClient sm.Client, operationType smClientTypes.OperationCategory, serviceInstance *servicesv1.ServiceInstance) (bool, ctrl.Result, error) {
+	if !r.Config.StrictOwnership {
+		return false, ctrl.Result{}, nil
+	}
+	smInstance, err := smClient.GetInstanceByID(serviceInstance.Status.InstanceID, nil)
+	if err != nil {
+		if isAlreadyDeletedError(err) {
+			return false, ctrl.Result{}, nil
+		}
+		GetLogger(ctx).Error(err, "failed to get instance from SM to verify its ownership")
+		result, err := r.handleError(ctx, operationType, err, serviceInstance)
+		return true, result, err
+	}
+	return r.refuseOnOwnershipMismatch(ctx, operationType, serviceInstance, r.Config.SMLabels.For(serviceInstance.Spec.ServiceOfferingName),
+		serviceInstance.Spec.ClusterIDOverride, smInstance.Labels)
+}
+
+// verifyBindingOwnership verifies the ownership of the binding in SM in strict ownership mode, a binding which is
+// already deleted from SM is left to the operation
+func (r *ServiceBindingReconciler) verifyBindingOwnership(ctx context.Context, smClient sm.Client, operationType smClientTypes.OperationCategory, serviceBinding *servicesv1.ServiceBinding, serviceOfferingName string) (bool, ctrl.Result, error) {
+	if !r.Config.StrictOwnership {
+		return false, ctrl.Result{}, nil
+	}
+	smBinding, err := smClient.GetBindingByID(serviceBinding.Status.BindingID, nil)
+	if err != nil {
+		if isAlreadyDeletedError(err) {
+			return false, ctrl.Result{}, nil
+		}
+		GetLogger(ctx).Error(err, "failed to get binding from SM to verify its ownership")
+		result, err := r.handleError(ctx, operationType, err, serviceBinding)
+		return true, result, err
+	}
+	return r.refuseOnOwnershipMismatch(ctx, operationType, serviceBinding, r.Config.SMLabels.For(serviceOfferingName),
+		serviceBinding.Spec.ClusterIDOverride, smBinding.Labels)
+}
+
+// refuseRotationOnOwnershipMismatch verifies the ownership of the binding in SM in strict ownership mode before the
+// credentials rotation renames it. The rotation of a binding which is not owned is stopped with the OwnershipMismatch
+// condition and the binding keeps its credentials, the next rotation verifies the ownership again.
+func (r *ServiceBindingReconciler) refuseRotationOnOwnershipMismatch(ctx context.Context, smClient sm.Client, binding *servicesv1.ServiceBinding, serviceOfferingName string) (bool, error) {
+	if !r.Config.StrictOwnership {
+		return false, nil
+	}
+	log := GetLogger(ctx)
+	smBinding, err := smClient.GetBindingByID(binding.Status.BindingID, nil)
+	if err != nil {
+		if isAlreadyDeletedError(err) {
+			return false, nil
+		}
+		log.Error(err, "Credentials rotation - failed to get binding from SM to verify its ownership")
+		setCredRotationInProgressConditions(CredPreparing, err.Error(), binding)
+		if errStatus := r.updateStatus(ctx, binding); errStatus != nil {
+			return true, errStatus
+		}
+		return true, err
+	}
+	mismatches := r.ownershipMismatches(r.Config.SMLabels.For(serviceOfferingName), binding, binding.Spec.ClusterIDOverride, smBinding.Labels)
+	if len(mismatches) == 0 {
+		meta.RemoveStatusCondition(&binding.Status.Conditions, api.ConditionOwnershipMismatch)
+		return false, nil
+	}
+
+	message := ownershipMismatchMessage(binding, mismatches)
+	log.Info(fmt.Sprintf("Credentials rotation - refusing to rename the binding in SM: %s", message))
+	setOwnershipMismatchCondition(binding, message)
+	return true, r.stopRotation(ctx, binding)
+}
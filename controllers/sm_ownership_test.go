@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	"github.com/SAP/sap-btp-service-operator/client/sm/smfakes"
+	smClientTypes "github.com/SAP/sap-btp-service-operator/client/sm/types"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("SM ownership", func() {
+	var reconciler *ServiceInstanceReconciler
+	var instance *servicesv1.ServiceInstance
+	var smClient *smfakes.FakeClient
+	var ctx context.Context
+
+	ownLabels := func() smClientTypes.Labels {
+		return smClientTypes.Labels{"_namespace": {"app1"}, "_k8sname": {"my-instance"}, "_clusterid": {"cluster-id"}}
+	}
+
+	BeforeEach(func() {
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		instance = &servicesv1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-instance", Namespace: "app1", Generation: 1},
+			Spec:       servicesv1.ServiceInstanceSpec{ServiceOfferingName: "offering", ServicePlanName: "plan"},
+			Status:     servicesv1.ServiceInstanceStatus{InstanceID: "instance-id"},
+		}
+		reconciler = &ServiceInstanceReconciler{BaseReconciler: &BaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance).WithStatusSubresource(instance).Build(),
+			Scheme: testScheme,
+			Config: config.Config{ClusterID: "cluster-id", LongPollInterval: time.Minute, StrictOwnership: true},
+		}}
+		smClient = &smfakes.FakeClient{}
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(instance), instance)).To(Succeed())
+	})
+
+	Context("ownershipMismatches", func() {
+		It("should accept the labels of the resource", func() {
+			Expect(reconciler.ownershipMismatches(config.SMLabels{}.For("offering"), instance, "", ownLabels())).To(BeEmpty())
+		})
+
+		It("should report the labels of another resource or cluster", func() {
+			labels := smClientTypes.Labels{"_namespace": {"app2"}, "_k8sname": {"my-instance"}, "_clusterid": {"other-cluster"}}
+			Expect(reconciler.ownershipMismatches(config.SMLabels{}.For("offering"), instance, "", labels)).To(Equal([]string{"_clusterid", "_namespace"}))
+			Expect(reconciler.ownershipMismatches(config.SMLabels{}.For("offering"), instance, "", nil)).To(Equal([]string{"_clusterid", "_k8sname", "_namespace"}))
+		})
+
+		It("should accept the cluster ID the resource was taken over from", func() {
+			Expect(reconciler.Config.FeatureGates.Decode("ClusterIDOverride=true")).To(Succeed())
+			labels := ownLabels()
+			labels["_clusterid"] = []string{"other-cluster"}
+			Expect(reconciler.ownershipMismatches(config.SMLabels{}.For("offering"), instance, "other-cluster", labels)).To(BeEmpty())
+		})
+
+		It("should accept the k8s name under earlier keys and skip disabled labels", func() {
+			smLabelsConfig := config.SMLabels{}
+			Expect(smLabelsConfig.Decode(`{"k8sname": "k8s-name", "offerings": {"strict-offering": {"namespace": "", "clusterid": ""}}}`)).To(Succeed())
+			Expect(reconciler.ownershipMismatches(smLabelsConfig.For("offering"), instance, "", ownLabels())).To(BeEmpty())
+			Expect(reconciler.ownershipMismatches(smLabelsConfig.For("strict-offering"), instance, "", smClientTypes.Labels{"k8s-name": {"my-instance"}})).To(BeEmpty())
+		})
+	})
+
+	Context("verifyInstanceOwnership", func() {
+		It("should not get the instance without strict ownership", func() {
+			reconciler.Config.StrictOwnership = false
+			refused, _, err := reconciler.verifyInstanceOwnership(ctx, smClient, smClientTypes.DELETE, instance)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(refused).To(BeFalse())
+			Expect(smClient.GetInstanceByIDCallCount()).To(BeZero())
+		})
+
+		It("should allow the operation on an owned instance", func() {
+			smClient.GetInstanceByIDReturns(&smClientTypes.ServiceInstance{ID: "instance-id", Labels: ownLabels()}, nil)
+			refused, _, err := reconciler.verifyInstanceOwnership(ctx, smClient, smClientTypes.UPDATE, instance)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(refused).To(BeFalse())
+		})
+
+		It("should leave an instance which is already deleted to the operation", func() {
+			smClient.GetInstanceByIDReturns(nil, &sm.ServiceManagerError{StatusCode: http.StatusNotFound, ErrorType: "NotFound", Description: "could not find such service_instance"})
+			refused, _, err := reconciler.verifyInstanceOwnership(ctx, smClient, smClientTypes.DELETE, instance)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(refused).To(BeFalse())
+		})
+
+		It("should refuse the operation on an instance owned by another cluster", func() {
+			labels := ownLabels()
+			labels["_clusterid"] = []string{"other-cluster"}
+			smClient.GetInstanceByIDReturns(&smClientTypes.ServiceInstance{ID: "instance-id", Labels: labels}, nil)
+
+			refused, result, err := reconciler.verifyInstanceOwnership(ctx, smClient, smClientTypes.DELETE, instance)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(refused).To(BeTrue())
+			Expect(result.RequeueAfter).To(Equal(time.Minute))
+
+			Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(instance), instance)).To(Succeed())
+			condition := meta.FindStatusCondition(instance.Status.Conditions, api.ConditionOwnershipMismatch)
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Reason).To(Equal(OwnershipMismatch))
+			Expect(condition.Message).To(ContainSubstring("_clusterid"))
+			Expect(meta.IsStatusConditionTrue(instance.Status.Conditions, api.ConditionFailed)).To(BeTrue())
+
+			smClient.GetInstanceByIDReturns(&smClientTypes.ServiceInstance{ID: "instance-id", Labels: ownLabels()}, nil)
+			refused, _, err = reconciler.verifyInstanceOwnership(ctx, smClient, smClientTypes.DELETE, instance)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(refused).To(BeFalse())
+			Expect(meta.FindStatusCondition(instance.Status.Conditions, api.ConditionOwnershipMismatch)).To(BeNil())
+		})
+
+		It("should not share an instance owned by another cluster", func() {
+			instance.Spec.Shared = pointer.Bool(true)
+			smClient.GetInstanceByIDReturns(&smClientTypes.ServiceInstance{ID: "instance-id", Labels: smClientTypes.Labels{"_clusterid": {"other-cluster"}}}, nil)
+			_, err := reconciler.handleInstanceSharing(ctx, instance, smClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(smClient.ShareInstanceCallCount()).To(BeZero())
+			Expect(meta.IsStatusConditionTrue(instance.Status.Conditions, api.ConditionOwnershipMismatch)).To(BeTrue())
+		})
+	})
+
+	Context("refuseRotationOnOwnershipMismatch", func() {
+		var bindingReconciler *ServiceBindingReconciler
+		var binding *servicesv1.ServiceBinding
+
+		BeforeEach(func() {
+			binding = &servicesv1.ServiceBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1", Generation: 1},
+				Status:     servicesv1.ServiceBindingStatus{BindingID: "binding-id"},
+			}
+			meta.SetStatusCondition(&binding.Status.Conditions, metav1.Condition{Type: api.ConditionCredRotationInProgress, Status: metav1.ConditionTrue, Reason: CredPreparing})
+			bindingReconciler = &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+				Client: fake.NewClientBuilder().WithScheme(reconciler.Scheme).WithObjects(binding).WithStatusSubresource(binding).Build(),
+				Scheme: reconciler.Scheme,
+				Config: reconciler.Config,
+			}}
+			Expect(bindingReconciler.Client.Get(ctx, client.ObjectKeyFromObject(binding), binding)).To(Succeed())
+		})
+
+		It("should stop the rotation of a binding owned by another cluster", func() {
+			smClient.GetBindingByIDReturns(&smClientTypes.ServiceBinding{ID: "binding-id", Labels: smClientTypes.Labels{"_clusterid": {"other-cluster"}}}, nil)
+			refused, err := bindingReconciler.refuseRotationOnOwnershipMismatch(ctx, smClient, binding, "offering")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(refused).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(binding.Status.Conditions, api.ConditionOwnershipMismatch)).To(BeTrue())
+			Expect(meta.FindStatusCondition(binding.Status.Conditions, api.ConditionCredRotationInProgress)).To(BeNil())
+		})
+
+		It("should rotate an owned binding", func() {
+			smClient.GetBindingByIDReturns(&smClientTypes.ServiceBinding{ID: "binding-id",
+				Labels: smClientTypes.Labels{"_namespace": {"app1"}, "_k8sname": {"my-binding"}, "_clusterid": {"cluster-id"}}}, nil)
+			refused, err := bindingReconciler.refuseRotationOnOwnershipMismatch(ctx, smClient, binding, "offering")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(refused).To(BeFalse())
+		})
+	})
+})
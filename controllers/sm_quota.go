@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// smQuotaSoftLimit is the share of the quota of the credentials from which the requeues of their resources are delayed
+const smQuotaSoftLimit = 0.8
+
+// smCalls counts the calls to SM of both controllers, the credentials are shared by instances and bindings
+var smCalls = newSMCallTracker()
+
+// smCallTracker counts the calls to SM per access credentials secret in a sliding window, so that the requeues
+// of the resources of credentials close to their quota can be delayed before SM starts throttling the technical
+// user of the subaccount, which may be shared with other clusters
+type smCallTracker struct {
+	mutex sync.Mutex
+	// calls holds the times of the calls in the window, oldest first, by credentials
+	calls map[string][]time.Time
+}
+
+func newSMCallTracker() *smCallTracker {
+	return &smCallTracker{calls: make(map[string][]time.Time)}
+}
+
+// record counts a call made with the credentials at the given time
+func (t *smCallTracker) record(credentials string, now time.Time, window time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	calls := append(t.prune(credentials, now, window), now)
+	t.calls[credentials] = calls
+	smCallsTotal.WithLabelValues(credentials).Inc()
+	smCallsInWindow.WithLabelValues(credentials).Set(float64(len(calls)))
+}
+
+// delay returns how long the requeues of resources of the credentials must be delayed for their calls in the window
+// to fall below the soft limit of the quota, zero if they are below it or no quota is configured
+func (t *smCallTracker) delay(credentials string, now time.Time, window time.Duration, quota int) time.Duration {
+	if quota <= 0 {
+		return 0
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	calls := t.prune(credentials, now, window)
+	smCallsInWindow.WithLabelValues(credentials).Set(float64(len(calls)))
+
+	softLimit := int(float64(quota) * smQuotaSoftLimit)
+	if softLimit < 1 {
+		softLimit = 1
+	}
+	if len(calls) < softLimit {
+		return 0
+	}
+	// the calls are below the soft limit once the call at this index left the window
+	return calls[len(calls)-softLimit].Add(window).Sub(now)
+}
+
+// prune drops the calls that left the window, it must be called with the lock held
+func (t *smCallTracker) prune(credentials string, now time.Time, window time.Duration) []time.Time {
+	calls := t.calls[credentials]
+	i := 0
+	for i < len(calls) && !calls[i].After(now.Add(-window)) {
+		i++
+	}
+	calls = calls[i:]
+	if len(calls) == 0 {
+		delete(t.calls, credentials)
+	}
+	return calls
+}
+
+// countSMCalls returns a hook for the SM client that counts its calls and remembers the credentials of the resource and
+// the time of its last call
+func (r *BaseReconciler) countSMCalls(resource types.NamespacedName, credentials string) func() {
+	r.smCredentials.Store(resource, credentials)
+	return func() {
+		now := time.Now()
+		smCalls.record(credentials, now, r.Config.SMCallQuotaWindow)
+		namespaceSMCalls.record(resource.Namespace, credentials, now)
+		r.smRequests.Store(resource, now)
+	}
+}
+
+// withSMBackpressure delays the reconciles of the resources whose credentials are close to their SM quota. Resources
+// that called SM before are requeued without being reconciled until the calls of their credentials drop below the soft
+// limit, whatever triggered the reconcile, and the requeues of successful reconciles are delayed in the same way.
+// Failed reconciles are retried with the backoff of the controller and are delayed on their retry.
+func (r *BaseReconciler) withSMBackpressure(reconciler reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+		if credentials, found := r.smCredentials.Load(req.NamespacedName); found {
+			if delay := r.smBackpressureDelay(req.NamespacedName, credentials.(string)); delay > 0 {
+				return ctrl.Result{RequeueAfter: delay}, nil
+			}
+		}
+
+		result, err := reconciler.Reconcile(ctx, req)
+		credentials, found := r.smCredentials.Load(req.NamespacedName)
+		if !found || err != nil || (!result.Requeue && result.RequeueAfter == 0) {
+			return result, err
+		}
+		if delay := r.smBackpressureDelay(req.NamespacedName, credentials.(string)); delay > result.RequeueAfter {
+			result.RequeueAfter = delay
+		}
+		return result, err
+	})
+}
+
+// smBackpressureDelay returns how long the resource must wait before it calls SM again with the credentials
+func (r *BaseReconciler) smBackpressureDelay(resource types.NamespacedName, credentials string) time.Duration {
+	delay := smCalls.delay(credentials, time.Now(), r.Config.SMCallQuotaWindow, r.Config.SMCallQuota)
+	if delay > 0 {
+		r.Log.Info("delaying the reconcile, the SM quota of the credentials is almost consumed", "resource", resource, "credentials", credentials, "delay", delay)
+		smBackpressureDelays.WithLabelValues(credentials).Inc()
+	}
+	return delay
+}
+
+// forgetSMCredentials drops the credentials and the time of the last call to SM of a deleted resource
+func (r *BaseReconciler) forgetSMCredentials(resource types.NamespacedName) {
+	r.smCredentials.Delete(resource)
+	r.smRequests.Delete(resource)
+}
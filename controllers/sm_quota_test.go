@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("SM call quota", func() {
+	const credentials = "default/sap-btp-service-operator"
+	var tracker *smCallTracker
+	var now time.Time
+
+	BeforeEach(func() {
+		tracker = newSMCallTracker()
+		now = time.Now()
+	})
+
+	It("should count the calls in the window only", func() {
+		tracker.record(credentials, now.Add(-2*time.Minute), time.Minute)
+		tracker.record(credentials, now.Add(-30*time.Second), time.Minute)
+		tracker.record(credentials, now, time.Minute)
+		Expect(tracker.prune(credentials, now, time.Minute)).To(HaveLen(2))
+		Expect(tracker.prune("other/secret", now, time.Minute)).To(BeEmpty())
+	})
+
+	It("should not delay below the soft limit or without quota", func() {
+		for i := 0; i < 7; i++ {
+			tracker.record(credentials, now, time.Minute)
+		}
+		Expect(tracker.delay(credentials, now, time.Minute, 10)).To(BeZero())
+		Expect(tracker.delay(credentials, now, time.Minute, 0)).To(BeZero())
+	})
+
+	It("should delay until the calls drop below the soft limit", func() {
+		for i := 10; i > 0; i-- {
+			tracker.record(credentials, now.Add(-time.Duration(i)*time.Second), time.Minute)
+		}
+		// 10 calls in the window, the soft limit of 10 is 8 calls: 3 calls must leave the window, the third one in 52s
+		Expect(tracker.delay(credentials, now, time.Minute, 10)).To(Equal(52 * time.Second))
+	})
+
+	Context("backpressure", func() {
+		var reconciler *BaseReconciler
+		var result ctrl.Result
+		resource := types.NamespacedName{Namespace: "app1", Name: "my-instance"}
+
+		BeforeEach(func() {
+			reconciler = &BaseReconciler{Log: ctrl.Log, Config: config.Config{SMCallQuota: 2, SMCallQuotaWindow: time.Hour}}
+			result = ctrl.Result{RequeueAfter: time.Second}
+		})
+
+		reconciles := 0
+		backpressure := func() reconcile.Reconciler {
+			reconciles = 0
+			return reconciler.withSMBackpressure(reconcile.Func(func(context.Context, ctrl.Request) (ctrl.Result, error) {
+				reconciles++
+				onRequest := reconciler.countSMCalls(resource, "app1/quota-test")
+				onRequest()
+				onRequest()
+				return result, nil
+			}))
+		}
+
+		It("should delay requeues of resources of credentials close to their quota", func() {
+			res, err := backpressure().Reconcile(context.Background(), ctrl.Request{NamespacedName: resource})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.RequeueAfter).To(BeNumerically(">", 59*time.Minute))
+		})
+
+		It("should delay any reconcile of resources of credentials close to their quota", func() {
+			result = ctrl.Result{}
+			quotaReconciler := backpressure()
+			res, err := quotaReconciler.Reconcile(context.Background(), ctrl.Request{NamespacedName: resource})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(Equal(ctrl.Result{}))
+
+			// e.g. a change of the resource
+			res, err = quotaReconciler.Reconcile(context.Background(), ctrl.Request{NamespacedName: resource})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.RequeueAfter).To(BeNumerically(">", 59*time.Minute))
+			Expect(reconciles).To(Equal(1))
+		})
+
+		It("should forget the credentials of deleted resources", func() {
+			result = ctrl.Result{}
+			_, err := backpressure().Reconcile(context.Background(), ctrl.Request{NamespacedName: resource})
+			Expect(err).ToNot(HaveOccurred())
+			reconciler.forgetSMCredentials(resource)
+			_, found := reconciler.smCredentials.Load(resource)
+			Expect(found).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+// stagedStartup orders the initial reconciliation of a controller when the operator starts with many resources:
+// resources with pending work are reconciled immediately while resources in a final state are spread over the
+// startup window. It also measures how long it takes until every resource that existed on startup was reconciled once.
+//
+// The window starts once the operator is elected as leader. The resources are counted in the informer cache once its
+// initial LIST synced, so that the count adds no LIST to the API server and covers only the namespaces the cache
+// watches, the count is used to detect the startup convergence.
+type stagedStartup struct {
+	handler.EnqueueRequestForObject
+
+	controller string
+	reader     client.Reader
+	newList    func() client.ObjectList
+	window     time.Duration
+	log        logr.Logger
+
+	begin           sync.Once
+	startedAt       time.Time
+	expected        atomic.Int64
+	reconciledCount atomic.Int64
+	converged       sync.Once
+	// initial holds the requests of resources that existed on startup and were not reconciled yet
+	initial sync.Map
+}
+
+func newStagedStartup(mgr ctrl.Manager, controller string, newList func() client.ObjectList, window time.Duration) *stagedStartup {
+	s := &stagedStartup{
+		controller: controller,
+		reader:     mgr.GetCache(),
+		newList:    newList,
+		window:     window,
+		log:        ctrl.Log.WithName("startup").WithName(controller),
+	}
+	s.expected.Store(-1)
+	return s
+}
+
+// Start starts the startup window and counts the resources in the cache, it runs once the operator is elected as leader
+func (s *stagedStartup) Start(ctx context.Context) error {
+	s.start()
+	count, err := s.countResources(ctx)
+	if err != nil {
+		// without the count the convergence is not measured, the staged startup itself still applies
+		s.log.Error(err, "failed to count resources on startup")
+		return nil
+	}
+	s.log.Info("counted resources on startup", "count", count)
+	startupResources.WithLabelValues(s.controller).Set(float64(count))
+	s.expected.Store(count)
+	s.checkConvergence()
+	return nil
+}
+
+// countResources counts the resources of the controller, the LIST of the cache waits until the informer synced
+func (s *stagedStartup) countResources(ctx context.Context) (int64, error) {
+	list := s.newList()
+	// the resources are only counted, no need to copy them
+	if err := s.reader.List(ctx, list, client.UnsafeDisableDeepCopy); err != nil {
+		return 0, err
+	}
+	return int64(meta.LenList(list)), nil
+}
+
+// start starts the startup window, either on Start or on the first event of the controller, whichever comes first
+func (s *stagedStartup) start() {
+	s.begin.Do(func() {
+		s.startedAt = time.Now()
+	})
+}
+
+// Create is called for every resource of the initial informer LIST and for resources created later on
+func (s *stagedStartup) Create(ctx context.Context, evt event.CreateEvent, q workqueue.RateLimitingInterface) {
+	if evt.Object == nil {
+		return
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: evt.Object.GetNamespace(), Name: evt.Object.GetName()}}
+	s.start()
+	elapsed := time.Since(s.startedAt)
+	if elapsed >= s.window {
+		q.Add(req)
+		return
+	}
+
+	s.initial.Store(req, struct{}{})
+	if resource, ok := evt.Object.(api.SAPBTPResource); ok && !hasPendingWork(resource) {
+		// resources in a final state only need a maintenance reconcile, spread them over the rest of the window
+		startupDeferredReconciles.WithLabelValues(s.controller).Inc()
+		q.AddAfter(req, time.Duration(rand.Int63n(int64(s.window-elapsed))))
+		return
+	}
+	q.Add(req)
+}
+
+// reconciled records that a request was reconciled, the startup converged once all initial resources were reconciled
+func (s *stagedStartup) reconciled(req ctrl.Request) {
+	if _, loaded := s.initial.LoadAndDelete(req); loaded {
+		s.reconciledCount.Add(1)
+		s.checkConvergence()
+	}
+}
+
+func (s *stagedStartup) checkConvergence() {
+	expected := s.expected.Load()
+	// resources created during the startup window are counted as well, hence not an exact match
+	if expected < 0 || s.reconciledCount.Load() < expected {
+		return
+	}
+	s.converged.Do(func() {
+		duration := time.Since(s.startedAt)
+		s.log.Info("all resources were reconciled since startup", "count", expected, "duration", duration.String())
+		startupConvergenceSeconds.WithLabelValues(s.controller).Set(duration.Seconds())
+	})
+}
+
+// hasPendingWork returns true if the resource is being deleted, has changes that were not reconciled yet,
+// has an operation in progress or is not ready
+func hasPendingWork(resource api.SAPBTPResource) bool {
+	return !resource.GetDeletionTimestamp().IsZero() ||
+		resource.GetGeneration() != resource.GetObservedGeneration() ||
+		isInProgress(resource) ||
+		resource.GetReady() != metav1.ConditionTrue
+}
@@ -125,9 +125,12 @@ var _ = BeforeSuite(func(done Done) {
 	Expect(err).ToNot(HaveOccurred())
 
 	fakeClient = &smfakes.FakeClient{}
+	fakeClient.CapabilitiesReturns(&sm.Capabilities{Features: map[sm.Feature]bool{sm.FeatureAttachLastOperations: true, sm.FeatureSharedInstances: true}})
 	testConfig := config.Get()
 	testConfig.SyncPeriod = syncPeriod
 	testConfig.PollInterval = pollInterval
+	testConfig.MaintenanceCheckInterval = 0
+	testConfig.DriftCheckInterval = time.Minute
 
 	By("registering webhooks")
 	k8sManager.GetWebhookServer().Register("/mutate-services-cloud-sap-com-v1-serviceinstance", &webhook.Admission{Handler: &webhooks.ServiceInstanceDefaulter{Decoder: admission.NewDecoder(k8sManager.GetScheme())}})
@@ -154,12 +157,13 @@ var _ = BeforeSuite(func(done Done) {
 
 	err = (&ServiceBindingReconciler{
 		BaseReconciler: &BaseReconciler{
-			Client:   k8sManager.GetClient(),
-			Scheme:   k8sManager.GetScheme(),
-			Log:      ctrl.Log.WithName("controllers").WithName("ServiceBinding"),
-			SMClient: func() sm.Client { return fakeClient },
-			Config:   testConfig,
-			Recorder: k8sManager.GetEventRecorderFor("ServiceBinding"),
+			Client:    k8sManager.GetClient(),
+			Scheme:    k8sManager.GetScheme(),
+			Log:       ctrl.Log.WithName("controllers").WithName("ServiceBinding"),
+			SMClient:  func() sm.Client { return fakeClient },
+			Config:    testConfig,
+			Recorder:  k8sManager.GetEventRecorderFor("ServiceBinding"),
+			APIReader: k8sManager.GetAPIReader(),
 		},
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultTLSCertificateKey = "certificate"
+	defaultTLSPrivateKeyKey  = "key"
+	defaultTLSCAKey          = "ca"
+
+	// tlsCAKey is the key of the CA certificate in TLS secrets, as used by ingress controllers and cert-manager
+	tlsCAKey = "ca.crt"
+
+	tlsSecretNameTakenErrorFormat = "the specified TLS secret name '%s' is already taken. Choose another name and try again"
+)
+
+// isTLSBindingSecret returns true if the binding secret itself is written as TLS secret
+func isTLSBindingSecret(binding *servicesv1.ServiceBinding) bool {
+	return binding.Spec.TLSSecret != nil && len(binding.Spec.TLSSecret.Name) == 0
+}
+
+// tlsSecretData returns the keys of a TLS secret from the certificate credentials returned by the broker,
+// the certificate and the private key are required, the CA certificate is optional
+func tlsSecretData(tlsSecret *servicesv1.TLSSecret, credentials json.RawMessage) (map[string][]byte, error) {
+	credentialsMap := make(map[string]interface{})
+	if len(credentials) > 0 {
+		if err := json.Unmarshal(credentials, &credentialsMap); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal given service binding credentials")
+		}
+	}
+
+	keys := []struct {
+		credential string
+		dataKey    string
+		required   bool
+	}{
+		{credential: valueOrDefault(tlsSecret.CertificateKey, defaultTLSCertificateKey), dataKey: corev1.TLSCertKey, required: true},
+		{credential: valueOrDefault(tlsSecret.PrivateKeyKey, defaultTLSPrivateKeyKey), dataKey: corev1.TLSPrivateKeyKey, required: true},
+		{credential: valueOrDefault(tlsSecret.CAKey, defaultTLSCAKey), dataKey: tlsCAKey},
+	}
+	data := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		value, ok := credentialsMap[key.credential]
+		if !ok {
+			if key.required {
+				return nil, fmt.Errorf("the binding credentials have no '%s' credential for the %s key of the TLS secret", key.credential, key.dataKey)
+			}
+			continue
+		}
+		pem, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("the '%s' credential for the %s key of the TLS secret is not a string", key.credential, key.dataKey)
+		}
+		data[key.dataKey] = []byte(pem)
+	}
+	return data, nil
+}
+
+func valueOrDefault(value, defaultValue string) string {
+	if len(value) == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// setTLSSecretData replaces the content of the binding secret with the certificate credentials
+func setTLSSecretData(binding *servicesv1.ServiceBinding, secret *corev1.Secret, credentials json.RawMessage) error {
+	data, err := tlsSecretData(binding.Spec.TLSSecret, credentials)
+	if err != nil {
+		return err
+	}
+	secret.Type = corev1.SecretTypeTLS
+	secret.Data = data
+	return nil
+}
+
+// createOrUpdateBindingTLSSecret stores the TLS secret written alongside the binding secret and deletes the one
+// no longer requested. It is owned by the binding like the config maps of the secret template.
+func (r *ServiceBindingReconciler) createOrUpdateBindingTLSSecret(ctx context.Context, binding *servicesv1.ServiceBinding, credentials json.RawMessage) error {
+	if binding.Spec.TLSSecret == nil || len(binding.Spec.TLSSecret.Name) == 0 {
+		return r.pruneGeneratedBindingSecrets(ctx, binding, r.generatedSecretNames(binding))
+	}
+
+	data, err := tlsSecretData(binding.Spec.TLSSecret, credentials)
+	if err != nil {
+		return err
+	}
+	key := r.bindingObjectKey(binding, binding.Spec.TLSSecret.Name)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+		Type:       corev1.SecretTypeTLS,
+		Data:       data,
+	}
+	taken, err := r.writeGeneratedBindingSecret(ctx, binding, secret)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf(tlsSecretNameTakenErrorFormat, binding.Spec.TLSSecret.Name)
+	}
+	return r.pruneGeneratedBindingSecrets(ctx, binding, r.generatedSecretNames(binding))
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("TLS secret", func() {
+	var reconciler *ServiceBindingReconciler
+	var binding *servicesv1.ServiceBinding
+	var ctx context.Context
+	credentials := []byte(`{"certificate": "cert-pem", "key": "key-pem", "ca": "ca-pem", "clientid": "my-client"}`)
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		reconciler = &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).Build(),
+			Scheme:   testScheme,
+			Log:      ctrl.Log,
+			Recorder: record.NewFakeRecorder(10),
+		}}
+		binding = &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1", UID: "binding-uid"},
+			Spec:       servicesv1.ServiceBindingSpec{SecretName: "my-secret", TLSSecret: &servicesv1.TLSSecret{Name: "my-tls"}},
+		}
+	})
+
+	It("should map the certificate credentials to the keys of a TLS secret", func() {
+		data, err := tlsSecretData(&servicesv1.TLSSecret{}, credentials)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(map[string][]byte{"tls.crt": []byte("cert-pem"), "tls.key": []byte("key-pem"), "ca.crt": []byte("ca-pem")}))
+
+		data, err = tlsSecretData(&servicesv1.TLSSecret{CertificateKey: "clientid", CAKey: "root"}, credentials)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(map[string][]byte{"tls.crt": []byte("my-client"), "tls.key": []byte("key-pem")}))
+
+		_, err = tlsSecretData(&servicesv1.TLSSecret{PrivateKeyKey: "private"}, credentials)
+		Expect(err).To(MatchError(ContainSubstring("no 'private' credential for the tls.key key")))
+	})
+
+	It("should write the binding secret as TLS secret", func() {
+		binding.Spec.TLSSecret.Name = ""
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-secret", Namespace: "app1"},
+			Data: map[string][]byte{"clientid": []byte("my-client")}}
+		Expect(setTLSSecretData(binding, secret, credentials)).To(Succeed())
+		Expect(secret.Type).To(Equal(corev1.SecretTypeTLS))
+		Expect(secret.Data).ToNot(HaveKey("clientid"))
+		Expect(secret.Data).To(HaveKeyWithValue("tls.key", []byte("key-pem")))
+	})
+
+	It("should write the TLS secret alongside the binding secret and prune it when it is no longer requested", func() {
+		Expect(reconciler.createOrUpdateBindingTLSSecret(ctx, binding, credentials)).To(Succeed())
+		tlsSecret := &corev1.Secret{}
+		Expect(reconciler.Client.Get(ctx, types.NamespacedName{Name: "my-tls", Namespace: "app1"}, tlsSecret)).To(Succeed())
+		Expect(tlsSecret.Type).To(Equal(corev1.SecretTypeTLS))
+		Expect(tlsSecret.Data).To(HaveKeyWithValue("tls.crt", []byte("cert-pem")))
+		Expect(tlsSecret.Labels).To(HaveKeyWithValue(api.BindingUIDLabel, "binding-uid"))
+		Expect(metav1.IsControlledBy(tlsSecret, binding)).To(BeTrue())
+
+		binding.Spec.TLSSecret.Name = "my-other-tls"
+		Expect(reconciler.createOrUpdateBindingTLSSecret(ctx, binding, credentials)).To(Succeed())
+		err := reconciler.Client.Get(ctx, types.NamespacedName{Name: "my-tls", Namespace: "app1"}, tlsSecret)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		binding.Spec.TLSSecret = nil
+		Expect(reconciler.createOrUpdateBindingTLSSecret(ctx, binding, credentials)).To(Succeed())
+		err = reconciler.Client.Get(ctx, types.NamespacedName{Name: "my-other-tls", Namespace: "app1"}, tlsSecret)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should not take over a secret of another owner", func() {
+		Expect(reconciler.Client.Create(ctx, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-tls", Namespace: "app1"}})).To(Succeed())
+		err := reconciler.createOrUpdateBindingTLSSecret(ctx, binding, credentials)
+		Expect(err).To(MatchError(ContainSubstring("the specified TLS secret name 'my-tls' is already taken")))
+	})
+})
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// UnbindFailurePolicyRetry keeps retrying a failed unbind at the maximal retry delay once the retries are exhausted
+	UnbindFailurePolicyRetry = "Retry"
+	// UnbindFailurePolicyOrphan removes a binding whose unbind keeps failing from the cluster and leaves it in SM
+	UnbindFailurePolicyOrphan = "Orphan"
+	// UnbindFailurePolicyForce deletes a binding whose unbind keeps failing in SM regardless of the answer of the broker
+	UnbindFailurePolicyForce = "Force"
+
+	// Orphaned is the reason of the event recorded when a resource is removed from the cluster without being deleted in SM
+	Orphaned = "Orphaned"
+	// ForceDeleted is the reason of the event recorded when a resource is deleted in SM regardless of the answer of the broker
+	ForceDeleted = "ForceDeleted"
+
+	// maxUnbindFailureClasses bounds the values of the class label of the unbind failures metric, the error types are
+	// reported by the brokers and not limited
+	maxUnbindFailureClasses = 20
+	otherUnbindFailureClass = "other"
+)
+
+var (
+	unbindFailureClassesMutex sync.Mutex
+	unbindFailureClasses      = map[string]bool{}
+)
+
+// ValidateUnbindFailurePolicy rejects unknown unbind failure policies on startup
+func ValidateUnbindFailurePolicy(policy string) error {
+	if policy != UnbindFailurePolicyRetry && policy != UnbindFailurePolicyOrphan && policy != UnbindFailurePolicyForce {
+		return fmt.Errorf("invalid unbind failure policy '%s', expected %s, %s or %s", policy, UnbindFailurePolicyRetry, UnbindFailurePolicyOrphan, UnbindFailurePolicyForce)
+	}
+	return nil
+}
+
+// handleUnbindFailure retries a failed async unbind with an escalating delay, many of these failures are transient
+// hiccups of the broker. Once the retries are exhausted the unbind failure policy applies.
+func (r *ServiceBindingReconciler) handleUnbindFailure(ctx context.Context, binding *servicesv1.ServiceBinding, operationErrors json.RawMessage, errMsg string) (ctrl.Result, error) {
+	log := GetLogger(ctx)
+	unbindFailures.WithLabelValues(boundedUnbindFailureClass(unbindFailureClass(operationErrors))).Inc()
+
+	failures := 1
+	if value, ok := r.unbindFailures.Load(binding.UID); ok {
+		failures = value.(int) + 1
+	}
+	r.unbindFailures.Store(binding.UID, failures)
+
+	if failures > r.Config.UnbindRetryLimit && r.Config.UnbindFailurePolicy == UnbindFailurePolicyOrphan {
+		message := fmt.Sprintf("%s, removing the binding from the cluster after %d attempts, it is left in Service Manager", errMsg, failures)
+		log.Info(message)
+		r.Recorder.Event(binding, corev1.EventTypeWarning, Orphaned, message)
+		return r.deleteSecretAndRemoveFinalizer(ctx, binding)
+	}
+
+	if err := r.updateStatus(ctx, binding); err != nil {
+		log.Error(err, "unable to update ServiceBinding status")
+		return ctrl.Result{}, err
+	}
+	if failures == r.Config.UnbindRetryLimit+1 && r.unbindForced(binding) {
+		message := fmt.Sprintf("%s, deleting the binding in Service Manager regardless of the broker after %d attempts", errMsg, failures)
+		log.Info(message)
+		r.Recorder.Event(binding, corev1.EventTypeWarning, ForceDeleted, message)
+		return ctrl.Result{Requeue: true}, nil
+	}
+	delay := unbindRetryDelay(r.Config.RetryBaseDelay, r.Config.RetryMaxDelay, failures)
+	log.Info(fmt.Sprintf("%s, retrying in %s", errMsg, delay), "attempt", failures)
+	return ctrl.Result{RequeueAfter: delay}, nil
+}
+
+// unbindForced checks whether the retries of a failed unbind are exhausted with the Force policy, the binding is then
+// deleted in SM with the force parameter, which removes it from SM even if the broker fails to delete it
+func (r *ServiceBindingReconciler) unbindForced(binding *servicesv1.ServiceBinding) bool {
+	if r.Config.UnbindFailurePolicy != UnbindFailurePolicyForce {
+		return false
+	}
+	failures, ok := r.unbindFailures.Load(binding.UID)
+	return ok && failures.(int) > r.Config.UnbindRetryLimit
+}
+
+// unbindParameters returns the query parameters of the unbind request of a binding
+func (r *ServiceBindingReconciler) unbindParameters(binding *servicesv1.ServiceBinding) *sm.Parameters {
+	if !r.unbindForced(binding) {
+		return nil
+	}
+	return &sm.Parameters{GeneralParams: []string{"force=true"}}
+}
+
+// unbindRetryDelay doubles the delay with every failed attempt up to the maximal retry delay
+func unbindRetryDelay(base, max time.Duration, failures int) time.Duration {
+	delay := base
+	for i := 1; i < failures && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// unbindFailureClass returns the error type of the failed operation, e.g. the error of the broker, for the metric
+func unbindFailureClass(operationErrors json.RawMessage) string {
+	var errorDescription struct {
+		Error string `json:"error"`
+	}
+	if len(operationErrors) == 0 || json.Unmarshal(operationErrors, &errorDescription) != nil || len(errorDescription.Error) == 0 {
+		return "unknown"
+	}
+	return errorDescription.Error
+}
+
+// boundedUnbindFailureClass keeps the first maxUnbindFailureClasses classes as metric labels and reports any further
+// class, and classes which are no identifiers, as other
+func boundedUnbindFailureClass(class string) string {
+	if len(class) > 64 || strings.IndexFunc(class, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' && r != '.'
+	}) >= 0 {
+		return otherUnbindFailureClass
+	}
+	unbindFailureClassesMutex.Lock()
+	defer unbindFailureClassesMutex.Unlock()
+	if !unbindFailureClasses[class] {
+		if len(unbindFailureClasses) >= maxUnbindFailureClasses {
+			return otherUnbindFailureClass
+		}
+		unbindFailureClasses[class] = true
+	}
+	return class
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Unbind failures", func() {
+	var reconciler *ServiceBindingReconciler
+	var binding *servicesv1.ServiceBinding
+	var recorder *record.FakeRecorder
+	var ctx context.Context
+
+	BeforeEach(func() {
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		binding = &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-binding", Namespace: "app1", UID: "binding-uid", Finalizers: []string{api.FinalizerName}},
+			Spec:       servicesv1.ServiceBindingSpec{SecretName: "my-secret"},
+		}
+		recorder = record.NewFakeRecorder(10)
+		reconciler = &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(binding).WithStatusSubresource(binding).Build(),
+			Scheme:   testScheme,
+			Recorder: recorder,
+			Config: config.Config{
+				RetryBaseDelay:      10 * time.Second,
+				RetryMaxDelay:       time.Minute,
+				UnbindRetryLimit:    2,
+				UnbindFailurePolicy: UnbindFailurePolicyRetry,
+			},
+		}}
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(binding), binding)).To(Succeed())
+	})
+
+	It("should escalate the delay up to the maximal retry delay", func() {
+		Expect(unbindRetryDelay(10*time.Second, time.Minute, 1)).To(Equal(10 * time.Second))
+		Expect(unbindRetryDelay(10*time.Second, time.Minute, 3)).To(Equal(40 * time.Second))
+		Expect(unbindRetryDelay(10*time.Second, time.Minute, 10)).To(Equal(time.Minute))
+	})
+
+	It("should classify failures by the error of the operation", func() {
+		Expect(unbindFailureClass(json.RawMessage(`{"error":"BrokerError","description":"timeout"}`))).To(Equal("BrokerError"))
+		Expect(unbindFailureClass(json.RawMessage(`not json`))).To(Equal("unknown"))
+		Expect(unbindFailureClass(nil)).To(Equal("unknown"))
+	})
+
+	It("should bound the classes of the metric", func() {
+		Expect(boundedUnbindFailureClass("BrokerError")).To(Equal("BrokerError"))
+		Expect(boundedUnbindFailureClass("broker said: {no}")).To(Equal(otherUnbindFailureClass))
+		for i := 0; i < maxUnbindFailureClasses; i++ {
+			boundedUnbindFailureClass(fmt.Sprintf("Class%d", i))
+		}
+		Expect(boundedUnbindFailureClass("OneClassTooMany")).To(Equal(otherUnbindFailureClass))
+		Expect(boundedUnbindFailureClass("BrokerError")).To(Equal("BrokerError"))
+	})
+
+	It("should reject unknown policies", func() {
+		Expect(ValidateUnbindFailurePolicy(UnbindFailurePolicyOrphan)).To(Succeed())
+		Expect(ValidateUnbindFailurePolicy(UnbindFailurePolicyForce)).To(Succeed())
+		Expect(ValidateUnbindFailurePolicy("Delete")).ToNot(Succeed())
+	})
+
+	It("should keep retrying with the Retry policy", func() {
+		for _, delay := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute} {
+			result, err := reconciler.handleUnbindFailure(ctx, binding, nil, "Async unbind operation failed")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(delay))
+		}
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(binding), binding)).To(Succeed())
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("should remove the binding from the cluster with the Orphan policy", func() {
+		reconciler.Config.UnbindFailurePolicy = UnbindFailurePolicyOrphan
+		for i := 0; i < 2; i++ {
+			result, err := reconciler.handleUnbindFailure(ctx, binding, nil, "Async unbind operation failed")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).ToNot(BeZero())
+		}
+		result, err := reconciler.handleUnbindFailure(ctx, binding, nil, "Async unbind operation failed")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(<-recorder.Events).To(ContainSubstring("Warning Orphaned Async unbind operation failed"))
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(binding), binding)
+		Expect(err == nil && len(binding.Finalizers) == 0 || apierrors.IsNotFound(err)).To(BeTrue())
+		_, found := reconciler.unbindFailures.Load(binding.UID)
+		Expect(found).To(BeFalse())
+	})
+
+	It("should force the unbind with the Force policy", func() {
+		reconciler.Config.UnbindFailurePolicy = UnbindFailurePolicyForce
+		for i := 0; i < 2; i++ {
+			_, err := reconciler.handleUnbindFailure(ctx, binding, nil, "Async unbind operation failed")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reconciler.unbindParameters(binding)).To(BeNil())
+		}
+		result, err := reconciler.handleUnbindFailure(ctx, binding, nil, "Async unbind operation failed")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Requeue).To(BeTrue())
+		Expect(<-recorder.Events).To(ContainSubstring("Warning ForceDeleted Async unbind operation failed"))
+		Expect(reconciler.unbindParameters(binding).GeneralParams).To(ConsistOf("force=true"))
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(binding), binding)).To(Succeed())
+		Expect(binding.Finalizers).ToNot(BeEmpty())
+	})
+})
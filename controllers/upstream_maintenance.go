@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// instanceUnderMaintenance returns true if an update of the instance is in progress, e.g. an upgrade of its plan or of
+// the service itself
+func instanceUnderMaintenance(instance *servicesv1.ServiceInstance) bool {
+	cond := meta.FindStatusCondition(instance.Status.Conditions, api.ConditionSucceeded)
+	return cond != nil && cond.Status == metav1.ConditionFalse && cond.Reason == UpdateInProgress
+}
+
+// pauseForUpstreamMaintenance pauses the rotation and recreation of a created binding while its instance is updated,
+// so that the bindings of the instance do not rotate against a service in the middle of an upgrade. The binding is
+// paused with the UpstreamMaintenance condition until the instance is ready again.
+// Returns true if the binding is paused.
+func (r *ServiceBindingReconciler) pauseForUpstreamMaintenance(ctx context.Context, binding *servicesv1.ServiceBinding, instance *servicesv1.ServiceInstance) (bool, error) {
+	log := GetLogger(ctx)
+	maintenanceCondition := meta.FindStatusCondition(binding.Status.Conditions, api.ConditionUpstreamMaintenance)
+	paused := instanceUnderMaintenance(instance) ||
+		(maintenanceCondition != nil && !meta.IsStatusConditionTrue(instance.Status.Conditions, api.ConditionReady))
+	if !paused {
+		if maintenanceCondition != nil {
+			log.Info("service instance is ready again, resuming the binding")
+			meta.RemoveStatusCondition(&binding.Status.Conditions, api.ConditionUpstreamMaintenance)
+			if err := r.updateStatus(ctx, binding); err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+
+	if maintenanceCondition == nil {
+		message := fmt.Sprintf("the service instance '%s' is being updated, the rotation and recreation of the binding are paused until it is ready again", instance.Name)
+		log.Info(message)
+		meta.SetStatusCondition(&binding.Status.Conditions, metav1.Condition{
+			Type:               api.ConditionUpstreamMaintenance,
+			Status:             metav1.ConditionTrue,
+			Reason:             InstanceUpdate,
+			Message:            message,
+			ObservedGeneration: binding.Generation,
+		})
+		if err := r.updateStatus(ctx, binding); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// instanceMaintenanceChanged passes the updates of instances which start or finish a maintenance, and those which
+// become ready
+var instanceMaintenanceChanged = predicate.Funcs{
+	CreateFunc: func(event.CreateEvent) bool { return false },
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldInstance, okOld := e.ObjectOld.(*servicesv1.ServiceInstance)
+		newInstance, okNew := e.ObjectNew.(*servicesv1.ServiceInstance)
+		if !okOld || !okNew {
+			return false
+		}
+		return instanceUnderMaintenance(oldInstance) != instanceUnderMaintenance(newInstance) ||
+			meta.IsStatusConditionTrue(oldInstance.Status.Conditions, api.ConditionReady) != meta.IsStatusConditionTrue(newInstance.Status.Conditions, api.ConditionReady)
+	},
+	DeleteFunc:  func(event.DeleteEvent) bool { return false },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+}
+
+// maintainedBindingsOf maps an instance to its created bindings when it enters a maintenance, so that they are all
+// paused, and to its paused bindings otherwise, so that they resume together once the instance is ready
+func (r *ServiceBindingReconciler) maintainedBindingsOf(ctx context.Context, object client.Object) []reconcile.Request {
+	instance, ok := object.(*servicesv1.ServiceInstance)
+	if !ok {
+		return nil
+	}
+	bindingList := &servicesv1.ServiceBindingList{}
+	if err := r.Client.List(ctx, bindingList, client.MatchingFields{bindingInstanceIndex: client.ObjectKeyFromObject(instance).String()}); err != nil {
+		r.Log.Error(err, "failed to list the bindings of the instance under maintenance", "serviceinstance", client.ObjectKeyFromObject(instance))
+		return nil
+	}
+	underMaintenance := instanceUnderMaintenance(instance)
+	var requests []reconcile.Request
+	for i := range bindingList.Items {
+		binding := &bindingList.Items[i]
+		paused := meta.FindStatusCondition(binding.Status.Conditions, api.ConditionUpstreamMaintenance) != nil
+		if paused || (underMaintenance && len(binding.Status.BindingID) > 0) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(binding)})
+		}
+	}
+	return requests
+}
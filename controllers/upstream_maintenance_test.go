@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Upstream maintenance", func() {
+	var reconciler *ServiceBindingReconciler
+	var binding *servicesv1.ServiceBinding
+	var instance *servicesv1.ServiceInstance
+	var ctx context.Context
+
+	setInstanceConditions := func(instance *servicesv1.ServiceInstance, reason string, ready metav1.ConditionStatus) {
+		succeeded := metav1.ConditionTrue
+		if reason == UpdateInProgress {
+			succeeded = metav1.ConditionFalse
+		}
+		instance.Status.Conditions = []metav1.Condition{
+			{Type: api.ConditionSucceeded, Status: succeeded, Reason: reason},
+			{Type: api.ConditionReady, Status: ready, Reason: reason},
+		}
+	}
+
+	newBinding := func(name, bindingID string) *servicesv1.ServiceBinding {
+		return &servicesv1.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "app1"},
+			Spec:       servicesv1.ServiceBindingSpec{ServiceInstanceName: "my-instance", SecretName: name},
+			Status:     servicesv1.ServiceBindingStatus{BindingID: bindingID},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), LogKey{}, ctrl.Log)
+		testScheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+		Expect(servicesv1.AddToScheme(testScheme)).To(Succeed())
+		binding = newBinding("my-binding", "1234")
+		paused := newBinding("paused", "5678")
+		paused.Status.Conditions = []metav1.Condition{{Type: api.ConditionUpstreamMaintenance, Status: metav1.ConditionTrue, Reason: InstanceUpdate}}
+		instance = &servicesv1.ServiceInstance{ObjectMeta: metav1.ObjectMeta{Name: "my-instance", Namespace: "app1"}}
+		setInstanceConditions(instance, UpdateInProgress, metav1.ConditionTrue)
+		reconciler = &ServiceBindingReconciler{BaseReconciler: &BaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(binding, paused, newBinding("creating", "")).
+				WithStatusSubresource(binding).WithIndex(&servicesv1.ServiceBinding{}, bindingInstanceIndex, indexBindingInstance).Build(),
+			Scheme:   testScheme,
+			Log:      ctrl.Log,
+			Recorder: record.NewFakeRecorder(10),
+		}}
+	})
+
+	It("should pause the binding while the instance is updated and resume it once the instance is ready", func() {
+		paused, err := reconciler.pauseForUpstreamMaintenance(ctx, binding, instance)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paused).To(BeTrue())
+		updated := &servicesv1.ServiceBinding{}
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(binding), updated)).To(Succeed())
+		condition := meta.FindStatusCondition(updated.Status.Conditions, api.ConditionUpstreamMaintenance)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Reason).To(Equal(InstanceUpdate))
+
+		setInstanceConditions(instance, UpdateFailed, metav1.ConditionFalse)
+		paused, err = reconciler.pauseForUpstreamMaintenance(ctx, binding, instance)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paused).To(BeTrue())
+
+		setInstanceConditions(instance, Updated, metav1.ConditionTrue)
+		paused, err = reconciler.pauseForUpstreamMaintenance(ctx, binding, instance)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paused).To(BeFalse())
+		Expect(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(binding), updated)).To(Succeed())
+		Expect(meta.FindStatusCondition(updated.Status.Conditions, api.ConditionUpstreamMaintenance)).To(BeNil())
+	})
+
+	It("should not pause bindings of a not ready instance that is not updated", func() {
+		setInstanceConditions(instance, UpdateFailed, metav1.ConditionFalse)
+		paused, err := reconciler.pauseForUpstreamMaintenance(ctx, binding, instance)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paused).To(BeFalse())
+	})
+
+	It("should enqueue all created bindings of an instance entering a maintenance and the paused ones once it is ready", func() {
+		Expect(reconciler.maintainedBindingsOf(ctx, instance)).To(ConsistOf(
+			reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "app1", Name: "my-binding"}},
+			reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "app1", Name: "paused"}},
+		))
+
+		setInstanceConditions(instance, Updated, metav1.ConditionTrue)
+		Expect(reconciler.maintainedBindingsOf(ctx, instance)).To(ConsistOf(
+			reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "app1", Name: "paused"}},
+		))
+	})
+
+	It("should pass the updates of instances starting or finishing a maintenance only", func() {
+		ready := instance.DeepCopy()
+		setInstanceConditions(ready, Updated, metav1.ConditionTrue)
+		Expect(instanceMaintenanceChanged.Update(event.UpdateEvent{ObjectOld: ready, ObjectNew: instance})).To(BeTrue())
+		Expect(instanceMaintenanceChanged.Update(event.UpdateEvent{ObjectOld: instance, ObjectNew: ready})).To(BeTrue())
+		Expect(instanceMaintenanceChanged.Update(event.UpdateEvent{ObjectOld: ready, ObjectNew: ready})).To(BeFalse())
+		Expect(instanceMaintenanceChanged.Create(event.CreateEvent{Object: instance})).To(BeFalse())
+	})
+})
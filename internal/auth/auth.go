@@ -16,17 +16,41 @@ type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
-func NewAuthClient(ccConfig *clientcredentials.Config, sslDisabled bool) HTTPClient {
-	httpClient := httputil.BuildHTTPClient(sslDisabled)
-	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
-	return oauth2.NewClient(ctx, ccConfig.TokenSource(ctx))
+// NewAuthClient returns a client authenticated with the client credentials, onTokenRequest is called for each request
+// to the token endpoint if set
+func NewAuthClient(ccConfig *clientcredentials.Config, sslDisabled bool, onTokenRequest func()) HTTPClient {
+	return newAuthClient(ccConfig, httputil.BuildHTTPClient(sslDisabled), onTokenRequest)
 }
 
-func NewAuthClientWithTLS(ccConfig *clientcredentials.Config, tlsCertKey, tlsPrivateKey string) (HTTPClient, error) {
+// NewAuthClientWithTLS returns a client authenticated with the client certificate, onTokenRequest is called for each
+// request to the token endpoint if set
+func NewAuthClientWithTLS(ccConfig *clientcredentials.Config, tlsCertKey, tlsPrivateKey string, onTokenRequest func()) (HTTPClient, error) {
 	httpClient, err := httputil.BuildHTTPClientTLS(tlsCertKey, tlsPrivateKey)
 	if err != nil {
 		return nil, err
 	}
+	return newAuthClient(ccConfig, httpClient, onTokenRequest), nil
+}
+
+func newAuthClient(ccConfig *clientcredentials.Config, httpClient *http.Client, onTokenRequest func()) HTTPClient {
 	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
-	return oauth2.NewClient(ctx, ccConfig.TokenSource(ctx)), nil
+	tokenCtx := ctx
+	if onTokenRequest != nil {
+		// the token requests are counted apart from the requests sent with the token
+		tokenClient := *httpClient
+		tokenClient.Transport = &requestHook{base: httpClient.Transport, onRequest: onTokenRequest}
+		tokenCtx = context.WithValue(context.Background(), oauth2.HTTPClient, &tokenClient)
+	}
+	return oauth2.NewClient(ctx, ccConfig.TokenSource(tokenCtx))
+}
+
+// requestHook calls onRequest before sending each request
+type requestHook struct {
+	base      http.RoundTripper
+	onRequest func()
+}
+
+func (h *requestHook) RoundTrip(req *http.Request) (*http.Response, error) {
+	h.onRequest()
+	return h.base.RoundTrip(req)
 }
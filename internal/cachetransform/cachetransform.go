@@ -0,0 +1,51 @@
+// Package cachetransform reduces the memory footprint of the informer cache of the manager by dropping
+// the parts of cached objects the operator never reads.
+package cachetransform
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// LastAppliedConfigAnnotation is the annotation kubectl apply stores the applied object in
+const LastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// Apply configures the cache options to strip the managed fields of all cached objects and, in addition, the last
+// applied configuration of secrets. The last applied configuration of the custom resources is kept, the controllers
+// update them from the cache and would otherwise remove it from the objects of kubectl apply and GitOps tools.
+func Apply(opts cache.Options) cache.Options {
+	opts.DefaultTransform = StripManagedFields
+	if opts.ByObject == nil {
+		opts.ByObject = make(map[client.Object]cache.ByObject)
+	}
+	opts.ByObject[&corev1.Secret{}] = cache.ByObject{Transform: StripSecret}
+	return opts
+}
+
+// StripManagedFields drops the managed fields of an object, which are often larger than the object itself
+func StripManagedFields(obj interface{}) (interface{}, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		// tombstones of deleted objects are stored as they are
+		return obj, nil
+	}
+	accessor.SetManagedFields(nil)
+	return obj, nil
+}
+
+// StripSecret drops the managed fields and the last applied configuration of a secret, the latter holds
+// a copy of the data of secrets created with kubectl apply
+func StripSecret(obj interface{}) (interface{}, error) {
+	obj, _ = StripManagedFields(obj)
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return obj, nil
+	}
+	if annotations := accessor.GetAnnotations(); len(annotations[LastAppliedConfigAnnotation]) > 0 {
+		delete(annotations, LastAppliedConfigAnnotation)
+		accessor.SetAnnotations(annotations)
+	}
+	return obj, nil
+}
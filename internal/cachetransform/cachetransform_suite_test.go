@@ -0,0 +1,13 @@
+package cachetransform
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCacheTransform(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Cache Transform Suite")
+}
@@ -0,0 +1,56 @@
+package cachetransform
+
+import (
+	v1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+var _ = Describe("Cache transform", func() {
+	objectMeta := func() metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:          "name",
+			Annotations:   map[string]string{LastAppliedConfigAnnotation: `{"kind": "Secret"}`, "other": "value"},
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationApply}},
+		}
+	}
+
+	It("should strip the managed fields of custom resources and keep their annotations", func() {
+		instance := &v1.ServiceInstance{ObjectMeta: objectMeta()}
+		obj, err := StripManagedFields(instance)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(obj.(*v1.ServiceInstance).ManagedFields).To(BeNil())
+		Expect(obj.(*v1.ServiceInstance).Annotations).To(HaveKey(LastAppliedConfigAnnotation))
+	})
+
+	It("should strip the managed fields and the last applied configuration of secrets", func() {
+		secret := &corev1.Secret{ObjectMeta: objectMeta(), Data: map[string][]byte{"key": []byte("value")}}
+		obj, err := StripSecret(secret)
+		Expect(err).ToNot(HaveOccurred())
+		stripped := obj.(*corev1.Secret)
+		Expect(stripped.ManagedFields).To(BeNil())
+		Expect(stripped.Annotations).To(Equal(map[string]string{"other": "value"}))
+		Expect(stripped.Data).To(HaveKey("key"))
+	})
+
+	It("should store tombstones as they are", func() {
+		tombstone := toolscache.DeletedFinalStateUnknown{Key: "default/name"}
+		obj, err := StripSecret(tombstone)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(obj).To(Equal(tombstone))
+	})
+
+	It("should configure the transforms of the cache", func() {
+		opts := Apply(cache.Options{})
+		Expect(opts.DefaultTransform).ToNot(BeNil())
+		Expect(opts.ByObject).To(HaveLen(1))
+		for obj, byObject := range opts.ByObject {
+			Expect(obj).To(BeAssignableToTypeOf(&corev1.Secret{}))
+			Expect(byObject.Transform).ToNot(BeNil())
+		}
+	})
+})
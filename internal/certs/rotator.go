@@ -0,0 +1,307 @@
+package certs
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// CACertKey and CAKeyKey hold the CA in the webhook certificate secret, next to tls.crt and tls.key
+	CACertKey = "ca.crt"
+	CAKeyKey  = "ca.key"
+	// PreviousCACertKey holds the rotated CA until it expires, it stays in the caBundle for the certificates it signed
+	PreviousCACertKey = "previous-ca.crt"
+
+	// dataLink links to the directory with the current certificate files in the certificate directory
+	dataLink = "..data"
+)
+
+// Rotator generates the webhook serving certificate and rotates it before it expires, without cert-manager.
+// The certificate is shared by all replicas through a secret and written by every replica to its webhook
+// certificate directory, the CA is injected into the caBundle of the webhook configurations. A rotated CA stays in the
+// caBundle until it expires, since the other replicas serve the certificate it signed until their next check.
+type Rotator struct {
+	Client                   client.Client
+	Log                      logr.Logger
+	SecretKey                types.NamespacedName
+	CertDir                  string
+	DNSNames                 []string
+	MutatingWebhookConfigs   []string
+	ValidatingWebhookConfigs []string
+	CAValidity               time.Duration
+	CertValidity             time.Duration
+	// RefreshBefore is how long before the expiration a certificate is rotated
+	RefreshBefore time.Duration
+	CheckInterval time.Duration
+
+	now func() time.Time
+}
+
+// Start checks the certificate every CheckInterval, it runs on all replicas so each of them refreshes its certificate files
+func (r *Rotator) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.EnsureCertificate(ctx); err != nil {
+				r.Log.Error(err, "failed to ensure webhook certificate")
+			}
+		}
+	}
+}
+
+func (r *Rotator) NeedLeaderElection() bool {
+	return false
+}
+
+// EnsureCertificate rotates the certificate in the secret if required, writes it to the certificate directory
+// and injects the CA into the webhook configurations
+func (r *Rotator) EnsureCertificate(ctx context.Context) error {
+	secret := &corev1.Secret{}
+	exists := true
+	if err := r.Client.Get(ctx, r.SecretKey, secret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		exists = false
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: r.SecretKey.Name, Namespace: r.SecretKey.Namespace},
+			Type:       corev1.SecretTypeTLS,
+		}
+	}
+
+	if reason := r.rotationReason(secret); len(reason) > 0 {
+		r.Log.Info("rotating webhook certificate", "reason", reason)
+		if err := r.rotate(secret); err != nil {
+			return err
+		}
+
+		var err error
+		if exists {
+			err = r.Client.Update(ctx, secret)
+		} else {
+			err = r.Client.Create(ctx, secret)
+		}
+		if apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err) {
+			// another replica rotated the certificate in the meantime
+			r.Log.Info("webhook certificate was rotated concurrently, using the rotated certificate")
+			return r.EnsureCertificate(ctx)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// the CA is trusted before the certificate it signed is served
+	if err := r.injectCABundle(ctx, r.caBundle(secret)); err != nil {
+		return err
+	}
+	return r.writeCertFiles(secret)
+}
+
+// caBundle returns the CA of the secret, followed by the previous CA if it did not expire yet
+func (r *Rotator) caBundle(secret *corev1.Secret) []byte {
+	caBundle := secret.Data[CACertKey]
+	if previous, err := ParseCert(secret.Data[PreviousCACertKey]); err == nil && r.currentTime().Before(previous.NotAfter) {
+		caBundle = append(append([]byte{}, caBundle...), secret.Data[PreviousCACertKey]...)
+	}
+	return caBundle
+}
+
+// rotationReason returns why the certificate in the secret has to be rotated, or an empty string if it is valid
+func (r *Rotator) rotationReason(secret *corev1.Secret) string {
+	caCert, err := ParseCert(secret.Data[CACertKey])
+	if err != nil {
+		return "CA is missing or invalid"
+	}
+	if r.expiresSoon(caCert.NotAfter) {
+		return "CA expires soon"
+	}
+	cert, err := ParseCert(secret.Data[corev1.TLSCertKey])
+	if err != nil || len(secret.Data[corev1.TLSPrivateKeyKey]) == 0 {
+		return "certificate is missing or invalid"
+	}
+	if r.expiresSoon(cert.NotAfter) {
+		return "certificate expires soon"
+	}
+	if cert.CheckSignatureFrom(caCert) != nil {
+		return "certificate is not signed by the CA"
+	}
+	if !reflect.DeepEqual(cert.DNSNames, r.DNSNames) {
+		return "DNS names changed"
+	}
+	return ""
+}
+
+// rotate replaces the serving certificate in the secret, the CA is kept unless it expires soon
+// so that the caBundle of the webhook configurations stays valid for the new certificate.
+// A replaced CA is kept as the previous CA while it is valid.
+func (r *Rotator) rotate(secret *corev1.Secret) error {
+	now := r.currentTime()
+	ca := &KeyPair{Cert: secret.Data[CACertKey], Key: secret.Data[CAKeyKey]}
+	previousCA := secret.Data[PreviousCACertKey]
+	if caCert, err := ParseCert(ca.Cert); err != nil || r.expiresSoon(caCert.NotAfter) {
+		previousCA = nil
+		if err == nil && now.Before(caCert.NotAfter) {
+			previousCA = ca.Cert
+		}
+		if ca, err = GenerateCA(now, now.Add(r.CAValidity)); err != nil {
+			return err
+		}
+	}
+
+	cert, err := GenerateServingCert(ca, r.DNSNames, now, now.Add(r.CertValidity))
+	if err != nil {
+		return err
+	}
+	secret.Data = map[string][]byte{
+		CACertKey:               ca.Cert,
+		CAKeyKey:                ca.Key,
+		corev1.TLSCertKey:       cert.Cert,
+		corev1.TLSPrivateKeyKey: cert.Key,
+	}
+	if previous, err := ParseCert(previousCA); err == nil && now.Before(previous.NotAfter) {
+		secret.Data[PreviousCACertKey] = previousCA
+	}
+	return nil
+}
+
+// writeCertFiles writes the key and certificate to the certificate directory if they changed, the webhook server
+// watches the files and reloads the certificate. Like in the secret volumes of the kubelet, the files link to a data
+// directory which is replaced in one rename, so that the webhook server never reads the key of one certificate with
+// another certificate.
+func (r *Rotator) writeCertFiles(secret *corev1.Secret) error {
+	if err := os.MkdirAll(r.CertDir, 0700); err != nil {
+		return err
+	}
+	keys := []string{corev1.TLSPrivateKeyKey, corev1.TLSCertKey}
+	current := true
+	for _, key := range keys {
+		if data, err := os.ReadFile(filepath.Join(r.CertDir, key)); err != nil || !bytes.Equal(data, secret.Data[key]) {
+			current = false
+		}
+	}
+	if current {
+		return nil
+	}
+
+	dataDir, err := os.MkdirTemp(r.CertDir, "..cert-")
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := os.WriteFile(filepath.Join(dataDir, key), secret.Data[key], 0600); err != nil {
+			return err
+		}
+	}
+	previousDataDir, _ := os.Readlink(filepath.Join(r.CertDir, dataLink))
+	if err := replaceSymlink(filepath.Base(dataDir), filepath.Join(r.CertDir, dataLink)); err != nil {
+		return err
+	}
+	// the files are linked once, later rotations only replace the data link
+	for _, key := range keys {
+		path, target := filepath.Join(r.CertDir, key), filepath.Join(dataLink, key)
+		if linked, err := os.Readlink(path); err == nil && linked == target {
+			continue
+		}
+		if err := replaceSymlink(target, path); err != nil {
+			return err
+		}
+	}
+	if len(previousDataDir) > 0 {
+		if err := os.RemoveAll(filepath.Join(r.CertDir, previousDataDir)); err != nil {
+			return err
+		}
+	}
+	r.Log.Info("updated webhook certificate files", "dir", r.CertDir)
+	return nil
+}
+
+// replaceSymlink atomically replaces path with a symbolic link to target
+func replaceSymlink(target, path string) error {
+	tmp := path + ".tmp"
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (r *Rotator) injectCABundle(ctx context.Context, caBundle []byte) error {
+	for _, name := range r.MutatingWebhookConfigs {
+		config := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Name: name}, config); err != nil {
+			if apierrors.IsNotFound(err) {
+				r.Log.Info("mutating webhook configuration not found, skipping CA injection", "name", name)
+				continue
+			}
+			return err
+		}
+		changed := false
+		for i := range config.Webhooks {
+			if !bytes.Equal(config.Webhooks[i].ClientConfig.CABundle, caBundle) {
+				config.Webhooks[i].ClientConfig.CABundle = caBundle
+				changed = true
+			}
+		}
+		if changed {
+			r.Log.Info("injecting CA into mutating webhook configuration", "name", name)
+			if err := r.Client.Update(ctx, config); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, name := range r.ValidatingWebhookConfigs {
+		config := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Name: name}, config); err != nil {
+			if apierrors.IsNotFound(err) {
+				r.Log.Info("validating webhook configuration not found, skipping CA injection", "name", name)
+				continue
+			}
+			return err
+		}
+		changed := false
+		for i := range config.Webhooks {
+			if !bytes.Equal(config.Webhooks[i].ClientConfig.CABundle, caBundle) {
+				config.Webhooks[i].ClientConfig.CABundle = caBundle
+				changed = true
+			}
+		}
+		if changed {
+			r.Log.Info("injecting CA into validating webhook configuration", "name", name)
+			if err := r.Client.Update(ctx, config); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Rotator) expiresSoon(notAfter time.Time) bool {
+	return r.currentTime().Add(r.RefreshBefore).After(notAfter)
+}
+
+func (r *Rotator) currentTime() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
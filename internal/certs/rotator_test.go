@@ -0,0 +1,190 @@
+package certs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Rotator", func() {
+	var (
+		ctx     context.Context
+		cl      client.Client
+		rotator *Rotator
+		now     time.Time
+	)
+
+	secretKey := types.NamespacedName{Namespace: "sap-btp-operator", Name: "webhook-server-cert"}
+
+	getSecret := func() *corev1.Secret {
+		secret := &corev1.Secret{}
+		Expect(cl.Get(ctx, secretKey, secret)).To(Succeed())
+		return secret
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Now()
+		cl = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Name: "mutating"},
+				Webhooks:   []admissionregistrationv1.MutatingWebhook{{Name: "m1.kb.io"}, {Name: "m2.kb.io"}},
+			},
+			&admissionregistrationv1.ValidatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Name: "validating"},
+				Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "v1.kb.io"}},
+			},
+		).Build()
+
+		certDir, err := os.MkdirTemp("", "serving-certs")
+		Expect(err).ToNot(HaveOccurred())
+		rotator = &Rotator{
+			Client:                   cl,
+			Log:                      logr.Discard(),
+			SecretKey:                secretKey,
+			CertDir:                  certDir,
+			DNSNames:                 []string{"webhook-service.sap-btp-operator.svc"},
+			MutatingWebhookConfigs:   []string{"mutating"},
+			ValidatingWebhookConfigs: []string{"validating", "missing"},
+			CAValidity:               10 * 365 * 24 * time.Hour,
+			CertValidity:             365 * 24 * time.Hour,
+			RefreshBefore:            30 * 24 * time.Hour,
+			now:                      func() time.Time { return now },
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(rotator.CertDir)).To(Succeed())
+	})
+
+	It("should create the certificate, write the files and inject the CA", func() {
+		Expect(rotator.EnsureCertificate(ctx)).To(Succeed())
+
+		secret := getSecret()
+		cert, err := ParseCert(secret.Data[corev1.TLSCertKey])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cert.DNSNames).To(Equal(rotator.DNSNames))
+		caCert, err := ParseCert(secret.Data[CACertKey])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cert.CheckSignatureFrom(caCert)).To(Succeed())
+
+		certFile, err := os.ReadFile(filepath.Join(rotator.CertDir, corev1.TLSCertKey))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(certFile).To(Equal(secret.Data[corev1.TLSCertKey]))
+		keyFile, err := os.ReadFile(filepath.Join(rotator.CertDir, corev1.TLSPrivateKeyKey))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(keyFile).To(Equal(secret.Data[corev1.TLSPrivateKeyKey]))
+
+		mutating := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		Expect(cl.Get(ctx, types.NamespacedName{Name: "mutating"}, mutating)).To(Succeed())
+		for _, webhook := range mutating.Webhooks {
+			Expect(webhook.ClientConfig.CABundle).To(Equal(secret.Data[CACertKey]))
+		}
+		validating := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		Expect(cl.Get(ctx, types.NamespacedName{Name: "validating"}, validating)).To(Succeed())
+		Expect(validating.Webhooks[0].ClientConfig.CABundle).To(Equal(secret.Data[CACertKey]))
+	})
+
+	It("should keep a valid certificate", func() {
+		Expect(rotator.EnsureCertificate(ctx)).To(Succeed())
+		secret := getSecret()
+
+		Expect(rotator.EnsureCertificate(ctx)).To(Succeed())
+		Expect(getSecret().Data).To(Equal(secret.Data))
+	})
+
+	It("should rotate the certificate before it expires and keep the CA", func() {
+		Expect(rotator.EnsureCertificate(ctx)).To(Succeed())
+		secret := getSecret()
+
+		now = now.Add(rotator.CertValidity - rotator.RefreshBefore + time.Hour)
+		Expect(rotator.EnsureCertificate(ctx)).To(Succeed())
+		rotated := getSecret()
+		Expect(rotated.Data[corev1.TLSCertKey]).ToNot(Equal(secret.Data[corev1.TLSCertKey]))
+		Expect(rotated.Data[CACertKey]).To(Equal(secret.Data[CACertKey]))
+
+		certFile, err := os.ReadFile(filepath.Join(rotator.CertDir, corev1.TLSCertKey))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(certFile).To(Equal(rotated.Data[corev1.TLSCertKey]))
+	})
+
+	It("should rotate the CA before it expires", func() {
+		Expect(rotator.EnsureCertificate(ctx)).To(Succeed())
+		secret := getSecret()
+
+		now = now.Add(rotator.CAValidity - rotator.RefreshBefore + time.Hour)
+		Expect(rotator.EnsureCertificate(ctx)).To(Succeed())
+		rotated := getSecret()
+		Expect(rotated.Data[CACertKey]).ToNot(Equal(secret.Data[CACertKey]))
+
+		Expect(rotated.Data[PreviousCACertKey]).To(Equal(secret.Data[CACertKey]))
+
+		// the certificates of the other replicas are signed by the previous CA until they refresh their files
+		caBundle := append(append([]byte{}, rotated.Data[CACertKey]...), secret.Data[CACertKey]...)
+		mutating := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		Expect(cl.Get(ctx, types.NamespacedName{Name: "mutating"}, mutating)).To(Succeed())
+		Expect(mutating.Webhooks[0].ClientConfig.CABundle).To(Equal(caBundle))
+		validating := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		Expect(cl.Get(ctx, types.NamespacedName{Name: "validating"}, validating)).To(Succeed())
+		Expect(validating.Webhooks[0].ClientConfig.CABundle).To(Equal(caBundle))
+	})
+
+	It("should drop the previous CA from the bundle once it expired", func() {
+		Expect(rotator.EnsureCertificate(ctx)).To(Succeed())
+		now = now.Add(rotator.CAValidity - rotator.RefreshBefore + time.Hour)
+		Expect(rotator.EnsureCertificate(ctx)).To(Succeed())
+
+		now = now.Add(rotator.RefreshBefore)
+		Expect(rotator.EnsureCertificate(ctx)).To(Succeed())
+		mutating := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		Expect(cl.Get(ctx, types.NamespacedName{Name: "mutating"}, mutating)).To(Succeed())
+		Expect(mutating.Webhooks[0].ClientConfig.CABundle).To(Equal(getSecret().Data[CACertKey]))
+
+		// the expired CA is not carried over by the next rotation
+		now = now.Add(rotator.CertValidity)
+		Expect(rotator.EnsureCertificate(ctx)).To(Succeed())
+		Expect(getSecret().Data).ToNot(HaveKey(PreviousCACertKey))
+	})
+
+	It("should replace the key and certificate files together", func() {
+		Expect(rotator.EnsureCertificate(ctx)).To(Succeed())
+		dataDir, err := os.Readlink(filepath.Join(rotator.CertDir, dataLink))
+		Expect(err).ToNot(HaveOccurred())
+		for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+			Expect(os.Readlink(filepath.Join(rotator.CertDir, key))).To(Equal(filepath.Join(dataLink, key)))
+		}
+
+		now = now.Add(rotator.CertValidity - rotator.RefreshBefore + time.Hour)
+		Expect(rotator.EnsureCertificate(ctx)).To(Succeed())
+		rotatedDataDir, err := os.Readlink(filepath.Join(rotator.CertDir, dataLink))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rotatedDataDir).ToNot(Equal(dataDir))
+		_, err = os.Stat(filepath.Join(rotator.CertDir, dataDir))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+		keyFile, err := os.ReadFile(filepath.Join(rotator.CertDir, corev1.TLSPrivateKeyKey))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(keyFile).To(Equal(getSecret().Data[corev1.TLSPrivateKeyKey]))
+	})
+
+	It("should rotate the certificate when the DNS names change", func() {
+		Expect(rotator.EnsureCertificate(ctx)).To(Succeed())
+
+		rotator.DNSNames = append(rotator.DNSNames, "webhook-service.sap-btp-operator.svc.cluster.local")
+		Expect(rotator.EnsureCertificate(ctx)).To(Succeed())
+		cert, err := ParseCert(getSecret().Data[corev1.TLSCertKey])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cert.DNSNames).To(Equal(rotator.DNSNames))
+	})
+})
@@ -13,30 +13,121 @@ var (
 )
 
 type Config struct {
-	SyncPeriod             time.Duration `envconfig:"sync_period"`
-	PollInterval           time.Duration `envconfig:"poll_interval"`
-	LongPollInterval       time.Duration `envconfig:"long_poll_interval"`
-	ManagementNamespace    string        `envconfig:"management_namespace"`
-	ReleaseNamespace       string        `envconfig:"release_namespace"`
-	AllowClusterAccess     bool          `envconfig:"allow_cluster_access"`
-	AllowedNamespaces      []string      `envconfig:"allowed_namespaces"`
-	EnableNamespaceSecrets bool          `envconfig:"enable_namespace_secrets"`
-	ClusterID              string        `envconfig:"cluster_id"`
-	RetryBaseDelay         time.Duration `envconfig:"retry_base_delay"`
-	RetryMaxDelay          time.Duration `envconfig:"retry_max_delay"`
+	SyncPeriod               time.Duration `envconfig:"sync_period"`
+	PollInterval             time.Duration `envconfig:"poll_interval"`
+	LongPollInterval         time.Duration `envconfig:"long_poll_interval"`
+	ManagementNamespace      string        `envconfig:"management_namespace"`
+	ReleaseNamespace         string        `envconfig:"release_namespace"`
+	AllowClusterAccess       bool          `envconfig:"allow_cluster_access"`
+	AllowedNamespaces        []string      `envconfig:"allowed_namespaces"`
+	EnableNamespaceSecrets   bool          `envconfig:"enable_namespace_secrets"`
+	ClusterID                string        `envconfig:"cluster_id"`
+	RetryBaseDelay           time.Duration `envconfig:"retry_base_delay"`
+	RetryMaxDelay            time.Duration `envconfig:"retry_max_delay"`
+	EnableDashboard          bool          `envconfig:"enable_dashboard"`
+	SummaryEventInterval     time.Duration `envconfig:"summary_event_interval"`
+	ParametersFromValidation string        `envconfig:"parameters_from_validation"`
+	MaintenanceCheckInterval time.Duration `envconfig:"maintenance_check_interval"`
+	DriftCheckInterval       time.Duration `envconfig:"drift_check_interval"`
+	RotationFrequencyGuard   bool          `envconfig:"rotation_frequency_guard"`
+	SecretTemplateDryRun     bool          `envconfig:"secret_template_dry_run"`
+	PollDescriptionInterval  time.Duration `envconfig:"poll_description_interval"`
+	CredentialsDir           string        `envconfig:"credentials_dir"`
+	ManageWebhookCerts       bool          `envconfig:"manage_webhook_certs"`
+	StagedStartup            bool          `envconfig:"staged_startup"`
+	StartupWindow            time.Duration `envconfig:"startup_window"`
+	StrictSecretErrors       bool          `envconfig:"strict_secret_errors"`
+	SecretRetryBudget        int           `envconfig:"secret_retry_budget"`
+	TransientSecretErrors    []string      `envconfig:"transient_secret_errors"`
+	SMLabels                 SMLabels      `envconfig:"sm_labels"`
+	ExpirationWarningPeriod  time.Duration `envconfig:"expiration_warning_period"`
+	BindingSecretsNamespace  string        `envconfig:"binding_secrets_namespace"`
+	FeatureGates             FeatureGates  `envconfig:"feature_gates"`
+	AdoptionRunNamespaces    []string      `envconfig:"adoption_run_namespaces"`
+	UnbindRetryLimit         int           `envconfig:"unbind_retry_limit"`
+	UnbindFailurePolicy      string        `envconfig:"unbind_failure_policy"`
+	BindConcurrency          int           `envconfig:"bind_concurrency_per_instance"`
+	SMCallQuota              int           `envconfig:"sm_call_quota"`
+	SMCallQuotaWindow        time.Duration `envconfig:"sm_call_quota_window"`
+	CacheTransform           bool          `envconfig:"cache_transform"`
+	DisableSecretsCache      bool          `envconfig:"disable_secrets_cache"`
+	SecretPresets            SecretPresets `envconfig:"secret_presets"`
+	CatalogCacheTTL          time.Duration `envconfig:"catalog_cache_ttl"`
+	CatalogSyncInterval      time.Duration `envconfig:"catalog_sync_interval"`
+	RotationMaxDefer         time.Duration `envconfig:"rotation_max_defer"`
+	PolicyURL                string        `envconfig:"policy_webhook_url"`
+	PolicyTimeout            time.Duration `envconfig:"policy_webhook_timeout"`
+	PolicyFailurePolicy      string        `envconfig:"policy_webhook_failure_policy"`
+	TelemetryInterval        time.Duration `envconfig:"reconcile_telemetry_interval"`
+	OperationStore           string        `envconfig:"operation_store"`
+	TokenURLCheck            bool          `envconfig:"token_url_check"`
+	StrictOwnership          bool          `envconfig:"strict_ownership"`
+	BindResourceOfferings    []string      `envconfig:"bind_resource_offerings"`
+	SLOReportInterval        time.Duration `envconfig:"slo_report_interval"`
+	SLOWindow                time.Duration `envconfig:"slo_window"`
+	NamespaceStatusInterval  time.Duration `envconfig:"namespace_status_interval"`
+	SMCatalogTimeout         time.Duration `envconfig:"sm_catalog_timeout"`
+	SMProvisionTimeout       time.Duration `envconfig:"sm_provision_timeout"`
+	SMPollTimeout            time.Duration `envconfig:"sm_poll_timeout"`
+	SMBindTimeout            time.Duration `envconfig:"sm_bind_timeout"`
+	SMRequestTimeout         time.Duration `envconfig:"sm_request_timeout"`
+	EscrowVaultAddress       string        `envconfig:"escrow_vault_address"`
+	EscrowVaultMount         string        `envconfig:"escrow_vault_mount"`
+	EscrowVaultPath          string        `envconfig:"escrow_vault_path"`
+	EscrowVaultTokenFile     string        `envconfig:"escrow_vault_token_file"`
+	EscrowTTL                time.Duration `envconfig:"escrow_ttl"`
+	EscrowTimeout            time.Duration `envconfig:"escrow_timeout"`
+	CertExpiryScanInterval   time.Duration `envconfig:"cert_expiry_scan_interval"`
+	CertExpiryWindow         time.Duration `envconfig:"cert_expiry_window"`
+	CredentialsVaultAddress  string        `envconfig:"credentials_vault_address"`
+	CredentialsVaultAudience string        `envconfig:"credentials_vault_audience"`
+	CredentialsVaultTimeout  time.Duration `envconfig:"credentials_vault_timeout"`
+	ParametersFromResources  []string      `envconfig:"parameters_from_resources"`
+	ParametersMaxSize        int           `envconfig:"parameters_max_size"`
+	ParametersMaxDepth       int           `envconfig:"parameters_max_depth"`
 }
 
 func Get() Config {
 	loadOnce.Do(func() {
 		config = Config{ // default values
-			SyncPeriod:             60 * time.Second,
-			PollInterval:           10 * time.Second,
-			LongPollInterval:       5 * time.Minute,
-			EnableNamespaceSecrets: true,
-			AllowedNamespaces:      []string{},
-			AllowClusterAccess:     true,
-			RetryBaseDelay:         10 * time.Second,
-			RetryMaxDelay:          time.Hour,
+			SyncPeriod:               60 * time.Second,
+			PollInterval:             10 * time.Second,
+			LongPollInterval:         5 * time.Minute,
+			EnableNamespaceSecrets:   true,
+			AllowedNamespaces:        []string{},
+			AllowClusterAccess:       true,
+			RetryBaseDelay:           10 * time.Second,
+			RetryMaxDelay:            time.Hour,
+			ParametersFromValidation: "warn",
+			MaintenanceCheckInterval: time.Hour,
+			DriftCheckInterval:       10 * time.Minute,
+			PollDescriptionInterval:  2 * time.Minute,
+			StartupWindow:            10 * time.Minute,
+			ExpirationWarningPeriod:  24 * time.Hour,
+			SMCallQuotaWindow:        time.Minute,
+			CacheTransform:           true,
+			UnbindRetryLimit:         5,
+			UnbindFailurePolicy:      "Retry",
+			CatalogCacheTTL:          5 * time.Minute,
+			PolicyTimeout:            5 * time.Second,
+			PolicyFailurePolicy:      "Fail",
+			RotationMaxDefer:         24 * time.Hour,
+			TelemetryInterval:        5 * time.Minute,
+			OperationStore:           "status",
+			SLOWindow:                24 * time.Hour,
+			SMCatalogTimeout:         30 * time.Second,
+			SMProvisionTimeout:       60 * time.Second,
+			SMPollTimeout:            30 * time.Second,
+			SMBindTimeout:            60 * time.Second,
+			SMRequestTimeout:         60 * time.Second,
+			EscrowVaultMount:         "secret",
+			EscrowVaultPath:          "sap-btp-service-operator",
+			EscrowTTL:                7 * 24 * time.Hour,
+			EscrowTimeout:            10 * time.Second,
+			CertExpiryWindow:         30 * 24 * time.Hour,
+			CredentialsVaultTimeout:  10 * time.Second,
+			ParametersMaxSize:        64 * 1024,
+			ParametersMaxDepth:       32,
 		}
 		envconfig.MustProcess("", &config)
 	})
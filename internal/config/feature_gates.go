@@ -0,0 +1,137 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Feature is the name of a behavior of the operator that can be switched on or off by a feature gate
+type Feature string
+
+// FeatureStage is the maturity of a feature, it determines whether the feature is enabled by default
+type FeatureStage string
+
+const (
+	Alpha FeatureStage = "Alpha"
+	Beta  FeatureStage = "Beta"
+	GA    FeatureStage = "GA"
+)
+
+const (
+	// DriftDetection compares instances with enforcementMode Enforce or Warn periodically with their state in SM
+	DriftDetection Feature = "DriftDetection"
+	// SecretFormatters shapes binding secrets using the formatter of .spec.secretFormat or the one registered for the offering
+	SecretFormatters Feature = "SecretFormatters"
+	// InstanceExpiration warns about and deletes instances with .spec.expiresAt
+	InstanceExpiration Feature = "InstanceExpiration"
+	// Adoption adopts existing resources of SM by the ID in the drResourceID annotation of imported resources
+	Adoption Feature = "Adoption"
+	// ClusterIDOverride labels and recovers resources with the cluster ID in their .spec.clusterIDOverride
+	ClusterIDOverride Feature = "ClusterIDOverride"
+	// CatalogPreflight resolves the offering and plan of instances in the catalog of SM before provisioning them
+	CatalogPreflight Feature = "CatalogPreflight"
+	// ParametersSchemaValidation validates the parameters against the schemas of the plan before sending them to SM
+	ParametersSchemaValidation Feature = "ParametersSchemaValidation"
+	// GitOpsHints annotates instances, bindings and binding secrets to order their deletion by GitOps engines
+	GitOpsHints Feature = "GitOpsHints"
+	// CredentialsEscrow stores the credentials of rotated bindings in the escrow before deleting them
+	CredentialsEscrow Feature = "CredentialsEscrow"
+	// BindingInjection injects the binding secrets of BindingInjection resources into pods
+	BindingInjection Feature = "BindingInjection"
+	// VaultCredentials writes the credentials of bindings with .spec.credentialsTarget.vault to Vault
+	VaultCredentials Feature = "VaultCredentials"
+	// CSICredentials serves the credentials of bindings with .spec.credentialsTarget.csi to Secrets Store CSI volumes
+	CSICredentials Feature = "CSICredentials"
+	// ImagePullServiceAccounts references binding secrets in the imagePullSecrets of the service accounts of
+	// .spec.imagePullServiceAccounts
+	ImagePullServiceAccounts Feature = "ImagePullServiceAccounts"
+)
+
+// FeatureSpec describes the default of a feature gate
+type FeatureSpec struct {
+	Default bool
+	Stage   FeatureStage
+}
+
+// knownFeatures lists all feature gates of the operator. Features enabled by default take effect only on resources
+// that opt in, e.g. with enforcementMode or expiresAt. Features that change existing resources, e.g. the keys of
+// binding secrets, are disabled by default, so that upgrading the operator does not change the resources.
+var knownFeatures = map[Feature]FeatureSpec{
+	DriftDetection:             {Default: true, Stage: Beta},
+	SecretFormatters:           {Default: false, Stage: Alpha},
+	InstanceExpiration:         {Default: true, Stage: Beta},
+	Adoption:                   {Default: false, Stage: Alpha},
+	ClusterIDOverride:          {Default: false, Stage: Alpha},
+	CatalogPreflight:           {Default: false, Stage: Alpha},
+	ParametersSchemaValidation: {Default: false, Stage: Alpha},
+	GitOpsHints:                {Default: false, Stage: Alpha},
+	CredentialsEscrow:          {Default: false, Stage: Alpha},
+	BindingInjection:           {Default: false, Stage: Alpha},
+	VaultCredentials:           {Default: false, Stage: Alpha},
+	CSICredentials:             {Default: false, Stage: Alpha},
+	ImagePullServiceAccounts:   {Default: false, Stage: Alpha},
+}
+
+// FeatureGates holds the feature gates overridden in the configuration, e.g. "DriftDetection=false,InstanceExpiration=true".
+// Unknown gates are kept aside and ignored, so that a configuration written for a newer version of the operator
+// does not prevent a rollback.
+type FeatureGates struct {
+	overrides map[Feature]bool
+	Unknown   []string
+}
+
+// Decode implements envconfig.Decoder
+func (g *FeatureGates) Decode(value string) error {
+	g.overrides = map[Feature]bool{}
+	g.Unknown = nil
+	for _, gate := range strings.Split(value, ",") {
+		gate = strings.TrimSpace(gate)
+		if len(gate) == 0 {
+			continue
+		}
+		name, enabled, found := strings.Cut(gate, "=")
+		if !found {
+			return fmt.Errorf("invalid feature gate '%s', expected <name>=<true|false>", gate)
+		}
+		feature := Feature(strings.TrimSpace(name))
+		isEnabled, err := strconv.ParseBool(strings.TrimSpace(enabled))
+		if err != nil {
+			return fmt.Errorf("invalid value of feature gate '%s': %s", feature, err.Error())
+		}
+		if _, ok := knownFeatures[feature]; !ok {
+			g.Unknown = append(g.Unknown, string(feature))
+			continue
+		}
+		g.overrides[feature] = isEnabled
+	}
+	return nil
+}
+
+// Enabled checks whether the feature is enabled, by its override or its default
+func (g FeatureGates) Enabled(feature Feature) bool {
+	if enabled, ok := g.overrides[feature]; ok {
+		return enabled
+	}
+	return knownFeatures[feature].Default
+}
+
+// KnownFeatures returns the specs of all feature gates of the operator by name
+func KnownFeatures() map[Feature]FeatureSpec {
+	features := make(map[Feature]FeatureSpec, len(knownFeatures))
+	for feature, spec := range knownFeatures {
+		features[feature] = spec
+	}
+	return features
+}
+
+// String lists the state of all feature gates, sorted by name
+func (g FeatureGates) String() string {
+	gates := make([]string, 0, len(knownFeatures))
+	for feature := range knownFeatures {
+		gates = append(gates, fmt.Sprintf("%s=%t", feature, g.Enabled(feature)))
+	}
+	sort.Strings(gates)
+	return strings.Join(gates, ",")
+}
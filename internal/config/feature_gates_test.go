@@ -0,0 +1,39 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Feature gates", func() {
+	It("should enable the features by their defaults", func() {
+		gates := FeatureGates{}
+		for feature, spec := range KnownFeatures() {
+			Expect(gates.Enabled(feature)).To(Equal(spec.Default))
+		}
+		Expect(gates.String()).To(Equal("Adoption=false,BindingInjection=false,CSICredentials=false,CatalogPreflight=false,ClusterIDOverride=false,CredentialsEscrow=false,DriftDetection=true,GitOpsHints=false,ImagePullServiceAccounts=false,InstanceExpiration=true,ParametersSchemaValidation=false,SecretFormatters=false,VaultCredentials=false"))
+	})
+
+	It("should override the defaults", func() {
+		gates := FeatureGates{}
+		Expect(gates.Decode(" DriftDetection=false, InstanceExpiration = true,")).To(Succeed())
+		Expect(gates.Enabled(DriftDetection)).To(BeFalse())
+		Expect(gates.Enabled(InstanceExpiration)).To(BeTrue())
+		Expect(gates.Enabled(SecretFormatters)).To(BeFalse())
+		Expect(gates.Unknown).To(BeEmpty())
+	})
+
+	It("should ignore unknown gates", func() {
+		gates := FeatureGates{}
+		Expect(gates.Decode("FutureFeature=true,SecretFormatters=true")).To(Succeed())
+		Expect(gates.Unknown).To(ConsistOf("FutureFeature"))
+		Expect(gates.Enabled(SecretFormatters)).To(BeTrue())
+		Expect(gates.Enabled("FutureFeature")).To(BeFalse())
+	})
+
+	It("should fail on invalid gates", func() {
+		gates := FeatureGates{}
+		Expect(gates.Decode("DriftDetection")).To(MatchError(ContainSubstring("expected <name>=<true|false>")))
+		Expect(gates.Decode("DriftDetection=maybe")).To(MatchError(ContainSubstring("invalid value of feature gate 'DriftDetection'")))
+	})
+})
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultNamespaceLabel = "_namespace"
+	defaultK8SNameLabel   = "_k8sname"
+	defaultClusterIDLabel = "_clusterid"
+)
+
+// LabelNames overrides the keys of the labels which are implicitly added to instances and bindings in SM.
+// A nil key inherits the key of the default, an empty key disables the label.
+type LabelNames struct {
+	Namespace *string `json:"namespace,omitempty"`
+	K8SName   *string `json:"k8sname,omitempty"`
+	ClusterID *string `json:"clusterid,omitempty"`
+}
+
+// SMLabels is the label scheme of the operator, e.g.
+// {"k8sname": "k8s-name", "offerings": {"my-offering": {"namespace": "", "clusterid": ""}}}
+// renames the _k8sname label for all offerings and disables the namespace and cluster ID labels for my-offering
+type SMLabels struct {
+	LabelNames
+	Offerings map[string]LabelNames `json:"offerings,omitempty"`
+	// PreviousK8SNames are the keys of the k8s name label of earlier label schemes,
+	// resources created before the scheme changed are recovered by them
+	PreviousK8SNames []string `json:"previousK8snames,omitempty"`
+}
+
+// LabelKeys are the resolved keys of the implicit labels, an empty key means the label is not sent
+type LabelKeys struct {
+	Namespace string
+	K8SName   string
+	ClusterID string
+	// K8SNameLookups are the keys of the k8s name label the resources are recovered by, the current key first
+	K8SNameLookups []string
+}
+
+// Decode implements envconfig.Decoder
+func (l *SMLabels) Decode(value string) error {
+	if len(value) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(value), l); err != nil {
+		return err
+	}
+	return l.validate()
+}
+
+// validate rejects label schemes that send two labels with the same key or keys that cannot be queried
+func (l SMLabels) validate() error {
+	offerings := make([]string, 0, len(l.Offerings))
+	for offering := range l.Offerings {
+		offerings = append(offerings, offering)
+	}
+	sort.Strings(offerings)
+	if err := l.For("").validate(); err != nil {
+		return err
+	}
+	for _, offering := range offerings {
+		if err := l.For(offering).validate(); err != nil {
+			return fmt.Errorf("invalid labels of offering '%s': %w", offering, err)
+		}
+	}
+	for _, key := range l.PreviousK8SNames {
+		if err := validateLabelKey(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (k LabelKeys) validate() error {
+	keys := map[string]bool{}
+	for _, key := range []string{k.Namespace, k.K8SName, k.ClusterID} {
+		if len(key) == 0 {
+			continue
+		}
+		if err := validateLabelKey(key); err != nil {
+			return err
+		}
+		if keys[key] {
+			return fmt.Errorf("the label key '%s' is used for more than one label", key)
+		}
+		keys[key] = true
+	}
+	return nil
+}
+
+func validateLabelKey(key string) error {
+	if len(strings.TrimSpace(key)) == 0 || strings.ContainsAny(key, " \t\n'\"") {
+		return fmt.Errorf("invalid label key '%s': the key must not be blank or contain whitespace or quotes", key)
+	}
+	return nil
+}
+
+// For returns the label keys of the instances and bindings of the offering
+func (l SMLabels) For(offering string) LabelKeys {
+	keys := LabelKeys{
+		Namespace: resolveLabelKey(defaultNamespaceLabel, l.Namespace),
+		K8SName:   resolveLabelKey(defaultK8SNameLabel, l.K8SName),
+		ClusterID: resolveLabelKey(defaultClusterIDLabel, l.ClusterID),
+	}
+	if override, ok := l.Offerings[offering]; ok {
+		keys.Namespace = resolveLabelKey(keys.Namespace, override.Namespace)
+		keys.K8SName = resolveLabelKey(keys.K8SName, override.K8SName)
+		keys.ClusterID = resolveLabelKey(keys.ClusterID, override.ClusterID)
+	}
+	if len(keys.K8SName) > 0 {
+		for _, key := range append([]string{keys.K8SName, defaultK8SNameLabel}, l.PreviousK8SNames...) {
+			if !contains(keys.K8SNameLookups, key) {
+				keys.K8SNameLookups = append(keys.K8SNameLookups, key)
+			}
+		}
+	}
+	return keys
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func resolveLabelKey(inherited string, key *string) string {
+	if key == nil {
+		return inherited
+	}
+	return *key
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"sync"
+	"time"
+)
+
+// maxSettingsHistory is the number of changes of the live settings that are kept, the changes of the cluster ID are
+// kept without a limit
+const maxSettingsHistory = 10
+
+// Settings are the part of the configuration that can be changed at runtime with the OperatorConfig resource
+type Settings struct {
+	ClusterID           string
+	ManagementNamespace string
+	PollInterval        time.Duration
+	LongPollInterval    time.Duration
+}
+
+// AppliedSettings are settings and the time they were applied at
+type AppliedSettings struct {
+	Settings
+	AppliedAt time.Time
+}
+
+// ClusterIDChange is a cluster ID and the time it was applied at
+type ClusterIDChange struct {
+	ClusterID string
+	AppliedAt time.Time
+}
+
+// Live holds the settings that can be changed at runtime, starting with the settings of the environment, and the
+// history of their changes. The changes of the cluster ID are kept apart from the capped history of the settings, so
+// that resources created before any change of the cluster ID keep the ID they were created with.
+type Live struct {
+	mu         sync.RWMutex
+	base       Settings
+	history    []AppliedSettings
+	clusterIDs []ClusterIDChange
+}
+
+// NewLive returns the live settings of the configuration
+func NewLive(cfg Config) *Live {
+	return &Live{base: Settings{
+		ClusterID:           cfg.ClusterID,
+		ManagementNamespace: cfg.ManagementNamespace,
+		PollInterval:        cfg.PollInterval,
+		LongPollInterval:    cfg.LongPollInterval,
+	}}
+}
+
+// Base returns the settings of the environment
+func (l *Live) Base() Settings {
+	return l.base
+}
+
+// Current returns the settings applied last
+func (l *Live) Current() Settings {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if len(l.history) == 0 {
+		return l.base
+	}
+	return l.history[len(l.history)-1].Settings
+}
+
+// ClusterIDAt returns the cluster ID that was in effect at the time, the ID of the environment before the first change
+func (l *Live) ClusterIDAt(t time.Time) string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	clusterID := l.base.ClusterID
+	for _, change := range l.clusterIDs {
+		if change.AppliedAt.After(t) {
+			break
+		}
+		clusterID = change.ClusterID
+	}
+	return clusterID
+}
+
+// Apply makes the settings the current settings, it returns false if they are already current
+func (l *Live) Apply(settings Settings, at time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	current := l.base
+	if len(l.history) > 0 {
+		current = l.history[len(l.history)-1].Settings
+	}
+	if current == settings {
+		return false
+	}
+	if current.ClusterID != settings.ClusterID {
+		l.clusterIDs = append(l.clusterIDs, ClusterIDChange{ClusterID: settings.ClusterID, AppliedAt: at})
+	}
+	l.history = append(l.history, AppliedSettings{Settings: settings, AppliedAt: at})
+	if len(l.history) > maxSettingsHistory {
+		l.history = l.history[len(l.history)-maxSettingsHistory:]
+	}
+	return true
+}
+
+// History returns the changes of the settings, the last change last
+func (l *Live) History() []AppliedSettings {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]AppliedSettings{}, l.history...)
+}
+
+// ClusterIDHistory returns the changes of the cluster ID, the last change last
+func (l *Live) ClusterIDHistory() []ClusterIDChange {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]ClusterIDChange{}, l.clusterIDs...)
+}
+
+// Restore replaces the history of the changes, e.g. with the history recorded before a restart. Without a recorded
+// history of the cluster ID, e.g. recorded by an earlier version, the changes of the cluster ID are taken from the
+// history of the settings.
+func (l *Live) Restore(history []AppliedSettings, clusterIDs []ClusterIDChange) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.history = append([]AppliedSettings{}, history...)
+	if len(l.history) > maxSettingsHistory {
+		l.history = l.history[len(l.history)-maxSettingsHistory:]
+	}
+	l.clusterIDs = append([]ClusterIDChange{}, clusterIDs...)
+	if len(clusterIDs) > 0 {
+		return
+	}
+	current := l.base.ClusterID
+	for _, applied := range history {
+		if applied.ClusterID != current {
+			l.clusterIDs = append(l.clusterIDs, ClusterIDChange{ClusterID: applied.ClusterID, AppliedAt: applied.AppliedAt})
+			current = applied.ClusterID
+		}
+	}
+}
+
+// ClusterIDs returns the IDs the cluster had, the current ID included
+func (l *Live) ClusterIDs() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	ids := []string{l.base.ClusterID}
+	for _, change := range l.clusterIDs {
+		known := false
+		for _, id := range ids {
+			known = known || id == change.ClusterID
+		}
+		if !known {
+			ids = append(ids, change.ClusterID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Live settings", func() {
+	It("should keep the settings in effect at the time of each change", func() {
+		live := NewLive(Config{ClusterID: "cluster-1", ManagementNamespace: "sap-btp", PollInterval: 10 * time.Second, LongPollInterval: 5 * time.Minute})
+		start := time.Now()
+		Expect(live.Current().ClusterID).To(Equal("cluster-1"))
+
+		changed := live.Current()
+		changed.ClusterID = "cluster-2"
+		Expect(live.Apply(changed, start.Add(time.Hour))).To(BeTrue())
+		Expect(live.Apply(changed, start.Add(2*time.Hour))).To(BeFalse())
+
+		Expect(live.Current().ClusterID).To(Equal("cluster-2"))
+		Expect(live.ClusterIDAt(start)).To(Equal("cluster-1"))
+		Expect(live.ClusterIDAt(start.Add(90 * time.Minute))).To(Equal("cluster-2"))
+		Expect(live.ClusterIDs()).To(Equal([]string{"cluster-1", "cluster-2"}))
+		Expect(live.Base().ClusterID).To(Equal("cluster-1"))
+
+		restored := NewLive(Config{ClusterID: "cluster-1"})
+		restored.Restore(live.History(), live.ClusterIDHistory())
+		Expect(restored.Current()).To(Equal(changed))
+		Expect(restored.ClusterIDAt(start.Add(90 * time.Minute))).To(Equal("cluster-2"))
+	})
+
+	It("should keep all changes of the cluster ID beyond the history of the settings", func() {
+		live := NewLive(Config{ClusterID: "cluster-1", PollInterval: time.Second})
+		start := time.Now()
+		changed := live.Current()
+		changed.ClusterID = "cluster-2"
+		Expect(live.Apply(changed, start.Add(time.Hour))).To(BeTrue())
+		for i := 1; i <= 2*maxSettingsHistory; i++ {
+			changed.PollInterval = time.Duration(i+1) * time.Second
+			Expect(live.Apply(changed, start.Add(time.Hour+time.Duration(i)*time.Minute))).To(BeTrue())
+		}
+
+		Expect(live.History()).To(HaveLen(maxSettingsHistory))
+		Expect(live.ClusterIDAt(start)).To(Equal("cluster-1"))
+		Expect(live.ClusterIDAt(start.Add(2 * time.Hour))).To(Equal("cluster-2"))
+		Expect(live.ClusterIDs()).To(Equal([]string{"cluster-1", "cluster-2"}))
+	})
+
+	It("should take the changes of the cluster ID from the history of the settings recorded without them", func() {
+		start := time.Now()
+		restored := NewLive(Config{ClusterID: "cluster-1"})
+		restored.Restore([]AppliedSettings{
+			{Settings: Settings{ClusterID: "cluster-1", PollInterval: time.Minute}, AppliedAt: start},
+			{Settings: Settings{ClusterID: "cluster-2"}, AppliedAt: start.Add(time.Hour)},
+		}, nil)
+		Expect(restored.ClusterIDHistory()).To(Equal([]ClusterIDChange{{ClusterID: "cluster-2", AppliedAt: start.Add(time.Hour)}}))
+		Expect(restored.ClusterIDAt(start.Add(30 * time.Minute))).To(Equal("cluster-1"))
+	})
+})
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"encoding/json"
+)
+
+// SecretPreset is the default shape of the secrets of bindings that do not specify one
+type SecretPreset struct {
+	// SecretFormat is applied to bindings without spec.secretFormat
+	SecretFormat string `json:"secretFormat,omitempty"`
+	// SecretTemplate is applied to bindings without spec.secretTemplate, spec.secretKey and spec.secretRootKey
+	SecretTemplate string `json:"secretTemplate,omitempty"`
+}
+
+// SecretPresets are the secret defaults of the bindings of instances of each service offering, e.g.
+// {"hana": {"secretFormat": "hana"}, "xsuaa": {"secretTemplate": "..."}}
+type SecretPresets map[string]SecretPreset
+
+// Decode implements envconfig.Decoder
+func (p *SecretPresets) Decode(value string) error {
+	if len(value) == 0 {
+		return nil
+	}
+	return json.Unmarshal([]byte(value), p)
+}
+
+// For returns the secret defaults of the bindings of instances of the offering
+func (p SecretPresets) For(offering string) SecretPreset {
+	return p[offering]
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Secret presets", func() {
+	It("should decode the defaults of the offerings", func() {
+		presets := SecretPresets{}
+		Expect(presets.Decode(`{"hana": {"secretFormat": "hana"}, "xsuaa": {"secretTemplate": "{{ .smBindingCredentials.clientid }}"}}`)).To(Succeed())
+		Expect(presets.For("hana")).To(Equal(SecretPreset{SecretFormat: "hana"}))
+		Expect(presets.For("xsuaa")).To(Equal(SecretPreset{SecretTemplate: "{{ .smBindingCredentials.clientid }}"}))
+		Expect(presets.For("other")).To(Equal(SecretPreset{}))
+	})
+
+	It("should keep no defaults if the value is empty", func() {
+		presets := SecretPresets{}
+		Expect(presets.Decode("")).To(Succeed())
+		Expect(presets).To(BeEmpty())
+	})
+
+	It("should fail on invalid defaults", func() {
+		presets := SecretPresets{}
+		Expect(presets.Decode(`["hana"]`)).ToNot(Succeed())
+	})
+})
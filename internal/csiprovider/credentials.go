@@ -0,0 +1,118 @@
+package csiprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CredentialsPath is the path of the endpoint of the manager that serves the credentials of the bindings to the
+// provider. It is served on the metrics endpoint, whose proxy authorizes the service account of the provider for this
+// path only.
+const CredentialsPath = "/csi-credentials"
+
+// credentialsResponse is the response of the credentials endpoint
+type credentialsResponse struct {
+	Data    map[string][]byte `json:"data"`
+	Version string            `json:"version"`
+}
+
+// CredentialsHandler serves the credentials of the binding in the namespace and name query parameters, errors are
+// returned as a Kubernetes Status
+type CredentialsHandler struct {
+	Credentials CredentialsSource
+	Log         logr.Logger
+}
+
+func (h *CredentialsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	namespace, name := req.URL.Query().Get("namespace"), req.URL.Query().Get("name")
+	if len(namespace) == 0 || len(name) == 0 {
+		writeStatus(w, apierrors.NewBadRequest("the namespace and name of the binding are required").Status())
+		return
+	}
+
+	data, version, err := h.Credentials.CSICredentials(req.Context(), namespace, name)
+	if err != nil {
+		h.Log.Error(err, "failed to read the credentials of the binding", "namespace", namespace, "binding", name)
+		var apiStatus apierrors.APIStatus
+		if errors.As(err, &apiStatus) {
+			writeStatus(w, apiStatus.Status())
+			return
+		}
+		writeStatus(w, metav1.Status{Status: metav1.StatusFailure, Code: http.StatusInternalServerError, Reason: metav1.StatusReasonInternalError, Message: err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(&credentialsResponse{Data: data, Version: version}); err != nil {
+		h.Log.Error(err, "failed to write the credentials of the binding", "namespace", namespace, "binding", name)
+	}
+}
+
+func writeStatus(w http.ResponseWriter, status metav1.Status) {
+	status.Kind, status.APIVersion = "Status", "v1"
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(int(status.Code))
+	_ = json.NewEncoder(w).Encode(&status)
+}
+
+// RemoteCredentials reads the credentials of the bindings from the credentials endpoint of the manager, authenticated
+// with the token of the service account of the provider
+type RemoteCredentials struct {
+	// URL is the URL of the credentials endpoint
+	URL string
+	// TokenFile is the service account token of the provider, read on every call since the kubelet rotates it
+	TokenFile string
+	Client    *http.Client
+}
+
+func (c *RemoteCredentials) CSICredentials(ctx context.Context, namespace, name string) (map[string][]byte, string, error) {
+	token, err := os.ReadFile(c.TokenFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read the service account token: %w", err)
+	}
+	endpoint, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, "", err
+	}
+	endpoint.RawQuery = url.Values{"namespace": {namespace}, "name": {name}}.Encode()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	request.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+
+	response, err := c.Client.Do(request)
+	if err != nil {
+		return nil, "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+		status := metav1.Status{}
+		if err := json.Unmarshal(body, &status); err != nil || status.Kind != "Status" {
+			return nil, "", fmt.Errorf("failed to read the credentials of binding %s from the manager: %s %s", name, response.Status, strings.TrimSpace(string(body)))
+		}
+		return nil, "", &apierrors.StatusError{ErrStatus: status}
+	}
+	credentials := &credentialsResponse{}
+	if err := json.NewDecoder(response.Body).Decode(credentials); err != nil {
+		return nil, "", fmt.Errorf("failed to decode the credentials of binding %s: %w", name, err)
+	}
+	return credentials.Data, credentials.Version, nil
+}
@@ -0,0 +1,79 @@
+package csiprovider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+var _ = Describe("Credentials endpoint", func() {
+	var server *httptest.Server
+	var remote *RemoteCredentials
+	var dir string
+	var authorization string
+
+	BeforeEach(func() {
+		handler := &CredentialsHandler{
+			Credentials: &fakeCredentials{bindings: map[string]map[string][]byte{
+				"app1/my-binding": {"password": []byte("secret")},
+			}},
+			Log: logr.Discard(),
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc(CredentialsPath, func(w http.ResponseWriter, req *http.Request) {
+			authorization = req.Header.Get("Authorization")
+			handler.ServeHTTP(w, req)
+		})
+		server = httptest.NewTLSServer(mux)
+
+		var err error
+		dir, err = os.MkdirTemp("", "csi")
+		Expect(err).ToNot(HaveOccurred())
+		tokenFile := filepath.Join(dir, "token")
+		Expect(os.WriteFile(tokenFile, []byte("provider-token\n"), 0600)).To(Succeed())
+		remote = &RemoteCredentials{URL: server.URL + CredentialsPath, TokenFile: tokenFile, Client: server.Client()}
+	})
+
+	AfterEach(func() {
+		server.Close()
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should read the credentials of the binding with the token of the provider", func() {
+		data, version, err := remote.CSICredentials(context.Background(), "app1", "my-binding")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(map[string][]byte{"password": []byte("secret")}))
+		Expect(version).To(Equal("1"))
+		Expect(authorization).To(Equal("Bearer provider-token"))
+	})
+
+	It("should return the errors of the manager", func() {
+		_, _, err := remote.CSICredentials(context.Background(), "app2", "my-binding")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		remote.URL = server.URL + "/unknown"
+		_, _, err = remote.CSICredentials(context.Background(), "app1", "my-binding")
+		Expect(err).To(MatchError(ContainSubstring("404 Not Found")))
+		Expect(apierrors.IsNotFound(err)).To(BeFalse())
+	})
+
+	It("should reject requests without a binding", func() {
+		response, err := server.Client().Get(server.URL + CredentialsPath + "?namespace=app1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Body.Close()).To(Succeed())
+		Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should fail without a service account token", func() {
+		remote.TokenFile = filepath.Join(dir, "missing")
+		_, _, err := remote.CSICredentials(context.Background(), "app1", "my-binding")
+		Expect(err).To(MatchError(ContainSubstring("failed to read the service account token")))
+	})
+})
@@ -0,0 +1,13 @@
+package csiprovider
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCSIProvider(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CSI Provider Suite")
+}
@@ -0,0 +1,91 @@
+package csiprovider
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+)
+
+// the provider serves the v1alpha1 CSIDriverProvider service of the driver. The generated stubs of the driver are not a
+// dependency of the operator, the service is registered by hand and its messages are encoded by codec.
+
+const serviceName = "v1alpha1.CSIDriverProvider"
+
+// driverProviderServer is the server of the CSIDriverProvider service
+type driverProviderServer interface {
+	version(ctx context.Context, request *versionRequest) (*versionResponse, error)
+	mount(ctx context.Context, request *mountRequest) (*mountResponse, error)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*driverProviderServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Version", Handler: versionHandler},
+		{MethodName: "Mount", Handler: mountHandler},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "service.proto",
+}
+
+func versionHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	request := &versionRequest{}
+	if err := dec(request); err != nil {
+		return nil, err
+	}
+	handler := func(ctx context.Context, request interface{}) (interface{}, error) {
+		return srv.(driverProviderServer).version(ctx, request.(*versionRequest))
+	}
+	if interceptor == nil {
+		return handler(ctx, request)
+	}
+	return interceptor(ctx, request, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/Version"}, handler)
+}
+
+func mountHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	request := &mountRequest{}
+	if err := dec(request); err != nil {
+		return nil, err
+	}
+	handler := func(ctx context.Context, request interface{}) (interface{}, error) {
+		return srv.(driverProviderServer).mount(ctx, request.(*mountRequest))
+	}
+	if interceptor == nil {
+		return handler(ctx, request)
+	}
+	return interceptor(ctx, request, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/Mount"}, handler)
+}
+
+// marshaler is a message that encodes itself with the protobuf wire format
+type marshaler interface {
+	marshal() []byte
+}
+
+// unmarshaler is a message that decodes itself from the protobuf wire format
+type unmarshaler interface {
+	unmarshal(b []byte) error
+}
+
+// codec encodes the messages of the CSIDriverProvider service, it replaces the proto codec of the server
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	message, ok := v.(marshaler)
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal message of type %T", v)
+	}
+	return message.marshal(), nil
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	message, ok := v.(unmarshaler)
+	if !ok {
+		return fmt.Errorf("cannot unmarshal message of type %T", v)
+	}
+	return message.unmarshal(data)
+}
+
+func (codec) Name() string {
+	return "proto"
+}
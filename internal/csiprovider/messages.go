@@ -0,0 +1,161 @@
+package csiprovider
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// the messages of the v1alpha1 CSIDriverProvider service of the Secrets Store CSI driver, encoded with the protobuf
+// wire format, only the fields used by the provider are read
+
+type mountRequest struct {
+	// attributes are the JSON encoded parameters of the SecretProviderClass and the attributes of the mounting pod
+	attributes string
+	// permission is the JSON encoded file mode of the files
+	permission string
+	// currentObjectVersions are the versions of the files currently mounted
+	currentObjectVersions []objectVersion
+}
+
+type mountResponse struct {
+	objectVersions []objectVersion
+	files          []file
+}
+
+type objectVersion struct {
+	id      string
+	version string
+}
+
+type file struct {
+	path     string
+	mode     int32
+	contents []byte
+}
+
+type versionRequest struct {
+	version string
+}
+
+type versionResponse struct {
+	version        string
+	runtimeName    string
+	runtimeVersion string
+}
+
+func (r *versionRequest) unmarshal(b []byte) error {
+	return consumeBytesFields(b, func(num protowire.Number, value []byte) error {
+		if num == 1 {
+			r.version = string(value)
+		}
+		return nil
+	})
+}
+
+func (r *mountRequest) unmarshal(b []byte) error {
+	err := consumeBytesFields(b, func(num protowire.Number, value []byte) error {
+		switch num {
+		case 1:
+			r.attributes = string(value)
+		case 4:
+			r.permission = string(value)
+		case 5:
+			version, err := unmarshalObjectVersion(value)
+			if err != nil {
+				return err
+			}
+			r.currentObjectVersions = append(r.currentObjectVersions, version)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("invalid mount request: %w", err)
+	}
+	return nil
+}
+
+func unmarshalObjectVersion(b []byte) (objectVersion, error) {
+	version := objectVersion{}
+	err := consumeBytesFields(b, func(num protowire.Number, value []byte) error {
+		switch num {
+		case 1:
+			version.id = string(value)
+		case 2:
+			version.version = string(value)
+		}
+		return nil
+	})
+	return version, err
+}
+
+// consumeBytesFields calls field with the length-delimited fields of the message and skips all other fields
+func consumeBytesFields(b []byte, field func(num protowire.Number, value []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		value, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if err := field(num, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *mountResponse) marshal() []byte {
+	var b []byte
+	for _, version := range r.objectVersions {
+		b = appendBytes(b, 1, version.marshal())
+	}
+	for _, f := range r.files {
+		b = appendBytes(b, 3, f.marshal())
+	}
+	return b
+}
+
+func (v objectVersion) marshal() []byte {
+	var b []byte
+	b = appendBytes(b, 1, []byte(v.id))
+	return appendBytes(b, 2, []byte(v.version))
+}
+
+func (f file) marshal() []byte {
+	var b []byte
+	b = appendBytes(b, 1, []byte(f.path))
+	if f.mode != 0 {
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(f.mode))
+	}
+	return appendBytes(b, 3, f.contents)
+}
+
+func (r *versionResponse) marshal() []byte {
+	var b []byte
+	b = appendBytes(b, 1, []byte(r.version))
+	b = appendBytes(b, 2, []byte(r.runtimeName))
+	return appendBytes(b, 3, []byte(r.runtimeVersion))
+}
+
+// appendBytes appends a length-delimited field, empty fields are omitted like in proto3
+func appendBytes(b []byte, num protowire.Number, value []byte) []byte {
+	if len(value) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, value)
+}
@@ -0,0 +1,161 @@
+package csiprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// Name is the name of the provider referenced by SecretProviderClasses, the driver connects to the socket of the
+	// same name in its providers directory
+	Name = "sap-btp"
+	// BindingsParameter is the parameter of SecretProviderClasses listing the comma separated names of the bindings
+	// mounted by the volume
+	BindingsParameter = "bindings"
+
+	// podNamespaceAttribute is the attribute of mount requests holding the namespace of the pod mounting the volume
+	podNamespaceAttribute = "csi.storage.k8s.io/pod.namespace"
+	apiVersion            = "v1alpha1"
+	defaultFileMode       = 0644
+)
+
+// CredentialsSource reads the credentials of the bindings served to CSI volumes
+type CredentialsSource interface {
+	// CSICredentials returns the data of the binding secret of the binding in namespace, and a version that changes
+	// with the data
+	CSICredentials(ctx context.Context, namespace, name string) (map[string][]byte, string, error)
+}
+
+// Provider is a provider of the Secrets Store CSI driver that mounts the credentials of bindings read from SM into
+// the volumes of pods, so that they are never stored in the cluster. The files of a binding are mounted at
+// <binding name>/<key>. On the nodes the credentials are fetched from the manager with RemoteCredentials, the provider
+// has no access to SM or to the secrets of the cluster.
+type Provider struct {
+	Credentials CredentialsSource
+	Log         logr.Logger
+}
+
+// Serve serves the provider on the unix socket at socketPath until the context is done, a socket left by a previous
+// run is removed
+func (p *Provider) Serve(ctx context.Context, socketPath string) error {
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return err
+	}
+	server := grpc.NewServer(grpc.ForceServerCodec(codec{}), grpc.UnaryInterceptor(p.logErrors))
+	server.RegisterService(&serviceDesc, p)
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+	p.Log.Info("serving the Secrets Store CSI provider", "socket", socketPath)
+	return server.Serve(listener)
+}
+
+// logErrors logs the calls of the driver that failed
+func (p *Provider) logErrors(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	response, err := handler(ctx, request)
+	if err != nil {
+		p.Log.Error(err, "failed to answer the call of the Secrets Store CSI driver", "method", info.FullMethod)
+	}
+	return response, err
+}
+
+func (p *Provider) version(_ context.Context, _ *versionRequest) (*versionResponse, error) {
+	runtimeVersion := "unknown"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		runtimeVersion = info.Main.Version
+	}
+	return &versionResponse{version: apiVersion, runtimeName: Name, runtimeVersion: runtimeVersion}, nil
+}
+
+// mount reads the credentials of the bindings of the SecretProviderClass in the namespace of the pod, the credentials
+// are read again whenever the driver rotates the mounted files
+func (p *Provider) mount(ctx context.Context, request *mountRequest) (*mountResponse, error) {
+	attributes := map[string]string{}
+	if err := json.Unmarshal([]byte(request.attributes), &attributes); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid attributes: %s", err)
+	}
+	namespace := attributes[podNamespaceAttribute]
+	if len(namespace) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "the attribute %s is missing, the driver must pass the pod info to the provider", podNamespaceAttribute)
+	}
+	bindings, err := bindingNames(attributes[BindingsParameter])
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	mode, err := fileMode(request.permission)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	response := &mountResponse{}
+	for _, name := range bindings {
+		data, version, err := p.Credentials.CSICredentials(ctx, namespace, name)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, status.Errorf(codes.NotFound, "binding %s not found in namespace %s", name, namespace)
+			}
+			return nil, err
+		}
+		keys := make([]string, 0, len(data))
+		for key := range data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			response.files = append(response.files, file{path: path.Join(name, key), mode: mode, contents: data[key]})
+		}
+		response.objectVersions = append(response.objectVersions, objectVersion{id: name, version: version})
+		p.Log.Info("mounted the credentials of the binding", "namespace", namespace, "binding", name, "version", version)
+	}
+	return response, nil
+}
+
+// bindingNames returns the names of the bindings of the bindings parameter
+func bindingNames(parameter string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(parameter, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		}
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return nil, fmt.Errorf("the binding name '%s' of the parameter %s is invalid: %s", name, BindingsParameter, strings.Join(errs, ", "))
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("the parameter %s of the SecretProviderClass lists no bindings", BindingsParameter)
+	}
+	return names, nil
+}
+
+// fileMode returns the JSON encoded permission of the files of the volume
+func fileMode(permission string) (int32, error) {
+	if len(permission) == 0 {
+		return defaultFileMode, nil
+	}
+	var mode os.FileMode
+	if err := json.Unmarshal([]byte(permission), &mode); err != nil {
+		return 0, fmt.Errorf("invalid permission '%s': %s", permission, err)
+	}
+	return int32(mode.Perm()), nil
+}
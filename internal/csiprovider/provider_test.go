@@ -0,0 +1,171 @@
+package csiprovider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeCredentials struct {
+	bindings map[string]map[string][]byte
+}
+
+func (f *fakeCredentials) CSICredentials(_ context.Context, namespace, name string) (map[string][]byte, string, error) {
+	data, found := f.bindings[namespace+"/"+name]
+	if !found {
+		return nil, "", apierrors.NewNotFound(schema.GroupResource{Group: "services.cloud.sap.com", Resource: "servicebindings"}, name)
+	}
+	return data, fmt.Sprintf("%d", len(data)), nil
+}
+
+// rawMessage is a message encoded by the test
+type rawMessage struct {
+	b []byte
+}
+
+func (m *rawMessage) marshal() []byte {
+	return m.b
+}
+
+func (m *rawMessage) unmarshal(b []byte) error {
+	m.b = b
+	return nil
+}
+
+// mountedFile is a file of a mount response decoded by the test
+type mountedFile struct {
+	path     string
+	mode     uint64
+	contents string
+}
+
+var _ = Describe("CSI provider", func() {
+	var cancel context.CancelFunc
+	var dir string
+	var conn *grpc.ClientConn
+	var credentials *fakeCredentials
+
+	// call sends a unary gRPC call to the provider and returns the message and the status of the response
+	call := func(method string, message []byte) ([]byte, codes.Code, string) {
+		response := &rawMessage{}
+		err := conn.Invoke(context.Background(), "/"+serviceName+"/"+method, &rawMessage{b: message}, response)
+		return response.b, status.Code(err), status.Convert(err).Message()
+	}
+
+	mountRequestOf := func(attributes, permission string) []byte {
+		var b []byte
+		b = appendBytes(b, 1, []byte(attributes))
+		b = appendBytes(b, 3, []byte("/var/lib/kubelet/pods/uid/volumes/secrets"))
+		b = appendBytes(b, 4, []byte(permission))
+		return appendBytes(b, 5, objectVersion{id: "my-binding", version: "0"}.marshal())
+	}
+
+	filesOf := func(response []byte) []mountedFile {
+		var files []mountedFile
+		Expect(consumeBytesFields(response, func(num protowire.Number, value []byte) error {
+			if num != 3 {
+				return nil
+			}
+			f := mountedFile{}
+			for len(value) > 0 {
+				fieldNum, typ, n := protowire.ConsumeTag(value)
+				Expect(n).To(BeNumerically(">", 0))
+				value = value[n:]
+				if typ == protowire.VarintType {
+					f.mode, n = protowire.ConsumeVarint(value)
+				} else {
+					var field []byte
+					field, n = protowire.ConsumeBytes(value)
+					if fieldNum == 1 {
+						f.path = string(field)
+					} else {
+						f.contents = string(field)
+					}
+				}
+				Expect(n).To(BeNumerically(">", 0))
+				value = value[n:]
+			}
+			files = append(files, f)
+			return nil
+		})).To(Succeed())
+		return files
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "csi")
+		Expect(err).ToNot(HaveOccurred())
+		socket := filepath.Join(dir, Name+".sock")
+		credentials = &fakeCredentials{bindings: map[string]map[string][]byte{
+			"app1/my-binding": {"password": []byte("secret"), "url": []byte("https://api.example.com")},
+		}}
+		provider := &Provider{Credentials: credentials, Log: logr.Discard()}
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		go func() {
+			defer GinkgoRecover()
+			Expect(provider.Serve(ctx, socket)).To(Succeed())
+		}()
+		Eventually(func() error {
+			_, err := os.Stat(socket)
+			return err
+		}).Should(Succeed())
+		conn, err = grpc.Dial("unix://"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithDefaultCallOptions(grpc.ForceCodec(codec{})))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(conn.Close()).To(Succeed())
+		cancel()
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should report its version", func() {
+		response, code, _ := call("Version", appendBytes(nil, 1, []byte(apiVersion)))
+		Expect(code).To(Equal(codes.OK))
+		Expect(response).To(ContainSubstring(apiVersion))
+		Expect(response).To(ContainSubstring(Name))
+	})
+
+	It("should mount the credentials of the bindings in the namespace of the pod", func() {
+		response, code, message := call("Mount", mountRequestOf(`{"bindings": "my-binding", "csi.storage.k8s.io/pod.namespace": "app1"}`, "288"))
+		Expect(code).To(Equal(codes.OK), message)
+		Expect(filesOf(response)).To(Equal([]mountedFile{
+			{path: "my-binding/password", mode: 0440, contents: "secret"},
+			{path: "my-binding/url", mode: 0440, contents: "https://api.example.com"},
+		}))
+		Expect(response).To(ContainSubstring("my-binding"))
+	})
+
+	It("should fail to mount the bindings of other namespaces", func() {
+		_, code, message := call("Mount", mountRequestOf(`{"bindings": "my-binding", "csi.storage.k8s.io/pod.namespace": "app2"}`, ""))
+		Expect(code).To(Equal(codes.NotFound))
+		Expect(message).To(Equal("binding my-binding not found in namespace app2"))
+	})
+
+	It("should reject invalid mount requests", func() {
+		_, code, message := call("Mount", mountRequestOf(`{"bindings": "My_Binding", "csi.storage.k8s.io/pod.namespace": "app1"}`, ""))
+		Expect(code).To(Equal(codes.InvalidArgument))
+		Expect(message).To(ContainSubstring("the binding name 'My_Binding' of the parameter bindings is invalid"))
+
+		_, code, message = call("Mount", mountRequestOf(`{"bindings": " , "}`, ""))
+		Expect(code).To(Equal(codes.InvalidArgument))
+		Expect(message).To(ContainSubstring("csi.storage.k8s.io/pod.namespace is missing"))
+
+		_, code, _ = call("Unknown", nil)
+		Expect(code).To(Equal(codes.Unimplemented))
+	})
+})
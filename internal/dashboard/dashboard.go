@@ -0,0 +1,135 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Path is the path on the metrics server where the dashboard data is served
+	Path = "/dashboard"
+
+	unknownState = "Unknown"
+)
+
+// Summary is the aggregated view of the service instances and bindings managed by the operator
+type Summary struct {
+	GeneratedAt metav1.Time     `json:"generatedAt"`
+	Instances   ResourceSummary `json:"instances"`
+	Bindings    ResourceSummary `json:"bindings"`
+}
+
+// ResourceSummary counts resources of a single kind by state, offering and namespace
+type ResourceSummary struct {
+	Total       int            `json:"total"`
+	Ready       int            `json:"ready"`
+	ByState     map[string]int `json:"byState"`
+	ByOffering  map[string]int `json:"byOffering"`
+	ByNamespace map[string]int `json:"byNamespace"`
+}
+
+// Handler serves the dashboard summary as read-only JSON.
+// The data is read from the manager cache, so consoles don't need to LIST the resources against the API server.
+type Handler struct {
+	Client client.Reader
+	Log    logr.Logger
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	summary, err := BuildSummary(req.Context(), h.Client, req.URL.Query().Get("namespace"))
+	if err != nil {
+		h.Log.Error(err, "failed to build dashboard summary")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summary); err != nil {
+		h.Log.Error(err, "failed to write dashboard summary")
+	}
+}
+
+// BuildSummary aggregates the service instances and bindings, optionally restricted to a single namespace
+func BuildSummary(ctx context.Context, reader client.Reader, namespace string) (*Summary, error) {
+	var opts []client.ListOption
+	if len(namespace) > 0 {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+
+	instances := &servicesv1.ServiceInstanceList{}
+	if err := reader.List(ctx, instances, opts...); err != nil {
+		return nil, err
+	}
+
+	bindings := &servicesv1.ServiceBindingList{}
+	if err := reader.List(ctx, bindings, opts...); err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{
+		GeneratedAt: metav1.NewTime(time.Now()),
+		Instances:   newResourceSummary(),
+		Bindings:    newResourceSummary(),
+	}
+
+	offerings := make(map[string]string)
+	for _, instance := range instances.Items {
+		offerings[instance.Namespace+"/"+instance.Name] = instance.Spec.ServiceOfferingName
+		summary.Instances.add(instance.Namespace, instance.Spec.ServiceOfferingName, state(instance.Status.Conditions), instance.Status.Ready)
+	}
+
+	for _, binding := range bindings.Items {
+		instanceNamespace := binding.Namespace
+		if len(binding.Spec.ServiceInstanceNamespace) > 0 {
+			instanceNamespace = binding.Spec.ServiceInstanceNamespace
+		}
+		offering, ok := offerings[instanceNamespace+"/"+binding.Spec.ServiceInstanceName]
+		if shared := binding.Status.SharedInstance; shared != nil && len(shared.ServiceOfferingName) > 0 {
+			offering, ok = shared.ServiceOfferingName, true
+		}
+		if !ok {
+			offering = unknownState
+		}
+		summary.Bindings.add(binding.Namespace, offering, state(binding.Status.Conditions), binding.Status.Ready)
+	}
+
+	return summary, nil
+}
+
+func newResourceSummary() ResourceSummary {
+	return ResourceSummary{
+		ByState:     make(map[string]int),
+		ByOffering:  make(map[string]int),
+		ByNamespace: make(map[string]int),
+	}
+}
+
+func (rs *ResourceSummary) add(namespace, offering, state string, ready metav1.ConditionStatus) {
+	rs.Total++
+	if ready == metav1.ConditionTrue {
+		rs.Ready++
+	}
+	rs.ByState[state]++
+	rs.ByOffering[offering]++
+	rs.ByNamespace[namespace]++
+}
+
+// state is the reason of the first condition, the same value presented in the "Status" printer column
+func state(conditions []metav1.Condition) string {
+	if len(conditions) == 0 {
+		return unknownState
+	}
+	return conditions[0].Reason
+}
@@ -0,0 +1,79 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Dashboard", func() {
+	var reader client.Reader
+
+	instance := func(namespace, name, offering, reason string, ready metav1.ConditionStatus) *servicesv1.ServiceInstance {
+		return &servicesv1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       servicesv1.ServiceInstanceSpec{ServiceOfferingName: offering, ServicePlanName: "plan"},
+			Status: servicesv1.ServiceInstanceStatus{
+				Ready:      ready,
+				Conditions: []metav1.Condition{{Type: "Succeeded", Reason: reason}},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(servicesv1.AddToScheme(scheme)).To(Succeed())
+		reader = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			instance("ns1", "i1", "xsuaa", "Created", metav1.ConditionTrue),
+			instance("ns1", "i2", "hana", "CreateFailed", metav1.ConditionFalse),
+			instance("ns2", "i3", "xsuaa", "Created", metav1.ConditionTrue),
+			&servicesv1.ServiceBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "b1", Namespace: "ns1"},
+				Spec:       servicesv1.ServiceBindingSpec{ServiceInstanceName: "i1"},
+				Status:     servicesv1.ServiceBindingStatus{Ready: metav1.ConditionTrue},
+			},
+		).Build()
+	})
+
+	It("should aggregate instances and bindings", func() {
+		summary, err := BuildSummary(context.Background(), reader, "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(summary.Instances.Total).To(Equal(3))
+		Expect(summary.Instances.Ready).To(Equal(2))
+		Expect(summary.Instances.ByOffering).To(Equal(map[string]int{"xsuaa": 2, "hana": 1}))
+		Expect(summary.Instances.ByState).To(Equal(map[string]int{"Created": 2, "CreateFailed": 1}))
+		Expect(summary.Instances.ByNamespace).To(Equal(map[string]int{"ns1": 2, "ns2": 1}))
+		Expect(summary.Bindings.Total).To(Equal(1))
+		Expect(summary.Bindings.ByOffering).To(Equal(map[string]int{"xsuaa": 1}))
+		Expect(summary.Bindings.ByState).To(Equal(map[string]int{unknownState: 1}))
+	})
+
+	It("should serve the summary of a single namespace", func() {
+		handler := &Handler{Client: reader, Log: logr.Discard()}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, Path+"?namespace=ns2", nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+
+		summary := &Summary{}
+		Expect(json.Unmarshal(recorder.Body.Bytes(), summary)).To(Succeed())
+		Expect(summary.Instances.Total).To(Equal(1))
+		Expect(summary.Bindings.Total).To(Equal(0))
+	})
+
+	It("should reject non GET requests", func() {
+		handler := &Handler{Client: reader, Log: logr.Discard()}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, Path, nil))
+		Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
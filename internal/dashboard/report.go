@@ -0,0 +1,123 @@
+package dashboard
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// SummaryReason is the reason of the summary events recorded in the namespaces
+	SummaryReason = "ResourceSummary"
+
+	maxReportedFailures = 3
+)
+
+// NamespaceReport counts the resources of a namespace and lists the failures of the last interval
+type NamespaceReport struct {
+	Instances ResourceCounts
+	Bindings  ResourceCounts
+	Failures  []Failure
+}
+
+// ResourceCounts counts the resources of a single kind by their progress
+type ResourceCounts struct {
+	Ready      int
+	Failed     int
+	InProgress int
+}
+
+// Failure is a resource whose Failed condition was set within the last interval
+type Failure struct {
+	Kind    string
+	Name    string
+	Message string
+	Time    metav1.Time
+}
+
+// RecordSummaries records the summary event of every namespace of the reports, the event is a warning while any
+// resource of the namespace has failed. The events let namespace owners without access to the metrics or the dashboard
+// see the health of their resources.
+func RecordSummaries(recorder record.EventRecorder, reports map[string]*NamespaceReport) {
+	for namespace, report := range reports {
+		eventType := corev1.EventTypeNormal
+		if report.Instances.Failed+report.Bindings.Failed > 0 {
+			eventType = corev1.EventTypeWarning
+		}
+		// the reference keeps the event in the namespace, events of the cluster-scoped namespace object are not
+		recorder.Event(&corev1.ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: namespace, Namespace: namespace},
+			eventType, SummaryReason, report.String())
+	}
+}
+
+// BuildReports builds the report of every namespace with service instances or bindings, listing the failures since
+// the given time
+func BuildReports(instances []servicesv1.ServiceInstance, bindings []servicesv1.ServiceBinding, since time.Time) map[string]*NamespaceReport {
+	reports := make(map[string]*NamespaceReport)
+	reportOf := func(namespace string) *NamespaceReport {
+		if _, ok := reports[namespace]; !ok {
+			reports[namespace] = &NamespaceReport{}
+		}
+		return reports[namespace]
+	}
+	for i := range instances {
+		instance := &instances[i]
+		report := reportOf(instance.Namespace)
+		report.add(&report.Instances, "ServiceInstance", instance.Name, instance.Status.Ready, instance.Status.Conditions, since)
+	}
+	for i := range bindings {
+		binding := &bindings[i]
+		report := reportOf(binding.Namespace)
+		report.add(&report.Bindings, "ServiceBinding", binding.Name, binding.Status.Ready, binding.Status.Conditions, since)
+	}
+	for _, report := range reports {
+		sort.SliceStable(report.Failures, func(i, j int) bool {
+			return report.Failures[j].Time.Before(&report.Failures[i].Time)
+		})
+	}
+	return reports
+}
+
+func (nr *NamespaceReport) add(counts *ResourceCounts, kind, name string, ready metav1.ConditionStatus, conditions []metav1.Condition, since time.Time) {
+	failed := meta.FindStatusCondition(conditions, api.ConditionFailed)
+	switch {
+	case failed != nil && failed.Status == metav1.ConditionTrue:
+		counts.Failed++
+		if failed.LastTransitionTime.After(since) {
+			nr.Failures = append(nr.Failures, Failure{Kind: kind, Name: name, Message: failed.Message, Time: failed.LastTransitionTime})
+		}
+	case ready == metav1.ConditionTrue:
+		counts.Ready++
+	default:
+		counts.InProgress++
+	}
+}
+
+// String formats the report as the message of the summary event
+func (nr *NamespaceReport) String() string {
+	message := fmt.Sprintf("service instances: %s; service bindings: %s", nr.Instances, nr.Bindings)
+	if len(nr.Failures) == 0 {
+		return message
+	}
+	var failures []string
+	for i, failure := range nr.Failures {
+		if i == maxReportedFailures {
+			failures = append(failures, fmt.Sprintf("and %d more", len(nr.Failures)-maxReportedFailures))
+			break
+		}
+		failures = append(failures, fmt.Sprintf("%s '%s': %s", failure.Kind, failure.Name, failure.Message))
+	}
+	return fmt.Sprintf("%s; recent failures: %s", message, strings.Join(failures, ", "))
+}
+
+func (rc ResourceCounts) String() string {
+	return fmt.Sprintf("%d ready, %d failed, %d in progress", rc.Ready, rc.Failed, rc.InProgress)
+}
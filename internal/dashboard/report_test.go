@@ -0,0 +1,68 @@
+package dashboard
+
+import (
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/api"
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+var _ = Describe("Namespace reports", func() {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	var instances []servicesv1.ServiceInstance
+	var bindings []servicesv1.ServiceBinding
+
+	failedInstance := func(name string, failedAt time.Time) servicesv1.ServiceInstance {
+		return servicesv1.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns1"},
+			Status: servicesv1.ServiceInstanceStatus{Conditions: []metav1.Condition{
+				{Type: api.ConditionFailed, Status: metav1.ConditionTrue, Reason: "CreateFailed", Message: "quota exceeded", LastTransitionTime: metav1.NewTime(failedAt)},
+			}},
+		}
+	}
+
+	BeforeEach(func() {
+		instances = []servicesv1.ServiceInstance{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "ready", Namespace: "ns1"},
+				Status:     servicesv1.ServiceInstanceStatus{Ready: metav1.ConditionTrue},
+			},
+			failedInstance("failed-recently", now.Add(-time.Minute)),
+			failedInstance("failed-long-ago", now.Add(-time.Hour)),
+		}
+		bindings = []servicesv1.ServiceBinding{{ObjectMeta: metav1.ObjectMeta{Name: "creating", Namespace: "ns2"}}}
+	})
+
+	It("should count the resources of each namespace and list the recent failures", func() {
+		reports := BuildReports(instances, bindings, now.Add(-10*time.Minute))
+		Expect(reports).To(HaveLen(2))
+		Expect(reports["ns1"].Instances).To(Equal(ResourceCounts{Ready: 1, Failed: 2}))
+		Expect(reports["ns1"].Failures).To(HaveLen(1))
+		Expect(reports["ns1"].Failures[0].Name).To(Equal("failed-recently"))
+		Expect(reports["ns2"].Bindings).To(Equal(ResourceCounts{InProgress: 1}))
+	})
+
+	It("should record a summary event per namespace", func() {
+		recorder := record.NewFakeRecorder(10)
+		RecordSummaries(recorder, BuildReports(instances, bindings, now.Add(-10*time.Minute)))
+		Expect(recorder.Events).To(HaveLen(2))
+		var events []string
+		events = append(events, <-recorder.Events, <-recorder.Events)
+		Expect(events).To(ContainElement("Warning ResourceSummary service instances: 1 ready, 2 failed, 0 in progress; " +
+			"service bindings: 0 ready, 0 failed, 0 in progress; recent failures: ServiceInstance 'failed-recently': quota exceeded"))
+		Expect(events).To(ContainElement("Normal ResourceSummary service instances: 0 ready, 0 failed, 0 in progress; " +
+			"service bindings: 0 ready, 0 failed, 1 in progress"))
+	})
+
+	It("should limit the listed failures", func() {
+		report := &NamespaceReport{}
+		for i := 0; i < maxReportedFailures+2; i++ {
+			report.Failures = append(report.Failures, Failure{Kind: "ServiceBinding", Name: "b", Message: "failed"})
+		}
+		Expect(report.String()).To(HaveSuffix("and 2 more"))
+	})
+})
@@ -0,0 +1,83 @@
+package escrow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/internal/vault"
+)
+
+// Record holds the credentials of a rotated binding which are about to be deleted
+type Record struct {
+	// Namespace and Name of the rotated binding
+	Namespace string
+	Name      string
+	// RotationOf is the name of the binding the credentials were rotated from
+	RotationOf string
+	// BindingID is the ID of the rotated binding in Service Manager
+	BindingID string
+	// Data is the content of the secret of the rotated binding
+	Data map[string][]byte
+}
+
+// Sink stores the credentials of rotated bindings for a recovery window before they are deleted
+type Sink interface {
+	// Store pushes the record to the escrow, and returns where it was stored
+	Store(ctx context.Context, record Record) (string, error)
+	// TTL is how long the escrow keeps the records
+	TTL() time.Duration
+}
+
+// VaultSink stores the records in a KV version 2 secrets engine of Vault. Each record is a secret under Path named by
+// the namespace and name of the rotated binding, its versions are deleted by Vault once the TTL elapsed.
+type VaultSink struct {
+	// KV is the client of the Vault server
+	KV vault.KV
+	// Mount is the path the KV secrets engine is mounted at
+	Mount string
+	// Path is the prefix of the secrets of the records in the secrets engine
+	Path string
+	// TokenFile holds the Vault token, e.g. written by Vault Agent. It is read for every record, so renewed tokens are
+	// used without a restart.
+	TokenFile string
+	// Expiry is how long Vault keeps a record, 0 keeps it until it is deleted in Vault
+	Expiry time.Duration
+}
+
+func (v *VaultSink) TTL() time.Duration {
+	return v.Expiry
+}
+
+func (v *VaultSink) Store(ctx context.Context, record Record) (string, error) {
+	tokenFile, err := os.ReadFile(v.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the Vault token: %w", err)
+	}
+	token := strings.TrimSpace(string(tokenFile))
+
+	secretPath := path.Join(v.Path, record.Namespace, record.Name)
+	data := make(map[string]string, len(record.Data))
+	for key, value := range record.Data {
+		data[key] = string(value)
+	}
+	if _, err := v.KV.Write(ctx, token, v.Mount, secretPath, data); err != nil {
+		return "", err
+	}
+	// the custom metadata makes the records searchable in Vault, the expiry deletes them after the recovery window
+	metadata := vault.Metadata{
+		CustomMetadata: map[string]string{
+			"namespace":  record.Namespace,
+			"rotationOf": record.RotationOf,
+			"bindingID":  record.BindingID,
+		},
+		DeleteVersionAfter: v.Expiry,
+	}
+	if err := v.KV.WriteMetadata(ctx, token, v.Mount, secretPath, metadata); err != nil {
+		return "", err
+	}
+	return path.Join(v.Mount, secretPath), nil
+}
@@ -0,0 +1,77 @@
+package escrow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/internal/vault"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Vault sink", func() {
+	var server *httptest.Server
+	var sink *VaultSink
+	var requests map[string]map[string]interface{}
+	var tokens []string
+	var status int
+
+	BeforeEach(func() {
+		requests = map[string]map[string]interface{}{}
+		tokens = nil
+		status = http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body := map[string]interface{}{}
+			Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+			requests[r.URL.Path] = body
+			tokens = append(tokens, r.Header.Get("X-Vault-Token"))
+			w.WriteHeader(status)
+			if status >= http.StatusBadRequest {
+				_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+			} else if strings.Contains(r.URL.Path, "/data/") {
+				_, _ = w.Write([]byte(`{"data": {"version": 1}}`))
+			}
+		}))
+		tokenFile := filepath.Join(GinkgoT().TempDir(), "token")
+		Expect(os.WriteFile(tokenFile, []byte("my-token\n"), 0600)).To(Succeed())
+		sink = &VaultSink{KV: &vault.Client{Client: server.Client(), Address: server.URL + "/"}, Mount: "secret", Path: "escrow",
+			TokenFile: tokenFile, Expiry: 24 * time.Hour}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should store the record as KV secret expiring after the TTL", func() {
+		location, err := sink.Store(context.Background(), Record{Namespace: "app1", Name: "my-binding-r1", RotationOf: "my-binding",
+			BindingID: "1234", Data: map[string][]byte{"password": []byte("secret")}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(location).To(Equal("secret/escrow/app1/my-binding-r1"))
+		Expect(tokens).To(Equal([]string{"my-token", "my-token"}))
+		Expect(requests).To(HaveKeyWithValue("/v1/secret/data/escrow/app1/my-binding-r1",
+			map[string]interface{}{"data": map[string]interface{}{"password": "secret"}}))
+		Expect(requests).To(HaveKeyWithValue("/v1/secret/metadata/escrow/app1/my-binding-r1", map[string]interface{}{
+			"delete_version_after": "24h0m0s",
+			"custom_metadata":      map[string]interface{}{"namespace": "app1", "rotationOf": "my-binding", "bindingID": "1234"},
+		}))
+	})
+
+	It("should fail if Vault rejects the record", func() {
+		status = http.StatusForbidden
+		_, err := sink.Store(context.Background(), Record{Namespace: "app1", Name: "my-binding-r1"})
+		Expect(err).To(MatchError(ContainSubstring("status 403: {\"errors\": [\"permission denied\"]}")))
+	})
+
+	It("should fail if the token cannot be read", func() {
+		sink.TokenFile = filepath.Join(GinkgoT().TempDir(), "missing")
+		_, err := sink.Store(context.Background(), Record{Namespace: "app1", Name: "my-binding-r1"})
+		Expect(err).To(MatchError(ContainSubstring("failed to read the Vault token")))
+		Expect(requests).To(BeEmpty())
+	})
+})
@@ -3,6 +3,7 @@ package httputil
 import (
 	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"net"
 	"net/http"
 	"strings"
@@ -73,3 +74,40 @@ func UnmarshalResponse(response *http.Response, jsonResult interface{}) error {
 
 	return json.NewDecoder(response.Body).Decode(&jsonResult)
 }
+
+// TLSVersion converts a TLS version (1.0, 1.1, 1.2 or 1.3) to its id
+func TLSVersion(version string) (uint16, error) {
+	switch strings.TrimSpace(version) {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unsupported TLS version '%s'", version)
+}
+
+// CipherSuites converts a list of IANA cipher suite names to their ids, insecure cipher suites are not supported
+func CipherSuites(names []string) ([]uint16, error) {
+	supported := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		supported[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		}
+		id, ok := supported[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported TLS cipher suite '%s'", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,58 @@
+package httputil
+
+import (
+	"crypto/tls"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TLS options", func() {
+
+	DescribeTable("TLSVersion",
+		func(version string, expected uint16) {
+			id, err := TLSVersion(version)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(Equal(expected))
+		},
+		Entry("1.0", "1.0", uint16(tls.VersionTLS10)),
+		Entry("1.1", "1.1", uint16(tls.VersionTLS11)),
+		Entry("1.2", "1.2", uint16(tls.VersionTLS12)),
+		Entry("1.3", "1.3", uint16(tls.VersionTLS13)),
+		Entry("surrounding blanks", " 1.2 ", uint16(tls.VersionTLS12)),
+	)
+
+	DescribeTable("TLSVersion of an unknown version",
+		func(version string) {
+			_, err := TLSVersion(version)
+			Expect(err).To(MatchError("unsupported TLS version '" + version + "'"))
+		},
+		Entry("blank", ""),
+		Entry("unknown", "1.4"),
+		Entry("name", "TLS12"),
+	)
+
+	DescribeTable("CipherSuites",
+		func(names []string, expected []uint16) {
+			ids, err := CipherSuites(names)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ids).To(Equal(expected))
+		},
+		Entry("no names", nil, []uint16{}),
+		Entry("known names", []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_AES_128_GCM_SHA256"},
+			[]uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_AES_128_GCM_SHA256}),
+		Entry("blank names", []string{" ", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 ", ""},
+			[]uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}),
+	)
+
+	DescribeTable("CipherSuites with an unknown name",
+		func(name string) {
+			_, err := CipherSuites([]string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", name})
+			Expect(err).To(MatchError("unsupported TLS cipher suite '" + name + "'"))
+		},
+		Entry("unknown", "TLS_UNKNOWN"),
+		Entry("insecure", "TLS_RSA_WITH_RC4_128_SHA"),
+		Entry("id", "0xc02f"),
+	)
+})
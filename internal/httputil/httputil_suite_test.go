@@ -0,0 +1,13 @@
+package httputil
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestHTTPUtil(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "HTTP Util Suite")
+}
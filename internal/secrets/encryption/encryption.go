@@ -0,0 +1,126 @@
+// Package encryption encrypts values of binding secrets with a public key provided by the consuming application,
+// so that only the application holding the private key can read them.
+//
+// Values are encrypted with a hybrid scheme: a random AES-256-GCM key encrypts the value and is itself
+// encrypted with RSA-OAEP (SHA-256) using the public key. The result is stored as a JSON envelope.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+)
+
+// Algorithm identifies the encryption scheme in the envelope
+const Algorithm = "RSA-OAEP-256+A256GCM"
+
+// minKeyBits is the minimal size of the RSA public key
+const minKeyBits = 2048
+
+// Envelope is the encrypted form of a value, all fields are base64 encoded by encoding/json
+type Envelope struct {
+	Algorithm string `json:"alg"`
+	// Key is the AES key encrypted with the RSA public key
+	Key []byte `json:"key"`
+	// Nonce is the nonce of the AES-GCM encryption
+	Nonce []byte `json:"nonce"`
+	// Data is the value encrypted with the AES key
+	Data []byte `json:"data"`
+}
+
+// ParsePublicKey parses a PEM encoded RSA public key in PKIX ("PUBLIC KEY") or PKCS#1 ("RSA PUBLIC KEY") format
+func ParsePublicKey(keyPEM []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded public key found")
+	}
+
+	var publicKey *rsa.PublicKey
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("unsupported public key type %T, only RSA keys are supported", key)
+		}
+		publicKey = rsaKey
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		publicKey = key
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %s", block.Type)
+	}
+
+	if publicKey.N.BitLen() < minKeyBits {
+		return nil, fmt.Errorf("public key must have at least %d bits", minKeyBits)
+	}
+	return publicKey, nil
+}
+
+// Encrypt encrypts the value with the public key and returns the JSON encoded envelope
+func Encrypt(publicKey *rsa.PublicKey, value []byte) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&Envelope{
+		Algorithm: Algorithm,
+		Key:       encryptedKey,
+		Nonce:     nonce,
+		Data:      gcm.Seal(nil, nonce, value, nil),
+	})
+}
+
+// Decrypt decrypts a JSON encoded envelope with the private key, it is meant for applications reading the secret
+func Decrypt(privateKey *rsa.PrivateKey, envelopeJSON []byte) ([]byte, error) {
+	envelope := &Envelope{}
+	if err := json.Unmarshal(envelopeJSON, envelope); err != nil {
+		return nil, err
+	}
+	if envelope.Algorithm != Algorithm {
+		return nil, fmt.Errorf("unsupported algorithm %s", envelope.Algorithm)
+	}
+
+	key, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, envelope.Key, nil)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, envelope.Nonce, envelope.Data, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
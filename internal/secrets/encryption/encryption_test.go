@@ -0,0 +1,67 @@
+package encryption
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Secret encryption", func() {
+	var privateKey *rsa.PrivateKey
+
+	BeforeEach(func() {
+		var err error
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	encodePKIX := func(key interface{}) []byte {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		Expect(err).ToNot(HaveOccurred())
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	}
+
+	It("should encrypt values that can be decrypted with the private key", func() {
+		publicKey, err := ParsePublicKey(encodePKIX(&privateKey.PublicKey))
+		Expect(err).ToNot(HaveOccurred())
+
+		encrypted, err := Encrypt(publicKey, []byte("my-password"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(encrypted)).ToNot(ContainSubstring("my-password"))
+
+		decrypted, err := Decrypt(privateKey, encrypted)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(decrypted)).To(Equal("my-password"))
+	})
+
+	It("should parse PKCS#1 public keys", func() {
+		keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&privateKey.PublicKey)})
+		_, err := ParsePublicKey(keyPEM)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should reject keys other than RSA", func() {
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = ParsePublicKey(encodePKIX(&ecKey.PublicKey))
+		Expect(err).To(MatchError(ContainSubstring("only RSA keys are supported")))
+	})
+
+	It("should reject short RSA keys", func() {
+		shortKey, err := rsa.GenerateKey(rand.Reader, 1024)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = ParsePublicKey(encodePKIX(&shortKey.PublicKey))
+		Expect(err).To(MatchError(ContainSubstring("at least 2048 bits")))
+	})
+
+	It("should reject invalid PEM", func() {
+		_, err := ParsePublicKey([]byte("not a key"))
+		Expect(err).To(HaveOccurred())
+	})
+})
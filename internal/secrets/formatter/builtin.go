@@ -0,0 +1,180 @@
+package formatter
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	Hana           = "hana"
+	XSUAA          = "xsuaa"
+	Destination    = "destination"
+	ServiceManager = "service-manager"
+	// DockerConfigJSON adds the registry credentials as a docker config, to use the secret as image pull secret
+	DockerConfigJSON = "dockerconfigjson"
+
+	dockerConfigJSONKey = ".dockerconfigjson"
+
+	hanaJDBCDriver = "com.sap.db.jdbc.Driver"
+)
+
+// registryHostKeys are the keys of the registry host in the credentials of container registries, by precedence
+var registryHostKeys = []string{"hostname", "registry", "server", "url"}
+
+func init() {
+	Register(Hana, Func(formatHana), "hana", "hanatrial")
+	Register(XSUAA, Func(formatXSUAA), "xsuaa")
+	Register(Destination, Func(formatDestination), "destination")
+	Register(ServiceManager, Func(formatServiceManager), "service-manager")
+	Register(DockerConfigJSON, Func(formatDockerConfigJSON))
+}
+
+// formatHana makes sure the jdbc url and driver expected by the hana client libraries are present
+func formatHana(credentials map[string]interface{}) (map[string]interface{}, error) {
+	if err := validateRequiredKeys(Hana, credentials, "host", "port", "user", "password"); err != nil {
+		return nil, err
+	}
+
+	formatted := copyCredentials(credentials)
+	if _, ok := formatted["url"]; !ok {
+		formatted["url"] = fmt.Sprintf("jdbc:sap://%v:%v/?encrypt=true&validateCertificate=true", formatted["host"], formatted["port"])
+	}
+	if _, ok := formatted["driver"]; !ok {
+		formatted["driver"] = hanaJDBCDriver
+	}
+	return formatted, nil
+}
+
+// formatXSUAA makes sure the uaa domain expected by the xssec libraries is present
+func formatXSUAA(credentials map[string]interface{}) (map[string]interface{}, error) {
+	if err := validateRequiredKeys(XSUAA, credentials, "clientid", "url"); err != nil {
+		return nil, err
+	}
+
+	formatted := copyCredentials(credentials)
+	if _, ok := formatted["uaadomain"]; !ok {
+		if domain := uaaDomain(formatted["url"]); len(domain) > 0 {
+			formatted["uaadomain"] = domain
+		}
+	}
+	return formatted, nil
+}
+
+// formatDestination flattens the nested uaa credentials used by the destination service client libraries
+func formatDestination(credentials map[string]interface{}) (map[string]interface{}, error) {
+	formatted := hoist(credentials, "uaa")
+	if err := validateRequiredKeys(Destination, formatted, "clientid", "url", "uri"); err != nil {
+		return nil, err
+	}
+	return formatted, nil
+}
+
+// formatServiceManager flattens the nested uaa credentials used by the service manager client libraries
+func formatServiceManager(credentials map[string]interface{}) (map[string]interface{}, error) {
+	formatted := hoist(credentials, "uaa")
+	if err := validateRequiredKeys(ServiceManager, formatted, "clientid", "url", "sm_url"); err != nil {
+		return nil, err
+	}
+	return formatted, nil
+}
+
+// formatDockerConfigJSON adds the registry credentials as docker config under the .dockerconfigjson key of
+// kubernetes.io/dockerconfigjson secrets
+func formatDockerConfigJSON(credentials map[string]interface{}) (map[string]interface{}, error) {
+	if err := validateRequiredKeys(DockerConfigJSON, credentials, "username", "password"); err != nil {
+		return nil, err
+	}
+	host := registryHost(credentials)
+	if len(host) == 0 {
+		return nil, fmt.Errorf("credentials do not match the '%s' secret format, missing keys: %s", DockerConfigJSON, strings.Join(registryHostKeys, " or "))
+	}
+
+	username := fmt.Sprint(credentials["username"])
+	password := fmt.Sprint(credentials["password"])
+	auth := map[string]string{
+		"username": username,
+		"password": password,
+		"auth":     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+	}
+	if email, ok := credentials["email"].(string); ok {
+		auth["email"] = email
+	}
+	dockerConfigJSON, err := json.Marshal(map[string]interface{}{"auths": map[string]interface{}{host: auth}})
+	if err != nil {
+		return nil, err
+	}
+
+	formatted := copyCredentials(credentials)
+	formatted[dockerConfigJSONKey] = string(dockerConfigJSON)
+	return formatted, nil
+}
+
+// registryHost returns the host of the registry, brokers return it under different keys and some as URL
+func registryHost(credentials map[string]interface{}) string {
+	for _, key := range registryHostKeys {
+		value, ok := credentials[key].(string)
+		if !ok || len(value) == 0 {
+			continue
+		}
+		if u, err := url.Parse(value); err == nil && len(u.Host) > 0 {
+			return u.Host
+		}
+		return value
+	}
+	return ""
+}
+
+func validateRequiredKeys(format string, credentials map[string]interface{}, keys ...string) error {
+	var missing []string
+	for _, key := range keys {
+		if _, ok := credentials[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("credentials do not match the '%s' secret format, missing keys: %s", format, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func copyCredentials(credentials map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(credentials))
+	for k, v := range credentials {
+		res[k] = v
+	}
+	return res
+}
+
+// hoist copies the properties of a nested object to the top level without overriding existing keys
+func hoist(credentials map[string]interface{}, key string) map[string]interface{} {
+	res := copyCredentials(credentials)
+	nested, ok := credentials[key].(map[string]interface{})
+	if !ok {
+		return res
+	}
+	for k, v := range nested {
+		if _, exists := res[k]; !exists {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+func uaaDomain(rawURL interface{}) string {
+	str, ok := rawURL.(string)
+	if !ok {
+		return ""
+	}
+	u, err := url.Parse(str)
+	if err != nil {
+		return ""
+	}
+	parts := strings.SplitN(u.Hostname(), ".", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	return parts[1]
+}
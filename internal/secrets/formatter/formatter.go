@@ -0,0 +1,101 @@
+package formatter
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const (
+	// None disables formatting, the credentials are stored exactly as returned by the broker
+	None = "none"
+)
+
+// Formatter shapes the credentials returned by Service Manager into the canonical
+// structure expected by the client libraries of a specific service offering
+type Formatter interface {
+	Format(credentials map[string]interface{}) (map[string]interface{}, error)
+}
+
+// Func is an adapter to allow the use of ordinary functions as formatters
+type Func func(credentials map[string]interface{}) (map[string]interface{}, error)
+
+func (f Func) Format(credentials map[string]interface{}) (map[string]interface{}, error) {
+	return f(credentials)
+}
+
+var (
+	mutex      sync.RWMutex
+	formatters = make(map[string]Formatter)
+	offerings  = make(map[string]string)
+)
+
+// Register adds a formatter to the registry under the given name and associates it with the provided
+// service offerings, so it is selected automatically for bindings of instances of these offerings
+func Register(name string, formatter Formatter, offeringNames ...string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	formatters[name] = formatter
+	for _, offering := range offeringNames {
+		offerings[offering] = name
+	}
+}
+
+// Get returns the formatter registered under the given name
+func Get(name string) (Formatter, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	f, ok := formatters[name]
+	return f, ok
+}
+
+// IsValid checks whether the given name can be used as a secret format
+func IsValid(name string) bool {
+	if len(name) == 0 || name == None {
+		return true
+	}
+	_, ok := Get(name)
+	return ok
+}
+
+// Names returns the names of all registered formatters
+func Names() []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve returns the formatter to use for a binding.
+// If secretFormat is specified it takes precedence, otherwise the formatter registered for the offering is used.
+// The second return value indicates whether the formatter was explicitly requested.
+func Resolve(secretFormat, offeringName string) (Formatter, bool, error) {
+	if secretFormat == None {
+		return nil, true, nil
+	}
+
+	if len(secretFormat) > 0 {
+		f, ok := Get(secretFormat)
+		if !ok {
+			return nil, true, fmt.Errorf("unknown secret format '%s', supported formats are %v", secretFormat, append(Names(), None))
+		}
+		return f, true, nil
+	}
+
+	mutex.RLock()
+	name, ok := offerings[offeringName]
+	mutex.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+
+	f, _ := Get(name)
+	return f, false, nil
+}
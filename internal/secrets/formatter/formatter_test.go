@@ -0,0 +1,109 @@
+package formatter
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Secret formatter", func() {
+
+	Describe("Resolve", func() {
+		It("should select the formatter registered for the offering", func() {
+			f, explicit, err := Resolve("", "xsuaa")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f).ToNot(BeNil())
+			Expect(explicit).To(BeFalse())
+		})
+
+		It("should return nil for offerings without formatter", func() {
+			f, explicit, err := Resolve("", "some-offering")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f).To(BeNil())
+			Expect(explicit).To(BeFalse())
+		})
+
+		It("should prefer the requested format over the offering", func() {
+			f, explicit, err := Resolve(Hana, "xsuaa")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(explicit).To(BeTrue())
+			_, err = f.Format(map[string]interface{}{"clientid": "id", "url": "https://a.b.c"})
+			Expect(err).To(MatchError(ContainSubstring("'hana' secret format")))
+		})
+
+		It("should disable formatting when format is none", func() {
+			f, explicit, err := Resolve(None, "xsuaa")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f).To(BeNil())
+			Expect(explicit).To(BeTrue())
+		})
+
+		It("should fail for unknown format", func() {
+			_, _, err := Resolve("unknown", "xsuaa")
+			Expect(err).To(MatchError(ContainSubstring("unknown secret format 'unknown'")))
+		})
+	})
+
+	Describe("IsValid", func() {
+		It("should accept empty, none and registered formats", func() {
+			Expect(IsValid("")).To(BeTrue())
+			Expect(IsValid(None)).To(BeTrue())
+			Expect(IsValid(Destination)).To(BeTrue())
+			Expect(IsValid("unknown")).To(BeFalse())
+		})
+	})
+
+	Describe("built-in formats", func() {
+		It("hana should add url and driver", func() {
+			res, err := formatHana(map[string]interface{}{"host": "h", "port": "443", "user": "u", "password": "p"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res["url"]).To(Equal("jdbc:sap://h:443/?encrypt=true&validateCertificate=true"))
+			Expect(res["driver"]).To(Equal(hanaJDBCDriver))
+		})
+
+		It("hana should keep the url returned by the broker", func() {
+			res, err := formatHana(map[string]interface{}{"host": "h", "port": "443", "user": "u", "password": "p", "url": "jdbc:custom"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res["url"]).To(Equal("jdbc:custom"))
+		})
+
+		It("xsuaa should derive the uaa domain", func() {
+			res, err := formatXSUAA(map[string]interface{}{"clientid": "id", "url": "https://sub.authentication.eu10.hana.ondemand.com"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res["uaadomain"]).To(Equal("authentication.eu10.hana.ondemand.com"))
+		})
+
+		It("destination should flatten nested uaa credentials", func() {
+			res, err := formatDestination(map[string]interface{}{
+				"uri": "https://destination",
+				"uaa": map[string]interface{}{"clientid": "id", "url": "https://uaa"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res["clientid"]).To(Equal("id"))
+			Expect(res["url"]).To(Equal("https://uaa"))
+			Expect(res).To(HaveKey("uaa"))
+		})
+
+		It("service-manager should fail when sm_url is missing", func() {
+			_, err := formatServiceManager(map[string]interface{}{"clientid": "id", "url": "https://uaa"})
+			Expect(err).To(MatchError(ContainSubstring("missing keys: sm_url")))
+		})
+
+		It("dockerconfigjson should add the registry credentials as docker config", func() {
+			res, err := formatDockerConfigJSON(map[string]interface{}{"hostname": "registry.example.com", "username": "u", "password": "p"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res["username"]).To(Equal("u"))
+			Expect(res[dockerConfigJSONKey]).To(MatchJSON(`{"auths": {"registry.example.com": {"username": "u", "password": "p", "auth": "dTpw"}}}`))
+		})
+
+		It("dockerconfigjson should take the registry host from its url", func() {
+			res, err := formatDockerConfigJSON(map[string]interface{}{"url": "https://registry.example.com/v2/", "username": "u", "password": "p", "email": "u@example.com"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res[dockerConfigJSONKey]).To(MatchJSON(`{"auths": {"registry.example.com": {"username": "u", "password": "p", "auth": "dTpw", "email": "u@example.com"}}}`))
+		})
+
+		It("dockerconfigjson should fail when the registry host is missing", func() {
+			_, err := formatDockerConfigJSON(map[string]interface{}{"username": "u", "password": "p"})
+			Expect(err).To(MatchError(ContainSubstring("missing keys: hostname or registry or server or url")))
+		})
+	})
+})
@@ -2,11 +2,19 @@ package secrets
 
 import (
 	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
 
 	"fmt"
 
+	"github.com/SAP/sap-btp-service-operator/internal/config"
 	"github.com/go-logr/logr"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -18,12 +26,37 @@ const (
 	SAPBTPOperatorTLSSecretName = "sap-btp-service-operator-tls"
 )
 
+// credentialsFiles are the keys of the cluster secrets that are read from the credentials directory
+var credentialsFiles = []string{"clientid", "clientsecret", "sm_url", "tokenurl", "tokenurlsuffix", v1.TLSCertKey, v1.TLSPrivateKeyKey}
+
+// LandscapeSecretName returns the name of the secret in the management namespace with the access credentials of a landscape
+func LandscapeSecretName(landscape string) string {
+	return fmt.Sprintf("%s-%s", SAPBTPOperatorSecretName, landscape)
+}
+
+// IsReservedLandscape returns true if the secret of the landscape is another secret of the operator,
+// e.g. the secret of landscape tls is the TLS secret sap-btp-service-operator-tls
+func IsReservedLandscape(landscape string) bool {
+	return LandscapeSecretName(landscape) == SAPBTPOperatorTLSSecretName
+}
+
 type SecretResolver struct {
 	ManagementNamespace    string
 	ReleaseNamespace       string
 	EnableNamespaceSecrets bool
+	CredentialsDir         string
 	Client                 client.Client
 	Log                    logr.Logger
+	// Live holds the management namespace changed at runtime with the OperatorConfig, ManagementNamespace is used if
+	// it is nil
+	Live *config.Live
+}
+
+func (sr *SecretResolver) managementNamespace() string {
+	if sr.Live == nil {
+		return sr.ManagementNamespace
+	}
+	return sr.Live.Current().ManagementNamespace
 }
 
 func (sr *SecretResolver) GetSecretForResource(ctx context.Context, namespace, name, btpAccessSecret string) (*v1.Secret, error) {
@@ -31,8 +64,8 @@ func (sr *SecretResolver) GetSecretForResource(ctx context.Context, namespace, n
 
 	if len(btpAccessSecret) > 0 {
 		sr.Log.Info(fmt.Sprintf("Searching for secret name %s in namespace %s",
-			btpAccessSecret, sr.ManagementNamespace))
-		err := sr.Client.Get(ctx, types.NamespacedName{Name: btpAccessSecret, Namespace: sr.ManagementNamespace}, secretForResource)
+			btpAccessSecret, sr.managementNamespace()))
+		err := sr.Client.Get(ctx, types.NamespacedName{Name: btpAccessSecret, Namespace: sr.managementNamespace()}, secretForResource)
 		if err != nil {
 			sr.Log.Error(err, fmt.Sprintf("Could not fetch secret named %s", btpAccessSecret))
 			return nil, err
@@ -55,8 +88,8 @@ func (sr *SecretResolver) GetSecretForResource(ctx context.Context, namespace, n
 	}
 
 	// secret not found in resource namespace, search for namespace-specific secret in management namespace
-	sr.Log.Info("Searching for namespace secret in management namespace", "namespace", namespace, "managementNamespace", sr.ManagementNamespace, "name", name)
-	err := sr.Client.Get(ctx, types.NamespacedName{Namespace: sr.ManagementNamespace, Name: fmt.Sprintf("%s-%s", namespace, name)}, secretForResource)
+	sr.Log.Info("Searching for namespace secret in management namespace", "namespace", namespace, "managementNamespace", sr.managementNamespace(), "name", name)
+	err := sr.Client.Get(ctx, types.NamespacedName{Namespace: sr.managementNamespace(), Name: fmt.Sprintf("%s-%s", namespace, name)}, secretForResource)
 	if err == nil {
 		return secretForResource, nil
 	}
@@ -71,6 +104,10 @@ func (sr *SecretResolver) GetSecretForResource(ctx context.Context, namespace, n
 }
 
 func (sr *SecretResolver) getDefaultSecret(ctx context.Context, name string) (*v1.Secret, error) {
+	if len(sr.CredentialsDir) > 0 {
+		return sr.getFileSecret(name)
+	}
+
 	secretForResource := &v1.Secret{}
 	sr.Log.Info("Searching for cluster secret", "releaseNamespace", sr.ReleaseNamespace, "name", name)
 	err := sr.Client.Get(ctx, types.NamespacedName{Namespace: sr.ReleaseNamespace, Name: name}, secretForResource)
@@ -80,3 +117,29 @@ func (sr *SecretResolver) getDefaultSecret(ctx context.Context, name string) (*v
 	}
 	return secretForResource, nil
 }
+
+// getFileSecret builds the cluster secret from the files in the credentials directory.
+// The files are read on every call, so refreshed credentials are used without restarting the operator.
+func (sr *SecretResolver) getFileSecret(name string) (*v1.Secret, error) {
+	sr.Log.Info("Reading cluster credentials from files", "credentialsDir", sr.CredentialsDir, "name", name)
+	data := make(map[string][]byte)
+	for _, key := range credentialsFiles {
+		value, err := os.ReadFile(filepath.Join(sr.CredentialsDir, key))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			sr.Log.Error(err, "Could not read credentials file", "file", key)
+			return nil, err
+		}
+		data[key] = value
+	}
+
+	if len(data) == 0 {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+	}
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: sr.ReleaseNamespace},
+		Data:       data,
+	}, nil
+}
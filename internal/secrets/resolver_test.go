@@ -2,6 +2,8 @@ package secrets_test
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 
 	"github.com/SAP/sap-btp-service-operator/internal/secrets"
 	"github.com/google/uuid"
@@ -178,6 +180,49 @@ var _ = Describe("Secrets Resolver", func() {
 		})
 	})
 
+	Context("Cluster credentials in files", func() {
+		BeforeEach(func() {
+			var err error
+			resolver.CredentialsDir, err = os.MkdirTemp("", "credentials")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(resolver.CredentialsDir)).To(Succeed())
+		})
+
+		It("should fail to resolve the secret when there are no credentials files", func() {
+			validateSecretNotResolved()
+		})
+
+		It("should resolve the secret from the credentials files", func() {
+			expectedClientID = uuid.New().String()
+			Expect(os.WriteFile(filepath.Join(resolver.CredentialsDir, "clientid"), []byte(expectedClientID), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(resolver.CredentialsDir, "clientsecret"), []byte("client-secret"), 0600)).To(Succeed())
+			validateSecretResolved()
+		})
+
+		It("should resolve refreshed credentials files", func() {
+			expectedClientID = uuid.New().String()
+			Expect(os.WriteFile(filepath.Join(resolver.CredentialsDir, "clientid"), []byte(expectedClientID), 0600)).To(Succeed())
+			validateSecretResolved()
+
+			expectedClientID = uuid.New().String()
+			Expect(os.WriteFile(filepath.Join(resolver.CredentialsDir, "clientid"), []byte(expectedClientID), 0600)).To(Succeed())
+			validateSecretResolved()
+		})
+
+		When("namespace secret exists in management namespace", func() {
+			BeforeEach(func() {
+				secret = createSecret(testNamespace, managementNamespace)
+			})
+
+			It("should resolve the namespace secret", func() {
+				validateSecretResolved()
+			})
+		})
+	})
+
 	Context("btp access secret in management namespace", func() {
 		subaccountID := "12345"
 		BeforeEach(func() {
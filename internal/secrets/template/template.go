@@ -3,8 +3,11 @@ package template
 import (
 	"fmt"
 	"io"
+	"regexp"
+	"sort"
 	"strings"
 	"text/template"
+	"text/template/parse"
 
 	sprig "github.com/Masterminds/sprig/v3"
 	"github.com/SAP/sap-btp-service-operator/internal/ioutils"
@@ -24,55 +27,192 @@ var validGroupVersionKind = schema.GroupVersionKind{
 	Version: "v1",
 }
 
+var configMapGroupVersionKind = schema.GroupVersionKind{
+	Group:   "",
+	Kind:    "ConfigMap",
+	Version: "v1",
+}
+
+var documentSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// Delimiters are the action delimiters of a template, "{{" and "}}" are used if they are empty
+type Delimiters struct {
+	Left  string
+	Right string
+}
+
 // CreateSecretFromTemplate executes the template to create a secret objects, validates and returns it
 // The template needs to be a v1 Secret and in metadata labels and annotations are allowed only
 // Set templateOptions of the "text/template" package to specify the template behavior
 func CreateSecretFromTemplate(templateName, secretTemplate string, data map[string]interface{}) (*corev1.Secret, error) {
 
-	secretManifest, err := executeTemplate(templateName, secretTemplate, data)
+	secretManifest, err := executeTemplate(templateName, secretTemplate, Delimiters{}, data)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not execute template")
 	}
 
-	yamlSerializer := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
-	o, err := runtime.Decode(yamlSerializer, []byte(secretManifest))
+	obj, err := decodeManifest(secretManifest)
+	if err != nil {
+		return nil, err
+	}
+
+	// validate GroupVersionKind
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk != validGroupVersionKind {
+		return nil, fmt.Errorf("generated secret manifest has unexpected type: %q", gvk.String())
+	}
+	return toSecret(obj)
+}
+
+// CreateObjectsFromTemplate executes a template which may generate several YAML documents separated by "---".
+// Exactly one document must be a v1 Secret, validated like in CreateSecretFromTemplate, the other documents must be
+// v1 ConfigMaps with a name, and labels and annotations only, in their metadata
+func CreateObjectsFromTemplate(templateName, secretTemplate string, delims Delimiters, data map[string]interface{}) (*corev1.Secret, []*corev1.ConfigMap, error) {
+	manifest, err := executeTemplate(templateName, secretTemplate, delims, data)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not execute template")
+	}
+	return objectsFromManifest(manifest)
+}
+
+// DryRun parses the template and renders it with placeholder values for the fields it references below the roots,
+// e.g. "smBindingCredentials", to verify that it generates the manifests CreateObjectsFromTemplate accepts.
+// Errors executing the template are not reported, since they depend on the actual values of the fields, e.g. ranges
+// over fields or fields referenced relative to the dot of a with block.
+func DryRun(templateName, text string, delims Delimiters, roots ...string) error {
+	data := make(map[string]interface{}, len(roots))
+	for _, root := range roots {
+		fields, err := ReferencedFields(templateName, text, delims, root)
+		if err != nil {
+			return err
+		}
+		data[root] = placeholders(fields)
+	}
+	manifest, err := executeTemplate(templateName, text, delims, data)
 	if err != nil {
-		return nil, errors.Wrapf(err, "the generated secret manifest is not a valid YAML document: %q", secretManifest)
+		return nil
+	}
+	_, _, err = objectsFromManifest(manifest)
+	return err
+}
+
+// placeholderValue is the value of the fields in a dry run, valid base64 so that it can be used in the data of a secret
+const placeholderValue = "cGxhY2Vob2xkZXI="
+
+// placeholders returns an object with the placeholder value at each of the dotted paths, paths with children are objects
+func placeholders(paths []string) map[string]interface{} {
+	sort.Strings(paths)
+	root := make(map[string]interface{})
+	for _, path := range paths {
+		object := root
+		keys := strings.Split(path, ".")
+		for _, key := range keys[:len(keys)-1] {
+			child, ok := object[key].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				object[key] = child
+			}
+			object = child
+		}
+		if _, ok := object[keys[len(keys)-1]]; !ok {
+			object[keys[len(keys)-1]] = placeholderValue
+		}
+	}
+	return root
+}
+
+func objectsFromManifest(manifest string) (*corev1.Secret, []*corev1.ConfigMap, error) {
+	var secret *corev1.Secret
+	var configMaps []*corev1.ConfigMap
+	for _, document := range documentSeparator.Split(manifest, -1) {
+		if len(strings.TrimSpace(document)) == 0 {
+			continue
+		}
+		obj, err := decodeManifest(document)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch gvk := obj.GetObjectKind().GroupVersionKind(); gvk {
+		case validGroupVersionKind:
+			if secret != nil {
+				return nil, nil, fmt.Errorf("the template generated more than one secret manifest")
+			}
+			if secret, err = toSecret(obj); err != nil {
+				return nil, nil, err
+			}
+		case configMapGroupVersionKind:
+			configMap, err := toConfigMap(obj)
+			if err != nil {
+				return nil, nil, err
+			}
+			configMaps = append(configMaps, configMap)
+		default:
+			return nil, nil, fmt.Errorf("generated secret manifest has unexpected type: %q", gvk.String())
+		}
 	}
 
-	obj := o.(*unstructured.Unstructured)
+	if secret == nil {
+		return nil, nil, fmt.Errorf("the template did not generate a secret manifest")
+	}
+	return secret, configMaps, nil
+}
 
-	// validate metadata
-	allowedMetadataFields := map[string]string{"labels": "any", "annotations": "any"}
+func decodeManifest(manifest string) (*unstructured.Unstructured, error) {
+	yamlSerializer := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+	o, err := runtime.Decode(yamlSerializer, []byte(manifest))
+	if err != nil {
+		return nil, errors.Wrapf(err, "the generated secret manifest is not a valid YAML document: %q", manifest)
+	}
+	return o.(*unstructured.Unstructured), nil
+}
 
+func validateMetadata(obj *unstructured.Unstructured, allowedMetadataFields map[string]string) error {
 	metadataKeyValues, _, err := unstructured.NestedMap(obj.Object, "metadata")
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to read metadata fields of generated secret manifest")
+		return errors.Wrapf(err, "failed to read metadata fields of generated %s manifest", strings.ToLower(obj.GetKind()))
 	}
 
 	for metadataKey := range metadataKeyValues {
 		if _, ok := allowedMetadataFields[metadataKey]; !ok {
-			return nil, fmt.Errorf("metadata field %s is not allowed in generated secret manifest", metadataKey)
+			return fmt.Errorf("metadata field %s is not allowed in generated %s manifest", metadataKey, strings.ToLower(obj.GetKind()))
 		}
 	}
+	return nil
+}
 
-	// validate GroupVersionKind
-	gvk := obj.GetObjectKind().GroupVersionKind()
-	if gvk != validGroupVersionKind {
-		return nil, fmt.Errorf("generated secret manifest has unexpected type: %q", gvk.String())
+func toSecret(obj *unstructured.Unstructured) (*corev1.Secret, error) {
+	if err := validateMetadata(obj, map[string]string{"labels": "any", "annotations": "any"}); err != nil {
+		return nil, err
 	}
 
 	var secret *corev1.Secret
-	err = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &secret)
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &secret)
 	if err != nil {
 		return nil, errors.Wrap(err, "the generated secret manifest is not valid")
 	}
 	return secret, nil
 }
 
-// ParseTemplate create a new template with given name, add allowed sprig functions and parse the template
-func ParseTemplate(templateName, text string) (*template.Template, error) {
-	return template.New(templateName).Funcs(filteredFuncMap()).Parse(text)
+func toConfigMap(obj *unstructured.Unstructured) (*corev1.ConfigMap, error) {
+	if err := validateMetadata(obj, map[string]string{"name": "any", "labels": "any", "annotations": "any"}); err != nil {
+		return nil, err
+	}
+	if len(obj.GetName()) == 0 {
+		return nil, fmt.Errorf("generated configmap manifest has no name")
+	}
+
+	var configMap *corev1.ConfigMap
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &configMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "the generated configmap manifest is not valid")
+	}
+	return configMap, nil
+}
+
+// ParseTemplate create a new template with given name and delimiters, add allowed sprig functions and parse the template
+func ParseTemplate(templateName, text string, delims Delimiters) (*template.Template, error) {
+	return template.New(templateName).Delims(delims.Left, delims.Right).Funcs(filteredFuncMap()).Parse(text)
 }
 
 func filteredFuncMap() template.FuncMap {
@@ -86,8 +226,8 @@ func filteredFuncMap() template.FuncMap {
 	return r
 }
 
-func executeTemplate(templateName, text string, parameters map[string]interface{}) (string, error) {
-	t, err := ParseTemplate(templateName, text)
+func executeTemplate(templateName, text string, delims Delimiters, parameters map[string]interface{}) (string, error) {
+	t, err := ParseTemplate(templateName, text, delims)
 	if err != nil {
 		return "", err
 	}
@@ -114,3 +254,96 @@ func executeTemplate(templateName, text string, parameters map[string]interface{
 
 	return stringBuilder.String(), nil
 }
+
+// ReferencedFields returns the paths of the fields below root that the template references, e.g. "uaa.clientid" for
+// {{ .root.uaa.clientid }} or {{ index .root "uaa" "clientid" }}. Fields referenced relative to the dot of a range or
+// with block are not resolved.
+func ReferencedFields(templateName, text string, delims Delimiters, root string) ([]string, error) {
+	t, err := ParseTemplate(templateName, text, delims)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]bool)
+	addPath := func(idents []string) {
+		if len(idents) > 1 && idents[0] == root {
+			fields[strings.Join(idents[1:], ".")] = true
+		}
+	}
+	rootIdents := func(node parse.Node) []string {
+		switch n := node.(type) {
+		case *parse.FieldNode:
+			return n.Ident
+		case *parse.VariableNode:
+			if len(n.Ident) > 0 && n.Ident[0] == "$" {
+				return n.Ident[1:]
+			}
+		}
+		return nil
+	}
+
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.IfNode:
+			walk(&n.BranchNode)
+		case *parse.RangeNode:
+			walk(&n.BranchNode)
+		case *parse.WithNode:
+			walk(&n.BranchNode)
+		case *parse.BranchNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			if identifier, ok := n.Args[0].(*parse.IdentifierNode); ok && identifier.Ident == "index" && len(n.Args) > 2 {
+				if idents := rootIdents(n.Args[1]); len(idents) > 0 {
+					path := append([]string{}, idents...)
+					for _, arg := range n.Args[2:] {
+						key, ok := arg.(*parse.StringNode)
+						if !ok {
+							break
+						}
+						path = append(path, key.Text)
+					}
+					addPath(path)
+				}
+			}
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode, *parse.VariableNode:
+			addPath(rootIdents(n))
+		}
+	}
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree != nil {
+			walk(tmpl.Tree.Root)
+		}
+	}
+
+	paths := make([]string, 0, len(fields))
+	for path := range fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
@@ -0,0 +1,159 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SAP/sap-btp-service-operator/internal/httputil"
+)
+
+// KV writes credentials to secrets of KV version 2 secrets engines of Vault, e.g. of bindings with a Vault credentials
+// target or of rotated bindings in escrow, with the tokens of logins with the Kubernetes auth method or of a token file
+type KV interface {
+	// Login exchanges the service account token for a Vault token of the role of the Kubernetes auth method mounted at
+	// authMount, and returns the token and how long it is valid, 0 if it doesn't expire
+	Login(ctx context.Context, authMount, role, jwt string) (string, time.Duration, error)
+	// Write stores the data as a new version of the secret at path of the secrets engine mounted at mount, and returns the version
+	Write(ctx context.Context, token, mount, secretPath string, data map[string]string) (int64, error)
+	// WriteMetadata sets the metadata of the secret
+	WriteMetadata(ctx context.Context, token, mount, secretPath string, metadata Metadata) error
+	// Version returns the current version of the secret, 0 if it doesn't exist or its current version is deleted. Only
+	// the metadata of the secret is read.
+	Version(ctx context.Context, token, mount, secretPath string) (int64, error)
+	// Delete deletes all versions of the secret
+	Delete(ctx context.Context, token, mount, secretPath string) error
+}
+
+// Metadata of a secret in a KV version 2 secrets engine
+type Metadata struct {
+	// CustomMetadata makes the secret searchable in Vault
+	CustomMetadata map[string]string
+	// DeleteVersionAfter is how long Vault keeps a version of the secret, 0 keeps it until it is deleted
+	DeleteVersionAfter time.Duration
+}
+
+// Client calls the Vault server at Address
+type Client struct {
+	Client *http.Client
+	// Address of the Vault server, e.g. https://vault.example.com:8200
+	Address string
+}
+
+type secretResponse struct {
+	Auth *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int64  `json:"lease_duration"`
+	} `json:"auth"`
+	Data *struct {
+		Version        int64 `json:"version"`
+		CurrentVersion int64 `json:"current_version"`
+		Versions       map[string]struct {
+			DeletionTime string `json:"deletion_time"`
+			Destroyed    bool   `json:"destroyed"`
+		} `json:"versions"`
+	} `json:"data"`
+}
+
+func (c *Client) Login(ctx context.Context, authMount, role, jwt string) (string, time.Duration, error) {
+	response := &secretResponse{}
+	if _, err := c.call(ctx, http.MethodPost, "", path.Join("auth", authMount, "login"), map[string]string{"role": role, "jwt": jwt}, response); err != nil {
+		return "", 0, err
+	}
+	if response.Auth == nil || len(response.Auth.ClientToken) == 0 {
+		return "", 0, fmt.Errorf("vault returned no token for role %s of auth method %s", role, authMount)
+	}
+	return response.Auth.ClientToken, time.Duration(response.Auth.LeaseDuration) * time.Second, nil
+}
+
+func (c *Client) Write(ctx context.Context, token, mount, secretPath string, data map[string]string) (int64, error) {
+	response := &secretResponse{}
+	if _, err := c.call(ctx, http.MethodPost, token, path.Join(mount, "data", secretPath), map[string]interface{}{"data": data}, response); err != nil {
+		return 0, err
+	}
+	if response.Data == nil {
+		return 0, fmt.Errorf("vault returned no version for secret %s", path.Join(mount, secretPath))
+	}
+	return response.Data.Version, nil
+}
+
+func (c *Client) WriteMetadata(ctx context.Context, token, mount, secretPath string, metadata Metadata) error {
+	body := map[string]interface{}{"custom_metadata": metadata.CustomMetadata}
+	if metadata.DeleteVersionAfter > 0 {
+		body["delete_version_after"] = metadata.DeleteVersionAfter.String()
+	}
+	_, err := c.call(ctx, http.MethodPost, token, path.Join(mount, "metadata", secretPath), body, nil)
+	return err
+}
+
+func (c *Client) Version(ctx context.Context, token, mount, secretPath string) (int64, error) {
+	response := &secretResponse{}
+	found, err := c.call(ctx, http.MethodGet, token, path.Join(mount, "metadata", secretPath), nil, response)
+	if err != nil || !found || response.Data == nil {
+		return 0, err
+	}
+	current, found := response.Data.Versions[strconv.FormatInt(response.Data.CurrentVersion, 10)]
+	if !found || current.Destroyed {
+		return 0, nil
+	}
+	// the deletion time is in the future for versions deleted by the delete_version_after of the secret
+	if deletionTime, err := time.Parse(time.RFC3339Nano, current.DeletionTime); err == nil && !deletionTime.After(time.Now()) {
+		return 0, nil
+	}
+	return response.Data.CurrentVersion, nil
+}
+
+func (c *Client) Delete(ctx context.Context, token, mount, secretPath string) error {
+	_, err := c.call(ctx, http.MethodDelete, token, path.Join(mount, "metadata", secretPath), nil, nil)
+	return err
+}
+
+// call sends the request to the Vault API and decodes the response into result. It returns false if Vault responded
+// with 404.
+func (c *Client) call(ctx context.Context, method, token, apiPath string, body, result interface{}) (bool, error) {
+	var payload io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return false, err
+		}
+		payload = bytes.NewReader(encoded)
+	}
+	url := fmt.Sprintf("%s/v1/%s", httputil.NormalizeURL(c.Address), apiPath)
+	req, err := http.NewRequestWithContext(ctx, method, url, payload)
+	if err != nil {
+		return false, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if len(token) > 0 {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to call Vault: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		// the errors of Vault don't contain the request body
+		response, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, fmt.Errorf("vault responded to %s with status %d: %s", apiPath, resp.StatusCode, strings.TrimSpace(string(response)))
+	}
+	if result != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return false, fmt.Errorf("failed to decode the response of Vault to %s: %w", apiPath, err)
+		}
+	}
+	return true, nil
+}
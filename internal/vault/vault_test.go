@@ -0,0 +1,115 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Vault client", func() {
+	var server *httptest.Server
+	var client *Client
+	var requests map[string]map[string]interface{}
+	var tokens map[string]string
+	var responses map[string]string
+
+	BeforeEach(func() {
+		requests = map[string]map[string]interface{}{}
+		tokens = map[string]string{}
+		responses = map[string]string{}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Method + " " + r.URL.Path
+			if r.Method == http.MethodPost {
+				body := map[string]interface{}{}
+				Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+				requests[key] = body
+			}
+			tokens[key] = r.Header.Get("X-Vault-Token")
+			response, found := responses[key]
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(response))
+		}))
+		client = &Client{Client: server.Client(), Address: server.URL + "/"}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should log in with the service account token", func() {
+		responses["POST /v1/auth/kubernetes/login"] = `{"auth": {"client_token": "my-token", "lease_duration": 0}}`
+		token, ttl, err := client.Login(context.Background(), "kubernetes", "my-role", "my-jwt")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(token).To(Equal("my-token"))
+		Expect(ttl).To(BeZero())
+		Expect(requests).To(HaveKeyWithValue("POST /v1/auth/kubernetes/login", map[string]interface{}{"role": "my-role", "jwt": "my-jwt"}))
+		Expect(tokens).To(HaveKeyWithValue("POST /v1/auth/kubernetes/login", ""))
+	})
+
+	It("should fail to log in if the role is not bound to the service account", func() {
+		responses["POST /v1/auth/kubernetes/login"] = `{"auth": null}`
+		_, _, err := client.Login(context.Background(), "kubernetes", "my-role", "my-jwt")
+		Expect(err).To(MatchError(ContainSubstring("vault returned no token for role my-role")))
+	})
+
+	It("should return how long the token is valid", func() {
+		responses["POST /v1/auth/kubernetes/login"] = `{"auth": {"client_token": "my-token", "lease_duration": 3600}}`
+		_, ttl, err := client.Login(context.Background(), "kubernetes", "my-role", "my-jwt")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ttl).To(Equal(time.Hour))
+	})
+
+	It("should write the secret and return its version", func() {
+		responses["POST /v1/secret/data/apps/my-binding"] = `{"data": {"version": 3}}`
+		version, err := client.Write(context.Background(), "my-token", "secret", "apps/my-binding", map[string]string{"password": "secret"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(version).To(Equal(int64(3)))
+		Expect(requests).To(HaveKeyWithValue("POST /v1/secret/data/apps/my-binding",
+			map[string]interface{}{"data": map[string]interface{}{"password": "secret"}}))
+		Expect(tokens).To(HaveKeyWithValue("POST /v1/secret/data/apps/my-binding", "my-token"))
+	})
+
+	It("should write the metadata of the secret", func() {
+		responses["POST /v1/secret/metadata/escrow/my-binding"] = ""
+		Expect(client.WriteMetadata(context.Background(), "my-token", "secret", "escrow/my-binding",
+			Metadata{CustomMetadata: map[string]string{"namespace": "app1"}, DeleteVersionAfter: 24 * time.Hour})).To(Succeed())
+		Expect(requests).To(HaveKeyWithValue("POST /v1/secret/metadata/escrow/my-binding", map[string]interface{}{
+			"delete_version_after": "24h0m0s",
+			"custom_metadata":      map[string]interface{}{"namespace": "app1"},
+		}))
+	})
+
+	It("should read the current version from the metadata of the secret, 0 if it is missing or deleted", func() {
+		responses["GET /v1/secret/metadata/apps/my-binding"] = `{"data": {"current_version": 4, "versions": {` +
+			`"3": {"deletion_time": "2020-01-01T00:00:00Z"}, "4": {"deletion_time": "2999-01-01T00:00:00Z"}}}}`
+		version, err := client.Version(context.Background(), "my-token", "secret", "apps/my-binding")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(version).To(Equal(int64(4)))
+		Expect(tokens).ToNot(HaveKey("GET /v1/secret/data/apps/my-binding"))
+
+		version, err = client.Version(context.Background(), "my-token", "secret", "apps/other-binding")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(version).To(BeZero())
+
+		responses["GET /v1/secret/metadata/apps/deleted-binding"] = `{"data": {"current_version": 2, "versions": {` +
+			`"2": {"deletion_time": "2020-01-01T00:00:00Z"}}}}`
+		version, err = client.Version(context.Background(), "my-token", "secret", "apps/deleted-binding")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(version).To(BeZero())
+	})
+
+	It("should delete the metadata and all versions of the secret", func() {
+		responses["DELETE /v1/secret/metadata/apps/my-binding"] = ""
+		Expect(client.Delete(context.Background(), "my-token", "secret", "apps/my-binding")).To(Succeed())
+		Expect(tokens).To(HaveKey("DELETE /v1/secret/metadata/apps/my-binding"))
+		Expect(client.Delete(context.Background(), "my-token", "secret", "apps/other-binding")).To(Succeed())
+	})
+})
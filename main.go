@@ -17,8 +17,15 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
@@ -32,10 +39,21 @@ import (
 	"github.com/SAP/sap-btp-service-operator/api/v1/webhooks"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	"github.com/SAP/sap-btp-service-operator/internal/cachetransform"
+	"github.com/SAP/sap-btp-service-operator/internal/certs"
 	"github.com/SAP/sap-btp-service-operator/internal/secrets"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/SAP/sap-btp-service-operator/internal/config"
+	"github.com/SAP/sap-btp-service-operator/internal/csiprovider"
+	"github.com/SAP/sap-btp-service-operator/internal/dashboard"
+	"github.com/SAP/sap-btp-service-operator/internal/escrow"
+	"github.com/SAP/sap-btp-service-operator/internal/httputil"
+	"github.com/SAP/sap-btp-service-operator/internal/vault"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -48,6 +66,19 @@ import (
 	// +kubebuilder:scaffold:imports
 )
 
+// the components the manager runs, the webhook server can run in a separate deployment so that restarts of the
+// controllers do not affect the admission of resources
+const (
+	modeAll         = "all"
+	modeControllers = "controllers"
+	modeWebhooks    = "webhooks"
+	// modeCSIProvider runs the provider of the Secrets Store CSI driver on the nodes instead of the manager
+	modeCSIProvider = "csi-provider"
+)
+
+// operatorServiceAccount is the service account of the operator deployment in the release namespace
+const operatorServiceAccount = "sap-btp-operator"
+
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
@@ -64,8 +95,28 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var webhookHost string
+	var webhookPort int
+	var webhookCertDir string
+	var webhookClientCAName string
+	var tlsMinVersion string
+	var tlsCipherSuites string
+	var mode string
+	var csiProviderSocket string
+	var csiCredentialsURL string
+	var csiCredentialsCAFile string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoints bind to.")
+	flag.StringVar(&webhookHost, "webhook-host", "", "The address the webhook server binds to, all interfaces if empty.")
+	flag.IntVar(&webhookPort, "webhook-port", 9443, "The port the webhook server binds to.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "", "The directory that contains the webhook server certificate and key (tls.crt, tls.key).")
+	flag.StringVar(&webhookClientCAName, "webhook-client-ca-name", "", "The CA bundle in the webhook certificate directory used to verify client certificates, client auth is disabled if empty.")
+	flag.StringVar(&tlsMinVersion, "tls-min-version", "1.2", "The minimum TLS version of the webhook server (1.0, 1.1, 1.2 or 1.3).")
+	flag.StringVar(&tlsCipherSuites, "tls-cipher-suites", "", "Comma separated list of TLS cipher suites of the webhook server, the Go defaults are used if empty.")
+	flag.StringVar(&mode, "mode", modeAll, "The components the manager runs: all, controllers or webhooks, to run the webhook server in a separate deployment, or csi-provider to run the provider of the Secrets Store CSI driver.")
+	flag.StringVar(&csiProviderSocket, "csi-provider-socket", filepath.Join("/etc/kubernetes/secrets-store-csi-providers", csiprovider.Name+".sock"), "The unix socket the provider of the Secrets Store CSI driver listens on in csi-provider mode.")
+	flag.StringVar(&csiCredentialsURL, "csi-credentials-url", "", "The URL of the CSI credentials endpoint of the manager the provider reads the credentials from in csi-provider mode.")
+	flag.StringVar(&csiCredentialsCAFile, "csi-credentials-ca-file", "", "The CA bundle used to verify the certificate of the CSI credentials endpoint in csi-provider mode.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
@@ -73,44 +124,156 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 
+	if mode != modeAll && mode != modeControllers && mode != modeWebhooks && mode != modeCSIProvider {
+		setupLog.Error(fmt.Errorf("unknown mode '%s', the supported modes are %s, %s, %s and %s", mode, modeAll, modeControllers, modeWebhooks, modeCSIProvider), "invalid mode")
+		os.Exit(1)
+	}
+	if mode == modeCSIProvider {
+		runCSIProvider(ctrl.SetupSignalHandler(), csiProviderSocket, csiCredentialsURL, csiCredentialsCAFile)
+		return
+	}
+	minVersion, err := httputil.TLSVersion(tlsMinVersion)
+	if err != nil {
+		setupLog.Error(err, "invalid tls min version")
+		os.Exit(1)
+	}
+	cipherSuites, err := httputil.CipherSuites(strings.Split(tlsCipherSuites, ","))
+	if err != nil {
+		setupLog.Error(err, "invalid tls cipher suites")
+		os.Exit(1)
+	}
+	webhookServer := webhook.NewServer(webhook.Options{
+		Host:         webhookHost,
+		Port:         webhookPort,
+		CertDir:      webhookCertDir,
+		ClientCAName: webhookClientCAName,
+		TLSOpts: []func(*tls.Config){
+			func(c *tls.Config) {
+				c.MinVersion = minVersion
+				if len(cipherSuites) > 0 {
+					c.CipherSuites = cipherSuites
+				}
+			},
+		},
+	})
+
+	// the webhook servers answer on every replica, electing a leader would keep the controllers from running
+	leaderElection := enableLeaderElection && mode != modeWebhooks
 	mgrOptions := ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
-		Port:                   9443,
+		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
+		LeaderElection:         leaderElection,
 		LeaderElectionID:       "aa689ecc.cloud.sap.com",
 	}
 
+	var allowedNamespaces []string
 	if !config.Get().AllowClusterAccess {
-		allowedNamespaces := config.Get().AllowedNamespaces
+		allowedNamespaces = config.Get().AllowedNamespaces
 		allowedNamespaces = append(allowedNamespaces, config.Get().ReleaseNamespace)
+		if len(config.Get().BindingSecretsNamespace) > 0 {
+			allowedNamespaces = append(allowedNamespaces, config.Get().BindingSecretsNamespace)
+		}
 		setupLog.Info(fmt.Sprintf("Allowed namespaces are %v", allowedNamespaces))
-		mgrOptions.NewCache = func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
-			opts.Namespaces = allowedNamespaces
-			return cache.New(config, opts)
+	}
+	cacheTransform := config.Get().CacheTransform
+	mgrOptions.NewCache = func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		opts.Namespaces = allowedNamespaces
+		if cacheTransform {
+			opts = cachetransform.Apply(opts)
 		}
+		return cache.New(config, opts)
+	}
+	if config.Get().DisableSecretsCache {
+		// the few lookups of secrets are live reads, no informer holds all the secrets of the cluster
+		setupLog.Info("Secrets are not cached")
+		mgrOptions.Client.Cache = &client.CacheOptions{DisableFor: []client.Object{&corev1.Secret{}}}
+	}
+	featureGates := config.Get().FeatureGates
+	setupLog.Info(fmt.Sprintf("Feature gates are %s", featureGates))
+	if len(featureGates.Unknown) > 0 {
+		setupLog.Info(fmt.Sprintf("Ignoring unknown feature gates %v", featureGates.Unknown))
 	}
+	controllers.RecordFeatureGates(featureGates)
+	if err := controllers.ValidateUnbindFailurePolicy(config.Get().UnbindFailurePolicy); err != nil {
+		setupLog.Error(err, "invalid unbind failure policy")
+		os.Exit(1)
+	}
+	if err := controllers.ValidateOperationStore(config.Get().OperationStore); err != nil {
+		setupLog.Error(err, "invalid operation store")
+		os.Exit(1)
+	}
+	if err := controllers.ValidateSecretPresets(config.Get().SecretPresets); err != nil {
+		setupLog.Error(err, "invalid secret presets")
+		os.Exit(1)
+	}
+	if len(config.Get().SecretPresets) > 0 && !featureGates.Enabled(config.SecretFormatters) {
+		setupLog.Error(fmt.Errorf("secret presets require the %s feature gate", config.SecretFormatters), "invalid secret presets")
+		os.Exit(1)
+	}
+
+	ctx := ctrl.SetupSignalHandler()
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), mgrOptions)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	if mode != modeWebhooks {
+		setupControllers(ctx, mgr)
+	}
+	if mode != modeControllers && os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		setupWebhooks(ctx, mgr, webhookCertDir)
+	}
+	// +kubebuilder:scaffold:builder
+
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up health check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up ready check")
+		os.Exit(1)
+	}
+	if mode == modeWebhooks {
+		if err := mgr.AddReadyzCheck("webhook", mgr.GetWebhookServer().StartedChecker()); err != nil {
+			setupLog.Error(err, "unable to set up webhook check")
+			os.Exit(1)
+		}
+	}
+
+	setupLog.Info("starting manager")
+	if err := mgr.Start(ctx); err != nil {
+		setupLog.Error(err, "problem running manager")
+		os.Exit(1)
+	}
+}
+
+// setupControllers sets up the controllers and the background tasks of the operator
+func setupControllers(ctx context.Context, mgr ctrl.Manager) {
+	// the settings changed at runtime with the OperatorConfig, restored before the resources are reconciled with the
+	// cluster IDs they were created with
+	live := config.NewLive(config.Get())
+	restoreOperatorConfig(ctx, mgr, live)
+
 	secretResolver := &secrets.SecretResolver{
 		ManagementNamespace:    config.Get().ManagementNamespace,
 		ReleaseNamespace:       config.Get().ReleaseNamespace,
 		EnableNamespaceSecrets: config.Get().EnableNamespaceSecrets,
+		CredentialsDir:         config.Get().CredentialsDir,
 		Client:                 mgr.GetClient(),
 		Log:                    logf.Log.WithName("secret-resolver"),
+		Live:                   live,
 	}
 
-	if err = (&controllers.ServiceInstanceReconciler{
+	if err := (&controllers.ServiceInstanceReconciler{
 		BaseReconciler: &controllers.BaseReconciler{
 			Client:         mgr.GetClient(),
 			Log:            ctrl.Log.WithName("controllers").WithName("ServiceInstance"),
 			Scheme:         mgr.GetScheme(),
 			Config:         config.Get(),
+			Live:           live,
 			SecretResolver: secretResolver,
 			Recorder:       mgr.GetEventRecorderFor("ServiceInstance"),
 		},
@@ -118,45 +281,304 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "ServiceInstance")
 		os.Exit(1)
 	}
-	if err = (&controllers.ServiceBindingReconciler{
+	featureGates := config.Get().FeatureGates
+	var credentialsEscrow escrow.Sink
+	if len(config.Get().EscrowVaultAddress) > 0 && featureGates.Enabled(config.CredentialsEscrow) {
+		credentialsEscrow = &escrow.VaultSink{
+			KV: &vault.Client{
+				Client:  &http.Client{Timeout: config.Get().EscrowTimeout},
+				Address: config.Get().EscrowVaultAddress,
+			},
+			Mount:     config.Get().EscrowVaultMount,
+			Path:      config.Get().EscrowVaultPath,
+			TokenFile: config.Get().EscrowVaultTokenFile,
+			Expiry:    config.Get().EscrowTTL,
+		}
+	}
+	var credentialsVault vault.KV
+	if len(config.Get().CredentialsVaultAddress) > 0 && featureGates.Enabled(config.VaultCredentials) {
+		if len(config.Get().CredentialsVaultAudience) == 0 {
+			// without an audience the service account tokens are valid for the API server, Vault could replay them
+			setupLog.Error(fmt.Errorf("the audience of the Vault server of the credentials targets is required"), "invalid credentials target configuration")
+			os.Exit(1)
+		}
+		credentialsVault = &vault.Client{
+			Client:  &http.Client{Timeout: config.Get().CredentialsVaultTimeout},
+			Address: config.Get().CredentialsVaultAddress,
+		}
+	}
+	bindingReconciler := &controllers.ServiceBindingReconciler{
 		BaseReconciler: &controllers.BaseReconciler{
 			Client:         mgr.GetClient(),
 			Log:            ctrl.Log.WithName("controllers").WithName("ServiceBinding"),
 			Scheme:         mgr.GetScheme(),
 			Config:         config.Get(),
+			Live:           live,
 			SecretResolver: secretResolver,
 			Recorder:       mgr.GetEventRecorderFor("ServiceBinding"),
+			APIReader:      mgr.GetAPIReader(),
 		},
-	}).SetupWithManager(mgr); err != nil {
+		Escrow:           credentialsEscrow,
+		CredentialsVault: credentialsVault,
+	}
+	if err := bindingReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ServiceBinding")
 		os.Exit(1)
 	}
-	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
-		mgr.GetWebhookServer().Register("/mutate-services-cloud-sap-com-v1-serviceinstance", &webhook.Admission{Handler: &webhooks.ServiceInstanceDefaulter{Decoder: admission.NewDecoder(mgr.GetScheme())}})
-		mgr.GetWebhookServer().Register("/mutate-services-cloud-sap-com-v1-servicebinding", &webhook.Admission{Handler: &webhooks.ServiceBindingDefaulter{Decoder: admission.NewDecoder(mgr.GetScheme())}})
-		if err = (&servicesv1.ServiceBinding{}).SetupWebhookWithManager(mgr); err != nil {
-			setupLog.Error(err, "unable to create webhook", "webhook", "ServiceBinding")
+	if featureGates.Enabled(config.CSICredentials) {
+		// the provider on the nodes reads the credentials through the metrics proxy, which authorizes its service account
+		if err := mgr.AddMetricsExtraHandler(csiprovider.CredentialsPath, &csiprovider.CredentialsHandler{Credentials: bindingReconciler, Log: ctrl.Log.WithName("csi-credentials")}); err != nil {
+			setupLog.Error(err, "unable to set up CSI credentials endpoint")
 			os.Exit(1)
 		}
-		if err = (&servicesv1.ServiceInstance{}).SetupWebhookWithManager(mgr); err != nil {
-			setupLog.Error(err, "unable to create webhook", "webhook", "ServiceInstance")
+	}
+	if err := (&controllers.AdoptionRunReconciler{
+		BaseReconciler: &controllers.BaseReconciler{
+			Client:         mgr.GetClient(),
+			Log:            ctrl.Log.WithName("controllers").WithName("AdoptionRun"),
+			Scheme:         mgr.GetScheme(),
+			Config:         config.Get(),
+			Live:           live,
+			SecretResolver: secretResolver,
+			Recorder:       mgr.GetEventRecorderFor("AdoptionRun"),
+		},
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AdoptionRun")
+		os.Exit(1)
+	}
+	if err := (&controllers.OperatorConfigReconciler{
+		BaseReconciler: &controllers.BaseReconciler{
+			Client:    mgr.GetClient(),
+			Log:       ctrl.Log.WithName("controllers").WithName("OperatorConfig"),
+			Scheme:    mgr.GetScheme(),
+			Config:    config.Get(),
+			Live:      live,
+			Recorder:  mgr.GetEventRecorderFor("OperatorConfig"),
+			APIReader: mgr.GetAPIReader(),
+		},
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "OperatorConfig")
+		os.Exit(1)
+	}
+
+	if config.Get().EnableDashboard {
+		if err := mgr.AddMetricsExtraHandler(dashboard.Path, &dashboard.Handler{Client: mgr.GetClient(), Log: ctrl.Log.WithName("dashboard")}); err != nil {
+			setupLog.Error(err, "unable to set up dashboard endpoint")
 			os.Exit(1)
 		}
 	}
-	// +kubebuilder:scaffold:builder
 
-	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
-		setupLog.Error(err, "unable to set up health check")
+	if config.Get().SummaryEventInterval > 0 || config.Get().SLOReportInterval > 0 || config.Get().NamespaceStatusInterval > 0 {
+		if err := mgr.Add(&controllers.PeriodicReporter{
+			BaseReconciler: &controllers.BaseReconciler{
+				Client:   mgr.GetClient(),
+				Log:      ctrl.Log.WithName("reports"),
+				Scheme:   mgr.GetScheme(),
+				Config:   config.Get(),
+				Recorder: mgr.GetEventRecorderFor("sap-btp-operator"),
+			},
+			SummaryEventInterval:    config.Get().SummaryEventInterval,
+			SLOReportInterval:       config.Get().SLOReportInterval,
+			SLOWindow:               config.Get().SLOWindow,
+			NamespaceStatusInterval: config.Get().NamespaceStatusInterval,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up the reports")
+			os.Exit(1)
+		}
+	}
+
+	if interval := config.Get().CertExpiryScanInterval; interval > 0 {
+		if err := mgr.Add(&controllers.CertificateExpiryScanner{
+			BaseReconciler: &controllers.BaseReconciler{
+				Client: mgr.GetClient(),
+				Log:    ctrl.Log.WithName("certificate-expiry"),
+				Scheme: mgr.GetScheme(),
+				Config: config.Get(),
+			},
+			Interval: interval,
+			Window:   config.Get().CertExpiryWindow,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up the certificate expiry scan")
+			os.Exit(1)
+		}
+	}
+
+	if interval := config.Get().CatalogSyncInterval; interval > 0 {
+		if err := mgr.Add(&controllers.CatalogSync{
+			BaseReconciler: &controllers.BaseReconciler{
+				Client:         mgr.GetClient(),
+				Log:            ctrl.Log.WithName("catalog-sync"),
+				Scheme:         mgr.GetScheme(),
+				Config:         config.Get(),
+				Live:           live,
+				SecretResolver: secretResolver,
+			},
+			Interval: interval,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up catalog sync")
+			os.Exit(1)
+		}
+	}
+
+	if config.Get().TokenURLCheck {
+		if err := mgr.AddReadyzCheck("token-urls", controllers.TokenURLCheck); err != nil {
+			setupLog.Error(err, "unable to set up token URL check")
+			os.Exit(1)
+		}
+	}
+}
+
+// setupWebhooks registers the admission webhooks and sets up the rotation of their certificate
+func setupWebhooks(ctx context.Context, mgr ctrl.Manager, webhookCertDir string) {
+	operatorUsername := fmt.Sprintf("system:serviceaccount:%s:%s", config.Get().ReleaseNamespace, operatorServiceAccount)
+	gitOpsHints := config.Get().FeatureGates.Enabled(config.GitOpsHints)
+	mgr.GetWebhookServer().Register("/mutate-services-cloud-sap-com-v1-serviceinstance", &webhook.Admission{Handler: &webhooks.ServiceInstanceDefaulter{
+		Decoder:          admission.NewDecoder(mgr.GetScheme()),
+		OperatorUsername: operatorUsername,
+		GitOpsHints:      gitOpsHints,
+	}})
+	mgr.GetWebhookServer().Register("/mutate-services-cloud-sap-com-v1-servicebinding", &webhook.Admission{Handler: &webhooks.ServiceBindingDefaulter{
+		Decoder:          admission.NewDecoder(mgr.GetScheme()),
+		OperatorUsername: operatorUsername,
+		GitOpsHints:      gitOpsHints,
+		Client:           mgr.GetAPIReader(),
+	}})
+	validation := config.Get().ParametersFromValidation
+	if err := webhooks.ValidateParametersFromValidation(validation); err != nil {
+		setupLog.Error(err, "invalid parametersFrom validation")
 		os.Exit(1)
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
-		setupLog.Error(err, "unable to set up ready check")
+	if validation != webhooks.ParametersFromValidationDisabled {
+		mgr.GetWebhookServer().Register("/validate-services-cloud-sap-com-v1-parametersfrom", &webhook.Admission{Handler: &webhooks.ParametersFromValidator{
+			Client:  mgr.GetAPIReader(),
+			Decoder: admission.NewDecoder(mgr.GetScheme()),
+			Enforce: validation == webhooks.ParametersFromValidationEnforce,
+		}})
+	}
+	if len(config.Get().PolicyURL) > 0 {
+		if err := webhooks.ValidatePolicyFailurePolicy(config.Get().PolicyFailurePolicy); err != nil {
+			setupLog.Error(err, "invalid policy webhook failure policy")
+			os.Exit(1)
+		}
+		mgr.GetWebhookServer().Register("/mutate-services-cloud-sap-com-v1-policy", &webhook.Admission{Handler: &webhooks.PolicyWebhook{
+			Client:        &http.Client{Timeout: config.Get().PolicyTimeout},
+			URL:           config.Get().PolicyURL,
+			FailurePolicy: config.Get().PolicyFailurePolicy,
+		}})
+	}
+	if config.Get().FeatureGates.Enabled(config.BindingInjection) {
+		mgr.GetWebhookServer().Register("/mutate-v1-pod-binding-injection", &webhook.Admission{Handler: &webhooks.PodBindingInjector{
+			Client:           mgr.GetClient(),
+			Decoder:          admission.NewDecoder(mgr.GetScheme()),
+			SecretsNamespace: config.Get().BindingSecretsNamespace,
+		}})
+	}
+	servicesv1.SetFeatureGates(config.Get().FeatureGates)
+	servicesv1.SetRotationFrequencyGuard(config.Get().RotationFrequencyGuard)
+	servicesv1.SetSecretTemplateDryRun(config.Get().SecretTemplateDryRun)
+	servicesv1.SetParametersLimits(config.Get().ParametersMaxSize, config.Get().ParametersMaxDepth)
+	if err := (&servicesv1.ServiceBinding{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "ServiceBinding")
 		os.Exit(1)
 	}
+	if err := (&servicesv1.ServiceInstance{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "ServiceInstance")
+		os.Exit(1)
+	}
+	if config.Get().ManageWebhookCerts {
+		setupWebhookCertRotation(ctx, mgr, webhookCertDir)
+	}
+}
 
-	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
-		setupLog.Error(err, "problem running manager")
+// runCSIProvider serves the credentials of the bindings with the csi credentials target to the Secrets Store CSI driver
+// of the node. The bindings are read from the API server on each mount, no cache of all bindings is held on the nodes.
+func runCSIProvider(ctx context.Context, socket, credentialsURL, caFile string) {
+	if !config.Get().FeatureGates.Enabled(config.CSICredentials) {
+		setupLog.Error(fmt.Errorf("the CSI provider requires the %s feature gate", config.CSICredentials), "unable to run CSI provider")
+		os.Exit(1)
+	}
+	if len(credentialsURL) == 0 || len(caFile) == 0 {
+		setupLog.Error(fmt.Errorf("the URL and the CA bundle of the CSI credentials endpoint are required"), "unable to run CSI provider")
+		os.Exit(1)
+	}
+	caBundle, err := os.ReadFile(caFile)
+	if err != nil {
+		setupLog.Error(err, "unable to read the CA bundle of the CSI credentials endpoint")
+		os.Exit(1)
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caBundle) {
+		setupLog.Error(fmt.Errorf("no certificates found in %s", caFile), "unable to read the CA bundle of the CSI credentials endpoint")
+		os.Exit(1)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}
+
+	// the provider has no access to SM or to the secrets of the cluster, the manager reads the credentials
+	credentials := &csiprovider.RemoteCredentials{
+		URL:       credentialsURL,
+		TokenFile: "/var/run/secrets/kubernetes.io/serviceaccount/token",
+		Client:    &http.Client{Transport: transport, Timeout: 30 * time.Second},
+	}
+	provider := &csiprovider.Provider{Credentials: credentials, Log: ctrl.Log.WithName("csi-provider")}
+	if err := provider.Serve(ctx, socket); err != nil {
+		setupLog.Error(err, "problem running CSI provider")
+		os.Exit(1)
+	}
+}
+
+// restoreOperatorConfig restores the changes of the settings recorded in the status of the OperatorConfig
+func restoreOperatorConfig(ctx context.Context, mgr ctrl.Manager, live *config.Live) {
+	// the manager cache is not started yet
+	cl, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		setupLog.Error(err, "unable to create operator config client")
+		os.Exit(1)
+	}
+	if err := controllers.RestoreOperatorConfigHistory(ctx, cl, live, config.Get().ReleaseNamespace); err != nil {
+		if !meta.IsNoMatchError(err) {
+			setupLog.Error(err, "unable to restore the operator config")
+			os.Exit(1)
+		}
+		setupLog.Info("the OperatorConfig CRD is not installed, using the settings of the installation")
+	}
+	if history := live.History(); len(history) > 0 {
+		setupLog.Info(fmt.Sprintf("Restored %d changes of the settings, the cluster ID is %s", len(history), live.Current().ClusterID))
+	}
+}
+
+// setupWebhookCertRotation creates the webhook certificate before the webhook server starts and rotates it afterwards
+func setupWebhookCertRotation(ctx context.Context, mgr ctrl.Manager, certDir string) {
+	if len(certDir) == 0 {
+		certDir = filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs")
+	}
+	// the manager cache is not started yet and doesn't cover the cluster scoped webhook configurations
+	cl, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		setupLog.Error(err, "unable to create webhook certificate client")
+		os.Exit(1)
+	}
+
+	namespace := config.Get().ReleaseNamespace
+	rotator := &certs.Rotator{
+		Client:                   cl,
+		Log:                      ctrl.Log.WithName("cert-rotator"),
+		SecretKey:                types.NamespacedName{Namespace: namespace, Name: "webhook-server-cert"},
+		CertDir:                  certDir,
+		DNSNames:                 []string{fmt.Sprintf("sap-btp-operator-webhook-service.%s.svc", namespace), fmt.Sprintf("sap-btp-operator-webhook-service.%s.svc.cluster.local", namespace)},
+		MutatingWebhookConfigs:   []string{"sap-btp-operator-mutating-webhook-configuration"},
+		ValidatingWebhookConfigs: []string{"sap-btp-operator-validating-webhook-configuration"},
+		CAValidity:               10 * 365 * 24 * time.Hour,
+		CertValidity:             365 * 24 * time.Hour,
+		RefreshBefore:            30 * 24 * time.Hour,
+		CheckInterval:            time.Hour,
+	}
+	if err := rotator.EnsureCertificate(ctx); err != nil {
+		setupLog.Error(err, "unable to create webhook certificate")
+		os.Exit(1)
+	}
+	if err := mgr.Add(rotator); err != nil {
+		setupLog.Error(err, "unable to set up webhook certificate rotation")
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,390 @@
+// Package lint validates ServiceInstance and ServiceBinding manifests offline, e.g. in CI pipelines, before they are
+// applied to a cluster. It runs the checks of the admission webhooks of the operator together with checks the
+// cluster can only report once the resources are reconciled: naming limits, the sanity of the credentials rotation
+// policy and a dry run of the secret template against sample credentials.
+package lint
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	servicesv1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/internal/secrets/template"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// Severity of a finding, errors are rejected by the cluster or fail the reconciliation,
+// warnings point to resources that may not behave as intended
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+)
+
+const (
+	group                = "services.cloud.sap.com"
+	serviceInstance      = "ServiceInstance"
+	serviceBinding       = "ServiceBinding"
+	rotationSuffixLength = 7 // "-r" and a revision of up to 5 digits appended to rotated bindings and their secrets
+)
+
+// DefaultSampleCredentials are the binding credentials secret templates are rendered with when no sample
+// credentials are given in the Options
+var DefaultSampleCredentials = map[string]interface{}{
+	"clientid":     "sample-client-id",
+	"clientsecret": "sample-client-secret",
+	"url":          "https://sample.example.com",
+	"uri":          "https://sample.example.com",
+	"username":     "sample-user",
+	"password":     "sample-password",
+}
+
+// Options of the linter
+type Options struct {
+	// SampleCredentials are the binding credentials secret templates are rendered with,
+	// DefaultSampleCredentials are used if nil. Templates that fail to render with the default
+	// credentials are reported as warnings only, with given credentials as errors.
+	SampleCredentials map[string]interface{}
+}
+
+// Finding is a problem found in a manifest
+type Finding struct {
+	// Source is the file or the name of the input the manifest was read from
+	Source string
+	// Kind, Namespace and Name identify the resource, they are empty if the document could not be decoded
+	Kind      string
+	Namespace string
+	Name      string
+	Severity  Severity
+	Message   string
+}
+
+func (f Finding) String() string {
+	resource := f.Name
+	if len(f.Namespace) > 0 {
+		resource = f.Namespace + "/" + f.Name
+	}
+	if len(f.Kind) == 0 {
+		return fmt.Sprintf("%s: %s: %s", f.Source, f.Severity, f.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s %s: %s", f.Source, f.Severity, f.Kind, resource, f.Message)
+}
+
+// HasErrors checks whether any of the findings is an error
+func HasErrors(findings []Finding) bool {
+	for _, finding := range findings {
+		if finding.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// LintPath lints the manifests in the given file, or in the .yaml, .yml and .json files below the given directory
+func LintPath(path string, options Options) ([]Finding, error) {
+	var findings []Finding
+	err := filepath.WalkDir(path, func(file string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		if file != path && !isManifestFile(file) {
+			return nil
+		}
+		manifests, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		fileFindings, err := Lint(file, manifests, options)
+		if err != nil {
+			return err
+		}
+		findings = append(findings, fileFindings...)
+		return nil
+	})
+	return findings, err
+}
+
+// Lint checks the ServiceInstances and ServiceBindings in the given YAML or JSON manifests, documents of other kinds
+// are skipped. An error is only returned if the manifests cannot be split into documents.
+func Lint(source string, manifests []byte, options Options) ([]Finding, error) {
+	l := &linter{source: source, options: options, explicitCredentials: options.SampleCredentials != nil}
+	if !l.explicitCredentials {
+		l.options.SampleCredentials = DefaultSampleCredentials
+	}
+
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(manifests)))
+	for {
+		document, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %s", source, err.Error())
+		}
+		if len(bytes.TrimSpace(document)) == 0 {
+			continue
+		}
+		l.lintDocument(document)
+	}
+	return l.findings, nil
+}
+
+type linter struct {
+	source              string
+	options             Options
+	explicitCredentials bool
+	findings            []Finding
+
+	// the resource currently linted
+	kind, namespace, name string
+
+	// the inline parameters of the instances linted so far by namespace and name, secret templates of their
+	// bindings are rendered with them
+	instanceParameters map[string]map[string]interface{}
+}
+
+func (l *linter) report(severity Severity, format string, args ...interface{}) {
+	l.findings = append(l.findings, Finding{
+		Source:    l.source,
+		Kind:      l.kind,
+		Namespace: l.namespace,
+		Name:      l.name,
+		Severity:  severity,
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *linter) lintDocument(document []byte) {
+	l.kind, l.namespace, l.name = "", "", ""
+	object := &metav1.PartialObjectMetadata{}
+	if err := yaml.Unmarshal(document, object); err != nil {
+		l.report(Error, "the document is not a valid manifest: %s", err.Error())
+		return
+	}
+	gvk := object.GroupVersionKind()
+	if gvk.Group != group || (gvk.Kind != serviceInstance && gvk.Kind != serviceBinding) {
+		return
+	}
+	l.kind, l.namespace, l.name = gvk.Kind, object.Namespace, object.Name
+
+	if gvk.Version != servicesv1.GroupVersion.Version {
+		l.report(Warning, "%s is deprecated and not linted, use %s", gvk.GroupVersion(), servicesv1.GroupVersion)
+		return
+	}
+	l.lintName()
+
+	switch gvk.Kind {
+	case serviceInstance:
+		instance := &servicesv1.ServiceInstance{}
+		if err := yaml.UnmarshalStrict(document, instance); err != nil {
+			l.report(Error, "the manifest does not match the schema: %s", err.Error())
+			return
+		}
+		l.lintInstance(instance)
+	case serviceBinding:
+		binding := &servicesv1.ServiceBinding{}
+		if err := yaml.UnmarshalStrict(document, binding); err != nil {
+			l.report(Error, "the manifest does not match the schema: %s", err.Error())
+			return
+		}
+		l.lintBinding(binding)
+	}
+}
+
+func (l *linter) lintName() {
+	if len(l.name) == 0 {
+		l.report(Error, "metadata.name is required")
+		return
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(l.name) {
+		l.report(Error, "metadata.name is invalid: %s", msg)
+	}
+}
+
+func (l *linter) lintInstance(instance *servicesv1.ServiceInstance) {
+	l.required("spec.serviceOfferingName", instance.Spec.ServiceOfferingName)
+	l.required("spec.servicePlanName", instance.Spec.ServicePlanName)
+	l.enum("spec.enforcementMode", string(instance.Spec.EnforcementMode),
+		string(servicesv1.EnforcementModeEnforce), string(servicesv1.EnforcementModeWarn), string(servicesv1.EnforcementModeIgnore))
+
+	if _, err := instance.ValidateCreate(); err != nil {
+		l.report(Error, "%s", err.Error())
+	}
+	if l.instanceParameters == nil {
+		l.instanceParameters = map[string]map[string]interface{}{}
+	}
+	l.instanceParameters[instance.Namespace+"/"+instance.Name] = inlineParameters(instance.Spec.Parameters, instance.Spec.ParametersMergeStrategy)
+}
+
+func (l *linter) lintBinding(binding *servicesv1.ServiceBinding) {
+	defaultBinding(binding)
+
+	// the reference of the instance, by name or by ID, is checked by the validation of the webhook
+	for _, msg := range validation.IsDNS1123Subdomain(binding.Spec.SecretName) {
+		l.report(Error, "spec.secretName is invalid: %s", msg)
+	}
+	if binding.Spec.SecretKey != nil {
+		l.secretKey("spec.secretKey", *binding.Spec.SecretKey)
+	}
+	if binding.Spec.SecretRootKey != nil {
+		l.secretKey("spec.secretRootKey", *binding.Spec.SecretRootKey)
+	}
+	if binding.Spec.EncryptionKeyRef != nil {
+		l.enum("spec.encryptionKeyRef.kind", binding.Spec.EncryptionKeyRef.Kind, "ConfigMap", "Secret")
+		l.required("spec.encryptionKeyRef.name", binding.Spec.EncryptionKeyRef.Name)
+		l.required("spec.encryptionKeyRef.key", binding.Spec.EncryptionKeyRef.Key)
+	}
+
+	if _, err := binding.ValidateCreate(); err != nil {
+		l.report(Error, "%s", err.Error())
+		return
+	}
+	l.lintRotation(binding)
+	l.lintSecretTemplate(binding)
+}
+
+// defaultBinding sets the defaults of the mutating webhook of the operator
+func defaultBinding(binding *servicesv1.ServiceBinding) {
+	if len(binding.Spec.ExternalName) == 0 {
+		binding.Spec.ExternalName = binding.Name
+	}
+	if len(binding.Spec.SecretName) == 0 {
+		binding.Spec.SecretName = binding.Name
+	}
+	if binding.Spec.CredRotationPolicy != nil {
+		if len(binding.Spec.CredRotationPolicy.RotationFrequency) == 0 {
+			binding.Spec.CredRotationPolicy.RotationFrequency = "72h"
+		}
+		if len(binding.Spec.CredRotationPolicy.RotatedBindingTTL) == 0 {
+			binding.Spec.CredRotationPolicy.RotatedBindingTTL = "48h"
+		}
+	}
+}
+
+// lintRotation checks that the names the operator derives when rotating the credentials are valid,
+// the rotation fails otherwise when the rotated binding is backed up
+func (l *linter) lintRotation(binding *servicesv1.ServiceBinding) {
+	if binding.Spec.CredRotationPolicy == nil || !binding.Spec.CredRotationPolicy.Enabled {
+		return
+	}
+	if len(validation.IsValidLabelValue(binding.Name)) > 0 {
+		l.report(Error, "metadata.name must be a valid label value of at most %d characters to rotate the credentials, the rotated binding is labeled with it", validation.LabelValueMaxLength)
+	}
+	if len(binding.Name)+rotationSuffixLength > validation.DNS1123SubdomainMaxLength {
+		l.report(Error, "metadata.name must be at most %d characters to rotate the credentials, the rotated binding is named with a suffix of %d characters", validation.DNS1123SubdomainMaxLength-rotationSuffixLength, rotationSuffixLength)
+	}
+	if len(binding.Spec.SecretName)+rotationSuffixLength > validation.DNS1123SubdomainMaxLength {
+		l.report(Error, "spec.secretName must be at most %d characters to rotate the credentials, the secret of the rotated binding is named with a suffix of %d characters", validation.DNS1123SubdomainMaxLength-rotationSuffixLength, rotationSuffixLength)
+	}
+}
+
+// lintSecretTemplate renders the secret template the way the operator does, with the sample credentials
+// and the information of a sample instance
+func (l *linter) lintSecretTemplate(binding *servicesv1.ServiceBinding) {
+	if len(binding.Spec.SecretTemplate) == 0 {
+		return
+	}
+	credentials, err := sampleCredentials(l.options.SampleCredentials)
+	if err != nil {
+		l.report(Error, "the sample credentials are invalid: %s", err.Error())
+		return
+	}
+	parameters := map[string]interface{}{
+		"smBindingCredentials": credentials,
+		"serviceInstanceInfos": map[string]string{
+			"instance_name": binding.Spec.ServiceInstanceName,
+			"instance_guid": "sample-instance-guid",
+			"plan":          "sample-plan",
+			"label":         "sample-offering",
+			"type":          "sample-offering",
+		},
+		"instanceParameters": l.instanceParametersOf(binding),
+		"bindingParameters":  inlineParameters(binding.Spec.Parameters, binding.Spec.ParametersMergeStrategy),
+	}
+	templateName := fmt.Sprintf("%s/%s", binding.Namespace, binding.Name)
+	if _, _, err := template.CreateObjectsFromTemplate(templateName, binding.Spec.SecretTemplate, binding.Spec.SecretTemplateDelimiters.Delimiters(), parameters); err != nil {
+		severity := Error
+		if !l.explicitCredentials {
+			severity = Warning
+		}
+		l.report(severity, "spec.secretTemplate cannot be rendered with the sample credentials: %s", err.Error())
+	}
+}
+
+// instanceParametersOf returns the inline parameters of the instance of the binding if it was linted before the
+// binding, the parameters of instances outside of the manifests are unknown
+func (l *linter) instanceParametersOf(binding *servicesv1.ServiceBinding) map[string]interface{} {
+	if parameters, ok := l.instanceParameters[binding.Namespace+"/"+binding.Spec.ServiceInstanceName]; ok {
+		return parameters
+	}
+	return map[string]interface{}{}
+}
+
+// inlineParameters returns the parameters secret templates are rendered with, the inline parameters unless they
+// are a JSON patch
+func inlineParameters(parameters *runtime.RawExtension, strategy servicesv1.ParametersMergeStrategy) map[string]interface{} {
+	result := make(map[string]interface{})
+	if parameters == nil || strategy == servicesv1.ParametersMergeJSONPatch {
+		return result
+	}
+	if err := yaml.Unmarshal(parameters.Raw, &result); err != nil {
+		return map[string]interface{}{}
+	}
+	return result
+}
+
+// sampleCredentials passes the credentials through JSON, as the credentials returned by SM are
+func sampleCredentials(credentials map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(credentials)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{})
+	return result, json.Unmarshal(data, &result)
+}
+
+func (l *linter) required(field, value string) {
+	if len(value) == 0 {
+		l.report(Error, "%s is required", field)
+	}
+}
+
+func (l *linter) enum(field, value string, allowed ...string) {
+	if len(value) == 0 {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	l.report(Error, "%s '%s' is not supported, supported values are %s", field, value, strings.Join(allowed, ", "))
+}
+
+func (l *linter) secretKey(field, key string) {
+	for _, msg := range validation.IsConfigMapKey(key) {
+		l.report(Error, "%s is invalid: %s", field, msg)
+	}
+}
+
+func isManifestFile(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
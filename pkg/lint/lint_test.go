@@ -0,0 +1,182 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const validManifests = `
+apiVersion: services.cloud.sap.com/v1
+kind: ServiceInstance
+metadata:
+  name: my-instance
+  namespace: app1
+spec:
+  serviceOfferingName: xsuaa
+  servicePlanName: application
+  parameters:
+    xsappname: my-app
+---
+apiVersion: services.cloud.sap.com/v1
+kind: ServiceBinding
+metadata:
+  name: my-binding
+  namespace: app1
+spec:
+  serviceInstanceName: my-instance
+  parameters:
+    credential-type: x509
+  credentialsRotationPolicy:
+    enabled: true
+    rotationFrequency: 168h
+    rotatedBindingTTL: 24h
+  secretTemplate: |
+    apiVersion: v1
+    kind: Secret
+    metadata:
+      labels:
+        plan: {{ .serviceInstanceInfos.plan }}
+    stringData:
+      clientid: {{ .smBindingCredentials.clientid }}
+      xsappname: {{ .instanceParameters.xsappname }}
+      credentialType: {{ index .bindingParameters "credential-type" }}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: not-linted
+`
+
+var _ = Describe("Lint", func() {
+	messages := func(findings []Finding) []string {
+		var result []string
+		for _, finding := range findings {
+			result = append(result, finding.String())
+		}
+		return result
+	}
+
+	lint := func(manifests string, options Options) []Finding {
+		findings, err := Lint("manifests.yaml", []byte(manifests), options)
+		Expect(err).ToNot(HaveOccurred())
+		return findings
+	}
+
+	It("should accept valid manifests", func() {
+		Expect(lint(validManifests, Options{})).To(BeEmpty())
+	})
+
+	It("should report unknown and missing fields", func() {
+		findings := lint(`
+apiVersion: services.cloud.sap.com/v1
+kind: ServiceInstance
+metadata:
+  name: my-instance
+spec:
+  serviceOfferingName: xsuaa
+  planName: application
+`, Options{})
+		Expect(messages(findings)).To(ConsistOf(ContainSubstring(`unknown field "planName"`)))
+
+		findings = lint(`
+apiVersion: services.cloud.sap.com/v1
+kind: ServiceInstance
+metadata:
+  name: My_Instance
+spec:
+  serviceOfferingName: xsuaa
+  enforcementMode: Always
+`, Options{})
+		Expect(HasErrors(findings)).To(BeTrue())
+		Expect(messages(findings)).To(ConsistOf(
+			ContainSubstring("metadata.name is invalid"),
+			ContainSubstring("spec.servicePlanName is required"),
+			ContainSubstring("spec.enforcementMode 'Always' is not supported"),
+		))
+	})
+
+	It("should run the admission checks", func() {
+		findings := lint(`
+apiVersion: services.cloud.sap.com/v1
+kind: ServiceBinding
+metadata:
+  name: my-binding
+spec:
+  serviceInstanceName: my-instance
+  credentialsRotationPolicy:
+    enabled: true
+    rotationFrequency: 1h
+    rotatedBindingTTL: -24h
+`, Options{})
+		Expect(messages(findings)).To(ConsistOf(ContainSubstring("rotatedBindingTTL must not be negative")))
+	})
+
+	It("should report names too long to rotate the credentials", func() {
+		findings := lint(`
+apiVersion: services.cloud.sap.com/v1
+kind: ServiceBinding
+metadata:
+  name: `+strings.Repeat("a", 64)+`
+spec:
+  serviceInstanceName: my-instance
+  credentialsRotationPolicy:
+    enabled: true
+`, Options{})
+		Expect(messages(findings)).To(ConsistOf(ContainSubstring("must be a valid label value")))
+	})
+
+	It("should render the secret template with the sample credentials", func() {
+		manifest := `
+apiVersion: services.cloud.sap.com/v1
+kind: ServiceBinding
+metadata:
+  name: my-binding
+spec:
+  serviceInstanceName: my-instance
+  secretTemplate: |
+    apiVersion: v1
+    kind: Secret
+    stringData:
+      host: {{ .smBindingCredentials.host }}
+`
+		findings := lint(manifest, Options{})
+		Expect(findings).To(HaveLen(1))
+		Expect(findings[0].Severity).To(Equal(Warning))
+		Expect(findings[0].Message).To(ContainSubstring("cannot be rendered with the sample credentials"))
+
+		findings = lint(manifest, Options{SampleCredentials: map[string]interface{}{"url": "https://example.com"}})
+		Expect(findings).To(HaveLen(1))
+		Expect(findings[0].Severity).To(Equal(Error))
+
+		Expect(lint(manifest, Options{SampleCredentials: map[string]interface{}{"host": "example.com"}})).To(BeEmpty())
+	})
+
+	It("should warn about deprecated versions", func() {
+		findings := lint(`
+apiVersion: services.cloud.sap.com/v1alpha1
+kind: ServiceBinding
+metadata:
+  name: my-binding
+spec:
+  serviceInstanceName: my-instance
+`, Options{})
+		Expect(findings).To(HaveLen(1))
+		Expect(findings[0].Severity).To(Equal(Warning))
+	})
+
+	It("should lint the manifest files of a directory", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "valid.yaml"), []byte(validManifests), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "invalid.yml"), []byte("apiVersion: services.cloud.sap.com/v1\nkind: ServiceInstance\nmetadata:\n  name: i\n"), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "README.md"), []byte("kind: ServiceInstance"), 0600)).To(Succeed())
+
+		findings, err := LintPath(dir, Options{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(findings).To(HaveLen(2))
+		Expect(findings[0].Source).To(Equal(filepath.Join(dir, "invalid.yml")))
+	})
+})
@@ -0,0 +1,42 @@
+package testing
+
+import (
+	"path/filepath"
+	"runtime"
+
+	v1 "github.com/SAP/sap-btp-service-operator/api/v1"
+	"github.com/SAP/sap-btp-service-operator/api/v1/webhooks"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// NewEnvironment returns an envtest environment that installs the operator CRDs and webhook configurations,
+// the manifests are located relative to this package so it works from the module cache as well
+func NewEnvironment() *envtest.Environment {
+	return &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join(configDir(), "crd", "bases")},
+		ErrorIfCRDPathMissing: true,
+		WebhookInstallOptions: envtest.WebhookInstallOptions{
+			Paths: []string{filepath.Join(configDir(), "webhook")},
+		},
+	}
+}
+
+// SetupWebhooks registers the defaulting and validating webhooks of the operator resources with the manager,
+// optional webhooks (parametersFrom validation, binding injection) are not registered and are ignored by the API server
+func SetupWebhooks(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register("/mutate-services-cloud-sap-com-v1-serviceinstance", &webhook.Admission{Handler: &webhooks.ServiceInstanceDefaulter{Decoder: admission.NewDecoder(mgr.GetScheme())}})
+	mgr.GetWebhookServer().Register("/mutate-services-cloud-sap-com-v1-servicebinding", &webhook.Admission{Handler: &webhooks.ServiceBindingDefaulter{Decoder: admission.NewDecoder(mgr.GetScheme())}})
+
+	if err := (&v1.ServiceBinding{}).SetupWebhookWithManager(mgr); err != nil {
+		return err
+	}
+	return (&v1.ServiceInstance{}).SetupWebhookWithManager(mgr)
+}
+
+func configDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "config")
+}
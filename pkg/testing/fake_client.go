@@ -0,0 +1,57 @@
+package testing
+
+import (
+	"github.com/SAP/sap-btp-service-operator/client/sm"
+	"github.com/SAP/sap-btp-service-operator/client/sm/smfakes"
+	"github.com/SAP/sap-btp-service-operator/client/sm/types"
+)
+
+// NewFakeClient returns a fake sm.Client where all operations complete synchronously and successfully.
+// The returned fake can be reconfigured per test, e.g. with ProvisionReturns or StatusReturns.
+func NewFakeClient() *smfakes.FakeClient {
+	fakeClient := &smfakes.FakeClient{}
+	fakeClient.ProvisionReturns(&sm.ProvisionResponse{InstanceID: FakeInstanceID, PlanID: FakePlanID, SubaccountID: FakeSubaccountID}, nil)
+	fakeClient.GetInstanceByIDReturns(Instance(FakeInstanceID), nil)
+	fakeClient.GetInstanceParametersReturns(map[string]interface{}{}, nil)
+	fakeClient.ListInstancesReturns(&types.ServiceInstances{}, nil)
+	fakeClient.UpdateInstanceReturns(Instance(FakeInstanceID), "", nil)
+	fakeClient.DeprovisionReturns("", nil)
+
+	binding := Binding(FakeBindingID, map[string]interface{}{"secret_key": "secret_value"})
+	fakeClient.BindReturns(binding, "", nil)
+	fakeClient.GetBindingByIDReturns(binding, nil)
+	fakeClient.ListBindingsReturns(&types.ServiceBindings{}, nil)
+	fakeClient.RenameBindingReturns(binding, nil)
+	fakeClient.UnbindReturns("", nil)
+
+	fakeClient.ListPlansReturns(&types.ServicePlans{ServicePlans: []types.ServicePlan{*Plan("fake-plan")}}, nil)
+	fakeClient.ListOfferingsReturns(&types.ServiceOfferings{ServiceOfferings: []types.ServiceOffering{*Offering("fake-offering")}}, nil)
+	return fakeClient
+}
+
+// WithAsyncOperations configures the fake to accept provision, update, deprovision, bind and unbind asynchronously,
+// the operation is reported as succeeded when polled
+func WithAsyncOperations(fakeClient *smfakes.FakeClient) *smfakes.FakeClient {
+	fakeClient.ProvisionReturns(&sm.ProvisionResponse{InstanceID: FakeInstanceID, PlanID: FakePlanID, SubaccountID: FakeSubaccountID, Location: FakeOperationURL}, nil)
+	fakeClient.UpdateInstanceReturns(nil, FakeOperationURL, nil)
+	fakeClient.DeprovisionReturns(FakeOperationURL, nil)
+	fakeClient.BindReturns(nil, FakeOperationURL, nil)
+	fakeClient.UnbindReturns(FakeOperationURL, nil)
+	fakeClient.StatusStub = func(string, *sm.Parameters) (*types.Operation, error) {
+		return Operation(operationTypeOf(fakeClient), types.SUCCEEDED), nil
+	}
+	return fakeClient
+}
+
+// operationTypeOf derives the type of the polled operation from the calls made to the fake,
+// a delete takes precedence over an update which takes precedence over a create
+func operationTypeOf(fakeClient *smfakes.FakeClient) types.OperationCategory {
+	switch {
+	case fakeClient.DeprovisionCallCount() > 0 || fakeClient.UnbindCallCount() > 0:
+		return types.DELETE
+	case fakeClient.UpdateInstanceCallCount() > 0:
+		return types.UPDATE
+	default:
+		return types.CREATE
+	}
+}
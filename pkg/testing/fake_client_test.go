@@ -0,0 +1,56 @@
+package testing
+
+import (
+	"encoding/json"
+
+	"github.com/SAP/sap-btp-service-operator/client/sm/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fake Client", func() {
+
+	Context("NewFakeClient", func() {
+		It("should complete operations synchronously", func() {
+			fakeClient := NewFakeClient()
+			res, err := fakeClient.Provision(nil, "", "", nil, "", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.InstanceID).To(Equal(FakeInstanceID))
+			Expect(res.Location).To(BeEmpty())
+
+			binding, location, err := fakeClient.Bind(nil, nil, "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(location).To(BeEmpty())
+			Expect(binding.ID).To(Equal(FakeBindingID))
+		})
+	})
+
+	Context("WithAsyncOperations", func() {
+		It("should report the polled operation as succeeded with the type of the last call", func() {
+			fakeClient := WithAsyncOperations(NewFakeClient())
+			res, err := fakeClient.Provision(nil, "", "", nil, "", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.Location).To(Equal(FakeOperationURL))
+
+			operation, err := fakeClient.Status(res.Location, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(operation.Type).To(Equal(types.CREATE))
+			Expect(operation.State).To(Equal(types.SUCCEEDED))
+
+			location, err := fakeClient.Deprovision(FakeInstanceID, nil, "")
+			Expect(err).ToNot(HaveOccurred())
+			operation, err = fakeClient.Status(location, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(operation.Type).To(Equal(types.DELETE))
+		})
+	})
+
+	Context("BindingWithCertificate", func() {
+		It("should return x509 credentials", func() {
+			credentials := map[string]interface{}{}
+			Expect(json.Unmarshal(BindingWithCertificate(FakeBindingID).Credentials, &credentials)).To(Succeed())
+			Expect(credentials).To(HaveKeyWithValue("certificate", FakeCertificate))
+			Expect(credentials).To(HaveKeyWithValue("key", FakePrivateKey))
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package testing
+
+import (
+	gotesting "testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestTesting(t *gotesting.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Testing Suite")
+}